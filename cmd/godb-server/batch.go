@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"goDB/internal/engine"
+	"goDB/internal/sql"
+)
+
+// stdinIsTerminal reports whether standard input is an interactive terminal.
+// When it is not (input is piped or redirected), the server runs in batch mode.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// runScript parses every statement in src up front and then executes them
+// in order, printing SELECT results to w. It stops at the first error, which
+// names the offending statement's position in the script.
+func runScript(w io.Writer, eng *engine.DBEngine, src io.Reader) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return fmt.Errorf("read script: %w", err)
+	}
+
+	stmts, err := sql.ParseMulti(string(data))
+	if err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+
+	for i, stmt := range stmts {
+		cols, rows, err := eng.Execute(stmt)
+		if err != nil {
+			return fmt.Errorf("statement %d: execution error: %w", i+1, err)
+		}
+		if len(cols) > 0 {
+			if err := printResultSet(w, defaultPrintOptions(), cols, rows); err != nil {
+				return fmt.Errorf("statement %d: output error: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tableKeywords are the words after which a table name is expected.
+var tableKeywords = map[string]bool{
+	"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true,
+}
+
+// tableMetaCommands are the meta commands whose argument is a table name.
+var tableMetaCommands = map[string]bool{
+	".schema": true, ".describe": true, ".dump": true, ".stats": true, ".sample": true,
+}
+
+// complete returns the completions for the word that ends at pos in line:
+// table names where a table is expected, and otherwise the column names of
+// the table the statement names after FROM, UPDATE or INTO.
+func (r *repl) complete(line string, pos int) []string {
+	start := wordStart(line, pos)
+	words := strings.Fields(line[:start])
+	prefix := line[start:pos]
+
+	var names []string
+	switch {
+	case len(words) > 0 && (tableKeywords[strings.ToUpper(words[len(words)-1])] ||
+		len(words) == 1 && tableMetaCommands[strings.ToLower(words[0])]):
+		names, _ = r.eng.ListTables()
+	default:
+		table := statementTable(line)
+		if table == "" || prefix == "" {
+			return nil
+		}
+		cols, err := r.eng.TableSchema(table)
+		if err != nil {
+			return nil
+		}
+		for _, c := range cols {
+			names = append(names, c.Name)
+		}
+	}
+
+	var out []string
+	for _, n := range names {
+		if len(n) >= len(prefix) && strings.EqualFold(n[:len(prefix)], prefix) {
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// statementTable returns the first table named after FROM, UPDATE or INTO
+// in line, or "" if there is none.
+func statementTable(line string) string {
+	words := strings.FieldsFunc(line, func(r rune) bool { return !isWordRune(r) })
+	for i := 0; i+1 < len(words); i++ {
+		switch strings.ToUpper(words[i]) {
+		case "FROM", "UPDATE", "INTO":
+			return words[i+1]
+		}
+	}
+	return ""
+}
+
+// wordStart returns the index in line where the word ending at pos begins.
+func wordStart(line string, pos int) int {
+	for pos > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:pos])
+		if !isWordRune(r) {
+			break
+		}
+		pos -= size
+	}
+	return pos
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
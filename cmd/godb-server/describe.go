@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"goDB/internal/engine"
+	"goDB/internal/sql"
+)
+
+// describeTable writes one row per column of a table to w: its name, type,
+// constraints and whether an index covers it.
+func describeTable(w io.Writer, opts printOptions, eng *engine.DBEngine, name string) error {
+	cols, err := eng.TableSchema(name)
+	if err != nil {
+		return fmt.Errorf("table %q: %w", name, err)
+	}
+	indexed, err := eng.IndexedColumns(name)
+	if err != nil {
+		return fmt.Errorf("indexes of %q: %w", name, err)
+	}
+
+	rows := make([]sql.Row, len(cols))
+	for i, c := range cols {
+		hasIndex := slices.ContainsFunc(indexed, func(col string) bool {
+			return strings.EqualFold(col, c.Name)
+		})
+		rows[i] = sql.Row{
+			{Type: sql.TypeString, S: c.Name},
+			{Type: sql.TypeString, S: formatType(c.Type)},
+			{Type: sql.TypeString, S: columnConstraints(c)},
+			{Type: sql.TypeBool, B: hasIndex},
+		}
+	}
+	return printResultSet(w, opts, []string{"column", "type", "constraints", "indexed"}, rows)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"goDB/internal/engine"
+	"goDB/internal/sql"
+)
+
+// dumpDatabase writes CREATE TABLE and INSERT statements that rebuild the
+// given tables to w. With no table names it dumps every table in the engine.
+func dumpDatabase(w io.Writer, eng *engine.DBEngine, tables []string) error {
+	if len(tables) == 0 {
+		names, err := eng.ListTables()
+		if err != nil {
+			return fmt.Errorf("list tables: %w", err)
+		}
+		tables = names
+	}
+
+	tables, err := parentsFirst(eng, tables)
+	if err != nil {
+		return err
+	}
+	for _, name := range tables {
+		if err := dumpTable(w, eng, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpSchema writes the CREATE TABLE statement of every table in the engine
+// to w, each followed by a CREATE INDEX statement per indexed column. It is
+// .dump without the rows. Index names are not stored, so the statements
+// name each index idx_<table>_<column>.
+func dumpSchema(w io.Writer, eng *engine.DBEngine) error {
+	names, err := eng.ListTables()
+	if err != nil {
+		return fmt.Errorf("list tables: %w", err)
+	}
+	names, err = parentsFirst(eng, names)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		cols, err := eng.TableSchema(name)
+		if err != nil {
+			return fmt.Errorf("table %q: %w", name, err)
+		}
+		if _, err := fmt.Fprintln(w, createTableSQL(name, cols)); err != nil {
+			return err
+		}
+		indexed, err := eng.IndexedColumns(name)
+		if err != nil {
+			return fmt.Errorf("indexes of %q: %w", name, err)
+		}
+		for _, col := range indexed {
+			if _, err := fmt.Fprintf(w, "CREATE INDEX idx_%s_%s ON %s (%s);\n", name, col, name, col); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parentsFirst orders tables so that every table comes after the tables
+// its FOREIGN KEY columns reference, which lets the dump be replayed with
+// the constraints enforced. Otherwise the original order is kept.
+func parentsFirst(eng *engine.DBEngine, tables []string) ([]string, error) {
+	parents := make(map[string][]string, len(tables))
+	for _, name := range tables {
+		cols, err := eng.TableSchema(name)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: %w", name, err)
+		}
+		for _, c := range cols {
+			if c.References != nil && c.References.Table != name {
+				parents[name] = append(parents[name], c.References.Table)
+			}
+		}
+	}
+
+	out := make([]string, 0, len(tables))
+	state := make(map[string]int) // 1 = visiting, 2 = done
+	var visit func(name string)
+	visit = func(name string) {
+		if state[name] != 0 {
+			return // done, or a reference cycle
+		}
+		state[name] = 1
+		for _, p := range parents[name] {
+			if slices.Contains(tables, p) {
+				visit(p)
+			}
+		}
+		state[name] = 2
+		out = append(out, name)
+	}
+	for _, name := range tables {
+		visit(name)
+	}
+	return out, nil
+}
+
+// dumpTable writes the DDL for one table followed by one INSERT per row.
+func dumpTable(w io.Writer, eng *engine.DBEngine, name string) error {
+	cols, err := eng.TableSchema(name)
+	if err != nil {
+		return fmt.Errorf("table %q: %w", name, err)
+	}
+
+	if _, err := fmt.Fprintln(w, createTableSQL(name, cols)); err != nil {
+		return err
+	}
+
+	_, rows, err := eng.Execute(&sql.SelectStmt{TableName: name})
+	if err != nil {
+		return fmt.Errorf("scan %q: %w", name, err)
+	}
+
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, insertSQL(name, row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTableSQL renders a CREATE TABLE statement for the given schema.
+func createTableSQL(name string, cols []sql.Column) string {
+	defs := make([]string, len(cols))
+	for i, c := range cols {
+		defs[i] = c.Name + " " + formatType(c.Type)
+		if cons := columnConstraints(c); cons != "" {
+			defs[i] += " " + cons
+		}
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s);", name, strings.Join(defs, ", "))
+}
+
+// columnConstraints renders the NOT NULL, COLLATE, DEFAULT and REFERENCES
+// clauses of a column definition, or "" when it has none.
+func columnConstraints(c sql.Column) string {
+	var parts []string
+	if c.NotNull {
+		parts = append(parts, "NOT NULL")
+	}
+	if c.NoCase {
+		parts = append(parts, "COLLATE NOCASE")
+	}
+	switch {
+	case c.DefaultCurrentTimestamp:
+		parts = append(parts, "DEFAULT CURRENT_TIMESTAMP")
+	case c.Default != nil:
+		parts = append(parts, "DEFAULT "+sqlFormatter.format(*c.Default))
+	}
+	if c.References != nil {
+		parts = append(parts, fmt.Sprintf("REFERENCES %s(%s)", c.References.Table, c.References.Column))
+	}
+	return strings.Join(parts, " ")
+}
+
+// insertSQL renders an INSERT statement for a single row.
+func insertSQL(table string, row sql.Row) string {
+	vals := make([]string, len(row))
+	for i, v := range row {
+		vals[i] = sqlFormatter.format(v)
+	}
+	return fmt.Sprintf("INSERT INTO %s VALUES (%s);", table, strings.Join(vals, ", "))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// defaultNullValue is how NULL is displayed unless changed with .nullvalue.
+const defaultNullValue = "NULL"
+
+// printOptions controls how result sets are rendered.
+type printOptions struct {
+	mode      string // modeList, modeCSV, modeJSON or modeInsert
+	nullValue string // text shown for NULL values
+	table     string // target table of modeInsert
+	// status receives the emptyResult note in the modes whose output has no
+	// room for it (CSV and INSERT statements); nil drops it.
+	status io.Writer
+}
+
+func defaultPrintOptions() printOptions {
+	return printOptions{mode: modeList, nullValue: defaultNullValue, status: os.Stdout}
+}
+
+// emptyResult marks a result set without rows, so it cannot be mistaken
+// for a failed query or for output that is still to come.
+const emptyResult = "(0 rows)"
+
+// printResultSet writes a header line followed by one line per row to w,
+// using the given output options. See printEmpty for a result without rows.
+func printResultSet(w io.Writer, opts printOptions, cols []string, rows []sql.Row) error {
+	if len(rows) == 0 {
+		return printEmpty(w, opts, cols)
+	}
+	switch opts.mode {
+	case modeCSV:
+		return printCSV(w, opts, cols, rows)
+	case modeJSON:
+		return printJSON(w, cols, rows)
+	case modeInsert:
+		return printInserts(w, opts.table, rows)
+	}
+
+	// Header
+	if _, err := fmt.Fprintln(w, strings.Join(cols, " | ")); err != nil {
+		return err
+	}
+
+	// Rows
+	f := listFormatter(opts.nullValue)
+	for _, row := range rows {
+		var parts []string
+		for _, v := range row {
+			parts = append(parts, f.format(v))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(parts, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printEmpty writes a result set without rows: the header and emptyResult
+// in list mode and [] in JSON mode. CSV and INSERT output stays empty, so
+// scripts reading it see no records, and emptyResult goes to opts.status.
+func printEmpty(w io.Writer, opts printOptions, cols []string) error {
+	switch opts.mode {
+	case modeJSON:
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	case modeCSV, modeInsert:
+		if opts.status != nil {
+			fmt.Fprintln(opts.status, emptyResult)
+		}
+		return nil
+	default:
+		_, err := fmt.Fprintf(w, "%s\n%s\n", strings.Join(cols, " | "), emptyResult)
+		return err
+	}
+}
+
+// printCSV writes the result set as RFC 4180 CSV with a header record.
+func printCSV(w io.Writer, opts printOptions, cols []string, rows []sql.Row) error {
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = csvField(c)
+	}
+	if _, err := io.WriteString(w, strings.Join(header, ",")+"\n"); err != nil {
+		return err
+	}
+
+	f := csvFormatter(opts.nullValue)
+	record := make([]string, len(cols))
+	for _, row := range rows {
+		record = record[:0]
+		for _, v := range row {
+			record = append(record, f.format(v))
+		}
+		if _, err := io.WriteString(w, strings.Join(record, ",")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printJSON writes the result set as a JSON array with one object per row,
+// keyed by column name. NULL is always null, whatever .nullvalue says.
+func printJSON(w io.Writer, cols []string, rows []sql.Row) error {
+	keys := make([]string, len(cols))
+	for i, c := range cols {
+		keys[i] = jsonString(c)
+	}
+
+	if _, err := fmt.Fprintln(w, "["); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		fields := make([]string, len(row))
+		for j, v := range row {
+			fields[j] = keys[j] + ": " + jsonFormatter.format(v)
+		}
+		line := "  {" + strings.Join(fields, ", ") + "}"
+		if i < len(rows)-1 {
+			line += ","
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "]")
+	return err
+}
+
+// printInserts writes one INSERT statement into table per row, quoting
+// values the same way .dump does. No header is written.
+func printInserts(w io.Writer, table string, rows []sql.Row) error {
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, insertSQL(table, row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatType(t sql.DataType) string {
+	switch t {
+	case sql.TypeInt:
+		return "INT"
+	case sql.TypeFloat:
+		return "FLOAT"
+	case sql.TypeString:
+		return "STRING"
+	case sql.TypeBool:
+		return "BOOL"
+	case sql.TypeTimestamp:
+		return "TIMESTAMP"
+	case sql.TypeBytes:
+		return "BLOB"
+	default:
+		return "UNKNOWN"
+	}
+}
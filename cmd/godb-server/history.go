@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// historyFile is the name of the REPL history file in the home directory.
+const historyFile = ".godb_history"
+
+// maxHistory is the number of lines kept in memory and in the file.
+const maxHistory = 1000
+
+// history holds the lines typed at the REPL, oldest first, and appends new
+// ones to a file so they can be recalled in later sessions.
+type history struct {
+	lines []string
+	path  string // "" when lines are not saved
+}
+
+// defaultHistoryPath returns ~/.godb_history, or "" when there is no home
+// directory.
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFile)
+}
+
+// loadHistory reads the history file at path. A missing file starts an empty
+// history; any other problem is reported and leaves history unsaved for the
+// session, since losing it should not stop the REPL.
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h
+	}
+	if err != nil {
+		h.disable(err)
+		return h
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		h.disable(err)
+		return h
+	}
+
+	// Keep the file from growing without bound across sessions.
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[len(h.lines)-maxHistory:]
+		data := strings.Join(h.lines, "\n") + "\n"
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			h.disable(err)
+		}
+	}
+	return h
+}
+
+// add records a line, skipping blanks and immediate repeats, and appends it
+// to the history file.
+func (h *history) add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(h.lines); n > 0 && h.lines[n-1] == line {
+		return
+	}
+	h.lines = append(h.lines, line)
+	if len(h.lines) > maxHistory {
+		h.lines = h.lines[1:]
+	}
+
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err == nil {
+		_, err = fmt.Fprintln(f, line)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		h.disable(err)
+	}
+}
+
+// disable stops saving history after err, with a single warning.
+func (h *history) disable(err error) {
+	fmt.Printf("Warning: history will not be saved: %v\n", err)
+	h.path = ""
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// lineReader reads REPL input one line at a time. On a terminal that
+// supports it, lines are edited in place and the arrow keys browse the
+// history; otherwise input is read as plain text.
+type lineReader struct {
+	in   *bufio.Reader
+	out  io.Writer
+	fd   int
+	hist *history
+	edit bool // raw-mode line editing is available
+
+	// complete, when set, returns the completions for the word ending at
+	// byte offset pos of line; Tab calls it.
+	complete func(line string, pos int) []string
+}
+
+func newLineReader(in *os.File, out io.Writer, hist *history) *lineReader {
+	lr := &lineReader{in: bufio.NewReader(in), out: out, fd: int(in.Fd()), hist: hist}
+	if restore, err := enableRawMode(lr.fd); err == nil {
+		restore()
+		lr.edit = true
+	}
+	return lr
+}
+
+// readLine prints prompt and returns the next line without its newline.
+// Non-blank lines are added to the history.
+func (lr *lineReader) readLine(prompt string) (string, error) {
+	line, err := lr.read(prompt)
+	if err != nil {
+		return "", err
+	}
+	lr.hist.add(line)
+	return line, nil
+}
+
+// read is readLine without recording the line in the history, for answers
+// to prompts such as the pager's.
+func (lr *lineReader) read(prompt string) (string, error) {
+	var line string
+	var err error
+	if lr.edit {
+		line, err = lr.editLine(prompt)
+	} else {
+		fmt.Fprint(lr.out, prompt)
+		line, err = lr.in.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		line = strings.TrimRight(line, "\r\n")
+	}
+	return line, err
+}
+
+// editLine reads one line in raw mode. Supported keys: printable input,
+// Backspace, Delete, Left/Right, Home/End (also Ctrl-A/Ctrl-E), Ctrl-U to
+// clear, Up/Down for history, Tab for completion, and Ctrl-D on an empty
+// line for end of input.
+func (lr *lineReader) editLine(prompt string) (string, error) {
+	restore, err := enableRawMode(lr.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	var buf []rune
+	pos := 0
+	histPos := len(lr.hist.lines)
+	var draft []rune // the line being typed while browsing history
+
+	refresh := func() {
+		fmt.Fprintf(lr.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if n := len(buf) - pos; n > 0 {
+			fmt.Fprintf(lr.out, "\x1b[%dD", n)
+		}
+	}
+	recall := func(i int) {
+		if histPos == len(lr.hist.lines) {
+			draft = buf
+		}
+		histPos = i
+		if i == len(lr.hist.lines) {
+			buf = draft
+		} else {
+			buf = []rune(lr.hist.lines[i])
+		}
+		pos = len(buf)
+	}
+
+	fmt.Fprint(lr.out, prompt)
+	for {
+		r, _, err := lr.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(lr.out, "\n")
+			return string(buf), nil
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(buf)
+		case 21: // Ctrl-U
+			buf, pos = nil, 0
+		case '\t':
+			buf, pos = lr.completeWord(buf, pos)
+		case 27: // escape sequence
+			if b, _ := lr.in.ReadByte(); b != '[' && b != 'O' {
+				continue
+			}
+			key, _ := lr.in.ReadByte()
+			switch key {
+			case 'A':
+				if histPos > 0 {
+					recall(histPos - 1)
+				}
+			case 'B':
+				if histPos < len(lr.hist.lines) {
+					recall(histPos + 1)
+				}
+			case 'C':
+				if pos < len(buf) {
+					pos++
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+				}
+			case 'H':
+				pos = 0
+			case 'F':
+				pos = len(buf)
+			case '3': // Delete: ESC [ 3 ~
+				if b, _ := lr.in.ReadByte(); b == '~' && pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if r < ' ' {
+				continue
+			}
+			buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+			pos++
+		}
+		refresh()
+	}
+}
+
+// completeWord completes the word before the cursor. A single match
+// replaces the word; several matches extend it to their longest common
+// prefix, or are listed below the line when that adds nothing.
+func (lr *lineReader) completeWord(buf []rune, pos int) ([]rune, int) {
+	if lr.complete == nil {
+		return buf, pos
+	}
+	line := string(buf)
+	bytePos := len(string(buf[:pos]))
+	matches := lr.complete(line, bytePos)
+	if len(matches) == 0 {
+		return buf, pos
+	}
+
+	start := utf8.RuneCountInString(line[:wordStart(line, bytePos)])
+	word := []rune(matches[0] + " ")
+	if len(matches) > 1 {
+		word = []rune(commonPrefix(matches))
+		if len(word) <= pos-start {
+			fmt.Fprintf(lr.out, "\n%s\n", strings.Join(matches, "  "))
+			return buf, pos
+		}
+	}
+
+	out := append(append(append([]rune{}, buf[:start]...), word...), buf[pos:]...)
+	return out, start + len(word)
+}
+
+// commonPrefix returns the longest prefix shared by all of words.
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// quoteRule turns the display text of a non-NULL value of type t into the
+// text an output mode writes, adding whatever quoting and escaping the
+// mode needs.
+type quoteRule func(text string, t sql.DataType) string
+
+// literalFormatter renders values for one output mode. Every mode goes
+// through a formatter, so a value is escaped the same way by .dump, .mode
+// insert, CSV and JSON output.
+type literalFormatter struct {
+	quote quoteRule
+	null  string // written for NULL as is, without quote
+	// exactFloats renders floats in their shortest form that reads back as
+	// the same value, always with a decimal point or exponent, instead of
+	// the fixed six decimals shown to people.
+	exactFloats bool
+}
+
+// listFormatter prints values raw, as the default list mode shows them.
+func listFormatter(nullValue string) literalFormatter {
+	return literalFormatter{quote: quoteRaw, null: nullValue}
+}
+
+// csvFormatter quotes every field that needs it following RFC 4180.
+func csvFormatter(nullValue string) literalFormatter {
+	return literalFormatter{quote: quoteCSV, null: csvField(nullValue)}
+}
+
+// sqlFormatter renders SQL literals that the parser reads back as the same
+// value, for .dump and .mode insert.
+var sqlFormatter = literalFormatter{quote: quoteSQL, null: "NULL", exactFloats: true}
+
+// jsonFormatter renders JSON values: numbers and booleans bare, everything
+// else as a string.
+var jsonFormatter = literalFormatter{quote: quoteJSON, null: "null", exactFloats: true}
+
+// format renders v.
+func (f literalFormatter) format(v sql.Value) string {
+	var text string
+	switch v.Type {
+	case sql.TypeInt:
+		text = strconv.FormatInt(v.I64, 10)
+	case sql.TypeFloat:
+		if !f.exactFloats {
+			text = strconv.FormatFloat(v.F64, 'f', 6, 64)
+			break
+		}
+		text = strconv.FormatFloat(v.F64, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eEnN") {
+			text += ".0"
+		}
+	case sql.TypeString:
+		text = v.S
+	case sql.TypeBool:
+		text = strconv.FormatBool(v.B)
+	case sql.TypeTimestamp:
+		text = sql.FormatTimestamp(v)
+	case sql.TypeBytes:
+		text = sql.FormatBlob(v)
+	default:
+		return f.null
+	}
+	return f.quote(text, v.Type)
+}
+
+func quoteRaw(text string, _ sql.DataType) string { return text }
+
+// quoteSQL single-quotes strings and timestamps, doubling embedded quotes.
+// BLOBs are already x'...' literals. NaN and infinite floats have no literal
+// form, so they become a CAST of their text, which the parser reads back.
+func quoteSQL(text string, t sql.DataType) string {
+	switch t {
+	case sql.TypeString, sql.TypeTimestamp:
+		return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+	case sql.TypeFloat:
+		if text == "NaN" || text == "+Inf" || text == "-Inf" {
+			return "CAST('" + text + "' AS FLOAT)"
+		}
+		return text
+	default:
+		return text
+	}
+}
+
+func quoteCSV(text string, _ sql.DataType) string { return csvField(text) }
+
+// csvField quotes a CSV field like encoding/csv does: when it contains a
+// comma, a double quote or a line break, or starts with a space or tab, it
+// is wrapped in double quotes with embedded quotes doubled.
+func csvField(s string) string {
+	if s == "" || !strings.ContainsAny(s, ",\"\r\n") && s[0] != ' ' && s[0] != '\t' {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// quoteJSON leaves numbers and booleans bare and quotes everything else.
+// Infinite and NaN floats have no JSON form and become null.
+func quoteJSON(text string, t sql.DataType) string {
+	switch t {
+	case sql.TypeInt, sql.TypeBool:
+		return text
+	case sql.TypeFloat:
+		if f, err := strconv.ParseFloat(text, 64); err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "null"
+		}
+		return text
+	default:
+		return jsonString(text)
+	}
+}
+
+// jsonString renders s as a JSON string. Unlike json.Marshal it leaves <, >
+// and & alone, since the output is not embedded in HTML.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // encoding a string cannot fail
+	return strings.TrimSuffix(buf.String(), "\n")
+}
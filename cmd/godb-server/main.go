@@ -1,252 +1,161 @@
 package main
 
 import (
-	"bufio"
-	"errors"
+	"flag"
 	"fmt"
+	"goDB/internal/storage"
 	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
 	"io"
 	"log"
+	"os"
 
 	"goDB/internal/engine"
-	"goDB/internal/sql"
-	"os"
-	"strings"
 )
 
 func main() {
-	fmt.Println("GoDB server starting (REPL mode)…")
-
-	// choose storage implementation
-	// mem := memstore.New()
-	// eng := engine.New(mem)
-
-	fs, err := filestore.New("./data")
-	if err != nil {
-		log.Fatalf("failed to init filestore: %v", err)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [script.sql]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Without a script, starts an interactive REPL when stdin is a terminal")
+		fmt.Fprintln(flag.CommandLine.Output(), "and otherwise executes the SQL read from stdin, exiting non-zero on the")
+		fmt.Fprintln(flag.CommandLine.Output(), "first error. With --listen or --http, serves SQL over the network instead.")
+		fmt.Fprintln(flag.CommandLine.Output())
+		flag.PrintDefaults()
 	}
-	eng := engine.New(fs)
-
-	if err := eng.Start(); err != nil {
-		log.Fatalf("engine start failed: %v", err)
+	listen := flag.String("listen", "", "serve the TCP line protocol on `addr` (e.g. :5480)")
+	httpAddr := flag.String("http", "", "serve the JSON query API on `addr` (e.g. :8080)")
+	engineName := flag.String("engine", "file", "storage engine: `file` (on-disk) or mem (in-memory, nothing is saved)")
+	dataDir := flag.String("data", "./data", "data `dir`ectory of the file engine")
+	readOnly := flag.Bool("read-only", false, "open the data directory for reading only; writes and DDL fail")
+	packBools := flag.Bool("pack-bools", false, "store the BOOL values of rows the file engine writes as bits; older versions cannot read such rows")
+	ckptInterval := flag.Duration("checkpoint-interval", 0, "checkpoint the file engine every `d` (e.g. 5m) when tables have changed; 0 only checkpoints on exit")
+	timeout := flag.Duration("timeout", 0, "with --listen or --http, cancel statements running longer than `d` (e.g. 5s)")
+	flag.Parse()
+
+	if *engineName != "file" && *engineName != "mem" {
+		fmt.Fprintf(os.Stderr, "unknown --engine %q (want file or mem)\n", *engineName)
+		os.Exit(2)
+	}
+	if *readOnly && *engineName != "file" {
+		fmt.Fprintln(os.Stderr, "--read-only needs --engine=file")
+		os.Exit(2)
 	}
 
-	fmt.Println("Engine started successfully (using on-disk filestore at ./data).")
-	fmt.Println("Type SQL statements like:")
-	fmt.Println("  CREATE TABLE users (id INT, name STRING, active BOOL);")
-	fmt.Println("  INSERT INTO users VALUES (1, 'Alice', true);")
-	fmt.Println("  SELECT * FROM users;")
-	fmt.Println("Meta commands:")
-	fmt.Println("  .tables        - list tables")
-	fmt.Println("  .schema <tbl>  - show column definitions")
-	fmt.Println("  .exit          - quit")
-	fmt.Println("  .help          - show this help")
-	fmt.Println()
-
-	runREPL(eng)
-}
-
-func runREPL(eng *engine.DBEngine) {
-	reader := bufio.NewReader(os.Stdin)
-	var buffer strings.Builder
-
-	for {
-		prompt := "godb> "
-		if buffer.Len() > 0 {
-			prompt = "...> "
-		}
-
-		fmt.Print(prompt)
-		line, err := reader.ReadString('\n')
+	var script io.Reader
+	switch {
+	case flag.NArg() > 1:
+		flag.Usage()
+		os.Exit(2)
+	case flag.NArg() == 1:
+		f, err := os.Open(flag.Arg(0))
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				fmt.Println("\nExiting.")
-				return
-			}
-
-			fmt.Println("Read error:", err)
-			return
-		}
-
-		line = strings.TrimSpace(line)
-
-		if buffer.Len() == 0 && line == "" {
-			continue
-		}
-
-		// Meta commands start with a dot, like SQLite. Only process them
-		// when no SQL is buffered to avoid mixing with multi-line input.
-		if buffer.Len() == 0 && strings.HasPrefix(line, ".") {
-			if handleMetaCommand(line, eng) {
-				return
-			}
-			continue
-		}
-
-		if line != "" {
-			if buffer.Len() > 0 {
-				buffer.WriteString(" ")
-			}
-			buffer.WriteString(line)
-		}
-
-		if strings.HasSuffix(line, ";") {
-			statement := buffer.String()
-			buffer.Reset()
-			handleSQL(statement, eng)
+			log.Fatalf("open script: %v", err)
 		}
+		defer f.Close()
+		script = f
+	case !stdinIsTerminal():
+		script = os.Stdin
 	}
-}
 
-// handleMetaCommand processes commands like .exit, .help.
-// Returns true if the REPL should exit.
-func handleMetaCommand(line string, eng *engine.DBEngine) bool {
-	trimmed := strings.TrimSpace(line)
-	parts := strings.Fields(trimmed)
-	if len(parts) == 0 {
-		return false
+	network := *listen != "" || *httpAddr != ""
+	if script == nil && !network {
+		fmt.Println("GoDB server starting (REPL mode)…")
 	}
 
-	switch strings.ToLower(parts[0]) {
-	case ".exit", ".quit":
-		fmt.Println("Bye.")
-		return true
-	case ".help":
-		fmt.Println("Supported SQL (current version):")
-		fmt.Println()
-		fmt.Println("  CREATE TABLE tableName (")
-		fmt.Println("      columnName TYPE, ...")
-		fmt.Println("  );")
-		fmt.Println("    - Supported types: INT, FLOAT, STRING, BOOL")
-		fmt.Println()
-		fmt.Println("  INSERT INTO tableName VALUES (value1, value2, ...);")
-		fmt.Println("    - Values must match table column order")
-		fmt.Println()
-		fmt.Println("  SELECT * FROM tableName;")
-		fmt.Println("  SELECT col1, col2, ... FROM tableName;")
-		fmt.Println("  SELECT col1, col2 FROM tableName WHERE column = literal;")
-		fmt.Println("    - WHERE: supports only equality (=)")
-		fmt.Println("    - WHERE literals: INT, FLOAT, STRING ('text'), BOOL")
-		fmt.Println()
-		fmt.Println("Meta commands:")
-		fmt.Println("  .tables        List available tables")
-		fmt.Println("  .schema <tbl>  Show column definitions")
-		fmt.Println("  .help          Show this help")
-		fmt.Println("  .exit          Exit the REPL")
-		fmt.Println()
-		return false
-	case ".tables":
-		names, err := eng.ListTables()
+	var store storage.Engine
+	var storeDesc string
+	// removeTemp deletes the index directory of the in-memory engine. The
+	// exits below run it by hand, since os.Exit skips deferred calls.
+	removeTemp := func() {}
+	switch *engineName {
+	case "mem":
+		mem, remove, err := newMemStore("godb-mem-")
 		if err != nil {
-			fmt.Println("Error listing tables:", err)
-			return false
-		}
-
-		if len(names) == 0 {
-			fmt.Println("(no tables)")
-			return false
+			log.Fatalf("failed to init memstore: %v", err)
 		}
-
-		fmt.Println(strings.Join(names, "\n"))
-		return false
-	case ".schema":
-		if len(parts) < 2 {
-			fmt.Println("Usage: .schema <table>")
-			return false
-		}
-
-		cols, err := eng.TableSchema(parts[1])
+		removeTemp = remove
+		defer removeTemp()
+		store = mem
+		storeDesc = "in-memory memstore; nothing is saved"
+	default:
+		fs, err := filestore.NewWithOptions(*dataDir, filestore.Options{
+			ReadOnly:           *readOnly,
+			CheckpointInterval: *ckptInterval,
+			PackBools:          *packBools,
+		})
 		if err != nil {
-			fmt.Println("Error loading schema:", err)
-			return false
+			log.Fatalf("failed to init filestore: %v", err)
 		}
-
-		if len(cols) == 0 {
-			fmt.Println("(no columns)")
-			return false
+		defer fs.Close()
+		store = fs
+		storeDesc = "on-disk filestore at " + *dataDir
+		if *readOnly {
+			storeDesc += ", read-only"
 		}
-
-		for _, col := range cols {
-			fmt.Printf("%s %s\n", col.Name, formatType(col.Type))
-		}
-		return false
-
-	default:
-		fmt.Printf("Unknown meta command: %s\n", trimmed)
 	}
-	return false
-}
 
-func handleSQL(line string, eng *engine.DBEngine) {
-	// Allow multi-line-ish usage by adding missing semicolon mentally, but for now
-	// we just pass the line as is; parser already handles optional trailing ';'.
-	stmt, err := sql.Parse(line)
-	if err != nil {
-		fmt.Println("Parse error:", err)
+	if network {
+		log.Printf("GoDB server starting (using %s)", storeDesc)
+		if err := serveNetwork(store, *listen, *httpAddr, *timeout); err != nil {
+			removeTemp()
+			log.Fatalf("serve: %v", err)
+		}
 		return
 	}
 
-	cols, rows, err := eng.Execute(stmt)
-	if err != nil {
-		fmt.Println("Execution error:", err)
-		return
-	}
+	eng := engine.New(store)
 
-	// If we got columns back, assume it's a SELECT and print a table.
-	if len(cols) > 0 {
-		printResultSet(cols, rows)
-	} else {
-		// For CREATE/INSERT we just say OK for now.
-		fmt.Println("OK")
+	if err := eng.Start(); err != nil {
+		removeTemp()
+		log.Fatalf("engine start failed: %v", err)
 	}
-}
 
-func printResultSet(cols []string, rows []sql.Row) {
-	// Header
-	fmt.Println(strings.Join(cols, " | "))
-
-	// Rows
-	for _, row := range rows {
-		var parts []string
-		for _, v := range row {
-			parts = append(parts, formatValue(v))
+	if script != nil {
+		if err := runScript(os.Stdout, eng, script); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			removeTemp()
+			os.Exit(1)
 		}
-		fmt.Println(strings.Join(parts, " | "))
+		return
 	}
-}
 
-// formatValue converts a sql.Value to a human-readable string.
-func formatValue(v sql.Value) string {
-	switch v.Type {
-	case sql.TypeInt:
-		return fmt.Sprintf("%d", v.I64)
-	case sql.TypeFloat:
-		return fmt.Sprintf("%f", v.F64)
-	case sql.TypeString:
-		return v.S
-	case sql.TypeBool:
-		if v.B {
-			return "true"
-		}
-		return "false"
-	case sql.TypeNull:
-		return "NULL"
-	default:
-		return "NULL"
-	}
+	fmt.Printf("Engine started successfully (using %s).\n", storeDesc)
+	fmt.Println("Type SQL statements like:")
+	fmt.Println("  CREATE TABLE users (id INT, name STRING, active BOOL);")
+	fmt.Println("  INSERT INTO users VALUES (1, 'Alice', true);")
+	fmt.Println("  SELECT * FROM users;")
+	fmt.Println("Meta commands:")
+	fmt.Println("  .tables        - list tables")
+	fmt.Println("  .schema [tbl]  - show column definitions (all tables as SQL)")
+	fmt.Println("  .describe <tbl> - show columns with constraints and indexes")
+	fmt.Println("  .stats [tbl]   - show table sizes")
+	fmt.Println("  .sample <tbl> [n] - show n random rows (default 10)")
+	fmt.Println("  .check         - verify tables and indexes")
+	fmt.Println("  .dump [tbl]    - print the database as SQL")
+	fmt.Println("  .clone mem     - switch to a throwaway in-memory copy")
+	fmt.Println("  .mode <mode>   - set output mode (list, csv, json, insert <table>)")
+	fmt.Println("  .output <file> - send results to a file (.output stdout to reset)")
+	fmt.Println("  .timer on|off  - report statement execution time")
+	fmt.Println("  .explain on|only|off - show query plans before (or instead of) running")
+	fmt.Println("  .pager on|off  - page long results")
+	fmt.Println("  .read <file>   - run the SQL statements in a file")
+	fmt.Println("  .nullvalue [t] - display NULL values as t")
+	fmt.Println("  .exit          - quit")
+	fmt.Println("  .help          - show this help")
+	fmt.Println()
+
+	r := newREPL(eng)
+	defer r.close()
+	r.run()
 }
 
-func formatType(t sql.DataType) string {
-	switch t {
-	case sql.TypeInt:
-		return "INT"
-	case sql.TypeFloat:
-		return "FLOAT"
-	case sql.TypeString:
-		return "STRING"
-	case sql.TypeBool:
-		return "BOOL"
-	default:
-		return "UNKNOWN"
+// newMemStore returns an in-memory engine whose index files go to a new
+// temporary directory named after prefix, so they never touch the data
+// directory, and a function that removes that directory.
+func newMemStore(prefix string) (storage.Engine, func(), error) {
+	dir, err := os.MkdirTemp("", prefix)
+	if err != nil {
+		return nil, nil, err
 	}
+	return memstore.NewWithDir(dir), func() { os.RemoveAll(dir) }, nil
 }
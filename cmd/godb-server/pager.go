@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"goDB/internal/sql"
+)
+
+// pagerLines is how many lines the built-in pager shows before asking
+// whether to continue.
+const pagerLines = 23
+
+// errPagerQuit stops printing once the user declines to see more.
+var errPagerQuit = errors.New("pager: quit")
+
+// printRows prints a result set to the REPL output. With .pager on and
+// results going to the terminal, the output goes through $PAGER when it is
+// set and through the built-in pager otherwise.
+func (r *repl) printRows(cols []string, rows []sql.Row) error {
+	print := func(w io.Writer) error {
+		return printResultSet(w, r.print, cols, rows)
+	}
+	if !r.pager || r.outFile != nil || r.in == nil {
+		return print(r.out)
+	}
+
+	if cmd := os.Getenv("PAGER"); cmd != "" {
+		return runPager(cmd, print)
+	}
+	err := print(&pageWriter{w: r.out, more: r.askMore})
+	if errors.Is(err, errPagerQuit) {
+		return nil
+	}
+	return err
+}
+
+// askMore asks whether to show the next page; anything but q continues.
+func (r *repl) askMore() bool {
+	answer, err := r.in.read("-- more? (Enter to continue, q to stop) -- ")
+	return err == nil && !strings.EqualFold(strings.TrimSpace(answer), "q")
+}
+
+// runPager pipes the output of print into the shell command cmdline. A
+// pager quitting before it has read everything is not an error.
+func runPager(cmdline string, print func(io.Writer) error) error {
+	cmd := exec.Command("sh", "-c", cmdline)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	printErr := print(in)
+	in.Close()
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+	if printErr != nil && !errors.Is(printErr, syscall.EPIPE) {
+		return printErr
+	}
+	return nil
+}
+
+// pageWriter passes writes through to w and calls more after every
+// pagerLines lines; once more returns false, writes fail with errPagerQuit.
+type pageWriter struct {
+	w     io.Writer
+	lines int
+	more  func() bool
+}
+
+func (p *pageWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		if p.lines >= pagerLines {
+			if !p.more() {
+				return written, errPagerQuit
+			}
+			p.lines = 0
+		}
+
+		chunk := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			chunk = b[:i+1]
+			p.lines++
+		}
+		n, err := p.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[len(chunk):]
+	}
+	return written, nil
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"goDB/internal/engine"
+	"goDB/internal/sql"
+)
+
+// Output modes understood by .mode.
+const (
+	modeList   = "list"
+	modeCSV    = "csv"
+	modeJSON   = "json"
+	modeInsert = "insert"
+)
+
+// repl holds the interactive shell state: the engine it talks to and the
+// settings changed by meta commands.
+type repl struct {
+	eng *engine.DBEngine
+
+	// out receives query results. It is os.Stdout unless redirected with
+	// .output, in which case outFile is the open file behind it.
+	out     io.Writer
+	outFile *os.File
+
+	print printOptions
+
+	// timer reports wall-clock execution time after each SQL statement.
+	timer bool
+
+	// explain prints the query plan of each SQL statement before running
+	// it; with explainOnly the statement is not run at all.
+	explain     bool
+	explainOnly bool
+
+	// pager pages results shown on the terminal, through $PAGER when it is
+	// set. in is the input the built-in pager reads its prompts from.
+	pager bool
+	in    *lineReader
+
+	// removeClone deletes the index directory of the in-memory copy made
+	// by .clone mem, if the REPL is using one.
+	removeClone func()
+}
+
+func newREPL(eng *engine.DBEngine) *repl {
+	return &repl{
+		eng:   eng,
+		out:   os.Stdout,
+		print: defaultPrintOptions(),
+	}
+}
+
+func (r *repl) run() {
+	reader := newLineReader(os.Stdin, os.Stdout, loadHistory(defaultHistoryPath()))
+	reader.complete = r.complete
+	r.in = reader
+	var buffer strings.Builder
+
+	for {
+		prompt := "godb> "
+		if buffer.Len() > 0 {
+			prompt = "...> "
+		}
+
+		line, err := reader.readLine(prompt)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Println("\nExiting.")
+				return
+			}
+
+			fmt.Println("Read error:", err)
+			return
+		}
+
+		line = strings.TrimSpace(line)
+
+		if buffer.Len() == 0 && line == "" {
+			continue
+		}
+
+		// Meta commands start with a dot, like SQLite. Only process them
+		// when no SQL is buffered to avoid mixing with multi-line input.
+		if buffer.Len() == 0 && strings.HasPrefix(line, ".") {
+			if r.handleMetaCommand(line) {
+				return
+			}
+			continue
+		}
+
+		if line != "" {
+			if buffer.Len() > 0 {
+				buffer.WriteString("\n")
+			}
+			buffer.WriteString(line)
+		}
+
+		// Only semicolons outside string literals end a statement, so a
+		// value like 'a;b' keeps buffering instead of being split.
+		statements, rest := sql.SplitStatements(buffer.String())
+		buffer.Reset()
+		buffer.WriteString(rest)
+		for _, statement := range statements {
+			r.handleSQL(statement)
+		}
+	}
+}
+
+// handleMetaCommand processes commands like .exit, .help.
+// Returns true if the REPL should exit.
+func (r *repl) handleMetaCommand(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	parts := strings.Fields(trimmed)
+	if len(parts) == 0 {
+		return false
+	}
+
+	switch strings.ToLower(parts[0]) {
+	case ".exit", ".quit":
+		fmt.Println("Bye.")
+		return true
+	case ".help":
+		fmt.Println("Supported SQL (current version):")
+		fmt.Println()
+		fmt.Println("  CREATE TABLE tableName (")
+		fmt.Println("      columnName TYPE, ...")
+		fmt.Println("  );")
+		fmt.Println("    - Supported types: INT, FLOAT, STRING, BOOL, TIMESTAMP, BLOB")
+		fmt.Println()
+		fmt.Println("  INSERT INTO tableName VALUES (value1, value2, ...);")
+		fmt.Println("    - Values must match table column order")
+		fmt.Println()
+		fmt.Println("  SELECT * FROM tableName;")
+		fmt.Println("  SELECT col1, col2, ... FROM tableName;")
+		fmt.Println("  SELECT col1, col2 FROM tableName WHERE column > literal AND column < literal;")
+		fmt.Println("    - WHERE: =, !=, <, <=, >, >= and IS [NOT] NULL/TRUE/FALSE, joined by AND")
+		fmt.Println("    - WHERE literals: INT, FLOAT, STRING ('text'), BOOL, TIMESTAMP ('2024-01-02 15:04:05'), BLOB (x'CAFE')")
+		fmt.Println()
+		fmt.Println("Meta commands:")
+		fmt.Println("  .tables        List available tables")
+		fmt.Println("  .schema [tbl]  Show column definitions of a table, or CREATE statements for all tables")
+		fmt.Println("  .describe <tbl> Show columns with constraints and indexes")
+		fmt.Println("  .stats [tbl]   Show row, page and dead-slot counts and file size")
+		fmt.Println("  .sample <tbl> [n] Show n random rows of a table (default 10)")
+		fmt.Println("  .check         Verify every table and index; prints ok or the problems found")
+		fmt.Println("  .dump [tbl]    Print CREATE TABLE/INSERT statements for all tables (or one)")
+		fmt.Println("  .clone mem     Switch to an in-memory copy of the database; changes are not saved")
+		fmt.Println("  .mode <mode>   Set result output mode: list (default), csv or json")
+		fmt.Println("  .mode insert <table>  Print results as INSERT statements for <table>")
+		fmt.Println("  .output <file> Write results to a file; .output stdout restores the terminal")
+		fmt.Println("  .timer on|off  Print execution time after each statement")
+		fmt.Println("  .explain on|only|off  Print the query plan before each statement, or instead of running it")
+		fmt.Println("  .pager on|off  Page long results through $PAGER or a built-in pager")
+		fmt.Println("  .read <file>   Execute the SQL statements in a file")
+		fmt.Println("  .nullvalue [t] Display NULL as t (empty when omitted; default NULL)")
+		fmt.Println("  .help          Show this help")
+		fmt.Println("  .exit          Exit the REPL")
+		fmt.Println()
+		return false
+	case ".tables":
+		names, err := r.eng.ListTables()
+		if err != nil {
+			fmt.Println("Error listing tables:", err)
+			return false
+		}
+
+		if len(names) == 0 {
+			fmt.Println("(no tables)")
+			return false
+		}
+
+		fmt.Println(strings.Join(names, "\n"))
+		return false
+	case ".schema":
+		if len(parts) < 2 {
+			if err := dumpSchema(r.out, r.eng); err != nil {
+				fmt.Println("Error loading schema:", err)
+			}
+			return false
+		}
+
+		cols, err := r.eng.TableSchema(parts[1])
+		if err != nil {
+			fmt.Println("Error loading schema:", err)
+			return false
+		}
+
+		if len(cols) == 0 {
+			fmt.Println("(no columns)")
+			return false
+		}
+
+		for _, col := range cols {
+			fmt.Printf("%s %s\n", col.Name, formatType(col.Type))
+		}
+		return false
+	case ".describe":
+		if len(parts) < 2 {
+			fmt.Println("Usage: .describe <table>")
+			return false
+		}
+		if err := describeTable(r.out, r.print, r.eng, parts[1]); err != nil {
+			fmt.Println("Error describing table:", err)
+		}
+		return false
+	case ".stats":
+		if err := printStats(r.out, r.eng, parts[1:]); err != nil {
+			fmt.Println("Error reading stats:", err)
+		}
+		return false
+	case ".check":
+		problems, err := r.eng.CheckIntegrity()
+		if err != nil {
+			fmt.Println("Error checking integrity:", err)
+			return false
+		}
+		if len(problems) == 0 {
+			fmt.Fprintln(r.out, "ok")
+			return false
+		}
+		for _, p := range problems {
+			fmt.Fprintln(r.out, p)
+		}
+		return false
+	case ".sample":
+		if len(parts) < 2 || len(parts) > 3 {
+			fmt.Println("Usage: .sample <table> [n]")
+			return false
+		}
+		n := 10
+		if len(parts) == 3 {
+			var err error
+			if n, err = strconv.Atoi(parts[2]); err != nil || n < 0 {
+				fmt.Println("Usage: .sample <table> [n]")
+				return false
+			}
+		}
+		res, err := r.eng.Sample(parts[1], n, uint64(time.Now().UnixNano()))
+		if err != nil {
+			fmt.Println("Error sampling table:", err)
+			return false
+		}
+		if err := r.printRows(res.Columns, res.Rows); err != nil {
+			fmt.Println("Output error:", err)
+		}
+		return false
+	case ".dump":
+		if err := dumpDatabase(r.out, r.eng, parts[1:]); err != nil {
+			fmt.Println("Error dumping database:", err)
+		}
+		return false
+	case ".clone":
+		if len(parts) != 2 || strings.ToLower(parts[1]) != "mem" {
+			fmt.Println("Usage: .clone mem")
+			return false
+		}
+		if err := r.cloneToMemory(); err != nil {
+			fmt.Println("Error cloning database:", err)
+		}
+		return false
+	case ".mode":
+		if len(parts) < 2 {
+			if r.print.mode == modeInsert {
+				fmt.Printf("Current mode: %s %s\n", r.print.mode, r.print.table)
+				return false
+			}
+			fmt.Printf("Current mode: %s\n", r.print.mode)
+			return false
+		}
+
+		switch m := strings.ToLower(parts[1]); m {
+		case modeList, modeCSV, modeJSON:
+			r.print.mode = m
+		case modeInsert:
+			if len(parts) < 3 {
+				fmt.Println("Usage: .mode insert <table>")
+				return false
+			}
+			r.print.mode = m
+			r.print.table = parts[2]
+		default:
+			fmt.Printf("Unknown mode %q (supported: list, csv, json, insert)\n", parts[1])
+		}
+		return false
+	case ".output":
+		target := "stdout"
+		if len(parts) >= 2 {
+			target = parts[1]
+		}
+		if err := r.setOutput(target); err != nil {
+			fmt.Println("Error opening output:", err)
+		}
+		return false
+	case ".read":
+		if len(parts) < 2 {
+			fmt.Println("Usage: .read <file>")
+			return false
+		}
+		if err := r.readFile(parts[1]); err != nil {
+			fmt.Println("Error reading file:", err)
+		}
+		return false
+	case ".nullvalue":
+		r.print.nullValue = unquoteSetting(strings.TrimSpace(trimmed[len(parts[0]):]))
+		return false
+	case ".timer":
+		if len(parts) < 2 {
+			fmt.Println("Usage: .timer on|off")
+			return false
+		}
+
+		switch strings.ToLower(parts[1]) {
+		case "on":
+			r.timer = true
+		case "off":
+			r.timer = false
+		default:
+			fmt.Println("Usage: .timer on|off")
+		}
+		return false
+	case ".explain":
+		if len(parts) < 2 {
+			fmt.Println("Usage: .explain on|only|off")
+			return false
+		}
+
+		switch strings.ToLower(parts[1]) {
+		case "on":
+			r.explain, r.explainOnly = true, false
+		case "only":
+			r.explain, r.explainOnly = true, true
+		case "off":
+			r.explain, r.explainOnly = false, false
+		default:
+			fmt.Println("Usage: .explain on|only|off")
+		}
+		return false
+	case ".pager":
+		if len(parts) < 2 {
+			fmt.Println("Usage: .pager on|off")
+			return false
+		}
+
+		switch strings.ToLower(parts[1]) {
+		case "on":
+			r.pager = true
+		case "off":
+			r.pager = false
+		default:
+			fmt.Println("Usage: .pager on|off")
+		}
+		return false
+
+	default:
+		fmt.Printf("Unknown meta command: %s\n", trimmed)
+	}
+	return false
+}
+
+// cloneToMemory copies the current database into a new memstore and makes
+// the copy the engine the REPL talks to. The original engine is left as it
+// is. Index files created in the copy go to a temporary directory, so they
+// cannot clash with those of the data directory; it is removed when the
+// REPL switches to another copy or exits.
+func (r *repl) cloneToMemory() error {
+	mem, remove, err := newMemStore("godb-clone-")
+	if err != nil {
+		return err
+	}
+	clone, err := r.eng.Clone(mem)
+	if err != nil {
+		remove()
+		return err
+	}
+	tables, err := clone.ListTables()
+	if err != nil {
+		remove()
+		return err
+	}
+	r.eng = clone
+	if r.removeClone != nil {
+		r.removeClone()
+	}
+	r.removeClone = remove
+	fmt.Printf("Switched to an in-memory copy of %d table(s); changes are not saved.\n", len(tables))
+	return nil
+}
+
+// unquoteSetting strips one pair of surrounding single quotes from a meta
+// command argument, so an empty setting can be written explicitly as two quotes.
+func unquoteSetting(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// setOutput redirects query results to the named file, or back to the
+// terminal when target is "stdout". Any previously opened file is closed.
+func (r *repl) setOutput(target string) error {
+	if strings.EqualFold(target, "stdout") {
+		r.closeOutput()
+		return nil
+	}
+
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+
+	r.closeOutput()
+	r.out = f
+	r.outFile = f
+	return nil
+}
+
+// close releases what the REPL holds when it exits: the file opened by
+// .output and the directory of a .clone copy.
+func (r *repl) close() {
+	r.closeOutput()
+	if r.removeClone != nil {
+		r.removeClone()
+		r.removeClone = nil
+	}
+}
+
+// closeOutput closes a file opened by .output and restores stdout.
+func (r *repl) closeOutput() {
+	if r.outFile != nil {
+		if err := r.outFile.Close(); err != nil {
+			fmt.Println("Error closing output:", err)
+		}
+		r.outFile = nil
+	}
+	r.out = os.Stdout
+}
+
+func (r *repl) handleSQL(line string) {
+	r.execSQL(line, "")
+}
+
+// execSQL parses and executes one statement, printing its result or error.
+// label prefixes any error message, e.g. to identify a statement in a script.
+func (r *repl) execSQL(line, label string) {
+	// Allow multi-line-ish usage by adding missing semicolon mentally, but for now
+	// we just pass the line as is; parser already handles optional trailing ';'.
+	stmt, err := sql.Parse(line)
+	if err != nil {
+		fmt.Printf("%sParse error: %v\n", label, err)
+		return
+	}
+
+	if r.explain {
+		r.printPlan(stmt, label)
+		if r.explainOnly {
+			return
+		}
+	}
+
+	start := time.Now()
+	res, err := r.eng.Exec(stmt)
+	elapsed := time.Since(start)
+	if r.timer {
+		defer fmt.Printf("Run Time: %s\n", elapsed)
+	}
+	if err != nil {
+		fmt.Printf("%sExecution error: %v\n", label, err)
+		return
+	}
+
+	// If we got columns back, assume it's a SELECT and print a table.
+	if len(res.Columns) > 0 {
+		if err := r.printRows(res.Columns, res.Rows); err != nil {
+			fmt.Printf("%sOutput error: %v\n", label, err)
+		}
+	} else {
+		fmt.Println(statusMessage(stmt, res.RowsAffected))
+	}
+}
+
+// printPlan prints the query plan of stmt for .explain, one step per line.
+// Statements that read no rows have none; they print nothing unless the
+// plan is all .explain only shows.
+func (r *repl) printPlan(stmt sql.Statement, label string) {
+	plan, err := r.eng.Explain(stmt)
+	if err != nil {
+		fmt.Printf("%sExplain error: %v\n", label, err)
+		return
+	}
+	if len(plan) == 0 {
+		if r.explainOnly {
+			fmt.Println("(no plan)")
+		}
+		return
+	}
+	fmt.Println("QUERY PLAN")
+	for _, step := range plan {
+		fmt.Println("  " + step)
+	}
+}
+
+// statusMessage reports how many rows an INSERT, UPDATE or DELETE affected,
+// e.g. "3 rows updated", and is "OK" for statements that change no rows.
+func statusMessage(stmt sql.Statement, affected int64) string {
+	var verb string
+	switch stmt.(type) {
+	case *sql.InsertStmt:
+		verb = "inserted"
+	case *sql.UpdateStmt:
+		verb = "updated"
+	case *sql.DeleteStmt:
+		verb = "deleted"
+	default:
+		return "OK"
+	}
+	if affected == 1 {
+		return "1 row " + verb
+	}
+	return fmt.Sprintf("%d rows %s", affected, verb)
+}
+
+// readFile executes every statement in a SQL file through the same path as
+// typed input. Errors are reported with the statement's position in the
+// file and do not stop the remaining statements.
+func (r *repl) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	statements, rest := sql.SplitStatements(string(data))
+	if rest != "" {
+		statements = append(statements, rest)
+	}
+	for i, statement := range statements {
+		r.execSQL(statement, fmt.Sprintf("Statement %d: ", i+1))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"goDB/internal/server"
+	"goDB/internal/storage"
+)
+
+// serveNetwork serves store over the TCP line protocol on tcpAddr and the
+// JSON API on httpAddr; an empty address disables that listener. It blocks
+// until one of the servers fails. A positive timeout limits how long each
+// statement may run.
+func serveNetwork(store storage.Engine, tcpAddr, httpAddr string, timeout time.Duration) error {
+	srv := server.New(store)
+	srv.Timeout = timeout
+	errc := make(chan error, 2)
+
+	if tcpAddr != "" {
+		ln, err := net.Listen("tcp", tcpAddr)
+		if err != nil {
+			return err
+		}
+		log.Printf("GoDB listening for TCP clients on %s", ln.Addr())
+		go func() { errc <- srv.ServeTCP(ln) }()
+	}
+
+	if httpAddr != "" {
+		ln, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			return err
+		}
+		log.Printf("GoDB serving HTTP on %s (POST /query)", ln.Addr())
+		go func() { errc <- http.Serve(ln, srv.HTTPHandler()) }()
+	}
+
+	return <-errc
+}
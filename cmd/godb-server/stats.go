@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"goDB/internal/engine"
+)
+
+// printStats writes an aligned table with the row, page and dead-slot counts
+// and the file size of each of the given tables, or of every table when none
+// are given.
+func printStats(w io.Writer, eng *engine.DBEngine, tables []string) error {
+	if len(tables) == 0 {
+		names, err := eng.ListTables()
+		if err != nil {
+			return fmt.Errorf("list tables: %w", err)
+		}
+		if len(names) == 0 {
+			_, err := fmt.Fprintln(w, "(no tables)")
+			return err
+		}
+		tables = names
+	}
+
+	lines := [][]string{{"table", "rows", "pages", "dead slots", "size"}}
+	for _, name := range tables {
+		st, err := eng.TableStats(name)
+		if err != nil {
+			return fmt.Errorf("table %q: %w", name, err)
+		}
+		lines = append(lines, []string{
+			name,
+			strconv.Itoa(st.Rows),
+			strconv.Itoa(st.Pages),
+			strconv.Itoa(st.DeadSlots),
+			formatSize(st.Bytes),
+		})
+	}
+
+	widths := make([]int, len(lines[0]))
+	for _, l := range lines {
+		for i, cell := range l {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	// The table name is left-aligned, the figures right-aligned.
+	for _, l := range lines {
+		out := fmt.Sprintf("%-*s", widths[0], l[0])
+		for i := 1; i < len(l); i++ {
+			out += fmt.Sprintf("  %*s", widths[i], l[i])
+		}
+		if _, err := fmt.Fprintln(w, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatSize renders a byte count with a binary unit, e.g. 12.0 KiB.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// enableRawMode is only implemented on Linux; elsewhere the REPL falls back
+// to plain line input without arrow-key history.
+func enableRawMode(fd int) (func(), error) {
+	return nil, errors.New("line editing is not supported on this platform")
+}
@@ -0,0 +1,317 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// aggregateFuncs are the functions computed over a group of rows rather
+// than a single row.
+var aggregateFuncs = map[string]bool{
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+}
+
+// isAggregateQuery reports whether a SELECT returns one row per group: it
+// has a GROUP BY clause or calls an aggregate function in its select list.
+func isAggregateQuery(s *sql.SelectStmt) bool {
+	if len(s.GroupBy) > 0 {
+		return true
+	}
+	for _, e := range s.Exprs {
+		if call, ok := e.(*sql.FuncCall); ok && aggregateFuncs[call.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// accumulator folds the values of one aggregate over a group.
+type accumulator interface {
+	add(v sql.Value) error
+	result() sql.Value
+}
+
+// aggregateItem is one entry of an aggregate select list: either a grouped
+// column (groupIdx >= 0) or an aggregate call.
+type aggregateItem struct {
+	groupIdx int
+	arg      evalFunc // nil for COUNT(*)
+	newAcc   func() accumulator
+}
+
+// groupState holds the first row of a group and its accumulators.
+type groupState struct {
+	first sql.Row
+	accs  []accumulator
+}
+
+// aggregate groups rows by the GROUP BY columns of s, in order of first
+// appearance, and evaluates the select list once per group. Without GROUP
+// BY all rows form a single group, which exists even when rows is empty.
+// Every select item must be a grouped column or an aggregate call.
+func aggregate(cols []string, rows []sql.Row, s *sql.SelectStmt) ([]string, []sql.Row, error) {
+	if len(s.Columns) == 0 {
+		return nil, nil, fmt.Errorf("SELECT * cannot be used with GROUP BY or aggregates")
+	}
+
+	groupIdx := make([]int, len(s.GroupBy))
+	for i, name := range s.GroupBy {
+		groupIdx[i] = columnIndex(cols, name)
+		if groupIdx[i] == -1 {
+			return nil, nil, fmt.Errorf("%w %q in GROUP BY", sql.ErrColumnNotFound, name)
+		}
+	}
+
+	items := make([]aggregateItem, len(s.Columns))
+	for i, name := range s.Columns {
+		var expr sql.Expr = &sql.ColumnRef{Name: name}
+		if s.Exprs != nil {
+			expr = s.Exprs[i]
+		}
+		item, err := compileAggregateItem(expr, name, cols, s.GroupBy)
+		if err != nil {
+			return nil, nil, fmt.Errorf("SELECT list: %w", err)
+		}
+		items[i] = item
+	}
+
+	var order []string
+	groups := make(map[string]*groupState)
+	for _, r := range rows {
+		key := groupKey(r, groupIdx)
+		g, ok := groups[key]
+		if !ok {
+			g = newGroupState(r, items)
+			groups[key] = g
+			order = append(order, key)
+		}
+		for i, item := range items {
+			if item.newAcc == nil {
+				continue
+			}
+			v := sql.Value{Type: sql.TypeInt} // COUNT(*) counts every row
+			if item.arg != nil {
+				var err error
+				if v, err = item.arg(r); err != nil {
+					return nil, nil, err
+				}
+			}
+			if err := g.accs[i].add(v); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if len(s.GroupBy) == 0 && len(order) == 0 {
+		groups[""] = newGroupState(nil, items)
+		order = append(order, "")
+	}
+
+	outCols := make([]string, len(s.Columns))
+	copy(outCols, s.Columns)
+	outRows := make([]sql.Row, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		out := make(sql.Row, len(items))
+		for i, item := range items {
+			if item.newAcc != nil {
+				out[i] = g.accs[i].result()
+			} else {
+				out[i] = g.first[item.groupIdx]
+			}
+		}
+		outRows = append(outRows, out)
+	}
+	return outCols, outRows, nil
+}
+
+func newGroupState(first sql.Row, items []aggregateItem) *groupState {
+	g := &groupState{first: first, accs: make([]accumulator, len(items))}
+	for i, item := range items {
+		if item.newAcc != nil {
+			g.accs[i] = item.newAcc()
+		}
+	}
+	return g
+}
+
+// compileAggregateItem resolves one select item of an aggregate query.
+func compileAggregateItem(expr sql.Expr, name string, cols, groupBy []string) (aggregateItem, error) {
+	switch e := expr.(type) {
+	case *sql.ColumnRef:
+		for _, g := range groupBy {
+			if strings.EqualFold(g, e.Name) {
+				return aggregateItem{groupIdx: columnIndex(cols, e.Name)}, nil
+			}
+		}
+		return aggregateItem{}, fmt.Errorf("column %q must appear in GROUP BY or be used in an aggregate", e.Name)
+
+	case *sql.FuncCall:
+		if !aggregateFuncs[e.Name] {
+			break
+		}
+		item := aggregateItem{groupIdx: -1}
+		if !e.Star {
+			if len(e.Args) != 1 {
+				return aggregateItem{}, fmt.Errorf("%s: wrong number of arguments (%d)", e.Name, len(e.Args))
+			}
+			arg, err := compileExpr(e.Args[0], cols)
+			if err != nil {
+				return aggregateItem{}, err
+			}
+			item.arg = arg
+		}
+
+		switch e.Name {
+		case "COUNT":
+			item.newAcc = func() accumulator { return &countAcc{} }
+		case "SUM":
+			item.newAcc = func() accumulator { return &sumAcc{} }
+		case "AVG":
+			item.newAcc = func() accumulator { return &avgAcc{} }
+		case "MIN":
+			item.newAcc = func() accumulator { return &minMaxAcc{name: "MIN", want: -1} }
+		case "MAX":
+			item.newAcc = func() accumulator { return &minMaxAcc{name: "MAX", want: 1} }
+		}
+		return item, nil
+	}
+	return aggregateItem{}, fmt.Errorf("%s must be a GROUP BY column or an aggregate", name)
+}
+
+// columnIndex returns the position of name in cols, ignoring case, or -1.
+func columnIndex(cols []string, name string) int {
+	for i, c := range cols {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// groupKey encodes the grouped values of a row. Values are tagged with
+// their type and quoted, so distinct tuples never share a key.
+func groupKey(row sql.Row, idxs []int) string {
+	var b strings.Builder
+	for _, i := range idxs {
+		v := row[i]
+		b.WriteString(strconv.Itoa(int(v.Type)))
+		if v.Type != sql.TypeNull {
+			b.WriteString(strconv.Quote(displayString(v)))
+		}
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// countAcc implements COUNT: the number of non-NULL values. COUNT(*) feeds
+// it a non-NULL value per row.
+type countAcc struct{ n int64 }
+
+func (a *countAcc) add(v sql.Value) error {
+	if v.Type != sql.TypeNull {
+		a.n++
+	}
+	return nil
+}
+
+func (a *countAcc) result() sql.Value { return sql.Value{Type: sql.TypeInt, I64: a.n} }
+
+// sumAcc implements SUM. It stays an integer until a FLOAT is added, and
+// is NULL when there were no non-NULL values.
+type sumAcc struct {
+	seen    bool
+	isFloat bool
+	i       int64
+	f       float64
+}
+
+func (a *sumAcc) add(v sql.Value) error {
+	switch v.Type {
+	case sql.TypeNull:
+		return nil
+	case sql.TypeInt:
+		a.i += v.I64
+	case sql.TypeFloat:
+		a.isFloat = true
+		a.f += v.F64
+	default:
+		return fmt.Errorf("SUM: %w: cannot add a %s value", sql.ErrTypeMismatch, v.Type)
+	}
+	a.seen = true
+	return nil
+}
+
+func (a *sumAcc) result() sql.Value {
+	switch {
+	case !a.seen:
+		return sql.Value{Type: sql.TypeNull}
+	case a.isFloat:
+		return sql.Value{Type: sql.TypeFloat, F64: a.f + float64(a.i)}
+	default:
+		return sql.Value{Type: sql.TypeInt, I64: a.i}
+	}
+}
+
+// avgAcc implements AVG, which is always a FLOAT, or NULL for no values.
+type avgAcc struct {
+	n   int
+	sum float64
+}
+
+func (a *avgAcc) add(v sql.Value) error {
+	switch v.Type {
+	case sql.TypeNull:
+		return nil
+	case sql.TypeInt:
+		a.sum += float64(v.I64)
+	case sql.TypeFloat:
+		a.sum += v.F64
+	default:
+		return fmt.Errorf("AVG: %w: cannot average a %s value", sql.ErrTypeMismatch, v.Type)
+	}
+	a.n++
+	return nil
+}
+
+func (a *avgAcc) result() sql.Value {
+	if a.n == 0 {
+		return sql.Value{Type: sql.TypeNull}
+	}
+	return sql.Value{Type: sql.TypeFloat, F64: a.sum / float64(a.n)}
+}
+
+// minMaxAcc implements MIN (want -1) and MAX (want 1), ignoring NULLs.
+type minMaxAcc struct {
+	name string
+	want int
+	best sql.Value
+	set  bool
+}
+
+func (a *minMaxAcc) add(v sql.Value) error {
+	if v.Type == sql.TypeNull {
+		return nil
+	}
+	if !a.set {
+		a.best, a.set = v, true
+		return nil
+	}
+	cmp, err := compareValues(v, a.best)
+	if err != nil {
+		return fmt.Errorf("%s: %w", a.name, err)
+	}
+	if cmp == a.want {
+		a.best = v
+	}
+	return nil
+}
+
+func (a *minMaxAcc) result() sql.Value {
+	if !a.set {
+		return sql.Value{Type: sql.TypeNull}
+	}
+	return a.best
+}
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func newSalesEngine(t *testing.T) *DBEngine {
+	t.Helper()
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE sales (region STRING, year INT, amount INT, price FLOAT);")
+	mustExec(t, eng, `INSERT INTO sales VALUES
+		('north', 2023, 10, 1.5), ('south', 2023, 5, 2.0), ('north', 2024, 7, NULL),
+		('north', 2023, 3, 0.5), ('south', 2024, NULL, 4.0), (NULL, 2024, 1, 1.0);`)
+	return eng
+}
+
+func TestEngineExecute_GroupByMultipleColumns(t *testing.T) {
+	eng := newSalesEngine(t)
+
+	res := mustExec(t, eng, "SELECT region, year, COUNT(*), SUM(amount), MAX(price) FROM sales GROUP BY region, year ORDER BY year;")
+	wantCols := []string{"region", "year", "COUNT(*)", "SUM(amount)", "MAX(price)"}
+	for i, c := range wantCols {
+		if res.Columns[i] != c {
+			t.Fatalf("columns = %v, want %v", res.Columns, wantCols)
+		}
+	}
+
+	null := sql.Value{Type: sql.TypeNull}
+	str := func(s string) sql.Value { return sql.Value{Type: sql.TypeString, S: s} }
+	num := func(n int64) sql.Value { return sql.Value{Type: sql.TypeInt, I64: n} }
+	flt := func(f float64) sql.Value { return sql.Value{Type: sql.TypeFloat, F64: f} }
+	want := []sql.Row{
+		{str("north"), num(2023), num(2), num(13), flt(1.5)},
+		{str("south"), num(2023), num(1), num(5), flt(2.0)},
+		{str("north"), num(2024), num(1), num(7), null},
+		{str("south"), num(2024), num(1), null, flt(4.0)},
+		{null, num(2024), num(1), num(1), flt(1.0)},
+	}
+	if len(res.Rows) != len(want) {
+		t.Fatalf("got %d groups, want %d: %v", len(res.Rows), len(want), res.Rows)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if res.Rows[i][j] != want[i][j] {
+				t.Fatalf("row %d: got %v, want %v", i, res.Rows[i], want[i])
+			}
+		}
+	}
+}
+
+func TestEngineExecute_Aggregates(t *testing.T) {
+	eng := newSalesEngine(t)
+
+	res := mustExec(t, eng, "SELECT COUNT(*), COUNT(amount), SUM(price), AVG(amount), MIN(region), MAX(year) FROM sales;")
+	row := res.Rows[0]
+	if len(res.Rows) != 1 || row[0].I64 != 6 || row[1].I64 != 5 || row[2].F64 != 9.0 ||
+		row[3].F64 != 26.0/5 || row[4].S != "north" || row[5].I64 != 2024 {
+		t.Fatalf("unexpected aggregates: %v", res.Rows)
+	}
+
+	res = mustExec(t, eng, "SELECT COUNT(*), SUM(amount) FROM sales WHERE year > 2030;")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 0 || res.Rows[0][1].Type != sql.TypeNull {
+		t.Fatalf("aggregates over no rows = %v, want [0 NULL]", res.Rows)
+	}
+
+	res = mustExec(t, eng, "SELECT year FROM sales WHERE year > 2030 GROUP BY year;")
+	if len(res.Rows) != 0 {
+		t.Fatalf("GROUP BY over no rows returned %v", res.Rows)
+	}
+
+	res = mustExec(t, eng, "SELECT region, COUNT(*) FROM sales WHERE amount > 1 GROUP BY region ORDER BY region DESC LIMIT 1;")
+	if len(res.Rows) != 1 || res.Rows[0][0].S != "south" || res.Rows[0][1].I64 != 1 {
+		t.Fatalf("unexpected ordered groups: %v", res.Rows)
+	}
+
+	for _, bad := range []string{
+		"SELECT region, amount FROM sales GROUP BY region;",
+		"SELECT region, COUNT(*) FROM sales;",
+		"SELECT * FROM sales GROUP BY region;",
+		"SELECT UPPER(region) FROM sales GROUP BY region;",
+		"SELECT region FROM sales GROUP BY missing;",
+		"SELECT SUM(region) FROM sales;",
+		"SELECT COUNT(amount, price) FROM sales;",
+		"SELECT id FROM sales WHERE COUNT(amount) > 1;",
+	} {
+		stmt, err := sql.Parse(bad)
+		if err != nil {
+			continue
+		}
+		if _, err := eng.Exec(stmt); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestEngineExecute_CountStar(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for name, eng := range map[string]*DBEngine{"memstore": New(memstore.New()), "filestore": New(fs)} {
+		t.Run(name, func(t *testing.T) {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE t (id INT);")
+
+			count := func(query string) int64 {
+				t.Helper()
+				res := mustExec(t, eng, query)
+				if len(res.Rows) != 1 || len(res.Columns) != 1 {
+					t.Fatalf("%s returned %v %v", query, res.Columns, res.Rows)
+				}
+				return res.Rows[0][0].I64
+			}
+			if n := count("SELECT COUNT(*) FROM t;"); n != 0 {
+				t.Fatalf("COUNT(*) of an empty table = %d", n)
+			}
+
+			mustExec(t, eng, "INSERT INTO t VALUES (1), (2), (3), (NULL);")
+			mustExec(t, eng, "DELETE FROM t WHERE id = 2;")
+			if n := count("SELECT COUNT(*) FROM t;"); n != 3 {
+				t.Fatalf("COUNT(*) = %d, want 3", n)
+			}
+			if n := count("SELECT COUNT(id) FROM t;"); n != 2 {
+				t.Fatalf("COUNT(id) = %d, want 2", n)
+			}
+			if n := count("SELECT COUNT(*) FROM t WHERE id > 1;"); n != 1 {
+				t.Fatalf("COUNT(*) with WHERE = %d, want 1", n)
+			}
+
+			mustExec(t, eng, "BEGIN;")
+			mustExec(t, eng, "INSERT INTO t VALUES (4);")
+			if n := count("SELECT COUNT(*) FROM t;"); n != 4 {
+				t.Fatalf("COUNT(*) inside a transaction = %d, want 4", n)
+			}
+			mustExec(t, eng, "ROLLBACK;")
+			if n := count("SELECT COUNT(*) FROM t;"); n != 3 {
+				t.Fatalf("COUNT(*) after ROLLBACK = %d, want 3", n)
+			}
+
+			if res := mustExec(t, eng, "SELECT COUNT(*) FROM t LIMIT 0;"); len(res.Rows) != 0 {
+				t.Fatalf("LIMIT 0 returned %v", res.Rows)
+			}
+			if err := execErr(t, eng, "SELECT COUNT(*) FROM nope;"); err == nil {
+				t.Fatalf("expected an error for an unknown table")
+			}
+		})
+	}
+}
+
+func TestEngineExecute_OrderByAlias(t *testing.T) {
+	eng := newSalesEngine(t)
+
+	res := mustExec(t, eng, "SELECT region, COUNT(*) AS c FROM sales GROUP BY region ORDER BY c DESC;")
+	if res.Columns[1] != "c" {
+		t.Fatalf("columns = %v, want the alias c", res.Columns)
+	}
+	var counts []int64
+	for _, r := range res.Rows {
+		counts = append(counts, r[1].I64)
+	}
+	if !reflect.DeepEqual(counts, []int64{3, 2, 1}) {
+		t.Fatalf("counts = %v, want [3 2 1]", counts)
+	}
+
+	res = mustExec(t, eng, "SELECT year, amount * 2 AS doubled FROM sales ORDER BY doubled DESC LIMIT 2;")
+	if len(res.Rows) != 2 || res.Rows[0][1].I64 != 20 || res.Rows[1][1].I64 != 14 {
+		t.Fatalf("rows = %v, want doubled 20 then 14", res.Rows)
+	}
+	if res.ColumnTypes[1].Type != sql.TypeInt {
+		t.Fatalf("unexpected column types: %+v", res.ColumnTypes)
+	}
+
+	// A column renamed with AS sorts under its new name; ORDER BY a table
+	// column that is not selected still works.
+	res = mustExec(t, eng, "SELECT amount AS a FROM sales WHERE amount > 2 ORDER BY a;")
+	if len(res.Rows) != 4 || res.Rows[0][0].I64 != 3 || res.Rows[3][0].I64 != 10 {
+		t.Fatalf("ORDER BY a: got %v", res.Rows)
+	}
+	res = mustExec(t, eng, "SELECT region FROM sales WHERE amount > 2 ORDER BY amount;")
+	if len(res.Rows) != 4 || res.Rows[0][0].S != "north" || res.Rows[1][0].S != "south" {
+		t.Fatalf("ORDER BY amount: got %v", res.Rows)
+	}
+}
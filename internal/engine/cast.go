@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// castValue converts v to type t for CAST(expr AS t):
+//
+//   - NULL casts to NULL of any type.
+//   - INT -> FLOAT is exact; FLOAT -> INT truncates toward zero and fails
+//     for NaN, infinities and values outside the INT range.
+//   - Any value -> STRING uses its display form (42, 1.5, true), except
+//     that a BLOB's bytes are taken as text. STRING -> BLOB is the reverse.
+//   - STRING -> INT/FLOAT parses the trimmed text as a number literal, and
+//     STRING -> BOOL accepts true/false/1/0 in any case.
+//   - BOOL -> INT gives 1 or 0; INT -> BOOL is true for any non-zero value.
+//   - STRING -> TIMESTAMP parses "2006-01-02 15:04:05" (see sql.ParseTimestamp).
+//
+// BOOL and FLOAT do not convert into each other.
+func castValue(v sql.Value, t sql.DataType) (sql.Value, error) {
+	if v.Type == sql.TypeNull {
+		return sql.Value{Type: sql.TypeNull}, nil
+	}
+	if v.Type == t {
+		return v, nil
+	}
+
+	switch t {
+	case sql.TypeString:
+		if v.Type == sql.TypeBytes {
+			return sql.Value{Type: sql.TypeString, S: v.S}, nil
+		}
+		return sql.Value{Type: sql.TypeString, S: displayString(v)}, nil
+
+	case sql.TypeInt:
+		switch v.Type {
+		case sql.TypeFloat:
+			f := math.Trunc(v.F64)
+			if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return sql.Value{}, fmt.Errorf("cannot cast %v to INT: out of range", v.F64)
+			}
+			return sql.Value{Type: sql.TypeInt, I64: int64(f)}, nil
+		case sql.TypeBool:
+			var i int64
+			if v.B {
+				i = 1
+			}
+			return sql.Value{Type: sql.TypeInt, I64: i}, nil
+		case sql.TypeString:
+			i, err := strconv.ParseInt(strings.TrimSpace(v.S), 10, 64)
+			if err != nil {
+				return sql.Value{}, fmt.Errorf("cannot cast %q to INT", v.S)
+			}
+			return sql.Value{Type: sql.TypeInt, I64: i}, nil
+		}
+
+	case sql.TypeFloat:
+		switch v.Type {
+		case sql.TypeInt:
+			return sql.Value{Type: sql.TypeFloat, F64: float64(v.I64)}, nil
+		case sql.TypeString:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v.S), 64)
+			if err != nil {
+				return sql.Value{}, fmt.Errorf("cannot cast %q to FLOAT", v.S)
+			}
+			return sql.Value{Type: sql.TypeFloat, F64: f}, nil
+		}
+
+	case sql.TypeBool:
+		switch v.Type {
+		case sql.TypeInt:
+			return sql.Value{Type: sql.TypeBool, B: v.I64 != 0}, nil
+		case sql.TypeString:
+			switch strings.ToLower(strings.TrimSpace(v.S)) {
+			case "true", "1":
+				return sql.Value{Type: sql.TypeBool, B: true}, nil
+			case "false", "0":
+				return sql.Value{Type: sql.TypeBool, B: false}, nil
+			}
+			return sql.Value{}, fmt.Errorf("cannot cast %q to BOOL", v.S)
+		}
+
+	case sql.TypeTimestamp:
+		if v.Type == sql.TypeString {
+			return sql.ParseTimestamp(v.S)
+		}
+
+	case sql.TypeBytes:
+		if v.Type == sql.TypeString {
+			return sql.Value{Type: sql.TypeBytes, S: v.S}, nil
+		}
+	}
+	return sql.Value{}, fmt.Errorf("%w: cannot cast %s to %s", sql.ErrTypeMismatch, v.Type, t)
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"goDB/internal/sql"
+)
+
+func TestCastValue(t *testing.T) {
+	i := func(n int64) sql.Value { return sql.Value{Type: sql.TypeInt, I64: n} }
+	f := func(x float64) sql.Value { return sql.Value{Type: sql.TypeFloat, F64: x} }
+	s := func(x string) sql.Value { return sql.Value{Type: sql.TypeString, S: x} }
+	b := func(x bool) sql.Value { return sql.Value{Type: sql.TypeBool, B: x} }
+	null := sql.Value{Type: sql.TypeNull}
+
+	ok := []struct {
+		in   sql.Value
+		to   sql.DataType
+		want sql.Value
+	}{
+		{i(3), sql.TypeFloat, f(3)},
+		{f(-2.7), sql.TypeInt, i(-2)},
+		{f(9.99), sql.TypeInt, i(9)},
+		{i(42), sql.TypeString, s("42")},
+		{f(1.5), sql.TypeString, s("1.5")},
+		{b(true), sql.TypeString, s("true")},
+		{s(" 12 "), sql.TypeInt, i(12)},
+		{s("2.5"), sql.TypeFloat, f(2.5)},
+		{s("TRUE"), sql.TypeBool, b(true)},
+		{s("0"), sql.TypeBool, b(false)},
+		{b(true), sql.TypeInt, i(1)},
+		{i(0), sql.TypeBool, b(false)},
+		{i(-5), sql.TypeBool, b(true)},
+		{null, sql.TypeInt, null},
+		{s("x"), sql.TypeString, s("x")},
+	}
+	for _, c := range ok {
+		got, err := castValue(c.in, c.to)
+		if err != nil {
+			t.Fatalf("cast %+v to %s failed: %v", c.in, c.to, err)
+		}
+		if got != c.want {
+			t.Fatalf("cast %+v to %s = %+v, want %+v", c.in, c.to, got, c.want)
+		}
+	}
+
+	bad := []struct {
+		in sql.Value
+		to sql.DataType
+	}{
+		{s("abc"), sql.TypeInt},
+		{s("1.5"), sql.TypeInt},
+		{s("abc"), sql.TypeFloat},
+		{s("yes"), sql.TypeBool},
+		{b(true), sql.TypeFloat},
+		{f(1), sql.TypeBool},
+		{f(math.NaN()), sql.TypeInt},
+		{f(1e19), sql.TypeInt},
+	}
+	for _, c := range bad {
+		if _, err := castValue(c.in, c.to); err == nil {
+			t.Fatalf("cast %+v to %s: expected error", c.in, c.to)
+		}
+	}
+}
+
+func TestEngineExecute_Cast(t *testing.T) {
+	eng := newUsersEngine(t)
+
+	res := mustExec(t, eng, "SELECT CAST(id AS STRING), CAST(score AS INT) FROM users;")
+	if res.Columns[0] != "CAST(id AS STRING)" {
+		t.Fatalf("unexpected column name %q", res.Columns[0])
+	}
+	if res.Rows[0][0] != (sql.Value{Type: sql.TypeString, S: "1"}) || res.Rows[0][1].I64 != 1 {
+		t.Fatalf("unexpected row: %v", res.Rows[0])
+	}
+
+	res = mustExec(t, eng, "SELECT id FROM users WHERE CAST(score AS INT) = 2;")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 2 {
+		t.Fatalf("unexpected WHERE CAST result: %v", res.Rows)
+	}
+
+	stmt, _ := sql.Parse("SELECT CAST(first AS INT) FROM users;")
+	if _, err := eng.Exec(stmt); err == nil {
+		t.Fatalf("expected error casting a name to INT")
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"fmt"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// Clone copies every table of the engine, schema and rows, into dst, which
+// should be empty, and returns a started engine over the copy. The tables
+// are read in one read-only transaction, so the copy is consistent; Clone
+// fails inside an explicit transaction. Indexes and sequences are not copied: memstore indexes live
+// in files, which a scratch copy should not create.
+//
+// Cloning a filestore database into a memstore gives a scratch copy that
+// queries can change without touching disk.
+func (e *DBEngine) Clone(dst storage.Engine) (*DBEngine, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	if e.inTx {
+		return nil, fmt.Errorf("cannot clone inside a transaction")
+	}
+
+	names, err := e.store.ListTables()
+	if err != nil {
+		return nil, fmt.Errorf("clone: list tables: %w", err)
+	}
+	schemas := make(map[string][]sql.Column, len(names))
+	data := make(map[string][]sql.Row, len(names))
+	read := func(tx storage.Tx) error {
+		for _, name := range names {
+			cols, err := e.store.TableSchema(name)
+			if err != nil {
+				return fmt.Errorf("clone: schema of %q: %w", name, err)
+			}
+			_, rows, err := tx.Scan(name)
+			if err != nil {
+				return fmt.Errorf("clone: scan %q: %w", name, err)
+			}
+			schemas[name] = cols
+			data[name] = rows
+		}
+		return nil
+	}
+	if err := e.inReadTx(read); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if err := dst.CreateTable(name, schemas[name]); err != nil {
+			return nil, fmt.Errorf("clone: create %q: %w", name, err)
+		}
+	}
+
+	tx, err := dst.Begin(false)
+	if err != nil {
+		return nil, fmt.Errorf("clone: begin tx: %w", err)
+	}
+	for _, name := range names {
+		if len(data[name]) == 0 {
+			continue
+		}
+		if err := tx.InsertBatch(name, data[name]); err != nil {
+			_ = dst.Rollback(tx)
+			return nil, fmt.Errorf("clone: copy rows of %q: %w", name, err)
+		}
+	}
+	if err := dst.Commit(tx); err != nil {
+		return nil, fmt.Errorf("clone: commit: %w", err)
+	}
+
+	clone := New(dst)
+	clone.now = e.now
+	if err := clone.Start(); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"testing"
+
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngine_Clone(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	defer fs.Close()
+	src := New(fs)
+	if err := src.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, src, "CREATE TABLE users (id INT, name STRING);")
+	mustExec(t, src, "CREATE TABLE orders (id INT, user_id INT REFERENCES users(id));")
+	mustExec(t, src, "CREATE TABLE empty (id INT);")
+	mustExec(t, src, "CREATE INDEX idx_users_id ON users (id);")
+	mustExec(t, src, "INSERT INTO users VALUES (1, 'a'), (2, 'b');")
+	mustExec(t, src, "INSERT INTO orders VALUES (10, 1);")
+
+	clone, err := src.Clone(memstore.New())
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+
+	tables, err := clone.ListTables()
+	if err != nil || len(tables) != 3 {
+		t.Fatalf("clone tables = %v, err %v", tables, err)
+	}
+	cols, err := clone.TableSchema("orders")
+	if err != nil || len(cols) != 2 || cols[1].References == nil || cols[1].References.Table != "users" {
+		t.Fatalf("clone schema of orders = %+v, err %v", cols, err)
+	}
+	if idx, _ := clone.IndexedColumns("users"); len(idx) != 0 {
+		t.Fatalf("clone indexes of users = %v, want none", idx)
+	}
+	rows := mustExec(t, clone, "SELECT * FROM users;").Rows
+	if len(rows) != 2 || rows[1][1].S != "b" {
+		t.Fatalf("clone rows = %v", rows)
+	}
+
+	// Writes to the clone stay in memory.
+	mustExec(t, clone, "DELETE FROM orders WHERE id = 10;")
+	mustExec(t, clone, "DELETE FROM users WHERE id = 1;")
+	if n := len(mustExec(t, src, "SELECT * FROM users;").Rows); n != 2 {
+		t.Fatalf("source lost rows after writing to the clone: %d", n)
+	}
+	if n := len(mustExec(t, src, "SELECT * FROM orders;").Rows); n != 1 {
+		t.Fatalf("source orders = %d rows, want 1", n)
+	}
+}
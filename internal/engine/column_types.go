@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// selectColumnTypes returns the types of the columns cols that s returned
+// as rows. Types follow from the table schema and the select list; a column
+// whose type cannot be told from them, such as a NULL literal, takes the
+// type of its first non-NULL value.
+func (e *DBEngine) selectColumnTypes(s *sql.SelectStmt, cols []string, rows []sql.Row) ([]ColumnType, error) {
+	schema, err := e.store.TableSchema(s.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	types := make([]ColumnType, len(cols))
+	for i, name := range cols {
+		types[i].Name = name
+		switch {
+		case len(s.Columns) == 0:
+			types[i].Type = columnType(schema, name)
+		case s.Exprs == nil:
+			types[i].Type = columnType(schema, s.Columns[i])
+		default:
+			types[i].Type = exprType(schema, s.Exprs[i])
+		}
+		if types[i].Type != sql.TypeNull {
+			continue
+		}
+		for _, r := range rows {
+			if r[i].Type != sql.TypeNull {
+				types[i].Type = r[i].Type
+				break
+			}
+		}
+	}
+	return types, nil
+}
+
+// columnType returns the type of the named column of schema, INT for the
+// rowid pseudo-column, or TypeNull when there is no such column.
+func columnType(schema []sql.Column, name string) sql.DataType {
+	for _, c := range schema {
+		if strings.EqualFold(c.Name, name) {
+			return c.Type
+		}
+	}
+	if strings.EqualFold(name, rowIDColumn) {
+		return sql.TypeInt
+	}
+	return sql.TypeNull
+}
+
+// exprType returns the type of the values expr yields for rows of schema,
+// or TypeNull when they are always NULL or the type depends on the row.
+func exprType(schema []sql.Column, expr sql.Expr) sql.DataType {
+	switch x := expr.(type) {
+	case *sql.ColumnRef:
+		return columnType(schema, x.Name)
+
+	case *sql.Literal:
+		return x.Value.Type
+
+	case *sql.BinaryExpr:
+		if x.Op == "||" {
+			return sql.TypeString
+		}
+		if isArithmetic(x.Op) {
+			l, r := exprType(schema, x.Left), exprType(schema, x.Right)
+			if l == sql.TypeInt && r == sql.TypeInt {
+				return sql.TypeInt
+			}
+			if (l == sql.TypeInt || l == sql.TypeFloat) && (r == sql.TypeInt || r == sql.TypeFloat) {
+				return sql.TypeFloat
+			}
+			return sql.TypeNull
+		}
+		return sql.TypeBool
+
+	case *sql.UnaryExpr:
+		return exprType(schema, x.Expr)
+
+	case *sql.CastExpr:
+		return x.Type
+
+	case *sql.CaseExpr:
+		// The first branch whose type is known.
+		types := make([]sql.DataType, 0, len(x.Whens)+1)
+		for _, w := range x.Whens {
+			types = append(types, exprType(schema, w.Then))
+		}
+		if x.Else != nil {
+			types = append(types, exprType(schema, x.Else))
+		}
+		return firstNonNullType(types)
+
+	case *sql.FuncCall:
+		switch x.Name {
+		case "COUNT":
+			return sql.TypeInt
+		case "AVG":
+			return sql.TypeFloat
+		case "SUM", "MIN", "MAX":
+			// SUM of INT values stays INT, like MIN and MAX keep the
+			// type of their argument.
+			return exprType(schema, x.Args[0])
+		}
+		fn, ok := scalarFuncs[x.Name]
+		if !ok {
+			return sql.TypeNull
+		}
+		args := make([]sql.DataType, len(x.Args))
+		for i, a := range x.Args {
+			args[i] = exprType(schema, a)
+		}
+		return fn.typ(args)
+	}
+	return sql.TypeNull
+}
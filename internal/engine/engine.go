@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"goDB/internal/sql"
 	"goDB/internal/storage"
+	"time"
 )
 
 // DBEngine is the main database engine struct.
@@ -13,6 +14,12 @@ type DBEngine struct {
 	store   storage.Engine
 	inTx    bool
 	currTx  storage.Tx
+	txMode  sql.TxMode // access mode of currTx
+
+	// now returns the time used for CURRENT_TIMESTAMP; tests replace it.
+	now func() time.Time
+
+	metrics *Metrics // nil unless SetMetrics was called
 }
 
 // New creates a new DBEngine instance.
@@ -22,6 +29,7 @@ func New(store storage.Engine) *DBEngine {
 		started: false,
 		store:   store,
 		inTx:    false,
+		now:     time.Now,
 	}
 }
 
@@ -44,6 +52,64 @@ func (e *DBEngine) ListTables() ([]string, error) {
 	return e.store.ListTables()
 }
 
+// IndexedColumns returns the indexed columns of a table, sorted by name. It
+// returns nil when the storage engine cannot report its indexes.
+func (e *DBEngine) IndexedColumns(table string) ([]string, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+
+	il, ok := e.store.(storage.IndexLister)
+	if !ok {
+		return nil, nil
+	}
+	return il.IndexedColumns(table)
+}
+
+// TableStats reports the row, page and dead-slot counts and the size of a
+// table. It fails when the storage engine does not keep such statistics.
+func (e *DBEngine) TableStats(table string) (storage.TableStats, error) {
+	if !e.started {
+		return storage.TableStats{}, fmt.Errorf("engine not started")
+	}
+
+	sr, ok := e.store.(storage.StatsReporter)
+	if !ok {
+		return storage.TableStats{}, fmt.Errorf("storage engine does not report table statistics")
+	}
+	return sr.TableStats(table)
+}
+
+// CheckIntegrity verifies every table and index of the storage engine and
+// returns the problems found; none means the data is consistent. It fails
+// when the storage engine cannot check its data.
+func (e *DBEngine) CheckIntegrity() ([]storage.Problem, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+
+	ic, ok := e.store.(storage.IntegrityChecker)
+	if !ok {
+		return nil, fmt.Errorf("storage engine does not support integrity checks")
+	}
+	return ic.CheckIntegrity(), nil
+}
+
+// Sync makes every committed transaction durable, even when the storage
+// engine commits asynchronously. It does nothing for storage engines that
+// cannot, such as the in-memory one, which has nothing on disk to flush.
+func (e *DBEngine) Sync() error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+
+	s, ok := e.store.(storage.Syncer)
+	if !ok {
+		return nil
+	}
+	return s.Sync()
+}
+
 // TableSchema returns the column definitions for a table.
 func (e *DBEngine) TableSchema(name string) ([]sql.Column, error) {
 	if !e.started {
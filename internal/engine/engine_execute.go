@@ -1,10 +1,13 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"goDB/internal/sql"
+	"goDB/internal/storage"
 	"sort"
 	"strings"
+	"time"
 )
 
 // Execute takes a parsed SQL Statement and executes it using the engine.
@@ -15,105 +18,406 @@ import (
 // nil error as success. SELECT statements return the full projected columns
 // and rows, applying WHERE/ORDER BY/LIMIT in that order.
 func (e *DBEngine) Execute(stmt sql.Statement) ([]string, []sql.Row, error) {
+	return e.ExecuteContext(context.Background(), stmt)
+}
+
+// ExecuteContext is like Execute, but gives up once ctx is done.
+func (e *DBEngine) ExecuteContext(ctx context.Context, stmt sql.Statement) ([]string, []sql.Row, error) {
+	res, err := e.ExecContext(ctx, stmt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.Columns, res.Rows, nil
+}
+
+// Exec executes a parsed SQL Statement like Execute, but also reports how
+// many rows an INSERT, UPDATE or DELETE affected.
+func (e *DBEngine) Exec(stmt sql.Statement) (*Result, error) {
+	return e.ExecContext(context.Background(), stmt)
+}
+
+// ExecContext is like Exec, but gives up once ctx is done. The context is
+// checked before the statement starts and between the pages of a SELECT
+// scan on storage engines that implement storage.ContextScanner; the error
+// then wraps ctx.Err(). Writes are not interrupted once they have started.
+func (e *DBEngine) ExecContext(ctx context.Context, stmt sql.Statement) (*Result, error) {
+	if e.metrics == nil {
+		return e.execContext(ctx, stmt)
+	}
+	start := time.Now()
+	res, err := e.execContext(ctx, stmt)
+	e.metrics.observe(statementKind(stmt), time.Since(start), err)
+	return res, err
+}
+
+// execContext is ExecContext without the metrics.
+func (e *DBEngine) execContext(ctx context.Context, stmt sql.Statement) (*Result, error) {
 	if !e.started {
-		return nil, nil, fmt.Errorf("engine not started")
+		return nil, fmt.Errorf("engine not started")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("query canceled: %w", err)
+	}
+	if e.inTx && e.txMode == sql.TxReadOnly {
+		if name := writeStatementName(stmt); name != "" {
+			return nil, fmt.Errorf("cannot run %s in a READ ONLY transaction", name)
+		}
 	}
 
 	switch s := stmt.(type) {
 	case *sql.CreateTableStmt:
-		err := e.CreateTable(s.TableName, s.Columns)
-		return nil, nil, err
+		if s.AsSelect != nil {
+			n, err := e.createTableAs(ctx, s)
+			if err != nil {
+				return nil, err
+			}
+			return &Result{RowsAffected: int64(n)}, nil
+		}
+		return &Result{}, e.CreateTable(s.TableName, s.Columns)
+
+	case *sql.CreateSequenceStmt:
+		return &Result{}, e.createSequence(s)
 
 	case *sql.CreateIndexStmt:
-		err := e.store.CreateIndex(s.IndexName, s.TableName, s.ColumnName)
-		return nil, nil, err
+		return &Result{}, e.store.CreateIndex(s.IndexName, s.TableName, s.ColumnName)
+
+	case *sql.AlterTableStmt:
+		return &Result{}, e.executeAlterTable(s)
 
 	case *sql.InsertStmt:
-		return nil, nil, e.executeInsert(s)
+		n, err := e.executeInsert(s)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{RowsAffected: int64(n)}, nil
 
 	case *sql.SelectStmt:
-		var fullCols []string
-		var fullRows []sql.Row
-		var err error
-
-		if e.inTx {
-			fullCols, fullRows, err = e.executeSelectInTx(e.currTx, s.TableName)
-		} else {
-			fullCols, fullRows, err = e.executeSelect(s.TableName)
+		s, err := e.expandStar(s)
+		if err != nil {
+			return nil, err
 		}
+		cols, rows, err := e.executeSelectStmt(ctx, s)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-
-		// WHERE
-		if s.Where != nil {
-			fullRows, err = filterRowsWhere(fullCols, fullRows, s.Where)
-			if err != nil {
-				return nil, nil, err
-			}
+		types, err := e.selectColumnTypes(s, cols, rows)
+		if err != nil {
+			return nil, err
 		}
+		return &Result{Columns: cols, ColumnTypes: types, Rows: rows}, nil
 
-		// ORDER BY
-		if s.OrderBy != nil {
-			if err := sortRows(fullCols, fullRows, s.OrderBy); err != nil {
-				return nil, nil, err
-			}
+	case *sql.UpdateStmt:
+		n, err := e.executeUpdate(s)
+		if err != nil {
+			return nil, err
 		}
+		return &Result{RowsAffected: int64(n)}, nil
 
-		// LIMIT
-		if s.Limit != nil {
-			n := *s.Limit
-			if n < len(fullRows) {
-				fullRows = fullRows[:n]
-			}
+	case *sql.DeleteStmt:
+		n, err := e.executeDelete(s)
+		if err != nil {
+			return nil, err
 		}
+		return &Result{RowsAffected: int64(n)}, nil
 
-		// Projection
-		if len(s.Columns) == 0 {
-			return fullCols, fullRows, nil
-		}
-		projCols, projRows, err := projectColumns(fullCols, fullRows, s.Columns)
-		return projCols, projRows, err
+	case *sql.BeginTxStmt:
+		return &Result{}, e.beginTx(s.Mode)
 
-	case *sql.UpdateStmt:
-		return nil, nil, e.executeUpdate(s)
+	case *sql.CommitTxStmt:
+		return &Result{}, e.commitTx()
+
+	case *sql.RollbackTxStmt:
+		return &Result{}, e.rollbackTx()
 
+	case *sql.PragmaStmt:
+		return e.executePragma(s)
+
+	default:
+		return nil, fmt.Errorf("unsupported statement type %T", stmt)
+	}
+}
+
+// writeStatementName returns the name of the statement kind when stmt
+// changes data or schema, and "" otherwise.
+func writeStatementName(stmt sql.Statement) string {
+	switch stmt.(type) {
+	case *sql.CreateTableStmt:
+		return "CREATE TABLE"
+	case *sql.CreateIndexStmt:
+		return "CREATE INDEX"
+	case *sql.CreateSequenceStmt:
+		return "CREATE SEQUENCE"
+	case *sql.AlterTableStmt:
+		return "ALTER TABLE"
+	case *sql.InsertStmt:
+		return "INSERT"
+	case *sql.UpdateStmt:
+		return "UPDATE"
 	case *sql.DeleteStmt:
-		return nil, nil, e.executeDelete(s)
+		return "DELETE"
+	default:
+		return ""
+	}
+}
 
-	case *sql.BeginTxStmt:
-		err := e.beginTx()
+// executeSelectStmt runs a SELECT, applying WHERE, ORDER BY, LIMIT and the
+// projection in that order. Aggregate queries are grouped after WHERE and
+// sorted and limited on their output columns.
+func (e *DBEngine) executeSelectStmt(ctx context.Context, s *sql.SelectStmt) ([]string, []sql.Row, error) {
+	var cols []string
+	var rows []sql.Row
+	read := func(tx storage.Tx) error {
+		var err error
+		cols, rows, err = e.selectInTx(ctx, tx, s)
+		return err
+	}
+
+	if err := e.readInTx(read); err != nil {
 		return nil, nil, err
+	}
+	return cols, rows, nil
+}
 
-	case *sql.CommitTxStmt:
-		err := e.commitTx()
+// selectInTx runs a SELECT in an existing transaction.
+func (e *DBEngine) selectInTx(ctx context.Context, tx storage.Tx, s *sql.SelectStmt) ([]string, []sql.Row, error) {
+	path, err := e.selectPath(tx, s)
+	if err != nil {
 		return nil, nil, err
+	}
+	if path.kind == accessCount {
+		return countStar(tx.(storage.RowCounter), s)
+	}
 
-	case *sql.RollbackTxStmt:
-		err := e.rollbackTx()
+	var fullCols []string
+	var fullRows []sql.Row
+	var sorted bool
+	rowID := path.kind == accessRowIDs
+	if rowID {
+		fullCols, fullRows, err = e.scanRowIDs(ctx, tx, s.TableName, s.Where)
+	} else {
+		fullCols, fullRows, sorted, err = e.selectRows(ctx, tx, s, path)
+	}
+	if err != nil {
 		return nil, nil, err
+	}
 
-	default:
-		return nil, nil, fmt.Errorf("unsupported statement type %T", stmt)
+	if isAggregateQuery(s) {
+		return aggregateSelect(fullCols, fullRows, s)
+	}
+
+	// ORDER BY, unless the rows were read in index order or are sorted by
+	// an output column after the projection
+	byOutput := orderByOutput(s)
+	if s.OrderBy != nil && !sorted && !byOutput {
+		schema, err := e.store.TableSchema(s.TableName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scan: %w", err)
+		}
+		if err := sortRows(fullCols, fullRows, s.OrderBy, nocaseColumns(schema)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !byOutput {
+		fullRows = limitRows(fullRows, s.Limit)
+	}
+
+	outCols, outRows, err := projectSelect(fullCols, fullRows, s, rowID)
+	if err != nil || !byOutput {
+		return outCols, outRows, err
+	}
+	if err := sortRows(outCols, outRows, s.OrderBy, nil); err != nil {
+		return nil, nil, err
+	}
+	return outCols, limitRows(outRows, s.Limit), nil
+}
+
+// projectSelect computes the select list of a non-aggregate SELECT. rowID
+// reports that the last column of rows is the rowid pseudo-column, which
+// SELECT * leaves out.
+func projectSelect(cols []string, rows []sql.Row, s *sql.SelectStmt, rowID bool) ([]string, []sql.Row, error) {
+	if len(s.Columns) == 0 {
+		if rowID {
+			return cols[:len(cols)-1], stripRowIDs(rows), nil
+		}
+		return cols, rows, nil
+	}
+	if s.Exprs != nil {
+		return projectExprs(cols, rows, s.Columns, s.Exprs)
+	}
+	return projectColumns(cols, rows, s.Columns)
+}
+
+// orderByOutput reports whether the ORDER BY of a SELECT names an output
+// column that is computed or renamed with AS, such as c in SELECT a + b AS
+// c FROM t ORDER BY c. Such rows are sorted after the projection; ORDER BY
+// any other name sorts on the table's columns before it, so it may name a
+// column that is not selected.
+func orderByOutput(s *sql.SelectStmt) bool {
+	if s.OrderBy == nil || s.Exprs == nil {
+		return false
+	}
+	for i, c := range s.Columns {
+		if strings.EqualFold(c, s.OrderBy.Column) {
+			ref, ok := s.Exprs[i].(*sql.ColumnRef)
+			return !ok || !strings.EqualFold(ref.Name, c)
+		}
+	}
+	return false
+}
+
+// limitRows returns the first limit rows, or all of them without a LIMIT.
+func limitRows(rows []sql.Row, limit *int) []sql.Row {
+	if limit != nil && *limit < len(rows) {
+		return rows[:*limit]
+	}
+	return rows
+}
+
+// isCountStar reports whether s is a plain SELECT COUNT(*) FROM table,
+// without WHERE or GROUP BY.
+func isCountStar(s *sql.SelectStmt) bool {
+	if s.Where != nil || len(s.GroupBy) > 0 || len(s.Exprs) != 1 {
+		return false
+	}
+	call, ok := s.Exprs[0].(*sql.FuncCall)
+	return ok && call.Name == "COUNT" && call.Star
+}
+
+// countStar answers SELECT COUNT(*) FROM table from the row count the
+// storage engine keeps, without reading any rows.
+func countStar(counter storage.RowCounter, s *sql.SelectStmt) ([]string, []sql.Row, error) {
+	n, err := counter.CountRows(s.TableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scan: %w", err)
+	}
+	cols := []string{s.Columns[0]}
+	rows := []sql.Row{{{Type: sql.TypeInt, I64: int64(n)}}}
+	if s.OrderBy != nil {
+		if err := sortRows(cols, rows, s.OrderBy, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cols, limitRows(rows, s.Limit), nil
+}
+
+// aggregateSelect finishes an aggregate SELECT: it groups the rows, then
+// applies ORDER BY to the output columns and LIMIT.
+func aggregateSelect(cols []string, rows []sql.Row, s *sql.SelectStmt) ([]string, []sql.Row, error) {
+	outCols, outRows, err := aggregate(cols, rows, s)
+	if err != nil {
+		return nil, nil, err
 	}
+	if s.OrderBy != nil {
+		if err := sortRows(outCols, outRows, s.OrderBy, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return outCols, limitRows(outRows, s.Limit), nil
+}
+
+// selectRows reads the rows of a SELECT that match its WHERE clause along
+// path, chosen by selectPath. Rows read in index order come back with
+// sorted true; otherwise they are in table order for sortRows. When the
+// storage engine gives up on an index, the table is scanned instead.
+func (e *DBEngine) selectRows(ctx context.Context, tx storage.Tx, s *sql.SelectStmt, path accessPath) (cols []string, rows []sql.Row, sorted bool, err error) {
+	switch path.kind {
+	case accessLimit:
+		cols, rows, err := tx.(storage.LimitScanner).ScanLimit(s.TableName, *s.Limit)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("scan: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, false, fmt.Errorf("query canceled: %w", err)
+		}
+		return cols, rows, false, nil
+
+	case accessOrdered:
+		var pred storage.RowPredicate
+		if s.Where != nil {
+			schema, err := e.store.TableSchema(s.TableName)
+			if err != nil {
+				return nil, nil, false, fmt.Errorf("scan: %w", err)
+			}
+			names := make([]string, len(schema))
+			for i, c := range schema {
+				names[i] = c.Name
+			}
+			if pred, err = buildPredicate(names, s.Where, nocaseColumns(schema)); err != nil {
+				return nil, nil, false, err
+			}
+		}
+
+		scanner := tx.(storage.OrderedScanner)
+		cols, rows, ok, err := scanner.ScanOrdered(s.TableName, path.column, s.OrderBy.Desc, pred)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("scan: %w", err)
+		}
+		if ok {
+			return cols, rows, true, nil
+		}
+
+	case accessRange:
+		scanner := tx.(storage.RangeScanner)
+		cols, rows, ok, err := e.scanRange(scanner, s.TableName, path.column, path.lo, path.hi, s.Where)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, false, fmt.Errorf("query canceled: %w", err)
+		}
+		if ok {
+			return cols, rows, false, nil
+		}
+	}
+
+	cols, rows, err = e.scanWhere(ctx, tx, s.TableName, s.Where)
+	return cols, rows, false, err
+}
+
+// scanRange reads the rows matching where through the index on column,
+// limited to keys in [lo, hi]. ok is false when the storage engine could
+// not use an index and the caller has to scan the table instead.
+func (e *DBEngine) scanRange(scanner storage.RangeScanner, table, column string, lo, hi int64, where *sql.WhereExpr) (cols []string, rows []sql.Row, ok bool, err error) {
+	schema, err := e.store.TableSchema(table)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("scan: %w", err)
+	}
+	names := make([]string, len(schema))
+	for i, c := range schema {
+		names[i] = c.Name
+	}
+	pred, err := buildPredicate(names, where, nocaseColumns(schema))
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	cols, rows, ok, err = scanner.ScanRange(table, column, lo, hi, pred)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("scan: %w", err)
+	}
+	return cols, rows, ok, nil
 }
 
 // sortRows orders the provided rows in place based on the ORDER BY clause.
 // It uses a stable sort so rows with equal keys preserve their original
-// relative order.
-func sortRows(cols []string, rows []sql.Row, ob *sql.OrderByClause) error {
+// relative order. A column named in nocase sorts ignoring case.
+func sortRows(cols []string, rows []sql.Row, ob *sql.OrderByClause, nocase map[string]bool) error {
 	colIndex := make(map[string]int, len(cols))
 	for i, name := range cols {
 		colIndex[strings.ToLower(name)] = i
 	}
 	idx, ok := colIndex[strings.ToLower(ob.Column)]
 	if !ok {
-		return fmt.Errorf("unknown column %q in ORDER BY", ob.Column)
+		return fmt.Errorf("%w %q in ORDER BY", sql.ErrColumnNotFound, ob.Column)
 	}
 
+	fold := nocase[strings.ToLower(ob.Column)]
 	sort.SliceStable(rows, func(i, j int) bool {
 		a := rows[i][idx]
 		b := rows[j][idx]
+		if fold {
+			a, b = foldCase(a), foldCase(b)
+		}
 		cmp, err := compareValues(a, b)
 		if err != nil {
 			// keep stable ordering on comparison errors
@@ -3,11 +3,75 @@ package engine
 import (
 	"fmt"
 	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"math"
 	"strings"
 )
 
 // filterRowsWhere filters rows according to a simple WHERE expression (column = literal).
-func filterRowsWhere(cols []string, rows []sql.Row, where *sql.WhereExpr) ([]sql.Row, error) {
+func filterRowsWhere(cols []string, rows []sql.Row, where *sql.WhereExpr, nocase map[string]bool) ([]sql.Row, error) {
+	pred, err := buildPredicate(cols, where, nocase)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]sql.Row, 0, len(rows))
+	for _, r := range rows {
+		ok, err := pred(r)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// buildPredicate compiles a WHERE clause over the given column headers into
+// a storage predicate; a nil clause matches every row. SELECT, UPDATE and
+// DELETE all evaluate WHERE through it, whether the predicate is pushed
+// into the storage layer or applied to scanned rows. Comparisons of the
+// columns in nocase (lower-cased names of COLLATE NOCASE columns, see
+// nocaseColumns) ignore case. The predicate is safe for concurrent use.
+func buildPredicate(cols []string, where *sql.WhereExpr, nocase map[string]bool) (storage.RowPredicate, error) {
+	if where == nil {
+		return func(sql.Row) (bool, error) { return true, nil }, nil
+	}
+	pred, err := buildComparison(cols, where, nocase)
+	if err != nil || where.And == nil {
+		return pred, err
+	}
+	rest, err := buildPredicate(cols, where.And, nocase)
+	if err != nil {
+		return nil, err
+	}
+	return func(r sql.Row) (bool, error) {
+		ok, err := pred(r)
+		if err != nil || !ok {
+			return false, err
+		}
+		return rest(r)
+	}, nil
+}
+
+// buildComparison compiles a single comparison of a WHERE clause, ignoring
+// the rest of its AND chain.
+func buildComparison(cols []string, where *sql.WhereExpr, nocase map[string]bool) (storage.RowPredicate, error) {
+	if where.Left != nil {
+		left, err := compileExpr(where.Left, cols)
+		if err != nil {
+			return nil, fmt.Errorf("%w in WHERE clause", err)
+		}
+		return func(r sql.Row) (bool, error) {
+			v, err := left(r)
+			if err != nil {
+				return false, err
+			}
+			return conditionMatches(v, where.Op, where.Value), nil
+		}, nil
+	}
+
 	colIndex := make(map[string]int, len(cols))
 	for i, name := range cols {
 		colIndex[strings.ToLower(name)] = i
@@ -15,19 +79,115 @@ func filterRowsWhere(cols []string, rows []sql.Row, where *sql.WhereExpr) ([]sql
 
 	idx, ok := colIndex[strings.ToLower(where.Column)]
 	if !ok {
-		return nil, fmt.Errorf("unknown column %q in WHERE clause", where.Column)
+		return nil, fmt.Errorf("%w %q in WHERE clause", sql.ErrColumnNotFound, where.Column)
 	}
 
-	out := make([]sql.Row, 0, len(rows))
-	for _, r := range rows {
+	if nocase[strings.ToLower(where.Column)] {
+		want := foldCase(where.Value)
+		return func(r sql.Row) (bool, error) {
+			if idx < 0 || idx >= len(r) {
+				return false, nil
+			}
+			return conditionMatches(foldCase(r[idx]), where.Op, want), nil
+		}, nil
+	}
+	return func(r sql.Row) (bool, error) {
 		if idx < 0 || idx >= len(r) {
-			continue
+			return false, nil
 		}
-		if conditionMatches(r[idx], where.Op, where.Value) {
-			out = append(out, r)
+		return conditionMatches(r[idx], where.Op, where.Value), nil
+	}, nil
+}
+
+// nocaseColumns returns the lower-cased names of the COLLATE NOCASE columns
+// of schema, or nil when it has none.
+func nocaseColumns(schema []sql.Column) map[string]bool {
+	var nocase map[string]bool
+	for _, c := range schema {
+		if c.NoCase {
+			if nocase == nil {
+				nocase = make(map[string]bool)
+			}
+			nocase[strings.ToLower(c.Name)] = true
 		}
 	}
-	return out, nil
+	return nocase
+}
+
+// foldCase returns v with a STRING value lower-cased, so values of a
+// COLLATE NOCASE column compare ignoring case.
+func foldCase(v sql.Value) sql.Value {
+	if v.Type == sql.TypeString {
+		v.S = strings.ToLower(v.S)
+	}
+	return v
+}
+
+// indexRange recognizes a WHERE clause of two or more comparisons of the
+// same column against INT literals, such as id > 5 AND id < 10, and returns
+// the inclusive key range they allow. ok is false for a single comparison,
+// for comparisons on different columns or expressions, and for != or
+// non-INT literals, which leave the clause to be evaluated row by row.
+func indexRange(where *sql.WhereExpr) (column string, lo, hi int64, ok bool) {
+	if where == nil || where.And == nil {
+		return "", 0, 0, false
+	}
+	return keyRange(where)
+}
+
+// writeRange is indexRange for UPDATE and DELETE, which also accepts a
+// single comparison such as id = 5: finding the rows to write through the
+// index saves reading the rest of the table.
+func writeRange(where *sql.WhereExpr) (column string, lo, hi int64, ok bool) {
+	if where == nil {
+		return "", 0, 0, false
+	}
+	return keyRange(where)
+}
+
+// keyRange returns the key range allowed by a non-nil chain of comparisons
+// for indexRange and writeRange.
+func keyRange(where *sql.WhereExpr) (column string, lo, hi int64, ok bool) {
+	lo, hi = math.MinInt64, math.MaxInt64
+	empty := false
+	for w := where; w != nil; w = w.And {
+		if w.Left != nil || w.Value.Type != sql.TypeInt {
+			return "", 0, 0, false
+		}
+		if column == "" {
+			column = w.Column
+		} else if !strings.EqualFold(column, w.Column) {
+			return "", 0, 0, false
+		}
+
+		v := w.Value.I64
+		switch w.Op {
+		case "=":
+			lo, hi = max(lo, v), min(hi, v)
+		case ">=":
+			lo = max(lo, v)
+		case "<=":
+			hi = min(hi, v)
+		case ">":
+			if v == math.MaxInt64 {
+				empty = true
+			} else {
+				lo = max(lo, v+1)
+			}
+		case "<":
+			if v == math.MinInt64 {
+				empty = true
+			} else {
+				hi = min(hi, v-1)
+			}
+		default:
+			return "", 0, 0, false
+		}
+	}
+	if empty {
+		lo, hi = 1, 0
+	}
+	return column, lo, hi, true
 }
 
 // valuesEqual compares two sql.Value for equality, considering their type.
@@ -40,11 +200,11 @@ func valuesEqual(a, b sql.Value) bool {
 		return false
 	}
 	switch a.Type {
-	case sql.TypeInt:
+	case sql.TypeInt, sql.TypeTimestamp:
 		return a.I64 == b.I64
 	case sql.TypeFloat:
 		return a.F64 == b.F64
-	case sql.TypeString:
+	case sql.TypeString, sql.TypeBytes:
 		return a.S == b.S
 	case sql.TypeBool:
 		return a.B == b.B
@@ -66,7 +226,7 @@ func projectColumns(allCols []string, rows []sql.Row, requestedCols []string) ([
 	for i, name := range requestedCols {
 		idx, ok := colIndex[strings.ToLower(name)]
 		if !ok {
-			return nil, nil, fmt.Errorf("unknown column %q in SELECT list", name)
+			return nil, nil, fmt.Errorf("%w %q in SELECT list", sql.ErrColumnNotFound, name)
 		}
 		indexes[i] = idx
 	}
@@ -91,6 +251,14 @@ func projectColumns(allCols []string, rows []sql.Row, requestedCols []string) ([
 	return outCols, outRows, nil
 }
 
+// isValue reports whether v IS want, where want is NULL, TRUE or FALSE.
+func isValue(v, want sql.Value) bool {
+	if want.Type == sql.TypeNull {
+		return v.Type == sql.TypeNull
+	}
+	return v.Type == sql.TypeBool && v.B == want.B
+}
+
 // compareValues compares two non-NULL values of the same type.
 // Returns -1 if a < b, 0 if a == b, 1 if a > b.
 // If types differ or comparison is not meaningful, returns an error.
@@ -99,11 +267,11 @@ func compareValues(a, b sql.Value) (int, error) {
 		return 0, fmt.Errorf("cannot compare NULL values")
 	}
 	if a.Type != b.Type {
-		return 0, fmt.Errorf("cannot compare values of different types")
+		return 0, fmt.Errorf("%w: cannot compare values of different types", sql.ErrTypeMismatch)
 	}
 
 	switch a.Type {
-	case sql.TypeInt:
+	case sql.TypeInt, sql.TypeTimestamp:
 		if a.I64 < b.I64 {
 			return -1, nil
 		} else if a.I64 > b.I64 {
@@ -117,7 +285,7 @@ func compareValues(a, b sql.Value) (int, error) {
 			return 1, nil
 		}
 		return 0, nil
-	case sql.TypeString:
+	case sql.TypeString, sql.TypeBytes:
 		return strings.Compare(a.S, b.S), nil
 	case sql.TypeBool:
 		ai := 0
@@ -139,9 +307,15 @@ func compareValues(a, b sql.Value) (int, error) {
 	}
 }
 
-// conditionMatches checks rowValue <op> whereValue.
+// conditionMatches checks rowValue <op> whereValue. Comparisons never match
+// NULL; IS and IS NOT test for NULL, TRUE or FALSE and always give an answer,
+// so NULL IS NOT TRUE holds while NULL != true does not.
 func conditionMatches(rowVal sql.Value, op string, whereVal sql.Value) bool {
 	switch op {
+	case "IS":
+		return isValue(rowVal, whereVal)
+	case "IS NOT":
+		return !isValue(rowVal, whereVal)
 	case "=":
 		return valuesEqual(rowVal, whereVal)
 	case "!=":
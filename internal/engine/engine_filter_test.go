@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"goDB/internal/sql"
+)
+
+func TestBuildPredicate(t *testing.T) {
+	cols := []string{"id", "Name", "score"}
+	row := sql.Row{
+		{Type: sql.TypeInt, I64: 2},
+		{Type: sql.TypeString, S: "Bob"},
+		{Type: sql.TypeNull},
+	}
+
+	tests := []struct {
+		where string
+		want  bool
+	}{
+		{"id = 2", true},
+		{"id != 2", false},
+		{"id < 3", true},
+		{"id >= 3", false},
+		{"name = 'Bob'", true},
+		{"NAME > 'Alice'", true},
+		{"score = 1", false},
+		{"score != 1", true},
+		{"UPPER(name) = 'BOB'", true},
+		{"name || '!' = 'Bob!'", true},
+		{"id > 1 AND id < 3", true},
+		{"id > 1 AND name = 'Alice'", false},
+		{"score IS NULL", true},
+		{"score IS NOT NULL", false},
+		{"name IS TRUE", false},
+		{"name IS NOT FALSE", true},
+		{"id = 2 AND score IS NOT TRUE", true},
+	}
+	for _, tt := range tests {
+		stmt, err := sql.Parse("SELECT * FROM t WHERE " + tt.where + ";")
+		if err != nil {
+			t.Fatalf("Parse %q failed: %v", tt.where, err)
+		}
+		pred, err := buildPredicate(cols, stmt.(*sql.SelectStmt).Where, nil)
+		if err != nil {
+			t.Fatalf("buildPredicate(%q) failed: %v", tt.where, err)
+		}
+		got, err := pred(row)
+		if err != nil {
+			t.Fatalf("predicate %q failed: %v", tt.where, err)
+		}
+		if got != tt.want {
+			t.Errorf("WHERE %s = %v, want %v", tt.where, got, tt.want)
+		}
+	}
+
+	all, err := buildPredicate(cols, nil, nil)
+	if err != nil {
+		t.Fatalf("buildPredicate(nil) failed: %v", err)
+	}
+	if ok, _ := all(row); !ok {
+		t.Fatalf("nil WHERE should match every row")
+	}
+
+	for _, where := range []string{"missing = 1", "LOWER(missing) = 'x'"} {
+		stmt, err := sql.Parse("SELECT * FROM t WHERE " + where + ";")
+		if err != nil {
+			t.Fatalf("Parse %q failed: %v", where, err)
+		}
+		if _, err := buildPredicate(cols, stmt.(*sql.SelectStmt).Where, nil); err == nil {
+			t.Errorf("expected an error for WHERE %s", where)
+		}
+	}
+}
+
+func TestIndexRange(t *testing.T) {
+	tests := []struct {
+		where  string
+		ok     bool
+		lo, hi int64
+	}{
+		{"id > 5 AND id < 10", true, 6, 9},
+		{"ID >= 5 AND id <= 10 AND id != 7", false, 0, 0},
+		{"id >= 5 AND id <= 10 AND id < 8", true, 5, 7},
+		{"id = 3 AND id >= 1", true, 3, 3},
+		{"id > 9223372036854775807 AND id < 10", true, 1, 0},
+		{"id > 5 AND name < 'x'", false, 0, 0},
+		{"id > 5 AND id < 1.5", false, 0, 0},
+		{"id > 5", false, 0, 0},
+		{"-id > 5 AND id < 10", false, 0, 0},
+	}
+	for _, tt := range tests {
+		stmt, err := sql.Parse("SELECT * FROM t WHERE " + tt.where + ";")
+		if err != nil {
+			t.Fatalf("Parse %q failed: %v", tt.where, err)
+		}
+		col, lo, hi, ok := indexRange(stmt.(*sql.SelectStmt).Where)
+		if ok != tt.ok {
+			t.Fatalf("indexRange(%q) ok = %v, want %v", tt.where, ok, tt.ok)
+		}
+		if ok && (col == "" || lo != tt.lo || hi != tt.hi) {
+			t.Errorf("indexRange(%q) = %q [%d, %d], want [%d, %d]", tt.where, col, lo, hi, tt.lo, tt.hi)
+		}
+	}
+
+	stmt, _ := sql.Parse("SELECT * FROM t WHERE id <= 0 AND id < 1;")
+	if _, lo, hi, _ := indexRange(stmt.(*sql.SelectStmt).Where); lo != math.MinInt64 || hi != 0 {
+		t.Errorf("open lower bound = [%d, %d], want [MinInt64, 0]", lo, hi)
+	}
+}
@@ -1,10 +1,16 @@
 package engine
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"goDB/internal/storage/filestore"
 	"goDB/internal/storage/memstore"
 )
 
@@ -972,6 +978,323 @@ func TestEngine_Select_OrderByLimitAndWhere(t *testing.T) {
 	}
 }
 
+// A SELECT with only a LIMIT stops scanning early on filestore; it must
+// return the same leading rows as a full scan.
+func TestEngine_Select_LimitOnly(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	defer fs.Close()
+	for _, eng := range []*DBEngine{New(memstore.New()), New(fs)} {
+		if err := eng.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+		mustExec(t, eng, "INSERT INTO users VALUES (1, 'a'), (2, 'b'), (3, 'c');")
+
+		res := mustExec(t, eng, "SELECT name FROM users LIMIT 2;")
+		if len(res.Columns) != 1 || len(res.Rows) != 2 || res.Rows[0][0].S != "a" || res.Rows[1][0].S != "b" {
+			t.Fatalf("LIMIT 2: %v %v", res.Columns, res.Rows)
+		}
+		if n := len(mustExec(t, eng, "SELECT * FROM users LIMIT 0;").Rows); n != 0 {
+			t.Fatalf("LIMIT 0 returned %d rows", n)
+		}
+		if n := len(mustExec(t, eng, "SELECT * FROM users LIMIT 10;").Rows); n != 3 {
+			t.Fatalf("LIMIT 10 returned %d rows, want 3", n)
+		}
+	}
+}
+
+// newBigTableEngine returns a started engine over a filestore without a page
+// cache, so every page read shows in its metrics, holding a table big of n
+// rows (id INT, pad STRING) of about 200 bytes each, ids 0 to n-1.
+func newBigTableEngine(tb testing.TB, n int) (*DBEngine, *filestore.FileEngine) {
+	tb.Helper()
+	fs, err := filestore.NewWithOptions(tb.TempDir(), filestore.Options{PageCacheSize: -1})
+	if err != nil {
+		tb.Fatalf("filestore.NewWithOptions failed: %v", err)
+	}
+	tb.Cleanup(func() { fs.Close() })
+	eng := New(fs)
+	if err := eng.Start(); err != nil {
+		tb.Fatalf("Start failed: %v", err)
+	}
+	mustExec(tb, eng, "CREATE TABLE big (id INT, pad STRING);")
+	pad := strings.Repeat("x", 200)
+	for i := 0; i < n; i += 50 {
+		var vals []string
+		for j := i; j < min(i+50, n); j++ {
+			vals = append(vals, fmt.Sprintf("(%d, '%s')", j, pad))
+		}
+		mustExec(tb, eng, "INSERT INTO big VALUES "+strings.Join(vals, ", ")+";")
+	}
+	return eng, fs
+}
+
+// A plain SELECT with a LIMIT reads only the pages holding the rows it
+// returns, not the whole table.
+func TestEngine_Select_LimitReadsFewPages(t *testing.T) {
+	eng, fs := newBigTableEngine(t, 1000)
+
+	pagesRead := func(q string) (int64, *Result) {
+		before := fs.Metrics().PageReads
+		res := mustExec(t, eng, q)
+		return fs.Metrics().PageReads - before, res
+	}
+	all, res := pagesRead("SELECT * FROM big;")
+	if len(res.Rows) != 1000 || all < 20 {
+		t.Fatalf("full scan: %d rows from %d pages, want 1000 rows from many pages", len(res.Rows), all)
+	}
+	limited, res := pagesRead("SELECT id FROM big LIMIT 5;")
+	if len(res.Rows) != 5 || res.Rows[4][0].I64 != 4 {
+		t.Fatalf("LIMIT 5 rows = %v", res.Rows)
+	}
+	if limited != 1 {
+		t.Fatalf("LIMIT 5 read %d pages, want 1 (the full scan read %d)", limited, all)
+	}
+}
+
+// UPDATE and DELETE with WHERE on an indexed column read only the pages the
+// index points at; without the index they scan the table.
+func TestEngine_WriteByIndexReadsFewPages(t *testing.T) {
+	eng, fs := newBigTableEngine(t, 1000)
+
+	pagesRead := func(q string) (int64, *Result) {
+		before := fs.Metrics().PageReads
+		res := mustExec(t, eng, q)
+		return fs.Metrics().PageReads - before, res
+	}
+	scanned, res := pagesRead("UPDATE big SET pad = 'scan' WHERE id = 500;")
+	if res.RowsAffected != 1 || scanned < 20 {
+		t.Fatalf("UPDATE without an index changed %d rows reading %d pages", res.RowsAffected, scanned)
+	}
+
+	mustExec(t, eng, "CREATE INDEX idx_big_id ON big (id);")
+	for _, q := range []string{
+		"UPDATE big SET pad = 'one' WHERE id = 700;",
+		"UPDATE big SET id = id + 1000, pad = 'moved' WHERE id >= 10 AND id < 12;",
+		"DELETE FROM big WHERE id = 900;",
+		"DELETE FROM big WHERE id = 5000;",
+	} {
+		n, _ := pagesRead(q)
+		if n > 3 {
+			t.Errorf("%s read %d pages, want at most 3 (a scan reads %d)", q, n, scanned)
+		}
+	}
+
+	pad := strings.Repeat("x", 200)
+	for id, want := range map[int]string{700: "one", 10: "", 11: "", 1010: "moved", 1011: "moved", 900: "", 901: pad} {
+		res := mustExec(t, eng, fmt.Sprintf("SELECT pad FROM big WHERE id = %d;", id))
+		switch {
+		case want == "" && len(res.Rows) != 0:
+			t.Errorf("row %d still exists: %v", id, res.Rows)
+		case want != "" && (len(res.Rows) != 1 || res.Rows[0][0].S != want):
+			t.Errorf("row %d = %v, want pad %.10q", id, res.Rows, want)
+		}
+	}
+	if res := mustExec(t, eng, "SELECT COUNT(*) FROM big;"); res.Rows[0][0].I64 != 999 {
+		t.Fatalf("COUNT(*) = %v, want 999", res.Rows[0][0])
+	}
+}
+
+func BenchmarkUpdateByIndex(b *testing.B) {
+	for _, indexed := range []bool{false, true} {
+		b.Run(fmt.Sprintf("indexed=%v", indexed), func(b *testing.B) {
+			eng, _ := newBigTableEngine(b, 20000)
+			if indexed {
+				mustExec(b, eng, "CREATE INDEX idx_big_id ON big (id);")
+			}
+			stmt, err := sql.Parse("UPDATE big SET pad = 'y' WHERE id = 12345;")
+			if err != nil {
+				b.Fatalf("Parse failed: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := eng.Exec(stmt); err != nil {
+					b.Fatalf("Exec failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// An indexed ORDER BY column is read in index order on filestore; the
+// results must match the sorted output of memstore, which has no index.
+func TestEngine_ExecContextCanceled(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for _, eng := range []*DBEngine{New(memstore.New()), New(fs)} {
+		if err := eng.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+		mustExec(t, eng, "INSERT INTO users VALUES (1, 'a'), (2, 'b');")
+
+		stmt, _ := sql.Parse("SELECT * FROM users WHERE id > 0;")
+		res, err := eng.ExecContext(context.Background(), stmt)
+		if err != nil || len(res.Rows) != 2 {
+			t.Fatalf("ExecContext: %v, err %v", res, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, _, err := eng.ExecuteContext(ctx, stmt); !errors.Is(err, context.Canceled) {
+			t.Fatalf("ExecuteContext error = %v, want context.Canceled", err)
+		}
+		ins, _ := sql.Parse("INSERT INTO users VALUES (3, 'c');")
+		if _, err := eng.ExecContext(ctx, ins); !errors.Is(err, context.Canceled) {
+			t.Fatalf("ExecContext(INSERT) error = %v, want context.Canceled", err)
+		}
+		if n := len(mustExec(t, eng, "SELECT * FROM users;").Rows); n != 2 {
+			t.Fatalf("canceled INSERT ran: %d rows", n)
+		}
+	}
+}
+
+func TestEngine_TxModes(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for _, store := range []storage.Engine{memstore.New(), fs} {
+		a, b := New(store), New(store)
+		for _, eng := range []*DBEngine{a, b} {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+		}
+		mustExec(t, a, "CREATE TABLE t (id INT);")
+		mustExec(t, a, "INSERT INTO t VALUES (1);")
+
+		exec := func(eng *DBEngine, q string) error {
+			stmt, err := sql.Parse(q)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", q, err)
+			}
+			_, err = eng.Exec(stmt)
+			return err
+		}
+
+		// READ ONLY rejects writes and DDL but still reads.
+		mustExec(t, a, "BEGIN READ ONLY;")
+		for _, q := range []string{"INSERT INTO t VALUES (2);", "DELETE FROM t WHERE id = 1;", "CREATE TABLE u (id INT);"} {
+			if err := exec(a, q); err == nil || !strings.Contains(err.Error(), "READ ONLY") {
+				t.Fatalf("%s in READ ONLY transaction: err = %v", q, err)
+			}
+		}
+		if n := len(mustExec(t, a, "SELECT * FROM t;").Rows); n != 1 {
+			t.Fatalf("expected 1 row, got %d", n)
+		}
+		mustExec(t, a, "COMMIT;")
+
+		// IMMEDIATE reserves the store: other writers get ErrBusy until it
+		// ends, and it cannot start while another transaction has written.
+		mustExec(t, a, "BEGIN IMMEDIATE;")
+		if err := exec(b, "INSERT INTO t VALUES (3);"); !errors.Is(err, storage.ErrBusy) {
+			t.Fatalf("INSERT during IMMEDIATE transaction: err = %v, want ErrBusy", err)
+		}
+		if err := exec(b, "BEGIN IMMEDIATE;"); !errors.Is(err, storage.ErrBusy) {
+			t.Fatalf("second BEGIN IMMEDIATE: err = %v, want ErrBusy", err)
+		}
+		mustExec(t, a, "INSERT INTO t VALUES (2);")
+		if n := len(mustExec(t, b, "SELECT * FROM t;").Rows); n < 1 {
+			t.Fatalf("reads should not be blocked, got %d rows", n)
+		}
+		mustExec(t, a, "COMMIT;")
+
+		mustExec(t, b, "BEGIN;")
+		mustExec(t, b, "INSERT INTO t VALUES (3);")
+		if err := exec(a, "BEGIN IMMEDIATE;"); !errors.Is(err, storage.ErrBusy) {
+			t.Fatalf("BEGIN IMMEDIATE after another writer: err = %v, want ErrBusy", err)
+		}
+		mustExec(t, b, "COMMIT;")
+		mustExec(t, a, "BEGIN IMMEDIATE;")
+		mustExec(t, a, "ROLLBACK;")
+
+		if n := len(mustExec(t, a, "SELECT * FROM t;").Rows); n != 3 {
+			t.Fatalf("expected 3 rows, got %d", n)
+		}
+	}
+}
+
+func TestEngine_Select_OrderByIndexedColumn(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	engines := []*DBEngine{New(memstore.New()), New(fs)}
+	for _, eng := range engines {
+		if err := eng.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+		mustExec(t, eng, "INSERT INTO users VALUES (3, 'c'), (1, 'a'), (2, 'b1'), (5, 'e'), (2, 'b2'), (4, 'd');")
+		mustExec(t, eng, "UPDATE users SET id = 0 WHERE name = 'e';")
+		mustExec(t, eng, "DELETE FROM users WHERE id = 4;")
+	}
+	mustExec(t, engines[1], "CREATE INDEX idx_users_id ON users (id);")
+	mustExec(t, engines[1], "UPDATE users SET id = 6 WHERE name = 'c';")
+	mustExec(t, engines[0], "UPDATE users SET id = 6 WHERE name = 'c';")
+
+	for _, q := range []string{
+		"SELECT * FROM users ORDER BY id;",
+		"SELECT * FROM users ORDER BY ID DESC;",
+		"SELECT name FROM users WHERE id >= 1 ORDER BY id DESC LIMIT 2;",
+	} {
+		want := mustExec(t, engines[0], q).Rows
+		got := mustExec(t, engines[1], q).Rows
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s\nfilestore: %v\nmemstore:  %v", q, got, want)
+		}
+	}
+}
+
+func TestEngine_Select_IndexRange(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	engines := []*DBEngine{New(memstore.New()), New(fs)}
+	for _, eng := range engines {
+		if err := eng.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		mustExec(t, eng, "CREATE TABLE items (id INT, qty INT, name STRING);")
+		for i := 0; i < 300; i++ {
+			mustExec(t, eng, fmt.Sprintf("INSERT INTO items VALUES (%d, %d, 'item%d');", (i*37)%300, i%7, i))
+		}
+		mustExec(t, eng, "INSERT INTO items VALUES (NULL, 1, 'none');")
+		mustExec(t, eng, "DELETE FROM items WHERE id = 42;")
+		mustExec(t, eng, "UPDATE items SET id = 1000 WHERE name = 'item5';")
+	}
+	mustExec(t, engines[1], "CREATE INDEX idx_items_id ON items (id);")
+
+	for _, q := range []string{
+		"SELECT * FROM items WHERE id > 40 AND id < 60;",
+		"SELECT name FROM items WHERE id >= 100 AND id <= 110 AND qty = 3;",
+		"SELECT * FROM items WHERE id > 250 AND id < 2000 ORDER BY id DESC;",
+		"SELECT COUNT(*) FROM items WHERE id >= 0 AND id < 150;",
+		"SELECT * FROM items WHERE id > 60 AND id < 40;",
+		"SELECT * FROM items WHERE id > 10 AND qty < 2;",
+		"SELECT * FROM items WHERE qty > 1 AND qty < 3;",
+	} {
+		want := mustExec(t, engines[0], q).Rows
+		got := mustExec(t, engines[1], q).Rows
+		if (len(got) > 0 || len(want) > 0) && !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s\nfilestore: %v\nmemstore:  %v", q, got, want)
+		}
+	}
+
+	res := mustExec(t, engines[1], "SELECT id FROM items WHERE id > 40 AND id < 45;")
+	if len(res.Rows) != 3 {
+		t.Fatalf("expected ids 41, 43 and 44, got %v", res.Rows)
+	}
+}
+
 func TestEngine_Select_ErrorsOnUnknownWhereColumn(t *testing.T) {
 	store := memstore.New()
 	eng := New(store)
@@ -1076,3 +1399,338 @@ func TestEngineExecute_SelectCaseInsensitiveColumns(t *testing.T) {
 		t.Fatalf("NAME mismatch: %+v", row[1])
 	}
 }
+
+func TestEngineExec_RowsAffected(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	cases := []struct {
+		query string
+		want  int64
+	}{
+		{"CREATE TABLE users (id INT, active BOOL);", 0},
+		{"INSERT INTO users VALUES (1, true);", 1},
+		{"INSERT INTO users VALUES (2, false);", 1},
+		{"INSERT INTO users VALUES (3, false);", 1},
+		{"UPDATE users SET active = true WHERE id >= 2;", 2},
+		{"UPDATE users SET active = true WHERE id = 42;", 0},
+		{"DELETE FROM users WHERE id != 2;", 2},
+		{"SELECT * FROM users;", 0},
+	}
+	for _, tc := range cases {
+		stmt, err := sql.Parse(tc.query)
+		if err != nil {
+			t.Fatalf("Parse failed for %q: %v", tc.query, err)
+		}
+		res, err := eng.Exec(stmt)
+		if err != nil {
+			t.Fatalf("Exec failed for %q: %v", tc.query, err)
+		}
+		if res.RowsAffected != tc.want {
+			t.Fatalf("%q: RowsAffected = %d, want %d", tc.query, res.RowsAffected, tc.want)
+		}
+	}
+}
+
+// TestEngineExec_NoMatchSkipsRewrite checks that an UPDATE or DELETE whose
+// WHERE matches nothing writes nothing, so no record reaches the WAL.
+func TestEngineExec_NoMatchSkipsRewrite(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	eng := New(fs)
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE users (id INT, active BOOL);")
+	mustExec(t, eng, "INSERT INTO users VALUES (1, true), (2, false);")
+
+	changes, stop := fs.Subscribe()
+	defer stop()
+
+	for _, q := range []string{
+		"UPDATE users SET active = true WHERE id = 42;",
+		"DELETE FROM users WHERE id > 2;",
+	} {
+		if res := mustExec(t, eng, q); res.RowsAffected != 0 {
+			t.Fatalf("%q: RowsAffected = %d, want 0", q, res.RowsAffected)
+		}
+	}
+	mustExec(t, eng, "DELETE FROM users WHERE id = 2;")
+
+	// The first change in the feed must come from the DELETE that matched.
+	ch := <-changes
+	if ch.Kind != filestore.ChangeDelete || len(ch.Row) != 2 || ch.Row[0].I64 != 2 {
+		t.Fatalf("first change = %+v, want the DELETE of the matching row", ch)
+	}
+}
+
+func TestEngineExecute_MultiRowInsert(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	for _, q := range []string{
+		"CREATE TABLE users (id INT, name STRING);",
+		"INSERT INTO users (name, id) VALUES ('Alice', 1), ('Bob', 2), ('Carol', 3);",
+	} {
+		stmt, err := sql.Parse(q)
+		if err != nil {
+			t.Fatalf("Parse failed for %q: %v", q, err)
+		}
+		res, err := eng.Exec(stmt)
+		if err != nil {
+			t.Fatalf("Exec failed for %q: %v", q, err)
+		}
+		if _, ok := stmt.(*sql.InsertStmt); ok && res.RowsAffected != 3 {
+			t.Fatalf("RowsAffected = %d, want 3", res.RowsAffected)
+		}
+	}
+
+	cols, rows, err := eng.executeSelect("users")
+	if err != nil {
+		t.Fatalf("executeSelect failed: %v", err)
+	}
+	if !reflect.DeepEqual(cols, []string{"id", "name"}) || len(rows) != 3 {
+		t.Fatalf("unexpected result: %v %v", cols, rows)
+	}
+	if rows[2][0].I64 != 3 || rows[2][1].S != "Carol" {
+		t.Fatalf("unexpected third row: %v", rows[2])
+	}
+
+	// A bad row anywhere rejects the whole statement.
+	stmt, err := sql.Parse("INSERT INTO users VALUES (4, 'Dan'), (5, 6);")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, _, err := eng.Execute(stmt); err == nil {
+		t.Fatalf("expected type error for second row")
+	}
+	_, rows, _ = eng.executeSelect("users")
+	if len(rows) != 3 {
+		t.Fatalf("failed multi-row INSERT left %d rows, want 3", len(rows))
+	}
+}
+
+func TestEngine_Where_IsTrueFalse(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE users (id INT, active BOOL);")
+	mustExec(t, eng, "INSERT INTO users VALUES (1, true), (2, false), (3, NULL);")
+
+	ids := func(where string) []int64 {
+		t.Helper()
+		res := mustExec(t, eng, "SELECT id FROM users WHERE "+where+" ORDER BY id;")
+		var out []int64
+		for _, r := range res.Rows {
+			out = append(out, r[0].I64)
+		}
+		return out
+	}
+	for where, want := range map[string][]int64{
+		"active IS TRUE":      {1},
+		"active IS FALSE":     {2},
+		"active IS NOT TRUE":  {2, 3},
+		"active IS NOT FALSE": {1, 3},
+		"active IS NULL":      {3},
+		"active IS NOT NULL":  {1, 2},
+		"active = false":      {2},
+	} {
+		if got := ids(where); !reflect.DeepEqual(got, want) {
+			t.Errorf("WHERE %s = %v, want %v", where, got, want)
+		}
+	}
+
+	res := mustExec(t, eng, "UPDATE users SET active = true WHERE active IS NOT TRUE;")
+	if res.RowsAffected != 2 {
+		t.Fatalf("UPDATE affected %d rows, want 2", res.RowsAffected)
+	}
+	if got := ids("active IS TRUE"); len(got) != 3 {
+		t.Fatalf("after UPDATE, IS TRUE matched %v", got)
+	}
+}
+
+func TestEngine_CollateNoCase(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for name, eng := range map[string]*DBEngine{"memstore": New(memstore.NewWithDir(t.TempDir())), "filestore": New(fs)} {
+		t.Run(name, func(t *testing.T) {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE users (id INT, email STRING COLLATE NOCASE, name STRING);")
+			mustExec(t, eng, "INSERT INTO users VALUES (1, 'bob@x.io', 'bob'), (2, 'Alice@X.io', 'Alice'), (3, 'carol@x.io', 'Carol');")
+
+			ids := func(query string) []int64 {
+				t.Helper()
+				res := mustExec(t, eng, query)
+				var out []int64
+				for _, r := range res.Rows {
+					out = append(out, r[0].I64)
+				}
+				return out
+			}
+			for query, want := range map[string][]int64{
+				"SELECT id FROM users WHERE email = 'ALICE@x.io';":            {2},
+				"SELECT id FROM users WHERE email != 'BOB@X.IO' ORDER BY id;": {2, 3},
+				"SELECT id FROM users WHERE email > 'B' ORDER BY id;":         {1, 3},
+				"SELECT id FROM users ORDER BY email;":                        {2, 1, 3},
+				"SELECT id FROM users ORDER BY email DESC;":                   {3, 1, 2},
+				// A column without COLLATE NOCASE still compares bytes.
+				"SELECT id FROM users WHERE name = 'alice';": nil,
+				"SELECT id FROM users ORDER BY name;":        {2, 3, 1},
+			} {
+				if got := ids(query); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %v, want %v", query, got, want)
+				}
+			}
+
+			if res := mustExec(t, eng, "UPDATE users SET name = 'Bobby' WHERE email = 'BOB@X.IO';"); res.RowsAffected != 1 {
+				t.Fatalf("UPDATE affected %d rows, want 1", res.RowsAffected)
+			}
+			if res := mustExec(t, eng, "DELETE FROM users WHERE email = 'CAROL@X.IO';"); res.RowsAffected != 1 {
+				t.Fatalf("DELETE affected %d rows, want 1", res.RowsAffected)
+			}
+			if got := ids("SELECT id FROM users WHERE name = 'Bobby';"); !reflect.DeepEqual(got, []int64{1}) {
+				t.Fatalf("after UPDATE got %v", got)
+			}
+		})
+	}
+}
+
+func TestEngineExec_ErrorKinds(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for name, eng := range map[string]*DBEngine{"memstore": New(memstore.NewWithDir(t.TempDir())), "filestore": New(fs)} {
+		t.Run(name, func(t *testing.T) {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+			mustExec(t, eng, "INSERT INTO users VALUES (1, 'Ada');")
+
+			for query, want := range map[string]error{
+				"SELECT * FROM nope;":                          sql.ErrTableNotFound,
+				"INSERT INTO nope VALUES (1);":                 sql.ErrTableNotFound,
+				"DELETE FROM nope WHERE id = 1;":               sql.ErrTableNotFound,
+				"SELECT email FROM users;":                     sql.ErrColumnNotFound,
+				"SELECT * FROM users WHERE email = 'x';":       sql.ErrColumnNotFound,
+				"UPDATE users SET email = 'x' WHERE id = 1;":   sql.ErrColumnNotFound,
+				"CREATE INDEX idx ON users (email);":           sql.ErrColumnNotFound,
+				"INSERT INTO users VALUES ('1', 'Ada');":       sql.ErrTypeMismatch,
+				"SELECT -name FROM users;":                     sql.ErrTypeMismatch,
+				"SELECT SUM(name) FROM users;":                 sql.ErrTypeMismatch,
+				"CREATE TABLE t (id INT REFERENCES nope(id));": sql.ErrTableNotFound,
+			} {
+				if err := execErr(t, eng, query); !errors.Is(err, want) {
+					t.Errorf("%s: got error %v, want one wrapping %v", query, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEngineExec_ColumnTypes(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE users (id INT, name STRING, score FLOAT, active BOOL);")
+	mustExec(t, eng, "INSERT INTO users VALUES (1, 'Ada', 1.5, true);")
+
+	for query, want := range map[string][]ColumnType{
+		"SELECT * FROM users;": {
+			{"id", sql.TypeInt}, {"name", sql.TypeString}, {"score", sql.TypeFloat}, {"active", sql.TypeBool},
+		},
+		"SELECT -score, name || '!', COALESCE(NULL, id), ROUND(score), NULL FROM users;": {
+			{"-score", sql.TypeFloat}, {"name || '!'", sql.TypeString}, {"COALESCE(NULL, id)", sql.TypeInt},
+			{"ROUND(score)", sql.TypeFloat}, {"NULL", sql.TypeNull},
+		},
+		"SELECT active, COUNT(*), SUM(score), MIN(id) FROM users GROUP BY active;": {
+			{"active", sql.TypeBool}, {"COUNT(*)", sql.TypeInt}, {"SUM(score)", sql.TypeFloat}, {"MIN(id)", sql.TypeInt},
+		},
+		"SELECT COUNT(*) FROM users;": {{"COUNT(*)", sql.TypeInt}},
+	} {
+		res := mustExec(t, eng, query)
+		if !reflect.DeepEqual(res.ColumnTypes, want) {
+			t.Errorf("%s: column types = %v, want %v", query, res.ColumnTypes, want)
+		}
+	}
+	if res := mustExec(t, eng, "INSERT INTO users VALUES (2, 'Bo', 2.5, false);"); res.ColumnTypes != nil {
+		t.Fatalf("INSERT returned column types %v", res.ColumnTypes)
+	}
+}
+
+// TestEngine_SelectSameInsideTransaction runs the same SELECTs outside and
+// inside an explicit transaction and expects identical results, including
+// for rows the transaction wrote itself.
+func TestEngine_SelectSameInsideTransaction(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for name, eng := range map[string]*DBEngine{"memstore": New(memstore.NewWithDir(t.TempDir())), "filestore": New(fs)} {
+		t.Run(name, func(t *testing.T) {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE users (id INT, name STRING, score FLOAT);")
+			mustExec(t, eng, "CREATE INDEX idx ON users (id);")
+			mustExec(t, eng, "INSERT INTO users VALUES (3, 'c', 1.5), (1, 'a', 3.0), (5, 'e', NULL), (2, 'b', 2.5), (4, 'd', 0.5);")
+
+			queries := []string{
+				"SELECT * FROM users;",
+				"SELECT name FROM users WHERE id > 1 AND id <= 4;",
+				"SELECT id, name FROM users WHERE score >= 1.0 ORDER BY score DESC LIMIT 2;",
+				"SELECT * FROM users ORDER BY id LIMIT 3;",
+				"SELECT id FROM users ORDER BY id DESC;",
+				"SELECT COUNT(*) FROM users;",
+				"SELECT COUNT(*), MAX(score) FROM users WHERE id < 5;",
+				"SELECT UPPER(name) FROM users WHERE id != 3 ORDER BY name LIMIT 10;",
+			}
+			run := func() []*Result {
+				t.Helper()
+				out := make([]*Result, len(queries))
+				for i, q := range queries {
+					out[i] = mustExec(t, eng, q)
+				}
+				return out
+			}
+			compare := func(what string, got, want []*Result) {
+				t.Helper()
+				for i, q := range queries {
+					if !reflect.DeepEqual(got[i], want[i]) {
+						t.Errorf("%s: %s\n got  %v\n want %v", what, q, got[i], want[i])
+					}
+				}
+			}
+
+			outside := run()
+			mustExec(t, eng, "BEGIN;")
+			compare("inside a transaction", run(), outside)
+			mustExec(t, eng, "COMMIT;")
+
+			// Rows written by the transaction show up inside it exactly as
+			// they do after it commits.
+			mustExec(t, eng, "BEGIN;")
+			mustExec(t, eng, "INSERT INTO users VALUES (0, 'z', 9.0), (6, 'f', 1.0);")
+			mustExec(t, eng, "DELETE FROM users WHERE id = 2;")
+			inside := run()
+			if _, rows, err := eng.executeSelect("users"); err != nil || len(rows) != len(inside[0].Rows) {
+				t.Fatalf("executeSelect inside the transaction returned %d rows (%v), want %d", len(rows), err, len(inside[0].Rows))
+			}
+			mustExec(t, eng, "COMMIT;")
+			compare("after the transaction's own writes", inside, run())
+		})
+	}
+}
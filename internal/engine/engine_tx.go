@@ -1,8 +1,15 @@
 package engine
 
-import "fmt"
+import (
+	"fmt"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
 
-func (e *DBEngine) beginTx() error {
+// beginTx starts an explicit transaction in the given access mode. READ
+// ONLY transactions are read-only storage transactions, and IMMEDIATE ones
+// need a store that implements storage.ImmediateBeginner.
+func (e *DBEngine) beginTx(mode sql.TxMode) error {
 	if !e.started {
 		return fmt.Errorf("engine not started")
 	}
@@ -10,13 +17,27 @@ func (e *DBEngine) beginTx() error {
 		return fmt.Errorf("transaction already in progress")
 	}
 
-	tx, err := e.store.Begin(false) // writeable transaction
+	var tx storage.Tx
+	var err error
+	switch mode {
+	case sql.TxReadOnly:
+		tx, err = e.store.Begin(true)
+	case sql.TxImmediate:
+		ib, ok := e.store.(storage.ImmediateBeginner)
+		if !ok {
+			return fmt.Errorf("BEGIN IMMEDIATE is not supported by this storage engine")
+		}
+		tx, err = ib.BeginImmediate()
+	default:
+		tx, err = e.store.Begin(false) // writeable transaction
+	}
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 
 	e.currTx = tx
 	e.inTx = true
+	e.txMode = mode
 	return nil
 }
 
@@ -3,44 +3,82 @@ package engine
 import (
 	"fmt"
 	"goDB/internal/sql"
+	"goDB/internal/storage"
 	"strings"
+	"time"
 )
 
-// applyUpdate returns a new rowset where all rows matching WHERE are updated
-// according to assignments. It returns the updated rows and the count of affected
-// rows. Column lookups are resolved once up front to avoid repeated map access
-// inside the loop.
-func applyUpdate(cols []string, rows []sql.Row, where *sql.WhereExpr, assigns []sql.Assignment) ([]sql.Row, int, error) {
-	colIndex := make(map[string]int, len(cols))
-	for i, name := range cols {
-		colIndex[strings.ToLower(name)] = i
-	}
-
-	whereIdx, ok := colIndex[strings.ToLower(where.Column)]
-	if !ok {
-		return nil, 0, fmt.Errorf("UPDATE: unknown column %q in WHERE", where.Column)
+// applyUpdate returns a new rowset where all rows for which match holds are
+// updated according to assignments. It returns the updated rows and the count
+// of affected rows. Column lookups are resolved once up front to avoid
+// repeated map access inside the loop. A DEFAULT assignment stores the
+// column's default, taking now for DEFAULT CURRENT_TIMESTAMP. Expression
+// assignments are compiled against cols, the column names of rows, and
+// all evaluated against the row before any of them is stored.
+func applyUpdate(schema []sql.Column, cols []string, rows []sql.Row, match storage.RowPredicate, assigns []sql.Assignment, now time.Time) ([]sql.Row, int, error) {
+	colIndex := make(map[string]int, len(schema))
+	for i, c := range schema {
+		colIndex[strings.ToLower(c.Name)] = i
 	}
 
 	assignIdx := make([]int, len(assigns))
+	values := make([]sql.Value, len(assigns))
+	exprs := make([]evalFunc, len(assigns))
 	for i, a := range assigns {
 		idx, ok := colIndex[strings.ToLower(a.Column)]
 		if !ok {
-			return nil, 0, fmt.Errorf("UPDATE: unknown column %q in SET list", a.Column)
+			return nil, 0, fmt.Errorf("UPDATE: %w %q in SET list", sql.ErrColumnNotFound, a.Column)
 		}
 		assignIdx[i] = idx
+		if a.Expr != nil {
+			fn, err := compileExpr(a.Expr, cols)
+			if err != nil {
+				return nil, 0, fmt.Errorf("UPDATE: %s: %w", a.Column, err)
+			}
+			exprs[i] = fn
+			continue
+		}
+		values[i] = a.Value
+		if a.Default {
+			values[i] = columnDefault(schema[idx], now)
+		}
+		if values[i].Type == sql.TypeNull && schema[idx].NotNull {
+			return nil, 0, fmt.Errorf("UPDATE: %s: %w", describeColumn(schema, idx), sql.ErrNotNull)
+		}
 	}
 
 	newRows := make([]sql.Row, len(rows))
 	affected := 0
+	rowValues := make([]sql.Value, len(assigns))
 
 	for i, r := range rows {
 		newRow := make(sql.Row, len(r))
 		copy(newRow, r)
 
-		if conditionMatches(newRow[whereIdx], where.Op, where.Value) {
-			for j, a := range assigns {
-				idx := assignIdx[j]
-				newRow[idx] = a.Value
+		ok, err := match(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			for j, idx := range assignIdx {
+				if exprs[j] == nil {
+					rowValues[j] = values[j]
+					continue
+				}
+				v, err := exprs[j](r)
+				if err != nil {
+					return nil, 0, fmt.Errorf("UPDATE: %s: %w", assigns[j].Column, err)
+				}
+				if v.Type != sql.TypeNull && v.Type != schema[idx].Type {
+					return nil, 0, fmt.Errorf("UPDATE: %s: %w: expected %s, got %s", describeColumn(schema, idx), sql.ErrTypeMismatch, schema[idx].Type, v.Type)
+				}
+				if v.Type == sql.TypeNull && schema[idx].NotNull {
+					return nil, 0, fmt.Errorf("UPDATE: %s: %w", describeColumn(schema, idx), sql.ErrNotNull)
+				}
+				rowValues[j] = v
+			}
+			for j, idx := range assignIdx {
+				newRow[idx] = rowValues[j]
 			}
 			affected++
 		}
@@ -51,29 +89,32 @@ func applyUpdate(cols []string, rows []sql.Row, where *sql.WhereExpr, assigns []
 	return newRows, affected, nil
 }
 
-// applyDelete returns a new rowset where all rows matching WHERE are removed.
-// It returns the new rows and the count of deleted rows.
-func applyDelete(cols []string, rows []sql.Row, where *sql.WhereExpr) ([]sql.Row, int, error) {
-	colIndex := make(map[string]int, len(cols))
-	for i, name := range cols {
-		colIndex[strings.ToLower(name)] = i
+// rowsEqual reports whether two rows hold identical values.
+func rowsEqual(a, b sql.Row) bool {
+	if len(a) != len(b) {
+		return false
 	}
-
-	whereIdx, ok := colIndex[strings.ToLower(where.Column)]
-	if !ok {
-		return nil, 0, fmt.Errorf("DELETE: unknown column %q in WHERE", where.Column)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
 	}
+	return true
+}
 
+// applyDelete returns a new rowset where all rows for which match holds are
+// removed. It returns the remaining rows and the deleted ones.
+func applyDelete(rows []sql.Row, match storage.RowPredicate) ([]sql.Row, []sql.Row, error) {
 	out := make([]sql.Row, 0, len(rows))
-	deleted := 0
+	var deleted []sql.Row
 
 	for _, r := range rows {
-		if whereIdx < 0 || whereIdx >= len(r) {
-			out = append(out, r)
-			continue
+		ok, err := match(r)
+		if err != nil {
+			return nil, nil, err
 		}
-		if conditionMatches(r[whereIdx], where.Op, where.Value) {
-			deleted++
+		if ok {
+			deleted = append(deleted, r)
 			continue
 		}
 		out = append(out, r)
@@ -0,0 +1,339 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// evalFunc evaluates a compiled expression against one row.
+type evalFunc func(row sql.Row) (sql.Value, error)
+
+// compileExpr resolves the column references of an expression against the
+// given column names and returns a function evaluating it per row.
+func compileExpr(expr sql.Expr, cols []string) (evalFunc, error) {
+	switch e := expr.(type) {
+	case *sql.Literal:
+		v := e.Value
+		return func(sql.Row) (sql.Value, error) { return v, nil }, nil
+
+	case *sql.ColumnRef:
+		idx := -1
+		for i, name := range cols {
+			if strings.EqualFold(name, e.Name) {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			return nil, fmt.Errorf("%w %q", sql.ErrColumnNotFound, e.Name)
+		}
+		return func(row sql.Row) (sql.Value, error) {
+			if idx >= len(row) {
+				return sql.Value{}, fmt.Errorf("internal error: column index %d out of range", idx)
+			}
+			return row[idx], nil
+		}, nil
+
+	case *sql.BinaryExpr:
+		left, err := compileExpr(e.Left, cols)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compileExpr(e.Right, cols)
+		if err != nil {
+			return nil, err
+		}
+		op := e.Op
+		if op == "||" {
+			return func(row sql.Row) (sql.Value, error) {
+				a, err := left(row)
+				if err != nil {
+					return sql.Value{}, err
+				}
+				b, err := right(row)
+				if err != nil {
+					return sql.Value{}, err
+				}
+				return concatValues(a, b), nil
+			}, nil
+		}
+		if isArithmetic(op) {
+			return func(row sql.Row) (sql.Value, error) {
+				a, err := left(row)
+				if err != nil {
+					return sql.Value{}, err
+				}
+				b, err := right(row)
+				if err != nil {
+					return sql.Value{}, err
+				}
+				return arithmeticValues(a, op, b)
+			}, nil
+		}
+		return func(row sql.Row) (sql.Value, error) {
+			a, err := left(row)
+			if err != nil {
+				return sql.Value{}, err
+			}
+			b, err := right(row)
+			if err != nil {
+				return sql.Value{}, err
+			}
+			return sql.Value{Type: sql.TypeBool, B: conditionMatches(a, op, b)}, nil
+		}, nil
+
+	case *sql.CastExpr:
+		inner, err := compileExpr(e.Expr, cols)
+		if err != nil {
+			return nil, err
+		}
+		t := e.Type
+		return func(row sql.Row) (sql.Value, error) {
+			v, err := inner(row)
+			if err != nil {
+				return sql.Value{}, err
+			}
+			return castValue(v, t)
+		}, nil
+
+	case *sql.UnaryExpr:
+		inner, err := compileExpr(e.Expr, cols)
+		if err != nil {
+			return nil, err
+		}
+		return func(row sql.Row) (sql.Value, error) {
+			v, err := inner(row)
+			if err != nil {
+				return sql.Value{}, err
+			}
+			return negateValue(v)
+		}, nil
+
+	case *sql.FuncCall:
+		return compileCall(e, cols)
+
+	case *sql.CaseExpr:
+		return compileCase(e, cols)
+
+	default:
+		return nil, fmt.Errorf("unsupported expression %T", expr)
+	}
+}
+
+// compileCase compiles a CASE expression. A searched CASE takes the first
+// WHEN whose condition is TRUE; NULL counts as not true, and any other
+// non-BOOL condition is an error. A simple CASE takes the first WHEN whose
+// value equals the operand, so a NULL operand matches nothing.
+func compileCase(c *sql.CaseExpr, cols []string) (evalFunc, error) {
+	var operand evalFunc
+	if c.Operand != nil {
+		fn, err := compileExpr(c.Operand, cols)
+		if err != nil {
+			return nil, err
+		}
+		operand = fn
+	}
+	whens := make([]evalFunc, len(c.Whens))
+	thens := make([]evalFunc, len(c.Whens))
+	for i, w := range c.Whens {
+		var err error
+		if whens[i], err = compileExpr(w.When, cols); err != nil {
+			return nil, err
+		}
+		if thens[i], err = compileExpr(w.Then, cols); err != nil {
+			return nil, err
+		}
+	}
+	elseFn := func(sql.Row) (sql.Value, error) { return sql.Value{Type: sql.TypeNull}, nil }
+	if c.Else != nil {
+		fn, err := compileExpr(c.Else, cols)
+		if err != nil {
+			return nil, err
+		}
+		elseFn = fn
+	}
+
+	return func(row sql.Row) (sql.Value, error) {
+		var subject sql.Value
+		if operand != nil {
+			v, err := operand(row)
+			if err != nil {
+				return sql.Value{}, err
+			}
+			subject = v
+		}
+		for i, when := range whens {
+			v, err := when(row)
+			if err != nil {
+				return sql.Value{}, err
+			}
+			var matched bool
+			if operand != nil {
+				matched = conditionMatches(subject, "=", v)
+			} else {
+				switch v.Type {
+				case sql.TypeBool:
+					matched = v.B
+				case sql.TypeNull:
+				default:
+					return sql.Value{}, fmt.Errorf("CASE: %w: WHEN condition is %s, not BOOL", sql.ErrTypeMismatch, v.Type)
+				}
+			}
+			if matched {
+				return thens[i](row)
+			}
+		}
+		return elseFn(row)
+	}, nil
+}
+
+// negateValue implements unary minus. NULL stays NULL; only INT and FLOAT
+// values can be negated.
+func negateValue(v sql.Value) (sql.Value, error) {
+	switch v.Type {
+	case sql.TypeNull:
+		return v, nil
+	case sql.TypeInt:
+		return sql.Value{Type: sql.TypeInt, I64: -v.I64}, nil
+	case sql.TypeFloat:
+		return sql.Value{Type: sql.TypeFloat, F64: -v.F64}, nil
+	default:
+		return sql.Value{}, fmt.Errorf("%w: cannot negate a %s value", sql.ErrTypeMismatch, v.Type)
+	}
+}
+
+// isArithmetic reports whether op is an arithmetic binary operator.
+func isArithmetic(op string) bool {
+	switch op {
+	case "+", "-", "*", "/", "%":
+		return true
+	}
+	return false
+}
+
+// arithmeticValues implements the arithmetic operators. Two INT operands
+// give an INT, with / truncating toward zero; an INT and a FLOAT give a
+// FLOAT. NULL yields NULL, and dividing by zero is an error.
+func arithmeticValues(a sql.Value, op string, b sql.Value) (sql.Value, error) {
+	if a.Type == sql.TypeNull || b.Type == sql.TypeNull {
+		return sql.Value{Type: sql.TypeNull}, nil
+	}
+	for _, v := range []sql.Value{a, b} {
+		if v.Type != sql.TypeInt && v.Type != sql.TypeFloat {
+			return sql.Value{}, fmt.Errorf("%w: cannot apply %s to a %s value", sql.ErrTypeMismatch, op, v.Type)
+		}
+	}
+
+	if a.Type == sql.TypeInt && b.Type == sql.TypeInt {
+		x, y := a.I64, b.I64
+		var r int64
+		switch op {
+		case "+":
+			r = x + y
+		case "-":
+			r = x - y
+		case "*":
+			r = x * y
+		case "/", "%":
+			if y == 0 {
+				return sql.Value{}, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				r = x / y
+			} else {
+				r = x % y
+			}
+		}
+		return sql.Value{Type: sql.TypeInt, I64: r}, nil
+	}
+
+	x, y := numericFloat(a), numericFloat(b)
+	var r float64
+	switch op {
+	case "+":
+		r = x + y
+	case "-":
+		r = x - y
+	case "*":
+		r = x * y
+	case "/", "%":
+		if y == 0 {
+			return sql.Value{}, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			r = x / y
+		} else {
+			r = math.Mod(x, y)
+		}
+	}
+	return sql.Value{Type: sql.TypeFloat, F64: r}, nil
+}
+
+// numericFloat returns an INT or FLOAT value as a float64.
+func numericFloat(v sql.Value) float64 {
+	if v.Type == sql.TypeInt {
+		return float64(v.I64)
+	}
+	return v.F64
+}
+
+// concatValues implements the || operator. Non-string operands are
+// converted to their display form (42, 1.5, true) first. As in standard
+// SQL, concatenating NULL yields NULL.
+func concatValues(a, b sql.Value) sql.Value {
+	if a.Type == sql.TypeNull || b.Type == sql.TypeNull {
+		return sql.Value{Type: sql.TypeNull}
+	}
+	return sql.Value{Type: sql.TypeString, S: displayString(a) + displayString(b)}
+}
+
+// displayString returns the text form of a non-NULL value.
+func displayString(v sql.Value) string {
+	switch v.Type {
+	case sql.TypeInt:
+		return strconv.FormatInt(v.I64, 10)
+	case sql.TypeFloat:
+		return strconv.FormatFloat(v.F64, 'g', -1, 64)
+	case sql.TypeBool:
+		return strconv.FormatBool(v.B)
+	case sql.TypeTimestamp:
+		return sql.FormatTimestamp(v)
+	case sql.TypeBytes:
+		return sql.FormatBlob(v)
+	default:
+		return v.S
+	}
+}
+
+// projectExprs evaluates one expression per output column for every row.
+func projectExprs(allCols []string, rows []sql.Row, names []string, exprs []sql.Expr) ([]string, []sql.Row, error) {
+	fns := make([]evalFunc, len(exprs))
+	for i, e := range exprs {
+		fn, err := compileExpr(e, allCols)
+		if err != nil {
+			return nil, nil, fmt.Errorf("SELECT list: %w", err)
+		}
+		fns[i] = fn
+	}
+
+	outCols := make([]string, len(names))
+	copy(outCols, names)
+
+	outRows := make([]sql.Row, 0, len(rows))
+	for _, r := range rows {
+		proj := make(sql.Row, len(fns))
+		for i, fn := range fns {
+			v, err := fn(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			proj[i] = v
+		}
+		outRows = append(outRows, proj)
+	}
+	return outCols, outRows, nil
+}
@@ -0,0 +1,25 @@
+package engine
+
+import (
+	"fmt"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// executeAlterTable runs ALTER TABLE ... RENAME COLUMN. The storage engine
+// renames the column together with its index and the foreign keys that
+// reference it. Like CREATE TABLE AS, it is rejected inside a transaction,
+// whose snapshot would still use the old column name.
+func (e *DBEngine) executeAlterTable(s *sql.AlterTableStmt) error {
+	if e.inTx {
+		return fmt.Errorf("ALTER TABLE cannot run inside a transaction")
+	}
+	renamer, ok := e.store.(storage.ColumnRenamer)
+	if !ok {
+		return fmt.Errorf("renaming columns is not supported by this storage engine")
+	}
+	if err := renamer.RenameColumn(s.TableName, s.Column, s.NewName); err != nil {
+		return fmt.Errorf("ALTER TABLE %s: %w", s.TableName, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngineExecute_RenameColumn(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for name, eng := range map[string]*DBEngine{"memstore": New(memstore.NewWithDir(t.TempDir())), "filestore": New(fs)} {
+		t.Run(name, func(t *testing.T) {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+			mustExec(t, eng, "CREATE TABLE orders (id INT, buyer INT REFERENCES users(id));")
+			mustExec(t, eng, "INSERT INTO users VALUES (1, 'Ada'), (2, 'Alan'), (3, 'Grace');")
+			mustExec(t, eng, "INSERT INTO orders VALUES (10, 1);")
+			mustExec(t, eng, "CREATE INDEX idx_users_id ON users (id);")
+
+			mustExec(t, eng, "ALTER TABLE users RENAME COLUMN id TO uid;")
+
+			res := mustExec(t, eng, "SELECT name FROM users WHERE uid >= 2 AND uid <= 3;")
+			if len(res.Rows) != 2 || res.Rows[0][0].S != "Alan" {
+				t.Fatalf("range query after rename returned %v", res.Rows)
+			}
+			if cols, err := eng.IndexedColumns("users"); err != nil || len(cols) != 1 || cols[0] != "uid" {
+				t.Fatalf("IndexedColumns = %v, %v; want [uid]", cols, err)
+			}
+			if err := execErr(t, eng, "SELECT * FROM users WHERE id = 1;"); err == nil {
+				t.Fatalf("the old column name should be gone")
+			}
+
+			// The foreign key of orders follows the rename.
+			mustExec(t, eng, "INSERT INTO orders VALUES (11, 3);")
+			if err := execErr(t, eng, "INSERT INTO orders VALUES (12, 9);"); err == nil {
+				t.Fatalf("expected a foreign key violation after the rename")
+			}
+			if err := execErr(t, eng, "DELETE FROM users WHERE uid = 1;"); err == nil {
+				t.Fatalf("expected deleting a referenced row to fail after the rename")
+			}
+
+			for query, want := range map[string]string{
+				"ALTER TABLE users RENAME COLUMN uid TO name;": "already exists",
+				"ALTER TABLE users RENAME COLUMN id TO x;":     `unknown column "id"`,
+				"ALTER TABLE nope RENAME COLUMN id TO x;":      "nope",
+			} {
+				if err := execErr(t, eng, query); err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("%s: got error %v, want one containing %q", query, err, want)
+				}
+			}
+
+			mustExec(t, eng, "BEGIN;")
+			if err := execErr(t, eng, "ALTER TABLE users RENAME COLUMN name TO label;"); err == nil {
+				t.Fatalf("expected ALTER TABLE to fail inside a transaction")
+			}
+			mustExec(t, eng, "ROLLBACK;")
+		})
+	}
+}
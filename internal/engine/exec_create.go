@@ -1,8 +1,10 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"goDB/internal/sql"
+	"strings"
 )
 
 // CreateTable creates a new table in the underlying storage engine.
@@ -10,5 +12,100 @@ func (e *DBEngine) CreateTable(name string, cols []sql.Column) error {
 	if !e.started {
 		return fmt.Errorf("engine not started")
 	}
+	if err := e.validateForeignKeys(name, cols); err != nil {
+		return err
+	}
 	return e.store.CreateTable(name, cols)
 }
+
+// createTableAs runs CREATE TABLE name AS SELECT ... and returns the number
+// of rows copied. The new table has one column per selected column, with
+// the name used in the SELECT list and the type selectSchema derives from
+// the query, so an empty result still gives a complete schema. Defaults
+// and foreign keys are not copied.
+//
+// The table is created first; the SELECT and the inserts then run in one
+// transaction, so the table either gets every row or stays empty. Storage engines
+// do not let a transaction see tables created after it began, so this is
+// rejected inside an explicit transaction.
+func (e *DBEngine) createTableAs(ctx context.Context, s *sql.CreateTableStmt) (int, error) {
+	if e.inTx {
+		return 0, fmt.Errorf("CREATE TABLE AS cannot run inside a transaction")
+	}
+
+	cols, err := e.selectSchema(s.AsSelect)
+	if err != nil {
+		return 0, fmt.Errorf("CREATE TABLE %s AS: %w", s.TableName, err)
+	}
+	if err := e.CreateTable(s.TableName, cols); err != nil {
+		return 0, err
+	}
+
+	sel, err := e.expandStar(s.AsSelect)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := e.store.Begin(false)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	_, rows, err := e.selectInTx(ctx, tx, sel)
+	for i := 0; err == nil && i < len(rows); i++ {
+		err = checkInsertTypes(cols, rows[i])
+	}
+	if err == nil && len(rows) > 0 {
+		err = tx.InsertBatch(s.TableName, rows)
+	}
+	if err != nil {
+		_ = e.store.Rollback(tx)
+		return 0, err
+	}
+	if err := e.store.Commit(tx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return len(rows), nil
+}
+
+// selectSchema returns the columns of the result of a SELECT, checking the
+// query first. Columns are named like the output columns and typed by
+// selectColumnTypes, without reading rows: a computed column must be named
+// with AS, and one whose type depends on the rows, such as NULL, must be
+// given a type with CAST.
+func (e *DBEngine) selectSchema(s *sql.SelectStmt) ([]sql.Column, error) {
+	s, err := e.expandStar(s)
+	if err != nil {
+		return nil, err
+	}
+	names, _, err := e.tableColumns(s.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSelect(withRowID(names), s); err != nil {
+		return nil, err
+	}
+
+	outNames := s.Columns
+	if len(outNames) == 0 {
+		outNames = names
+	}
+	types, err := e.selectColumnTypes(s, outNames, nil)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]sql.Column, len(types))
+	for i, ct := range types {
+		if !sql.IsIdentifier(ct.Name) {
+			return nil, fmt.Errorf("column %q is an expression; name it with AS", ct.Name)
+		}
+		if ct.Type == sql.TypeNull {
+			return nil, fmt.Errorf("the type of column %q depends on its values; give it one with CAST", ct.Name)
+		}
+		for _, prev := range cols[:i] {
+			if strings.EqualFold(prev.Name, ct.Name) {
+				return nil, fmt.Errorf("column %q is selected twice", ct.Name)
+			}
+		}
+		cols[i] = sql.Column{Name: ct.Name, Type: ct.Type}
+	}
+	return cols, nil
+}
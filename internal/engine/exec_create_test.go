@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngineExecute_CreateTableAs(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for name, eng := range map[string]*DBEngine{"memstore": New(memstore.New()), "filestore": New(fs)} {
+		t.Run(name, func(t *testing.T) {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE users (id INT, name STRING DEFAULT 'x', active BOOL);")
+			mustExec(t, eng, "INSERT INTO users VALUES (1, 'Ada', true), (2, 'Alan', false), (3, 'Grace', true);")
+
+			res := mustExec(t, eng, "CREATE TABLE active_users AS SELECT id, NAME FROM users WHERE active = true ORDER BY id DESC;")
+			if res.RowsAffected != 2 {
+				t.Fatalf("RowsAffected = %d, want 2", res.RowsAffected)
+			}
+			schema, err := eng.TableSchema("active_users")
+			if err != nil {
+				t.Fatalf("TableSchema failed: %v", err)
+			}
+			wantSchema := []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "NAME", Type: sql.TypeString}}
+			if !reflect.DeepEqual(schema, wantSchema) {
+				t.Fatalf("schema = %+v, want %+v", schema, wantSchema)
+			}
+			rows := mustExec(t, eng, "SELECT * FROM active_users;").Rows
+			want := []sql.Row{
+				{{Type: sql.TypeInt, I64: 3}, {Type: sql.TypeString, S: "Grace"}},
+				{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "Ada"}},
+			}
+			if !reflect.DeepEqual(rows, want) {
+				t.Fatalf("rows = %v, want %v", rows, want)
+			}
+
+			// An empty result still yields the source column types.
+			mustExec(t, eng, "CREATE TABLE nobody AS SELECT * FROM users WHERE id > 100;")
+			schema, err = eng.TableSchema("nobody")
+			if err != nil {
+				t.Fatalf("TableSchema failed: %v", err)
+			}
+			if len(schema) != 3 || schema[2].Name != "active" || schema[2].Type != sql.TypeBool || schema[1].Default != nil {
+				t.Fatalf("unexpected schema of empty copy: %+v", schema)
+			}
+			if n := len(mustExec(t, eng, "SELECT * FROM nobody;").Rows); n != 0 {
+				t.Fatalf("expected an empty table, got %d rows", n)
+			}
+
+			// Computed columns take the type of their expression, even
+			// with no rows to look at.
+			res = mustExec(t, eng, "CREATE TABLE derived AS SELECT id + 1 AS next, UPPER(name) AS up, CAST(NULL AS FLOAT) AS f FROM users WHERE id = 1;")
+			if res.RowsAffected != 1 {
+				t.Fatalf("RowsAffected = %d, want 1", res.RowsAffected)
+			}
+			mustExec(t, eng, "CREATE TABLE counts AS SELECT active, COUNT(*) AS n FROM users WHERE id > 100 GROUP BY active;")
+			for table, want := range map[string][]sql.Column{
+				"derived": {{Name: "next", Type: sql.TypeInt}, {Name: "up", Type: sql.TypeString}, {Name: "f", Type: sql.TypeFloat}},
+				"counts":  {{Name: "active", Type: sql.TypeBool}, {Name: "n", Type: sql.TypeInt}},
+			} {
+				schema, err := eng.TableSchema(table)
+				if err != nil {
+					t.Fatalf("TableSchema failed: %v", err)
+				}
+				if !reflect.DeepEqual(schema, want) {
+					t.Fatalf("schema of %s = %+v, want %+v", table, schema, want)
+				}
+			}
+			rows = mustExec(t, eng, "SELECT * FROM derived;").Rows
+			want = []sql.Row{{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeString, S: "ADA"}, {Type: sql.TypeNull}}}
+			if !reflect.DeepEqual(rows, want) {
+				t.Fatalf("rows = %v, want %v", rows, want)
+			}
+
+			for query, want := range map[string]string{
+				"CREATE TABLE bad AS SELECT UPPER(name) FROM users;":       "name it with AS",
+				"CREATE TABLE bad AS SELECT NULL AS n FROM users;":         "CAST",
+				"CREATE TABLE bad AS SELECT id, ID FROM users;":            "selected twice",
+				"CREATE TABLE bad AS SELECT missing FROM users;":           "unknown column",
+				"CREATE TABLE bad AS SELECT id + missing AS x FROM users;": "unknown column",
+				"CREATE TABLE active_users AS SELECT id FROM users;":       "exists",
+				"CREATE TABLE bad AS SELECT id FROM no_such_table;":        "",
+			} {
+				err := execErr(t, eng, query)
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("%s: got error %v, want one containing %q", query, err, want)
+				}
+			}
+
+			mustExec(t, eng, "BEGIN;")
+			if err := execErr(t, eng, "CREATE TABLE inner AS SELECT id FROM users;"); err == nil {
+				t.Fatalf("expected CREATE TABLE AS to fail inside a transaction")
+			}
+			mustExec(t, eng, "ROLLBACK;")
+		})
+	}
+}
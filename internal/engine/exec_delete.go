@@ -6,9 +6,10 @@ import (
 	"goDB/internal/storage"
 )
 
-func (e *DBEngine) executeDelete(stmt *sql.DeleteStmt) error {
+// executeDelete runs a DELETE and returns the number of rows it removed.
+func (e *DBEngine) executeDelete(stmt *sql.DeleteStmt) (int, error) {
 	if stmt.Where == nil {
-		return fmt.Errorf("DELETE without WHERE is not supported yet")
+		return 0, fmt.Errorf("DELETE without WHERE is not supported yet")
 	}
 
 	if e.inTx {
@@ -17,33 +18,67 @@ func (e *DBEngine) executeDelete(stmt *sql.DeleteStmt) error {
 
 	tx, err := e.store.Begin(false)
 	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
+		return 0, fmt.Errorf("begin tx: %w", err)
 	}
 
-	if err := e.executeDeleteInTx(tx, stmt); err != nil {
+	n, err := e.executeDeleteInTx(tx, stmt)
+	if err != nil {
 		_ = e.store.Rollback(tx)
-		return err
+		return 0, err
 	}
 
 	if err := e.store.Commit(tx); err != nil {
-		return fmt.Errorf("commit: %w", err)
+		return 0, fmt.Errorf("commit: %w", err)
 	}
-	return nil
+	return n, nil
 }
 
-func (e *DBEngine) executeDeleteInTx(tx storage.Tx, stmt *sql.DeleteStmt) error {
-	cols, rows, err := tx.Scan(stmt.TableName)
+// executeDeleteInTx applies a DELETE in tx. When tx is a
+// storage.RowIDStore, only the deleted rows are written and the others keep
+// their rowids; otherwise the table is rewritten with ReplaceAll.
+func (e *DBEngine) executeDeleteInTx(tx storage.Tx, stmt *sql.DeleteStmt) (int, error) {
+	cols, rows, ids, err := e.scanForWrite(tx, stmt.TableName, stmt.Where)
 	if err != nil {
-		return fmt.Errorf("scan: %w", err)
+		return 0, err
 	}
 
-	newRows, _, err := applyDelete(cols, rows, stmt.Where)
+	schema, err := e.store.TableSchema(stmt.TableName)
+	if err != nil {
+		return 0, fmt.Errorf("schema: %w", err)
+	}
+	match, err := buildPredicate(cols, stmt.Where, nocaseColumns(schema))
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("DELETE: %w", err)
+	}
+	newRows, deleted, err := applyDelete(rows, match)
+	if err != nil {
+		return 0, err
+	}
+	if len(deleted) == 0 {
+		// Nothing to write; leave the table and the WAL alone.
+		return 0, nil
+	}
+
+	var deletedIDs []int64
+	if ids != nil {
+		for _, r := range deleted {
+			deletedIDs = append(deletedIDs, r[len(r)-1].I64)
+		}
+		newRows, deleted = stripRowIDs(newRows), stripRowIDs(deleted)
+	}
+	if err := e.checkForeignKeys(tx, stmt.TableName, deleted, nil, newRows); err != nil {
+		return 0, err
+	}
+
+	if ids != nil {
+		if err := tx.(storage.RowIDStore).DeleteRowIDs(stmt.TableName, deletedIDs); err != nil {
+			return 0, fmt.Errorf("delete: %w", err)
+		}
+		return len(deleted), nil
 	}
 	if err := tx.ReplaceAll(stmt.TableName, newRows); err != nil {
-		return fmt.Errorf("replaceAll: %w", err)
+		return 0, fmt.Errorf("replaceAll: %w", err)
 	}
 
-	return nil
+	return len(deleted), nil
 }
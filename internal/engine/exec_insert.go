@@ -4,83 +4,211 @@ import (
 	"fmt"
 	"goDB/internal/sql"
 	"goDB/internal/storage"
+	"time"
 )
 
-func (e *DBEngine) executeInsert(stmt *sql.InsertStmt) error {
+// executeInsert runs an INSERT and returns the number of rows it inserted.
+func (e *DBEngine) executeInsert(stmt *sql.InsertStmt) (int, error) {
 	if e.inTx {
 		return e.executeInsertInTx(e.currTx, stmt)
 	}
 
 	tx, err := e.store.Begin(false)
 	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
+		return 0, fmt.Errorf("begin tx: %w", err)
 	}
 
-	if err := e.executeInsertInTx(tx, stmt); err != nil {
+	n, err := e.executeInsertInTx(tx, stmt)
+	if err != nil {
 		_ = e.store.Rollback(tx)
-		return err
+		return 0, err
 	}
 
 	if err := e.store.Commit(tx); err != nil {
-		return fmt.Errorf("commit: %w", err)
+		return 0, fmt.Errorf("commit: %w", err)
 	}
 
-	return nil
+	return n, nil
 }
 
-// Uses an existing transaction (either currTx or a one-off).
-func (e *DBEngine) executeInsertInTx(tx storage.Tx, stmt *sql.InsertStmt) error {
-	// Use tx.Scan to get column names
-	cols, _, err := tx.Scan(stmt.TableName)
+// Uses an existing transaction (either currTx or a one-off). All rows of a
+// multi-row INSERT are mapped to table order first and then written with a
+// single InsertBatch call.
+//
+// Column defaults are resolved here, before the rows reach storage, so the
+// WAL records the actual values: replaying an INSERT after a crash restores
+// the original CURRENT_TIMESTAMP rather than the time of recovery.
+func (e *DBEngine) executeInsertInTx(tx storage.Tx, stmt *sql.InsertStmt) (int, error) {
+	cols, err := e.store.TableSchema(stmt.TableName)
 	if err != nil {
-		return fmt.Errorf("scan: %w", err)
+		return 0, fmt.Errorf("schema: %w", err)
 	}
 
-	// No column list: values must match schema order.
-	if len(stmt.Columns) == 0 {
-		if len(stmt.Values) != len(cols) {
-			return fmt.Errorf("INSERT: value count %d does not match table columns %d",
-				len(stmt.Values), len(cols))
+	values := stmt.Rows
+	if len(values) == 0 {
+		values = []sql.Row{stmt.Values}
+	}
+
+	// One timestamp per statement, so every row of a multi-row INSERT
+	// gets the same CURRENT_TIMESTAMP.
+	now := e.now()
+	rows := make([]sql.Row, len(values))
+	for i, v := range values {
+		if stmt.Exprs != nil && stmt.Exprs[i] != nil {
+			if v, err = e.evalInsertExprs(v, stmt.Exprs[i]); err != nil {
+				return 0, err
+			}
+		}
+		rows[i], err = insertRowInTableOrder(cols, stmt.Columns, v, now)
+		if err == nil {
+			err = checkInsertTypes(cols, rows[i])
+		}
+		if err == nil {
+			err = checkNotNull(cols, rows[i])
+		}
+		if err != nil {
+			if len(values) > 1 {
+				return 0, fmt.Errorf("INSERT: row %d: %w", i+1, err)
+			}
+			return 0, fmt.Errorf("INSERT: %w", err)
 		}
-		return tx.Insert(stmt.TableName, stmt.Values)
 	}
 
-	// Column list present; must specify all columns for now.
-	if len(stmt.Columns) != len(cols) {
-		return fmt.Errorf("INSERT: for now, all columns must be specified in column list (have %d, expected %d)",
-			len(stmt.Columns), len(cols))
+	if err := e.checkForeignKeys(tx, stmt.TableName, nil, rows, nil); err != nil {
+		return 0, err
 	}
-	if len(stmt.Values) != len(stmt.Columns) {
-		return fmt.Errorf("INSERT: number of values %d does not match number of columns %d",
-			len(stmt.Values), len(stmt.Columns))
+	if err := tx.InsertBatch(stmt.TableName, rows); err != nil {
+		return 0, err
 	}
+	return len(rows), nil
+}
 
-	// Map name -> index in table schema
-	colIndex := make(map[string]int, len(cols))
-	for i, name := range cols {
-		colIndex[name] = i
+// evalInsertExprs returns a copy of values with the non-literal values of
+// the row (non-nil entries of exprs) evaluated.
+func (e *DBEngine) evalInsertExprs(values sql.Row, exprs []sql.Expr) (sql.Row, error) {
+	out := make(sql.Row, len(values))
+	copy(out, values)
+	for j, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+		v, err := e.evalInsertExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("INSERT: %w", err)
+		}
+		out[j] = v
 	}
+	return out, nil
+}
 
-	out := make(sql.Row, len(cols))
-	seen := make([]bool, len(cols))
+// insertRowInTableOrder maps the values of one INSERT row onto the table's
+// column order, using the statement's column list when there is one.
+// Columns missing from the list get their default, which is NULL unless the
+// column declares one. String literals are accepted for TIMESTAMP columns,
+// which parse them, and for BLOB columns, which store their bytes.
+func insertRowInTableOrder(cols []sql.Column, columns []string, values sql.Row, now time.Time) (sql.Row, error) {
+	var out sql.Row
 
-	for i, colName := range stmt.Columns {
-		pos, ok := colIndex[colName]
-		if !ok {
-			return fmt.Errorf("INSERT: unknown column %q", colName)
+	if len(columns) == 0 {
+		// No column list: values must match schema order.
+		if len(values) < len(cols) {
+			return nil, fmt.Errorf("%s: missing value", describeColumn(cols, len(values)))
+		}
+		if len(values) > len(cols) {
+			return nil, fmt.Errorf("%d values for %d columns", len(values), len(cols))
 		}
-		if seen[pos] {
-			return fmt.Errorf("INSERT: duplicate column %q in column list", colName)
+		out = make(sql.Row, len(values))
+		copy(out, values)
+	} else {
+		if len(values) != len(columns) {
+			return nil, fmt.Errorf("number of values %d does not match number of columns %d",
+				len(values), len(columns))
+		}
+
+		// Map name -> index in table schema
+		colIndex := make(map[string]int, len(cols))
+		for i, c := range cols {
+			colIndex[c.Name] = i
+		}
+
+		out = make(sql.Row, len(cols))
+		seen := make([]bool, len(cols))
+
+		for i, colName := range columns {
+			pos, ok := colIndex[colName]
+			if !ok {
+				return nil, fmt.Errorf("%w %q", sql.ErrColumnNotFound, colName)
+			}
+			if seen[pos] {
+				return nil, fmt.Errorf("duplicate column %q in column list", colName)
+			}
+			out[pos] = values[i]
+			seen[pos] = true
+		}
+
+		for i, s := range seen {
+			if !s {
+				out[i] = columnDefault(cols[i], now)
+			}
 		}
-		out[pos] = stmt.Values[i]
-		seen[pos] = true
 	}
 
-	for i, s := range seen {
-		if !s {
-			return fmt.Errorf("INSERT: no value provided for column %q", cols[i])
+	for i, c := range cols {
+		if out[i].Type != sql.TypeString {
+			continue
+		}
+		switch c.Type {
+		case sql.TypeTimestamp:
+			v, err := sql.ParseTimestamp(out[i].S)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", describeColumn(cols, i), err)
+			}
+			out[i] = v
+		case sql.TypeBytes:
+			out[i].Type = sql.TypeBytes
 		}
 	}
 
-	return tx.Insert(stmt.TableName, out)
+	return out, nil
+}
+
+// checkInsertTypes reports the first value of row, in table order, whose
+// type does not match its column. NULL fits every column.
+func checkInsertTypes(cols []sql.Column, row sql.Row) error {
+	for i, c := range cols {
+		if t := row[i].Type; t != sql.TypeNull && t != c.Type {
+			return fmt.Errorf("%s: %w: expected %s, got %s", describeColumn(cols, i), sql.ErrTypeMismatch, c.Type, t)
+		}
+	}
+	return nil
+}
+
+// checkNotNull reports the first NULL of row, in table order, in a NOT NULL
+// column.
+func checkNotNull(cols []sql.Column, row sql.Row) error {
+	for i, c := range cols {
+		if c.NotNull && row[i].Type == sql.TypeNull {
+			return fmt.Errorf("%s: %w", describeColumn(cols, i), sql.ErrNotNull)
+		}
+	}
+	return nil
+}
+
+// describeColumn names the i-th column of a table for error messages, e.g.
+// "column 3 (active BOOL)".
+func describeColumn(cols []sql.Column, i int) string {
+	return fmt.Sprintf("column %d (%s %s)", i+1, cols[i].Name, cols[i].Type)
+}
+
+// columnDefault returns the value an INSERT stores in c when it omits it,
+// which is also what UPDATE ... SET c = DEFAULT stores.
+func columnDefault(c sql.Column, now time.Time) sql.Value {
+	switch {
+	case c.DefaultCurrentTimestamp:
+		return sql.TimestampValue(now)
+	case c.Default != nil:
+		return *c.Default
+	default:
+		return sql.Value{Type: sql.TypeNull}
+	}
 }
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngineExecute_InsertDefaults(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := filestore.New(dir)
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	eng := New(fs)
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	inserted := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	eng.now = func() time.Time { return inserted }
+
+	mustExec(t, eng, "CREATE TABLE events (id INT, note STRING DEFAULT 'none', created TIMESTAMP DEFAULT CURRENT_TIMESTAMP, done BOOL);")
+	mustExec(t, eng, "INSERT INTO events (id) VALUES (1), (2);")
+	mustExec(t, eng, "INSERT INTO events (id, created, note) VALUES (3, '2020-01-01 00:00:00', 'set');")
+	mustExec(t, eng, "INSERT INTO events VALUES (4, 'all', NULL, true);")
+
+	want := []sql.Row{
+		{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "none"}, sql.TimestampValue(inserted), {Type: sql.TypeNull}},
+		{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeString, S: "none"}, sql.TimestampValue(inserted), {Type: sql.TypeNull}},
+		{{Type: sql.TypeInt, I64: 3}, {Type: sql.TypeString, S: "set"}, sql.TimestampValue(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)), {Type: sql.TypeNull}},
+		{{Type: sql.TypeInt, I64: 4}, {Type: sql.TypeString, S: "all"}, {Type: sql.TypeNull}, {Type: sql.TypeBool, B: true}},
+	}
+	check := func(eng *DBEngine) {
+		t.Helper()
+		res := mustExec(t, eng, "SELECT * FROM events ORDER BY id;")
+		if len(res.Rows) != len(want) {
+			t.Fatalf("expected %d rows, got %d", len(want), len(res.Rows))
+		}
+		for i, row := range res.Rows {
+			for j, v := range row {
+				if v != want[i][j] {
+					t.Fatalf("row %d, column %s: got %+v, want %+v", i, res.Columns[j], v, want[i][j])
+				}
+			}
+		}
+	}
+	check(eng)
+
+	// Reopening replays the WAL; the timestamps must be the recorded ones,
+	// not the time of recovery.
+	fs2, err := filestore.New(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	eng2 := New(fs2)
+	if err := eng2.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	eng2.now = func() time.Time { return inserted.Add(time.Hour) }
+	check(eng2)
+
+	schema, err := eng2.TableSchema("events")
+	if err != nil {
+		t.Fatalf("TableSchema failed: %v", err)
+	}
+	if !schema[2].DefaultCurrentTimestamp || schema[1].Default == nil || schema[1].Default.S != "none" {
+		t.Fatalf("defaults lost on reopen: %+v", schema)
+	}
+
+	res := mustExec(t, eng2, "SELECT id FROM events WHERE CAST(created AS STRING) = '2024-05-06 07:08:09';")
+	if len(res.Rows) != 2 {
+		t.Fatalf("expected 2 rows by timestamp, got %v", res.Rows)
+	}
+}
+
+func TestEngineExecute_UpdateSetDefault(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	eng.now = func() time.Time { return now }
+
+	mustExec(t, eng, "CREATE TABLE events (id INT, note STRING DEFAULT 'none', seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP, done BOOL);")
+	mustExec(t, eng, "INSERT INTO events VALUES (1, 'a', '2020-01-01 00:00:00', true), (2, 'b', NULL, true);")
+	res := mustExec(t, eng, "UPDATE events SET note = DEFAULT, seen = DEFAULT, done = DEFAULT WHERE id = 1;")
+	if res.RowsAffected != 1 {
+		t.Fatalf("expected 1 row updated, got %d", res.RowsAffected)
+	}
+
+	res = mustExec(t, eng, "SELECT * FROM events ORDER BY id;")
+	want := []sql.Row{
+		{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "none"}, sql.TimestampValue(now), {Type: sql.TypeNull}},
+		{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeString, S: "b"}, {Type: sql.TypeNull}, {Type: sql.TypeBool, B: true}},
+	}
+	for i, row := range res.Rows {
+		for j, v := range row {
+			if v != want[i][j] {
+				t.Fatalf("row %d, column %s: got %+v, want %+v", i, res.Columns[j], v, want[i][j])
+			}
+		}
+	}
+}
+
+func TestEngineExecute_NotNull(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE users (id INT NOT NULL, name STRING NOT NULL DEFAULT 'anon', note STRING);")
+	mustExec(t, eng, "INSERT INTO users (id) VALUES (1);")
+	mustExec(t, eng, "UPDATE users SET note = NULL WHERE id = 1;")
+
+	for _, tc := range []struct{ query, want string }{
+		{"INSERT INTO users VALUES (2, NULL, 'x');", "INSERT: column 2 (name STRING): NOT NULL constraint failed"},
+		{"INSERT INTO users (name) VALUES ('bob');", "INSERT: column 1 (id INT): NOT NULL constraint failed"},
+		{"INSERT INTO users VALUES (2, 'a', NULL), (NULL, 'b', NULL);", "INSERT: row 2: column 1 (id INT): NOT NULL constraint failed"},
+		{"UPDATE users SET name = NULL WHERE id = 1;", "UPDATE: column 2 (name STRING): NOT NULL constraint failed"},
+	} {
+		stmt, err := sql.Parse(tc.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tc.query, err)
+		}
+		if err := eng.Validate(stmt); err == nil || err.Error() != tc.want {
+			t.Fatalf("Validate(%s) = %v, want %q", tc.query, err, tc.want)
+		}
+		if _, err := eng.Exec(stmt); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: error = %v, want %q", tc.query, err, tc.want)
+		}
+	}
+
+	res := mustExec(t, eng, "SELECT name FROM users;")
+	if len(res.Rows) != 1 || res.Rows[0][0].S != "anon" {
+		t.Fatalf("rows after failed writes = %v, want one row named anon", res.Rows)
+	}
+}
+
+func TestEngineExecute_InsertValidationMessages(t *testing.T) {
+	eng := newUsersEngine(t)
+
+	for _, tc := range []struct{ query, want string }{
+		{"INSERT INTO users VALUES (3, 'Grace', 'Hopper', 1.0);", "INSERT: column 5 (active BOOL): missing value"},
+		{"INSERT INTO users VALUES (3, 'Grace', 'Hopper', 1.0, true, 7);", "INSERT: 6 values for 5 columns"},
+		{"INSERT INTO users VALUES ('3', 'Grace', 'Hopper', 1.0, true);", "INSERT: column 1 (id INT): type mismatch: expected INT, got STRING"},
+		{"INSERT INTO users (id, score) VALUES (3, 'high');", "INSERT: column 4 (score FLOAT): type mismatch: expected FLOAT, got STRING"},
+		{"INSERT INTO users VALUES (3, 'a', 'b', 1.0, true), (4, 'c', 'd', 2.0, 1);", "INSERT: row 2: column 5 (active BOOL): type mismatch: expected BOOL, got INT"},
+	} {
+		stmt, err := sql.Parse(tc.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tc.query, err)
+		}
+		_, err = eng.Exec(stmt)
+		if err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: error = %v, want %q", tc.query, err, tc.want)
+		}
+	}
+
+	// Nothing was inserted by the failed statements.
+	if n := len(mustExec(t, eng, "SELECT * FROM users;").Rows); n != 2 {
+		t.Fatalf("expected 2 rows, got %d", n)
+	}
+}
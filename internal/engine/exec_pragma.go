@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"fmt"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"strings"
+)
+
+// executePragma runs a PRAGMA. table_info(t), index_list(t) and
+// integrity_check report metadata; every other name reads or changes a
+// setting of the storage engine through storage.Configurer.
+func (e *DBEngine) executePragma(s *sql.PragmaStmt) (*Result, error) {
+	switch s.Name {
+	case "table_info", "index_list":
+		if s.Arg == "" || s.Set {
+			return nil, fmt.Errorf("PRAGMA %s takes a table name: PRAGMA %s(table)", s.Name, s.Name)
+		}
+		if s.Name == "table_info" {
+			return e.pragmaTableInfo(s.Arg)
+		}
+		return e.pragmaIndexList(s.Arg)
+
+	case "integrity_check":
+		if s.Arg != "" || s.Set {
+			return nil, fmt.Errorf("PRAGMA integrity_check takes no value")
+		}
+		return e.pragmaIntegrityCheck()
+	}
+
+	if s.Arg != "" {
+		return nil, fmt.Errorf("PRAGMA %s does not take a table name", s.Name)
+	}
+	conf, ok := e.store.(storage.Configurer)
+	if !ok {
+		return nil, fmt.Errorf("PRAGMA %s: %w", s.Name, storage.ErrUnknownSetting)
+	}
+	if s.Set {
+		if err := conf.SetSetting(s.Name, s.Value); err != nil {
+			return nil, fmt.Errorf("PRAGMA %s: %w", s.Name, err)
+		}
+		return &Result{}, nil
+	}
+	v, err := conf.Setting(s.Name)
+	if err != nil {
+		return nil, fmt.Errorf("PRAGMA %s: %w", s.Name, err)
+	}
+	return &Result{
+		Columns:     []string{s.Name},
+		ColumnTypes: []ColumnType{{Name: s.Name, Type: sql.TypeString}},
+		Rows:        []sql.Row{{stringValue(v)}},
+	}, nil
+}
+
+// pragmaTableInfo lists the columns of a table, one row each: position,
+// name, type, NOT NULL, the DEFAULT as SQL text and the REFERENCES target.
+func (e *DBEngine) pragmaTableInfo(table string) (*Result, error) {
+	schema, err := e.store.TableSchema(table)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{ColumnTypes: []ColumnType{
+		{Name: "cid", Type: sql.TypeInt},
+		{Name: "name", Type: sql.TypeString},
+		{Name: "type", Type: sql.TypeString},
+		{Name: "notnull", Type: sql.TypeBool},
+		{Name: "dflt_value", Type: sql.TypeString},
+		{Name: "references", Type: sql.TypeString},
+	}}
+	for _, ct := range res.ColumnTypes {
+		res.Columns = append(res.Columns, ct.Name)
+	}
+	null := sql.Value{Type: sql.TypeNull}
+	for i, c := range schema {
+		dflt, refs := null, null
+		switch {
+		case c.DefaultCurrentTimestamp:
+			dflt = stringValue("CURRENT_TIMESTAMP")
+		case c.Default != nil:
+			dflt = stringValue(defaultSQL(*c.Default))
+		}
+		if c.References != nil {
+			refs = stringValue(c.References.Table + "(" + c.References.Column + ")")
+		}
+		res.Rows = append(res.Rows, sql.Row{
+			{Type: sql.TypeInt, I64: int64(i)},
+			stringValue(c.Name),
+			stringValue(c.Type.String()),
+			{Type: sql.TypeBool, B: c.NotNull},
+			dflt,
+			refs,
+		})
+	}
+	return res, nil
+}
+
+// pragmaIndexList lists the indexed columns of a table.
+func (e *DBEngine) pragmaIndexList(table string) (*Result, error) {
+	if _, err := e.store.TableSchema(table); err != nil {
+		return nil, err
+	}
+	cols, err := e.IndexedColumns(table)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{
+		Columns:     []string{"column"},
+		ColumnTypes: []ColumnType{{Name: "column", Type: sql.TypeString}},
+	}
+	for _, c := range cols {
+		res.Rows = append(res.Rows, sql.Row{stringValue(c)})
+	}
+	return res, nil
+}
+
+// pragmaIntegrityCheck returns one row per problem found, or a single "ok".
+func (e *DBEngine) pragmaIntegrityCheck() (*Result, error) {
+	problems, err := e.CheckIntegrity()
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{
+		Columns:     []string{"integrity_check"},
+		ColumnTypes: []ColumnType{{Name: "integrity_check", Type: sql.TypeString}},
+	}
+	for _, p := range problems {
+		res.Rows = append(res.Rows, sql.Row{stringValue(p.String())})
+	}
+	if len(problems) == 0 {
+		res.Rows = []sql.Row{{stringValue("ok")}}
+	}
+	return res, nil
+}
+
+// defaultSQL renders a DEFAULT value as it would be written in CREATE
+// TABLE; strings and timestamps are quoted.
+func defaultSQL(v sql.Value) string {
+	switch v.Type {
+	case sql.TypeString, sql.TypeTimestamp:
+		return "'" + strings.ReplaceAll(displayString(v), "'", "''") + "'"
+	default:
+		return displayString(v)
+	}
+}
+
+func stringValue(s string) sql.Value {
+	return sql.Value{Type: sql.TypeString, S: s}
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"goDB/internal/storage/filestore"
+)
+
+func TestEngineExecute_PragmaTableInfo(t *testing.T) {
+	eng := newUsersEngine(t)
+	mustExec(t, eng, "CREATE TABLE notes (id INT NOT NULL, user_id INT REFERENCES users(id), body STRING DEFAULT 'it''s', at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);")
+
+	res := mustExec(t, eng, "PRAGMA table_info(notes);")
+	if want := []string{"cid", "name", "type", "notnull", "dflt_value", "references"}; !reflect.DeepEqual(res.Columns, want) {
+		t.Fatalf("columns = %v, want %v", res.Columns, want)
+	}
+	str := func(s string) sql.Value { return sql.Value{Type: sql.TypeString, S: s} }
+	null := sql.Value{Type: sql.TypeNull}
+	want := []sql.Row{
+		{{Type: sql.TypeInt, I64: 0}, str("id"), str("INT"), {Type: sql.TypeBool, B: true}, null, null},
+		{{Type: sql.TypeInt, I64: 1}, str("user_id"), str("INT"), {Type: sql.TypeBool}, null, str("users(id)")},
+		{{Type: sql.TypeInt, I64: 2}, str("body"), str("STRING"), {Type: sql.TypeBool}, str("'it''s'"), null},
+		{{Type: sql.TypeInt, I64: 3}, str("at"), str("TIMESTAMP"), {Type: sql.TypeBool}, str("CURRENT_TIMESTAMP"), null},
+	}
+	if !reflect.DeepEqual(res.Rows, want) {
+		t.Fatalf("rows = %v, want %v", res.Rows, want)
+	}
+
+	if err := execErr(t, eng, "PRAGMA table_info(missing);"); !errors.Is(err, sql.ErrTableNotFound) {
+		t.Fatalf("table_info(missing) error = %v, want ErrTableNotFound", err)
+	}
+	for _, q := range []string{"PRAGMA table_info;", "PRAGMA index_list = 1;", "PRAGMA synchronous(users);"} {
+		if err := execErr(t, eng, q); err == nil {
+			t.Errorf("%s should fail", q)
+		}
+	}
+	// The in-memory engine has no settings.
+	if err := execErr(t, eng, "PRAGMA synchronous;"); !errors.Is(err, storage.ErrUnknownSetting) {
+		t.Fatalf("PRAGMA synchronous on memstore error = %v, want ErrUnknownSetting", err)
+	}
+}
+
+func TestEngineExecute_PragmaSettings(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	defer fs.Close()
+	eng := New(fs)
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	get := func(name string) string {
+		t.Helper()
+		res := mustExec(t, eng, "PRAGMA "+name+";")
+		if len(res.Rows) != 1 || !reflect.DeepEqual(res.Columns, []string{name}) {
+			t.Fatalf("PRAGMA %s = %v %v, want one row", name, res.Columns, res.Rows)
+		}
+		return res.Rows[0][0].S
+	}
+
+	mustExec(t, eng, "PRAGMA synchronous = off;")
+	if got := get("synchronous"); got != "off" {
+		t.Fatalf("synchronous = %q, want off", got)
+	}
+	mustExec(t, eng, "PRAGMA page_cache = 1000;")
+	if got := get("page_cache"); got != "1000" {
+		t.Fatalf("page_cache = %q, want 1000", got)
+	}
+	mustExec(t, eng, "PRAGMA checkpoint_interval = '5m';")
+	if got := get("checkpoint_interval"); got != "5m0s" {
+		t.Fatalf("checkpoint_interval = %q, want 5m0s", got)
+	}
+	if got := get("read_only"); got != "false" {
+		t.Fatalf("read_only = %q, want false", got)
+	}
+
+	mustExec(t, eng, "CREATE TABLE t (id INT);")
+	mustExec(t, eng, "INSERT INTO t VALUES (1);")
+	mustExec(t, eng, "CREATE INDEX idx_t_id ON t (id);")
+	res := mustExec(t, eng, "PRAGMA index_list(t);")
+	if want := []sql.Row{{{Type: sql.TypeString, S: "id"}}}; !reflect.DeepEqual(res.Rows, want) {
+		t.Fatalf("index_list rows = %v, want %v", res.Rows, want)
+	}
+	if got := get("integrity_check"); got != "ok" {
+		t.Fatalf("integrity_check = %q, want ok", got)
+	}
+
+	if err := execErr(t, eng, "PRAGMA synchronous = sometimes;"); err == nil {
+		t.Fatalf("PRAGMA synchronous = sometimes should fail")
+	}
+	if err := execErr(t, eng, "PRAGMA nope;"); !errors.Is(err, storage.ErrUnknownSetting) {
+		t.Fatalf("PRAGMA nope error = %v, want ErrUnknownSetting", err)
+	}
+}
@@ -1,40 +1,122 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"goDB/internal/sql"
 	"goDB/internal/storage"
 )
 
-func (e *DBEngine) executeSelectInTx(tx storage.Tx, table string) ([]string, []sql.Row, error) {
-	cols, rows, err := tx.Scan(table)
+// executeSelect returns all rows from the given table.
+func (e *DBEngine) executeSelect(tableName string) ([]string, []sql.Row, error) {
+	return e.executeSelectWhere(tableName, nil)
+}
+
+// executeSelectWhere returns the rows of the given table that match where
+// (all rows when where is nil).
+func (e *DBEngine) executeSelectWhere(tableName string, where *sql.WhereExpr) ([]string, []sql.Row, error) {
+	if !e.started {
+		return nil, nil, fmt.Errorf("engine not started")
+	}
+
+	var cols []string
+	var rows []sql.Row
+	err := e.readInTx(func(tx storage.Tx) error {
+		var err error
+		cols, rows, err = e.scanWhere(context.Background(), tx, tableName, where)
+		return err
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("scan: %w", err)
+		return nil, nil, err
 	}
 	return cols, rows, nil
 }
 
-// executeSelect returns all rows from the given table.
-func (e *DBEngine) executeSelect(tableName string) ([]string, []sql.Row, error) {
-	if !e.started {
-		return nil, nil, fmt.Errorf("engine not started")
+// readInTx runs fn in the explicit transaction when one is open, so reads
+// see its uncommitted writes, and in a new read-only transaction otherwise.
+// Every read path goes through it, so a query gives the same result whether
+// or not it runs inside BEGIN ... COMMIT.
+func (e *DBEngine) readInTx(fn func(tx storage.Tx) error) error {
+	if e.inTx {
+		return fn(e.currTx)
 	}
+	return e.inReadTx(fn)
+}
 
-	// Start a read-only transaction.
+// inReadTx runs fn in a new read-only transaction.
+func (e *DBEngine) inReadTx(fn func(tx storage.Tx) error) error {
 	tx, err := e.store.Begin(true /* readOnly */)
 	if err != nil {
-		return nil, nil, fmt.Errorf("begin tx: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
 	}
 
-	cols, rows, err := tx.Scan(tableName)
-	if err != nil {
+	if err := fn(tx); err != nil {
 		_ = e.store.Rollback(tx)
-		return nil, nil, fmt.Errorf("scan: %w", err)
+		return err
 	}
 
 	if err := e.store.Commit(tx); err != nil {
-		return nil, nil, fmt.Errorf("commit: %w", err)
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// scanWhere scans a table and applies the WHERE clause. When the storage
+// transaction implements storage.FilteredScanner, the predicate is pushed
+// down so rows are filtered while they are decoded. A transaction that
+// implements storage.ContextScanner stops scanning once ctx is done; other
+// scans run to completion and ctx is only checked afterwards.
+func (e *DBEngine) scanWhere(ctx context.Context, tx storage.Tx, table string, where *sql.WhereExpr) ([]string, []sql.Row, error) {
+	_, filtered := tx.(storage.FilteredScanner)
+	cs, cancellable := tx.(storage.ContextScanner)
+	var schema []sql.Column
+	if where != nil {
+		var err error
+		if schema, err = e.store.TableSchema(table); err != nil {
+			return nil, nil, fmt.Errorf("scan: %w", err)
+		}
+	}
+	nocase := nocaseColumns(schema)
+
+	if (filtered && where != nil) || cancellable {
+		var pred storage.RowPredicate
+		if where != nil {
+			var err error
+			names := make([]string, len(schema))
+			for i, c := range schema {
+				names[i] = c.Name
+			}
+			if pred, err = buildPredicate(names, where, nocase); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var cols []string
+		var rows []sql.Row
+		var err error
+		if cancellable {
+			cols, rows, err = cs.ScanContext(ctx, table, pred)
+		} else {
+			cols, rows, err = tx.(storage.FilteredScanner).ScanWhere(table, pred)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("scan: %w", err)
+		}
+		return cols, rows, nil
 	}
 
+	cols, rows, err := tx.Scan(table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scan: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("query canceled: %w", err)
+	}
+	if where != nil {
+		rows, err = filterRowsWhere(cols, rows, where, nocase)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	return cols, rows, nil
 }
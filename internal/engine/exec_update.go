@@ -6,9 +6,10 @@ import (
 	"goDB/internal/storage"
 )
 
-func (e *DBEngine) executeUpdate(stmt *sql.UpdateStmt) error {
+// executeUpdate runs an UPDATE and returns the number of rows it changed.
+func (e *DBEngine) executeUpdate(stmt *sql.UpdateStmt) (int, error) {
 	if stmt.Where == nil {
-		return fmt.Errorf("UPDATE without WHERE is not supported yet")
+		return 0, fmt.Errorf("UPDATE without WHERE is not supported yet")
 	}
 
 	if e.inTx {
@@ -17,34 +18,74 @@ func (e *DBEngine) executeUpdate(stmt *sql.UpdateStmt) error {
 
 	tx, err := e.store.Begin(false)
 	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
+		return 0, fmt.Errorf("begin tx: %w", err)
 	}
 
-	if err := e.executeUpdateInTx(tx, stmt); err != nil {
+	n, err := e.executeUpdateInTx(tx, stmt)
+	if err != nil {
 		_ = e.store.Rollback(tx)
-		return err
+		return 0, err
 	}
 
 	if err := e.store.Commit(tx); err != nil {
-		return fmt.Errorf("commit: %w", err)
+		return 0, fmt.Errorf("commit: %w", err)
 	}
-	return nil
+	return n, nil
 }
 
-func (e *DBEngine) executeUpdateInTx(tx storage.Tx, stmt *sql.UpdateStmt) error {
-	cols, rows, err := tx.Scan(stmt.TableName)
+// executeUpdateInTx applies an UPDATE in tx. When tx is a
+// storage.RowIDStore, only the changed rows are written and every row keeps
+// its rowid; otherwise the table is rewritten with ReplaceAll.
+func (e *DBEngine) executeUpdateInTx(tx storage.Tx, stmt *sql.UpdateStmt) (int, error) {
+	cols, rows, ids, err := e.scanForWrite(tx, stmt.TableName, stmt.Where)
 	if err != nil {
-		return fmt.Errorf("scan: %w", err)
+		return 0, err
 	}
 
-	newRows, _, err := applyUpdate(cols, rows, stmt.Where, stmt.Assignments)
+	schema, err := e.store.TableSchema(stmt.TableName)
+	if err != nil {
+		return 0, fmt.Errorf("schema: %w", err)
+	}
+	match, err := buildPredicate(cols, stmt.Where, nocaseColumns(schema))
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("UPDATE: %w", err)
+	}
+	newRows, affected, err := applyUpdate(schema, cols, rows, match, stmt.Assignments, e.now())
+	if err != nil {
+		return 0, err
+	}
+	if affected == 0 {
+		// Nothing to write; leave the table and the WAL alone.
+		return 0, nil
+	}
+	if ids != nil {
+		rows, newRows = stripRowIDs(rows), stripRowIDs(newRows)
 	}
 
+	var removed, added []sql.Row
+	var changedIDs []int64
+	for i := range rows {
+		if !rowsEqual(rows[i], newRows[i]) {
+			removed = append(removed, rows[i])
+			added = append(added, newRows[i])
+			if ids != nil {
+				changedIDs = append(changedIDs, ids[i])
+			}
+		}
+	}
+	if err := e.checkForeignKeys(tx, stmt.TableName, removed, added, newRows); err != nil {
+		return 0, err
+	}
+
+	if ids != nil {
+		if err := tx.(storage.RowIDStore).UpdateRowIDs(stmt.TableName, changedIDs, added); err != nil {
+			return 0, fmt.Errorf("update: %w", err)
+		}
+		return affected, nil
+	}
 	if err := tx.ReplaceAll(stmt.TableName, newRows); err != nil {
-		return fmt.Errorf("replaceAll: %w", err)
+		return 0, fmt.Errorf("replaceAll: %w", err)
 	}
 
-	return nil
+	return affected, nil
 }
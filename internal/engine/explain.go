@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// Explain describes how Exec would run stmt, one step per line in the order
+// they happen, without running it: whether a table is scanned or searched
+// through an index, and which filtering, grouping, sorting and limiting
+// follow. The plan reflects the storage engine's capabilities; an index the
+// engine gives up on when it runs (ORDER BY over an index that does not
+// cover every row, for one) still shows as used. Statements that read no
+// table rows, such as CREATE TABLE or BEGIN, have no plan and return nil.
+func (e *DBEngine) Explain(stmt sql.Statement) ([]string, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+
+	var plan []string
+	err := e.readInTx(func(tx storage.Tx) error {
+		var err error
+		switch s := stmt.(type) {
+		case *sql.SelectStmt:
+			plan, err = e.explainSelect(tx, s)
+		case *sql.UpdateStmt:
+			plan, err = e.explainWrite(tx, s.TableName, s.Where, "UPDATE")
+		case *sql.DeleteStmt:
+			plan, err = e.explainWrite(tx, s.TableName, s.Where, "DELETE")
+		case *sql.InsertStmt:
+			n := max(len(s.Rows), 1)
+			plan = []string{fmt.Sprintf("INSERT %d row(s) INTO %s", n, s.TableName)}
+		case *sql.CreateTableStmt:
+			if s.AsSelect != nil {
+				plan, err = e.explainSelect(tx, s.AsSelect)
+				plan = append(plan, "INSERT rows INTO new table "+s.TableName)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// explainSelect describes the access path selectPath chooses and the steps
+// selectInTx runs after it.
+func (e *DBEngine) explainSelect(tx storage.Tx, s *sql.SelectStmt) ([]string, error) {
+	if _, _, err := e.tableColumns(s.TableName); err != nil {
+		return nil, err
+	}
+	path, err := e.selectPath(tx, s)
+	if err != nil {
+		return nil, err
+	}
+	if path.kind == accessCount {
+		return []string{"COUNT rows of " + s.TableName + " from the stored row count"}, nil
+	}
+
+	plan := []string{describePath(s.TableName, path, s.Limit)}
+	if s.Where != nil {
+		plan = append(plan, "FILTER BY WHERE")
+	}
+
+	byOutput := orderByOutput(s)
+	if isAggregateQuery(s) {
+		if len(s.GroupBy) > 0 {
+			plan = append(plan, "GROUP BY "+strings.Join(s.GroupBy, ", "))
+		} else {
+			plan = append(plan, "AGGREGATE all rows")
+		}
+		byOutput = s.OrderBy != nil
+	}
+	if s.OrderBy != nil && path.kind != accessOrdered {
+		step := "SORT BY " + s.OrderBy.Column
+		if s.OrderBy.Desc {
+			step += " DESC"
+		}
+		if byOutput {
+			step += " (output column)"
+		}
+		plan = append(plan, step)
+	}
+	if s.Limit != nil {
+		plan = append(plan, fmt.Sprintf("LIMIT %d", *s.Limit))
+	}
+	return plan, nil
+}
+
+// explainWrite describes the access path writePath chooses for an UPDATE or
+// DELETE (verb) of table, and how scanForWrite's caller writes the rows.
+func (e *DBEngine) explainWrite(tx storage.Tx, table string, where *sql.WhereExpr, verb string) ([]string, error) {
+	if _, _, err := e.tableColumns(table); err != nil {
+		return nil, err
+	}
+	path, err := e.writePath(tx, table, where)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := []string{describePath(table, path, nil)}
+	if where != nil {
+		plan = append(plan, "FILTER BY WHERE")
+	}
+	if _, ok := tx.(storage.RowIDStore); ok {
+		return append(plan, verb+" matching rows by rowid"), nil
+	}
+	return append(plan, verb+" by rewriting the table"), nil
+}
+
+// describePath renders the step of an access path that reads table; limit
+// is the LIMIT of an accessLimit path.
+func describePath(table string, path accessPath, limit *int) string {
+	switch path.kind {
+	case accessRowIDs:
+		return "SCAN " + table + " WITH ROWIDS"
+	case accessLimit:
+		return fmt.Sprintf("SCAN %s, STOPPING AFTER %d ROW(S)", table, *limit)
+	case accessOrdered:
+		return fmt.Sprintf("SCAN %s IN ORDER OF INDEX ON %s", table, path.column)
+	case accessRange:
+		return fmt.Sprintf("SEARCH %s USING INDEX ON %s (%s)", table, path.column, describeKeyRange(path.column, path.lo, path.hi))
+	default:
+		return "SCAN " + table
+	}
+}
+
+// describeKeyRange renders the key range [lo, hi] of column, as returned by
+// indexRange, as a condition.
+func describeKeyRange(column string, lo, hi int64) string {
+	switch {
+	case lo > hi:
+		return "no keys"
+	case lo == hi:
+		return fmt.Sprintf("%s = %d", column, lo)
+	case lo == math.MinInt64:
+		return fmt.Sprintf("%s <= %d", column, hi)
+	case hi == math.MaxInt64:
+		return fmt.Sprintf("%s >= %d", column, lo)
+	default:
+		return fmt.Sprintf("%d <= %s <= %d", lo, column, hi)
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"goDB/internal/sql"
+)
+
+func TestEngineExplain(t *testing.T) {
+	eng, _ := newBigTableEngine(t, 100)
+	mustExec(t, eng, "CREATE INDEX idx_big_id ON big (id);")
+
+	for q, want := range map[string][]string{
+		"SELECT * FROM big WHERE id >= 5 AND id < 10;": {"SEARCH big USING INDEX ON id (5 <= id <= 9)", "FILTER BY WHERE"},
+		"SELECT * FROM big WHERE pad = 'x';":           {"SCAN big", "FILTER BY WHERE"},
+		"SELECT id FROM big LIMIT 3;":                  {"SCAN big, STOPPING AFTER 3 ROW(S)", "LIMIT 3"},
+		"SELECT id FROM big ORDER BY id DESC LIMIT 3;": {"SCAN big IN ORDER OF INDEX ON id", "LIMIT 3"},
+		"SELECT pad FROM big ORDER BY pad;":            {"SCAN big", "SORT BY pad"},
+		"SELECT id * 2 AS d FROM big ORDER BY d;":      {"SCAN big", "SORT BY d (output column)"},
+		"SELECT COUNT(*) FROM big;":                    {"COUNT rows of big from the stored row count"},
+		"SELECT pad, COUNT(*) FROM big GROUP BY pad;":  {"SCAN big", "GROUP BY pad"},
+		"SELECT rowid FROM big WHERE id = 1;":          {"SCAN big WITH ROWIDS", "FILTER BY WHERE"},
+		"UPDATE big SET pad = 'y' WHERE id = 5;":       {"SEARCH big USING INDEX ON id (id = 5)", "FILTER BY WHERE", "UPDATE matching rows by rowid"},
+		"DELETE FROM big WHERE pad = 'y';":             {"SCAN big", "FILTER BY WHERE", "DELETE matching rows by rowid"},
+		"INSERT INTO big VALUES (1, 'a'), (2, 'b');":   {"INSERT 2 row(s) INTO big"},
+		"CREATE TABLE other (id INT);":                 nil,
+	} {
+		stmt, err := sql.Parse(q)
+		if err != nil {
+			t.Fatalf("Parse failed for %q: %v", q, err)
+		}
+		got, err := eng.Explain(stmt)
+		if err != nil {
+			t.Fatalf("Explain(%q) failed: %v", q, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Explain(%q) = %q, want %q", q, got, want)
+		}
+	}
+
+	// Explain does not run the statement.
+	if res := mustExec(t, eng, "SELECT COUNT(*) FROM big;"); res.Rows[0][0].I64 != 100 {
+		t.Fatalf("COUNT(*) = %v after Explain, want 100", res.Rows[0][0])
+	}
+
+	stmt, _ := sql.Parse("SELECT * FROM nope;")
+	if _, err := eng.Explain(stmt); err == nil {
+		t.Fatalf("Explain of an unknown table should fail")
+	}
+}
@@ -0,0 +1,249 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage/memstore"
+)
+
+// mustExec parses and executes one statement, failing the test on error.
+func mustExec(t testing.TB, eng *DBEngine, query string) *Result {
+	t.Helper()
+	stmt, err := sql.Parse(query)
+	if err != nil {
+		t.Fatalf("Parse failed for %q: %v", query, err)
+	}
+	res, err := eng.Exec(stmt)
+	if err != nil {
+		t.Fatalf("Exec failed for %q: %v", query, err)
+	}
+	return res
+}
+
+// newUsersEngine returns a started engine with a small users table.
+func newUsersEngine(t *testing.T) *DBEngine {
+	t.Helper()
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE users (id INT, first STRING, last STRING, score FLOAT, active BOOL);")
+	mustExec(t, eng, "INSERT INTO users VALUES (1, 'Ada', 'Lovelace', 1.5, true), (2, 'Alan', 'Turing', 2.0, false);")
+	return eng
+}
+
+func TestEngineExecute_Concat(t *testing.T) {
+	eng := newUsersEngine(t)
+
+	res := mustExec(t, eng, "SELECT id, first || ' ' || last FROM users;")
+	if !reflect.DeepEqual(res.Columns, []string{"id", "first || ' ' || last"}) {
+		t.Fatalf("unexpected columns: %v", res.Columns)
+	}
+	want := []sql.Value{
+		{Type: sql.TypeString, S: "Ada Lovelace"},
+		{Type: sql.TypeString, S: "Alan Turing"},
+	}
+	for i, w := range want {
+		if res.Rows[i][1] != w {
+			t.Fatalf("row %d: got %+v, want %+v", i, res.Rows[i][1], w)
+		}
+	}
+
+	// Non-string operands use their display form.
+	res = mustExec(t, eng, "SELECT id || ':' || score || ':' || active FROM users WHERE id = 1;")
+	if len(res.Rows) != 1 || res.Rows[0][0].S != "1:1.5:true" {
+		t.Fatalf("unexpected coerced concat: %v", res.Rows)
+	}
+
+	res = mustExec(t, eng, "SELECT id FROM users WHERE first || last = 'AlanTuring';")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 2 {
+		t.Fatalf("unexpected WHERE concat result: %v", res.Rows)
+	}
+
+	mustExec(t, eng, "UPDATE users SET active = false WHERE first || '!' = 'Ada!';")
+	mustExec(t, eng, "DELETE FROM users WHERE last || 'x' = 'Turingx';")
+	res = mustExec(t, eng, "SELECT id, active FROM users;")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 1 || res.Rows[0][1].B {
+		t.Fatalf("unexpected rows after UPDATE/DELETE: %v", res.Rows)
+	}
+
+	// NULL || anything is NULL.
+	mustExec(t, eng, "INSERT INTO users VALUES (3, 'Grace', NULL, 3.25, true);")
+	res = mustExec(t, eng, "SELECT first || last FROM users WHERE id = 3;")
+	if len(res.Rows) != 1 || res.Rows[0][0].Type != sql.TypeNull {
+		t.Fatalf("expected NULL concat, got %v", res.Rows)
+	}
+
+	stmt, _ := sql.Parse("SELECT nope || 'x' FROM users;")
+	if _, err := eng.Exec(stmt); err == nil {
+		t.Fatalf("expected error for unknown column in expression")
+	}
+}
+
+func TestEngineExecute_UnaryMinus(t *testing.T) {
+	eng := newUsersEngine(t)
+
+	res := mustExec(t, eng, "SELECT -id, -(score), - 3 FROM users WHERE id = 1;")
+	want := sql.Row{
+		{Type: sql.TypeInt, I64: -1},
+		{Type: sql.TypeFloat, F64: -1.5},
+		{Type: sql.TypeInt, I64: -3},
+	}
+	if len(res.Rows) != 1 || !reflect.DeepEqual(res.Rows[0], want) {
+		t.Fatalf("unexpected rows: %v", res.Rows)
+	}
+
+	for _, q := range []string{
+		"SELECT id FROM users WHERE -id = -2;",
+		"SELECT id FROM users WHERE -id = - 2;",
+		"SELECT id FROM users WHERE -id < -1;",
+	} {
+		res = mustExec(t, eng, q)
+		if len(res.Rows) != 1 || res.Rows[0][0].I64 != 2 {
+			t.Fatalf("%s: unexpected rows %v", q, res.Rows)
+		}
+	}
+
+	mustExec(t, eng, "UPDATE users SET score = - 4.0 WHERE id = 2;")
+	res = mustExec(t, eng, "SELECT id FROM users WHERE score = -4.0;")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 2 {
+		t.Fatalf("unexpected rows after UPDATE: %v", res.Rows)
+	}
+
+	// NULL stays NULL; non-numeric values cannot be negated.
+	mustExec(t, eng, "INSERT INTO users VALUES (NULL, 'Grace', 'Hopper', 3.25, true);")
+	res = mustExec(t, eng, "SELECT -id FROM users WHERE first = 'Grace';")
+	if len(res.Rows) != 1 || res.Rows[0][0].Type != sql.TypeNull {
+		t.Fatalf("expected NULL, got %v", res.Rows)
+	}
+	stmt, _ := sql.Parse("SELECT -first FROM users;")
+	if _, err := eng.Exec(stmt); err == nil {
+		t.Fatalf("expected error negating a string")
+	}
+}
+
+func TestEngineExecute_Blob(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE files (id INT, data BLOB);")
+	mustExec(t, eng, "INSERT INTO files VALUES (1, x'48656C6C6F'), (2, x'00FF'), (3, 'raw');")
+
+	res := mustExec(t, eng, "SELECT id, LENGTH(data), CAST(data AS STRING), data || '' FROM files WHERE data = x'00ff';")
+	if len(res.Rows) != 1 {
+		t.Fatalf("expected 1 row, got %v", res.Rows)
+	}
+	want := sql.Row{
+		{Type: sql.TypeInt, I64: 2},
+		{Type: sql.TypeInt, I64: 2},
+		{Type: sql.TypeString, S: "\x00\xff"},
+		{Type: sql.TypeString, S: "x'00FF'"},
+	}
+	if !reflect.DeepEqual(res.Rows[0], want) {
+		t.Fatalf("got %+v, want %+v", res.Rows[0], want)
+	}
+
+	// A string literal inserted into a BLOB column keeps its bytes.
+	res = mustExec(t, eng, "SELECT data FROM files WHERE id = 3;")
+	if got := res.Rows[0][0]; got != (sql.Value{Type: sql.TypeBytes, S: "raw"}) {
+		t.Fatalf("got %+v, want BLOB 'raw'", got)
+	}
+}
+
+func TestEngineExecute_Arithmetic(t *testing.T) {
+	eng := newUsersEngine(t)
+
+	res := mustExec(t, eng, "SELECT id + 1, id * 10 - 3, 7 / 2, 7 % 2, score * 2, score / 4 FROM users WHERE id * 2 = 4;")
+	want := sql.Row{
+		{Type: sql.TypeInt, I64: 3},
+		{Type: sql.TypeInt, I64: 17},
+		{Type: sql.TypeInt, I64: 3},
+		{Type: sql.TypeInt, I64: 1},
+		{Type: sql.TypeFloat, F64: 4},
+		{Type: sql.TypeFloat, F64: 0.5},
+	}
+	if len(res.Rows) != 1 || !reflect.DeepEqual(res.Rows[0], want) {
+		t.Fatalf("rows = %v, want [%v]", res.Rows, want)
+	}
+	if res.ColumnTypes[0].Type != sql.TypeInt || res.ColumnTypes[4].Type != sql.TypeFloat {
+		t.Fatalf("unexpected column types: %+v", res.ColumnTypes)
+	}
+
+	res = mustExec(t, eng, "SELECT id + NULL FROM users WHERE id = 1;")
+	if res.Rows[0][0].Type != sql.TypeNull {
+		t.Fatalf("id + NULL = %+v, want NULL", res.Rows[0][0])
+	}
+
+	for _, q := range []string{
+		"SELECT id / 0 FROM users;",
+		"SELECT first + 1 FROM users;",
+	} {
+		if err := execErr(t, eng, q); err == nil {
+			t.Errorf("%s should fail", q)
+		}
+	}
+}
+
+func TestEngineExecute_UpdateExpressions(t *testing.T) {
+	eng := newUsersEngine(t)
+
+	res := mustExec(t, eng, "UPDATE users SET score = score * 2.0, first = last, last = first WHERE id >= 1;")
+	if res.RowsAffected != 2 {
+		t.Fatalf("RowsAffected = %d, want 2", res.RowsAffected)
+	}
+	// Every assignment sees the old row, so first and last are swapped.
+	res = mustExec(t, eng, "SELECT first, last, score FROM users;")
+	want := []sql.Row{
+		{{Type: sql.TypeString, S: "Lovelace"}, {Type: sql.TypeString, S: "Ada"}, {Type: sql.TypeFloat, F64: 3}},
+		{{Type: sql.TypeString, S: "Turing"}, {Type: sql.TypeString, S: "Alan"}, {Type: sql.TypeFloat, F64: 4}},
+	}
+	if !reflect.DeepEqual(res.Rows, want) {
+		t.Fatalf("rows = %v, want %v", res.Rows, want)
+	}
+
+	mustExec(t, eng, "UPDATE users SET id = id + 10 WHERE id = 2;")
+	if res := mustExec(t, eng, "SELECT id FROM users WHERE id = 12;"); len(res.Rows) != 1 {
+		t.Fatalf("id + 10: got %v", res.Rows)
+	}
+
+	for _, q := range []string{
+		"UPDATE users SET id = missing + 1 WHERE id = 1;",
+		"UPDATE users SET id = first || 'x' WHERE id = 1;",
+	} {
+		if err := execErr(t, eng, q); err == nil {
+			t.Errorf("%s should fail", q)
+		}
+	}
+}
+
+func TestEngineExecute_Case(t *testing.T) {
+	eng := newUsersEngine(t)
+	mustExec(t, eng, "INSERT INTO users VALUES (3, 'Grace', 'Hopper', NULL, NULL);")
+
+	res := mustExec(t, eng, "SELECT CASE WHEN active THEN 'yes' ELSE 'no' END, CASE id WHEN 1 THEN 'one' WHEN 2 THEN 'two' END, CASE WHEN score > 1.8 THEN score * 2 END FROM users;")
+	str := func(s string) sql.Value { return sql.Value{Type: sql.TypeString, S: s} }
+	null := sql.Value{Type: sql.TypeNull}
+	want := []sql.Row{
+		{str("yes"), str("one"), null},
+		{str("no"), str("two"), {Type: sql.TypeFloat, F64: 4}},
+		{str("no"), null, null},
+	}
+	if !reflect.DeepEqual(res.Rows, want) {
+		t.Fatalf("rows = %v, want %v", res.Rows, want)
+	}
+	if res.ColumnTypes[0].Type != sql.TypeString || res.ColumnTypes[2].Type != sql.TypeFloat {
+		t.Fatalf("unexpected column types: %+v", res.ColumnTypes)
+	}
+
+	res = mustExec(t, eng, "SELECT id FROM users WHERE CASE WHEN active THEN 1 ELSE 0 END = 1;")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 1 {
+		t.Fatalf("CASE in WHERE: got %v", res.Rows)
+	}
+
+	if err := execErr(t, eng, "SELECT CASE WHEN id THEN 1 END FROM users;"); err == nil {
+		t.Fatalf("a non-BOOL WHEN condition should fail")
+	}
+}
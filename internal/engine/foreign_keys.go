@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// validateForeignKeys checks the REFERENCES clauses of a new table: the
+// parent table and column must exist and the column types must match. A
+// table may reference one of its own columns.
+func (e *DBEngine) validateForeignKeys(name string, cols []sql.Column) error {
+	for _, c := range cols {
+		ref := c.References
+		if ref == nil {
+			continue
+		}
+
+		parent := cols
+		if ref.Table != name {
+			var err error
+			if parent, err = e.store.TableSchema(ref.Table); err != nil {
+				return fmt.Errorf("CREATE TABLE: column %q references %w %q", c.Name, sql.ErrTableNotFound, ref.Table)
+			}
+		}
+		idx := schemaIndex(parent, ref.Column)
+		if idx < 0 {
+			return fmt.Errorf("CREATE TABLE: column %q references %w %s(%s)", c.Name, sql.ErrColumnNotFound, ref.Table, ref.Column)
+		}
+		if parent[idx].Type != c.Type {
+			return fmt.Errorf("CREATE TABLE: column %q is %s but %s(%s) is %s",
+				c.Name, c.Type, ref.Table, ref.Column, parent[idx].Type)
+		}
+	}
+	return nil
+}
+
+// checkForeignKeys enforces the FOREIGN KEY constraints involving table
+// before a change is written. removed holds the rows the change takes away
+// (for UPDATE, their old versions) and added the rows it writes. after is
+// the full table once the change is applied, or nil when the change only
+// adds rows.
+//
+// Every non-NULL value added to a REFERENCES column must exist in the
+// parent column, and a parent key that disappears must not be referenced by
+// any remaining row (RESTRICT semantics).
+func (e *DBEngine) checkForeignKeys(tx storage.Tx, table string, removed, added, after []sql.Row) error {
+	schema, err := e.store.TableSchema(table)
+	if err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+
+	afterRows := func() ([]sql.Row, error) {
+		if after == nil {
+			_, rows, err := tx.Scan(table)
+			if err != nil {
+				return nil, fmt.Errorf("scan: %w", err)
+			}
+			after = append(rows, added...)
+		}
+		return after, nil
+	}
+
+	// Outgoing references: the new values must exist in the parent.
+	for i, c := range schema {
+		ref := c.References
+		if ref == nil {
+			continue
+		}
+		missing := columnKeys(added, i)
+		if len(missing) == 0 {
+			continue
+		}
+
+		var parentRows []sql.Row
+		var pidx int
+		if ref.Table == table {
+			if parentRows, err = afterRows(); err != nil {
+				return err
+			}
+			pidx = schemaIndex(schema, ref.Column)
+		} else {
+			parent, err := e.store.TableSchema(ref.Table)
+			if err != nil {
+				return fmt.Errorf("FOREIGN KEY: %w", err)
+			}
+			pidx = schemaIndex(parent, ref.Column)
+			_, parentRows, err = scanMatching(tx, ref.Table, keyPredicate(pidx, missing))
+			if err != nil {
+				return fmt.Errorf("scan: %w", err)
+			}
+		}
+		if pidx < 0 {
+			return fmt.Errorf("FOREIGN KEY: %w %s(%s)", sql.ErrColumnNotFound, ref.Table, ref.Column)
+		}
+		for _, r := range parentRows {
+			delete(missing, r[pidx])
+		}
+		for v := range missing {
+			return fmt.Errorf("FOREIGN KEY: %s.%s = %s has no matching row in %s(%s)",
+				table, c.Name, displayString(v), ref.Table, ref.Column)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	// Incoming references: keys that disappear must not be in use.
+	tables, err := e.store.ListTables()
+	if err != nil {
+		return fmt.Errorf("list tables: %w", err)
+	}
+	for _, child := range tables {
+		childSchema := schema
+		if child != table {
+			if childSchema, err = e.store.TableSchema(child); err != nil {
+				return fmt.Errorf("schema: %w", err)
+			}
+		}
+
+		for j, c := range childSchema {
+			ref := c.References
+			if ref == nil || ref.Table != table {
+				continue
+			}
+			pidx := schemaIndex(schema, ref.Column)
+			if pidx < 0 {
+				continue
+			}
+
+			gone := columnKeys(removed, pidx)
+			if len(gone) == 0 {
+				continue
+			}
+			rows, err := afterRows()
+			if err != nil {
+				return err
+			}
+			for _, r := range rows {
+				delete(gone, r[pidx])
+			}
+			if len(gone) == 0 {
+				continue
+			}
+
+			var childRows []sql.Row
+			if child == table {
+				childRows = rows
+			} else if _, childRows, err = scanMatching(tx, child, keyPredicate(j, gone)); err != nil {
+				return fmt.Errorf("scan: %w", err)
+			}
+			for _, r := range childRows {
+				if _, ok := gone[r[j]]; ok {
+					return fmt.Errorf("FOREIGN KEY: %s(%s) = %s is still referenced by %s.%s",
+						table, ref.Column, displayString(r[j]), child, c.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hasForeignKeys reports whether table references another table or is
+// referenced by one.
+func (e *DBEngine) hasForeignKeys(table string) (bool, error) {
+	tables, err := e.store.ListTables()
+	if err != nil {
+		return false, fmt.Errorf("list tables: %w", err)
+	}
+	for _, t := range tables {
+		schema, err := e.store.TableSchema(t)
+		if err != nil {
+			return false, fmt.Errorf("schema: %w", err)
+		}
+		for _, c := range schema {
+			if c.References != nil && (t == table || c.References.Table == table) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// columnKeys returns the distinct non-NULL values of column idx in rows.
+func columnKeys(rows []sql.Row, idx int) map[sql.Value]struct{} {
+	keys := make(map[sql.Value]struct{})
+	for _, r := range rows {
+		if idx < len(r) && r[idx].Type != sql.TypeNull {
+			keys[r[idx]] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// keyPredicate matches rows whose column idx holds one of keys. keys is only
+// read, so the predicate is safe for concurrent use.
+func keyPredicate(idx int, keys map[sql.Value]struct{}) storage.RowPredicate {
+	return func(r sql.Row) (bool, error) {
+		if idx < 0 || idx >= len(r) {
+			return false, nil
+		}
+		_, ok := keys[r[idx]]
+		return ok, nil
+	}
+}
+
+// scanMatching returns the rows of table for which pred holds, pushing the
+// predicate down when the transaction supports it.
+func scanMatching(tx storage.Tx, table string, pred storage.RowPredicate) ([]string, []sql.Row, error) {
+	if fs, ok := tx.(storage.FilteredScanner); ok {
+		return fs.ScanWhere(table, pred)
+	}
+	cols, rows, err := tx.Scan(table)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := rows[:0:0]
+	for _, r := range rows {
+		ok, err := pred(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			out = append(out, r)
+		}
+	}
+	return cols, out, nil
+}
+
+// schemaIndex returns the position of column name in cols, or -1.
+func schemaIndex(cols []sql.Column, name string) int {
+	for i, c := range cols {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+// execErr parses and executes one statement and returns its error.
+func execErr(t *testing.T, eng *DBEngine, query string) error {
+	t.Helper()
+	stmt, err := sql.Parse(query)
+	if err != nil {
+		t.Fatalf("Parse failed for %q: %v", query, err)
+	}
+	_, err = eng.Exec(stmt)
+	return err
+}
+
+func TestEngineExecute_ForeignKeys(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for name, eng := range map[string]*DBEngine{"memstore": New(memstore.New()), "filestore": New(fs)} {
+		t.Run(name, func(t *testing.T) {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+			mustExec(t, eng, "CREATE TABLE orders (id INT, user_id INT REFERENCES users(id));")
+			mustExec(t, eng, "INSERT INTO users VALUES (1, 'Ada'), (2, 'Alan'), (3, 'Grace');")
+			mustExec(t, eng, "INSERT INTO orders VALUES (10, 1), (11, 1), (12, NULL);")
+
+			expectErr := func(query, want string) {
+				t.Helper()
+				err := execErr(t, eng, query)
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Fatalf("%s: got error %v, want one containing %q", query, err, want)
+				}
+			}
+
+			expectErr("INSERT INTO orders VALUES (13, 9);", "has no matching row in users(id)")
+			expectErr("UPDATE orders SET user_id = 9 WHERE id = 10;", "has no matching row")
+			expectErr("DELETE FROM users WHERE id = 1;", "still referenced by orders.user_id")
+			expectErr("UPDATE users SET id = 5 WHERE id = 1;", "still referenced")
+
+			// Unreferenced parents can change, and children can move.
+			mustExec(t, eng, "UPDATE orders SET user_id = 2 WHERE id = 11;")
+			mustExec(t, eng, "DELETE FROM users WHERE id = 3;")
+			mustExec(t, eng, "DELETE FROM orders WHERE id = 10;")
+			mustExec(t, eng, "DELETE FROM users WHERE id = 1;")
+
+			res := mustExec(t, eng, "SELECT id FROM users ORDER BY id;")
+			if len(res.Rows) != 1 || res.Rows[0][0].I64 != 2 {
+				t.Fatalf("unexpected users: %v", res.Rows)
+			}
+
+			// A failed statement inside a transaction leaves it usable.
+			mustExec(t, eng, "BEGIN;")
+			expectErr("INSERT INTO orders VALUES (14, 7);", "has no matching row")
+			mustExec(t, eng, "INSERT INTO orders VALUES (14, 2);")
+			mustExec(t, eng, "COMMIT;")
+		})
+	}
+}
+
+func TestEngineExecute_ForeignKeySelfReference(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE staff (id INT, boss INT REFERENCES staff(id));")
+	mustExec(t, eng, "INSERT INTO staff VALUES (1, NULL), (2, 1), (3, 3);")
+
+	if err := execErr(t, eng, "DELETE FROM staff WHERE id = 1;"); err == nil {
+		t.Fatalf("expected error deleting a referenced row")
+	}
+	// Deleting the boss together with everyone reporting to them is fine.
+	mustExec(t, eng, "DELETE FROM staff WHERE id < 3;")
+}
+
+func TestEngineExecute_ForeignKeyValidation(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+
+	for query, want := range map[string]string{
+		"CREATE TABLE a (x INT REFERENCES missing(id));":  "unknown table",
+		"CREATE TABLE b (x INT REFERENCES users(nope));":  "unknown column",
+		"CREATE TABLE c (x STRING REFERENCES users(id));": "is STRING but users(id) is INT",
+	} {
+		err := execErr(t, eng, query)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: got error %v, want one containing %q", query, err, want)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"goDB/internal/sql"
+)
+
+// scalarFunc describes a built-in function callable from expressions.
+// maxArgs < 0 means the function is variadic. typ returns the type of the
+// result for arguments of the given types.
+type scalarFunc struct {
+	minArgs, maxArgs int
+	call             func(args []sql.Value) (sql.Value, error)
+	typ              func(args []sql.DataType) sql.DataType
+}
+
+// scalarFuncs holds the built-in functions by upper-case name.
+var scalarFuncs = map[string]scalarFunc{
+	"COALESCE": {minArgs: 1, maxArgs: -1, call: fnCoalesce, typ: firstNonNullType},
+	"NULLIF":   {minArgs: 2, maxArgs: 2, call: fnNullIf, typ: firstArgType},
+	"LOWER":    {minArgs: 1, maxArgs: 1, call: fnLower, typ: returns(sql.TypeString)},
+	"UPPER":    {minArgs: 1, maxArgs: 1, call: fnUpper, typ: returns(sql.TypeString)},
+	"LENGTH":   {minArgs: 1, maxArgs: 1, call: fnLength, typ: returns(sql.TypeInt)},
+	"SUBSTR":   {minArgs: 2, maxArgs: 3, call: fnSubstr, typ: returns(sql.TypeString)},
+	"ABS":      {minArgs: 1, maxArgs: 1, call: fnAbs, typ: firstArgType},
+	"ROUND":    {minArgs: 1, maxArgs: 2, call: fnRound, typ: firstArgType},
+	"CEIL":     {minArgs: 1, maxArgs: 1, call: fnCeil, typ: firstArgType},
+	"FLOOR":    {minArgs: 1, maxArgs: 1, call: fnFloor, typ: firstArgType},
+}
+
+// returns is the typ of a function whose result always has type t.
+func returns(t sql.DataType) func([]sql.DataType) sql.DataType {
+	return func([]sql.DataType) sql.DataType { return t }
+}
+
+// firstArgType is the typ of a function whose result has the type of its
+// first argument.
+func firstArgType(args []sql.DataType) sql.DataType {
+	return args[0]
+}
+
+// firstNonNullType is the typ of COALESCE: the type of its first argument
+// that is not a NULL literal.
+func firstNonNullType(args []sql.DataType) sql.DataType {
+	for _, t := range args {
+		if t != sql.TypeNull {
+			return t
+		}
+	}
+	return sql.TypeNull
+}
+
+// compileCall resolves a function call and compiles its arguments.
+func compileCall(c *sql.FuncCall, cols []string) (evalFunc, error) {
+	fn, ok := scalarFuncs[c.Name]
+	if !ok && aggregateFuncs[c.Name] {
+		return nil, fmt.Errorf("aggregate function %s is not allowed here", c.Name)
+	}
+	if !ok && c.Name == "NEXTVAL" {
+		return nil, fmt.Errorf("NEXTVAL is only supported in INSERT VALUES")
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", c.Name)
+	}
+	if len(c.Args) < fn.minArgs || (fn.maxArgs >= 0 && len(c.Args) > fn.maxArgs) {
+		return nil, fmt.Errorf("%s: wrong number of arguments (%d)", c.Name, len(c.Args))
+	}
+
+	args := make([]evalFunc, len(c.Args))
+	for i, a := range c.Args {
+		f, err := compileExpr(a, cols)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = f
+	}
+
+	name := c.Name
+	return func(row sql.Row) (sql.Value, error) {
+		vals := make([]sql.Value, len(args))
+		for i, a := range args {
+			v, err := a(row)
+			if err != nil {
+				return sql.Value{}, err
+			}
+			vals[i] = v
+		}
+		v, err := fn.call(vals)
+		if err != nil {
+			return sql.Value{}, fmt.Errorf("%s: %w", name, err)
+		}
+		return v, nil
+	}, nil
+}
+
+// fnCoalesce returns its first non-NULL argument, or NULL.
+func fnCoalesce(args []sql.Value) (sql.Value, error) {
+	for _, a := range args {
+		if a.Type != sql.TypeNull {
+			return a, nil
+		}
+	}
+	return sql.Value{Type: sql.TypeNull}, nil
+}
+
+// fnNullIf returns NULL when both arguments are equal, and the first one
+// otherwise.
+func fnNullIf(args []sql.Value) (sql.Value, error) {
+	if valuesEqual(args[0], args[1]) {
+		return sql.Value{Type: sql.TypeNull}, nil
+	}
+	return args[0], nil
+}
+
+// String functions return NULL for a NULL argument. Non-string arguments
+// are converted to their display form first, as with ||, so LENGTH(12345)
+// is 5. Lengths and positions count characters, not bytes, except that
+// LENGTH of a BLOB is its size in bytes.
+
+func fnLower(args []sql.Value) (sql.Value, error) {
+	if args[0].Type == sql.TypeNull {
+		return args[0], nil
+	}
+	return sql.Value{Type: sql.TypeString, S: strings.ToLower(displayString(args[0]))}, nil
+}
+
+func fnUpper(args []sql.Value) (sql.Value, error) {
+	if args[0].Type == sql.TypeNull {
+		return args[0], nil
+	}
+	return sql.Value{Type: sql.TypeString, S: strings.ToUpper(displayString(args[0]))}, nil
+}
+
+func fnLength(args []sql.Value) (sql.Value, error) {
+	if args[0].Type == sql.TypeNull {
+		return args[0], nil
+	}
+	if args[0].Type == sql.TypeBytes {
+		return sql.Value{Type: sql.TypeInt, I64: int64(len(args[0].S))}, nil
+	}
+	n := utf8.RuneCountInString(displayString(args[0]))
+	return sql.Value{Type: sql.TypeInt, I64: int64(n)}, nil
+}
+
+// fnSubstr implements SUBSTR(s, start[, length]). start is 1-based; the
+// part of the range before the first character is dropped, so
+// SUBSTR('hello', 0, 2) is 'h'. A negative length is an error.
+func fnSubstr(args []sql.Value) (sql.Value, error) {
+	for _, a := range args {
+		if a.Type == sql.TypeNull {
+			return sql.Value{Type: sql.TypeNull}, nil
+		}
+	}
+	for _, a := range args[1:] {
+		if a.Type != sql.TypeInt {
+			return sql.Value{}, fmt.Errorf("%w: start and length must be INT, got %s", sql.ErrTypeMismatch, a.Type)
+		}
+	}
+
+	runes := []rune(displayString(args[0]))
+	start := args[1].I64 - 1 // 0-based
+	end := int64(len(runes))
+	if len(args) == 3 {
+		if args[2].I64 < 0 {
+			return sql.Value{}, fmt.Errorf("negative length %d", args[2].I64)
+		}
+		end = min(end, start+args[2].I64)
+	}
+	start = max(start, 0)
+	if start >= end {
+		return sql.Value{Type: sql.TypeString, S: ""}, nil
+	}
+	return sql.Value{Type: sql.TypeString, S: string(runes[start:end])}, nil
+}
+
+// Numeric functions return NULL for a NULL argument and reject non-numeric
+// ones. They keep the argument's type: an INT argument gives an INT result
+// and a FLOAT argument a FLOAT result.
+
+// checkNumeric reports whether v can be passed to a numeric function.
+func checkNumeric(v sql.Value) error {
+	if v.Type != sql.TypeInt && v.Type != sql.TypeFloat {
+		return fmt.Errorf("%w: expected INT or FLOAT, got %s", sql.ErrTypeMismatch, v.Type)
+	}
+	return nil
+}
+
+func fnAbs(args []sql.Value) (sql.Value, error) {
+	v := args[0]
+	if v.Type == sql.TypeNull {
+		return v, nil
+	}
+	if err := checkNumeric(v); err != nil {
+		return sql.Value{}, err
+	}
+	if v.Type == sql.TypeFloat {
+		return sql.Value{Type: sql.TypeFloat, F64: math.Abs(v.F64)}, nil
+	}
+	if v.I64 == math.MinInt64 {
+		return sql.Value{}, fmt.Errorf("integer overflow")
+	}
+	if v.I64 < 0 {
+		v.I64 = -v.I64
+	}
+	return v, nil
+}
+
+// fnRound implements ROUND(x[, digits]), rounding half away from zero to
+// the given number of decimal places (default 0). Negative digits round to
+// tens, hundreds, and so on.
+func fnRound(args []sql.Value) (sql.Value, error) {
+	for _, a := range args {
+		if a.Type == sql.TypeNull {
+			return sql.Value{Type: sql.TypeNull}, nil
+		}
+	}
+	v := args[0]
+	if err := checkNumeric(v); err != nil {
+		return sql.Value{}, err
+	}
+	var digits int64
+	if len(args) == 2 {
+		if args[1].Type != sql.TypeInt {
+			return sql.Value{}, fmt.Errorf("%w: digits must be INT, got %s", sql.ErrTypeMismatch, args[1].Type)
+		}
+		digits = args[1].I64
+	}
+
+	if v.Type == sql.TypeInt {
+		if digits >= 0 {
+			return v, nil
+		}
+		if digits < -18 {
+			return sql.Value{Type: sql.TypeInt, I64: 0}, nil
+		}
+		p := int64(math.Pow10(int(-digits)))
+		q := (v.I64 / p) * p
+		// The remainder has the sign of v, so this rounds half away from zero.
+		switch r := v.I64 - q; {
+		case r >= p/2:
+			if q > math.MaxInt64-p {
+				return sql.Value{}, fmt.Errorf("integer overflow")
+			}
+			q += p
+		case r <= -p/2:
+			if q < math.MinInt64+p {
+				return sql.Value{}, fmt.Errorf("integer overflow")
+			}
+			q -= p
+		}
+		return sql.Value{Type: sql.TypeInt, I64: q}, nil
+	}
+
+	if digits > 15 || math.IsInf(v.F64, 0) || math.IsNaN(v.F64) {
+		return v, nil
+	}
+	p := math.Pow10(int(max(digits, -308)))
+	return sql.Value{Type: sql.TypeFloat, F64: math.Round(v.F64*p) / p}, nil
+}
+
+func fnCeil(args []sql.Value) (sql.Value, error) {
+	return roundWith(args[0], math.Ceil)
+}
+
+func fnFloor(args []sql.Value) (sql.Value, error) {
+	return roundWith(args[0], math.Floor)
+}
+
+// roundWith applies f to a FLOAT; INT values are already integral.
+func roundWith(v sql.Value, f func(float64) float64) (sql.Value, error) {
+	if v.Type == sql.TypeNull {
+		return v, nil
+	}
+	if err := checkNumeric(v); err != nil {
+		return sql.Value{}, err
+	}
+	if v.Type == sql.TypeFloat {
+		v.F64 = f(v.F64)
+	}
+	return v, nil
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"goDB/internal/sql"
+)
+
+func TestEngineExecute_CoalesceNullIf(t *testing.T) {
+	eng := newUsersEngine(t)
+	mustExec(t, eng, "INSERT INTO users VALUES (3, 'Grace', NULL, 3.25, true);")
+
+	res := mustExec(t, eng, "SELECT COALESCE(last, 'unknown'), NULLIF(first, 'Ada') FROM users;")
+	if res.Columns[0] != "COALESCE(last, 'unknown')" {
+		t.Fatalf("unexpected column name %q", res.Columns[0])
+	}
+	want := [][2]sql.Value{
+		{{Type: sql.TypeString, S: "Lovelace"}, {Type: sql.TypeNull}},
+		{{Type: sql.TypeString, S: "Turing"}, {Type: sql.TypeString, S: "Alan"}},
+		{{Type: sql.TypeString, S: "unknown"}, {Type: sql.TypeString, S: "Grace"}},
+	}
+	for i, w := range want {
+		if res.Rows[i][0] != w[0] || res.Rows[i][1] != w[1] {
+			t.Fatalf("row %d: got %v, want %v", i, res.Rows[i], w)
+		}
+	}
+
+	res = mustExec(t, eng, "SELECT id FROM users WHERE COALESCE(last, first) = 'Grace';")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 3 {
+		t.Fatalf("unexpected WHERE COALESCE result: %v", res.Rows)
+	}
+
+	res = mustExec(t, eng, "SELECT COALESCE(NULL, NULL) FROM users WHERE id = 1;")
+	if res.Rows[0][0].Type != sql.TypeNull {
+		t.Fatalf("COALESCE of NULLs = %v, want NULL", res.Rows[0][0])
+	}
+
+	// Tables holding NULLs can be rewritten by UPDATE.
+	mustExec(t, eng, "UPDATE users SET active = false WHERE id = 1;")
+	res = mustExec(t, eng, "SELECT COALESCE(last, '?') FROM users WHERE id = 3;")
+	if res.Rows[0][0].S != "?" {
+		t.Fatalf("NULL not preserved by UPDATE: %v", res.Rows[0])
+	}
+
+	for _, bad := range []string{
+		"SELECT NULLIF(first) FROM users;",
+		"SELECT COALESCE() FROM users;",
+		"SELECT NOSUCH(first) FROM users;",
+	} {
+		stmt, err := sql.Parse(bad)
+		if err != nil {
+			t.Fatalf("Parse failed for %q: %v", bad, err)
+		}
+		if _, err := eng.Exec(stmt); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestEngineExecute_StringFunctions(t *testing.T) {
+	eng := newUsersEngine(t)
+	mustExec(t, eng, "INSERT INTO users VALUES (3, 'Grace', NULL, 3.25, true);")
+
+	res := mustExec(t, eng, "SELECT UPPER(first), LOWER(first), LENGTH(first), SUBSTR(first, 2, 2), LENGTH(last) FROM users WHERE id = 3;")
+	want := sql.Row{
+		{Type: sql.TypeString, S: "GRACE"},
+		{Type: sql.TypeString, S: "grace"},
+		{Type: sql.TypeInt, I64: 5},
+		{Type: sql.TypeString, S: "ra"},
+		{Type: sql.TypeNull},
+	}
+	for i, w := range want {
+		if res.Rows[0][i] != w {
+			t.Fatalf("column %d (%s): got %+v, want %+v", i, res.Columns[i], res.Rows[0][i], w)
+		}
+	}
+
+	res = mustExec(t, eng, "SELECT id FROM users WHERE LOWER(first) = 'alan';")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 2 {
+		t.Fatalf("unexpected WHERE LOWER result: %v", res.Rows)
+	}
+
+	// Non-string arguments use their display form.
+	res = mustExec(t, eng, "SELECT LENGTH(id), LENGTH(score), UPPER(active) FROM users WHERE id = 3;")
+	if res.Rows[0][0].I64 != 1 || res.Rows[0][1].I64 != 4 || res.Rows[0][2].S != "TRUE" {
+		t.Fatalf("unexpected results for non-string arguments: %v", res.Rows[0])
+	}
+}
+
+func TestSubstr(t *testing.T) {
+	s := func(x string) sql.Value { return sql.Value{Type: sql.TypeString, S: x} }
+	i := func(n int64) sql.Value { return sql.Value{Type: sql.TypeInt, I64: n} }
+
+	cases := []struct {
+		args []sql.Value
+		want string
+	}{
+		{[]sql.Value{s("hello"), i(2)}, "ello"},
+		{[]sql.Value{s("hello"), i(2), i(3)}, "ell"},
+		{[]sql.Value{s("hello"), i(0), i(2)}, "h"},
+		{[]sql.Value{s("hello"), i(4), i(10)}, "lo"},
+		{[]sql.Value{s("hello"), i(9)}, ""},
+		{[]sql.Value{s("héllo"), i(2), i(1)}, "é"},
+	}
+	for _, c := range cases {
+		got, err := fnSubstr(c.args)
+		if err != nil {
+			t.Fatalf("SUBSTR%v failed: %v", c.args, err)
+		}
+		if got.S != c.want {
+			t.Fatalf("SUBSTR%v = %q, want %q", c.args, got.S, c.want)
+		}
+	}
+
+	if _, err := fnSubstr([]sql.Value{s("x"), i(1), i(-1)}); err == nil {
+		t.Fatalf("expected error for negative length")
+	}
+	if _, err := fnSubstr([]sql.Value{s("x"), s("1")}); err == nil {
+		t.Fatalf("expected error for non-INT start")
+	}
+}
+
+func TestNumericFunctions(t *testing.T) {
+	i := func(n int64) sql.Value { return sql.Value{Type: sql.TypeInt, I64: n} }
+	f := func(x float64) sql.Value { return sql.Value{Type: sql.TypeFloat, F64: x} }
+	null := sql.Value{Type: sql.TypeNull}
+
+	cases := []struct {
+		name string
+		fn   func([]sql.Value) (sql.Value, error)
+		args []sql.Value
+		want sql.Value
+	}{
+		{"ABS", fnAbs, []sql.Value{i(-5)}, i(5)},
+		{"ABS", fnAbs, []sql.Value{f(-2.5)}, f(2.5)},
+		{"ABS", fnAbs, []sql.Value{null}, null},
+		{"ROUND", fnRound, []sql.Value{f(2.5)}, f(3)},
+		{"ROUND", fnRound, []sql.Value{f(-2.5)}, f(-3)},
+		{"ROUND", fnRound, []sql.Value{f(3.14159), i(2)}, f(3.14)},
+		{"ROUND", fnRound, []sql.Value{f(1234.5), i(-2)}, f(1200)},
+		{"ROUND", fnRound, []sql.Value{i(7)}, i(7)},
+		{"ROUND", fnRound, []sql.Value{i(1250), i(-2)}, i(1300)},
+		{"ROUND", fnRound, []sql.Value{i(-1250), i(-2)}, i(-1300)},
+		{"ROUND", fnRound, []sql.Value{i(1249), i(-2)}, i(1200)},
+		{"ROUND", fnRound, []sql.Value{f(1.5), null}, null},
+		{"CEIL", fnCeil, []sql.Value{f(1.2)}, f(2)},
+		{"CEIL", fnCeil, []sql.Value{f(-1.2)}, f(-1)},
+		{"FLOOR", fnFloor, []sql.Value{f(-1.2)}, f(-2)},
+		{"FLOOR", fnFloor, []sql.Value{i(3)}, i(3)},
+	}
+	for _, c := range cases {
+		got, err := c.fn(c.args)
+		if err != nil {
+			t.Fatalf("%s%v failed: %v", c.name, c.args, err)
+		}
+		if got != c.want {
+			t.Fatalf("%s%v = %+v, want %+v", c.name, c.args, got, c.want)
+		}
+	}
+
+	str := sql.Value{Type: sql.TypeString, S: "1"}
+	if _, err := fnAbs([]sql.Value{str}); err == nil {
+		t.Fatalf("expected error for ABS of a string")
+	}
+	if _, err := fnRound([]sql.Value{f(1), f(1)}); err == nil {
+		t.Fatalf("expected error for non-INT digits")
+	}
+	if _, err := fnAbs([]sql.Value{i(math.MinInt64)}); err == nil {
+		t.Fatalf("expected overflow error")
+	}
+}
+
+func TestEngineExecute_NumericFunctions(t *testing.T) {
+	eng := newUsersEngine(t)
+
+	res := mustExec(t, eng, "SELECT ROUND(score, 1), ABS(id), CEIL(score), FLOOR(score) FROM users WHERE id = 1;")
+	want := sql.Row{
+		{Type: sql.TypeFloat, F64: 1.5},
+		{Type: sql.TypeInt, I64: 1},
+		{Type: sql.TypeFloat, F64: 2},
+		{Type: sql.TypeFloat, F64: 1},
+	}
+	for i, w := range want {
+		if res.Rows[0][i] != w {
+			t.Fatalf("column %d (%s): got %+v, want %+v", i, res.Columns[i], res.Rows[0][i], w)
+		}
+	}
+
+	res = mustExec(t, eng, "SELECT id FROM users WHERE ROUND(score) = 2.0;")
+	if len(res.Rows) != 2 {
+		t.Fatalf("unexpected WHERE ROUND result: %v", res.Rows)
+	}
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"goDB/internal/sql"
+)
+
+// Metrics counts the statements executed by the engines it is attached to
+// (see SetMetrics), by statement kind. Several engines, such as the
+// sessions of a server, can share one Metrics.
+type Metrics struct {
+	mu    sync.Mutex
+	kinds map[string]*StatementStats
+}
+
+// StatementStats are the counters of one statement kind.
+type StatementStats struct {
+	Kind     string        // e.g. "SELECT", "INSERT", "BEGIN"
+	Count    int64         // statements executed, including failed ones
+	Errors   int64         // statements that returned an error
+	Duration time.Duration // total execution time
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{kinds: make(map[string]*StatementStats)}
+}
+
+// SetMetrics makes the engine count every statement it executes in m, or
+// stop counting when m is nil.
+func (e *DBEngine) SetMetrics(m *Metrics) {
+	e.metrics = m
+}
+
+// observe records one statement of the given kind.
+func (m *Metrics) observe(kind string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.kinds[kind]
+	if !ok {
+		st = &StatementStats{Kind: kind}
+		m.kinds[kind] = st
+	}
+	st.Count++
+	st.Duration += d
+	if err != nil {
+		st.Errors++
+	}
+}
+
+// Stats returns the counters of every statement kind executed so far,
+// sorted by kind.
+func (m *Metrics) Stats() []StatementStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]StatementStats, 0, len(m.kinds))
+	for _, st := range m.kinds {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out
+}
+
+// statementKind names the kind of stmt for Metrics.
+func statementKind(stmt sql.Statement) string {
+	if name := writeStatementName(stmt); name != "" {
+		return name
+	}
+	switch stmt.(type) {
+	case *sql.SelectStmt:
+		return "SELECT"
+	case *sql.BeginTxStmt:
+		return "BEGIN"
+	case *sql.CommitTxStmt:
+		return "COMMIT"
+	case *sql.RollbackTxStmt:
+		return "ROLLBACK"
+	case *sql.PragmaStmt:
+		return "PRAGMA"
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"testing"
+
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngine_Metrics(t *testing.T) {
+	m := NewMetrics()
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	eng.SetMetrics(m)
+
+	mustExec(t, eng, "CREATE TABLE users (id INT);")
+	mustExec(t, eng, "INSERT INTO users VALUES (1), (2);")
+	mustExec(t, eng, "SELECT * FROM users;")
+	_ = execErr(t, eng, "SELECT * FROM missing;")
+
+	// A second engine can share the same Metrics.
+	other := New(memstore.New())
+	if err := other.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	other.SetMetrics(m)
+	_ = execErr(t, other, "INSERT INTO missing VALUES (1);")
+
+	want := map[string][2]int64{ // kind -> count, errors
+		"CREATE TABLE": {1, 0},
+		"INSERT":       {2, 1},
+		"SELECT":       {2, 1},
+	}
+	stats := m.Stats()
+	if len(stats) != len(want) {
+		t.Fatalf("got stats for %d kinds, want %d: %+v", len(stats), len(want), stats)
+	}
+	for _, st := range stats {
+		w, ok := want[st.Kind]
+		if !ok || st.Count != w[0] || st.Errors != w[1] {
+			t.Fatalf("%s: count %d, errors %d; want %v", st.Kind, st.Count, st.Errors, w)
+		}
+		if st.Duration <= 0 {
+			t.Fatalf("%s: no execution time recorded", st.Kind)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// accessKind is the way a statement reads the rows of its table.
+type accessKind int
+
+const (
+	// accessScan reads every row and filters it with WHERE.
+	accessScan accessKind = iota
+	// accessCount answers COUNT(*) from the stored row count.
+	accessCount
+	// accessRowIDs reads every row with its rowid.
+	accessRowIDs
+	// accessLimit reads only the first LIMIT rows.
+	accessLimit
+	// accessOrdered reads the rows in the order of the index on column.
+	accessOrdered
+	// accessRange reads the keys [lo, hi] of the index on column.
+	accessRange
+)
+
+// accessPath is the access path chosen for a statement. The executor and
+// Explain both take it from selectPath or writePath, so EXPLAIN shows the
+// path that runs. The storage engine may still give up on an index path
+// when it runs, and the executor then scans the table.
+type accessPath struct {
+	kind   accessKind
+	column string
+	lo, hi int64
+}
+
+// selectPath chooses how a SELECT reads its table. A plain COUNT(*) uses
+// the storage engine's row count, a query naming the rowid reads rowids,
+// and a plain SELECT with only a LIMIT reads no more rows than it returns.
+// Otherwise an indexed ORDER BY column is read in index order, and a WHERE
+// clause of comparisons on one indexed column, such as id > 5 AND id < 10,
+// reads only that key range. Each path needs the matching optional
+// interface of tx.
+func (e *DBEngine) selectPath(tx storage.Tx, s *sql.SelectStmt) (accessPath, error) {
+	if _, ok := tx.(storage.RowCounter); ok && isCountStar(s) {
+		return accessPath{kind: accessCount}, nil
+	}
+	if selectUsesRowID(s) {
+		names, _, err := e.tableColumns(s.TableName)
+		if err != nil {
+			return accessPath{}, err
+		}
+		if !hasColumn(names, rowIDColumn) {
+			return accessPath{kind: accessRowIDs}, nil
+		}
+	}
+	if _, ok := tx.(storage.LimitScanner); ok && s.Limit != nil && s.Where == nil && s.OrderBy == nil && !isAggregateQuery(s) {
+		return accessPath{kind: accessLimit}, nil
+	}
+
+	_, orderedScan := tx.(storage.OrderedScanner)
+	ordered := orderedScan && s.OrderBy != nil && !isAggregateQuery(s) && !orderByOutput(s)
+	_, rangeScan := tx.(storage.RangeScanner)
+	column, lo, hi, isRange := indexRange(s.Where)
+	if !ordered && !(rangeScan && isRange) {
+		return accessPath{kind: accessScan}, nil
+	}
+
+	indexed, err := e.IndexedColumns(s.TableName)
+	if err != nil {
+		return accessPath{}, err
+	}
+	switch {
+	case ordered && hasColumn(indexed, s.OrderBy.Column):
+		return accessPath{kind: accessOrdered, column: s.OrderBy.Column}, nil
+	case rangeScan && isRange && hasColumn(indexed, column):
+		return accessPath{kind: accessRange, column: column, lo: lo, hi: hi}, nil
+	default:
+		return accessPath{kind: accessScan}, nil
+	}
+}
+
+// writePath chooses how an UPDATE or DELETE reads table: the key range of
+// an indexed INT column that where limits, such as id = 5, when tx is a
+// storage.RowIDStore and storage.RowIDRangeScanner and the table takes no
+// part in a FOREIGN KEY, whose checks need every row of it; otherwise every
+// row.
+func (e *DBEngine) writePath(tx storage.Tx, table string, where *sql.WhereExpr) (accessPath, error) {
+	if _, ok := tx.(storage.RowIDStore); !ok {
+		return accessPath{kind: accessScan}, nil
+	}
+	if _, ok := tx.(storage.RowIDRangeScanner); !ok {
+		return accessPath{kind: accessScan}, nil
+	}
+	column, lo, hi, ok := writeRange(where)
+	if !ok {
+		return accessPath{kind: accessScan}, nil
+	}
+	indexed, err := e.IndexedColumns(table)
+	if err != nil || !hasColumn(indexed, column) {
+		return accessPath{kind: accessScan}, err
+	}
+	if related, err := e.hasForeignKeys(table); err != nil || related {
+		return accessPath{kind: accessScan}, err
+	}
+	return accessPath{kind: accessRange, column: column, lo: lo, hi: hi}, nil
+}
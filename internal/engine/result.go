@@ -0,0 +1,28 @@
+package engine
+
+import "goDB/internal/sql"
+
+// Result is the outcome of executing a single statement.
+type Result struct {
+	// Columns and Rows hold the result set of a SELECT; both are empty for
+	// other statements.
+	Columns []string
+	Rows    []sql.Row
+
+	// ColumnTypes describes the columns of a SELECT result, in the order of
+	// Columns.
+	ColumnTypes []ColumnType
+
+	// RowsAffected is the number of rows an INSERT, UPDATE or DELETE
+	// inserted, changed or removed.
+	RowsAffected int64
+}
+
+// ColumnType is the name and type of a result column. Columns of the table
+// take the type from its schema; expressions and aggregates get the type
+// their result has. Type is TypeNull only for a column that can hold
+// nothing but NULL, such as SELECT NULL FROM t, when no row says otherwise.
+type ColumnType struct {
+	Name string
+	Type sql.DataType
+}
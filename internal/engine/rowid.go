@@ -0,0 +1,227 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"strings"
+)
+
+// rowIDColumn is the name of the rowid pseudo-column, which storage engines
+// implementing storage.RowIDStore provide for every table. A table column
+// of the same name hides it.
+const rowIDColumn = "rowid"
+
+// hasColumn reports whether names holds name, ignoring case.
+func hasColumn(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRowID returns the column names of a table with the rowid
+// pseudo-column appended, unless a table column hides it.
+func withRowID(names []string) []string {
+	if hasColumn(names, rowIDColumn) {
+		return names
+	}
+	return append(names[:len(names):len(names)], rowIDColumn)
+}
+
+// selectUsesRowID reports whether s refers to a column named rowid; unless
+// the table has such a column, that is the rowid pseudo-column.
+func selectUsesRowID(s *sql.SelectStmt) bool {
+	for i, c := range s.Columns {
+		if s.Exprs != nil {
+			if exprUsesColumn(s.Exprs[i], rowIDColumn) {
+				return true
+			}
+		} else if strings.EqualFold(c, rowIDColumn) {
+			return true
+		}
+	}
+	if whereUsesColumn(s.Where, rowIDColumn) {
+		return true
+	}
+	if s.OrderBy != nil && strings.EqualFold(s.OrderBy.Column, rowIDColumn) {
+		return true
+	}
+	return hasColumn(s.GroupBy, rowIDColumn)
+}
+
+// whereUsesColumn reports whether a comparison of w refers to a column.
+func whereUsesColumn(w *sql.WhereExpr, name string) bool {
+	for ; w != nil; w = w.And {
+		if strings.EqualFold(w.Column, name) || (w.Left != nil && exprUsesColumn(w.Left, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// exprUsesColumn reports whether expr refers to a column.
+func exprUsesColumn(expr sql.Expr, name string) bool {
+	switch x := expr.(type) {
+	case *sql.ColumnRef:
+		return strings.EqualFold(x.Name, name)
+	case *sql.BinaryExpr:
+		return exprUsesColumn(x.Left, name) || exprUsesColumn(x.Right, name)
+	case *sql.UnaryExpr:
+		return exprUsesColumn(x.Expr, name)
+	case *sql.CastExpr:
+		return exprUsesColumn(x.Expr, name)
+	case *sql.CaseExpr:
+		if x.Operand != nil && exprUsesColumn(x.Operand, name) || x.Else != nil && exprUsesColumn(x.Else, name) {
+			return true
+		}
+		for _, w := range x.Whens {
+			if exprUsesColumn(w.When, name) || exprUsesColumn(w.Then, name) {
+				return true
+			}
+		}
+	case *sql.FuncCall:
+		for _, a := range x.Args {
+			if exprUsesColumn(a, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scanRowIDs reads the rows of a table that match where, each with its
+// rowid appended as a last column.
+func (e *DBEngine) scanRowIDs(ctx context.Context, tx storage.Tx, table string, where *sql.WhereExpr) ([]string, []sql.Row, error) {
+	store, ok := tx.(storage.RowIDStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w %q: the storage engine has no rowids", sql.ErrColumnNotFound, rowIDColumn)
+	}
+	cols, rows, ids, err := store.ScanRowIDs(table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scan: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("query canceled: %w", err)
+	}
+	cols = append(cols, rowIDColumn)
+	rows = appendRowIDs(rows, ids)
+	if where == nil {
+		return cols, rows, nil
+	}
+
+	schema, err := e.store.TableSchema(table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scan: %w", err)
+	}
+	match, err := buildPredicate(cols, where, nocaseColumns(schema))
+	if err != nil {
+		return nil, nil, err
+	}
+	out := rows[:0]
+	for _, r := range rows {
+		ok, err := match(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			out = append(out, r)
+		}
+	}
+	return cols, out, nil
+}
+
+// appendRowIDs appends ids[i] to rows[i] as an INT value.
+func appendRowIDs(rows []sql.Row, ids []int64) []sql.Row {
+	for i, r := range rows {
+		rows[i] = append(r[:len(r):len(r)], sql.Value{Type: sql.TypeInt, I64: ids[i]})
+	}
+	return rows
+}
+
+// stripRowIDs drops the last value, the rowid, of every row.
+func stripRowIDs(rows []sql.Row) []sql.Row {
+	out := make([]sql.Row, len(rows))
+	for i, r := range rows {
+		out[i] = r[:len(r)-1]
+	}
+	return out
+}
+
+// scanForWrite reads the rows of a table for UPDATE or DELETE. When tx is a
+// storage.RowIDStore, every row carries its rowid as a last value, and ids
+// holds them too; the rowid column is named "" when a table column hides
+// it, so WHERE can only refer to the table column.
+//
+// When writePath chooses an index key range, such as id = 5, only the rows
+// in that range are read. The caller still filters them with where, and
+// writes them back by rowid.
+func (e *DBEngine) scanForWrite(tx storage.Tx, table string, where *sql.WhereExpr) (cols []string, rows []sql.Row, ids []int64, err error) {
+	store, ok := tx.(storage.RowIDStore)
+	if !ok {
+		cols, rows, err = tx.Scan(table)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("scan: %w", err)
+		}
+		return cols, rows, nil, nil
+	}
+
+	path, err := e.writePath(tx, table, where)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ok = false
+	if path.kind == accessRange {
+		scanner := tx.(storage.RowIDRangeScanner)
+		cols, rows, ids, ok, err = scanner.ScanRangeRowIDs(table, path.column, path.lo, path.hi)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("scan: %w", err)
+		}
+	}
+	if !ok {
+		cols, rows, ids, err = store.ScanRowIDs(table)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("scan: %w", err)
+		}
+	}
+	name := rowIDColumn
+	if hasColumn(cols, rowIDColumn) {
+		name = ""
+	}
+	return append(cols, name), appendRowIDs(rows, ids), ids, nil
+}
+
+// expandStar returns s with every * item of its select list, as in SELECT
+// rowid, * FROM t, replaced by the columns of the table. s itself is
+// returned when it has none.
+func (e *DBEngine) expandStar(s *sql.SelectStmt) (*sql.SelectStmt, error) {
+	if !hasColumn(s.Columns, "*") {
+		return s, nil
+	}
+	names, _, err := e.tableColumns(s.TableName)
+	if err != nil {
+		return nil, err
+	}
+	out := *s
+	out.Columns = nil
+	out.Exprs = nil
+	for i, c := range s.Columns {
+		if c == "*" {
+			out.Columns = append(out.Columns, names...)
+			if s.Exprs != nil {
+				for _, n := range names {
+					out.Exprs = append(out.Exprs, &sql.ColumnRef{Name: n})
+				}
+			}
+			continue
+		}
+		out.Columns = append(out.Columns, c)
+		if s.Exprs != nil {
+			out.Exprs = append(out.Exprs, s.Exprs[i])
+		}
+	}
+	return &out, nil
+}
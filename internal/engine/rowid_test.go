@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngineExecute_RowID(t *testing.T) {
+	stores := map[string]func(t *testing.T) storage.Engine{
+		"memstore": func(t *testing.T) storage.Engine { return memstore.New() },
+		"filestore": func(t *testing.T) storage.Engine {
+			fs, err := filestore.New(t.TempDir())
+			if err != nil {
+				t.Fatalf("filestore.New failed: %v", err)
+			}
+			return fs
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			eng := New(newStore(t))
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+			mustExec(t, eng, "INSERT INTO users VALUES (1, 'Ada'), (2, 'Alan'), (3, 'Grace');")
+
+			res := mustExec(t, eng, "SELECT rowid, * FROM users ORDER BY rowid;")
+			if want := []string{"rowid", "id", "name"}; !reflect.DeepEqual(res.Columns, want) {
+				t.Fatalf("columns = %v, want %v", res.Columns, want)
+			}
+			if res.ColumnTypes[0].Type != sql.TypeInt {
+				t.Fatalf("rowid type = %v, want INT", res.ColumnTypes[0].Type)
+			}
+			if len(res.Rows) != 3 {
+				t.Fatalf("got %d rows, want 3", len(res.Rows))
+			}
+			ids := make(map[int64]int64) // id -> rowid
+			for i, r := range res.Rows {
+				if i > 0 && r[0].I64 <= res.Rows[i-1][0].I64 {
+					t.Fatalf("rowids not ascending: %v", res.Rows)
+				}
+				ids[r[1].I64] = r[0].I64
+			}
+
+			if res := mustExec(t, eng, "SELECT * FROM users;"); len(res.Columns) != 2 {
+				t.Fatalf("SELECT * columns = %v, want no rowid", res.Columns)
+			}
+
+			q := func(format string, id int64) string {
+				return fmt.Sprintf(format, ids[id])
+			}
+			res = mustExec(t, eng, q("SELECT name FROM users WHERE rowid = %d;", 2))
+			if len(res.Rows) != 1 || res.Rows[0][0].S != "Alan" {
+				t.Fatalf("WHERE rowid rows = %v, want Alan", res.Rows)
+			}
+
+			if res := mustExec(t, eng, q("UPDATE users SET name = 'Alan T.' WHERE rowid = %d;", 2)); res.RowsAffected != 1 {
+				t.Fatalf("UPDATE by rowid affected %d rows, want 1", res.RowsAffected)
+			}
+			if res := mustExec(t, eng, q("DELETE FROM users WHERE rowid = %d;", 1)); res.RowsAffected != 1 {
+				t.Fatalf("DELETE by rowid affected %d rows, want 1", res.RowsAffected)
+			}
+
+			// The remaining rows keep their rowids.
+			res = mustExec(t, eng, "SELECT rowid, id, name FROM users ORDER BY id;")
+			want := []sql.Row{
+				{{Type: sql.TypeInt, I64: ids[2]}, {Type: sql.TypeInt, I64: 2}, {Type: sql.TypeString, S: "Alan T."}},
+				{{Type: sql.TypeInt, I64: ids[3]}, {Type: sql.TypeInt, I64: 3}, {Type: sql.TypeString, S: "Grace"}},
+			}
+			if !reflect.DeepEqual(res.Rows, want) {
+				t.Fatalf("rows = %v, want %v", res.Rows, want)
+			}
+		})
+	}
+}
+
+func TestEngineExecute_RowIDColumnHidesPseudoColumn(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE t (rowid STRING, n INT);")
+	mustExec(t, eng, "INSERT INTO t VALUES ('a', 1), ('b', 2);")
+
+	res := mustExec(t, eng, "SELECT rowid FROM t WHERE rowid = 'b';")
+	if len(res.Rows) != 1 || res.Rows[0][0].S != "b" {
+		t.Fatalf("rows = %v, want the table column", res.Rows)
+	}
+	if res := mustExec(t, eng, "DELETE FROM t WHERE rowid = 'a';"); res.RowsAffected != 1 {
+		t.Fatalf("DELETE affected %d rows, want 1", res.RowsAffected)
+	}
+}
+
+// UPDATE and DELETE edit filestore pages in place, so changing the last row
+// of a page must leave the rows before it intact for later inserts and
+// scans.
+func TestEngineExecute_WriteLastRowOfPage(t *testing.T) {
+	for name, write := range map[string]string{
+		"delete": "DELETE FROM t WHERE id = 3;",
+		"grow":   "UPDATE t SET s = 'cccccccccccccccccccccc' WHERE id = 3;",
+	} {
+		t.Run(name, func(t *testing.T) {
+			fs, err := filestore.New(t.TempDir())
+			if err != nil {
+				t.Fatalf("filestore.New failed: %v", err)
+			}
+			defer fs.Close()
+			eng := New(fs)
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE t (id INT, s STRING);")
+			mustExec(t, eng, "INSERT INTO t VALUES (1, 'aaaa'), (2, 'bbbb'), (3, 'cccc');")
+			mustExec(t, eng, write)
+			mustExec(t, eng, "INSERT INTO t VALUES (4, 'dddd');")
+
+			want := map[int64]string{1: "aaaa", 2: "bbbb", 3: "cccccccccccccccccccccc", 4: "dddd"}
+			if name == "delete" {
+				delete(want, 3)
+			}
+			got := make(map[int64]string)
+			for _, r := range mustExec(t, eng, "SELECT id, s FROM t;").Rows {
+				got[r[0].I64] = r[1].S
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("rows after %s = %v, want %v", name, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// Sample returns up to n rows of a table picked at random, every row being
+// equally likely, for a quick look at a large table. The table is read once
+// and at most n rows are kept in memory (reservoir sampling), so unlike
+// sorting by a random key it does not materialize the whole table. The
+// sampled rows come back in table order with all columns, like SELECT *.
+//
+// The same seed picks the same rows from an unchanged table as long as the
+// scan visits the rows in table order; a filestore with several scan
+// workers visits pages in varying order, so its samples vary too.
+func (e *DBEngine) Sample(table string, n int, seed uint64) (*Result, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("sample: negative row count %d", n)
+	}
+
+	var cols []string
+	var rows []sql.Row
+	read := func(tx storage.Tx) error {
+		var err error
+		cols, rows, err = sampleRows(tx, table, n, seed)
+		return err
+	}
+	if err := e.readInTx(read); err != nil {
+		return nil, err
+	}
+
+	types, err := e.selectColumnTypes(&sql.SelectStmt{TableName: table}, cols, rows)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Columns: cols, ColumnTypes: types, Rows: rows}, nil
+}
+
+// reservoir keeps a uniform sample of the rows offered to it.
+type reservoir struct {
+	rng  *rand.Rand
+	seen int
+	rows []sampledRow
+}
+
+// sampledRow is a row kept in a reservoir with its position in the table.
+type sampledRow struct {
+	pos int
+	row sql.Row
+}
+
+// offer shows the next row of the table to the reservoir. keep is called
+// to copy the row when the reservoir takes it.
+func (r *reservoir) offer(row sql.Row, n int, keep func(sql.Row) sql.Row) {
+	pos := r.seen
+	r.seen++
+	if len(r.rows) < n {
+		r.rows = append(r.rows, sampledRow{pos, keep(row)})
+		return
+	}
+	if j := r.rng.IntN(pos + 1); j < n {
+		r.rows[j] = sampledRow{pos, keep(row)}
+	}
+}
+
+// sampleRows draws the sample of Sample in tx. On storage engines that
+// filter while scanning, rows are offered to the reservoir from the scan
+// predicate, which rejects them all, so only the sample is ever copied out.
+func sampleRows(tx storage.Tx, table string, n int, seed uint64) ([]string, []sql.Row, error) {
+	res := &reservoir{rng: rand.New(rand.NewPCG(seed, seed))}
+
+	var cols []string
+	if fs, ok := tx.(storage.FilteredScanner); ok {
+		var mu sync.Mutex
+		pred := func(row sql.Row) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			// The predicate must not retain row, which may also
+			// borrow its strings from the page being scanned.
+			res.offer(row, n, func(r sql.Row) sql.Row {
+				out := make(sql.Row, len(r))
+				for i, v := range r {
+					v.S = strings.Clone(v.S)
+					out[i] = v
+				}
+				return out
+			})
+			return false, nil
+		}
+		var err error
+		if cols, _, err = fs.ScanWhere(table, pred); err != nil {
+			return nil, nil, fmt.Errorf("sample: %w", err)
+		}
+	} else {
+		c, all, err := tx.Scan(table)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sample: %w", err)
+		}
+		cols = c
+		for _, row := range all {
+			res.offer(row, n, func(r sql.Row) sql.Row { return r })
+		}
+	}
+
+	sort.Slice(res.rows, func(i, j int) bool { return res.rows[i].pos < res.rows[j].pos })
+	rows := make([]sql.Row, len(res.rows))
+	for i, s := range res.rows {
+		rows[i] = s.row
+	}
+	return cols, rows, nil
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngineSample(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	for name, eng := range map[string]*DBEngine{"memstore": New(memstore.New()), "filestore": New(fs)} {
+		t.Run(name, func(t *testing.T) {
+			if err := eng.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			mustExec(t, eng, "CREATE TABLE events (id INT, note STRING);")
+			values := make([]string, 200)
+			for i := range values {
+				values[i] = fmt.Sprintf("(%d, 'event %d')", i, i)
+			}
+			mustExec(t, eng, "INSERT INTO events VALUES "+strings.Join(values, ", ")+";")
+
+			ids := func(n int, seed uint64) []int64 {
+				t.Helper()
+				res, err := eng.Sample("events", n, seed)
+				if err != nil {
+					t.Fatalf("Sample failed: %v", err)
+				}
+				if !reflect.DeepEqual(res.Columns, []string{"id", "note"}) {
+					t.Fatalf("Sample columns = %v", res.Columns)
+				}
+				var out []int64
+				for _, r := range res.Rows {
+					if r[1].S != fmt.Sprintf("event %d", r[0].I64) {
+						t.Fatalf("sampled row %v does not match the table", r)
+					}
+					out = append(out, r[0].I64)
+				}
+				return out
+			}
+
+			a := ids(10, 1)
+			if len(a) != 10 {
+				t.Fatalf("Sample returned %d rows, want 10", len(a))
+			}
+			for i := 1; i < len(a); i++ {
+				if a[i] <= a[i-1] {
+					t.Fatalf("sample %v is not in table order", a)
+				}
+			}
+			if b := ids(10, 1); !reflect.DeepEqual(a, b) {
+				t.Fatalf("the same seed gave %v and %v", a, b)
+			}
+			if c := ids(10, 2); reflect.DeepEqual(a, c) {
+				t.Fatalf("different seeds gave the same sample %v", a)
+			}
+			if all := ids(500, 1); len(all) != 200 {
+				t.Fatalf("Sample of more rows than the table has returned %d rows", len(all))
+			}
+			if none := ids(0, 1); len(none) != 0 {
+				t.Fatalf("Sample of 0 rows returned %v", none)
+			}
+
+			if _, err := eng.Sample("events", -1, 1); err == nil {
+				t.Fatalf("expected an error for a negative count")
+			}
+			if _, err := eng.Sample("nope", 5, 1); err == nil {
+				t.Fatalf("expected an error for an unknown table")
+			}
+		})
+	}
+}
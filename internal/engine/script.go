@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"goDB/internal/sql"
+)
+
+// ExecuteScript runs stmts in a single transaction, committing only if every
+// statement succeeds and rolling back as soon as one fails, so a failing
+// script leaves none of its writes behind. It returns one Result per
+// statement.
+//
+// The script cannot contain BEGIN, COMMIT or ROLLBACK and cannot run inside
+// an explicit transaction. Schema statements (CREATE TABLE, CREATE INDEX,
+// CREATE SEQUENCE) are not transactional and are kept after a rollback.
+func (e *DBEngine) ExecuteScript(stmts []sql.Statement) ([]*Result, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+	if e.inTx {
+		return nil, fmt.Errorf("cannot run a script inside a transaction")
+	}
+	for i, stmt := range stmts {
+		switch stmt.(type) {
+		case *sql.BeginTxStmt, *sql.CommitTxStmt, *sql.RollbackTxStmt:
+			return nil, fmt.Errorf("statement %d: transaction control is not allowed in a script", i+1)
+		}
+	}
+
+	if err := e.beginTx(sql.TxDeferred); err != nil {
+		return nil, err
+	}
+	results := make([]*Result, 0, len(stmts))
+	for i, stmt := range stmts {
+		res, err := e.ExecContext(context.Background(), stmt)
+		if err != nil {
+			if rerr := e.rollbackTx(); rerr != nil {
+				return nil, fmt.Errorf("statement %d: %w (%v)", i+1, err, rerr)
+			}
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		results = append(results, res)
+	}
+	if err := e.commitTx(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
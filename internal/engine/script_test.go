@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func parseScript(t *testing.T, queries ...string) []sql.Statement {
+	t.Helper()
+	stmts := make([]sql.Statement, len(queries))
+	for i, q := range queries {
+		stmt, err := sql.Parse(q)
+		if err != nil {
+			t.Fatalf("Parse failed for %q: %v", q, err)
+		}
+		stmts[i] = stmt
+	}
+	return stmts
+}
+
+func TestEngine_ExecuteScript(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	defer fs.Close()
+	for _, eng := range []*DBEngine{New(memstore.New()), New(fs)} {
+		if err := eng.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+		mustExec(t, eng, "INSERT INTO users VALUES (1, 'a');")
+
+		results, err := eng.ExecuteScript(parseScript(t,
+			"INSERT INTO users VALUES (2, 'b'), (3, 'c');",
+			"UPDATE users SET name = 'z' WHERE id = 1;",
+			"SELECT * FROM users;",
+		))
+		if err != nil {
+			t.Fatalf("ExecuteScript failed: %v", err)
+		}
+		if len(results) != 3 || results[0].RowsAffected != 2 || results[1].RowsAffected != 1 || len(results[2].Rows) != 3 {
+			t.Fatalf("unexpected results: %+v", results)
+		}
+
+		// The third statement fails, so the first two are undone.
+		_, err = eng.ExecuteScript(parseScript(t,
+			"INSERT INTO users VALUES (4, 'd');",
+			"DELETE FROM users WHERE id = 2;",
+			"INSERT INTO users VALUES ('five', 'e');",
+		))
+		if err == nil || !strings.HasPrefix(err.Error(), "statement 3: ") {
+			t.Fatalf("ExecuteScript error = %v, want a statement 3 error", err)
+		}
+		rows := mustExec(t, eng, "SELECT id, name FROM users;").Rows
+		if len(rows) != 3 || rows[0][1].S != "z" || rows[1][0].I64 != 2 || rows[2][0].I64 != 3 {
+			t.Fatalf("rows after failed script: %v", rows)
+		}
+		if eng.inTx {
+			t.Fatalf("failed script left a transaction open")
+		}
+
+		if _, err := eng.ExecuteScript(parseScript(t, "INSERT INTO users VALUES (6, 'f');", "COMMIT;")); err == nil {
+			t.Fatalf("expected COMMIT in a script to be rejected")
+		}
+		mustExec(t, eng, "BEGIN;")
+		if _, err := eng.ExecuteScript(parseScript(t, "SELECT * FROM users;")); err == nil {
+			t.Fatalf("expected a script inside a transaction to be rejected")
+		}
+		mustExec(t, eng, "ROLLBACK;")
+		if n := len(mustExec(t, eng, "SELECT * FROM users;").Rows); n != 3 {
+			t.Fatalf("rejected scripts changed the table: %d rows", n)
+		}
+	}
+}
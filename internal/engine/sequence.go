@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"fmt"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// sequencer returns the storage engine's sequence support.
+func (e *DBEngine) sequencer() (storage.Sequencer, error) {
+	seq, ok := e.store.(storage.Sequencer)
+	if !ok {
+		return nil, fmt.Errorf("storage engine does not support sequences")
+	}
+	return seq, nil
+}
+
+// createSequence runs CREATE SEQUENCE.
+func (e *DBEngine) createSequence(s *sql.CreateSequenceStmt) error {
+	seq, err := e.sequencer()
+	if err != nil {
+		return err
+	}
+	return seq.CreateSequence(s.Name, s.Start)
+}
+
+// evalInsertExpr evaluates a non-literal INSERT value. NEXTVAL calls are
+// resolved against the storage engine first, once per evaluation, so every
+// inserted row gets its own value.
+func (e *DBEngine) evalInsertExpr(expr sql.Expr) (sql.Value, error) {
+	resolved, err := e.resolveNextVal(expr)
+	if err != nil {
+		return sql.Value{}, err
+	}
+	fn, err := compileExpr(resolved, nil)
+	if err != nil {
+		return sql.Value{}, err
+	}
+	return fn(nil)
+}
+
+// resolveNextVal returns expr with every NEXTVAL('seq') call replaced by
+// the literal value it hands out. expr itself is not modified.
+func (e *DBEngine) resolveNextVal(expr sql.Expr) (sql.Expr, error) {
+	switch x := expr.(type) {
+	case *sql.FuncCall:
+		if x.Name == "NEXTVAL" {
+			name, ok := sequenceName(x)
+			if !ok {
+				return nil, fmt.Errorf("NEXTVAL: expected a sequence name string, as in NEXTVAL('seq')")
+			}
+			seq, err := e.sequencer()
+			if err != nil {
+				return nil, err
+			}
+			v, err := seq.NextVal(name)
+			if err != nil {
+				return nil, fmt.Errorf("NEXTVAL: %w", err)
+			}
+			return &sql.Literal{Value: sql.Value{Type: sql.TypeInt, I64: v}}, nil
+		}
+		out := &sql.FuncCall{Name: x.Name, Args: make([]sql.Expr, len(x.Args))}
+		for i, a := range x.Args {
+			r, err := e.resolveNextVal(a)
+			if err != nil {
+				return nil, err
+			}
+			out.Args[i] = r
+		}
+		return out, nil
+
+	case *sql.BinaryExpr:
+		left, err := e.resolveNextVal(x.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.resolveNextVal(x.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &sql.BinaryExpr{Op: x.Op, Left: left, Right: right}, nil
+
+	case *sql.CastExpr:
+		inner, err := e.resolveNextVal(x.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &sql.CastExpr{Expr: inner, Type: x.Type}, nil
+
+	case *sql.UnaryExpr:
+		inner, err := e.resolveNextVal(x.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &sql.UnaryExpr{Op: x.Op, Expr: inner}, nil
+
+	case *sql.CaseExpr:
+		// Every branch is resolved, so a NEXTVAL in a branch that is not
+		// taken still advances its sequence.
+		out := &sql.CaseExpr{Whens: make([]sql.CaseWhen, len(x.Whens))}
+		var err error
+		if x.Operand != nil {
+			if out.Operand, err = e.resolveNextVal(x.Operand); err != nil {
+				return nil, err
+			}
+		}
+		for i, w := range x.Whens {
+			if out.Whens[i].When, err = e.resolveNextVal(w.When); err != nil {
+				return nil, err
+			}
+			if out.Whens[i].Then, err = e.resolveNextVal(w.Then); err != nil {
+				return nil, err
+			}
+		}
+		if x.Else != nil {
+			if out.Else, err = e.resolveNextVal(x.Else); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+
+	default:
+		return expr, nil
+	}
+}
+
+// sequenceName returns the sequence a NEXTVAL call names.
+func sequenceName(c *sql.FuncCall) (string, bool) {
+	if len(c.Args) != 1 {
+		return "", false
+	}
+	lit, ok := c.Args[0].(*sql.Literal)
+	if !ok || lit.Value.Type != sql.TypeString {
+		return "", false
+	}
+	return lit.Value.S, true
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngineExecute_Sequence(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE SEQUENCE ids START WITH 10;")
+	mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+	mustExec(t, eng, "CREATE TABLE teams (id INT, name STRING);")
+
+	mustExec(t, eng, "INSERT INTO users VALUES (NEXTVAL('ids'), 'Ada'), (NEXTVAL('ids'), 'Alan');")
+	mustExec(t, eng, "INSERT INTO teams (name, id) VALUES ('core', nextval('ids'));")
+	mustExec(t, eng, "INSERT INTO users VALUES (CAST(NEXTVAL('ids') AS INT), 'Grace' || '!');")
+
+	res := mustExec(t, eng, "SELECT id, name FROM users ORDER BY id;")
+	if len(res.Rows) != 3 || res.Rows[0][0].I64 != 10 || res.Rows[1][0].I64 != 11 ||
+		res.Rows[2][0].I64 != 13 || res.Rows[2][1].S != "Grace!" {
+		t.Fatalf("unexpected users: %v", res.Rows)
+	}
+	res = mustExec(t, eng, "SELECT id FROM teams;")
+	if len(res.Rows) != 1 || res.Rows[0][0].I64 != 12 {
+		t.Fatalf("unexpected teams: %v", res.Rows)
+	}
+
+	for query, want := range map[string]string{
+		"INSERT INTO users VALUES (NEXTVAL('nope'), 'x');": "does not exist",
+		"CREATE SEQUENCE ids;":                             "already exists",
+		"SELECT NEXTVAL('ids') FROM users;":                "only supported in INSERT VALUES",
+	} {
+		if err := execErr(t, eng, query); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: got error %v, want one containing %q", query, err, want)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package engine
+
+import (
+	"fmt"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"strings"
+)
+
+// Validate checks stmt against the current catalog without executing it:
+// the tables and columns it names must exist, literal values must fit
+// their columns, and values written to REFERENCES columns must exist in
+// the parent table. It returns the error Exec would report for these
+// problems, or nil when the statement looks runnable. Validate only reads;
+// it never writes, takes no write locks and does not advance sequences, so
+// values computed at execution time such as NEXTVAL are not checked, and
+// an UPDATE or DELETE may still fail on the rows it would touch.
+//
+// Inside an explicit transaction the statement is checked against that
+// transaction's view of the data.
+func (e *DBEngine) Validate(stmt sql.Statement) error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	if e.inTx && e.txMode == sql.TxReadOnly {
+		if name := writeStatementName(stmt); name != "" {
+			return fmt.Errorf("cannot run %s in a READ ONLY transaction", name)
+		}
+	}
+
+	switch s := stmt.(type) {
+	case *sql.CreateTableStmt:
+		return e.validateCreateTable(s)
+
+	case *sql.CreateSequenceStmt:
+		_, err := e.sequencer()
+		return err
+
+	case *sql.CreateIndexStmt:
+		return e.validateCreateIndex(s)
+
+	case *sql.AlterTableStmt:
+		return e.validateAlterTable(s)
+
+	case *sql.InsertStmt:
+		return e.validateInsert(s)
+
+	case *sql.SelectStmt:
+		s, err := e.expandStar(s)
+		if err != nil {
+			return err
+		}
+		names, _, err := e.tableColumns(s.TableName)
+		if err != nil {
+			return err
+		}
+		return validateSelect(withRowID(names), s)
+
+	case *sql.UpdateStmt:
+		return e.validateUpdate(s)
+
+	case *sql.DeleteStmt:
+		if s.Where == nil {
+			return fmt.Errorf("DELETE without WHERE is not supported yet")
+		}
+		names, _, err := e.tableColumns(s.TableName)
+		if err != nil {
+			return err
+		}
+		if _, err := buildPredicate(withRowID(names), s.Where, nil); err != nil {
+			return fmt.Errorf("DELETE: %w", err)
+		}
+		return nil
+
+	case *sql.BeginTxStmt:
+		if e.inTx {
+			return fmt.Errorf("transaction already in progress")
+		}
+		if _, ok := e.store.(storage.ImmediateBeginner); s.Mode == sql.TxImmediate && !ok {
+			return fmt.Errorf("BEGIN IMMEDIATE is not supported by this storage engine")
+		}
+		return nil
+
+	case *sql.CommitTxStmt:
+		if !e.inTx {
+			return fmt.Errorf("no active transaction to commit")
+		}
+		return nil
+
+	case *sql.RollbackTxStmt:
+		if !e.inTx {
+			return fmt.Errorf("no active transaction to rollback")
+		}
+		return nil
+
+	case *sql.PragmaStmt:
+		return e.validatePragma(s)
+
+	default:
+		return fmt.Errorf("unsupported statement type %T", stmt)
+	}
+}
+
+// tableColumns returns the column names and the schema of a table.
+func (e *DBEngine) tableColumns(table string) ([]string, []sql.Column, error) {
+	schema, err := e.store.TableSchema(table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("schema: %w", err)
+	}
+	names := make([]string, len(schema))
+	for i, c := range schema {
+		names[i] = c.Name
+	}
+	return names, schema, nil
+}
+
+// validateSelect runs the steps of selectInTx that resolve columns, on no
+// rows, so unknown columns in WHERE, GROUP BY, ORDER BY and the SELECT list
+// are reported without reading the table.
+func validateSelect(cols []string, s *sql.SelectStmt) error {
+	if _, err := buildPredicate(cols, s.Where, nil); err != nil {
+		return err
+	}
+	if isAggregateQuery(s) {
+		_, _, err := aggregateSelect(cols, nil, s)
+		return err
+	}
+	byOutput := orderByOutput(s)
+	if s.OrderBy != nil && !byOutput {
+		if err := sortRows(cols, nil, s.OrderBy, nil); err != nil {
+			return err
+		}
+	}
+	outCols, _, err := projectSelect(cols, nil, s, false)
+	if err != nil || !byOutput {
+		return err
+	}
+	return sortRows(outCols, nil, s.OrderBy, nil)
+}
+
+// validateCreateTable checks that a new table does not exist yet and that
+// its foreign keys, or for CREATE TABLE AS its query, are valid.
+func (e *DBEngine) validateCreateTable(s *sql.CreateTableStmt) error {
+	if _, err := e.store.TableSchema(s.TableName); err == nil {
+		return fmt.Errorf("table %s already exists", s.TableName)
+	}
+	if s.AsSelect == nil {
+		return e.validateForeignKeys(s.TableName, s.Columns)
+	}
+
+	if e.inTx {
+		return fmt.Errorf("CREATE TABLE AS cannot run inside a transaction")
+	}
+	if _, err := e.selectSchema(s.AsSelect); err != nil {
+		return fmt.Errorf("CREATE TABLE %s AS: %w", s.TableName, err)
+	}
+	return nil
+}
+
+// validateCreateIndex checks that the indexed column exists, is an INT
+// column and is not indexed yet, as the storage engines do.
+func (e *DBEngine) validateCreateIndex(s *sql.CreateIndexStmt) error {
+	schema, err := e.store.TableSchema(s.TableName)
+	if err != nil {
+		return err
+	}
+	i := schemaIndex(schema, s.ColumnName)
+	if i < 0 {
+		return fmt.Errorf("%w %q in table %q", sql.ErrColumnNotFound, s.ColumnName, s.TableName)
+	}
+	if schema[i].Type != sql.TypeInt {
+		return fmt.Errorf("cannot create index on non-integer column %q", s.ColumnName)
+	}
+	indexed, err := e.IndexedColumns(s.TableName)
+	if err != nil {
+		return err
+	}
+	for _, c := range indexed {
+		if strings.EqualFold(c, s.ColumnName) {
+			return fmt.Errorf("index on %s.%s already exists", s.TableName, s.ColumnName)
+		}
+	}
+	return nil
+}
+
+// validatePragma checks a PRAGMA like executePragma: metadata pragmas need
+// their table, and any other name must be a setting the storage engine
+// knows. New values for a setting are only checked when it is changed.
+func (e *DBEngine) validatePragma(s *sql.PragmaStmt) error {
+	switch s.Name {
+	case "table_info", "index_list":
+		if s.Arg == "" || s.Set {
+			return fmt.Errorf("PRAGMA %s takes a table name: PRAGMA %s(table)", s.Name, s.Name)
+		}
+		_, err := e.store.TableSchema(s.Arg)
+		return err
+
+	case "integrity_check":
+		if s.Arg != "" || s.Set {
+			return fmt.Errorf("PRAGMA integrity_check takes no value")
+		}
+		if _, ok := e.store.(storage.IntegrityChecker); !ok {
+			return fmt.Errorf("storage engine does not support integrity checks")
+		}
+		return nil
+	}
+
+	if s.Arg != "" {
+		return fmt.Errorf("PRAGMA %s does not take a table name", s.Name)
+	}
+	conf, ok := e.store.(storage.Configurer)
+	if !ok {
+		return fmt.Errorf("PRAGMA %s: %w", s.Name, storage.ErrUnknownSetting)
+	}
+	if _, err := conf.Setting(s.Name); err != nil {
+		return fmt.Errorf("PRAGMA %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// validateAlterTable checks that a column can be renamed.
+func (e *DBEngine) validateAlterTable(s *sql.AlterTableStmt) error {
+	if e.inTx {
+		return fmt.Errorf("ALTER TABLE cannot run inside a transaction")
+	}
+	if _, ok := e.store.(storage.ColumnRenamer); !ok {
+		return fmt.Errorf("renaming columns is not supported by this storage engine")
+	}
+	_, schema, err := e.tableColumns(s.TableName)
+	if err != nil {
+		return fmt.Errorf("ALTER TABLE %s: %w", s.TableName, err)
+	}
+	i := schemaIndex(schema, s.Column)
+	if i < 0 {
+		return fmt.Errorf("ALTER TABLE %s: %w %q", s.TableName, sql.ErrColumnNotFound, s.Column)
+	}
+	if j := schemaIndex(schema, s.NewName); j >= 0 && j != i {
+		return fmt.Errorf("ALTER TABLE %s: column %q already exists", s.TableName, s.NewName)
+	}
+	return nil
+}
+
+// validateInsert maps the rows of an INSERT to table order like
+// executeInsertInTx and checks their types and foreign keys.
+func (e *DBEngine) validateInsert(s *sql.InsertStmt) error {
+	cols, err := e.store.TableSchema(s.TableName)
+	if err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+
+	values := s.Rows
+	if len(values) == 0 {
+		values = []sql.Row{s.Values}
+	}
+	if s.Exprs != nil {
+		if _, err := e.sequencer(); err != nil {
+			return err
+		}
+	}
+
+	now := e.now()
+	rows := make([]sql.Row, len(values))
+	for i, v := range values {
+		rows[i], err = insertRowInTableOrder(cols, s.Columns, v, now)
+		if err == nil {
+			err = checkInsertTypes(cols, rows[i])
+		}
+		// Expressions such as NEXTVAL are NULL placeholders until executed.
+		if err == nil && (s.Exprs == nil || s.Exprs[i] == nil) {
+			err = checkNotNull(cols, rows[i])
+		}
+		if err != nil {
+			if len(values) > 1 {
+				return fmt.Errorf("INSERT: row %d: %w", i+1, err)
+			}
+			return fmt.Errorf("INSERT: %w", err)
+		}
+	}
+	return e.validateReferences(s.TableName, rows)
+}
+
+// validateUpdate checks the WHERE clause and SET list of an UPDATE. Values
+// assigned to REFERENCES columns must exist in the parent table.
+func (e *DBEngine) validateUpdate(s *sql.UpdateStmt) error {
+	if s.Where == nil {
+		return fmt.Errorf("UPDATE without WHERE is not supported yet")
+	}
+	names, schema, err := e.tableColumns(s.TableName)
+	if err != nil {
+		return err
+	}
+	if _, err := buildPredicate(withRowID(names), s.Where, nil); err != nil {
+		return fmt.Errorf("UPDATE: %w", err)
+	}
+
+	// One row holding only the assigned values, enough to check their
+	// foreign keys.
+	row := make(sql.Row, len(schema))
+	for i := range row {
+		row[i] = sql.Value{Type: sql.TypeNull}
+	}
+	for _, a := range s.Assignments {
+		i := schemaIndex(schema, a.Column)
+		if i < 0 {
+			return fmt.Errorf("UPDATE: %w %q in SET list", sql.ErrColumnNotFound, a.Column)
+		}
+		if a.Expr != nil {
+			// The value depends on the row; applyUpdate checks it.
+			if _, err := compileExpr(a.Expr, withRowID(names)); err != nil {
+				return fmt.Errorf("UPDATE: %s: %w", a.Column, err)
+			}
+			continue
+		}
+		if t := a.Value.Type; t != sql.TypeNull && t != schema[i].Type {
+			return fmt.Errorf("UPDATE: %s: %w: expected %s, got %s", describeColumn(schema, i), sql.ErrTypeMismatch, schema[i].Type, t)
+		}
+		row[i] = a.Value
+		if a.Default {
+			row[i] = columnDefault(schema[i], e.now())
+		}
+		if row[i].Type == sql.TypeNull && schema[i].NotNull {
+			return fmt.Errorf("UPDATE: %s: %w", describeColumn(schema, i), sql.ErrNotNull)
+		}
+	}
+	return e.validateReferences(s.TableName, []sql.Row{row})
+}
+
+// validateReferences checks that rows about to be written to table only
+// reference existing parent keys, reading through the current transaction
+// or a read-only one.
+func (e *DBEngine) validateReferences(table string, rows []sql.Row) error {
+	check := func(tx storage.Tx) error {
+		return e.checkForeignKeys(tx, table, nil, rows, nil)
+	}
+	return e.readInTx(check)
+}
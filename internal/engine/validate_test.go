@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+func TestEngineValidate(t *testing.T) {
+	eng := New(memstore.New())
+	if err := eng.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	mustExec(t, eng, "CREATE TABLE users (id INT, name STRING);")
+	mustExec(t, eng, "CREATE TABLE orders (id INT, buyer INT REFERENCES users(id));")
+	mustExec(t, eng, "INSERT INTO users VALUES (1, 'Ada');")
+
+	validate := func(query string) error {
+		t.Helper()
+		stmt, err := sql.Parse(query)
+		if err != nil {
+			t.Fatalf("Parse failed for %q: %v", query, err)
+		}
+		return eng.Validate(stmt)
+	}
+
+	for _, q := range []string{
+		"SELECT name FROM users WHERE id = 1 ORDER BY name;",
+		"SELECT name, COUNT(*) FROM users GROUP BY name;",
+		"SELECT id * 2 AS twice FROM users ORDER BY twice;",
+		"INSERT INTO users (name) VALUES ('Alan');",
+		"INSERT INTO orders VALUES (10, 1), (11, NULL);",
+		"UPDATE orders SET buyer = 1 WHERE id = 10;",
+		"DELETE FROM users WHERE id = 1;",
+		"CREATE TABLE admins (id INT REFERENCES users(id));",
+		"CREATE TABLE names AS SELECT name FROM users;",
+		"CREATE TABLE names AS SELECT LOWER(name) AS lower_name FROM users;",
+		"CREATE INDEX idx_users_id ON users (id);",
+		"ALTER TABLE users RENAME COLUMN name TO label;",
+		"BEGIN;",
+	} {
+		if err := validate(q); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", q, err)
+		}
+	}
+
+	for q, want := range map[string]string{
+		"SELECT * FROM nope;":                                  "nope",
+		"SELECT email FROM users;":                             `unknown column "email"`,
+		"SELECT * FROM users WHERE email = 'x';":               `unknown column "email"`,
+		"SELECT * FROM users ORDER BY email;":                  `unknown column "email"`,
+		"SELECT name, COUNT(*) FROM users GROUP BY email;":     `unknown column "email"`,
+		"SELECT id * 2 AS twice FROM users ORDER BY thrice;":   `unknown column "thrice"`,
+		"INSERT INTO users VALUES ('Ada', 1);":                 "expected INT, got STRING",
+		"INSERT INTO users (email) VALUES ('x');":              `unknown column "email"`,
+		"INSERT INTO orders VALUES (10, 7);":                   "FOREIGN KEY",
+		"UPDATE orders SET buyer = 7 WHERE id = 10;":           "FOREIGN KEY",
+		"UPDATE users SET name = 5 WHERE id = 1;":              "expected STRING, got INT",
+		"UPDATE users SET email = 'x' WHERE id = 1;":           `unknown column "email"`,
+		"DELETE FROM users WHERE email = 'x';":                 `unknown column "email"`,
+		"CREATE TABLE users (id INT);":                         "already exists",
+		"CREATE TABLE admins (id INT REFERENCES nope(id));":    "unknown table",
+		"CREATE TABLE names AS SELECT LOWER(name) FROM users;": "name it with AS",
+		"CREATE INDEX idx_users_email ON users (email);":       `unknown column "email"`,
+		"ALTER TABLE users RENAME COLUMN id TO name;":          "already exists",
+		"COMMIT;": "no active transaction",
+	} {
+		if err := validate(q); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate(%q) = %v, want an error containing %q", q, err, want)
+		}
+	}
+
+	// Nothing was executed.
+	res := mustExec(t, eng, "SELECT * FROM users;")
+	if len(res.Rows) != 1 || len(res.Columns) != 2 || res.Columns[1] != "name" {
+		t.Fatalf("Validate changed the database: %v %v", res.Columns, res.Rows)
+	}
+	if tables, _ := eng.ListTables(); len(tables) != 2 {
+		t.Fatalf("Validate created tables: %v", tables)
+	}
+
+	mustExec(t, eng, "BEGIN READ ONLY;")
+	if err := validate("INSERT INTO users VALUES (2, 'Alan');"); err == nil || !strings.Contains(err.Error(), "READ ONLY") {
+		t.Errorf("Validate in a READ ONLY transaction = %v", err)
+	}
+	if err := validate("SELECT * FROM users;"); err != nil {
+		t.Errorf("Validate(SELECT) in a transaction = %v", err)
+	}
+	mustExec(t, eng, "ROLLBACK;")
+}
+
+// Validate reports an error exactly when Exec would for CREATE INDEX and
+// PRAGMA, on both storage engines.
+func TestEngineValidate_MatchesExec(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	t.Cleanup(func() { fs.Close() })
+
+	for name, store := range map[string]storage.Engine{"mem": memstore.NewWithDir(t.TempDir()), "file": fs} {
+		eng := New(store)
+		if err := eng.Start(); err != nil {
+			t.Fatalf("%s: Start failed: %v", name, err)
+		}
+		mustExec(t, eng, "CREATE TABLE users (id INT, age INT, name STRING);")
+		mustExec(t, eng, "CREATE INDEX idx_users_id ON users (id);")
+
+		// In order: each statement is validated, then executed.
+		for _, q := range []string{
+			"CREATE INDEX idx_users_age ON users (age);",
+			"CREATE INDEX idx_users_age2 ON users (age);",
+			"CREATE INDEX idx_users_id2 ON users (id);",
+			"CREATE INDEX idx_users_name ON users (name);",
+			"CREATE INDEX idx_users_email ON users (email);",
+			"CREATE INDEX idx_nope ON nope (id);",
+			"PRAGMA table_info(users);",
+			"PRAGMA table_info(nope);",
+			"PRAGMA table_info;",
+			"PRAGMA index_list(users);",
+			"PRAGMA integrity_check;",
+			"PRAGMA integrity_check(users);",
+			"PRAGMA synchronous;",
+			"PRAGMA synchronous(users);",
+			"PRAGMA no_such_setting;",
+			"PRAGMA no_such_setting = 1;",
+		} {
+			stmt, err := sql.Parse(q)
+			if err != nil {
+				t.Fatalf("Parse failed for %q: %v", q, err)
+			}
+			verr := eng.Validate(stmt)
+			_, eerr := eng.Exec(stmt)
+			if (verr == nil) != (eerr == nil) {
+				t.Errorf("%s: %s: Validate = %v, Exec = %v", name, q, verr, eerr)
+			}
+		}
+	}
+}
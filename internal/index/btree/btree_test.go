@@ -1,6 +1,7 @@
 package btree
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 )
@@ -229,6 +230,10 @@ func TestInternalSplitGrowsTreeHeight(t *testing.T) {
 		t.Fatalf("separator key %d outside expected range", sep)
 	}
 
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify after inserts: %v", err)
+	}
+
 	// Spot-check searches across the tree height.
 	checkKeys := []int{0, total / 3, total - 1}
 	for _, k := range checkKeys {
@@ -303,6 +308,9 @@ func TestDeleteRemovesRIDAndBorrows(t *testing.T) {
 	if keys[0] != Key(minLeafKeys+2) {
 		t.Fatalf("separator after borrow = %d, want %d", keys[0], Key(minLeafKeys+2))
 	}
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify after borrow: %v", err)
+	}
 }
 
 func TestDeleteKeyCollapsesRoot(t *testing.T) {
@@ -342,4 +350,479 @@ func TestDeleteKeyCollapsesRoot(t *testing.T) {
 	if rh.NumKeys != 0 {
 		t.Fatalf("root NumKeys = %d, want 0 after full deletion", rh.NumKeys)
 	}
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify after collapse: %v", err)
+	}
+}
+
+func TestParentPointersAfterMixedInsertsAndDeletes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "idx.idx")
+
+	idxIface, err := OpenFileIndex(path, Meta{TableName: "t", Column: "id"})
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	idx := idxIface.(*fileIndex)
+	defer idx.Close()
+
+	// Interleaved keys exercise splits away from the rightmost path.
+	total := (maxInternalKeys + 2) * maxLeafKeys
+	for i := 0; i < total; i++ {
+		k := Key((i * 7919) % total)
+		if err := idx.Insert(k, RID{PageID: uint32(k + 1)}); err != nil {
+			t.Fatalf("Insert %d failed: %v", k, err)
+		}
+	}
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify after inserts: %v", err)
+	}
+
+	// Deleting most keys forces internal borrows and merges.
+	for k := 0; k < total*9/10; k++ {
+		if err := idx.DeleteKey(Key(k)); err != nil {
+			t.Fatalf("DeleteKey %d failed: %v", k, err)
+		}
+	}
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify after deletes: %v", err)
+	}
+	if got, _ := idx.Search(Key(total - 1)); len(got) != 1 {
+		t.Fatalf("expected surviving key %d, got %v", total-1, got)
+	}
+}
+
+func TestOpenUpgradesLegacyPageLinks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "idx.idx")
+
+	idxIface, err := OpenFileIndex(path, Meta{TableName: "t", Column: "id"})
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	idx := idxIface.(*fileIndex)
+	total := 3 * maxLeafKeys
+	for i := 0; i < total; i++ {
+		if err := idx.Insert(Key(i), RID{PageID: uint32(i + 1)}); err != nil {
+			t.Fatalf("Insert %d failed: %v", i, err)
+		}
+	}
+
+	// Rewrite the file the way BTREE1 left it: page links all zero.
+	for id := uint32(0); id < idx.pageCount; id++ {
+		p, err := idx.readPage(id)
+		if err != nil {
+			t.Fatalf("readPage %d: %v", id, err)
+		}
+		setPageParent(p, 0)
+		setPageNext(p, 0)
+		if err := idx.writePage(id, p); err != nil {
+			t.Fatalf("writePage %d: %v", id, err)
+		}
+	}
+	if _, err := idx.f.WriteAt([]byte("BTREE1"), 0); err != nil {
+		t.Fatalf("write legacy magic: %v", err)
+	}
+	idx.Close()
+
+	idxIface, err = OpenFileIndex(path, Meta{TableName: "t", Column: "id"})
+	if err != nil {
+		t.Fatalf("reopen legacy index: %v", err)
+	}
+	idx = idxIface.(*fileIndex)
+	defer idx.Close()
+
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify after upgrade: %v", err)
+	}
+	magic := make([]byte, len(indexFileMagic))
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.ReadAt(magic, 0); err != nil {
+		t.Fatalf("read magic: %v", err)
+	}
+	if string(magic) != indexFileMagic {
+		t.Fatalf("magic after upgrade = %q, want %q", magic, indexFileMagic)
+	}
+}
+
+func TestMinMaxCount(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "idx.idx")
+
+	idxIface, err := OpenFileIndex(path, Meta{TableName: "t", Column: "id"})
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	idx := idxIface.(*fileIndex)
+	defer idx.Close()
+
+	if _, err := idx.Min(); err != ErrNotFound {
+		t.Fatalf("Min on empty index: err = %v, want ErrNotFound", err)
+	}
+	if _, err := idx.Max(); err != ErrNotFound {
+		t.Fatalf("Max on empty index: err = %v, want ErrNotFound", err)
+	}
+	if n, err := idx.Count(); err != nil || n != 0 {
+		t.Fatalf("Count on empty index = %d, %v; want 0", n, err)
+	}
+
+	// Spread keys over several leaves, inserted out of order, with every
+	// key present twice.
+	distinct := 3 * maxLeafKeys
+	for i := 0; i < distinct; i++ {
+		k := Key((i*37)%distinct - 100)
+		for dup := uint16(0); dup < 2; dup++ {
+			if err := idx.Insert(k, RID{PageID: 1, SlotID: dup}); err != nil {
+				t.Fatalf("Insert %d failed: %v", k, err)
+			}
+		}
+	}
+
+	if k, err := idx.Min(); err != nil || k != -100 {
+		t.Fatalf("Min = %d, %v; want -100", k, err)
+	}
+	if k, err := idx.Max(); err != nil || k != Key(distinct-101) {
+		t.Fatalf("Max = %d, %v; want %d", k, err, distinct-101)
+	}
+	if n, err := idx.Count(); err != nil || n != 2*distinct {
+		t.Fatalf("Count = %d, %v; want %d", n, err, 2*distinct)
+	}
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify with duplicate keys: %v", err)
+	}
+
+	if err := idx.DeleteKey(-100); err != nil {
+		t.Fatalf("DeleteKey failed: %v", err)
+	}
+	if err := idx.Delete(Key(distinct-101), RID{PageID: 1, SlotID: 0}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if k, err := idx.Min(); err != nil || k != -99 {
+		t.Fatalf("Min after delete = %d, %v; want -99", k, err)
+	}
+	if k, err := idx.Max(); err != nil || k != Key(distinct-101) {
+		t.Fatalf("Max after deleting one duplicate = %d, %v; want %d", k, err, distinct-101)
+	}
+	if n, err := idx.Count(); err != nil || n != 2*distinct-3 {
+		t.Fatalf("Count after delete = %d, %v; want %d", n, err, 2*distinct-3)
+	}
+}
+
+func TestAscend(t *testing.T) {
+	idxIface, err := OpenFileIndex(filepath.Join(t.TempDir(), "idx.idx"), Meta{TableName: "t", Column: "id"})
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	idx := idxIface.(*fileIndex)
+	defer idx.Close()
+
+	visited := 0
+	if err := idx.Ascend(func(Key, RID) bool { visited++; return true }); err != nil || visited != 0 {
+		t.Fatalf("Ascend on empty index visited %d entries, err %v", visited, err)
+	}
+
+	// Keys inserted out of order over several leaves.
+	n := 3 * maxLeafKeys
+	for i := 0; i < n; i++ {
+		k := Key((i * 37) % n)
+		if err := idx.Insert(k-50, RID{PageID: uint32(k), SlotID: 1}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	var keys []Key
+	if err := idx.Ascend(func(k Key, rid RID) bool {
+		if rid.PageID != uint32(k+50) {
+			t.Fatalf("key %d carries RID %+v", k, rid)
+		}
+		keys = append(keys, k)
+		return true
+	}); err != nil {
+		t.Fatalf("Ascend failed: %v", err)
+	}
+	if len(keys) != n {
+		t.Fatalf("Ascend visited %d entries, want %d", len(keys), n)
+	}
+	for i, k := range keys {
+		if k != Key(i-50) {
+			t.Fatalf("entry %d has key %d, want %d", i, k, i-50)
+		}
+	}
+
+	visited = 0
+	if err := idx.Ascend(func(Key, RID) bool { visited++; return visited < 5 }); err != nil || visited != 5 {
+		t.Fatalf("Ascend stopped after %d entries (err %v), want 5", visited, err)
+	}
+}
+
+func TestVerifyDetectsCorruption(t *testing.T) {
+	build := func(t *testing.T) *fileIndex {
+		t.Helper()
+		idxIface, err := OpenFileIndex(filepath.Join(t.TempDir(), "idx.idx"), Meta{TableName: "t", Column: "id"})
+		if err != nil {
+			t.Fatalf("OpenFileIndex failed: %v", err)
+		}
+		idx := idxIface.(*fileIndex)
+		t.Cleanup(func() { idx.Close() })
+		for i := 0; i < 2*maxLeafKeys; i++ {
+			if err := idx.Insert(Key(i), RID{PageID: uint32(i + 1)}); err != nil {
+				t.Fatalf("Insert %d failed: %v", i, err)
+			}
+		}
+		if err := idx.Verify(); err != nil {
+			t.Fatalf("Verify on healthy tree: %v", err)
+		}
+		return idx
+	}
+
+	// rewriteRoot lets a case corrupt the root's children and keys.
+	rewriteRoot := func(t *testing.T, idx *fileIndex, edit func(children []uint32, keys []Key)) {
+		t.Helper()
+		p, err := idx.readPage(idx.rootPageID)
+		if err != nil {
+			t.Fatalf("read root: %v", err)
+		}
+		h := readPageHeader(p)
+		children, keys, err := internalReadAll(p, h)
+		if err != nil {
+			t.Fatalf("internalReadAll: %v", err)
+		}
+		edit(children, keys)
+		if err := internalWriteAll(p, h, children, keys); err != nil {
+			t.Fatalf("internalWriteAll: %v", err)
+		}
+		if err := idx.writePage(idx.rootPageID, p); err != nil {
+			t.Fatalf("write root: %v", err)
+		}
+	}
+
+	cases := map[string]func(t *testing.T, idx *fileIndex){
+		"unsorted leaf": func(t *testing.T, idx *fileIndex) {
+			leafID, p, err := idx.findLeafForKey(0)
+			if err != nil {
+				t.Fatalf("findLeafForKey: %v", err)
+			}
+			leafSetEntry(p, 0, 1_000_000, RID{})
+			if err := idx.writePage(leafID, p); err != nil {
+				t.Fatalf("writePage: %v", err)
+			}
+		},
+		"separator out of range": func(t *testing.T, idx *fileIndex) {
+			rewriteRoot(t, idx, func(_ []uint32, keys []Key) { keys[0] = 1 })
+		},
+		"dangling child": func(t *testing.T, idx *fileIndex) {
+			rewriteRoot(t, idx, func(children []uint32, _ []Key) { children[1] = idx.pageCount + 5 })
+		},
+		"shared child": func(t *testing.T, idx *fileIndex) {
+			rewriteRoot(t, idx, func(children []uint32, _ []Key) { children[1] = children[0] })
+		},
+	}
+	for name, corrupt := range cases {
+		t.Run(name, func(t *testing.T) {
+			idx := build(t)
+			corrupt(t, idx)
+			if err := idx.Verify(); err == nil {
+				t.Fatalf("Verify did not report the corruption")
+			}
+		})
+	}
+}
+
+func TestDeleteRange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "idx.idx")
+
+	idxIface, err := OpenFileIndex(path, Meta{TableName: "t", Column: "id"})
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	idx := idxIface.(*fileIndex)
+	defer idx.Close()
+
+	// Two entries per key across a couple of dozen leaves.
+	total := 12 * maxLeafKeys
+	for i := 0; i < total; i++ {
+		for dup := uint16(0); dup < 2; dup++ {
+			if err := idx.Insert(Key(i), RID{PageID: uint32(i + 1), SlotID: dup}); err != nil {
+				t.Fatalf("Insert %d failed: %v", i, err)
+			}
+		}
+	}
+
+	if err := idx.DeleteRange(10, 5); err != nil {
+		t.Fatalf("DeleteRange with lo > hi failed: %v", err)
+	}
+	if n, _ := idx.Count(); n != 2*total {
+		t.Fatalf("Count after empty range = %d, want %d", n, 2*total)
+	}
+
+	lo, hi := Key(maxLeafKeys/2), Key(total-maxLeafKeys)
+	if err := idx.DeleteRange(lo, hi); err != nil {
+		t.Fatalf("DeleteRange failed: %v", err)
+	}
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify after DeleteRange: %v", err)
+	}
+	want := 2 * (total - int(hi-lo+1))
+	if n, err := idx.Count(); err != nil || n != want {
+		t.Fatalf("Count after DeleteRange = %d, %v; want %d", n, err, want)
+	}
+	for _, k := range []Key{0, lo - 1, lo, (lo + hi) / 2, hi, hi + 1, Key(total - 1)} {
+		got, err := idx.Search(k)
+		if err != nil {
+			t.Fatalf("Search %d failed: %v", k, err)
+		}
+		wantN := 2
+		if k >= lo && k <= hi {
+			wantN = 0
+		}
+		if len(got) != wantN {
+			t.Fatalf("Search %d returned %d RIDs, want %d", k, len(got), wantN)
+		}
+	}
+
+	// Removing everything leaves an empty but usable tree.
+	if err := idx.DeleteRange(-1, Key(total)); err != nil {
+		t.Fatalf("DeleteRange(all) failed: %v", err)
+	}
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify after deleting everything: %v", err)
+	}
+	if n, _ := idx.Count(); n != 0 {
+		t.Fatalf("Count after deleting everything = %d, want 0", n)
+	}
+	if err := idx.Insert(7, RID{PageID: 8}); err != nil {
+		t.Fatalf("Insert after DeleteRange failed: %v", err)
+	}
+	if got, _ := idx.Search(7); len(got) != 1 {
+		t.Fatalf("Search after reinsert returned %v", got)
+	}
+}
+
+func TestSearchDuplicatesAcrossSplitsAreSorted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "idx.idx")
+
+	idxIface, err := OpenFileIndex(path, Meta{TableName: "t", Column: "id"})
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	idx := idxIface.(*fileIndex)
+	defer idx.Close()
+
+	for i := 0; i < 100; i++ {
+		if err := idx.Insert(Key(i), RID{PageID: 1, SlotID: uint16(i)}); err != nil {
+			t.Fatalf("Insert %d failed: %v", i, err)
+		}
+	}
+	// More duplicates than fit in one leaf, with RIDs inserted in
+	// descending order so leaf order differs from RID order.
+	dups := 2*maxLeafKeys + 10
+	for i := 0; i < dups; i++ {
+		rid := RID{PageID: uint32(1000 - i/4), SlotID: uint16(3 - i%4)}
+		if err := idx.Insert(50, rid); err != nil {
+			t.Fatalf("Insert duplicate %d failed: %v", i, err)
+		}
+	}
+	if err := idx.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	got, err := idx.Search(50)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(got) != dups+1 {
+		t.Fatalf("Search(50) returned %d RIDs, want %d", len(got), dups+1)
+	}
+	if got[0] != (RID{PageID: 1, SlotID: 50}) {
+		t.Fatalf("first RID = %+v, want {1 50}", got[0])
+	}
+	for i := 1; i < len(got); i++ {
+		a, b := got[i-1], got[i]
+		if a.PageID > b.PageID || (a.PageID == b.PageID && a.SlotID >= b.SlotID) {
+			t.Fatalf("RIDs not sorted at %d: %+v then %+v", i, a, b)
+		}
+	}
+
+	for _, k := range []Key{49, 51} {
+		if got, _ := idx.Search(k); len(got) != 1 {
+			t.Fatalf("Search(%d) returned %d RIDs, want 1", k, len(got))
+		}
+	}
+}
+
+func TestSearchRange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "idx.idx")
+
+	idxIface, err := OpenFileIndex(path, Meta{TableName: "t", Column: "id"})
+	if err != nil {
+		t.Fatalf("OpenFileIndex failed: %v", err)
+	}
+	idx := idxIface.(*fileIndex)
+	defer idx.Close()
+
+	// Keys inserted in descending order, so RID order differs from key
+	// order, with two entries per key across several leaves.
+	total := 6 * maxLeafKeys
+	for i := total - 1; i >= 0; i-- {
+		for dup := uint16(0); dup < 2; dup++ {
+			if err := idx.Insert(Key(i), RID{PageID: uint32(total - i), SlotID: dup}); err != nil {
+				t.Fatalf("Insert %d failed: %v", i, err)
+			}
+		}
+	}
+
+	lo, hi := Key(maxLeafKeys/2), Key(3*maxLeafKeys)
+	got, err := idx.SearchRange(lo, hi)
+	if err != nil {
+		t.Fatalf("SearchRange failed: %v", err)
+	}
+	if want := 2 * int(hi-lo+1); len(got) != want {
+		t.Fatalf("SearchRange returned %d RIDs, want %d", len(got), want)
+	}
+	for i, rid := range got {
+		key := Key(total - int(rid.PageID))
+		if key < lo || key > hi {
+			t.Fatalf("RID %+v has key %d outside [%d, %d]", rid, key, lo, hi)
+		}
+		if i > 0 {
+			a := got[i-1]
+			if a.PageID > rid.PageID || (a.PageID == rid.PageID && a.SlotID >= rid.SlotID) {
+				t.Fatalf("RIDs not sorted at %d: %+v then %+v", i, a, rid)
+			}
+		}
+	}
+
+	if got, _ := idx.SearchRange(hi, lo); len(got) != 0 {
+		t.Fatalf("SearchRange with lo > hi returned %d RIDs", len(got))
+	}
+	if got, _ := idx.SearchRange(Key(total), Key(total+10)); len(got) != 0 {
+		t.Fatalf("SearchRange past the last key returned %d RIDs", len(got))
+	}
+	if got, _ := idx.SearchRange(-5, 0); len(got) != 2 {
+		t.Fatalf("SearchRange(-5, 0) returned %d RIDs, want 2", len(got))
+	}
+}
+
+func TestParseIndexFileName(t *testing.T) {
+	for _, c := range []struct {
+		table, col string
+	}{{"users", "id"}, {"user_profiles", "user_id"}, {"_t", "c_"}} {
+		name := IndexFileName(c.table, c.col)
+		table, col, ok := ParseIndexFileName(name)
+		if !ok || table != c.table || col != c.col {
+			t.Fatalf("ParseIndexFileName(%q) = %q, %q, %v; want %q, %q", name, table, col, ok, c.table, c.col)
+		}
+	}
+	for _, name := range []string{"users_id.idx", "users.id", ".id.idx", "users..idx", "a.b.c.idx"} {
+		if _, _, ok := ParseIndexFileName(name); ok {
+			t.Fatalf("ParseIndexFileName(%q) succeeded", name)
+		}
+	}
 }
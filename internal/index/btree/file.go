@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"sort"
 )
@@ -100,16 +101,18 @@ func (idx *fileIndex) Insert(key Key, rid RID) error {
 		leftKeys[i] = e.k
 		leftRIDs[i] = e.r
 	}
-	leafWriteAll(leafPage, leftKeys, leftRIDs)
-	if err := idx.writePage(leafID, leafPage); err != nil {
+	// Create right leaf and link it in after the left one.
+	rightID, rightPage, err := idx.allocPage(PageTypeLeaf)
+	if err != nil {
 		return err
 	}
 
-	// Create right leaf
-	rightID, rightPage, err := idx.allocPage(PageTypeLeaf)
-	if err != nil {
+	leafWriteAll(leafPage, leftKeys, leftRIDs)
+	setPageNext(leafPage, rightID)
+	if err := idx.writePage(leafID, leafPage); err != nil {
 		return err
 	}
+
 	rightKeys := make([]Key, len(rightEntries))
 	rightRIDs := make([]RID, len(rightEntries))
 	for i, e := range rightEntries {
@@ -117,6 +120,8 @@ func (idx *fileIndex) Insert(key Key, rid RID) error {
 		rightRIDs[i] = e.r
 	}
 	leafWriteAll(rightPage, rightKeys, rightRIDs)
+	setPageParent(rightPage, h.ParentPageID)
+	setPageNext(rightPage, h.NextPageID)
 	if err := idx.writePage(rightID, rightPage); err != nil {
 		return err
 	}
@@ -238,43 +243,349 @@ func (idx *fileIndex) DeleteKey(key Key) error {
 	return nil
 }
 
+// DeleteRange implements Index.DeleteRange. It starts at the leftmost leaf
+// that may hold lo and follows the sibling links, compacting each leaf in
+// place until it passes hi. Leaves left underfull are then rebalanced one
+// step each, which keeps the tree valid without restoring full occupancy.
+func (idx *fileIndex) DeleteRange(lo, hi Key) error {
+	if lo > hi {
+		return nil
+	}
+
+	pageID, err := idx.findFirstLeafFor(lo)
+	if err != nil {
+		return err
+	}
+
+	var affected []uint32
+	for pageID != noPage {
+		p, err := idx.readPage(pageID)
+		if err != nil {
+			return err
+		}
+		h := readPageHeader(p)
+		if h.PageType != PageTypeLeaf {
+			return fmt.Errorf("btree: DeleteRange: expected leaf, got type %d", h.PageType)
+		}
+
+		keys, rids := leafReadAll(p, h)
+		keptKeys := keys[:0:0]
+		keptRIDs := rids[:0:0]
+		for i, k := range keys {
+			if k < lo || k > hi {
+				keptKeys = append(keptKeys, k)
+				keptRIDs = append(keptRIDs, rids[i])
+			}
+		}
+		if len(keptKeys) != len(keys) {
+			leafWriteAll(p, keptKeys, keptRIDs)
+			if err := idx.writePage(pageID, p); err != nil {
+				return err
+			}
+			affected = append(affected, pageID)
+		}
+
+		if len(keys) > 0 && keys[len(keys)-1] > hi {
+			break
+		}
+		pageID = h.NextPageID
+	}
+
+	for _, leafID := range affected {
+		if leafID == idx.rootPageID {
+			continue
+		}
+		// An earlier rebalance may have merged this leaf away.
+		path, ok, err := idx.pathTo(leafID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		p, err := idx.readPage(leafID)
+		if err != nil {
+			return err
+		}
+		n := readPageHeader(p).NumKeys
+		if n > 0 {
+			if err := idx.updateAncestorMinKeys(leafID, path); err != nil {
+				return err
+			}
+		}
+		if n < uint32(minLeafKeys) {
+			if err := idx.rebalanceAfterDelete(leafID, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findFirstLeafFor returns the leftmost leaf that can hold key. Duplicates of
+// a separator may sit on both sides of it, so the descent goes left on
+// equality and callers continue along the sibling links.
+func (idx *fileIndex) findFirstLeafFor(key Key) (uint32, error) {
+	pageID := idx.rootPageID
+	for {
+		p, err := idx.readPage(pageID)
+		if err != nil {
+			return 0, err
+		}
+		h := readPageHeader(p)
+		switch h.PageType {
+		case PageTypeLeaf:
+			return pageID, nil
+		case PageTypeInternal:
+			i := uint32(0)
+			for i < h.NumKeys && key > internalGetKey(p, i) {
+				i++
+			}
+			pageID = internalGetChild(p, i)
+		default:
+			return 0, fmt.Errorf("btree: unknown page type %d at page %d", h.PageType, pageID)
+		}
+	}
+}
+
+// pathTo rebuilds the root-to-page path for pageID by following parent
+// pointers upwards. ok is false when the page is no longer linked into the
+// tree, for example after being merged into a sibling.
+func (idx *fileIndex) pathTo(pageID uint32) (path []uint32, ok bool, err error) {
+	path = []uint32{pageID}
+	for cur := pageID; cur != idx.rootPageID; {
+		p, err := idx.readPage(cur)
+		if err != nil {
+			return nil, false, err
+		}
+		parentID := readPageHeader(p).ParentPageID
+		if parentID == noParent || parentID >= idx.pageCount || len(path) > int(idx.pageCount) {
+			return nil, false, nil
+		}
+
+		pp, err := idx.readPage(parentID)
+		if err != nil {
+			return nil, false, err
+		}
+		ph := readPageHeader(pp)
+		if ph.PageType != PageTypeInternal {
+			return nil, false, nil
+		}
+		children, _, err := internalReadAll(pp, ph)
+		if err != nil {
+			return nil, false, err
+		}
+		linked := false
+		for _, c := range children {
+			if c == cur {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			return nil, false, nil
+		}
+
+		path = append([]uint32{parentID}, path...)
+		cur = parentID
+	}
+	return path, true, nil
+}
+
 // Search implements Index.Search: return all RIDs for a given key.
+//
+// Duplicates of a key can span several leaves after splits, so the search
+// starts at the leftmost leaf that may hold key and follows the sibling
+// links until it sees a larger key. The RIDs are returned sorted by PageID
+// and then SlotID, independent of the order they were inserted or how the
+// leaves were split.
 func (idx *fileIndex) Search(key Key) ([]RID, error) {
-	_, p, err := idx.findLeafForKey(key)
+	pageID, err := idx.findFirstLeafFor(key)
 	if err != nil {
 		return nil, err
 	}
 
-	h := readPageHeader(p)
-	if h.PageType != PageTypeLeaf {
-		return nil, fmt.Errorf("btree: Search: expected leaf, got type %d", h.PageType)
-	}
-	n := h.NumKeys
+	var rids []RID
+	for pageID != noPage {
+		p, err := idx.readPage(pageID)
+		if err != nil {
+			return nil, err
+		}
+		h := readPageHeader(p)
+		if h.PageType != PageTypeLeaf {
+			return nil, fmt.Errorf("btree: Search: expected leaf, got type %d", h.PageType)
+		}
+		n := h.NumKeys
+
+		// Binary search for first position >= key
+		lo, hi := uint32(0), n
+		for lo < hi {
+			mid := (lo + hi) / 2
+			k := leafGetKey(p, mid)
+			if key > k {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
 
-	// Binary search for first position >= key
-	lo, hi := uint32(0), n
-	for lo < hi {
-		mid := (lo + hi) / 2
-		k := leafGetKey(p, mid)
-		if key > k {
-			lo = mid + 1
-		} else {
-			hi = mid
+		// Collect all equal keys from lo onwards
+		for i := lo; i < n; i++ {
+			if leafGetKey(p, i) != key {
+				sortRIDs(rids)
+				return rids, nil
+			}
+			rids = append(rids, leafGetRID(p, i))
 		}
+		pageID = h.NextPageID
+	}
+	sortRIDs(rids)
+	return rids, nil
+}
+
+// SearchRange implements Index.SearchRange. Like Search, it starts at the
+// leftmost leaf that may hold lo and follows the sibling links until it
+// passes hi, and returns the RIDs sorted by PageID and then SlotID.
+func (idx *fileIndex) SearchRange(lo, hi Key) ([]RID, error) {
+	if lo > hi {
+		return nil, nil
+	}
+
+	pageID, err := idx.findFirstLeafFor(lo)
+	if err != nil {
+		return nil, err
 	}
 
-	// Collect all equal keys from lo onwards
 	var rids []RID
-	for i := lo; i < n; i++ {
-		k := leafGetKey(p, i)
-		if k != key {
-			break
+	for pageID != noPage {
+		p, err := idx.readPage(pageID)
+		if err != nil {
+			return nil, err
 		}
-		rids = append(rids, leafGetRID(p, i))
+		h := readPageHeader(p)
+		if h.PageType != PageTypeLeaf {
+			return nil, fmt.Errorf("btree: SearchRange: expected leaf, got type %d", h.PageType)
+		}
+		for i := uint32(0); i < h.NumKeys; i++ {
+			k := leafGetKey(p, i)
+			if k > hi {
+				sortRIDs(rids)
+				return rids, nil
+			}
+			if k >= lo {
+				rids = append(rids, leafGetRID(p, i))
+			}
+		}
+		pageID = h.NextPageID
 	}
+	sortRIDs(rids)
 	return rids, nil
 }
 
+// sortRIDs orders rids by PageID, then SlotID.
+func sortRIDs(rids []RID) {
+	sort.Slice(rids, func(i, j int) bool {
+		if rids[i].PageID != rids[j].PageID {
+			return rids[i].PageID < rids[j].PageID
+		}
+		return rids[i].SlotID < rids[j].SlotID
+	})
+}
+
+// Min implements Index.Min by descending to the leftmost leaf.
+func (idx *fileIndex) Min() (Key, error) {
+	k, ok, err := idx.findMinKey(idx.rootPageID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return k, nil
+}
+
+// Max implements Index.Max by descending to the rightmost leaf.
+func (idx *fileIndex) Max() (Key, error) {
+	pageID := idx.rootPageID
+	for {
+		p, err := idx.readPage(pageID)
+		if err != nil {
+			return 0, err
+		}
+		h := readPageHeader(p)
+		switch h.PageType {
+		case PageTypeLeaf:
+			if h.NumKeys == 0 {
+				return 0, ErrNotFound
+			}
+			return leafGetKey(p, h.NumKeys-1), nil
+		case PageTypeInternal:
+			pageID = internalGetChild(p, h.NumKeys)
+		default:
+			return 0, fmt.Errorf("btree: Max: unknown page type %d at page %d", h.PageType, pageID)
+		}
+	}
+}
+
+// Ascend implements Index.Ascend by starting at the leftmost leaf and
+// following the sibling links.
+func (idx *fileIndex) Ascend(fn func(key Key, rid RID) bool) error {
+	pageID, err := idx.findFirstLeafFor(math.MinInt64)
+	if err != nil {
+		return err
+	}
+	for pageID != noPage {
+		p, err := idx.readPage(pageID)
+		if err != nil {
+			return err
+		}
+		h := readPageHeader(p)
+		if h.PageType != PageTypeLeaf {
+			return fmt.Errorf("btree: Ascend: expected leaf, got type %d", h.PageType)
+		}
+		for i := uint32(0); i < h.NumKeys; i++ {
+			if !fn(leafGetKey(p, i), leafGetRID(p, i)) {
+				return nil
+			}
+		}
+		pageID = h.NextPageID
+	}
+	return nil
+}
+
+// Count implements Index.Count by summing NumKeys over every leaf.
+func (idx *fileIndex) Count() (int, error) {
+	return idx.countFrom(idx.rootPageID)
+}
+
+func (idx *fileIndex) countFrom(pageID uint32) (int, error) {
+	p, err := idx.readPage(pageID)
+	if err != nil {
+		return 0, err
+	}
+	h := readPageHeader(p)
+	switch h.PageType {
+	case PageTypeLeaf:
+		return int(h.NumKeys), nil
+	case PageTypeInternal:
+		children, _, err := internalReadAll(p, h)
+		if err != nil {
+			return 0, err
+		}
+		total := 0
+		for _, c := range children {
+			n, err := idx.countFrom(c)
+			if err != nil {
+				return 0, err
+			}
+			total += n
+		}
+		return total, nil
+	default:
+		return 0, fmt.Errorf("btree: Count: unknown page type %d at page %d", h.PageType, pageID)
+	}
+}
+
 func (idx *fileIndex) Close() error {
 	if idx.f != nil {
 		err := idx.f.Close()
@@ -303,7 +614,9 @@ func writeFileHeader(f *os.File, root, pages uint32) error {
 	return err
 }
 
-func readFileHeader(f *os.File) (root uint32, pages uint32, err error) {
+// readFileHeader reads the file header. legacy is set for files from older
+// versions whose page links need rebuilding.
+func readFileHeader(f *os.File) (root uint32, pages uint32, legacy bool, err error) {
 	if _, err = f.Seek(0, io.SeekStart); err != nil {
 		return
 	}
@@ -313,8 +626,15 @@ func readFileHeader(f *os.File) (root uint32, pages uint32, err error) {
 		return
 	}
 	if string(magic) != indexFileMagic {
-		err = fmt.Errorf("btree: bad index magic")
-		return
+		for _, m := range legacyIndexFileMagics {
+			if string(magic) == m {
+				legacy = true
+			}
+		}
+		if !legacy {
+			err = fmt.Errorf("btree: bad index magic")
+			return
+		}
 	}
 
 	buf := make([]byte, 8)
@@ -346,8 +666,9 @@ func OpenFileIndex(path string, meta Meta) (Index, error) {
 		rootPage := make([]byte, PageSize)
 		h := PageHeader{
 			PageType:     PageTypeLeaf,
-			ParentPageID: 0,
+			ParentPageID: noParent,
 			NumKeys:      0,
+			NextPageID:   noPage,
 		}
 		writePageHeader(rootPage, h)
 
@@ -365,12 +686,25 @@ func OpenFileIndex(path string, meta Meta) (Index, error) {
 	}
 
 	// Existing index → read header
-	root, pages, err := readFileHeader(f)
+	root, pages, legacy, err := readFileHeader(f)
 	if err != nil {
 		return nil, err
 	}
 	idx.rootPageID = root
 	idx.pageCount = pages
+
+	if legacy {
+		// Older files wrote 0 for parent pointers and leaf links. Fill
+		// them in once and mark the file as upgraded.
+		if err := idx.relink(); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("btree: upgrade page links: %w", err)
+		}
+		if err := writeFileHeader(f, idx.rootPageID, idx.pageCount); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
 	return idx, nil
 }
 func (idx *fileIndex) pageOffset(pageID uint32) int64 {
@@ -449,8 +783,9 @@ func (idx *fileIndex) allocPage(pageType uint8) (uint32, []byte, error) {
 	p := make([]byte, PageSize)
 	h := PageHeader{
 		PageType:     pageType,
-		ParentPageID: 0, // we won't rely on this yet
+		ParentPageID: noParent, // set once the page is linked into the tree
 		NumKeys:      0,
+		NextPageID:   noPage,
 	}
 	writePageHeader(p, h)
 
@@ -477,14 +812,18 @@ func leafReadAll(p []byte, h PageHeader) ([]Key, []RID) {
 	return keys, rids
 }
 
+// leafWriteAll rewrites the entries of leaf page p, keeping its parent
+// pointer and sibling link.
 func leafWriteAll(p []byte, keys []Key, rids []RID) {
 	if len(keys) != len(rids) {
 		panic("leafWriteAll: keys and rids length mismatch")
 	}
+	old := readPageHeader(p)
 	h := PageHeader{
 		PageType:     PageTypeLeaf,
-		ParentPageID: 0, // we ignore for now
+		ParentPageID: old.ParentPageID,
 		NumKeys:      uint32(len(keys)),
+		NextPageID:   old.NextPageID,
 	}
 	writePageHeader(p, h)
 
@@ -610,8 +949,9 @@ func (idx *fileIndex) insertIntoParent(leftID, rightID uint32, sepKey Key, path
 
 		h := PageHeader{
 			PageType:     PageTypeInternal,
-			ParentPageID: 0,
+			ParentPageID: noParent,
 			NumKeys:      1,
+			NextPageID:   noPage,
 		}
 		children := []uint32{leftID, rightID}
 		keys := []Key{sepKey}
@@ -622,6 +962,9 @@ func (idx *fileIndex) insertIntoParent(leftID, rightID uint32, sepKey Key, path
 		if err := idx.writePage(rootID, rootPage); err != nil {
 			return err
 		}
+		if err := idx.setParents(children, rootID); err != nil {
+			return err
+		}
 
 		// Update in-memory and on-disk header
 		idx.rootPageID = rootID
@@ -677,6 +1020,10 @@ func (idx *fileIndex) insertIntoParent(leftID, rightID uint32, sepKey Key, path
 
 	hp.NumKeys = uint32(n + 1)
 
+	if err := idx.setParent(rightID, parentID); err != nil {
+		return err
+	}
+
 	if hp.NumKeys <= uint32(maxInternalKeys) {
 		if err := internalWriteAll(parentPage, hp, children, keys); err != nil {
 			return err
@@ -712,13 +1059,16 @@ func (idx *fileIndex) insertIntoParent(leftID, rightID uint32, sepKey Key, path
 	if err != nil {
 		return err
 	}
-	rightHeader := PageHeader{PageType: PageTypeInternal, ParentPageID: 0, NumKeys: uint32(len(rightKeys))}
+	rightHeader := PageHeader{PageType: PageTypeInternal, ParentPageID: hp.ParentPageID, NumKeys: uint32(len(rightKeys)), NextPageID: noPage}
 	if err := internalWriteAll(rightParentPage, rightHeader, rightChildren, rightKeys); err != nil {
 		return err
 	}
 	if err := idx.writePage(rightParentID, rightParentPage); err != nil {
 		return err
 	}
+	if err := idx.setParents(rightChildren, rightParentID); err != nil {
+		return err
+	}
 
 	return idx.insertIntoParent(parentID, rightParentID, promote, parentPath)
 }
@@ -947,6 +1297,7 @@ func (idx *fileIndex) rebalanceLeaf(nodeID uint32, nodePage []byte, pos int, par
 		mergedRIDs := append(leftRIDs, nodeRIDs...)
 
 		leafWriteAll(leftPage, mergedKeys, mergedRIDs)
+		setPageNext(leftPage, nh.NextPageID)
 		if err := idx.writePage(leftID, leftPage); err != nil {
 			return err
 		}
@@ -982,6 +1333,7 @@ func (idx *fileIndex) rebalanceLeaf(nodeID uint32, nodePage []byte, pos int, par
 	mergedRIDs := append(nodeRIDs, rightRIDs...)
 
 	leafWriteAll(nodePage, mergedKeys, mergedRIDs)
+	setPageNext(nodePage, rh.NextPageID)
 	if err := idx.writePage(nodeID, nodePage); err != nil {
 		return err
 	}
@@ -1048,6 +1400,9 @@ func (idx *fileIndex) rebalanceInternal(nodeID uint32, nodePage []byte, pos int,
 			if err := idx.writePage(nodeID, nodePage); err != nil {
 				return err
 			}
+			if err := idx.setParent(borrowedChild, nodeID); err != nil {
+				return err
+			}
 
 			if err := internalWriteAll(parentPage, ph, children, keys); err != nil {
 				return err
@@ -1098,6 +1453,9 @@ func (idx *fileIndex) rebalanceInternal(nodeID uint32, nodePage []byte, pos int,
 			if err := idx.writePage(nodeID, nodePage); err != nil {
 				return err
 			}
+			if err := idx.setParent(borrowedChild, nodeID); err != nil {
+				return err
+			}
 
 			if err := internalWriteAll(parentPage, ph, children, keys); err != nil {
 				return err
@@ -1134,6 +1492,9 @@ func (idx *fileIndex) rebalanceInternal(nodeID uint32, nodePage []byte, pos int,
 		if err := idx.writePage(leftID, leftPage); err != nil {
 			return err
 		}
+		if err := idx.setParents(nodeChildren, leftID); err != nil {
+			return err
+		}
 
 		children = append(children[:pos], children[pos+1:]...)
 		keys = append(keys[:pos-1], keys[pos:]...)
@@ -1175,6 +1536,9 @@ func (idx *fileIndex) rebalanceInternal(nodeID uint32, nodePage []byte, pos int,
 	if err := idx.writePage(nodeID, nodePage); err != nil {
 		return err
 	}
+	if err := idx.setParents(rightChildren, nodeID); err != nil {
+		return err
+	}
 
 	children = append(children[:pos+1], children[pos+2:]...)
 	keys = append(keys[:pos], keys[pos+1:]...)
@@ -1197,6 +1561,9 @@ func (idx *fileIndex) handleParentAfterMerge(parentID uint32, parentPath []uint3
 	if parentID == idx.rootPageID {
 		if len(keys) == 0 && len(children) == 1 {
 			idx.rootPageID = children[0]
+			if err := idx.setParent(idx.rootPageID, noParent); err != nil {
+				return err
+			}
 			if err := writeFileHeader(idx.f, idx.rootPageID, idx.pageCount); err != nil {
 				return err
 			}
@@ -1215,3 +1582,197 @@ func (idx *fileIndex) handleParentAfterMerge(parentID uint32, parentPath []uint3
 
 	return idx.rebalanceAfterDelete(parentID, parentPath)
 }
+
+// setPageParent points the header of page p at parentID.
+func setPageParent(p []byte, parentID uint32) {
+	h := readPageHeader(p)
+	h.ParentPageID = parentID
+	writePageHeader(p, h)
+}
+
+// setPageNext points the sibling link of leaf page p at nextID.
+func setPageNext(p []byte, nextID uint32) {
+	h := readPageHeader(p)
+	h.NextPageID = nextID
+	writePageHeader(p, h)
+}
+
+// setParent records parentID as the parent of page pageID, writing the page
+// only when the pointer changes.
+func (idx *fileIndex) setParent(pageID, parentID uint32) error {
+	p, err := idx.readPage(pageID)
+	if err != nil {
+		return err
+	}
+	h := readPageHeader(p)
+	if h.ParentPageID == parentID {
+		return nil
+	}
+	h.ParentPageID = parentID
+	writePageHeader(p, h)
+	return idx.writePage(pageID, p)
+}
+
+// relink rebuilds every parent pointer and leaf sibling link from the tree
+// structure. It is used to upgrade files written before those were kept.
+func (idx *fileIndex) relink() error {
+	var leaves []uint32
+	var walk func(pageID, parentID uint32) error
+	walk = func(pageID, parentID uint32) error {
+		p, err := idx.readPage(pageID)
+		if err != nil {
+			return err
+		}
+		h := readPageHeader(p)
+		h.ParentPageID = parentID
+		h.NextPageID = noPage
+		writePageHeader(p, h)
+		if err := idx.writePage(pageID, p); err != nil {
+			return err
+		}
+		if h.PageType == PageTypeLeaf {
+			leaves = append(leaves, pageID)
+			return nil
+		}
+		children, _, err := internalReadAll(p, h)
+		if err != nil {
+			return err
+		}
+		for _, c := range children {
+			if err := walk(c, pageID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(idx.rootPageID, noParent); err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < len(leaves); i++ {
+		p, err := idx.readPage(leaves[i])
+		if err != nil {
+			return err
+		}
+		setPageNext(p, leaves[i+1])
+		if err := idx.writePage(leaves[i], p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setParents records parentID as the parent of every page in children.
+func (idx *fileIndex) setParents(children []uint32, parentID uint32) error {
+	for _, c := range children {
+		if err := idx.setParent(c, parentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify walks the whole tree and checks its structural invariants: every
+// page reference is in range and reached exactly once, keys are sorted
+// within each node and lie between the separators that lead to it, internal
+// nodes have one more child than keys, all leaves sit at the same depth,
+// every page's ParentPageID names the page that references it and the leaf
+// sibling links visit the leaves in key order. It is a
+// diagnostic for catching split and delete bugs; the first violation found
+// is returned.
+func (idx *fileIndex) Verify() error {
+	v := &verifier{idx: idx, seen: make(map[uint32]bool), leafDepth: -1, prevNext: noPage}
+	if err := v.walk(idx.rootPageID, noParent, 0, nil, nil); err != nil {
+		return err
+	}
+	if v.prevNext != noPage {
+		return fmt.Errorf("btree: verify: last leaf %d links to page %d", v.prevLeaf, v.prevNext)
+	}
+	return nil
+}
+
+type verifier struct {
+	idx       *fileIndex
+	seen      map[uint32]bool
+	leafDepth int
+
+	// prevLeaf and prevNext describe the last leaf visited, whose sibling
+	// link must point at the next leaf in key order.
+	prevLeaf uint32
+	prevNext uint32
+}
+
+// walk checks the subtree at pageID. lo and hi, when set, are the separator
+// keys bounding it in its parent; duplicates of a separator may sit on either
+// side of it, so both bounds are inclusive.
+func (v *verifier) walk(pageID, parentID uint32, depth int, lo, hi *Key) error {
+	if pageID >= v.idx.pageCount {
+		return fmt.Errorf("btree: verify: page %d out of range (page count %d)", pageID, v.idx.pageCount)
+	}
+	if v.seen[pageID] {
+		return fmt.Errorf("btree: verify: page %d is referenced more than once", pageID)
+	}
+	v.seen[pageID] = true
+
+	p, err := v.idx.readPage(pageID)
+	if err != nil {
+		return err
+	}
+	h := readPageHeader(p)
+	if h.ParentPageID != parentID {
+		return fmt.Errorf("btree: verify: page %d has parent %d, want %d", pageID, h.ParentPageID, parentID)
+	}
+
+	var keys []Key
+	var children []uint32
+	switch h.PageType {
+	case PageTypeLeaf:
+		if h.NumKeys > uint32(maxLeafKeys) {
+			return fmt.Errorf("btree: verify: leaf %d has %d keys, max %d", pageID, h.NumKeys, maxLeafKeys)
+		}
+		keys, _ = leafReadAll(p, h)
+		if v.leafDepth < 0 {
+			v.leafDepth = depth
+		} else if depth != v.leafDepth {
+			return fmt.Errorf("btree: verify: leaf %d at depth %d, want %d", pageID, depth, v.leafDepth)
+		} else if v.prevNext != pageID {
+			return fmt.Errorf("btree: verify: leaf %d links to page %d, want %d", v.prevLeaf, v.prevNext, pageID)
+		}
+		v.prevLeaf, v.prevNext = pageID, h.NextPageID
+	case PageTypeInternal:
+		if h.NumKeys == 0 || h.NumKeys > uint32(maxInternalKeys) {
+			return fmt.Errorf("btree: verify: internal page %d has %d keys", pageID, h.NumKeys)
+		}
+		if children, keys, err = internalReadAll(p, h); err != nil {
+			return err
+		}
+		if len(children) != len(keys)+1 {
+			return fmt.Errorf("btree: verify: internal page %d has %d children for %d keys", pageID, len(children), len(keys))
+		}
+	default:
+		return fmt.Errorf("btree: verify: unknown page type %d at page %d", h.PageType, pageID)
+	}
+
+	for i, k := range keys {
+		if i > 0 && k < keys[i-1] {
+			return fmt.Errorf("btree: verify: page %d keys out of order at position %d", pageID, i)
+		}
+		if (lo != nil && k < *lo) || (hi != nil && k > *hi) {
+			return fmt.Errorf("btree: verify: page %d key %d outside its parent's separator range", pageID, k)
+		}
+	}
+
+	for i, c := range children {
+		clo, chi := lo, hi
+		if i > 0 {
+			clo = &keys[i-1]
+		}
+		if i < len(keys) {
+			chi = &keys[i]
+		}
+		if err := v.walk(c, pageID, depth+1, clo, chi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
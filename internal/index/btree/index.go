@@ -23,9 +23,27 @@ type Index interface {
 	// DeleteKey removes all RIDs for a given key (optional, but handy).
 	DeleteKey(key Key) error
 
+	// DeleteRange removes every mapping whose key lies in [lo, hi].
+	DeleteRange(lo, hi Key) error
+
 	// Search returns all RIDs for a key.
 	Search(key Key) ([]RID, error)
 
+	// SearchRange returns the RIDs of all keys in [lo, hi].
+	SearchRange(lo, hi Key) ([]RID, error)
+
+	// Min and Max return the smallest and largest key in the index, or
+	// ErrNotFound when it is empty.
+	Min() (Key, error)
+	Max() (Key, error)
+
+	// Count returns the number of key -> rid mappings in the index.
+	Count() (int, error)
+
+	// Ascend calls fn for every key -> rid mapping in ascending key order
+	// until fn returns false.
+	Ascend(fn func(key Key, rid RID) bool) error
+
 	// Close flushes and closes the index file.
 	Close() error
 }
@@ -1,7 +1,11 @@
 package btree
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -9,7 +13,7 @@ import (
 type Manager struct {
 	dir  string
 	mu   sync.Mutex
-	open map[string]Index // key: indexFileName or "table.column"
+	open map[string]Index // key: "table.column"
 }
 
 // NewManager creates a new index manager rooted at dir.
@@ -20,8 +24,43 @@ func NewManager(dir string) *Manager {
 	}
 }
 
-// indexFileName is a simple convention: table_column.idx
-func indexFileName(table, col string) string {
+// IndexFileName returns the name of the file holding the index on
+// (table, col): table.column.idx. SQL identifiers cannot contain dots, so
+// the name parses back unambiguously with ParseIndexFileName.
+func IndexFileName(table, col string) string {
+	return table + "." + col + ".idx"
+}
+
+// ParseIndexFileName returns the table and column of an index file named by
+// IndexFileName. ok is false for any other name, including the legacy
+// table_column.idx names, which cannot be split reliably when the table or
+// column contains an underscore.
+func ParseIndexFileName(name string) (table, col string, ok bool) {
+	base, ok := strings.CutSuffix(name, ".idx")
+	if !ok {
+		return "", "", false
+	}
+	table, col, ok = strings.Cut(base, ".")
+	if !ok || table == "" || col == "" || strings.Contains(col, ".") {
+		return "", "", false
+	}
+	return table, col, true
+}
+
+// checkNames fails for a table or column name that would not make a single,
+// parseable file name.
+func checkNames(names ...string) error {
+	for _, n := range names {
+		if n == "" || strings.ContainsAny(n, "/\\.\x00") {
+			return fmt.Errorf("btree: invalid table or column name %q", n)
+		}
+	}
+	return nil
+}
+
+// legacyIndexFileName is the name index files had before IndexFileName:
+// table_column.idx.
+func legacyIndexFileName(table, col string) string {
 	return table + "_" + col + ".idx"
 }
 
@@ -41,10 +80,14 @@ func (m *Manager) OpenOrCreateIndex(table, col string) (Index, error) {
 		return idx, nil
 	}
 
-	fileName := indexFileName(table, col)
-	path := filepath.Join(m.dir, fileName)
+	if err := checkNames(table, col); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(m.dir, IndexFileName(table, col))
+	if err := m.migrateLegacyFile(table, col, path); err != nil {
+		return nil, err
+	}
 
-	// We'll implement OpenFileIndex in the next step (B-Tree on disk).
 	idx, err := OpenFileIndex(path, Meta{
 		TableName: table,
 		Column:    col,
@@ -57,6 +100,52 @@ func (m *Manager) OpenOrCreateIndex(table, col string) (Index, error) {
 	return idx, nil
 }
 
+// migrateLegacyFile renames the legacy file of (table, col) to path, unless
+// path already exists.
+func (m *Manager) migrateLegacyFile(table, col, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	legacy := filepath.Join(m.dir, legacyIndexFileName(table, col))
+	if err := os.Rename(legacy, path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("btree: rename %s: %w", filepath.Base(legacy), err)
+	}
+	return nil
+}
+
+// RenameIndex moves the index of (table, oldCol) to the file of
+// (table, newCol), for a renamed column. An open index stays open and is
+// returned for the new name from then on.
+func (m *Manager) RenameIndex(table, oldCol, newCol string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := checkNames(table, oldCol, newCol); err != nil {
+		return err
+	}
+	oldPath := filepath.Join(m.dir, IndexFileName(table, oldCol))
+	if err := m.migrateLegacyFile(table, oldCol, oldPath); err != nil {
+		return err
+	}
+	newPath := filepath.Join(m.dir, IndexFileName(table, newCol))
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("btree: index file %s already exists", filepath.Base(newPath))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+
+	if idx, ok := m.open[indexKey(table, oldCol)]; ok {
+		delete(m.open, indexKey(table, oldCol))
+		m.open[indexKey(table, newCol)] = idx
+	}
+	return nil
+}
+
 // CloseAll closes all open indexes.
 func (m *Manager) CloseAll() error {
 	m.mu.Lock()
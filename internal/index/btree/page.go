@@ -11,18 +11,32 @@ const (
 	PageTypeLeaf     = 1
 	PageTypeInternal = 2
 
-	indexFileMagic = "BTREE1" // 6 bytes
+	indexFileMagic = "BTREE3" // 6 bytes
+
+	// noParent is the ParentPageID of the root page.
+	noParent = ^uint32(0)
+
+	// noPage is the NextPageID of the rightmost leaf and of internal pages.
+	noPage = ^uint32(0)
 )
 
+// legacyIndexFileMagics mark files written before parent pointers (BTREE1)
+// and leaf sibling links (BTREE2) were maintained. They are upgraded when
+// opened.
+var legacyIndexFileMagics = []string{"BTREE1", "BTREE2"}
+
 var (
 	ErrBadPage = errors.New("btree: bad page")
 )
 
-// PageHeader describes the fixed part of an index page.
+// PageHeader describes the fixed part of an index page. ParentPageID names
+// the internal page that points at this one, or noParent for the root. For
+// leaves, NextPageID links to the leaf holding the next larger keys.
 type PageHeader struct {
 	PageType     uint8
 	ParentPageID uint32
 	NumKeys      uint32
+	NextPageID   uint32
 }
 
 func readPageHeader(p []byte) PageHeader {
@@ -30,6 +44,7 @@ func readPageHeader(p []byte) PageHeader {
 		PageType:     p[0],
 		ParentPageID: binary.LittleEndian.Uint32(p[4:8]),
 		NumKeys:      binary.LittleEndian.Uint32(p[8:12]),
+		NextPageID:   binary.LittleEndian.Uint32(p[12:16]),
 	}
 }
 
@@ -38,6 +53,7 @@ func writePageHeader(p []byte, h PageHeader) {
 	// p[1:4] unused
 	binary.LittleEndian.PutUint32(p[4:8], h.ParentPageID)
 	binary.LittleEndian.PutUint32(p[8:12], h.NumKeys)
+	binary.LittleEndian.PutUint32(p[12:16], h.NextPageID)
 }
 
 func leafGetKey(p []byte, idx uint32) Key {
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// queryRequest is the body accepted by POST /query.
+type queryRequest struct {
+	SQL string `json:"sql"`
+}
+
+// queryResponse is the body returned by POST /query on success. Types holds
+// the type name of each column, such as "INT" or "STRING". Statements
+// without a result set return empty columns, types and rows.
+type queryResponse struct {
+	Columns []string `json:"columns"`
+	Types   []string `json:"types"`
+	Rows    [][]any  `json:"rows"`
+}
+
+// errorResponse is the body returned when a request fails.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// HTTPHandler returns a handler serving the JSON query API:
+//
+//	POST /query  {"sql": "SELECT ..."}  ->  {"columns": [...], "rows": [[...]]}
+//	GET /metrics                        ->  Prometheus text format
+//
+// Each request runs one statement in its own session, so a transaction
+// cannot span requests; use the TCP protocol for that.
+func (s *Server) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", s.handleQuery)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	return mux
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "use POST"})
+		return
+	}
+
+	var req queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	stmt, err := sql.ParseCached(req.SQL)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("parse error: %v", err)})
+		return
+	}
+
+	ss, err := s.newSession()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	defer ss.close()
+
+	res, err := ss.execute(r.Context(), stmt)
+	if err != nil {
+		writeJSON(w, errorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	resp := queryResponse{
+		Columns: res.Columns,
+		Types:   make([]string, len(res.ColumnTypes)),
+		Rows:    make([][]any, len(res.Rows)),
+	}
+	if resp.Columns == nil {
+		resp.Columns = []string{}
+	}
+	for i, ct := range res.ColumnTypes {
+		resp.Types[i] = ct.Type.String()
+	}
+	for i, row := range res.Rows {
+		out := make([]any, len(row))
+		for j, v := range row {
+			out[j] = jsonValue(v)
+		}
+		resp.Rows[i] = out
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// errorStatus maps a failed statement to an HTTP status: 404 when it names
+// a table that does not exist, 409 when another transaction holds the
+// database, and 400 for any other error in the statement.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrTableNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrBusy):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// jsonValue maps a sql.Value to the Go value encoding/json renders as the
+// matching JSON type.
+func jsonValue(v sql.Value) any {
+	switch v.Type {
+	case sql.TypeInt:
+		return v.I64
+	case sql.TypeFloat:
+		return v.F64
+	case sql.TypeString:
+		return v.S
+	case sql.TypeBool:
+		return v.B
+	case sql.TypeTimestamp:
+		return sql.FormatTimestamp(v)
+	case sql.TypeBytes:
+		return sql.FormatBlob(v)
+	default:
+		return nil
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
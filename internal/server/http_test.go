@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goDB/internal/storage/filestore"
+	"goDB/internal/storage/memstore"
+)
+
+// postQuery sends one statement to the handler and returns the response.
+func postQuery(t *testing.T, h http.Handler, query string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, err := json.Marshal(queryRequest{SQL: query})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/query", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHTTPQuery(t *testing.T) {
+	h := New(memstore.New()).HTTPHandler()
+
+	for _, q := range []string{
+		"CREATE TABLE users (id INT, name STRING, score FLOAT, active BOOL);",
+		"INSERT INTO users VALUES (1, 'Alice', 1.5, true);",
+		"INSERT INTO users VALUES (2, NULL, 2.0, false);",
+	} {
+		rec := postQuery(t, h, q)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status %d, body %s", q, rec.Code, rec.Body)
+		}
+		if got, want := rec.Body.String(), `{"columns":[],"types":[],"rows":[]}`+"\n"; got != want {
+			t.Fatalf("%q: body %q, want %q", q, got, want)
+		}
+	}
+
+	rec := postQuery(t, h, "SELECT * FROM users ORDER BY id;")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("SELECT: status %d, body %s", rec.Code, rec.Body)
+	}
+	want := `{"columns":["id","name","score","active"],"types":["INT","STRING","FLOAT","BOOL"],"rows":[[1,"Alice",1.5,true],[2,null,2,false]]}` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("SELECT body:\n got  %s want %s", got, want)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+}
+
+func TestHTTPQuery_Errors(t *testing.T) {
+	h := New(memstore.New()).HTTPHandler()
+
+	rec := postQuery(t, h, "SELEKT nonsense")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"error":"parse error:`) {
+		t.Fatalf("parse error: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	rec = postQuery(t, h, "SELECT * FROM missing;")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"error":`) {
+		t.Fatalf("missing table: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	postQuery(t, h, "CREATE TABLE users (id INT);")
+	rec = postQuery(t, h, "SELECT name FROM users;")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"error":`) {
+		t.Fatalf("execution error: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/query", nil)
+	get := httptest.NewRecorder()
+	h.ServeHTTP(get, req)
+	if get.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET: status %d, want %d", get.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	fs, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("filestore.New failed: %v", err)
+	}
+	defer fs.Close()
+	h := New(fs).HTTPHandler()
+
+	for _, q := range []string{
+		"CREATE TABLE users (id INT);",
+		"INSERT INTO users VALUES (1);",
+		"SELECT * FROM users;",
+		"SELECT * FROM missing;",
+	} {
+		postQuery(t, h, q)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE godb_statements_total counter\n",
+		`godb_statements_total{kind="SELECT"} 2` + "\n",
+		`godb_statements_total{kind="INSERT"} 1` + "\n",
+		`godb_statement_errors_total{kind="SELECT"} 1` + "\n",
+		`godb_statement_errors_total{kind="INSERT"} 0` + "\n",
+		`godb_statement_duration_seconds_count{kind="SELECT"} 2` + "\n",
+		"# TYPE godb_wal_syncs_total counter\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `kind="ROLLBACK"`) {
+		t.Fatalf("session cleanup counted as a statement:\n%s", body)
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"goDB/internal/engine"
+	"goDB/internal/storage"
+)
+
+// handleMetrics serves GET /metrics: statement counts, errors and latency
+// by statement kind, and the storage engine's I/O counters when it keeps
+// them, in the Prometheus text exposition format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "use GET"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	bw := bufio.NewWriter(w)
+	writeStatementMetrics(bw, s.metrics.Stats())
+	if rep, ok := s.store.(storage.IOReporter); ok {
+		writeIOMetrics(bw, rep.Metrics())
+	}
+	_ = bw.Flush()
+}
+
+// metric is one metric family: its samples share a name, help and type.
+type metric struct {
+	name, help, typ string
+	samples         []sample
+}
+
+// sample is one value of a metric, with an optional kind label. suffix is
+// appended to the family name, e.g. "_sum" for a summary.
+type sample struct {
+	suffix string
+	kind   string
+	value  float64
+}
+
+func (m metric) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+	for _, s := range m.samples {
+		w.WriteString(m.name + s.suffix)
+		if s.kind != "" {
+			w.WriteString(`{kind="` + s.kind + `"}`)
+		}
+		w.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+	}
+}
+
+// writeStatementMetrics writes the statement counters. Latency is exported
+// as a summary (total seconds and count, for rate-based averages) and as
+// the average since the server started.
+func writeStatementMetrics(w *bufio.Writer, stats []engine.StatementStats) {
+	count := metric{name: "godb_statements_total", help: "Statements executed, by kind.", typ: "counter"}
+	errs := metric{name: "godb_statement_errors_total", help: "Statements that failed, by kind.", typ: "counter"}
+	dur := metric{name: "godb_statement_duration_seconds", help: "Time spent executing statements, by kind.", typ: "summary"}
+	avg := metric{name: "godb_statement_duration_avg_seconds", help: "Average statement execution time since start, by kind.", typ: "gauge"}
+	for _, st := range stats {
+		secs := st.Duration.Seconds()
+		count.samples = append(count.samples, sample{kind: st.Kind, value: float64(st.Count)})
+		errs.samples = append(errs.samples, sample{kind: st.Kind, value: float64(st.Errors)})
+		dur.samples = append(dur.samples,
+			sample{suffix: "_sum", kind: st.Kind, value: secs},
+			sample{suffix: "_count", kind: st.Kind, value: float64(st.Count)})
+		avg.samples = append(avg.samples, sample{kind: st.Kind, value: secs / float64(st.Count)})
+	}
+	for _, m := range []metric{count, errs, dur, avg} {
+		m.write(w)
+	}
+}
+
+// writeIOMetrics writes the storage engine's I/O counters.
+func writeIOMetrics(w *bufio.Writer, io storage.IOMetrics) {
+	for _, m := range []metric{
+		{"godb_page_reads_total", "Table pages read from disk.", "counter", []sample{{value: float64(io.PageReads)}}},
+		{"godb_page_writes_total", "Table pages written to disk.", "counter", []sample{{value: float64(io.PageWrites)}}},
+		{"godb_page_cache_hits_total", "Page reads answered by the page cache.", "counter", []sample{{value: float64(io.CacheHits)}}},
+		{"godb_read_bytes_total", "Bytes read from table files.", "counter", []sample{{value: float64(io.BytesRead)}}},
+		{"godb_written_bytes_total", "Bytes written to table files.", "counter", []sample{{value: float64(io.BytesWritten)}}},
+		{"godb_wal_bytes_total", "Bytes appended to the write-ahead log.", "counter", []sample{{value: float64(io.WALBytes)}}},
+		{"godb_wal_syncs_total", "Fsyncs of the write-ahead log.", "counter", []sample{{value: float64(io.WALSyncs)}}},
+	} {
+		m.write(w)
+	}
+}
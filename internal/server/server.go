@@ -0,0 +1,76 @@
+// Package server exposes a GoDB storage engine to network clients.
+//
+// Every client connection gets its own engine.DBEngine (and therefore its own
+// transaction state) on top of one shared storage.Engine. The storage
+// engines are not safe for concurrent statements yet, so the server
+// serializes statement execution across all connections.
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"goDB/internal/engine"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// Server executes SQL received from network clients against a shared store.
+type Server struct {
+	store storage.Engine
+
+	// Timeout limits how long a single statement may run; a SELECT still
+	// scanning when it expires fails with a cancellation error. Zero means
+	// no limit.
+	Timeout time.Duration
+
+	mu sync.Mutex // serializes statement execution on store
+
+	metrics *engine.Metrics // statements run by all sessions, see /metrics
+}
+
+// New creates a Server backed by store.
+func New(store storage.Engine) *Server {
+	return &Server{store: store, metrics: engine.NewMetrics()}
+}
+
+// session is the per-connection state: an engine that tracks the client's
+// open transaction, if any.
+type session struct {
+	srv *Server
+	eng *engine.DBEngine
+}
+
+func (s *Server) newSession() (*session, error) {
+	eng := engine.New(s.store)
+	if err := eng.Start(); err != nil {
+		return nil, fmt.Errorf("server: start engine: %w", err)
+	}
+	eng.SetMetrics(s.metrics)
+	return &session{srv: s, eng: eng}, nil
+}
+
+// execute runs a single statement while holding the server lock. It gives
+// up once ctx is done or the server's Timeout has passed.
+func (ss *session) execute(ctx context.Context, stmt sql.Statement) (*engine.Result, error) {
+	if ss.srv.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ss.srv.Timeout)
+		defer cancel()
+	}
+
+	ss.srv.mu.Lock()
+	defer ss.srv.mu.Unlock()
+	return ss.eng.ExecContext(ctx, stmt)
+}
+
+// close rolls back a transaction the client left open when it disconnected.
+func (ss *session) close() {
+	// Rolling back without an open transaction just returns an error,
+	// which is fine to ignore here, and is not the client's statement, so
+	// it is left out of the metrics.
+	ss.eng.SetMetrics(nil)
+	_, _ = ss.execute(context.Background(), &sql.RollbackTxStmt{})
+}
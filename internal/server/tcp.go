@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// ServeTCP accepts connections on ln and serves the line protocol on each
+// one until ln is closed.
+//
+// Clients send SQL text. A statement ends at a semicolon, or at the end of a
+// line when no string literal or parenthesis is left open, so both
+// "SELECT * FROM t;" and a bare "SELECT * FROM t" line work. Each statement
+// gets exactly one reply:
+//
+//	OK                          statement without a result set
+//	ERR <message>               parse or execution error
+//	ROWS <ncols> <nrows>        result set, followed by one header line
+//	                            and nrows data lines
+//
+// Header and data lines hold tab-separated fields. In data lines NULL is
+// sent as \N, and backslash, tab, newline and carriage return inside values
+// are escaped as \\, \t, \n and \r.
+func (s *Server) ServeTCP(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("server: accept: %w", err)
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn serves one client until it disconnects.
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	ss, err := s.newSession()
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+	defer ss.close()
+
+	in := bufio.NewScanner(conn)
+	out := bufio.NewWriter(conn)
+	var buffer string
+
+	for in.Scan() {
+		if buffer == "" {
+			buffer = in.Text()
+		} else {
+			buffer += "\n" + in.Text()
+		}
+
+		stmts, rest := sql.SplitStatements(buffer)
+		if rest != "" && !isUnfinished(rest) {
+			// End of line terminates the statement too.
+			stmts = append(stmts, rest)
+			rest = ""
+		}
+		buffer = rest
+
+		for _, text := range stmts {
+			ss.reply(out, text)
+		}
+		if err := out.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// reply parses and executes one statement and writes its framed result.
+func (ss *session) reply(w io.Writer, text string) {
+	stmt, err := sql.ParseCached(text)
+	if err != nil {
+		writeError(w, fmt.Errorf("parse error: %w", err))
+		return
+	}
+
+	res, err := ss.execute(context.Background(), stmt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	cols, rows := res.Columns, res.Rows
+	if len(cols) == 0 {
+		fmt.Fprintln(w, "OK")
+		return
+	}
+
+	fmt.Fprintf(w, "ROWS %d %d\n", len(cols), len(rows))
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = escapeField(c)
+	}
+	fmt.Fprintln(w, strings.Join(names, "\t"))
+
+	fields := make([]string, len(cols))
+	for _, row := range rows {
+		for i, v := range row {
+			fields[i] = encodeValue(v)
+		}
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+}
+
+// writeError writes an ERR reply, flattening the message onto one line.
+func writeError(w io.Writer, err error) {
+	msg := strings.ReplaceAll(err.Error(), "\n", " ")
+	fmt.Fprintf(w, "ERR %s\n", msg)
+}
+
+// encodeValue renders a value as a protocol field.
+func encodeValue(v sql.Value) string {
+	switch v.Type {
+	case sql.TypeInt:
+		return strconv.FormatInt(v.I64, 10)
+	case sql.TypeFloat:
+		return strconv.FormatFloat(v.F64, 'g', -1, 64)
+	case sql.TypeString:
+		return escapeField(v.S)
+	case sql.TypeBool:
+		return strconv.FormatBool(v.B)
+	case sql.TypeTimestamp:
+		return sql.FormatTimestamp(v)
+	case sql.TypeBytes:
+		return sql.FormatBlob(v)
+	default:
+		return `\N`
+	}
+}
+
+var fieldEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// escapeField escapes the characters that delimit fields and lines.
+func escapeField(s string) string {
+	return fieldEscaper.Replace(s)
+}
+
+// isUnfinished reports whether s ends inside a string literal or with
+// unclosed parentheses, in which case a newline does not end the statement.
+func isUnfinished(s string) bool {
+	inQuote := false
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			// A doubled quote toggles twice, leaving inQuote unchanged.
+			inQuote = !inQuote
+		case !inQuote && c == '(':
+			depth++
+		case !inQuote && c == ')':
+			depth--
+		}
+	}
+	return inQuote || depth > 0
+}
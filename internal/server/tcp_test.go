@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"goDB/internal/storage/memstore"
+)
+
+// startTCP serves a fresh memstore-backed Server on a loopback port and
+// returns its address.
+func startTCP(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := New(memstore.New())
+	go srv.ServeTCP(ln)
+	return ln.Addr().String()
+}
+
+// readLines reads n reply lines from r.
+func readLines(t *testing.T, r *bufio.Reader, n int) []string {
+	t.Helper()
+
+	lines := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read reply line %d: %v", i+1, err)
+		}
+		lines = append(lines, line[:len(line)-1])
+	}
+	return lines
+}
+
+func TestServeTCP_Statements(t *testing.T) {
+	conn, err := net.Dial("tcp", startTCP(t))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	fmt.Fprint(conn, "CREATE TABLE users (id INT, name STRING);\n")
+	fmt.Fprint(conn, "INSERT INTO users VALUES (1, 'a\tb'); INSERT INTO users VALUES (2, NULL);\n")
+	fmt.Fprint(conn, "INSERT INTO users VALUES (3,\n'multi\nline');\n")
+	fmt.Fprint(conn, "SELECT * FROM users ORDER BY id\n")
+	fmt.Fprint(conn, "SELECT * FROM missing;\n")
+
+	got := readLines(t, bufio.NewReader(conn), 10)
+	want := []string{
+		"OK",
+		"OK",
+		"OK",
+		"OK",
+		"ROWS 2 3",
+		"id\tname",
+		`1	a\tb`,
+		`2	\N`,
+		`3	multi\nline`,
+	}
+	if !reflect.DeepEqual(got[:9], want) {
+		t.Fatalf("unexpected replies:\n got  %q\n want %q", got[:9], want)
+	}
+	if !strings.HasPrefix(got[9], "ERR ") {
+		t.Fatalf("expected ERR reply for missing table, got %q", got[9])
+	}
+}
+
+func TestServeTCP_SeparateSessions(t *testing.T) {
+	addr := startTCP(t)
+
+	a, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial a: %v", err)
+	}
+	defer a.Close()
+	b, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial b: %v", err)
+	}
+	defer b.Close()
+	ra, rb := bufio.NewReader(a), bufio.NewReader(b)
+
+	// A transaction opened on one connection does not affect the other.
+	fmt.Fprint(a, "BEGIN;\n")
+	if got := readLines(t, ra, 1); got[0] != "OK" {
+		t.Fatalf("BEGIN on a: got %q", got[0])
+	}
+	fmt.Fprint(b, "BEGIN;\n")
+	if got := readLines(t, rb, 1); got[0] != "OK" {
+		t.Fatalf("BEGIN on b: got %q", got[0])
+	}
+}
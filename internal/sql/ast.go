@@ -5,10 +5,12 @@ type Statement interface {
 	stmtNode()
 }
 
-// CreateTableStmt represents a parsed CREATE TABLE statement.
+// CreateTableStmt represents a parsed CREATE TABLE statement, either with
+// a column list or as CREATE TABLE name AS SELECT ...
 type CreateTableStmt struct {
 	TableName string
-	Columns   []Column
+	Columns   []Column    // nil when AsSelect is set
+	AsSelect  *SelectStmt // the query whose rows fill the new table
 }
 
 func (*CreateTableStmt) stmtNode() {}
@@ -17,43 +19,81 @@ func (*CreateTableStmt) stmtNode() {}
 //
 //	INSERT INTO table VALUES (...)
 //	INSERT INTO table(col1, col2, ...) VALUES (...)
+//	INSERT INTO table VALUES (...), (...), ...
 //
 // If Columns is empty, it means "all columns in table order".
 type InsertStmt struct {
 	TableName string
 	Columns   []string // optional; nil/empty = no column list
-	Values    Row      // one row of literal values
+	Values    Row      // first (or only) row of literal values
+	Rows      []Row    // all rows; nil means just Values
+
+	// Exprs holds, per row, the values that are not plain literals (such
+	// as NEXTVAL('seq')), evaluated when the row is inserted. It is nil
+	// when every value is a literal; otherwise Exprs[i][j] is nil for
+	// literals and Rows[i][j] is a NULL placeholder for expressions.
+	Exprs [][]Expr
 }
 
 func (*InsertStmt) stmtNode() {}
 
+// CreateSequenceStmt represents:
+//
+//	CREATE SEQUENCE name [START [WITH] n]
+type CreateSequenceStmt struct {
+	Name  string
+	Start int64 // first value returned by NEXTVAL; 1 by default
+}
+
+func (*CreateSequenceStmt) stmtNode() {}
+
 // SelectStmt represents a parsed SELECT statement.
 // Supported forms (for now):
 //
 //	SELECT * FROM table;
 //	SELECT col1, col2 FROM table;
-//	... optionally with WHERE column = literal
+//	SELECT first || ' ' || last FROM table;
+//	... optionally with WHERE column = literal [AND ...]
 type SelectStmt struct {
 	TableName string
-	Columns   []string   // nil or empty => SELECT *
+	Columns   []string   // nil or empty => SELECT *; output names (the AS name when given) otherwise, "*" for a * item
+	Exprs     []Expr     // one per column; nil when all are plain column names
 	Where     *WhereExpr // nil if no WHERE clause
+	GroupBy   []string   // grouped column names; nil without GROUP BY
 	OrderBy   *OrderByClause
 	Limit     *int // nil if no LIMIT
 }
 
 func (*SelectStmt) stmtNode() {}
 
-// WhereExpr represents a simple WHERE condition: column = literal.
+// WhereExpr represents a simple WHERE condition: column = literal, or
+// column IS [NOT] TRUE, FALSE or NULL.
 type WhereExpr struct {
 	Column string
-	Op     string // currently only "=" is supported
-	Value  Value
+	Op     string // "=", "!=", "<", "<=", ">", ">=", "IS" or "IS NOT"
+	Value  Value  // a BOOL or NULL for IS and IS NOT
+
+	// Left is set instead of Column when the left side is an expression
+	// rather than a plain column name.
+	Left Expr
+
+	// And is the next comparison of a clause such as
+	// id > 5 AND id < 10; a row must match every comparison in the chain.
+	And *WhereExpr
 }
 
 // Assignment represents "column = value" in UPDATE.
 type Assignment struct {
 	Column string
 	Value  Value
+	// Default marks "column = DEFAULT": the column is reset to its declared
+	// default, or NULL without one. Value is NULL then.
+	Default bool
+	// Expr is set when the value is not a plain literal, as in
+	// balance = balance + 100; Value is NULL then. It is evaluated against
+	// the row as it was before the UPDATE, so every assignment of a
+	// statement sees the old values: SET a = b, b = a swaps a and b.
+	Expr Expr
 }
 
 // UpdateStmt represents:
@@ -77,8 +117,35 @@ type DeleteStmt struct {
 
 func (*DeleteStmt) stmtNode() {}
 
-// BeginTxStmt BEGIN [TRANSACTION]
-type BeginTxStmt struct{}
+// TxMode is the access mode a transaction declares in BEGIN.
+type TxMode int
+
+const (
+	// TxDeferred is a plain BEGIN: the transaction may read and write and
+	// takes no locks up front.
+	TxDeferred TxMode = iota
+	// TxImmediate (BEGIN IMMEDIATE) reserves the store for writing as the
+	// transaction starts.
+	TxImmediate
+	// TxReadOnly (BEGIN READ ONLY) rejects every write.
+	TxReadOnly
+)
+
+func (m TxMode) String() string {
+	switch m {
+	case TxImmediate:
+		return "IMMEDIATE"
+	case TxReadOnly:
+		return "READ ONLY"
+	default:
+		return "DEFERRED"
+	}
+}
+
+// BeginTxStmt BEGIN [DEFERRED | IMMEDIATE] [TRANSACTION] [READ ONLY | READ WRITE]
+type BeginTxStmt struct {
+	Mode TxMode
+}
 
 func (*BeginTxStmt) stmtNode() {}
 
@@ -104,3 +171,31 @@ type CreateIndexStmt struct {
 }
 
 func (*CreateIndexStmt) stmtNode() {}
+
+// AlterTableStmt represents:
+//
+//	ALTER TABLE tableName RENAME [COLUMN] column TO newName
+type AlterTableStmt struct {
+	TableName string
+	Column    string
+	NewName   string
+}
+
+func (*AlterTableStmt) stmtNode() {}
+
+// PragmaStmt represents:
+//
+//	PRAGMA name
+//	PRAGMA name = value
+//	PRAGMA name(arg)
+//
+// Name is lower-cased. Value is the text after '=', with the quotes of a
+// string literal removed; Set reports whether one was given.
+type PragmaStmt struct {
+	Name  string
+	Arg   string
+	Value string
+	Set   bool
+}
+
+func (*PragmaStmt) stmtNode() {}
@@ -0,0 +1,126 @@
+package sql
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// DefaultParseCacheSize is the number of statements ParseCached keeps until
+// SetParseCacheSize changes it.
+const DefaultParseCacheSize = 256
+
+// stmtCache is an LRU cache of parsed statements keyed by normalized query
+// text. It is safe for concurrent use.
+type stmtCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List // front = most recently used
+}
+
+type stmtCacheEntry struct {
+	query string
+	stmt  Statement
+}
+
+func newStmtCache(capacity int) *stmtCache {
+	return &stmtCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+var parseCache = newStmtCache(DefaultParseCacheSize)
+
+// ParseCached is like Parse, but remembers the statements of recently parsed
+// queries so that running the same text again skips tokenizing. Queries that
+// differ only in surrounding whitespace or a trailing semicolon share an
+// entry; failed parses are not cached.
+//
+// The returned Statement may be shared with other callers and must not be
+// modified.
+func ParseCached(query string) (Statement, error) {
+	key := normalizeQuery(query)
+	if stmt, ok := parseCache.get(key); ok {
+		return stmt, nil
+	}
+
+	stmt, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	parseCache.put(key, stmt)
+	return stmt, nil
+}
+
+// SetParseCacheSize sets how many statements ParseCached keeps, evicting the
+// least recently used ones if there are more. Zero or a negative size
+// disables the cache.
+func SetParseCacheSize(n int) {
+	parseCache.resize(n)
+}
+
+// normalizeQuery returns the text Parse actually looks at: the query without
+// surrounding whitespace and without a trailing semicolon.
+func normalizeQuery(query string) string {
+	q := strings.TrimSpace(query)
+	if strings.HasSuffix(q, ";") {
+		q = strings.TrimSpace(q[:len(q)-1])
+	}
+	return q
+}
+
+func (c *stmtCache) get(query string) (Statement, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[query]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*stmtCacheEntry).stmt, true
+}
+
+func (c *stmtCache) put(query string, stmt Statement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.capacity <= 0 {
+		return
+	}
+	if elem, ok := c.entries[query]; ok {
+		elem.Value.(*stmtCacheEntry).stmt = stmt
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[query] = c.lru.PushFront(&stmtCacheEntry{query: query, stmt: stmt})
+	c.evict()
+}
+
+func (c *stmtCache) resize(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	c.evict()
+}
+
+// evict drops least recently used entries until the cache fits its
+// capacity. The caller holds c.mu.
+func (c *stmtCache) evict() {
+	for c.lru.Len() > max(c.capacity, 0) {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*stmtCacheEntry).query)
+	}
+}
+
+// len returns the number of cached statements.
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
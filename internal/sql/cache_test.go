@@ -0,0 +1,95 @@
+package sql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseCached(t *testing.T) {
+	defer SetParseCacheSize(DefaultParseCacheSize)
+	SetParseCacheSize(2)
+
+	a, err := ParseCached("SELECT * FROM users WHERE id = 1;")
+	if err != nil {
+		t.Fatalf("ParseCached failed: %v", err)
+	}
+	b, err := ParseCached("  SELECT * FROM users WHERE id = 1 ")
+	if err != nil {
+		t.Fatalf("ParseCached failed: %v", err)
+	}
+	if a != b {
+		t.Fatalf("expected the normalized query to hit the cache")
+	}
+	if _, ok := a.(*SelectStmt); !ok {
+		t.Fatalf("unexpected statement %#v", a)
+	}
+
+	// Failed parses are not cached.
+	if _, err := ParseCached("SELEC nothing"); err == nil {
+		t.Fatalf("expected a parse error")
+	}
+	if n := parseCache.len(); n != 1 {
+		t.Fatalf("cache holds %d entries, want 1", n)
+	}
+
+	// The least recently used entry is evicted first.
+	if _, err := ParseCached("SELECT * FROM t;"); err != nil {
+		t.Fatalf("ParseCached failed: %v", err)
+	}
+	if _, err := ParseCached("SELECT * FROM users WHERE id = 1;"); err != nil {
+		t.Fatalf("ParseCached failed: %v", err)
+	}
+	if _, err := ParseCached("SELECT * FROM u;"); err != nil {
+		t.Fatalf("ParseCached failed: %v", err)
+	}
+	if _, ok := parseCache.get("SELECT * FROM t"); ok {
+		t.Fatalf("expected the least recently used statement to be evicted")
+	}
+	if c, _ := ParseCached("SELECT * FROM users WHERE id = 1"); c != a {
+		t.Fatalf("expected the recently used statement to stay cached")
+	}
+
+	SetParseCacheSize(0)
+	if n := parseCache.len(); n != 0 {
+		t.Fatalf("disabled cache holds %d entries", n)
+	}
+	c, err := ParseCached("SELECT * FROM users WHERE id = 1;")
+	if err != nil || c == a {
+		t.Fatalf("disabled cache returned a cached statement (err %v)", err)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	const query = "SELECT id, name || '!' FROM users WHERE CAST(score AS INT) >= 10 ORDER BY id DESC LIMIT 5;"
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := Parse(query); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ParseCached(query); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached-miss", func(b *testing.B) {
+		// Distinct queries overflow the cache, so every call parses and
+		// evicts.
+		queries := make([]string, 2*DefaultParseCacheSize)
+		for i := range queries {
+			queries[i] = fmt.Sprintf("SELECT * FROM users WHERE id = %d;", i)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ParseCached(queries[i%len(queries)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
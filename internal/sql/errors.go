@@ -0,0 +1,22 @@
+package sql
+
+import "errors"
+
+// Sentinel errors shared by the parser, the engine and the storage engines.
+// Errors of these kinds wrap one of them, so callers such as the network
+// server can tell them apart with errors.Is; the message adds the details.
+var (
+	// ErrSyntax is wrapped by every error Parse returns.
+	ErrSyntax = errors.New("syntax error")
+	// ErrTableNotFound reports a statement naming a table that does not
+	// exist.
+	ErrTableNotFound = errors.New("unknown table")
+	// ErrColumnNotFound reports a column missing from the table it is looked
+	// up in.
+	ErrColumnNotFound = errors.New("unknown column")
+	// ErrTypeMismatch reports a value whose type does not fit the column or
+	// operation it is used with.
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrNotNull reports a NULL written to a column declared NOT NULL.
+	ErrNotNull = errors.New("NOT NULL constraint failed")
+)
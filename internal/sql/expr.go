@@ -0,0 +1,81 @@
+package sql
+
+// Expr is a scalar expression evaluated per row, as used in SELECT lists and
+// on the left side of WHERE comparisons.
+type Expr interface {
+	exprNode()
+}
+
+// ColumnRef refers to a column of the current row by name.
+type ColumnRef struct {
+	Name string
+}
+
+func (*ColumnRef) exprNode() {}
+
+// Literal is a constant value.
+type Literal struct {
+	Value Value
+}
+
+func (*Literal) exprNode() {}
+
+// BinaryExpr applies a binary operator to two expressions. Op is "||"
+// (string concatenation), one of the arithmetic operators "+", "-", "*",
+// "/", "%" or one of the comparison operators "=", "!=", "<", "<=", ">",
+// ">=".
+type BinaryExpr struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+func (*BinaryExpr) exprNode() {}
+
+// UnaryExpr negates a numeric expression: -col or -(a). A minus directly in
+// front of a number, with or without spaces, is folded into the Literal.
+type UnaryExpr struct {
+	Op   string // "-"
+	Expr Expr
+}
+
+func (*UnaryExpr) exprNode() {}
+
+// CastExpr converts the value of Expr to Type: CAST(expr AS type).
+type CastExpr struct {
+	Expr Expr
+	Type DataType
+}
+
+func (*CastExpr) exprNode() {}
+
+// FuncCall is a call of a scalar or aggregate function such as
+// COALESCE(a, b) or COUNT(*). Name is upper-cased. Star marks COUNT(*),
+// which has no Args.
+type FuncCall struct {
+	Name string
+	Args []Expr
+	Star bool
+}
+
+func (*FuncCall) exprNode() {}
+
+// CaseExpr is a CASE expression. Without an Operand it is a searched CASE,
+// CASE WHEN cond THEN result ... [ELSE result] END, and yields the result
+// of the first WHEN whose condition is true. With one it is a simple CASE,
+// CASE x WHEN value THEN result ... END, and yields the result of the first
+// WHEN whose value equals x. Else is nil when there is no ELSE; the
+// expression is NULL then if no WHEN matches.
+type CaseExpr struct {
+	Operand Expr
+	Whens   []CaseWhen
+	Else    Expr
+}
+
+// CaseWhen is one WHEN ... THEN ... branch of a CaseExpr.
+type CaseWhen struct {
+	When Expr
+	Then Expr
+}
+
+func (*CaseExpr) exprNode() {}
@@ -0,0 +1,29 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseAlterTable parses an ALTER TABLE statement.
+// Format: ALTER TABLE table_name RENAME [COLUMN] old_name TO new_name
+func parseAlterTable(q string) (*AlterTableStmt, error) {
+	parts := strings.Fields(q)
+	if len(parts) == 8 && strings.EqualFold(parts[4], "COLUMN") {
+		parts = append(parts[:4], parts[5:]...)
+	}
+
+	if len(parts) != 7 ||
+		!strings.EqualFold(parts[0], "ALTER") ||
+		!strings.EqualFold(parts[1], "TABLE") ||
+		!strings.EqualFold(parts[3], "RENAME") ||
+		!strings.EqualFold(parts[5], "TO") {
+		return nil, fmt.Errorf("invalid ALTER TABLE format (want ALTER TABLE t RENAME COLUMN a TO b)")
+	}
+
+	return &AlterTableStmt{
+		TableName: parts[2],
+		Column:    parts[4],
+		NewName:   parts[6],
+	}, nil
+}
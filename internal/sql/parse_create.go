@@ -3,6 +3,7 @@ package sql
 import (
 	"fmt"
 	"strings"
+	"unicode"
 )
 
 func parseCreateTable(query string) (Statement, error) {
@@ -11,6 +12,11 @@ func parseCreateTable(query string) (Statement, error) {
 	// - trailing ';' removed
 	// - we already know it's some form of CREATE TABLE
 
+	// CREATE TABLE name AS SELECT ...
+	if fields := strings.Fields(query); len(fields) >= 4 && strings.EqualFold(fields[3], "AS") {
+		return parseCreateTableAs(query, fields[2])
+	}
+
 	// Find the opening parenthesis for column list.
 	openIdx := strings.Index(query, "(")
 	if openIdx == -1 {
@@ -67,24 +73,25 @@ func parseCreateTable(query string) (Statement, error) {
 		colName := parts[0]
 		typeStr := strings.ToUpper(parts[1])
 
-		var dt DataType
-		switch typeStr {
-		case "INT", "INTEGER":
-			dt = TypeInt
-		case "FLOAT", "DOUBLE", "REAL":
-			dt = TypeFloat
-		case "STRING", "TEXT", "VARCHAR":
-			dt = TypeString
-		case "BOOL", "BOOLEAN":
-			dt = TypeBool
-		default:
+		dt, ok := ParseDataType(typeStr)
+		if !ok {
 			return nil, fmt.Errorf("unknown column type %q in %q", typeStr, def)
 		}
 
-		columns = append(columns, Column{
+		col := Column{
 			Name: colName,
 			Type: dt,
-		})
+		}
+		if len(parts) > 2 {
+			// Everything after the type, e.g. "DEFAULT 'n/a'".
+			afterName := strings.TrimSpace(def[len(parts[0]):])
+			rest := strings.TrimSpace(afterName[len(parts[1]):])
+			if err := parseColumnConstraints(&col, rest); err != nil {
+				return nil, fmt.Errorf("column %q: %w", colName, err)
+			}
+		}
+
+		columns = append(columns, col)
 	}
 
 	if len(columns) == 0 {
@@ -96,3 +103,131 @@ func parseCreateTable(query string) (Statement, error) {
 		Columns:   columns,
 	}, nil
 }
+
+// parseCreateTableAs parses CREATE TABLE name AS SELECT ..., where the
+// query is any SELECT statement.
+func parseCreateTableAs(query, tableName string) (Statement, error) {
+	// Skip the four words CREATE TABLE name AS.
+	rest := query
+	for range 4 {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		rest = strings.TrimLeftFunc(rest, func(r rune) bool { return !unicode.IsSpace(r) })
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(strings.ToUpper(rest), "SELECT") {
+		return nil, fmt.Errorf("CREATE TABLE: expected SELECT after AS")
+	}
+
+	stmt, err := parseSelect(rest)
+	if err != nil {
+		return nil, fmt.Errorf("CREATE TABLE %s AS: %w", tableName, err)
+	}
+	return &CreateTableStmt{
+		TableName: tableName,
+		AsSelect:  stmt.(*SelectStmt),
+	}, nil
+}
+
+// parseColumnConstraints parses what follows the type in a column
+// definition: any of
+//
+//	DEFAULT <literal>
+//	DEFAULT CURRENT_TIMESTAMP
+//	REFERENCES parent(column)
+//	COLLATE NOCASE | BINARY
+//	NOT NULL | NULL
+func parseColumnConstraints(col *Column, clause string) error {
+	toks, err := tokenize(clause)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; toks[i].kind != tokEOF; {
+		switch kw := strings.ToUpper(toks[i].text); {
+		case kw == "DEFAULT" && toks[i].kind == tokIdent:
+			// The literal is one token, or a sign followed by a number.
+			j := i + 1
+			if toks[j].kind == tokOp && (toks[j].text == "-" || toks[j].text == "+") {
+				j++
+			}
+			if toks[j].kind == tokEOF {
+				return fmt.Errorf("missing DEFAULT value")
+			}
+			if err := parseColumnDefault(col, clause[toks[i+1].pos:toks[j].end]); err != nil {
+				return err
+			}
+			i = j + 1
+
+		case kw == "REFERENCES" && toks[i].kind == tokIdent:
+			t := toks[i+1 : min(i+5, len(toks))]
+			if len(t) < 4 || t[0].kind != tokIdent || t[1].kind != tokLParen ||
+				t[2].kind != tokIdent || t[3].kind != tokRParen {
+				return fmt.Errorf("expected REFERENCES table(column)")
+			}
+			col.References = &ForeignKey{Table: t[0].text, Column: t[2].text}
+			i += 5
+
+		case kw == "NOT" && toks[i].kind == tokIdent:
+			if next := toks[i+1]; next.kind != tokIdent || !strings.EqualFold(next.text, "NULL") {
+				return fmt.Errorf("expected NOT NULL")
+			}
+			col.NotNull = true
+			i += 2
+
+		case kw == "NULL" && toks[i].kind == tokIdent:
+			// Explicitly nullable, which is the default.
+			col.NotNull = false
+			i++
+
+		case kw == "COLLATE" && toks[i].kind == tokIdent:
+			name := toks[i+1]
+			if name.kind != tokIdent {
+				return fmt.Errorf("expected COLLATE NOCASE or COLLATE BINARY")
+			}
+			switch strings.ToUpper(name.text) {
+			case "NOCASE":
+				col.NoCase = true
+			case "BINARY":
+				col.NoCase = false
+			default:
+				return fmt.Errorf("unknown collation %q (want NOCASE or BINARY)", name.text)
+			}
+			if col.Type != TypeString {
+				return fmt.Errorf("COLLATE requires a STRING column")
+			}
+			i += 2
+
+		default:
+			return fmt.Errorf("unexpected %q after column type", clause[toks[i].pos:])
+		}
+	}
+	return nil
+}
+
+// parseColumnDefault parses the value of a DEFAULT clause into col.
+func parseColumnDefault(col *Column, lit string) error {
+	if strings.ToUpper(lit) == "CURRENT_TIMESTAMP" {
+		if col.Type != TypeTimestamp {
+			return fmt.Errorf("DEFAULT CURRENT_TIMESTAMP requires a TIMESTAMP column")
+		}
+		col.DefaultCurrentTimestamp = true
+		return nil
+	}
+
+	v, err := parseLiteral(lit)
+	if err != nil {
+		return fmt.Errorf("invalid DEFAULT %q: %w", lit, err)
+	}
+	if col.Type == TypeTimestamp && v.Type == TypeString {
+		if v, err = ParseTimestamp(v.S); err != nil {
+			return fmt.Errorf("invalid DEFAULT: %w", err)
+		}
+	}
+	if v.Type != col.Type && v.Type != TypeNull {
+		return fmt.Errorf("DEFAULT %s does not match column type %s", lit, col.Type)
+	}
+	if v.Type != TypeNull {
+		col.Default = &v
+	}
+	return nil
+}
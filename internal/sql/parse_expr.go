@@ -0,0 +1,442 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+// token is a lexical token of an expression. pos and end are byte offsets
+// into the source, so callers can recover the text an expression came from.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	end  int
+}
+
+// operators lists the recognized operator tokens, longest first.
+var operators = []string{"||", "!=", "<=", ">=", "=", "<", ">", "-", "+", "*", "/", "%"}
+
+// tokenize splits an expression into tokens. String and BLOB literals keep
+// their quotes, so parseLiteral can decode them.
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\'' || (c == 'x' || c == 'X') && i+1 < len(s) && s[i+1] == '\'':
+			// A string literal, or a BLOB literal such as x'CAFE'.
+			j := i + 1
+			if c != '\'' {
+				j++
+			}
+			for {
+				if j >= len(s) {
+					return nil, fmt.Errorf("unterminated string literal")
+				}
+				if s[j] == '\'' {
+					if j+1 < len(s) && s[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			toks = append(toks, token{kind: tokString, text: s[i : j+1], pos: i, end: j + 1})
+			i = j + 1
+
+		case isDigit(c) || (c == '.' && i+1 < len(s) && isDigit(s[i+1])):
+			j := i
+			hex := strings.HasPrefix(strings.ToLower(s[i:]), "0x")
+			for j < len(s) {
+				d := s[j]
+				if isIdentChar(d) || d == '.' {
+					j++
+					continue
+				}
+				// Exponent sign, as in 1e-3.
+				if !hex && (d == '+' || d == '-') && (s[j-1] == 'e' || s[j-1] == 'E') {
+					j++
+					continue
+				}
+				break
+			}
+			toks = append(toks, token{kind: tokNumber, text: s[i:j], pos: i, end: j})
+			i = j
+
+		case isIdentStart(c):
+			j := i
+			for j < len(s) && isIdentChar(s[j]) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: s[i:j], pos: i, end: j})
+			i = j
+
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i, end: i + 1})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i, end: i + 1})
+			i++
+		case c == ',':
+			toks = append(toks, token{kind: tokComma, text: ",", pos: i, end: i + 1})
+			i++
+
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(s[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: i, end: i + len(op)})
+			i += len(op)
+		}
+	}
+	toks = append(toks, token{kind: tokEOF, pos: len(s), end: len(s)})
+	return toks, nil
+}
+
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isIdentChar(c byte) bool  { return isIdentStart(c) || isDigit(c) }
+
+// binaryPrecedence returns the binding power of a binary operator, or 0 if
+// op is not one. As in SQLite, concatenation binds tightest, then
+// multiplication and division, then addition and subtraction, then
+// comparison: a || b = 'xy' compares the concatenation, and a + b * 2 > 10
+// compares a + (b * 2).
+func binaryPrecedence(op string) int {
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=":
+		return 1
+	case "+", "-":
+		return 2
+	case "*", "/", "%":
+		return 3
+	case "||":
+		return 4
+	default:
+		return 0
+	}
+}
+
+// exprParser is a precedence-climbing parser over a token slice.
+type exprParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *exprParser) peek() token { return p.toks[p.pos] }
+
+func (p *exprParser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// parseExpr parses a binary expression whose operators bind at least as
+// tightly as minPrec. All binary operators are left-associative.
+func (p *exprParser) parseExpr(minPrec int) (Expr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if t.kind != tokOp {
+			return left, nil
+		}
+		prec := binaryPrecedence(t.text)
+		if prec == 0 {
+			return nil, fmt.Errorf("unsupported operator %q", t.text)
+		}
+		if prec < minPrec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseExpr(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: t.text, Left: left, Right: right}
+	}
+}
+
+// parsePrimary parses a literal, a column reference or a parenthesized
+// expression.
+func (p *exprParser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber, tokString:
+		v, err := parseLiteral(t.text)
+		if err != nil {
+			return nil, err
+		}
+		return &Literal{Value: v}, nil
+
+	case tokOp:
+		if t.text != "-" && t.text != "+" {
+			return nil, fmt.Errorf("unexpected operator %q", t.text)
+		}
+		// A sign in front of a number is part of the literal, so -10 and
+		// - 10 are the same constant.
+		if p.peek().kind == tokNumber {
+			num := p.next()
+			v, err := parseLiteral(t.text + num.text)
+			if err != nil {
+				return nil, err
+			}
+			return &Literal{Value: v}, nil
+		}
+		// Otherwise the sign applies to the operand that follows and binds
+		// tighter than any binary operator: -a || b is (-a) || b.
+		operand, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "+" {
+			return operand, nil
+		}
+		return &UnaryExpr{Op: "-", Expr: operand}, nil
+
+	case tokIdent:
+		switch strings.ToUpper(t.text) {
+		case "TRUE", "FALSE", "NULL":
+			v, err := parseLiteral(t.text)
+			if err != nil {
+				return nil, err
+			}
+			return &Literal{Value: v}, nil
+		case "CAST":
+			if p.peek().kind == tokLParen {
+				return p.parseCast()
+			}
+		case "CASE":
+			return p.parseCase()
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(strings.ToUpper(t.text))
+		}
+		return &ColumnRef{Name: t.text}, nil
+
+	case tokLParen:
+		e, err := p.parseExpr(1)
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return e, nil
+
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+}
+
+// parseCast parses the rest of CAST(expr AS type) after the CAST keyword.
+func (p *exprParser) parseCast() (Expr, error) {
+	p.next() // (
+	e, err := p.parseExpr(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.kind != tokIdent || !strings.EqualFold(t.text, "AS") {
+		return nil, fmt.Errorf("CAST: expected AS")
+	}
+	t := p.next()
+	dt, ok := ParseDataType(t.text)
+	if t.kind != tokIdent || !ok {
+		return nil, fmt.Errorf("CAST: unknown type %q", t.text)
+	}
+	if p.next().kind != tokRParen {
+		return nil, fmt.Errorf("CAST: missing closing parenthesis")
+	}
+	return &CastExpr{Expr: e, Type: dt}, nil
+}
+
+// parseCase parses the rest of a CASE expression after the CASE keyword.
+func (p *exprParser) parseCase() (Expr, error) {
+	c := &CaseExpr{}
+	if !p.atKeyword("WHEN") {
+		operand, err := p.parseExpr(1)
+		if err != nil {
+			return nil, fmt.Errorf("CASE: %w", err)
+		}
+		c.Operand = operand
+	}
+	for p.atKeyword("WHEN") {
+		p.next()
+		when, err := p.parseExpr(1)
+		if err != nil {
+			return nil, fmt.Errorf("CASE: %w", err)
+		}
+		if !p.atKeyword("THEN") {
+			return nil, fmt.Errorf("CASE: expected THEN")
+		}
+		p.next()
+		then, err := p.parseExpr(1)
+		if err != nil {
+			return nil, fmt.Errorf("CASE: %w", err)
+		}
+		c.Whens = append(c.Whens, CaseWhen{When: when, Then: then})
+	}
+	if len(c.Whens) == 0 {
+		return nil, fmt.Errorf("CASE: expected WHEN")
+	}
+	if p.atKeyword("ELSE") {
+		p.next()
+		e, err := p.parseExpr(1)
+		if err != nil {
+			return nil, fmt.Errorf("CASE: %w", err)
+		}
+		c.Else = e
+	}
+	if !p.atKeyword("END") {
+		return nil, fmt.Errorf("CASE: expected END")
+	}
+	p.next()
+	return c, nil
+}
+
+// atKeyword reports whether the next token is the keyword kw.
+func (p *exprParser) atKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+// parseCall parses the parenthesized argument list of a function call.
+func (p *exprParser) parseCall(name string) (Expr, error) {
+	p.next() // (
+	call := &FuncCall{Name: name}
+	if p.peek().kind == tokRParen {
+		p.next()
+		return call, nil
+	}
+	if t := p.peek(); t.kind == tokOp && t.text == "*" {
+		p.next()
+		if name != "COUNT" || p.next().kind != tokRParen {
+			return nil, fmt.Errorf("%s: * is only allowed as COUNT(*)", name)
+		}
+		call.Star = true
+		return call, nil
+	}
+	for {
+		arg, err := p.parseExpr(1)
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		switch t := p.next(); t.kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			return call, nil
+		default:
+			return nil, fmt.Errorf("%s: expected , or ) in argument list", name)
+		}
+	}
+}
+
+// parseExpr parses a complete expression.
+func parseExpr(s string) (Expr, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	e, err := p.parseExpr(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return e, nil
+}
+
+// parseExprList parses a comma-separated list of expressions and returns
+// each expression with the source text it was parsed from.
+func parseExprList(s string) ([]Expr, []string, error) {
+	return parseList(s, false)
+}
+
+// parseSelectList is parseExprList for a SELECT list, where a bare * item,
+// as in SELECT rowid, * FROM t, stands for all columns of the table, and an
+// item may be named with AS, as in COUNT(*) AS c. A * item is returned as a
+// ColumnRef named "*", and the text of a named item is its name.
+func parseSelectList(s string) ([]Expr, []string, error) {
+	return parseList(s, true)
+}
+
+func parseList(s string, selectList bool) ([]Expr, []string, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &exprParser{toks: toks}
+
+	var exprs []Expr
+	var texts []string
+	for {
+		start := p.peek().pos
+		var e Expr
+		if t := p.toks[p.pos]; selectList && t.kind == tokOp && t.text == "*" &&
+			(p.toks[p.pos+1].kind == tokComma || p.toks[p.pos+1].kind == tokEOF) {
+			p.next()
+			e = &ColumnRef{Name: "*"}
+		} else if e, err = p.parseExpr(1); err != nil {
+			return nil, nil, err
+		}
+		text := s[start:p.toks[p.pos-1].end]
+		if selectList && p.atKeyword("AS") {
+			p.next()
+			name := p.next()
+			if name.kind != tokIdent {
+				return nil, nil, fmt.Errorf("expected a name after AS")
+			}
+			text = name.text
+		}
+		exprs = append(exprs, e)
+		texts = append(texts, text)
+
+		switch t := p.next(); t.kind {
+		case tokComma:
+			continue
+		case tokEOF:
+			return exprs, texts, nil
+		default:
+			return nil, nil, fmt.Errorf("unexpected %q", t.text)
+		}
+	}
+}
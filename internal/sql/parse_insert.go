@@ -9,6 +9,7 @@ import (
 //
 //	INSERT INTO table VALUES (v1, v2, ...);
 //	INSERT INTO table(col1, col2) VALUES (v1, v2, ...);
+//	INSERT INTO table VALUES (v1, v2, ...), (v1, v2, ...), ...;
 func parseInsert(query string) (Statement, error) {
 	q := strings.TrimSpace(query)
 	if q == "" {
@@ -79,14 +80,50 @@ func parseInsert(query string) (Statement, error) {
 		}
 	}
 
-	// Parse VALUES part: must be "( ... )"
-	if !strings.HasPrefix(afterValues, "(") || !strings.HasSuffix(afterValues, ")") {
-		return nil, fmt.Errorf("INSERT: VALUES must be in parentheses")
+	// Parse VALUES part: one or more "( ... )" tuples separated by commas.
+	tuples, err := splitValueTuples(afterValues)
+	if err != nil {
+		return nil, err
 	}
 
-	inner := strings.TrimSpace(afterValues[1 : len(afterValues)-1])
+	rows := make([]Row, 0, len(tuples))
+	var exprs [][]Expr
+	for _, inner := range tuples {
+		values, rowExprs, err := parseValueTuple(inner)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 && len(values) != len(rows[0]) {
+			return nil, fmt.Errorf("INSERT: row %d has %d values, expected %d",
+				len(rows)+1, len(values), len(rows[0]))
+		}
+		if rowExprs != nil && exprs == nil {
+			exprs = make([][]Expr, len(rows), len(tuples))
+		}
+		if exprs != nil {
+			exprs = append(exprs, rowExprs)
+		}
+		rows = append(rows, values)
+	}
+
+	return &InsertStmt{
+		TableName: tableName,
+		Columns:   columnList, // nil/empty means no column list
+		Values:    rows[0],
+		Rows:      rows,
+		Exprs:     exprs,
+	}, nil
+}
+
+// parseValueTuple parses the values inside one parenthesized VALUES tuple.
+// Values are normally literals. When one is not, the whole tuple is parsed
+// as an expression list: literals still go to the row, and the other
+// expressions are returned in exprs with a NULL placeholder in the row.
+// exprs is nil when every value is a literal.
+func parseValueTuple(inner string) (Row, []Expr, error) {
+	inner = strings.TrimSpace(inner)
 	if inner == "" {
-		return nil, fmt.Errorf("INSERT: empty VALUES list")
+		return nil, nil, fmt.Errorf("INSERT: empty VALUES list")
 	}
 
 	rawVals := splitCommaSeparated(inner)
@@ -98,17 +135,112 @@ func parseInsert(query string) (Statement, error) {
 		}
 		v, err := parseLiteral(rv)
 		if err != nil {
-			return nil, fmt.Errorf("INSERT: invalid literal %q: %w", rv, err)
+			if row, exprs, ok := parseValueExprs(inner); ok {
+				return row, exprs, nil
+			}
+			return nil, nil, fmt.Errorf("INSERT: invalid literal %q: %w", rv, err)
 		}
 		values = append(values, v)
 	}
 	if len(values) == 0 {
-		return nil, fmt.Errorf("INSERT: no values parsed")
+		return nil, nil, fmt.Errorf("INSERT: no values parsed")
 	}
+	return Row(values), nil, nil
+}
 
-	return &InsertStmt{
-		TableName: tableName,
-		Columns:   columnList, // nil/empty means no column list
-		Values:    Row(values),
-	}, nil
+// parseValueExprs parses a VALUES tuple as an expression list. It reports
+// false when the tuple does not parse or holds column references, which
+// have no meaning in VALUES.
+func parseValueExprs(inner string) (Row, []Expr, bool) {
+	list, _, err := parseExprList(inner)
+	if err != nil {
+		return nil, nil, false
+	}
+	row := make(Row, len(list))
+	exprs := make([]Expr, len(list))
+	for i, e := range list {
+		if hasColumnRef(e) {
+			return nil, nil, false
+		}
+		if lit, ok := e.(*Literal); ok {
+			row[i] = lit.Value
+			continue
+		}
+		row[i] = Value{Type: TypeNull}
+		exprs[i] = e
+	}
+	return row, exprs, true
+}
+
+// hasColumnRef reports whether e refers to a column anywhere.
+func hasColumnRef(e Expr) bool {
+	switch e := e.(type) {
+	case *ColumnRef:
+		return true
+	case *BinaryExpr:
+		return hasColumnRef(e.Left) || hasColumnRef(e.Right)
+	case *CaseExpr:
+		if e.Operand != nil && hasColumnRef(e.Operand) || e.Else != nil && hasColumnRef(e.Else) {
+			return true
+		}
+		for _, w := range e.Whens {
+			if hasColumnRef(w.When) || hasColumnRef(w.Then) {
+				return true
+			}
+		}
+	case *CastExpr:
+		return hasColumnRef(e.Expr)
+	case *UnaryExpr:
+		return hasColumnRef(e.Expr)
+	case *FuncCall:
+		for _, a := range e.Args {
+			if hasColumnRef(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitValueTuples splits "(a, b), (c, d)" into the tuple contents
+// "a, b" and "c, d". Parentheses and commas inside string literals are
+// ignored.
+func splitValueTuples(s string) ([]string, error) {
+	var tuples []string
+	for {
+		s = strings.TrimSpace(s)
+		if !strings.HasPrefix(s, "(") {
+			return nil, fmt.Errorf("INSERT: VALUES must be in parentheses")
+		}
+
+		end := -1
+		inQuote := false
+		depth := 0 // parentheses opened inside the tuple, as in NEXTVAL('s')
+		for i := 1; i < len(s) && end == -1; i++ {
+			switch {
+			case s[i] == '\'':
+				inQuote = !inQuote
+			case inQuote:
+			case s[i] == '(':
+				depth++
+			case s[i] == ')' && depth > 0:
+				depth--
+			case s[i] == ')':
+				end = i
+			}
+		}
+		if end == -1 {
+			return nil, fmt.Errorf("INSERT: VALUES must be in parentheses")
+		}
+		tuples = append(tuples, s[1:end])
+
+		rest := strings.TrimSpace(s[end+1:])
+		if rest == "" {
+			return tuples, nil
+		}
+		if rest[0] != ',' {
+			return nil, fmt.Errorf("INSERT: expected ',' between VALUES tuples")
+		}
+		s = rest[1:]
+	}
 }
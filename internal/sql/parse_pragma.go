@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parsePragma parses a PRAGMA statement.
+// Format: PRAGMA name [= value | (arg)]
+func parsePragma(q string) (*PragmaStmt, error) {
+	toks, err := tokenize(q)
+	if err != nil {
+		return nil, err
+	}
+	if toks[1].kind != tokIdent {
+		return nil, fmt.Errorf("PRAGMA: expected a name")
+	}
+	stmt := &PragmaStmt{Name: strings.ToLower(toks[1].text)}
+
+	rest := toks[2:]
+	switch {
+	case rest[0].kind == tokEOF:
+		return stmt, nil
+
+	case rest[0].kind == tokOp && rest[0].text == "=":
+		// The value runs to the end: a word, a number (with an optional
+		// sign) or a string literal.
+		if rest[1].kind == tokEOF {
+			return nil, fmt.Errorf("PRAGMA %s: missing value", stmt.Name)
+		}
+		val := q[rest[1].pos:]
+		if rest[1].kind == tokString && val[0] == '\'' && rest[2].kind == tokEOF {
+			val = strings.ReplaceAll(val[1:len(val)-1], "''", "'")
+		} else if !isPragmaWord(rest[1:]) {
+			return nil, fmt.Errorf("PRAGMA %s: invalid value %q", stmt.Name, val)
+		}
+		stmt.Value = val
+		stmt.Set = true
+		return stmt, nil
+
+	case rest[0].kind == tokLParen:
+		if rest[1].kind != tokIdent || rest[2].kind != tokRParen || rest[3].kind != tokEOF {
+			return nil, fmt.Errorf("PRAGMA %s: expected %s(name)", stmt.Name, stmt.Name)
+		}
+		stmt.Arg = rest[1].text
+		return stmt, nil
+
+	default:
+		return nil, fmt.Errorf("PRAGMA %s: unexpected %q", stmt.Name, q[rest[0].pos:])
+	}
+}
+
+// isPragmaWord reports whether toks, up to EOF, are a single identifier or
+// number, the latter with an optional sign.
+func isPragmaWord(toks []token) bool {
+	if toks[0].kind == tokOp && (toks[0].text == "-" || toks[0].text == "+") {
+		toks = toks[1:]
+		if toks[0].kind != tokNumber {
+			return false
+		}
+	}
+	return (toks[0].kind == tokIdent || toks[0].kind == tokNumber) && toks[1].kind == tokEOF
+}
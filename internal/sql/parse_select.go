@@ -13,6 +13,8 @@ import (
 //	SELECT * FROM users;
 //	SELECT id, name FROM users;
 //	SELECT id, name FROM users WHERE active = true;
+//	SELECT rowid, * FROM users;
+//	SELECT id, COUNT(*) AS c FROM t GROUP BY id ORDER BY c DESC;
 func parseSelect(query string) (Statement, error) {
 	q := strings.TrimSpace(query)
 	if q == "" {
@@ -41,21 +43,24 @@ func parseSelect(query string) (Statement, error) {
 	}
 
 	var cols []string
-	if selectPart == "*" {
-		cols = nil // SELECT * => nil/empty means "all columns"
-	} else {
-		colDefs := splitCommaSeparated(selectPart)
-		if len(colDefs) == 0 {
-			return nil, fmt.Errorf("SELECT: no valid column names")
+	var exprs []Expr
+	if selectPart != "*" {
+		items, texts, err := parseSelectList(selectPart)
+		if err != nil {
+			return nil, fmt.Errorf("SELECT: invalid projection list: %w", err)
 		}
-		for _, c := range colDefs {
-			c = strings.TrimSpace(c)
-			if c != "" {
-				cols = append(cols, c)
+		cols = make([]string, len(items))
+		simple := true
+		for i, item := range items {
+			cols[i] = texts[i]
+			if ref, ok := item.(*ColumnRef); !ok || ref.Name != texts[i] {
+				simple = false
 			}
 		}
-		if len(cols) == 0 {
-			return nil, fmt.Errorf("SELECT: no valid column names")
+		// Exprs is only set when some item is more than a column name,
+		// including a column renamed with AS.
+		if !simple {
+			exprs = items
 		}
 	}
 
@@ -90,12 +95,16 @@ func parseSelect(query string) (Statement, error) {
 			wherePartAndRest := strings.TrimSpace(tail[len("WHERE "):])
 			upperWR := strings.ToUpper(wherePartAndRest)
 
-			// WHERE ... [ORDER BY ...] [LIMIT ...]
-			// split WHERE clause from possible ORDER BY / LIMIT
+			// WHERE ... [GROUP BY ...] [ORDER BY ...] [LIMIT ...]
+			// split WHERE clause from possible GROUP BY / ORDER BY / LIMIT
+			idxGroup := strings.Index(upperWR, " GROUP BY ")
 			idxOrder := strings.Index(upperWR, " ORDER BY ")
 			idxLimit := strings.Index(upperWR, " LIMIT ")
 
 			endWhere := len(wherePartAndRest)
+			if idxGroup != -1 && idxGroup < endWhere {
+				endWhere = idxGroup
+			}
 			if idxOrder != -1 && idxOrder < endWhere {
 				endWhere = idxOrder
 			}
@@ -119,7 +128,34 @@ func parseSelect(query string) (Statement, error) {
 		}
 	}
 
-	// 2) Optional ORDER BY ...
+	// 2) Optional GROUP BY col [, col ...]
+	var groupBy []string
+	if tail != "" {
+		upperTail := strings.ToUpper(tail)
+		if strings.HasPrefix(upperTail, "GROUP BY ") {
+			groupPartAndRest := strings.TrimSpace(tail[len("GROUP BY "):])
+			upperGR := strings.ToUpper(groupPartAndRest)
+
+			endGroup := len(groupPartAndRest)
+			for _, kw := range []string{" ORDER BY ", " LIMIT "} {
+				if idx := strings.Index(upperGR, kw); idx != -1 && idx < endGroup {
+					endGroup = idx
+				}
+			}
+
+			for _, part := range strings.Split(groupPartAndRest[:endGroup], ",") {
+				name := strings.TrimSpace(part)
+				if !IsIdentifier(name) {
+					return nil, fmt.Errorf("SELECT: invalid GROUP BY column %q", name)
+				}
+				groupBy = append(groupBy, name)
+			}
+
+			tail = strings.TrimSpace(groupPartAndRest[endGroup:])
+		}
+	}
+
+	// 3) Optional ORDER BY ...
 	if tail != "" {
 		upperTail := strings.ToUpper(tail)
 		if strings.HasPrefix(upperTail, "ORDER BY ") {
@@ -163,7 +199,7 @@ func parseSelect(query string) (Statement, error) {
 		}
 	}
 
-	// 3) Optional LIMIT ...
+	// 4) Optional LIMIT ...
 	if tail != "" {
 		upperTail := strings.ToUpper(tail)
 		if strings.HasPrefix(upperTail, "LIMIT ") {
@@ -188,13 +224,16 @@ func parseSelect(query string) (Statement, error) {
 	return &SelectStmt{
 		TableName: tableName,
 		Columns:   cols,
+		Exprs:     exprs,
 		Where:     whereExpr,
+		GroupBy:   groupBy,
 		OrderBy:   orderBy,
 		Limit:     limitVal,
 	}, nil
 }
 
-// parseWhereClause parses a simple binary comparison:
+// parseWhereClause parses one or more comparisons against a literal joined
+// by AND:
 //
 //	column = literal
 //	column != literal
@@ -202,50 +241,148 @@ func parseSelect(query string) (Statement, error) {
 //	column <= literal
 //	column > literal
 //	column >= literal
+//	column > literal AND column < literal
+//	column IS [NOT] TRUE | FALSE | NULL
 //
-// We keep it deliberately simple and do not support AND/OR yet.
+// The left side may also be an expression, e.g. first || last = 'xy'.
+// We keep it deliberately simple and do not support OR yet.
 func parseWhereClause(s string) (*WhereExpr, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return nil, fmt.Errorf("WHERE: empty clause")
 	}
 
-	upper := strings.ToUpper(s)
+	terms, err := splitConjunction(s)
+	if err != nil {
+		return nil, fmt.Errorf("WHERE: invalid expression %q: %w", s, err)
+	}
 
-	// Order is important: multi-char operators first.
-	ops := []string{">=", "<=", "!=", "=", ">", "<"}
+	var first, last *WhereExpr
+	for _, term := range terms {
+		w, err := parseComparison(term)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = w
+		} else {
+			last.And = w
+		}
+		last = w
+	}
+	return first, nil
+}
 
-	var op string
-	var idx = -1
+// splitConjunction splits s at every AND outside parentheses and string
+// literals.
+func splitConjunction(s string) ([]string, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, candidate := range ops {
-		i := strings.Index(upper, candidate)
-		if i != -1 {
-			op = candidate
-			idx = i
-			break
+	var terms []string
+	depth, start := 0, 0
+	for _, t := range toks {
+		switch {
+		case t.kind == tokLParen:
+			depth++
+		case t.kind == tokRParen:
+			depth--
+		case t.kind == tokIdent && depth == 0 && strings.EqualFold(t.text, "AND"):
+			terms = append(terms, strings.TrimSpace(s[start:t.pos]))
+			start = t.end
 		}
 	}
+	return append(terms, strings.TrimSpace(s[start:])), nil
+}
 
-	if idx == -1 {
+// parseComparison parses a single comparison of a WHERE clause.
+func parseComparison(s string) (*WhereExpr, error) {
+	if s == "" {
+		return nil, fmt.Errorf("WHERE: missing comparison around AND")
+	}
+
+	if w, ok, err := parseIsTest(s); ok || err != nil {
+		return w, err
+	}
+
+	e, err := parseExpr(s)
+	if err != nil {
+		return nil, fmt.Errorf("WHERE: invalid expression %q: %w", s, err)
+	}
+
+	cmp, ok := e.(*BinaryExpr)
+	if !ok || binaryPrecedence(cmp.Op) != 1 {
 		return nil, fmt.Errorf("WHERE: could not find comparison operator in %q", s)
 	}
 
-	left := strings.TrimSpace(s[:idx])
-	right := strings.TrimSpace(s[idx+len(op):])
+	lit, ok := cmp.Right.(*Literal)
+	if !ok {
+		return nil, fmt.Errorf("WHERE: right side of %q must be a literal", s)
+	}
 
-	if left == "" || right == "" {
-		return nil, fmt.Errorf("WHERE: invalid expression %q", s)
+	w := &WhereExpr{Op: cmp.Op, Value: lit.Value}
+	if ref, ok := cmp.Left.(*ColumnRef); ok {
+		w.Column = ref.Name
+	} else {
+		w.Left = cmp.Left
 	}
+	return w, nil
+}
 
-	val, err := parseLiteral(right)
+// parseIsTest parses a comparison of the form x IS [NOT] TRUE, FALSE or
+// NULL into a WhereExpr with Op "IS" or "IS NOT" and a BOOL or NULL Value.
+// ok is false when s does not end in such a test.
+func parseIsTest(s string) (w *WhereExpr, ok bool, err error) {
+	toks, err := tokenize(s)
 	if err != nil {
-		return nil, fmt.Errorf("WHERE: invalid literal %q: %w", right, err)
+		return nil, false, nil
+	}
+	toks = toks[:len(toks)-1] // drop tokEOF
+	n := len(toks)
+	if n < 3 {
+		return nil, false, nil
 	}
 
-	return &WhereExpr{
-		Column: left,
-		Op:     op,
-		Value:  val,
-	}, nil
+	last := toks[n-1]
+	if last.kind != tokIdent {
+		return nil, false, nil
+	}
+	var v Value
+	switch strings.ToUpper(last.text) {
+	case "TRUE":
+		v = Value{Type: TypeBool, B: true}
+	case "FALSE":
+		v = Value{Type: TypeBool, B: false}
+	case "NULL":
+		v = Value{Type: TypeNull}
+	default:
+		return nil, false, nil
+	}
+
+	op, is := "IS", toks[n-2]
+	if is.kind == tokIdent && strings.EqualFold(is.text, "NOT") {
+		op, is = "IS NOT", toks[n-3]
+	}
+	if is.kind != tokIdent || !strings.EqualFold(is.text, "IS") {
+		return nil, false, nil
+	}
+
+	left := strings.TrimSpace(s[:is.pos])
+	if left == "" {
+		return nil, true, fmt.Errorf("WHERE: missing operand before %s in %q", op, s)
+	}
+	e, err := parseExpr(left)
+	if err != nil {
+		return nil, true, fmt.Errorf("WHERE: invalid expression %q: %w", left, err)
+	}
+
+	w = &WhereExpr{Op: op, Value: v}
+	if ref, ok := e.(*ColumnRef); ok {
+		w.Column = ref.Name
+	} else {
+		w.Left = e
+	}
+	return w, true, nil
 }
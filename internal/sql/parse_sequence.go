@@ -0,0 +1,59 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseCreateSequence parses a CREATE SEQUENCE statement.
+// Format: CREATE SEQUENCE name [START [WITH] n]
+func parseCreateSequence(q string) (*CreateSequenceStmt, error) {
+	parts := strings.Fields(strings.TrimSpace(q))
+	if len(parts) < 3 ||
+		!strings.EqualFold(parts[0], "CREATE") ||
+		!strings.EqualFold(parts[1], "SEQUENCE") {
+		return nil, fmt.Errorf("invalid CREATE SEQUENCE format")
+	}
+
+	name := parts[2]
+	if !IsIdentifier(name) {
+		return nil, fmt.Errorf("CREATE SEQUENCE: invalid sequence name %q", name)
+	}
+	stmt := &CreateSequenceStmt{Name: name, Start: 1}
+
+	rest := parts[3:]
+	if len(rest) == 0 {
+		return stmt, nil
+	}
+	if !strings.EqualFold(rest[0], "START") {
+		return nil, fmt.Errorf("CREATE SEQUENCE: unexpected %q", rest[0])
+	}
+	rest = rest[1:]
+	if len(rest) > 0 && strings.EqualFold(rest[0], "WITH") {
+		rest = rest[1:]
+	}
+	if len(rest) != 1 {
+		return nil, fmt.Errorf("CREATE SEQUENCE: expected START [WITH] n")
+	}
+	start, err := strconv.ParseInt(rest[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("CREATE SEQUENCE: invalid start value %q", rest[0])
+	}
+	stmt.Start = start
+	return stmt, nil
+}
+
+// IsIdentifier reports whether s is a plain SQL identifier: a letter or
+// underscore followed by letters, digits and underscores.
+func IsIdentifier(s string) bool {
+	if s == "" || !isIdentStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
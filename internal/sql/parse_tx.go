@@ -13,16 +13,39 @@ func parseBegin(query string) (Statement, error) {
 		q = strings.TrimSpace(q[:len(q)-1])
 	}
 
-	upper := strings.ToUpper(q)
+	words := strings.Fields(strings.ToUpper(q))
+	if len(words) == 0 || words[0] != "BEGIN" {
+		return nil, fmt.Errorf("BEGIN: invalid syntax")
+	}
+	words = words[1:]
 
-	// Allow: BEGIN or BEGIN TRANSACTION
-	if upper == "BEGIN" || upper == "BEGIN TRANSACTION" {
-		return &BeginTxStmt{}, nil
+	// BEGIN [DEFERRED | IMMEDIATE] [TRANSACTION] [READ ONLY | READ WRITE]
+	stmt := &BeginTxStmt{}
+	if len(words) > 0 && (words[0] == "DEFERRED" || words[0] == "IMMEDIATE") {
+		if words[0] == "IMMEDIATE" {
+			stmt.Mode = TxImmediate
+		}
+		words = words[1:]
+	}
+	if len(words) > 0 && words[0] == "TRANSACTION" {
+		words = words[1:]
+	}
+	if len(words) == 2 && words[0] == "READ" {
+		switch words[1] {
+		case "ONLY":
+			if stmt.Mode == TxImmediate {
+				return nil, fmt.Errorf("BEGIN: IMMEDIATE and READ ONLY cannot be combined")
+			}
+			stmt.Mode = TxReadOnly
+			words = nil
+		case "WRITE":
+			words = nil
+		}
 	}
-	if strings.HasPrefix(upper, "BEGIN ") {
-		return nil, fmt.Errorf("BEGIN: only 'BEGIN' or 'BEGIN TRANSACTION' are supported")
+	if len(words) > 0 {
+		return nil, fmt.Errorf("BEGIN: expected [DEFERRED | IMMEDIATE] [TRANSACTION] [READ ONLY | READ WRITE], got %q", strings.Join(words, " "))
 	}
-	return nil, fmt.Errorf("BEGIN: invalid syntax")
+	return stmt, nil
 }
 
 func parseCommit(query string) (Statement, error) {
@@ -8,6 +8,9 @@ import (
 // parseUpdate parses:
 //
 //	UPDATE tableName SET col1 = value1, col2 = value2 WHERE column = literal;
+//
+// A value is normally a literal or DEFAULT; it may also be an expression over
+// the row's current values, such as balance + 100.
 func parseUpdate(query string) (Statement, error) {
 	q := strings.TrimSpace(query)
 
@@ -85,12 +88,17 @@ func parseUpdate(query string) (Statement, error) {
 
 		val, err := parseLiteral(valPart)
 		if err != nil {
-			return nil, fmt.Errorf("UPDATE: invalid literal %q: %w", valPart, err)
+			if exprs, exprErr := parseAssignmentExprs(assignsPart); exprErr == nil {
+				assignments = exprs
+				break
+			}
+			return nil, fmt.Errorf("UPDATE: invalid value %q: %w", valPart, err)
 		}
 
 		assignments = append(assignments, Assignment{
-			Column: colPart,
-			Value:  val,
+			Column:  colPart,
+			Value:   val,
+			Default: strings.EqualFold(valPart, "DEFAULT"),
 		})
 	}
 
@@ -109,3 +117,52 @@ func parseUpdate(query string) (Statement, error) {
 		Where:       whereExpr,
 	}, nil
 }
+
+// parseAssignmentExprs parses a SET list whose values are not all literals:
+// "col1 = expr1, col2 = expr2". Literal values still go to Value, and
+// DEFAULT sets Default.
+func parseAssignmentExprs(s string) ([]Assignment, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+
+	var out []Assignment
+	for {
+		col := p.next()
+		if col.kind != tokIdent {
+			return nil, fmt.Errorf("expected a column name")
+		}
+		if t := p.next(); t.kind != tokOp || t.text != "=" {
+			return nil, fmt.Errorf("expected '=' after %s", col.text)
+		}
+
+		a := Assignment{Column: col.text, Value: Value{Type: TypeNull}}
+		if t := p.peek(); t.kind == tokIdent && strings.EqualFold(t.text, "DEFAULT") &&
+			(p.toks[p.pos+1].kind == tokComma || p.toks[p.pos+1].kind == tokEOF) {
+			p.next()
+			a.Default = true
+		} else {
+			e, err := p.parseExpr(1)
+			if err != nil {
+				return nil, err
+			}
+			if lit, ok := e.(*Literal); ok {
+				a.Value = lit.Value
+			} else {
+				a.Expr = e
+			}
+		}
+		out = append(out, a)
+
+		switch t := p.next(); t.kind {
+		case tokComma:
+			continue
+		case tokEOF:
+			return out, nil
+		default:
+			return nil, fmt.Errorf("unexpected %q", t.text)
+		}
+	}
+}
@@ -5,9 +5,18 @@ import (
 	"strings"
 )
 
-// Parse parses a single SQL statement string into an AST Statement.
-// For now it only supports CREATE TABLE statements.
+// Parse parses a single SQL statement string into an AST Statement. Every
+// error it returns wraps ErrSyntax.
 func Parse(query string) (Statement, error) {
+	stmt, err := parseStatement(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSyntax, err)
+	}
+	return stmt, nil
+}
+
+// parseStatement dispatches a statement to the parser for its kind.
+func parseStatement(query string) (Statement, error) {
 	// Trim leading & trailing whitespace
 	q := strings.TrimSpace(query)
 	if q == "" {
@@ -33,9 +42,16 @@ func Parse(query string) (Statement, error) {
 				return parseCreateTable(q)
 			case "INDEX":
 				return parseCreateIndex(q)
+			case "SEQUENCE":
+				return parseCreateSequence(q)
 			}
 		}
 		return nil, fmt.Errorf("invalid CREATE statement")
+	case "ALTER":
+		if len(tokens) >= 2 && tokens[1] == "TABLE" {
+			return parseAlterTable(q)
+		}
+		return nil, fmt.Errorf("invalid ALTER statement")
 	case "INSERT":
 		if len(tokens) >= 2 && tokens[1] == "INTO" {
 			return parseInsert(q)
@@ -53,8 +69,63 @@ func Parse(query string) (Statement, error) {
 		return parseCommit(q)
 	case "ROLLBACK":
 		return parseRollback(q)
+	case "PRAGMA":
+		return parsePragma(q)
 	default:
-		return nil, fmt.Errorf("unsupported statement (supported: CREATE TABLE, CREATE INDEX, INSERT, SELECT, UPDATE, DELETE, BEGIN, COMMIT, ROLLBACK)")
+		return nil, fmt.Errorf("unsupported statement (supported: CREATE TABLE, CREATE INDEX, CREATE SEQUENCE, ALTER TABLE, INSERT, SELECT, UPDATE, DELETE, BEGIN, COMMIT, ROLLBACK, PRAGMA)")
+	}
+}
+
+// ParseMulti parses a script containing several semicolon-separated
+// statements. It stops at the first statement that fails to parse and
+// reports its 1-based position in the script.
+func ParseMulti(script string) ([]Statement, error) {
+	parts, rest := SplitStatements(script)
+	if rest != "" {
+		parts = append(parts, rest)
+	}
+
+	stmts := make([]Statement, 0, len(parts))
+	for i, p := range parts {
+		stmt, err := Parse(p)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, nil
+}
+
+// SplitStatements splits a script on semicolons that appear outside
+// single-quoted string literals and "--" line comments. Comments are
+// dropped and the returned statements carry no trailing semicolon. Any text
+// after the last semicolon is returned as rest (trimmed, possibly empty) so
+// callers reading input incrementally can keep buffering it.
+func SplitStatements(script string) (stmts []string, rest string) {
+	var cur strings.Builder
+	inQuote := false
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'':
+			inQuote = !inQuote
+			cur.WriteByte(c)
+		case !inQuote && c == '-' && i+1 < len(script) && script[i+1] == '-':
+			// Skip the comment up to (but not including) the newline.
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			i--
+		case !inQuote && c == ';':
+			if s := strings.TrimSpace(cur.String()); s != "" {
+				stmts = append(stmts, s)
+			}
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
 	}
 
+	return stmts, strings.TrimSpace(cur.String())
 }
@@ -1,6 +1,11 @@
 package sql
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestParseCreateTable_Basic(t *testing.T) {
 	query := "CREATE TABLE users (id INT, name STRING, active BOOL);"
@@ -37,6 +42,117 @@ func TestParseCreateTable_Basic(t *testing.T) {
 	assertCol(2, "active", TypeBool)
 }
 
+func TestParseCreateTable_Defaults(t *testing.T) {
+	stmt, err := Parse("CREATE TABLE events (id INT, note STRING DEFAULT 'n/a, none', created TIMESTAMP DEFAULT CURRENT_TIMESTAMP, at TIMESTAMP DEFAULT '2024-01-02 03:04:05');")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cols := stmt.(*CreateTableStmt).Columns
+	if len(cols) != 4 {
+		t.Fatalf("expected 4 columns, got %d", len(cols))
+	}
+	if cols[0].Default != nil || cols[0].DefaultCurrentTimestamp {
+		t.Fatalf("id: unexpected default %+v", cols[0])
+	}
+	if cols[1].Default == nil || *cols[1].Default != (Value{Type: TypeString, S: "n/a, none"}) {
+		t.Fatalf("note: unexpected default %+v", cols[1].Default)
+	}
+	if cols[2].Type != TypeTimestamp || !cols[2].DefaultCurrentTimestamp {
+		t.Fatalf("created: unexpected column %+v", cols[2])
+	}
+	if cols[3].Default == nil || FormatTimestamp(*cols[3].Default) != "2024-01-02 03:04:05" {
+		t.Fatalf("at: unexpected default %+v", cols[3].Default)
+	}
+
+	for _, q := range []string{
+		"CREATE TABLE t (id INT DEFAULT CURRENT_TIMESTAMP);",
+		"CREATE TABLE t (id INT DEFAULT 'x');",
+		"CREATE TABLE t (id INT PRIMARY KEY);",
+		"CREATE TABLE t (at TIMESTAMP DEFAULT 'yesterday');",
+		"CREATE TABLE t (id INT REFERENCES users);",
+		"CREATE TABLE t (id INT REFERENCES users(id) ON DELETE CASCADE);",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Fatalf("expected error for %q", q)
+		}
+	}
+}
+
+func TestParseCreateTable_References(t *testing.T) {
+	stmt, err := Parse("CREATE TABLE orders (id INT, user_id INT REFERENCES users(id), note STRING DEFAULT 'x' REFERENCES notes(text));")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cols := stmt.(*CreateTableStmt).Columns
+	if cols[0].References != nil {
+		t.Fatalf("id: unexpected reference %+v", cols[0].References)
+	}
+	if ref := cols[1].References; ref == nil || *ref != (ForeignKey{Table: "users", Column: "id"}) {
+		t.Fatalf("user_id: unexpected reference %+v", ref)
+	}
+	if ref := cols[2].References; ref == nil || *ref != (ForeignKey{Table: "notes", Column: "text"}) || cols[2].Default == nil {
+		t.Fatalf("note: unexpected column %+v", cols[2])
+	}
+}
+
+func TestParseCreateTable_Collate(t *testing.T) {
+	stmt, err := Parse("CREATE TABLE users (id INT, email STRING COLLATE nocase DEFAULT 'x', name STRING COLLATE BINARY);")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cols := stmt.(*CreateTableStmt).Columns
+	if cols[0].NoCase || !cols[1].NoCase || cols[1].Default == nil || cols[2].NoCase {
+		t.Fatalf("unexpected columns %+v", cols)
+	}
+
+	for _, q := range []string{
+		"CREATE TABLE t (id INT COLLATE NOCASE);",
+		"CREATE TABLE t (name STRING COLLATE RTRIM);",
+		"CREATE TABLE t (name STRING COLLATE);",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Fatalf("expected error for %q", q)
+		}
+	}
+}
+
+func TestParseCreateTable_NotNull(t *testing.T) {
+	stmt, err := Parse("CREATE TABLE users (id INT NOT NULL, name STRING not null DEFAULT 'x', note STRING NULL);")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cols := stmt.(*CreateTableStmt).Columns
+	if !cols[0].NotNull || !cols[1].NotNull || cols[1].Default == nil || cols[2].NotNull {
+		t.Fatalf("unexpected columns %+v", cols)
+	}
+
+	for _, q := range []string{
+		"CREATE TABLE t (id INT NOT);",
+		"CREATE TABLE t (id INT NOT DEFAULT 1);",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Fatalf("expected error for %q", q)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	for in, want := range map[string]string{
+		"2024-01-02 03:04:05":       "2024-01-02 03:04:05",
+		"2024-01-02 03:04:05.250":   "2024-01-02 03:04:05.25",
+		"2024-01-02T05:04:05+02:00": "2024-01-02 03:04:05",
+		"2024-01-02":                "2024-01-02 00:00:00",
+	} {
+		v, err := ParseTimestamp(in)
+		if err != nil {
+			t.Fatalf("ParseTimestamp(%q) failed: %v", in, err)
+		}
+		if got := FormatTimestamp(v); got != want {
+			t.Fatalf("ParseTimestamp(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
 func TestParseCreateTable_CaseAndSpaces(t *testing.T) {
 	query := "  create   table   Accounts  (  balance   float ,  owner  text );  "
 
@@ -222,6 +338,70 @@ func TestParseSelect_WithWhereString(t *testing.T) {
 		t.Fatalf("unexpected WHERE value: %+v", sel.Where.Value)
 	}
 }
+func TestParseSelect_WhereAnd(t *testing.T) {
+	stmt, err := Parse("SELECT * FROM users WHERE id > 5 AND id <= 10 and name = 'x AND y' ORDER BY id;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+
+	var got []WhereExpr
+	for w := sel.Where; w != nil; w = w.And {
+		c := *w
+		c.And = nil
+		got = append(got, c)
+	}
+	want := []WhereExpr{
+		{Column: "id", Op: ">", Value: Value{Type: TypeInt, I64: 5}},
+		{Column: "id", Op: "<=", Value: Value{Type: TypeInt, I64: 10}},
+		{Column: "name", Op: "=", Value: Value{Type: TypeString, S: "x AND y"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("WHERE chain = %+v, want %+v", got, want)
+	}
+	if sel.OrderBy == nil || sel.OrderBy.Column != "id" {
+		t.Fatalf("unexpected ORDER BY: %+v", sel.OrderBy)
+	}
+
+	for _, q := range []string{
+		"SELECT * FROM users WHERE id > 5 AND;",
+		"SELECT * FROM users WHERE AND id > 5;",
+		"DELETE FROM users WHERE id > 5 AND name;",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) should fail", q)
+		}
+	}
+}
+
+func TestParseSelect_WhereIs(t *testing.T) {
+	stmt, err := Parse("SELECT * FROM users WHERE active IS NOT TRUE AND name is null AND LOWER(name) IS FALSE;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	w := stmt.(*SelectStmt).Where
+	if w.Column != "active" || w.Op != "IS NOT" || w.Value != (Value{Type: TypeBool, B: true}) {
+		t.Fatalf("first term = %+v", w)
+	}
+	if w = w.And; w.Column != "name" || w.Op != "IS" || w.Value.Type != TypeNull {
+		t.Fatalf("second term = %+v", w)
+	}
+	if w = w.And; w.Left == nil || w.Op != "IS" || w.Value != (Value{Type: TypeBool}) {
+		t.Fatalf("third term = %+v", w)
+	}
+
+	for _, q := range []string{
+		"SELECT * FROM users WHERE IS TRUE;",
+		"SELECT * FROM users WHERE active IS;",
+		"SELECT * FROM users WHERE active IS NOT;",
+		"SELECT * FROM users WHERE active IS 1;",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) should fail", q)
+		}
+	}
+}
+
 func TestParseSelect_ColumnList(t *testing.T) {
 	query := "SELECT id, name FROM users;"
 
@@ -346,6 +526,50 @@ func TestParseUpdate_MultiAssignmentWithSpaces(t *testing.T) {
 		t.Fatalf("unexpected second assignment: %+v", a1)
 	}
 }
+
+func TestParseUpdate_Default(t *testing.T) {
+	stmt, err := Parse("UPDATE users SET active = DEFAULT, name = NULL WHERE id = 1;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	upd := stmt.(*UpdateStmt)
+	a0, a1 := upd.Assignments[0], upd.Assignments[1]
+	if !a0.Default || a0.Value.Type != TypeNull {
+		t.Fatalf("expected DEFAULT assignment, got %+v", a0)
+	}
+	if a1.Default || a1.Value.Type != TypeNull {
+		t.Fatalf("expected NULL assignment, got %+v", a1)
+	}
+}
+
+func TestParseUpdate_Expressions(t *testing.T) {
+	stmt, err := Parse("UPDATE accounts SET balance = balance + 100, note = COALESCE(note, 'x, y'), active = DEFAULT, n = 2 WHERE id = 1;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	as := stmt.(*UpdateStmt).Assignments
+	if len(as) != 4 {
+		t.Fatalf("expected 4 assignments, got %+v", as)
+	}
+	bin, ok := as[0].Expr.(*BinaryExpr)
+	if as[0].Column != "balance" || !ok || bin.Op != "+" || as[0].Value.Type != TypeNull {
+		t.Fatalf("unexpected first assignment: %+v", as[0])
+	}
+	if call, ok := as[1].Expr.(*FuncCall); !ok || len(call.Args) != 2 {
+		t.Fatalf("unexpected second assignment: %+v", as[1])
+	}
+	if !as[2].Default || as[2].Expr != nil {
+		t.Fatalf("expected DEFAULT assignment, got %+v", as[2])
+	}
+	if as[3].Expr != nil || as[3].Value.I64 != 2 {
+		t.Fatalf("expected literal assignment, got %+v", as[3])
+	}
+
+	if _, err := Parse("UPDATE accounts SET balance = balance + WHERE id = 1;"); err == nil {
+		t.Fatalf("expected an error for an incomplete expression")
+	}
+}
+
 func TestParseDelete_Basic(t *testing.T) {
 	query := "DELETE FROM users WHERE id = 1;"
 
@@ -446,3 +670,717 @@ func TestParseSelect_OrderByLimit(t *testing.T) {
 		t.Fatalf("unexpected LIMIT: %+v", sel.Limit)
 	}
 }
+
+func TestParseSelect_GroupBy(t *testing.T) {
+	query := "SELECT a, b, COUNT(*) FROM t WHERE c > 1 GROUP BY a, b ORDER BY a LIMIT 5;"
+
+	stmt, err := Parse(query)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+
+	if !reflect.DeepEqual(sel.GroupBy, []string{"a", "b"}) {
+		t.Fatalf("unexpected GROUP BY: %v", sel.GroupBy)
+	}
+	if sel.Where == nil || sel.Where.Column != "c" {
+		t.Fatalf("unexpected WHERE: %+v", sel.Where)
+	}
+	if sel.OrderBy == nil || sel.OrderBy.Column != "a" || sel.Limit == nil || *sel.Limit != 5 {
+		t.Fatalf("unexpected ORDER BY/LIMIT: %+v %v", sel.OrderBy, sel.Limit)
+	}
+	if !reflect.DeepEqual(sel.Columns, []string{"a", "b", "COUNT(*)"}) {
+		t.Fatalf("unexpected columns: %v", sel.Columns)
+	}
+	if call, ok := sel.Exprs[2].(*FuncCall); !ok || call.Name != "COUNT" || !call.Star {
+		t.Fatalf("unexpected COUNT(*) expression: %#v", sel.Exprs[2])
+	}
+
+	for _, bad := range []string{
+		"SELECT a FROM t GROUP BY a,;",
+		"SELECT a FROM t GROUP BY a b;",
+		"SELECT SUM(*) FROM t;",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("expected %q to fail", bad)
+		}
+	}
+}
+
+func TestParseInsert_QuotedCommaAndEscapedQuote(t *testing.T) {
+	query := "INSERT INTO notes VALUES (1, 'a, b', 'it''s');"
+
+	stmt, err := Parse(query)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	ins, ok := stmt.(*InsertStmt)
+	if !ok {
+		t.Fatalf("expected *InsertStmt, got %T", stmt)
+	}
+
+	if len(ins.Values) != 3 {
+		t.Fatalf("expected 3 values, got %d: %#v", len(ins.Values), ins.Values)
+	}
+	if ins.Values[1].Type != TypeString || ins.Values[1].S != "a, b" {
+		t.Fatalf("unexpected value 1: %#v", ins.Values[1])
+	}
+	if ins.Values[2].Type != TypeString || ins.Values[2].S != "it's" {
+		t.Fatalf("unexpected value 2: %#v", ins.Values[2])
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `
+-- create a table
+CREATE TABLE t (id INT, s STRING);
+INSERT INTO t VALUES (1, 'a;b'); INSERT INTO t VALUES (2, 'it''s -- not a comment');
+SELECT * FROM t`
+
+	stmts, rest := SplitStatements(script)
+	want := []string{
+		"CREATE TABLE t (id INT, s STRING)",
+		"INSERT INTO t VALUES (1, 'a;b')",
+		"INSERT INTO t VALUES (2, 'it''s -- not a comment')",
+	}
+	if len(stmts) != len(want) {
+		t.Fatalf("expected %d statements, got %d: %#v", len(want), len(stmts), stmts)
+	}
+	for i := range want {
+		if stmts[i] != want[i] {
+			t.Fatalf("statement %d: expected %q, got %q", i, want[i], stmts[i])
+		}
+	}
+	if rest != "SELECT * FROM t" {
+		t.Fatalf("unexpected rest: %q", rest)
+	}
+}
+
+func TestParseMulti(t *testing.T) {
+	stmts, err := ParseMulti("BEGIN; INSERT INTO t VALUES (1); COMMIT;")
+	if err != nil {
+		t.Fatalf("ParseMulti failed: %v", err)
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(stmts))
+	}
+	if _, ok := stmts[1].(*InsertStmt); !ok {
+		t.Fatalf("expected *InsertStmt, got %T", stmts[1])
+	}
+
+	if _, err := ParseMulti("SELECT * FROM t; BOGUS;"); err == nil {
+		t.Fatalf("expected error for invalid second statement")
+	} else if !strings.Contains(err.Error(), "statement 2") || !errors.Is(err, ErrSyntax) {
+		t.Fatalf("expected a syntax error naming statement 2, got %v", err)
+	}
+}
+
+func TestParse_ErrSyntax(t *testing.T) {
+	for _, q := range []string{
+		"",
+		"SELEKT * FROM t;",
+		"SELECT * FROM t WHERE;",
+		"CREATE TABLE t (id INT DEFAULT 'x');",
+	} {
+		if _, err := Parse(q); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) = %v, want an error wrapping ErrSyntax", q, err)
+		}
+	}
+}
+
+func TestParseInsert_MultiRow(t *testing.T) {
+	query := "INSERT INTO users VALUES (1, 'a (b)'), (2, 'c'),(3, NULL);"
+
+	stmt, err := Parse(query)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	ins, ok := stmt.(*InsertStmt)
+	if !ok {
+		t.Fatalf("expected *InsertStmt, got %T", stmt)
+	}
+
+	if len(ins.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d: %#v", len(ins.Rows), ins.Rows)
+	}
+	if ins.Rows[0][1].S != "a (b)" || ins.Rows[2][0].I64 != 3 || ins.Rows[2][1].Type != TypeNull {
+		t.Fatalf("unexpected rows: %#v", ins.Rows)
+	}
+	if len(ins.Values) != 2 || ins.Values[0].I64 != 1 {
+		t.Fatalf("Values should hold the first row, got %#v", ins.Values)
+	}
+
+	for _, bad := range []string{
+		"INSERT INTO users VALUES (1, 'a'), (2);",
+		"INSERT INTO users VALUES (1, 'a') (2, 'b');",
+		"INSERT INTO users VALUES (1, 'a'),;",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseLiteral_Blob(t *testing.T) {
+	for in, want := range map[string]string{
+		"x'48656C6C6F'": "Hello",
+		"X'00ff'":       "\x00\xff",
+		"x''":           "",
+	} {
+		got, err := parseLiteral(in)
+		if err != nil {
+			t.Fatalf("parseLiteral(%q) failed: %v", in, err)
+		}
+		if got != (Value{Type: TypeBytes, S: want}) {
+			t.Fatalf("parseLiteral(%q) = %+v, want bytes %q", in, got, want)
+		}
+	}
+	if got := FormatBlob(Value{Type: TypeBytes, S: "\xca\xfe"}); got != "x'CAFE'" {
+		t.Fatalf("FormatBlob = %s, want x'CAFE'", got)
+	}
+	for _, bad := range []string{"x'ABC'", "x'zz'"} {
+		if _, err := parseLiteral(bad); err == nil {
+			t.Fatalf("parseLiteral(%q): expected error", bad)
+		}
+	}
+
+	stmt, err := Parse("INSERT INTO files VALUES (1, x'CAFE', 'x''y');")
+	if err != nil {
+		t.Fatalf("Parse INSERT failed: %v", err)
+	}
+	vals := stmt.(*InsertStmt).Values
+	if vals[1] != (Value{Type: TypeBytes, S: "\xca\xfe"}) || vals[2] != (Value{Type: TypeString, S: "x'y"}) {
+		t.Fatalf("unexpected values: %+v", vals)
+	}
+
+	stmt, err = Parse("SELECT * FROM files WHERE data = x'CAFE';")
+	if err != nil {
+		t.Fatalf("Parse SELECT failed: %v", err)
+	}
+	if v := stmt.(*SelectStmt).Where.Value; v != (Value{Type: TypeBytes, S: "\xca\xfe"}) {
+		t.Fatalf("unexpected WHERE value: %+v", v)
+	}
+}
+
+func TestParseLiteral_Hex(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Value
+	}{
+		{"0xFF", Value{Type: TypeInt, I64: 255}},
+		{"0X10", Value{Type: TypeInt, I64: 16}},
+		{"-0x1f", Value{Type: TypeInt, I64: -31}},
+		{"010", Value{Type: TypeInt, I64: 10}},
+		{"1.5", Value{Type: TypeFloat, F64: 1.5}},
+		{"'0x10'", Value{Type: TypeString, S: "0x10"}},
+	}
+	for _, c := range cases {
+		got, err := parseLiteral(c.in)
+		if err != nil {
+			t.Fatalf("parseLiteral(%q) failed: %v", c.in, err)
+		}
+		if got != c.want {
+			t.Fatalf("parseLiteral(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"0xZZ", "0x", "0x8000000000000000"} {
+		if _, err := parseLiteral(bad); err == nil {
+			t.Fatalf("parseLiteral(%q): expected error", bad)
+		}
+	}
+
+	stmt, err := Parse("SELECT * FROM t WHERE flags = 0x10;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if v := stmt.(*SelectStmt).Where.Value; v.Type != TypeInt || v.I64 != 16 {
+		t.Fatalf("unexpected WHERE value: %+v", v)
+	}
+}
+
+func TestParseLiteral_Underscores(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Value
+	}{
+		{"1_000", Value{Type: TypeInt, I64: 1000}},
+		{"1_000_000", Value{Type: TypeInt, I64: 1000000}},
+		{"-1_000", Value{Type: TypeInt, I64: -1000}},
+		{"1_000.5", Value{Type: TypeFloat, F64: 1000.5}},
+		{"0xFF_FF", Value{Type: TypeInt, I64: 0xFFFF}},
+		{"'a_b'", Value{Type: TypeString, S: "a_b"}},
+	}
+	for _, c := range cases {
+		got, err := parseLiteral(c.in)
+		if err != nil {
+			t.Fatalf("parseLiteral(%q) failed: %v", c.in, err)
+		}
+		if got != c.want {
+			t.Fatalf("parseLiteral(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"_1", "1_", "1__0", "1_.5", "1._5", "0x_FF"} {
+		if _, err := parseLiteral(bad); err == nil {
+			t.Fatalf("parseLiteral(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseSelect_ConcatExpr(t *testing.T) {
+	stmt, err := Parse("SELECT id, first || ' ' || last FROM users WHERE a || b = 'xy';")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+
+	if len(sel.Columns) != 2 || sel.Columns[1] != "first || ' ' || last" {
+		t.Fatalf("unexpected columns: %#v", sel.Columns)
+	}
+	if len(sel.Exprs) != 2 {
+		t.Fatalf("expected 2 exprs, got %d", len(sel.Exprs))
+	}
+	// Left-associative: (first || ' ') || last
+	outer, ok := sel.Exprs[1].(*BinaryExpr)
+	if !ok || outer.Op != "||" {
+		t.Fatalf("unexpected expr: %#v", sel.Exprs[1])
+	}
+	if inner, ok := outer.Left.(*BinaryExpr); !ok || inner.Op != "||" {
+		t.Fatalf("expected nested || on the left, got %#v", outer.Left)
+	}
+	if ref, ok := outer.Right.(*ColumnRef); !ok || ref.Name != "last" {
+		t.Fatalf("unexpected right operand: %#v", outer.Right)
+	}
+
+	if sel.Where == nil || sel.Where.Column != "" || sel.Where.Op != "=" || sel.Where.Value.S != "xy" {
+		t.Fatalf("unexpected WHERE: %+v", sel.Where)
+	}
+	if left, ok := sel.Where.Left.(*BinaryExpr); !ok || left.Op != "||" {
+		t.Fatalf("unexpected WHERE left side: %#v", sel.Where.Left)
+	}
+
+	// Plain column lists keep Exprs nil.
+	stmt, err = Parse("SELECT id, name FROM users;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if sel := stmt.(*SelectStmt); sel.Exprs != nil {
+		t.Fatalf("expected nil Exprs for plain columns, got %#v", sel.Exprs)
+	}
+
+	for _, bad := range []string{
+		"SELECT a || FROM t;",
+		"SELECT * FROM t WHERE a || 'x';",
+		"SELECT * FROM t WHERE a = b;",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseSelect_ArithmeticPrecedence(t *testing.T) {
+	stmt, err := Parse("SELECT a + b * 2 - c FROM t WHERE a % 3 + 1 > 2;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+
+	// (a + (b * 2)) - c
+	sub, ok := sel.Exprs[0].(*BinaryExpr)
+	if !ok || sub.Op != "-" {
+		t.Fatalf("unexpected expr: %#v", sel.Exprs[0])
+	}
+	add, ok := sub.Left.(*BinaryExpr)
+	if !ok || add.Op != "+" {
+		t.Fatalf("expected + on the left, got %#v", sub.Left)
+	}
+	if mul, ok := add.Right.(*BinaryExpr); !ok || mul.Op != "*" {
+		t.Fatalf("expected * to bind tighter than +, got %#v", add.Right)
+	}
+
+	if sel.Where == nil || sel.Where.Op != ">" || sel.Where.Value.I64 != 2 {
+		t.Fatalf("unexpected WHERE: %+v", sel.Where)
+	}
+	if left, ok := sel.Where.Left.(*BinaryExpr); !ok || left.Op != "+" {
+		t.Fatalf("unexpected WHERE left side: %#v", sel.Where.Left)
+	}
+}
+
+func TestParseSelect_Alias(t *testing.T) {
+	stmt, err := Parse("SELECT id AS user_id, COUNT(*) as c, score FROM t GROUP BY id ORDER BY c DESC;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+
+	want := []string{"user_id", "c", "score"}
+	if !reflect.DeepEqual(sel.Columns, want) {
+		t.Fatalf("columns = %v, want %v", sel.Columns, want)
+	}
+	if sel.Exprs == nil {
+		t.Fatalf("expected Exprs for a renamed column")
+	}
+	if ref, ok := sel.Exprs[0].(*ColumnRef); !ok || ref.Name != "id" {
+		t.Fatalf("unexpected first expr: %#v", sel.Exprs[0])
+	}
+	if sel.OrderBy == nil || sel.OrderBy.Column != "c" || !sel.OrderBy.Desc {
+		t.Fatalf("unexpected ORDER BY: %+v", sel.OrderBy)
+	}
+
+	if _, err := Parse("SELECT id AS FROM t;"); err == nil {
+		t.Fatalf("expected error for AS without a name")
+	}
+}
+
+func TestParseSelect_Case(t *testing.T) {
+	stmt, err := Parse("SELECT CASE WHEN active THEN 'yes' WHEN score > 1 THEN 'maybe' ELSE 'no' END, case id when 1 then 'one' end FROM users;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+
+	searched, ok := sel.Exprs[0].(*CaseExpr)
+	if !ok || searched.Operand != nil || len(searched.Whens) != 2 || searched.Else == nil {
+		t.Fatalf("unexpected searched CASE: %#v", sel.Exprs[0])
+	}
+	if cmp, ok := searched.Whens[1].When.(*BinaryExpr); !ok || cmp.Op != ">" {
+		t.Fatalf("unexpected second WHEN: %#v", searched.Whens[1].When)
+	}
+	simple, ok := sel.Exprs[1].(*CaseExpr)
+	if !ok || simple.Operand == nil || len(simple.Whens) != 1 || simple.Else != nil {
+		t.Fatalf("unexpected simple CASE: %#v", sel.Exprs[1])
+	}
+	if sel.Columns[1] != "case id when 1 then 'one' end" {
+		t.Fatalf("unexpected column name %q", sel.Columns[1])
+	}
+
+	for _, bad := range []string{
+		"SELECT CASE END FROM t;",
+		"SELECT CASE WHEN a 'x' END FROM t;",
+		"SELECT CASE WHEN a THEN 'x' FROM t;",
+		"SELECT CASE WHEN a THEN 'x' ELSE END FROM t;",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseSelect_Cast(t *testing.T) {
+	stmt, err := Parse("SELECT CAST(id AS text) FROM users WHERE CAST(price AS INT) = 10;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+	c, ok := sel.Exprs[0].(*CastExpr)
+	if !ok || c.Type != TypeString {
+		t.Fatalf("unexpected projection: %#v", sel.Exprs[0])
+	}
+	if ref, ok := c.Expr.(*ColumnRef); !ok || ref.Name != "id" {
+		t.Fatalf("unexpected CAST operand: %#v", c.Expr)
+	}
+	if w, ok := sel.Where.Left.(*CastExpr); !ok || w.Type != TypeInt {
+		t.Fatalf("unexpected WHERE left side: %#v", sel.Where.Left)
+	}
+
+	for _, bad := range []string{
+		"SELECT CAST(id) FROM t;",
+		"SELECT CAST(id AS DATE) FROM t;",
+		"SELECT CAST(id AS INT FROM t;",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParse_UnaryMinus(t *testing.T) {
+	// A minus in front of a number is part of the literal, with or without
+	// a space.
+	for _, q := range []string{
+		"SELECT * FROM t WHERE x = -10;",
+		"SELECT * FROM t WHERE x = - 10;",
+	} {
+		stmt, err := Parse(q)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", q, err)
+		}
+		w := stmt.(*SelectStmt).Where
+		if w.Column != "x" || w.Value.Type != TypeInt || w.Value.I64 != -10 {
+			t.Fatalf("Parse(%q): unexpected WHERE %#v", q, w)
+		}
+	}
+
+	stmt, err := Parse("UPDATE t SET x = - 5 WHERE y = -1.5;")
+	if err != nil {
+		t.Fatalf("Parse UPDATE failed: %v", err)
+	}
+	upd := stmt.(*UpdateStmt)
+	if upd.Assignments[0].Value.I64 != -5 || upd.Where.Value.F64 != -1.5 {
+		t.Fatalf("unexpected UPDATE: %#v", upd)
+	}
+
+	// In front of anything else it negates the operand.
+	stmt, err = Parse("SELECT -x, -(x), - 5 FROM t WHERE -x = 10;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+	for i := 0; i < 2; i++ {
+		u, ok := sel.Exprs[i].(*UnaryExpr)
+		if !ok || u.Op != "-" {
+			t.Fatalf("expr %d: expected UnaryExpr, got %#v", i, sel.Exprs[i])
+		}
+		if ref, ok := u.Expr.(*ColumnRef); !ok || ref.Name != "x" {
+			t.Fatalf("expr %d: unexpected operand %#v", i, u.Expr)
+		}
+	}
+	if lit, ok := sel.Exprs[2].(*Literal); !ok || lit.Value.I64 != -5 {
+		t.Fatalf("expected literal -5, got %#v", sel.Exprs[2])
+	}
+	if _, ok := sel.Where.Left.(*UnaryExpr); !ok {
+		t.Fatalf("unexpected WHERE left side: %#v", sel.Where.Left)
+	}
+
+	for _, bad := range []string{
+		"SELECT - FROM t;",
+		"SELECT * FROM t WHERE x = -;",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseBegin_Modes(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  TxMode
+	}{
+		{"BEGIN;", TxDeferred},
+		{"begin transaction", TxDeferred},
+		{"BEGIN DEFERRED", TxDeferred},
+		{"BEGIN IMMEDIATE;", TxImmediate},
+		{"BEGIN IMMEDIATE TRANSACTION", TxImmediate},
+		{"BEGIN READ ONLY", TxReadOnly},
+		{"BEGIN TRANSACTION READ ONLY;", TxReadOnly},
+		{"BEGIN READ WRITE", TxDeferred},
+		{"BEGIN IMMEDIATE READ WRITE", TxImmediate},
+	} {
+		stmt, err := Parse(tc.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tc.query, err)
+		}
+		if got := stmt.(*BeginTxStmt).Mode; got != tc.want {
+			t.Fatalf("Parse(%q): mode %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"BEGIN WORK",
+		"BEGIN READ",
+		"BEGIN IMMEDIATE READ ONLY",
+		"BEGIN READ ONLY TRANSACTION",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestParseSelect_FuncCall(t *testing.T) {
+	stmt, err := Parse("SELECT coalesce(name, 'unknown'), nullif(a, b) FROM users;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+	call, ok := sel.Exprs[0].(*FuncCall)
+	if !ok || call.Name != "COALESCE" || len(call.Args) != 2 {
+		t.Fatalf("unexpected expr: %#v", sel.Exprs[0])
+	}
+	if lit, ok := call.Args[1].(*Literal); !ok || lit.Value.S != "unknown" {
+		t.Fatalf("unexpected second argument: %#v", call.Args[1])
+	}
+	if sel.Columns[0] != "coalesce(name, 'unknown')" {
+		t.Fatalf("unexpected column name %q", sel.Columns[0])
+	}
+
+	if _, err := Parse("SELECT COALESCE(a b) FROM t;"); err == nil {
+		t.Fatalf("expected error for missing comma")
+	}
+}
+
+func TestParseSelect_StarItem(t *testing.T) {
+	stmt, err := Parse("SELECT rowid, * FROM users WHERE rowid = 3;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel := stmt.(*SelectStmt)
+	if !reflect.DeepEqual(sel.Columns, []string{"rowid", "*"}) || sel.Exprs != nil {
+		t.Fatalf("unexpected select list: %q %#v", sel.Columns, sel.Exprs)
+	}
+
+	stmt, err = Parse("SELECT first || last, * FROM users;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	sel = stmt.(*SelectStmt)
+	if ref, ok := sel.Exprs[1].(*ColumnRef); !ok || ref.Name != "*" {
+		t.Fatalf("unexpected second item: %#v", sel.Exprs[1])
+	}
+
+	if _, err := Parse("SELECT * + 1 FROM users;"); err == nil {
+		t.Fatalf("expected error for * in an expression")
+	}
+}
+
+func TestParseCreateSequence(t *testing.T) {
+	for q, want := range map[string]CreateSequenceStmt{
+		"CREATE SEQUENCE ids;":                {Name: "ids", Start: 1},
+		"create sequence ids start with 100;": {Name: "ids", Start: 100},
+		"CREATE SEQUENCE ids START -5":        {Name: "ids", Start: -5},
+	} {
+		stmt, err := Parse(q)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", q, err)
+		}
+		if got := stmt.(*CreateSequenceStmt); *got != want {
+			t.Fatalf("Parse(%q) = %+v, want %+v", q, *got, want)
+		}
+	}
+	for _, q := range []string{"CREATE SEQUENCE;", "CREATE SEQUENCE a-b;", "CREATE SEQUENCE s START WITH x;", "CREATE SEQUENCE s INCREMENT 2;"} {
+		if _, err := Parse(q); err == nil {
+			t.Fatalf("Parse(%q): expected error", q)
+		}
+	}
+}
+
+func TestParseInsert_Expressions(t *testing.T) {
+	stmt, err := Parse("INSERT INTO users VALUES (NEXTVAL('ids'), 'a, b'), (7, 'x' || 'y');")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	ins := stmt.(*InsertStmt)
+	if len(ins.Rows) != 2 || len(ins.Exprs) != 2 {
+		t.Fatalf("unexpected statement: %+v", ins)
+	}
+	call, ok := ins.Exprs[0][0].(*FuncCall)
+	if !ok || call.Name != "NEXTVAL" || ins.Exprs[0][1] != nil {
+		t.Fatalf("row 1: unexpected exprs %+v", ins.Exprs[0])
+	}
+	if ins.Rows[0][1] != (Value{Type: TypeString, S: "a, b"}) || ins.Rows[1][0] != (Value{Type: TypeInt, I64: 7}) {
+		t.Fatalf("unexpected literal values: %+v", ins.Rows)
+	}
+	if _, ok := ins.Exprs[1][1].(*BinaryExpr); !ok {
+		t.Fatalf("row 2: unexpected exprs %+v", ins.Exprs[1])
+	}
+
+	// Plain literal rows carry no expressions, and column references are
+	// rejected.
+	stmt, err = Parse("INSERT INTO users VALUES (1, 'Ada');")
+	if err != nil || stmt.(*InsertStmt).Exprs != nil {
+		t.Fatalf("literal INSERT: %v, %+v", err, stmt)
+	}
+	if _, err := Parse("INSERT INTO users VALUES (id, 'Ada');"); err == nil {
+		t.Fatalf("expected error for a column reference in VALUES")
+	}
+}
+
+func TestParseCreateTableAs(t *testing.T) {
+	stmt, err := Parse("create table  active_users AS\n  SELECT id, name FROM users WHERE active = true;")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	ct, ok := stmt.(*CreateTableStmt)
+	if !ok {
+		t.Fatalf("expected *CreateTableStmt, got %T", stmt)
+	}
+	if ct.TableName != "active_users" || ct.Columns != nil || ct.AsSelect == nil {
+		t.Fatalf("unexpected statement: %+v", ct)
+	}
+	sel := ct.AsSelect
+	if sel.TableName != "users" || !reflect.DeepEqual(sel.Columns, []string{"id", "name"}) || sel.Where == nil || sel.Where.Column != "active" {
+		t.Fatalf("unexpected SELECT: %+v", sel)
+	}
+
+	for _, q := range []string{
+		"CREATE TABLE t AS;",
+		"CREATE TABLE t AS VALUES (1);",
+		"CREATE TABLE t AS SELECT FROM users;",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) should fail", q)
+		}
+	}
+}
+
+func TestParseAlterTable(t *testing.T) {
+	for _, q := range []string{
+		"ALTER TABLE users RENAME COLUMN name TO full_name;",
+		"alter table users rename name to full_name",
+	} {
+		stmt, err := Parse(q)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", q, err)
+		}
+		want := &AlterTableStmt{TableName: "users", Column: "name", NewName: "full_name"}
+		if !reflect.DeepEqual(stmt, want) {
+			t.Fatalf("Parse(%q) = %+v, want %+v", q, stmt, want)
+		}
+	}
+
+	for _, q := range []string{
+		"ALTER TABLE users RENAME COLUMN name;",
+		"ALTER TABLE users DROP COLUMN name;",
+		"ALTER users RENAME a TO b;",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) should fail", q)
+		}
+	}
+}
+
+func TestParsePragma(t *testing.T) {
+	tests := []struct {
+		q    string
+		want *PragmaStmt
+	}{
+		{"PRAGMA page_cache;", &PragmaStmt{Name: "page_cache"}},
+		{"pragma SYNCHRONOUS = off", &PragmaStmt{Name: "synchronous", Value: "off", Set: true}},
+		{"PRAGMA page_cache = 1000;", &PragmaStmt{Name: "page_cache", Value: "1000", Set: true}},
+		{"PRAGMA checkpoint_interval = 5m", &PragmaStmt{Name: "checkpoint_interval", Value: "5m", Set: true}},
+		{"PRAGMA x = -1", &PragmaStmt{Name: "x", Value: "-1", Set: true}},
+		{"PRAGMA x = 'it''s'", &PragmaStmt{Name: "x", Value: "it's", Set: true}},
+		{"PRAGMA table_info(users);", &PragmaStmt{Name: "table_info", Arg: "users"}},
+		{"PRAGMA index_list ( users )", &PragmaStmt{Name: "index_list", Arg: "users"}},
+	}
+	for _, tt := range tests {
+		stmt, err := Parse(tt.q)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.q, err)
+		}
+		if !reflect.DeepEqual(stmt, tt.want) {
+			t.Fatalf("Parse(%q) = %+v, want %+v", tt.q, stmt, tt.want)
+		}
+	}
+
+	for _, q := range []string{
+		"PRAGMA;",
+		"PRAGMA 1",
+		"PRAGMA x =",
+		"PRAGMA x = a b",
+		"PRAGMA x = - a",
+		"PRAGMA table_info(users",
+		"PRAGMA table_info(a, b)",
+		"PRAGMA table_info(users) = 1",
+		"PRAGMA x y",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) should fail", q)
+		}
+	}
+}
@@ -1,15 +1,33 @@
 package sql
 
 import (
+	"encoding/hex"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
 // splitCommaSeparated splits a string by commas, but keeps it simple:
-// it's fine for "id INT, name STRING, active BOOL".
+// it's fine for "id INT, name STRING, active BOOL". Commas inside
+// single-quoted string literals are not treated as separators.
 func splitCommaSeparated(s string) []string {
-	parts := strings.Split(s, ",")
+	var parts []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			// An escaped quote ('') toggles twice, so it stays inside the literal.
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+
 	out := make([]string, 0, len(parts))
 	for _, p := range parts {
 		p = strings.TrimSpace(p)
@@ -22,10 +40,12 @@ func splitCommaSeparated(s string) []string {
 
 // parseLiteral parses a single literal token into a Value.
 // Supports:
-//   - integers:  1, 42
+//   - integers:  1, 42, 0xFF (hexadecimal with a 0x or 0X prefix)
 //   - floats:    3.14, 1e3
-//   - strings:   'Alice'  (single quotes)
+//   - numbers may use underscores between digits: 1_000_000, 1_000.5
+//   - strings:   'Alice'  (single quotes; a doubled quote escapes one)
 //   - booleans:  true / false (case-insensitive)
+//   - blobs:     x'48656C6C6F' (an even number of hex digits; X also works)
 func parseLiteral(tok string) (Value, error) {
 	s := strings.TrimSpace(tok)
 	if s == "" {
@@ -46,11 +66,45 @@ func parseLiteral(tok string) (Value, error) {
 		return Value{Type: TypeNull}, nil
 	}
 
+	// BLOB literal: x'...' with hex digits
+	if len(s) >= 3 && (s[0] == 'x' || s[0] == 'X') && s[1] == '\'' && s[len(s)-1] == '\'' {
+		b, err := hex.DecodeString(s[2 : len(s)-1])
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid BLOB literal %q", tok)
+		}
+		return Value{Type: TypeBytes, S: string(b)}, nil
+	}
+
 	// String literal with single quotes
 	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
-		inner := s[1 : len(s)-1]
-		// TODO: handle escaped quotes like '' inside string
-		return Value{Type: TypeString, S: inner}, nil
+		raw := s[1 : len(s)-1]
+		// Quotes inside must be doubled; 'a' || 'b' is not one literal.
+		if strings.Contains(strings.ReplaceAll(raw, "''", ""), "'") {
+			return Value{}, fmt.Errorf("cannot parse literal %q: unescaped quote", tok)
+		}
+		return Value{Type: TypeString, S: strings.ReplaceAll(raw, "''", "'")}, nil
+	}
+
+	// A sign may be separated from its number by spaces: - 10.
+	if len(s) > 1 && (s[0] == '-' || s[0] == '+') {
+		s = s[:1] + strings.TrimLeft(s[1:], " \t")
+	}
+
+	if strings.Contains(s, "_") {
+		stripped, err := stripDigitSeparators(s)
+		if err != nil {
+			return Value{}, fmt.Errorf("cannot parse literal %q: %w", tok, err)
+		}
+		s = stripped
+	}
+
+	// Hexadecimal integer
+	if isHexLiteral(s) {
+		i, err := strconv.ParseInt(s, 0, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("invalid hexadecimal literal %q", tok)
+		}
+		return Value{Type: TypeInt, I64: i}, nil
 	}
 
 	// Try integer
@@ -65,3 +119,38 @@ func parseLiteral(tok string) (Value, error) {
 
 	return Value{}, fmt.Errorf("cannot parse literal %q", tok)
 }
+
+// isHexLiteral reports whether s is an optionally signed number with a 0x or
+// 0X prefix. Other base prefixes are not recognized, so a leading zero never
+// makes a decimal literal octal.
+func isHexLiteral(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	return len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
+
+// stripDigitSeparators removes the underscores of a numeric literal. Each
+// underscore must sit between two digits (hex digits after a 0x prefix), so
+// leading, trailing and doubled underscores are rejected.
+func stripDigitSeparators(s string) (string, error) {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	if isHexLiteral(s) {
+		isDigit = func(c byte) bool {
+			return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i == 0 || i == len(s)-1 || !isDigit(s[i-1]) || !isDigit(s[i+1]) {
+			return "", fmt.Errorf("underscore must separate digits")
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,46 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimestampLayout is the text form of TIMESTAMP values. Fractional seconds
+// are printed only when present.
+const TimestampLayout = "2006-01-02 15:04:05.999999"
+
+// timestampInputLayouts are the layouts accepted by ParseTimestamp.
+var timestampInputLayouts = []string{
+	TimestampLayout,
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02",
+}
+
+// TimestampValue returns a TIMESTAMP value for t. Timestamps are stored as
+// microseconds since the Unix epoch, in UTC.
+func TimestampValue(t time.Time) Value {
+	return Value{Type: TypeTimestamp, I64: t.UnixMicro()}
+}
+
+// Time returns the time of a TIMESTAMP value, in UTC.
+func (v Value) Time() time.Time {
+	return time.UnixMicro(v.I64).UTC()
+}
+
+// FormatTimestamp returns the text form of a TIMESTAMP value.
+func FormatTimestamp(v Value) string {
+	return v.Time().Format(TimestampLayout)
+}
+
+// ParseTimestamp parses "2006-01-02 15:04:05[.ffffff]", an RFC 3339 time or a
+// bare date. Times without a zone are taken as UTC.
+func ParseTimestamp(s string) (Value, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timestampInputLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return TimestampValue(t), nil
+		}
+	}
+	return Value{}, fmt.Errorf("invalid TIMESTAMP %q", s)
+}
@@ -1,5 +1,11 @@
 package sql
 
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
 // DataType represents the logical type of a value in a column.
 type DataType int
 
@@ -9,16 +15,64 @@ const (
 	TypeString
 	TypeBool
 	TypeNull // represents a NULL/DEFAULT literal
+	// TypeTimestamp is a point in time, stored in I64 as microseconds since
+	// the Unix epoch (UTC).
+	TypeTimestamp
+	// TypeBytes is raw binary data (BLOB). The bytes are kept in S, which
+	// keeps Value comparable; they are not necessarily valid UTF-8.
+	TypeBytes
 )
 
+func (t DataType) String() string {
+	switch t {
+	case TypeInt:
+		return "INT"
+	case TypeFloat:
+		return "FLOAT"
+	case TypeString:
+		return "STRING"
+	case TypeBool:
+		return "BOOL"
+	case TypeNull:
+		return "NULL"
+	case TypeTimestamp:
+		return "TIMESTAMP"
+	case TypeBytes:
+		return "BLOB"
+	default:
+		return fmt.Sprintf("DataType(%d)", int(t))
+	}
+}
+
+// ParseDataType maps a type name as written in SQL (INT, INTEGER, TEXT, ...)
+// to its DataType. Names are case-insensitive.
+func ParseDataType(name string) (DataType, bool) {
+	switch strings.ToUpper(name) {
+	case "INT", "INTEGER":
+		return TypeInt, true
+	case "FLOAT", "DOUBLE", "REAL":
+		return TypeFloat, true
+	case "STRING", "TEXT", "VARCHAR":
+		return TypeString, true
+	case "BOOL", "BOOLEAN":
+		return TypeBool, true
+	case "TIMESTAMP", "DATETIME":
+		return TypeTimestamp, true
+	case "BLOB", "BYTES", "BYTEA":
+		return TypeBytes, true
+	default:
+		return 0, false
+	}
+}
+
 // Value represents a single cell in a table (one column in one row).
 // Only the field matching Type is meaningful.
 type Value struct {
 	Type DataType
 
-	I64 int64   // for TypeInt
+	I64 int64   // for TypeInt and TypeTimestamp
 	F64 float64 // for TypeFloat
-	S   string  // for TypeString
+	S   string  // for TypeString and TypeBytes
 	B   bool    // for TypeBool
 }
 
@@ -29,4 +83,33 @@ type Row []Value
 type Column struct {
 	Name string
 	Type DataType
+
+	// Default is the value an INSERT uses when it omits the column; nil
+	// means NULL.
+	Default *Value
+	// DefaultCurrentTimestamp marks DEFAULT CURRENT_TIMESTAMP: the default
+	// is the time of the INSERT, resolved by the engine.
+	DefaultCurrentTimestamp bool
+	// References is the FOREIGN KEY target of the column, or nil.
+	References *ForeignKey
+	// NoCase marks a STRING column declared COLLATE NOCASE: WHERE and
+	// ORDER BY compare its values ignoring case.
+	NoCase bool
+	// NotNull marks a column declared NOT NULL: writing NULL to it fails
+	// with ErrNotNull.
+	NotNull bool
+}
+
+// ForeignKey names the parent column a REFERENCES column points to. Every
+// non-NULL value in the referencing column must exist in the parent column,
+// and parent rows cannot be deleted or re-keyed while they are referenced.
+type ForeignKey struct {
+	Table  string
+	Column string
+}
+
+// FormatBlob returns the literal form of a BLOB value, e.g. x'CAFE', which
+// parses back to the same value.
+func FormatBlob(v Value) string {
+	return "x'" + strings.ToUpper(hex.EncodeToString([]byte(v.S))) + "'"
 }
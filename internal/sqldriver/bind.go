@@ -0,0 +1,101 @@
+package sqldriver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"goDB/internal/sql"
+)
+
+// countPlaceholders returns the number of "?" placeholders in query that are
+// outside string literals.
+func countPlaceholders(query string) int {
+	n := 0
+	inQuote := false
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '\'':
+			inQuote = !inQuote
+		case '?':
+			if !inQuote {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// bindPlaceholders replaces each "?" outside string literals with the SQL
+// literal for the matching argument.
+func bindPlaceholders(query string, args []driver.Value) (string, error) {
+	if len(args) == 0 {
+		return query, nil
+	}
+
+	var b strings.Builder
+	inQuote := false
+	next := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			inQuote = !inQuote
+		}
+		if c != '?' || inQuote {
+			b.WriteByte(c)
+			continue
+		}
+		if next >= len(args) {
+			return "", fmt.Errorf("sqldriver: not enough arguments for placeholders")
+		}
+		lit, err := literal(args[next])
+		if err != nil {
+			return "", fmt.Errorf("sqldriver: argument %d: %w", next+1, err)
+		}
+		b.WriteString(lit)
+		next++
+	}
+	if next != len(args) {
+		return "", fmt.Errorf("sqldriver: got %d arguments for %d placeholders", len(args), next)
+	}
+	return b.String(), nil
+}
+
+// literal renders a driver.Value as a SQL literal the parser reads back as
+// the same value.
+func literal(v driver.Value) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "NULL", nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return "", fmt.Errorf("cannot bind non-finite float %v", x)
+		}
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case string:
+		return quote(x), nil
+	case []byte:
+		return sql.FormatBlob(sql.Value{Type: sql.TypeBytes, S: string(x)}), nil
+	case time.Time:
+		// Read back as a TIMESTAMP when bound to a TIMESTAMP column.
+		return quote(x.UTC().Format(sql.TimestampLayout)), nil
+	default:
+		return "", fmt.Errorf("unsupported argument type %T", v)
+	}
+}
+
+// quote single-quotes s, doubling embedded quotes.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
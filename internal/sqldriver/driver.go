@@ -0,0 +1,270 @@
+// Package sqldriver registers GoDB with database/sql under the name "godb".
+//
+//	db, err := sql.Open("godb", "./data")
+//
+// The data source name is the filestore directory. All connections opened on
+// the same directory share one storage engine, while each connection keeps
+// its own transaction state. Statements from different connections are
+// serialized, because the storage engines do not support concurrent writers.
+//
+// The engine has no server-side parameter binding, so "?" placeholders are
+// replaced with SQL literals of the bound arguments before parsing.
+package sqldriver
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"goDB/internal/engine"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+	"goDB/internal/storage/filestore"
+)
+
+func init() {
+	dbsql.Register("godb", &Driver{})
+}
+
+// Driver implements driver.Driver.
+type Driver struct{}
+
+// sharedStore is a storage engine shared by every connection on one data
+// directory, together with the lock that serializes their statements.
+type sharedStore struct {
+	mu    sync.Mutex
+	store storage.Engine
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*sharedStore) // absolute data dir -> store
+)
+
+// openStore returns the shared store for dir, opening it on first use.
+func openStore(dir string) (*sharedStore, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("sqldriver: resolve data dir: %w", err)
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[abs]; ok {
+		return s, nil
+	}
+	fs, err := filestore.New(abs)
+	if err != nil {
+		return nil, fmt.Errorf("sqldriver: open filestore: %w", err)
+	}
+	s := &sharedStore{store: fs}
+	stores[abs] = s
+	return s, nil
+}
+
+// Open returns a new connection to the database stored in dataDir.
+func (d *Driver) Open(dataDir string) (driver.Conn, error) {
+	if dataDir == "" {
+		return nil, fmt.Errorf("sqldriver: data directory must not be empty")
+	}
+
+	shared, err := openStore(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	eng := engine.New(shared.store)
+	if err := eng.Start(); err != nil {
+		return nil, fmt.Errorf("sqldriver: start engine: %w", err)
+	}
+	return &conn{shared: shared, eng: eng}, nil
+}
+
+// conn implements driver.Conn on top of a connection-private DBEngine.
+type conn struct {
+	shared *sharedStore
+	eng    *engine.DBEngine
+	inTx   bool
+}
+
+// exec runs a statement while holding the shared store lock.
+func (c *conn) exec(stmt sql.Statement) (*engine.Result, error) {
+	c.shared.mu.Lock()
+	defer c.shared.mu.Unlock()
+	return c.eng.Exec(stmt)
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query, numInput: countPlaceholders(query)}, nil
+}
+
+// Close rolls back a transaction left open on the connection. The shared
+// store stays open for other connections.
+func (c *conn) Close() error {
+	if c.inTx {
+		c.inTx = false
+		if _, err := c.exec(&sql.RollbackTxStmt{}); err != nil {
+			return fmt.Errorf("sqldriver: rollback on close: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.begin(sql.TxDeferred)
+}
+
+// BeginTx implements driver.ConnBeginTx. TxOptions.ReadOnly starts a READ
+// ONLY transaction; only the default isolation level is supported.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if dbsql.IsolationLevel(opts.Isolation) != dbsql.LevelDefault {
+		return nil, fmt.Errorf("sqldriver: isolation level %v is not supported", dbsql.IsolationLevel(opts.Isolation))
+	}
+	if opts.ReadOnly {
+		return c.begin(sql.TxReadOnly)
+	}
+	return c.begin(sql.TxDeferred)
+}
+
+func (c *conn) begin(mode sql.TxMode) (driver.Tx, error) {
+	if _, err := c.exec(&sql.BeginTxStmt{Mode: mode}); err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &tx{conn: c}, nil
+}
+
+// tx implements driver.Tx.
+type tx struct {
+	conn *conn
+}
+
+func (t *tx) Commit() error {
+	t.conn.inTx = false
+	_, err := t.conn.exec(&sql.CommitTxStmt{})
+	return err
+}
+
+func (t *tx) Rollback() error {
+	t.conn.inTx = false
+	_, err := t.conn.exec(&sql.RollbackTxStmt{})
+	return err
+}
+
+// stmt implements driver.Stmt. The query is parsed on every execution,
+// after its placeholders have been bound; sql.ParseCached skips the work
+// when the bound text repeats.
+type stmt struct {
+	conn     *conn
+	query    string
+	numInput int
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return s.numInput }
+
+// run binds args, parses the query and executes it.
+func (s *stmt) run(args []driver.Value) (*engine.Result, error) {
+	text, err := bindPlaceholders(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := sql.ParseCached(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	return s.conn.exec(parsed)
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	res, err := s.run(args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	res, err := s.run(args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{cols: res.Columns, types: res.ColumnTypes, rows: res.Rows}, nil
+}
+
+// rows implements driver.Rows over a fully materialized result set. It also
+// reports the column types, for sql.Rows.ColumnTypes.
+type rows struct {
+	cols  []string
+	types []engine.ColumnType
+	rows  []sql.Row
+	pos   int
+}
+
+func (r *rows) Columns() []string { return r.cols }
+func (r *rows) Close() error      { return nil }
+
+// ColumnTypeDatabaseTypeName returns the GoDB type name of a column, such
+// as "INT" or "STRING", or "NULL" for a column of NULL literals.
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index].Type.String()
+}
+
+// ColumnTypeScanType returns the Go type Next stores for the values of a
+// column.
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	switch r.types[index].Type {
+	case sql.TypeInt:
+		return reflect.TypeFor[int64]()
+	case sql.TypeFloat:
+		return reflect.TypeFor[float64]()
+	case sql.TypeString:
+		return reflect.TypeFor[string]()
+	case sql.TypeBool:
+		return reflect.TypeFor[bool]()
+	case sql.TypeTimestamp:
+		return reflect.TypeFor[time.Time]()
+	case sql.TypeBytes:
+		return reflect.TypeFor[[]byte]()
+	default:
+		return reflect.TypeFor[any]()
+	}
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	for i, v := range r.rows[r.pos] {
+		dest[i] = driverValue(v)
+	}
+	r.pos++
+	return nil
+}
+
+// driverValue maps a sql.Value to the matching driver.Value type.
+func driverValue(v sql.Value) driver.Value {
+	switch v.Type {
+	case sql.TypeInt:
+		return v.I64
+	case sql.TypeFloat:
+		return v.F64
+	case sql.TypeString:
+		return v.S
+	case sql.TypeBool:
+		return v.B
+	case sql.TypeTimestamp:
+		return v.Time()
+	case sql.TypeBytes:
+		return []byte(v.S)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,241 @@
+package sqldriver
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func openTestDB(t *testing.T) *dbsql.DB {
+	t.Helper()
+
+	db, err := dbsql.Open("godb", t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDriver_ExecAndQuery(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.Exec("CREATE TABLE users (id INT, name STRING, score FLOAT, active BOOL)"); err != nil {
+		t.Fatalf("CREATE: %v", err)
+	}
+	for _, args := range [][]any{
+		{1, "Alice", 1.5, true},
+		{2, "O'Brien", 2.0, false},
+		{3, nil, 3.25, true},
+	} {
+		res, err := db.Exec("INSERT INTO users VALUES (?, ?, ?, ?)", args...)
+		if err != nil {
+			t.Fatalf("INSERT %v: %v", args, err)
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			t.Fatalf("INSERT RowsAffected = %d, want 1", n)
+		}
+	}
+
+	res, err := db.Exec("UPDATE users SET active = ? WHERE score >= ?", false, 2.0)
+	if err != nil {
+		t.Fatalf("UPDATE: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Fatalf("UPDATE RowsAffected = %d, want 2", n)
+	}
+
+	var name string
+	if err := db.QueryRow("SELECT name FROM users WHERE id = ?", 2).Scan(&name); err != nil {
+		t.Fatalf("QueryRow: %v", err)
+	}
+	if name != "O'Brien" {
+		t.Fatalf("name = %q, want %q", name, "O'Brien")
+	}
+
+	rows, err := db.Query("SELECT id, name, active FROM users ORDER BY id")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+
+	type user struct {
+		id     int64
+		name   dbsql.NullString
+		active bool
+	}
+	var got []user
+	for rows.Next() {
+		var u user
+		if err := rows.Scan(&u.id, &u.name, &u.active); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		got = append(got, u)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %v", err)
+	}
+	want := []user{
+		{1, dbsql.NullString{String: "Alice", Valid: true}, true},
+		{2, dbsql.NullString{String: "O'Brien", Valid: true}, false},
+		{3, dbsql.NullString{}, false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("row %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	res, err = db.Exec("DELETE FROM users WHERE id != ?", 1)
+	if err != nil {
+		t.Fatalf("DELETE: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Fatalf("DELETE RowsAffected = %d, want 2", n)
+	}
+}
+
+func TestDriver_ColumnTypes(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.Exec("CREATE TABLE users (id INT, name STRING, score FLOAT)"); err != nil {
+		t.Fatalf("CREATE: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO users VALUES (1, 'Ada', 1.5)"); err != nil {
+		t.Fatalf("INSERT: %v", err)
+	}
+
+	for query, want := range map[string][]string{
+		"SELECT * FROM users": {"INT int64", "STRING string", "FLOAT float64"},
+		"SELECT id, UPPER(name), LENGTH(name), CAST(id AS FLOAT), NULL FROM users": {
+			"INT int64", "STRING string", "INT int64", "FLOAT float64", "NULL interface {}",
+		},
+		"SELECT COUNT(*), AVG(id), SUM(id), MAX(name) FROM users": {
+			"INT int64", "FLOAT float64", "INT int64", "STRING string",
+		},
+	} {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		types, err := rows.ColumnTypes()
+		rows.Close()
+		if err != nil {
+			t.Fatalf("%s: ColumnTypes: %v", query, err)
+		}
+		var got []string
+		for _, ct := range types {
+			got = append(got, ct.DatabaseTypeName()+" "+ct.ScanType().String())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: column types = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestDriver_BindBlob(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.Exec("CREATE TABLE files (id INT, data BLOB)"); err != nil {
+		t.Fatalf("CREATE: %v", err)
+	}
+	want := []byte{0x00, 0xCA, 0xFE, '\'', 0xFF}
+	if _, err := db.Exec("INSERT INTO files VALUES (?, ?)", 1, want); err != nil {
+		t.Fatalf("INSERT: %v", err)
+	}
+
+	var id int64
+	if err := db.QueryRow("SELECT id FROM files WHERE data = ?", want).Scan(&id); err != nil {
+		t.Fatalf("QueryRow by blob: %v", err)
+	}
+	var got []byte
+	if err := db.QueryRow("SELECT data FROM files WHERE id = ?", id).Scan(&got); err != nil {
+		t.Fatalf("QueryRow: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("data = %x, want %x", got, want)
+	}
+}
+
+func TestDriver_Tx(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.Exec("CREATE TABLE t (id INT)"); err != nil {
+		t.Fatalf("CREATE: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO t VALUES (?)", 1); err != nil {
+		t.Fatalf("tx INSERT: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	var id int
+	if err := db.QueryRow("SELECT id FROM t").Scan(&id); err != nil {
+		t.Fatalf("QueryRow: %v", err)
+	}
+	if id != 1 {
+		t.Fatalf("id = %d, want 1", id)
+	}
+
+	ro, err := db.BeginTx(context.Background(), &dbsql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("BeginTx(ReadOnly): %v", err)
+	}
+	if _, err := ro.Exec("INSERT INTO t VALUES (2)"); err == nil {
+		t.Fatalf("expected INSERT in a read-only transaction to fail")
+	}
+	if err := ro.QueryRow("SELECT id FROM t").Scan(&id); err != nil || id != 1 {
+		t.Fatalf("read-only QueryRow: id %d, err %v", id, err)
+	}
+	if err := ro.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if _, err := db.BeginTx(context.Background(), &dbsql.TxOptions{Isolation: dbsql.LevelSerializable}); err == nil {
+		t.Fatalf("expected an unsupported isolation level to fail")
+	}
+}
+
+func TestBindPlaceholders(t *testing.T) {
+	got, err := bindPlaceholders("SELECT * FROM t WHERE s = '?' AND id = ?", []driver.Value{int64(7)})
+	if err != nil {
+		t.Fatalf("bindPlaceholders: %v", err)
+	}
+	if want := "SELECT * FROM t WHERE s = '?' AND id = 7"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	got, err = bindPlaceholders("INSERT INTO t VALUES (?, ?, ?)", []driver.Value{nil, 2.0, "it's"})
+	if err != nil {
+		t.Fatalf("bindPlaceholders: %v", err)
+	}
+	if want := "INSERT INTO t VALUES (NULL, 2.0, 'it''s')"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	got, err = bindPlaceholders("INSERT INTO t VALUES (?)", []driver.Value{[]byte{0xCA, 0xFE}})
+	if err != nil {
+		t.Fatalf("bindPlaceholders: %v", err)
+	}
+	if want := "INSERT INTO t VALUES (x'CAFE')"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if n := countPlaceholders("INSERT INTO t VALUES (?, '?', ?)"); n != 2 {
+		t.Fatalf("countPlaceholders = %d, want 2", n)
+	}
+
+	if _, err := bindPlaceholders("INSERT INTO t VALUES (?, ?)", []driver.Value{int64(1)}); err == nil {
+		t.Fatalf("expected error for missing argument")
+	}
+}
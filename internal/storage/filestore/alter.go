@@ -0,0 +1,138 @@
+package filestore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// RenameColumn implements storage.ColumnRenamer. It rewrites the header of
+// the table, and of every table with a foreign key on the column, and
+// renames the column's index file. Rows and the WAL do not name columns, so
+// nothing else changes. The files are replaced one at a time; a crash in
+// between can leave the index under the old name, which then fails to open
+// against the table.
+func (e *FileEngine) RenameColumn(tableName, oldName, newName string) error {
+	if e.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	cols, err := e.TableSchema(tableName)
+	if err != nil {
+		return err
+	}
+	if err := checkName("column", newName); err != nil {
+		return err
+	}
+	colIdx := -1
+	for i, c := range cols {
+		if strings.EqualFold(c.Name, newName) && !strings.EqualFold(c.Name, oldName) {
+			return fmt.Errorf("filestore: column %q already exists in table %q", newName, tableName)
+		}
+		if strings.EqualFold(c.Name, oldName) {
+			colIdx = i
+		}
+	}
+	if colIdx == -1 {
+		return fmt.Errorf("filestore: %w %q in table %q", sql.ErrColumnNotFound, oldName, tableName)
+	}
+
+	tables, err := e.ListTables()
+	if err != nil {
+		return err
+	}
+	schemas := map[string][]sql.Column{tableName: cols}
+	cols[colIdx].Name = newName
+	for _, t := range tables {
+		tcols, ok := schemas[t]
+		if !ok {
+			if tcols, err = e.TableSchema(t); err != nil {
+				return err
+			}
+		}
+		changed := t == tableName
+		for i, c := range tcols {
+			if ref := c.References; ref != nil && strings.EqualFold(ref.Table, tableName) && strings.EqualFold(ref.Column, oldName) {
+				tcols[i].References = &sql.ForeignKey{Table: ref.Table, Column: newName}
+				changed = true
+			}
+		}
+		if changed {
+			schemas[t] = tcols
+		}
+	}
+
+	for t, tcols := range schemas {
+		if err := e.rewriteHeader(t, tcols); err != nil {
+			return err
+		}
+	}
+
+	e.idxMu.Lock()
+	for col, info := range e.indexes[tableName] {
+		if !strings.EqualFold(col, oldName) {
+			continue
+		}
+		if err := e.indexMgr.RenameIndex(tableName, col, newName); err != nil {
+			e.idxMu.Unlock()
+			return fmt.Errorf("filestore: rename index %q: %w", info.name, err)
+		}
+		delete(e.indexes[tableName], col)
+		info.columnName = newName
+		e.indexes[tableName][newName] = info
+		break
+	}
+	e.idxMu.Unlock()
+
+	return syncDir(e.dir)
+}
+
+// rewriteHeader replaces the schema header of a table file and keeps its
+// pages. The header may change size, so the file is copied to a temporary
+// file that is then renamed over it. The caller holds writeMu exclusively.
+func (e *FileEngine) rewriteHeader(table string, cols []sql.Column) error {
+	path, err := e.tablePath(table)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("filestore: open table to rewrite header: %w", err)
+	}
+	defer in.Close()
+	if _, err := readHeader(in); err != nil {
+		return fmt.Errorf("filestore: read header of %q: %w", table, err)
+	}
+
+	tmp := path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("filestore: rewrite header of %q: %w", table, err)
+	}
+	err = writeHeader(out, cols)
+	if err == nil {
+		_, err = io.Copy(out, in)
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("filestore: rewrite header of %q: %w", table, err)
+	}
+
+	// Cached pages remember their offset in the old file.
+	e.invalidatePages(table)
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("filestore: rewrite header of %q: %w", table, err)
+	}
+	return nil
+}
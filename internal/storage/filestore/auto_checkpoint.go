@@ -0,0 +1,66 @@
+package filestore
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// checkpointer runs Options.CheckpointInterval checkpoints on a background
+// goroutine.
+type checkpointer struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (e *FileEngine) startCheckpointer(interval time.Duration) *checkpointer {
+	c := &checkpointer{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(c.done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-t.C:
+			}
+			if err := e.autoCheckpoint(); err != nil {
+				log.Printf("filestore: auto checkpoint: %v", err)
+			}
+		}
+	}()
+	return c
+}
+
+// close stops the goroutine, waiting for a checkpoint in progress.
+func (c *checkpointer) close() {
+	close(c.stop)
+	<-c.done
+}
+
+// autoCheckpoint checkpoints when tables have changed since the last
+// checkpoint. While write transactions are open it does nothing; the next
+// tick tries again.
+func (e *FileEngine) autoCheckpoint() error {
+	if !e.needsCheckpoint() {
+		return nil
+	}
+	err := e.Checkpoint()
+	if errors.Is(err, errCheckpointBusy) {
+		return nil
+	}
+	return err
+}
+
+// needsCheckpoint reports whether a checkpoint has work to do and no write
+// transaction would make it fail. It lets autoCheckpoint skip taking
+// writeMu exclusively, which stalls writers, when it would be for nothing.
+func (e *FileEngine) needsCheckpoint() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.activeWriters == 0 && (len(e.dirty) > 0 || len(e.rolledBack) > 0)
+}
@@ -0,0 +1,157 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Backup writes a consistent copy of the database to destDir, which must not
+// exist yet or be empty. It is safe to call while other transactions are
+// running.
+//
+// Table and index writers are paused for the duration of the copy, and the
+// WAL is copied under its own lock, so it ends on a record boundary. Opening
+// the copy with New replays that WAL, which restores every transaction that
+// had committed when the WAL was copied (the first step after pausing
+// writers); transactions still in flight have no COMMIT record in the copy
+// and are discarded.
+func (e *FileEngine) Backup(destDir string) error {
+	if e.opts.ReadOnly {
+		// Without the WAL lock there is no consistent copy to take.
+		return ErrReadOnly
+	}
+	src, err := filepath.Abs(e.dir)
+	if err != nil {
+		return fmt.Errorf("filestore: backup: %w", err)
+	}
+	dst, err := filepath.Abs(destDir)
+	if err != nil {
+		return fmt.Errorf("filestore: backup: %w", err)
+	}
+	if src == dst {
+		return fmt.Errorf("filestore: backup: destination is the data directory")
+	}
+
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("filestore: backup: create dir: %w", err)
+	}
+	existing, err := os.ReadDir(dst)
+	if err != nil {
+		return fmt.Errorf("filestore: backup: read dir: %w", err)
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("filestore: backup: destination %q is not empty", destDir)
+	}
+
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	if err := e.wal.copyTo(dst); err != nil {
+		return fmt.Errorf("filestore: backup WAL: %w", err)
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("filestore: backup: read data dir: %w", err)
+	}
+	for _, ent := range entries {
+		name := ent.Name()
+		if !isBackupFile(name) {
+			continue
+		}
+		if err := copyFile(filepath.Join(src, name), filepath.Join(dst, name), -1); err != nil {
+			return fmt.Errorf("filestore: backup %s: %w", name, err)
+		}
+	}
+
+	return syncDir(dst)
+}
+
+// isBackupFile reports whether the data directory entry name belongs in a
+// backup: tables, indexes, sequences, table snapshots and the checkpoint.
+func isBackupFile(name string) bool {
+	if name == checkpointFile {
+		return true
+	}
+	for _, ext := range []string{".godb", ".idx", ".seq", ".ckpt"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyTo copies the WAL segments as written so far to dir. No records can
+// be appended while the copy is in progress.
+func (w *walLogger) copyTo(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return fmt.Errorf("wal: closed")
+	}
+
+	size, err := w.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	segs, err := walSegments(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, n := range segs {
+		limit := int64(-1)
+		if n == w.seg {
+			limit = size
+		}
+		if err := copyFile(walSegmentPath(w.dir, n), walSegmentPath(dir, n), limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the first n bytes of src (all of it when n < 0) to a new
+// file dst and fsyncs it.
+func copyFile(src, dst string, n int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = in
+	if n >= 0 {
+		r = io.LimitReader(in, n)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir fsyncs a directory so newly created entries are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
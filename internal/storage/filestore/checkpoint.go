@@ -0,0 +1,310 @@
+package filestore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"goDB/internal/sql"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// A checkpoint bounds how much of the WAL recovery has to replay. It runs
+// while no write transaction is open, and:
+//
+//  1. rewrites the tables that rolled-back transactions touched, dropping
+//     their changes;
+//  2. copies every table changed since the previous checkpoint, and every
+//     table that has no copy yet, to <table>.ckpt, tagged with the LSN of
+//     the last WAL record it reflects;
+//  3. stores that LSN in the checkpoint file;
+//  4. starts a new WAL segment and deletes the older ones, unless a change
+//     feed is reading them.
+//
+// Recovery skips WAL records at or below the checkpoint LSN and rebuilds
+// only the tables named by newer records, starting from their snapshot. It
+// ends with a checkpoint of its own, so running it again finds nothing to
+// redo.
+
+const (
+	snapshotMagic  = "GODBCKP1"
+	checkpointFile = "checkpoint"
+)
+
+// errCheckpointBusy is returned by Checkpoint while write transactions are
+// open, because their changes are already in the table files.
+var errCheckpointBusy = errors.New("filestore: checkpoint: write transactions are open")
+
+func (e *FileEngine) snapshotPath(table string) string {
+	return filepath.Join(e.dir, table+".ckpt")
+}
+
+// Checkpoint snapshots the changed tables and records the last WAL LSN as
+// the point recovery starts from. It fails while write transactions are
+// open.
+func (e *FileEngine) Checkpoint() error {
+	if e.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.checkpointLocked(); err != nil {
+		if errors.Is(err, errCheckpointBusy) {
+			return err
+		}
+		return fmt.Errorf("filestore: checkpoint: %w", err)
+	}
+	return nil
+}
+
+// checkpointLocked performs a checkpoint. The caller holds writeMu
+// exclusively and e.mu.
+func (e *FileEngine) checkpointLocked() error {
+	if e.activeWriters > 0 {
+		return errCheckpointBusy
+	}
+
+	tables, err := e.ListTables()
+	if err != nil {
+		return err
+	}
+
+	if len(e.rolledBack) > 0 {
+		if err := e.discardRolledBack(tables); err != nil {
+			return err
+		}
+	}
+
+	lsn := e.wal.lastLSN()
+	for _, t := range tables {
+		_, dirty := e.dirty[t]
+		_, rolled := e.rolledBack[t]
+		if !dirty && !rolled {
+			if _, err := os.Stat(e.snapshotPath(t)); err == nil {
+				continue
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("stat snapshot of %q: %w", t, err)
+			}
+		}
+		if err := e.writeSnapshot(t, lsn); err != nil {
+			return fmt.Errorf("snapshot %q: %w", t, err)
+		}
+	}
+	if err := writeCheckpointLSN(e.dir, lsn); err != nil {
+		return err
+	}
+	e.checkpointLSN = lsn
+	e.dirty = make(map[string]struct{})
+	e.rolledBack = make(map[string]struct{})
+
+	// Feeds read the WAL segment by segment, so old segments are only
+	// deleted while nobody is reading them.
+	e.subsMu.Lock()
+	defer e.subsMu.Unlock()
+	if len(e.subs) > 0 {
+		return nil
+	}
+	size, err := e.wal.dropSegments()
+	if err != nil {
+		return err
+	}
+	e.syncMu.RLock()
+	if e.flusher != nil {
+		e.flusher.reset(size)
+	}
+	e.syncMu.RUnlock()
+	return nil
+}
+
+// discardRolledBack rewrites the tables written by rolled-back transactions
+// from their snapshot and the committed changes logged since.
+func (e *FileEngine) discardRolledBack(tables []string) error {
+	schemas, err := e.tableSchemas(tables)
+	if err != nil {
+		return err
+	}
+	snaps, err := e.snapshotLSNs(tables)
+	if err != nil {
+		return err
+	}
+	replay, err := e.readWAL(e.checkpointLSN, snaps, schemas)
+	if err != nil {
+		return err
+	}
+	return e.rebuildTables(e.rolledBack, replay)
+}
+
+// writeSnapshot copies the table file to its snapshot, tagged with lsn.
+func (e *FileEngine) writeSnapshot(table string, lsn uint64) error {
+	path := e.snapshotPath(table)
+	tmp := path + ".tmp"
+
+	src, err := e.tablePath(table)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	var hdr [len(snapshotMagic) + 8]byte
+	copy(hdr[:], snapshotMagic)
+	binary.LittleEndian.PutUint64(hdr[len(snapshotMagic):], lsn)
+	if _, err := out.Write(hdr[:]); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// openSnapshot opens the snapshot of table and returns its LSN, leaving f
+// positioned at the start of the copied table file. ok is false when the
+// table has no snapshot.
+func (e *FileEngine) openSnapshot(table string) (f *os.File, lsn uint64, ok bool, err error) {
+	f, err = os.Open(e.snapshotPath(table))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, false, nil
+	}
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	var hdr [len(snapshotMagic) + 8]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil {
+		f.Close()
+		return nil, 0, false, fmt.Errorf("read snapshot of %q: %w", table, err)
+	}
+	if string(hdr[:len(snapshotMagic)]) != snapshotMagic {
+		f.Close()
+		return nil, 0, false, fmt.Errorf("snapshot of %q: bad magic", table)
+	}
+	return f, binary.LittleEndian.Uint64(hdr[len(snapshotMagic):]), true, nil
+}
+
+// snapshotLSNs returns the snapshot LSN of each table that has a snapshot.
+func (e *FileEngine) snapshotLSNs(tables []string) (map[string]uint64, error) {
+	lsns := make(map[string]uint64)
+	for _, t := range tables {
+		f, lsn, ok, err := e.openSnapshot(t)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			f.Close()
+			lsns[t] = lsn
+		}
+	}
+	return lsns, nil
+}
+
+// snapshotRows returns the rows stored in the snapshot of table, or nil when
+// it has none.
+func (e *FileEngine) snapshotRows(table string) ([]sql.Row, error) {
+	f, _, ok, err := e.openSnapshot(table)
+	if err != nil || !ok {
+		return nil, err
+	}
+	defer f.Close()
+
+	cols, err := readHeader(f)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot of %q: %w", table, err)
+	}
+	headerEnd, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	dataBytes := fi.Size() - headerEnd
+	if dataBytes%PageSize != 0 {
+		return nil, fmt.Errorf("snapshot of %q: corrupt data (not multiple of page size)", table)
+	}
+
+	var rows []sql.Row
+	p := make(pageBuf, PageSize)
+	for off := headerEnd; off < fi.Size(); off += PageSize {
+		if _, err := f.ReadAt(p, off); err != nil {
+			return nil, fmt.Errorf("snapshot of %q: %w", table, err)
+		}
+		e.pageIO.read(len(p))
+		if err := p.iterateRows(len(cols), func(_ uint16, r sql.Row) error {
+			rows = append(rows, r)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("snapshot of %q: %w", table, err)
+		}
+	}
+	return rows, nil
+}
+
+// readCheckpointLSN returns the LSN stored in the checkpoint file of dir, or
+// 0 when there has been no checkpoint.
+func readCheckpointLSN(dir string) (uint64, error) {
+	buf, err := os.ReadFile(filepath.Join(dir, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read checkpoint: %w", err)
+	}
+	if len(buf) != 8 {
+		return 0, fmt.Errorf("checkpoint file is corrupt")
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+// writeCheckpointLSN atomically replaces the checkpoint LSN of dir and syncs
+// the directory, which also makes earlier snapshot renames durable.
+func writeCheckpointLSN(dir string, lsn uint64) error {
+	path := filepath.Join(dir, checkpointFile)
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], lsn)
+	if _, err := f.Write(buf[:]); err != nil {
+		f.Close()
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := syncDir(dir); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package filestore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goDB/internal/sql"
+)
+
+func newCheckpointTable(t *testing.T, dir string) *FileEngine {
+	t.Helper()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	return fs
+}
+
+func commitIDs(t *testing.T, fs *FileEngine, ids ...int64) {
+	t.Helper()
+	tx, err := fs.Begin(false)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	for _, id := range ids {
+		if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: id}}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+}
+
+func scanIDs(t *testing.T, fs *FileEngine) []int64 {
+	t.Helper()
+	_, rows := scanAll(t, fs, "t")
+	ids := make([]int64, len(rows))
+	for i, r := range rows {
+		ids[i] = r[0].I64
+	}
+	return ids
+}
+
+func checkIDs(t *testing.T, fs *FileEngine, want ...int64) {
+	t.Helper()
+	got := scanIDs(t, fs)
+	if len(got) != len(want) {
+		t.Fatalf("got ids %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got ids %v, want %v", got, want)
+		}
+	}
+}
+
+func walSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	info, err := os.Stat(currentWALPath(t, dir))
+	if err != nil {
+		t.Fatalf("stat WAL: %v", err)
+	}
+	return info.Size()
+}
+
+func TestFilestore_CheckpointTruncatesWAL(t *testing.T) {
+	dir := t.TempDir()
+	fs := newCheckpointTable(t, dir)
+	commitIDs(t, fs, 1, 2)
+
+	lsn := fs.wal.lastLSN()
+	if err := fs.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if got := walSize(t, dir); got != int64(len(walMagic)) {
+		t.Fatalf("WAL size after checkpoint = %d, want %d", got, len(walMagic))
+	}
+	if got, err := readCheckpointLSN(dir); err != nil || got != lsn {
+		t.Fatalf("checkpoint LSN = %d, %v; want %d", got, err, lsn)
+	}
+
+	// Committed after the checkpoint, then a crash with one tx still open.
+	commitIDs(t, fs, 3)
+	pending, _ := fs.Begin(false)
+	if err := pending.Insert("t", sql.Row{{Type: sql.TypeInt, I64: 99}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("New after crash failed: %v", err)
+	}
+	checkIDs(t, fs2, 1, 2, 3)
+	if got := fs2.wal.lastLSN(); got <= lsn {
+		t.Fatalf("LSN went backwards across restart: %d after %d", got, lsn)
+	}
+}
+
+// Records the checkpoint already covers stay in the WAL while a change feed
+// reads it; recovery must not apply them on top of the snapshot again.
+func TestFilestore_RecoverySkipsCheckpointedRecords(t *testing.T) {
+	dir := t.TempDir()
+	fs := newCheckpointTable(t, dir)
+	commitIDs(t, fs, 1, 2)
+
+	_, cancel := fs.Subscribe()
+	defer cancel()
+	if err := fs.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if got := walSize(t, dir); got == int64(len(walMagic)) {
+		t.Fatalf("WAL was truncated under an active subscriber")
+	}
+	commitIDs(t, fs, 3)
+
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("New after crash failed: %v", err)
+	}
+	checkIDs(t, fs2, 1, 2, 3)
+
+	// Recovery checkpointed, so running it again changes nothing.
+	fs3, err := New(dir)
+	if err != nil {
+		t.Fatalf("second recovery failed: %v", err)
+	}
+	checkIDs(t, fs3, 1, 2, 3)
+}
+
+func TestFilestore_CheckpointDiscardsRolledBackChanges(t *testing.T) {
+	dir := t.TempDir()
+	fs := newCheckpointTable(t, dir)
+	commitIDs(t, fs, 1)
+
+	tx, _ := fs.Begin(false)
+	if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: 2}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := fs.Rollback(tx); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if err := fs.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	checkIDs(t, fs, 1)
+
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	checkIDs(t, fs2, 1)
+}
+
+func TestFilestore_CheckpointWaitsForWriters(t *testing.T) {
+	fs := newCheckpointTable(t, t.TempDir())
+
+	tx, _ := fs.Begin(false)
+	if err := fs.Checkpoint(); !errors.Is(err, errCheckpointBusy) {
+		t.Fatalf("Checkpoint with an open write tx: got %v, want busy error", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	ro, _ := fs.Begin(true)
+	defer fs.Commit(ro)
+	if err := fs.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint with only a read tx open failed: %v", err)
+	}
+}
+
+func TestFilestore_LSNsSurviveClose(t *testing.T) {
+	dir := t.TempDir()
+	fs := newCheckpointTable(t, dir)
+	commitIDs(t, fs, 1)
+	lsn := fs.wal.lastLSN()
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs2.Close()
+	if got := fs2.wal.lastLSN(); got != lsn {
+		t.Fatalf("last LSN after reopen = %d, want %d", got, lsn)
+	}
+	commitIDs(t, fs2, 2)
+	if got := fs2.wal.lastLSN(); got <= lsn {
+		t.Fatalf("new records got LSN %d, not above %d", got, lsn)
+	}
+	checkIDs(t, fs2, 1, 2)
+}
+
+func TestFilestore_BackupAfterCheckpoint(t *testing.T) {
+	fs := newCheckpointTable(t, t.TempDir())
+	commitIDs(t, fs, 1, 2)
+	if err := fs.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	commitIDs(t, fs, 3)
+
+	dest := filepath.Join(t.TempDir(), "backup")
+	if err := fs.Backup(dest); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	for _, name := range []string{"t.ckpt", checkpointFile} {
+		if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+			t.Fatalf("backup is missing %s: %v", name, err)
+		}
+	}
+
+	restored, err := New(dest)
+	if err != nil {
+		t.Fatalf("New on backup failed: %v", err)
+	}
+	checkIDs(t, restored, 1, 2, 3)
+}
+
+func TestFilestore_AutoCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewWithOptions(dir, Options{SyncMode: SyncGroupCommit, CheckpointInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	// Ticks are skipped while a write transaction is open.
+	tx, _ := fs.Begin(false)
+	if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: 1}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got, err := readCheckpointLSN(dir); err != nil || got != 0 {
+		t.Fatalf("checkpoint LSN with a write tx open = %d, %v; want 0", got, err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	lsn := fs.wal.lastLSN()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := readCheckpointLSN(dir)
+		if err != nil {
+			t.Fatalf("readCheckpointLSN failed: %v", err)
+		}
+		if got == lsn {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no checkpoint after commit: LSN %d, want %d", got, lsn)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := walSize(t, dir); got != int64(len(walMagic)) {
+		t.Fatalf("WAL size after auto checkpoint = %d, want %d", got, len(walMagic))
+	}
+
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package filestore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// jsonExportVersion is the version written to, and accepted from, JSON exports.
+const jsonExportVersion = 1
+
+// jsonDoc is the top-level JSON export document.
+type jsonDoc struct {
+	Version int         `json:"version"`
+	Tables  []jsonTable `json:"tables"`
+}
+
+type jsonTable struct {
+	Name    string       `json:"name"`
+	Columns []jsonColumn `json:"columns"`
+	Rows    [][]any      `json:"rows"`
+}
+
+type jsonColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Default is the column's DEFAULT value, or "CURRENT_TIMESTAMP".
+	Default any `json:"default,omitempty"`
+	// References is the column's FOREIGN KEY target as "table(column)".
+	References string `json:"references,omitempty"`
+	// Collate is "NOCASE" for COLLATE NOCASE columns.
+	Collate string `json:"collate,omitempty"`
+	// NotNull marks NOT NULL columns.
+	NotNull bool `json:"not_null,omitempty"`
+}
+
+var typeNames = map[sql.DataType]string{
+	sql.TypeInt:       "INT",
+	sql.TypeFloat:     "FLOAT",
+	sql.TypeString:    "STRING",
+	sql.TypeBool:      "BOOL",
+	sql.TypeTimestamp: "TIMESTAMP",
+	sql.TypeBytes:     "BLOB",
+}
+
+// ExportJSON writes every table's schema and rows to w as one JSON document:
+//
+//	{"version": 1, "tables": [{"name": "users",
+//	  "columns": [{"name": "id", "type": "INT"}, ...],
+//	  "rows": [[1, "Alice", true], ...]}]}
+//
+// NULL values are written as null, TIMESTAMP values as text and BLOB values
+// as hex strings. The document does not depend on the
+// on-disk page layout and can be loaded back with ImportJSON.
+func (e *FileEngine) ExportJSON(w io.Writer) error {
+	tables, err := e.ListTables()
+	if err != nil {
+		return err
+	}
+
+	tx, err := e.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer e.Rollback(tx)
+
+	doc := jsonDoc{Version: jsonExportVersion, Tables: make([]jsonTable, 0, len(tables))}
+	for _, name := range tables {
+		cols, err := e.TableSchema(name)
+		if err != nil {
+			return err
+		}
+		_, rows, err := tx.Scan(name)
+		if err != nil {
+			return err
+		}
+
+		t := jsonTable{
+			Name:    name,
+			Columns: make([]jsonColumn, len(cols)),
+			Rows:    make([][]any, len(rows)),
+		}
+		for i, c := range cols {
+			typ, ok := typeNames[c.Type]
+			if !ok {
+				return fmt.Errorf("filestore: export %q: unsupported column type %d", name, c.Type)
+			}
+			t.Columns[i] = jsonColumn{Name: c.Name, Type: typ}
+			switch {
+			case c.DefaultCurrentTimestamp:
+				t.Columns[i].Default = "CURRENT_TIMESTAMP"
+			case c.Default != nil:
+				if t.Columns[i].Default, err = exportValue(*c.Default); err != nil {
+					return fmt.Errorf("filestore: export %q column %q default: %w", name, c.Name, err)
+				}
+			}
+			if c.References != nil {
+				t.Columns[i].References = c.References.Table + "(" + c.References.Column + ")"
+			}
+			if c.NoCase {
+				t.Columns[i].Collate = "NOCASE"
+			}
+			t.Columns[i].NotNull = c.NotNull
+		}
+		for i, row := range rows {
+			out := make([]any, len(row))
+			for j, v := range row {
+				out[j], err = exportValue(v)
+				if err != nil {
+					return fmt.Errorf("filestore: export %q row %d: %w", name, i+1, err)
+				}
+			}
+			t.Rows[i] = out
+		}
+		doc.Tables = append(doc.Tables, t)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("filestore: encode export: %w", err)
+	}
+	return nil
+}
+
+func exportValue(v sql.Value) (any, error) {
+	switch v.Type {
+	case sql.TypeInt:
+		return v.I64, nil
+	case sql.TypeFloat:
+		if math.IsNaN(v.F64) || math.IsInf(v.F64, 0) {
+			return nil, fmt.Errorf("non-finite float %v cannot be exported", v.F64)
+		}
+		return v.F64, nil
+	case sql.TypeString:
+		return v.S, nil
+	case sql.TypeBool:
+		return v.B, nil
+	case sql.TypeTimestamp:
+		return sql.FormatTimestamp(v), nil
+	case sql.TypeBytes:
+		return hex.EncodeToString([]byte(v.S)), nil
+	default:
+		return nil, nil
+	}
+}
+
+// ImportJSON reads a document written by ExportJSON, creates its tables and
+// inserts their rows in a single transaction. It fails if any of the tables
+// already exists.
+func (e *FileEngine) ImportJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var doc jsonDoc
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("filestore: decode import: %w", err)
+	}
+	if doc.Version != jsonExportVersion {
+		return fmt.Errorf("filestore: unsupported import version %d", doc.Version)
+	}
+
+	// Convert everything before touching the store, so a malformed document
+	// does not leave half-created tables behind.
+	schemas := make([][]sql.Column, len(doc.Tables))
+	data := make([][]sql.Row, len(doc.Tables))
+	for i, t := range doc.Tables {
+		cols, rows, err := decodeTable(t)
+		if err != nil {
+			return fmt.Errorf("filestore: import %q: %w", t.Name, err)
+		}
+		schemas[i], data[i] = cols, rows
+	}
+
+	// A row the store rejects, or a table that already exists, undoes the
+	// whole import: the rows are rolled back and the tables created so far
+	// removed again. Every created table is removed even if an earlier
+	// cleanup step failed; all the errors are returned together.
+	var created []string
+	fail := func(errs ...error) error {
+		for _, name := range created {
+			if rerr := e.removeTable(name); rerr != nil {
+				errs = append(errs, fmt.Errorf("filestore: remove imported table %q: %w", name, rerr))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	for i, t := range doc.Tables {
+		if err := e.CreateTable(t.Name, schemas[i]); err != nil {
+			return fail(err)
+		}
+		created = append(created, t.Name)
+	}
+
+	tx, err := e.Begin(false)
+	if err != nil {
+		return fail(err)
+	}
+	for i, t := range doc.Tables {
+		for _, row := range data[i] {
+			if err := tx.Insert(t.Name, row); err != nil {
+				return fail(err, e.Rollback(tx))
+			}
+		}
+	}
+	return e.Commit(tx)
+}
+
+// removeTable deletes a table ImportJSON created, with its snapshot, and
+// forgets its cached pages and row count. The table must have no indexes.
+// WAL records naming it are skipped by recovery, like those of any table
+// whose file is missing.
+func (e *FileEngine) removeTable(name string) error {
+	e.writeMu.RLock()
+	defer e.writeMu.RUnlock()
+
+	path, err := e.tablePath(name)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{path, e.snapshotPath(name)} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	e.invalidatePages(name)
+
+	e.mu.Lock()
+	delete(e.dirty, name)
+	delete(e.rolledBack, name)
+	e.mu.Unlock()
+	e.countMu.Lock()
+	delete(e.rowCounts, name)
+	e.countMu.Unlock()
+	return nil
+}
+
+// decodeTable converts one exported table back to a schema and typed rows.
+func decodeTable(t jsonTable) ([]sql.Column, []sql.Row, error) {
+	if t.Name == "" {
+		return nil, nil, fmt.Errorf("missing table name")
+	}
+
+	cols := make([]sql.Column, len(t.Columns))
+	for i, c := range t.Columns {
+		found := false
+		for dt, name := range typeNames {
+			if name == c.Type {
+				cols[i] = sql.Column{Name: c.Name, Type: dt}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("column %q: unknown type %q", c.Name, c.Type)
+		}
+		if c.Default == "CURRENT_TIMESTAMP" && cols[i].Type == sql.TypeTimestamp {
+			cols[i].DefaultCurrentTimestamp = true
+		} else if c.Default != nil {
+			def, err := importValue(c.Default, cols[i].Type)
+			if err != nil {
+				return nil, nil, fmt.Errorf("column %q default: %w", c.Name, err)
+			}
+			cols[i].Default = &def
+		}
+		if c.References != "" {
+			table, col, ok := strings.Cut(strings.TrimSuffix(c.References, ")"), "(")
+			if !ok || table == "" || col == "" {
+				return nil, nil, fmt.Errorf("column %q: invalid reference %q", c.Name, c.References)
+			}
+			cols[i].References = &sql.ForeignKey{Table: table, Column: col}
+		}
+		switch c.Collate {
+		case "":
+		case "NOCASE":
+			cols[i].NoCase = true
+		default:
+			return nil, nil, fmt.Errorf("column %q: unknown collation %q", c.Name, c.Collate)
+		}
+		cols[i].NotNull = c.NotNull
+	}
+
+	rows := make([]sql.Row, len(t.Rows))
+	for i, raw := range t.Rows {
+		if len(raw) != len(cols) {
+			return nil, nil, fmt.Errorf("row %d has %d values, expected %d", i+1, len(raw), len(cols))
+		}
+		row := make(sql.Row, len(cols))
+		for j, v := range raw {
+			val, err := importValue(v, cols[j].Type)
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %d, column %q: %w", i+1, cols[j].Name, err)
+			}
+			row[j] = val
+		}
+		rows[i] = row
+	}
+	return cols, rows, nil
+}
+
+// importValue converts a decoded JSON value to a sql.Value of type dt.
+func importValue(v any, dt sql.DataType) (sql.Value, error) {
+	if v == nil {
+		return sql.Value{Type: sql.TypeNull}, nil
+	}
+
+	switch dt {
+	case sql.TypeInt:
+		if n, ok := v.(json.Number); ok {
+			i, err := n.Int64()
+			if err != nil {
+				return sql.Value{}, fmt.Errorf("invalid INT %s", n)
+			}
+			return sql.Value{Type: sql.TypeInt, I64: i}, nil
+		}
+	case sql.TypeFloat:
+		if n, ok := v.(json.Number); ok {
+			f, err := n.Float64()
+			if err != nil {
+				return sql.Value{}, fmt.Errorf("invalid FLOAT %s", n)
+			}
+			return sql.Value{Type: sql.TypeFloat, F64: f}, nil
+		}
+	case sql.TypeString:
+		if s, ok := v.(string); ok {
+			return sql.Value{Type: sql.TypeString, S: s}, nil
+		}
+	case sql.TypeBool:
+		if b, ok := v.(bool); ok {
+			return sql.Value{Type: sql.TypeBool, B: b}, nil
+		}
+	case sql.TypeTimestamp:
+		if s, ok := v.(string); ok {
+			return sql.ParseTimestamp(s)
+		}
+	case sql.TypeBytes:
+		if s, ok := v.(string); ok {
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return sql.Value{}, fmt.Errorf("invalid BLOB hex %q", s)
+			}
+			return sql.Value{Type: sql.TypeBytes, S: string(b)}, nil
+		}
+	}
+	return sql.Value{}, fmt.Errorf("value %v does not match column type %s", v, typeNames[dt])
+}
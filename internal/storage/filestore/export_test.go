@@ -0,0 +1,174 @@
+package filestore
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"goDB/internal/sql"
+)
+
+func TestFilestore_ExportImportJSON(t *testing.T) {
+	src, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	cols := []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "name", Type: sql.TypeString},
+		{Name: "score", Type: sql.TypeFloat},
+		{Name: "active", Type: sql.TypeBool},
+	}
+	if err := src.CreateTable("users", cols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	defNote := sql.Value{Type: sql.TypeString, S: "none"}
+	emptyCols := []sql.Column{
+		{Name: "x", Type: sql.TypeInt, References: &sql.ForeignKey{Table: "users", Column: "id"}},
+		{Name: "note", Type: sql.TypeString, Default: &defNote},
+		{Name: "at", Type: sql.TypeTimestamp, DefaultCurrentTimestamp: true},
+		{Name: "email", Type: sql.TypeString, NoCase: true},
+	}
+	if err := src.CreateTable("empty", emptyCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	want := []sql.Row{
+		{
+			{Type: sql.TypeInt, I64: 1},
+			{Type: sql.TypeString, S: "Alice"},
+			{Type: sql.TypeFloat, F64: 2},
+			{Type: sql.TypeBool, B: true},
+		},
+		{
+			{Type: sql.TypeInt, I64: 2},
+			{Type: sql.TypeNull},
+			{Type: sql.TypeFloat, F64: 0.5},
+			{Type: sql.TypeBool, B: false},
+		},
+	}
+	tx, _ := src.Begin(false)
+	for _, r := range want {
+		if err := tx.Insert("users", r); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if err := src.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.ExportJSON(&buf); err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"type": "FLOAT"`) {
+		t.Fatalf("export does not contain column types:\n%s", buf.String())
+	}
+
+	dst, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := dst.ImportJSON(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("ImportJSON failed: %v", err)
+	}
+
+	tables, err := dst.ListTables()
+	if err != nil {
+		t.Fatalf("ListTables failed: %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"empty", "users"}) {
+		t.Fatalf("unexpected tables after import: %v", tables)
+	}
+
+	schema, err := dst.TableSchema("users")
+	if err != nil {
+		t.Fatalf("TableSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(schema, cols) {
+		t.Fatalf("schema mismatch: got %v, want %v", schema, cols)
+	}
+	if schema, _ = dst.TableSchema("empty"); !reflect.DeepEqual(schema, emptyCols) {
+		t.Fatalf("schema mismatch: got %+v, want %+v", schema, emptyCols)
+	}
+
+	rtx, _ := dst.Begin(true)
+	_, got, err := rtx.Scan("users")
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows mismatch:\n got  %v\n want %v", got, want)
+	}
+
+	// Importing again must fail: the tables already exist.
+	if err := dst.ImportJSON(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Fatalf("expected error importing into existing tables")
+	}
+}
+
+func TestFilestore_ImportJSONRejectsTypeMismatch(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	doc := `{"version": 1, "tables": [{"name": "t",
+		"columns": [{"name": "id", "type": "INT"}],
+		"rows": [["one"]]}]}`
+	if err := fs.ImportJSON(strings.NewReader(doc)); err == nil {
+		t.Fatalf("expected type mismatch error")
+	}
+
+	tables, _ := fs.ListTables()
+	if len(tables) != 0 {
+		t.Fatalf("failed import created tables: %v", tables)
+	}
+}
+
+// A row the store rejects, or a table that already exists, leaves no
+// imported table behind, including the ones created before the failure.
+func TestFilestore_ImportJSONFailureRemovesTables(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	if err := fs.CreateTable("existing", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	for name, doc := range map[string]string{
+		"not null": `{"version": 1, "tables": [
+			{"name": "a", "columns": [{"name": "id", "type": "INT"}], "rows": [[1], [2]]},
+			{"name": "b", "columns": [{"name": "id", "type": "INT", "not_null": true}], "rows": [[1], [null]]}]}`,
+		"table exists": `{"version": 1, "tables": [
+			{"name": "a", "columns": [{"name": "id", "type": "INT"}], "rows": [[1]]},
+			{"name": "existing", "columns": [{"name": "id", "type": "INT"}], "rows": []}]}`,
+	} {
+		if err := fs.ImportJSON(strings.NewReader(doc)); err == nil {
+			t.Fatalf("%s: expected import to fail", name)
+		}
+		tables, err := fs.ListTables()
+		if err != nil {
+			t.Fatalf("ListTables failed: %v", err)
+		}
+		if len(tables) != 1 || tables[0] != "existing" {
+			t.Fatalf("%s: tables after failed import = %v, want only existing", name, tables)
+		}
+	}
+
+	// The names are free again, and the rolled-back rows stay gone.
+	doc := `{"version": 1, "tables": [{"name": "a", "columns": [{"name": "id", "type": "INT"}], "rows": [[7]]}]}`
+	if err := fs.ImportJSON(strings.NewReader(doc)); err != nil {
+		t.Fatalf("import after failures failed: %v", err)
+	}
+	if err := fs.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if _, rows := scanAll(t, fs, "a"); len(rows) != 1 || rows[0][0].I64 != 7 {
+		t.Fatalf("rows of a = %v, want only id 7", rows)
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"goDB/internal/sql"
 	"goDB/internal/storage"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -22,33 +24,100 @@ type indexInfo struct {
 
 // FileEngine is a simple on-disk storage engine.
 type FileEngine struct {
-	dir string
-	wal *walLogger
+	dir     string
+	opts    Options
+	wal     *walLogger
+	flusher *walFlusher   // nil with SyncEachCommit
+	ckpt    *checkpointer // nil without Options.CheckpointInterval
+
+	// syncMu guards opts.SyncMode, opts.GroupCommitWindow and flusher, which
+	// SetSetting may change; settingsMu serializes Setting and SetSetting.
+	syncMu     sync.RWMutex
+	settingsMu sync.Mutex
 
 	mu       sync.Mutex
 	nextTxID uint64
 	indexMgr *btree.Manager
 
+	// Checkpoint state, guarded by mu.
+	activeWriters int                 // open write transactions
+	dirty         map[string]struct{} // tables committed to since the last checkpoint
+	rolledBack    map[string]struct{} // tables holding rolled-back changes
+	checkpointLSN uint64
+
+	reservedBy uint64 // IMMEDIATE transaction holding the write reservation, guarded by mu
+
 	idxMu   sync.RWMutex
 	indexes map[string]map[string]*indexInfo // tableName -> columnName -> info
+
+	// writeMu is held shared by everything that writes table or index
+	// files, and exclusively by Backup while it copies them.
+	writeMu sync.RWMutex
+
+	// tableLocks serializes writes to each table file. Writers read a page,
+	// change it and write it back, so two of them working on the same page
+	// at once would lose one of the changes. Scans of read-only
+	// transactions hold them shared, see readLockTable.
+	tableLocksMu sync.Mutex
+	tableLocks   map[string]*sync.RWMutex
+
+	subsMu sync.Mutex
+	subs   map[*subscriber]struct{} // active Subscribe feeds
+
+	cache  *pageCache   // nil when disabled
+	pageIO pageCounters // see Metrics
+
+	countMu   sync.Mutex
+	rowCounts map[string]int // live rows per table; nil in read-only mode, see row_count.go
+
+	seqMu sync.Mutex // serializes sequence updates
 }
 
-// New creates a new FileEngine storing all tables in dir.
+// New creates a new FileEngine storing all tables in dir, using the default
+// Options.
 func New(dir string) (*FileEngine, error) {
+	return NewWithOptions(dir, Options{})
+}
+
+// NewWithOptions creates a new FileEngine storing all tables in dir.
+func NewWithOptions(dir string, opts Options) (*FileEngine, error) {
+	switch opts.SyncMode {
+	case SyncEachCommit, SyncGroupCommit, SyncAsync:
+	default:
+		return nil, fmt.Errorf("filestore: unknown sync mode %d", opts.SyncMode)
+	}
+
+	if opts.ReadOnly {
+		return openReadOnly(dir, opts)
+	}
+
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, fmt.Errorf("filestore: create dir: %w", err)
 	}
 
-	w, err := newWAL(dir)
+	segSize := opts.WALSegmentSize
+	if segSize == 0 {
+		segSize = DefaultWALSegmentSize
+	}
+	w, err := newWAL(dir, segSize)
 	if err != nil {
 		return nil, fmt.Errorf("filestore: init WAL: %w", err)
 	}
 
 	e := &FileEngine{
-		dir:      dir,
-		wal:      w,
-		nextTxID: 1,
-		indexes:  make(map[string]map[string]*indexInfo),
+		dir:        dir,
+		opts:       opts,
+		wal:        w,
+		nextTxID:   1,
+		indexes:    make(map[string]map[string]*indexInfo),
+		subs:       make(map[*subscriber]struct{}),
+		dirty:      make(map[string]struct{}),
+		rolledBack: make(map[string]struct{}),
+	}
+
+	if err := e.checkTableHeaders(); err != nil {
+		_ = w.Close()
+		return nil, err
 	}
 
 	e.indexMgr = btree.NewManager(dir)
@@ -60,26 +129,33 @@ func New(dir string) (*FileEngine, error) {
 	}
 	for _, ent := range entries {
 		name := ent.Name()
-		if strings.HasSuffix(name, ".idx") {
-			parts := strings.Split(strings.TrimSuffix(name, ".idx"), "_")
-			if len(parts) == 2 {
-				tableName := parts[0]
-				columnName := parts[1]
-
-				bt, err := e.indexMgr.OpenOrCreateIndex(tableName, columnName)
-				if err != nil {
-					return nil, fmt.Errorf("filestore: could not open existing index %s: %w", name, err)
-				}
-				if e.indexes[tableName] == nil {
-					e.indexes[tableName] = make(map[string]*indexInfo)
-				}
-				e.indexes[tableName][columnName] = &indexInfo{
-					name:       name, // Use filename as internal name
-					tableName:  tableName,
-					columnName: columnName,
-					btree:      bt,
-				}
-			}
+		if !strings.HasSuffix(name, ".idx") {
+			continue
+		}
+		tableName, columnName, ok, err := e.resolveIndexFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if _, dup := e.indexes[tableName][columnName]; dup {
+			log.Printf("filestore: ignoring %s: the index on %s.%s is already loaded", name, tableName, columnName)
+			continue
+		}
+
+		bt, err := e.indexMgr.OpenOrCreateIndex(tableName, columnName)
+		if err != nil {
+			return nil, fmt.Errorf("filestore: could not open existing index %s: %w", name, err)
+		}
+		if e.indexes[tableName] == nil {
+			e.indexes[tableName] = make(map[string]*indexInfo)
+		}
+		e.indexes[tableName][columnName] = &indexInfo{
+			name:       btree.IndexFileName(tableName, columnName), // Use filename as internal name
+			tableName:  tableName,
+			columnName: columnName,
+			btree:      bt,
 		}
 	}
 
@@ -88,10 +164,134 @@ func New(dir string) (*FileEngine, error) {
 		return nil, fmt.Errorf("filestore: recovery failed: %w", err)
 	}
 
+	// Recovery rewrites table files directly, so the page cache only starts
+	// afterwards.
+	cacheSize := opts.PageCacheSize
+	if cacheSize == 0 {
+		cacheSize = DefaultPageCacheSize
+	}
+	e.cache = newPageCache(cacheSize, e.tablePath, &e.pageIO)
+	e.rowCounts = make(map[string]int)
+
+	if opts.SyncMode != SyncEachCommit {
+		e.flusher = newWALFlusher(w, opts.GroupCommitWindow)
+	}
+	if opts.CheckpointInterval > 0 {
+		e.ckpt = e.startCheckpointer(opts.CheckpointInterval)
+	}
+
 	return e, nil
 }
 
+// openReadOnly opens dir without a WAL, indexes or page cache; see
+// Options.ReadOnly.
+func openReadOnly(dir string, opts Options) (*FileEngine, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("filestore: open read-only: %w", err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("filestore: open read-only: %s is not a directory", dir)
+	}
+	e := &FileEngine{
+		dir:        dir,
+		opts:       opts,
+		indexes:    make(map[string]map[string]*indexInfo),
+		subs:       make(map[*subscriber]struct{}),
+		dirty:      make(map[string]struct{}),
+		rolledBack: make(map[string]struct{}),
+		indexMgr:   btree.NewManager(dir),
+	}
+	if err := e.checkTableHeaders(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close checkpoints the database unless write transactions are still open,
+// then flushes and closes the WAL and all open indexes. Change feeds started
+// with Subscribe are closed as well. The engine must not be used afterwards.
+func (e *FileEngine) Close() error {
+	if e.opts.ReadOnly {
+		return nil
+	}
+	e.settingsMu.Lock()
+	if e.ckpt != nil {
+		e.ckpt.close()
+	}
+	e.settingsMu.Unlock()
+	e.syncMu.Lock()
+	if e.flusher != nil {
+		e.flusher.close()
+	}
+	e.syncMu.Unlock()
+
+	var firstErr error
+	if err := e.Checkpoint(); err != nil && !errors.Is(err, errCheckpointBusy) {
+		firstErr = err
+	}
+	if err := e.wal.Sync(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	if err := e.wal.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	if err := e.indexMgr.CloseAll(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	// Subscribers stop once they find the WAL closed.
+	e.notifySubscribers()
+
+	if firstErr != nil {
+		return fmt.Errorf("filestore: close: %w", firstErr)
+	}
+	return nil
+}
+
+// syncWAL makes the WAL durable according to the configured SyncMode, after
+// a COMMIT or ROLLBACK record has been appended.
+func (e *FileEngine) syncWAL() error {
+	e.syncMu.RLock()
+	defer e.syncMu.RUnlock()
+	switch e.opts.SyncMode {
+	case SyncGroupCommit:
+		pos, err := e.wal.size()
+		if err != nil {
+			return err
+		}
+		return e.flusher.wait(pos)
+	case SyncAsync:
+		e.flusher.request()
+		return nil
+	default:
+		return e.wal.Sync()
+	}
+}
+
+// Sync makes everything committed so far durable, whatever the SyncMode,
+// by fsyncing the WAL right away instead of waiting for the background
+// flusher. Table pages need no flushing: the page cache writes them back at
+// the end of every operation, and recovery rebuilds them from the WAL. Use
+// it before copying the data directory from outside, or before shutting
+// down with SyncAsync.
+func (e *FileEngine) Sync() error {
+	if e.opts.ReadOnly {
+		return nil
+	}
+	if err := e.wal.Sync(); err != nil {
+		return fmt.Errorf("filestore: sync: %w", err)
+	}
+	return nil
+}
+
 func (e *FileEngine) CreateIndex(indexName, tableName, columnName string) error {
+	if e.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	e.writeMu.RLock()
+	defer e.writeMu.RUnlock()
+
 	e.idxMu.RLock()
 	if columns, ok := e.indexes[tableName]; ok {
 		if _, exists := columns[columnName]; exists {
@@ -101,10 +301,13 @@ func (e *FileEngine) CreateIndex(indexName, tableName, columnName string) error
 	}
 	e.idxMu.RUnlock()
 
-	path := e.tablePath(tableName)
+	path, err := e.tablePath(tableName)
+	if err != nil {
+		return err
+	}
 	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("filestore: open table for index creation: %w", err)
+		return fmt.Errorf("filestore: open table for index creation: %w", missingTable(tableName, err))
 	}
 	defer f.Close()
 
@@ -125,7 +328,7 @@ func (e *FileEngine) CreateIndex(indexName, tableName, columnName string) error
 		}
 	}
 	if colIdx == -1 {
-		return fmt.Errorf("filestore: column %q not found in table %q", columnName, tableName)
+		return fmt.Errorf("filestore: %w %q in table %q", sql.ErrColumnNotFound, columnName, tableName)
 	}
 	if cols[colIdx].Type != sql.TypeInt {
 		return fmt.Errorf("filestore: cannot create index on non-integer column %q", columnName)
@@ -151,14 +354,14 @@ func (e *FileEngine) CreateIndex(indexName, tableName, columnName string) error
 		}
 		numPages := uint32(dataBytes / PageSize)
 
+		scratch := make(sql.Row, len(cols))
 		for pageID := uint32(0); pageID < numPages; pageID++ {
-			p := make(pageBuf, PageSize)
-			offset := headerEnd + int64(pageID)*PageSize
-			if _, err := f.ReadAt(p, offset); err != nil {
-				return fmt.Errorf("filestore: read page %d for index creation: %w", pageID, err)
+			p, err := e.readPage(tableName, f, headerEnd, pageID)
+			if err != nil {
+				return err
 			}
 
-			err := p.iterateRows(len(cols), func(slotID uint16, r sql.Row) error {
+			err = p.iterateRowsInto(scratch, true, func(slotID uint16, r sql.Row) error {
 				val := r[colIdx]
 				if val.Type == sql.TypeNull {
 					return nil
@@ -192,6 +395,26 @@ func (e *FileEngine) CreateIndex(indexName, tableName, columnName string) error
 	return nil
 }
 
+// IndexedColumns returns the indexed columns of a table, sorted by name.
+func (e *FileEngine) IndexedColumns(tableName string) ([]string, error) {
+	path, err := e.tablePath(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("filestore: list indexes: %w", missingTable(tableName, err))
+	}
+
+	e.idxMu.RLock()
+	defer e.idxMu.RUnlock()
+	cols := make([]string, 0, len(e.indexes[tableName]))
+	for col := range e.indexes[tableName] {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	return cols, nil
+}
+
 // ListTables returns all *.godb files in the storage directory.
 func (e *FileEngine) ListTables() ([]string, error) {
 	entries, err := os.ReadDir(e.dir)
@@ -212,11 +435,14 @@ func (e *FileEngine) ListTables() ([]string, error) {
 
 // TableSchema reads the schema header of the given table.
 func (e *FileEngine) TableSchema(name string) ([]sql.Column, error) {
-	path := e.tablePath(name)
+	path, err := e.tablePath(name)
+	if err != nil {
+		return nil, err
+	}
 
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("filestore: open table for schema: %w", err)
+		return nil, fmt.Errorf("filestore: open table for schema: %w", missingTable(name, err))
 	}
 	defer f.Close()
 
@@ -228,13 +454,87 @@ func (e *FileEngine) TableSchema(name string) ([]sql.Column, error) {
 	return cols, nil
 }
 
-func (e *FileEngine) tablePath(name string) string {
-	return filepath.Join(e.dir, name+".godb")
+// ErrInvalidName is returned for a table, column or sequence name that
+// cannot be stored safely. Names become file names in the data directory,
+// so they may not be empty or contain path separators or NUL bytes, and
+// they may not contain dots, which separate the table and column in index
+// file names.
+var ErrInvalidName = errors.New("filestore: invalid name")
+
+// checkName fails with ErrInvalidName unless name may be used as a kind
+// ("table", "column" or "sequence") name.
+func checkName(kind, name string) error {
+	if name == "" || strings.ContainsAny(name, "/\\.\x00") {
+		return fmt.Errorf("%w: %s %q", ErrInvalidName, kind, name)
+	}
+	return nil
+}
+
+// tablePath returns the file of a table, failing for a name that could
+// point outside the data directory.
+func (e *FileEngine) tablePath(name string) (string, error) {
+	if err := checkName("table", name); err != nil {
+		return "", err
+	}
+	return filepath.Join(e.dir, name+".godb"), nil
+}
+
+// missingTable reports a table file that does not exist as
+// sql.ErrTableNotFound and returns other errors from opening it unchanged.
+func missingTable(name string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w %q", sql.ErrTableNotFound, name)
+	}
+	return err
+}
+
+// lockTable takes the write lock of a table and returns its unlock.
+func (e *FileEngine) lockTable(name string) func() {
+	mu := e.tableLock(name)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// readLockTable takes the lock of a table shared, holding off writers but
+// not other readers, and returns its unlock.
+func (e *FileEngine) readLockTable(name string) func() {
+	mu := e.tableLock(name)
+	mu.RLock()
+	return mu.RUnlock
+}
+
+// tableLock returns the lock of a table, creating it on first use.
+func (e *FileEngine) tableLock(name string) *sync.RWMutex {
+	e.tableLocksMu.Lock()
+	defer e.tableLocksMu.Unlock()
+	if e.tableLocks == nil {
+		e.tableLocks = make(map[string]*sync.RWMutex)
+	}
+	mu, ok := e.tableLocks[name]
+	if !ok {
+		mu = &sync.RWMutex{}
+		e.tableLocks[name] = mu
+	}
+	return mu
 }
 
 // CreateTable creates a new table file with the given schema.
 func (e *FileEngine) CreateTable(name string, cols []sql.Column) error {
-	path := e.tablePath(name)
+	if e.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	e.writeMu.RLock()
+	defer e.writeMu.RUnlock()
+
+	path, err := e.tablePath(name)
+	if err != nil {
+		return err
+	}
+	for _, c := range cols {
+		if err := checkName("column", c.Name); err != nil {
+			return err
+		}
+	}
 
 	if _, err := os.Stat(path); err == nil {
 		return fmt.Errorf("filestore: table %q already exists", name)
@@ -253,35 +553,73 @@ func (e *FileEngine) CreateTable(name string, cols []sql.Column) error {
 		_ = os.Remove(path)
 		return fmt.Errorf("filestore: write header: %w", err)
 	}
+	e.setRowCount(name, 0, nil)
+
+	// A snapshot left behind by an earlier table of the same name would
+	// replace this one's rows during recovery.
+	if err := os.Remove(e.snapshotPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("filestore: remove stale snapshot: %w", err)
+	}
 
 	return nil
 }
 
-// Begin starts a new (very simple) transaction.
+// Begin starts a new (very simple) transaction. Each scan of a read-only
+// transaction holds the table's lock shared while it reads the pages, so
+// writers to that table wait for it to finish and the scan returns the
+// table as it was at one moment, never half of a statement's changes.
+// Separate scans of one transaction may still see different data.
 func (e *FileEngine) Begin(readOnly bool) (storage.Tx, error) {
-	tx := &fileTx{
-		eng:      e,
-		readOnly: readOnly,
-		closed:   false,
-		id:       0,
+	if readOnly {
+		return &fileTx{eng: e, readOnly: true}, nil
 	}
+	return e.beginWrite(false)
+}
 
-	if !readOnly {
-		e.mu.Lock()
-		txID := e.nextTxID
-		e.nextTxID++
-		e.mu.Unlock()
+// BeginImmediate starts a write transaction holding the engine's write
+// reservation. It fails with storage.ErrBusy while any other write
+// transaction is open; until it ends, other transactions cannot write.
+func (e *FileEngine) BeginImmediate() (storage.Tx, error) {
+	return e.beginWrite(true)
+}
 
-		tx.id = txID
+// beginWrite starts a write transaction and logs its BEGIN record.
+func (e *FileEngine) beginWrite(immediate bool) (*fileTx, error) {
+	if e.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
 
-		if err := e.wal.appendBegin(txID); err != nil {
-			return nil, fmt.Errorf("filestore: WAL BEGIN: %w", err)
-		}
+	e.mu.Lock()
+	if immediate && (e.reservedBy != 0 || e.activeWriters > 0) {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("filestore: begin immediate: %w", storage.ErrBusy)
 	}
+	tx := &fileTx{eng: e, id: e.nextTxID}
+	e.nextTxID++
+	e.activeWriters++
+	if immediate {
+		e.reservedBy = tx.id
+	}
+	e.mu.Unlock()
 
+	if err := e.wal.appendBegin(tx.id); err != nil {
+		e.endWriter(tx, false)
+		return nil, fmt.Errorf("filestore: WAL BEGIN: %w", err)
+	}
 	return tx, nil
 }
 
+// checkReservation fails with storage.ErrBusy when a transaction other than
+// tx holds the write reservation.
+func (e *FileEngine) checkReservation(tx *fileTx) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.reservedBy != 0 && e.reservedBy != tx.id {
+		return fmt.Errorf("filestore: %w", storage.ErrBusy)
+	}
+	return nil
+}
+
 func (e *FileEngine) Commit(tx storage.Tx) error {
 	ft, err := e.validateTx(tx)
 	if err != nil {
@@ -292,9 +630,18 @@ func (e *FileEngine) Commit(tx storage.Tx) error {
 		if err := e.wal.appendCommit(ft.id); err != nil {
 			return fmt.Errorf("filestore: WAL COMMIT: %w", err)
 		}
-		if err := e.wal.Sync(); err != nil {
+		if err := e.syncWAL(); err != nil {
 			return fmt.Errorf("filestore: WAL sync on commit: %w", err)
 		}
+		e.notifySubscribers()
+		e.endWriter(ft, true)
+		ft.closed = true
+		// The last writer to finish undoes the transactions that rolled
+		// back while it was open.
+		if err := e.undoRolledBack(); err != nil {
+			return fmt.Errorf("filestore: commit: %w", err)
+		}
+		return nil
 	}
 
 	ft.closed = true
@@ -311,11 +658,62 @@ func (e *FileEngine) Rollback(tx storage.Tx) error {
 		if err := e.wal.appendRollback(ft.id); err != nil {
 			return fmt.Errorf("filestore: WAL ROLLBACK: %w", err)
 		}
-		if err := e.wal.Sync(); err != nil {
+		if err := e.syncWAL(); err != nil {
 			return fmt.Errorf("filestore: WAL sync on rollback: %w", err)
 		}
+		e.notifySubscribers()
+		e.endWriter(ft, false)
+		ft.closed = true
+		if err := e.undoRolledBack(); err != nil {
+			return fmt.Errorf("filestore: rollback: %w", err)
+		}
+		return nil
 	}
 
 	ft.closed = true
 	return nil
 }
+
+// undoRolledBack rewrites the tables written by rolled-back transactions
+// from their snapshot and the committed changes logged since, removing the
+// rolled-back changes from the table files. While other write transactions
+// are open their uncommitted changes share those files, so the tables are
+// left until the last of them commits or rolls back, which calls it again.
+func (e *FileEngine) undoRolledBack() error {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.activeWriters > 0 || len(e.rolledBack) == 0 {
+		return nil
+	}
+	tables, err := e.ListTables()
+	if err != nil {
+		return err
+	}
+	if err := e.discardRolledBack(tables); err != nil {
+		return err
+	}
+	e.rolledBack = make(map[string]struct{})
+	return nil
+}
+
+// endWriter records the end of write transaction tx for the next
+// checkpoint. The tables of a rolled-back transaction still hold its
+// changes until undoRolledBack rebuilds them.
+func (e *FileEngine) endWriter(tx *fileTx, committed bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.activeWriters--
+	if e.reservedBy == tx.id {
+		e.reservedBy = 0
+	}
+	for t := range tx.tables {
+		if committed {
+			e.dirty[t] = struct{}{}
+		} else {
+			e.rolledBack[t] = struct{}{}
+		}
+	}
+}
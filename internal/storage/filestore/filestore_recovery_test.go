@@ -1,10 +1,15 @@
 package filestore
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"goDB/internal/sql"
 	"goDB/internal/storage"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -127,11 +132,10 @@ func TestFilestore_Recovery_IgnoresRolledBackTx(t *testing.T) {
 		t.Fatalf("Rollback(tx2) failed: %v", err)
 	}
 
-	// Before restart, because our current filestore writes directly to the table
-	// even for tx2, we may see both rows:
+	// Rollback already removed tx2's row from the table file.
 	_, rowsBefore := scanAll(t, fs1, "t")
-	if len(rowsBefore) != 2 {
-		t.Fatalf("before restart: expected 2 rows (no undo), got %d", len(rowsBefore))
+	if len(rowsBefore) != 1 {
+		t.Fatalf("before restart: expected 1 row (rollback undone), got %d", len(rowsBefore))
 	}
 
 	// Restart: recovery should rebuild table only from committed txs.
@@ -209,13 +213,13 @@ func TestFilestore_Recovery_WalExistsAndGrows(t *testing.T) {
 		t.Fatalf("Commit failed: %v", err)
 	}
 
-	walPath := filepath.Join(dir, "wal.log")
+	walPath := currentWALPath(t, dir)
 	info, err := os.Stat(walPath)
 	if err != nil {
-		t.Fatalf("wal.log not found: %v", err)
+		t.Fatalf("WAL segment not found: %v", err)
 	}
 	if info.Size() <= int64(len("GODBWAL2")) {
-		t.Fatalf("wal.log too small, no records? size=%d", info.Size())
+		t.Fatalf("WAL segment too small, no records? size=%d", info.Size())
 	}
 }
 func TestFilestore_Recovery_Delete_Replayed(t *testing.T) {
@@ -414,3 +418,386 @@ func TestFilestore_Recovery_Update_RollbackIgnored(t *testing.T) {
 		t.Fatalf("after restart: expected name=Alice, got rows=%v", rows)
 	}
 }
+
+// A record cut short by a crash mid-append must not prevent startup:
+// recovery drops it, keeps every committed transaction before it and lets
+// new commits append after the last complete record.
+func TestFilestore_Recovery_TornTrailingRecord(t *testing.T) {
+	var partial bytes.Buffer
+	partial.WriteByte(walRecInsert)
+	binary.Write(&partial, binary.LittleEndian, uint64(99))
+	binary.Write(&partial, binary.LittleEndian, uint16(len("users")))
+	partial.WriteString("users")
+	binary.Write(&partial, binary.LittleEndian, uint32(1))
+	partial.WriteByte(byte(sql.TypeInt))
+	partial.Write([]byte{1, 2, 3}) // int64 cut after three bytes
+
+	tails := map[string][]byte{
+		"partial record": partial.Bytes(),
+		"zero filled":    make([]byte, 64),
+	}
+	for name, tail := range tails {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			fs1, err := New(dir)
+			if err != nil {
+				t.Fatalf("New(fs1) failed: %v", err)
+			}
+			cols := []sql.Column{
+				{Name: "id", Type: sql.TypeInt},
+				{Name: "name", Type: sql.TypeString},
+			}
+			if err := fs1.CreateTable("users", cols); err != nil {
+				t.Fatalf("CreateTable(users) failed: %v", err)
+			}
+			tx, err := fs1.Begin(false)
+			if err != nil {
+				t.Fatalf("Begin failed: %v", err)
+			}
+			if err := tx.Insert("users", sql.Row{
+				{Type: sql.TypeInt, I64: 1},
+				{Type: sql.TypeString, S: "Alice"},
+			}); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+			if err := fs1.Commit(tx); err != nil {
+				t.Fatalf("Commit failed: %v", err)
+			}
+			if err := fs1.Close(); err != nil {
+				t.Fatalf("Close(fs1) failed: %v", err)
+			}
+
+			walPath := currentWALPath(t, dir)
+			info, err := os.Stat(walPath)
+			if err != nil {
+				t.Fatalf("stat WAL: %v", err)
+			}
+			goodSize := info.Size()
+
+			f, err := os.OpenFile(walPath, os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				t.Fatalf("open WAL: %v", err)
+			}
+			if _, err := f.Write(tail); err != nil {
+				t.Fatalf("append torn record: %v", err)
+			}
+			f.Close()
+
+			fs2, err := New(dir)
+			if err != nil {
+				t.Fatalf("New(fs2) after torn record failed: %v", err)
+			}
+			_, rows := scanAll(t, fs2, "users")
+			if len(rows) != 1 || rows[0][0].I64 != 1 || rows[0][1].S != "Alice" {
+				t.Fatalf("after recovery: unexpected rows %v", rows)
+			}
+			if info, err := os.Stat(walPath); err != nil {
+				t.Fatalf("stat WAL: %v", err)
+			} else if info.Size() != goodSize {
+				t.Fatalf("WAL size after recovery = %d, want %d", info.Size(), goodSize)
+			}
+
+			// New commits land after the last good record and survive a
+			// further restart.
+			tx, err = fs2.Begin(false)
+			if err != nil {
+				t.Fatalf("Begin failed: %v", err)
+			}
+			if err := tx.Insert("users", sql.Row{
+				{Type: sql.TypeInt, I64: 2},
+				{Type: sql.TypeString, S: "Bob"},
+			}); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+			if err := fs2.Commit(tx); err != nil {
+				t.Fatalf("Commit failed: %v", err)
+			}
+			if err := fs2.Close(); err != nil {
+				t.Fatalf("Close(fs2) failed: %v", err)
+			}
+
+			fs3, err := New(dir)
+			if err != nil {
+				t.Fatalf("New(fs3) failed: %v", err)
+			}
+			defer fs3.Close()
+			_, rows = scanAll(t, fs3, "users")
+			if len(rows) != 2 || rows[1][0].I64 != 2 {
+				t.Fatalf("after second restart: unexpected rows %v", rows)
+			}
+		})
+	}
+}
+
+// Anything other than a short or zero-filled tail is corruption and still
+// fails recovery.
+func TestFilestore_Recovery_CorruptRecordFails(t *testing.T) {
+	dir := t.TempDir()
+	fs1, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(fs1) failed: %v", err)
+	}
+	if err := fs1.CreateTable("users", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable(users) failed: %v", err)
+	}
+	tx, _ := fs1.Begin(false)
+	_ = tx.Insert("users", sql.Row{{Type: sql.TypeInt, I64: 1}})
+	if err := fs1.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	fs1.Close()
+
+	f, err := os.OpenFile(currentWALPath(t, dir), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open WAL: %v", err)
+	}
+	f.Write([]byte{0x0E, 1, 0, 0, 0, 0, 0, 0, 0})
+	f.Close()
+
+	if fs2, err := New(dir); err == nil {
+		fs2.Close()
+		t.Fatalf("expected recovery to fail on an unknown record type")
+	}
+}
+
+// Replayed rows are checked against the NOT NULL constraints in the table
+// header, so a WAL record holding a NULL for such a column cannot slip into
+// the table across a restart.
+func TestFilestore_Recovery_NotNullViolationFails(t *testing.T) {
+	dir := t.TempDir()
+	fs1, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(fs1) failed: %v", err)
+	}
+	cols := []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "name", Type: sql.TypeString, NotNull: true}}
+	if err := fs1.CreateTable("users", cols); err != nil {
+		t.Fatalf("CreateTable(users) failed: %v", err)
+	}
+	tx, _ := fs1.Begin(false)
+	if err := tx.Insert("users", sql.Row{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeNull}}); !errors.Is(err, sql.ErrNotNull) {
+		t.Fatalf("Insert of NULL name = %v, want ErrNotNull", err)
+	}
+	if err := tx.Insert("users", sql.Row{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "Alice"}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := fs1.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// Restart: the constraint comes back from the header.
+	fs1.Close()
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(fs2) failed: %v", err)
+	}
+	got, err := fs2.TableSchema("users")
+	if err != nil || got[0].NotNull || !got[1].NotNull {
+		t.Fatalf("schema after restart = %+v, %v; want name NOT NULL", got, err)
+	}
+
+	// A committed record that bypassed the check, then a crash.
+	if err := fs2.wal.appendInsertBatch(99, "users", got, []sql.Row{{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeNull}}}); err != nil {
+		t.Fatalf("append insert: %v", err)
+	}
+	if err := fs2.wal.appendCommit(99); err != nil {
+		t.Fatalf("append commit: %v", err)
+	}
+
+	fs3, err := New(dir)
+	if err == nil {
+		fs3.Close()
+		t.Fatalf("expected recovery to fail on a NULL in a NOT NULL column")
+	}
+	if !errors.Is(err, sql.ErrNotNull) || !strings.Contains(err.Error(), `"users"`) || !strings.Contains(err.Error(), `"name"`) {
+		t.Fatalf("recovery error %q does not report the NOT NULL violation in users.name", err)
+	}
+}
+
+// A committed record logged for other column types than the table has is
+// not replayed into it, even when its rows would pass the type checks.
+func TestFilestore_Recovery_ColumnTypesChanged(t *testing.T) {
+	dir := t.TempDir()
+	fs1, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(fs1) failed: %v", err)
+	}
+	cols := []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "score", Type: sql.TypeString}}
+	if err := fs1.CreateTable("t", cols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	// Logged while score was a FLOAT column; the NULL fits either type.
+	logged := []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "score", Type: sql.TypeFloat}}
+	if err := fs1.wal.appendInsertBatch(99, "t", logged, []sql.Row{{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeNull}}}); err != nil {
+		t.Fatalf("append insert: %v", err)
+	}
+	if err := fs1.wal.appendCommit(99); err != nil {
+		t.Fatalf("append commit: %v", err)
+	}
+
+	fs2, err := New(dir)
+	if err == nil {
+		fs2.Close()
+		t.Fatalf("expected recovery to reject a record logged for other column types")
+	}
+	if !strings.Contains(err.Error(), `"t"`) || !strings.Contains(err.Error(), "(INT, FLOAT)") || !strings.Contains(err.Error(), "(INT, STRING)") {
+		t.Fatalf("recovery error %q does not report the logged and current column types", err)
+	}
+}
+
+// Damaged table headers are reported by table and file name when the
+// engine opens, before recovery or any query reads them.
+func TestFilestore_Open_CorruptHeader(t *testing.T) {
+	dir := t.TempDir()
+	fs1, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(fs1) failed: %v", err)
+	}
+	for _, name := range []string{"good", "short", "magic"} {
+		if err := fs1.CreateTable(name, []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "name", Type: sql.TypeString}}); err != nil {
+			t.Fatalf("CreateTable(%s) failed: %v", name, err)
+		}
+	}
+	fs1.Close()
+
+	if err := os.Truncate(filepath.Join(dir, "short.godb"), 9); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "magic.godb"), os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	f.WriteAt([]byte("XXXX"), 0)
+	f.Close()
+
+	for _, opts := range []Options{{}, {ReadOnly: true}} {
+		fs2, err := NewWithOptions(dir, opts)
+		if err == nil {
+			fs2.Close()
+			t.Fatalf("expected opening with corrupt headers to fail (read-only %v)", opts.ReadOnly)
+		}
+		msg := err.Error()
+		for _, want := range []string{
+			"table magic (magic.godb): corrupt header: invalid file magic",
+			"table short (short.godb): corrupt header: file is truncated",
+		} {
+			if !strings.Contains(msg, want) {
+				t.Fatalf("error %q does not contain %q", msg, want)
+			}
+		}
+		if strings.Contains(msg, "good") {
+			t.Fatalf("error %q mentions the intact table", msg)
+		}
+	}
+}
+
+// A table file deleted out from under the engine must not keep the database
+// from opening: recovery rebuilds it from its checkpoint snapshot plus the
+// WAL when it has one, and otherwise drops its WAL records.
+func TestFilestore_Recovery_MissingTableFile(t *testing.T) {
+	dir := t.TempDir()
+	fs1, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(fs1) failed: %v", err)
+	}
+	insert := func(table string, id int64) {
+		t.Helper()
+		tx, _ := fs1.Begin(false)
+		if err := tx.Insert(table, sql.Row{{Type: sql.TypeInt, I64: id}}); err != nil {
+			t.Fatalf("Insert(%s) failed: %v", table, err)
+		}
+		if err := fs1.Commit(tx); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+	for _, table := range []string{"snap", "nosnap", "kept"} {
+		if err := fs1.CreateTable(table, []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+			t.Fatalf("CreateTable(%s) failed: %v", table, err)
+		}
+	}
+	insert("snap", 1)
+	if err := fs1.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "nosnap.ckpt")); err != nil {
+		t.Fatalf("remove snapshot: %v", err)
+	}
+	insert("snap", 2)
+	insert("nosnap", 1)
+	insert("kept", 1)
+
+	for _, table := range []string{"snap", "nosnap"} {
+		if err := os.Remove(filepath.Join(dir, table+".godb")); err != nil {
+			t.Fatalf("remove table file: %v", err)
+		}
+	}
+
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("New(fs2) with missing table files failed: %v", err)
+	}
+	defer fs2.Close()
+
+	if _, rows := scanAll(t, fs2, "snap"); len(rows) != 2 || rows[0][0].I64 != 1 || rows[1][0].I64 != 2 {
+		t.Fatalf("snap after recovery = %v, want ids 1 and 2", rows)
+	}
+	if _, rows := scanAll(t, fs2, "kept"); len(rows) != 1 || rows[0][0].I64 != 1 {
+		t.Fatalf("kept after recovery = %v, want id 1", rows)
+	}
+	if _, err := fs2.TableSchema("nosnap"); err == nil {
+		t.Fatalf("table without a file or snapshot should stay missing")
+	}
+}
+
+// Replay matches DELETE and UPDATE records to rows by value, which must
+// work for a row holding NaN: after a restart, and when a later rollback
+// rebuilds the table.
+func TestFilestore_Recovery_NaNRowDeleteReplayed(t *testing.T) {
+	for _, undoBy := range []string{"reopen", "rollback"} {
+		t.Run(undoBy, func(t *testing.T) {
+			dir := t.TempDir()
+			fs1, err := New(dir)
+			if err != nil {
+				t.Fatalf("New(fs1) failed: %v", err)
+			}
+			if err := fs1.CreateTable("f", []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "x", Type: sql.TypeFloat}}); err != nil {
+				t.Fatalf("CreateTable failed: %v", err)
+			}
+			tx, _ := fs1.Begin(false)
+			_ = tx.Insert("f", sql.Row{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeFloat, F64: math.NaN()}})
+			_ = tx.Insert("f", sql.Row{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeFloat, F64: 1.5}})
+			if err := fs1.Commit(tx); err != nil {
+				t.Fatalf("Commit failed: %v", err)
+			}
+			if err := fs1.Checkpoint(); err != nil {
+				t.Fatalf("Checkpoint failed: %v", err)
+			}
+
+			tx, _ = fs1.Begin(false)
+			if err := tx.DeleteWhere("f", func(r sql.Row) (bool, error) { return math.IsNaN(r[1].F64), nil }); err != nil {
+				t.Fatalf("DeleteWhere failed: %v", err)
+			}
+			if err := fs1.Commit(tx); err != nil {
+				t.Fatalf("Commit failed: %v", err)
+			}
+
+			fs := fs1
+			if undoBy == "reopen" {
+				// No Close: the delete is only in the WAL.
+				if fs, err = New(dir); err != nil {
+					t.Fatalf("New(fs2) failed: %v", err)
+				}
+			} else {
+				tx, _ = fs1.Begin(false)
+				_ = tx.Insert("f", sql.Row{{Type: sql.TypeInt, I64: 3}, {Type: sql.TypeFloat, F64: 2.5}})
+				if err := fs1.Rollback(tx); err != nil {
+					t.Fatalf("Rollback failed: %v", err)
+				}
+			}
+			if _, rows := scanAll(t, fs, "f"); len(rows) != 1 || rows[0][0].I64 != 2 {
+				t.Fatalf("rows after %s = %v, want only id 2", undoBy, rows)
+			}
+		})
+	}
+}
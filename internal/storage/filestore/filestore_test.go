@@ -2,10 +2,17 @@ package filestore
 
 import (
 	"errors"
+	"fmt"
 	"goDB/internal/sql"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+
+	"goDB/internal/storage"
 )
 
 // Basic: create table, verify file exists, read schema.
@@ -174,8 +181,9 @@ func TestFilestore_ReplaceAll(t *testing.T) {
 	}
 }
 
-// Rollback does NOT undo writes (documented)
-func TestFilestore_Rollback_NoUndo(t *testing.T) {
+// Rollback undoes the transaction's writes; while another write transaction
+// is open, the undo waits until the last one commits or rolls back.
+func TestFilestore_Rollback_Undo(t *testing.T) {
 	dir := t.TempDir()
 	fs, err := New(dir)
 	if err != nil {
@@ -189,29 +197,60 @@ func TestFilestore_Rollback_NoUndo(t *testing.T) {
 	if err := fs.CreateTable("t", cols); err != nil {
 		t.Fatalf("CreateTable failed: %v", err)
 	}
+	if err := fs.CreateIndex("idx_id", "t", "id"); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
 
-	tx, err := fs.Begin(false)
-	if err != nil {
-		t.Fatalf("Begin failed: %v", err)
+	insert := func(id int64) storage.Tx {
+		t.Helper()
+		tx, err := fs.Begin(false)
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: id}}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+		return tx
+	}
+	countRows := func() int {
+		t.Helper()
+		_, rows := scanAll(t, fs, "t")
+		return len(rows)
 	}
-	if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: 1}}); err != nil {
-		t.Fatalf("Insert failed: %v", err)
+
+	if err := fs.Commit(insert(1)); err != nil {
+		t.Fatalf("Commit failed: %v", err)
 	}
-	if err := fs.Rollback(tx); err != nil {
+	if err := fs.Rollback(insert(2)); err != nil {
 		t.Fatalf("Rollback failed: %v", err)
-	} // does NOT undo writes
+	}
+	if n := countRows(); n != 1 {
+		t.Fatalf("expected 1 row after rollback, got %d", n)
+	}
+	if rids, err := fs.indexes["t"]["id"].btree.Search(2); err != nil || len(rids) != 0 {
+		t.Fatalf("index still finds the rolled-back row: %v, %v", rids, err)
+	}
 
-	// Scan should still see row
-	tx2, err := fs.Begin(true)
-	if err != nil {
-		t.Fatalf("Begin2 failed: %v", err)
+	// With another writer open the undo waits for it to commit.
+	open := insert(3)
+	if err := fs.Rollback(insert(4)); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
 	}
-	_, rows, err := tx2.Scan("t")
-	if err != nil {
-		t.Fatalf("Scan failed: %v", err)
+	if err := fs.Commit(open); err != nil {
+		t.Fatalf("Commit failed: %v", err)
 	}
-	if len(rows) != 1 {
-		t.Fatalf("expected 1 row, got %d", len(rows))
+	_, rows := scanAll(t, fs, "t")
+	if len(rows) != 2 || rows[0][0].I64 != 1 || rows[1][0].I64 != 3 {
+		t.Fatalf("rows after the other writer committed = %v, want ids 1 and 3", rows)
+	}
+	if rids, err := fs.indexes["t"]["id"].btree.Search(4); err != nil || len(rids) != 0 {
+		t.Fatalf("index still finds the rolled-back row: %v, %v", rids, err)
+	}
+	if err := fs.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if n := countRows(); n != 2 {
+		t.Fatalf("expected 2 rows after the checkpoint, got %d", n)
 	}
 }
 
@@ -307,6 +346,12 @@ func TestFilestore_CreateIndex(t *testing.T) {
 	if err := fs.CreateIndex("idx_id", "users", "id"); err != nil {
 		t.Fatalf("CreateIndex failed: %v", err)
 	}
+	if got, err := fs.IndexedColumns("users"); err != nil || len(got) != 1 || got[0] != "id" {
+		t.Fatalf("IndexedColumns = %v, %v; want [id]", got, err)
+	}
+	if _, err := fs.IndexedColumns("missing"); err == nil {
+		t.Fatalf("expected IndexedColumns on a missing table to fail")
+	}
 
 	// Verify index contents
 	bt, err := fs.indexMgr.OpenOrCreateIndex("users", "id")
@@ -342,6 +387,239 @@ func TestFilestore_CreateIndex(t *testing.T) {
 	}
 }
 
+func TestFilestore_ScanOrdered(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cols := []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "name", Type: sql.TypeString}}
+	if err := fs.CreateTable("users", cols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	row := func(id int64, name string) sql.Row {
+		return sql.Row{{Type: sql.TypeInt, I64: id}, {Type: sql.TypeString, S: name}}
+	}
+	names := func(rows []sql.Row) string {
+		var out []string
+		for _, r := range rows {
+			out = append(out, r[1].S)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("users", []sql.Row{row(3, "c"), row(1, "a"), row(2, "b1"), row(4, "d"), row(2, "b2")}); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	ro, _ := fs.Begin(true)
+	scanner := ro.(storage.OrderedScanner)
+	if _, _, ok, err := scanner.ScanOrdered("users", "id", false, nil); ok || err != nil {
+		t.Fatalf("ScanOrdered without an index: ok = %v, err = %v", ok, err)
+	}
+	if err := fs.CreateIndex("idx_id", "users", "id"); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+
+	// Updates and deletes keep the index in step with the table.
+	tx, _ = fs.Begin(false)
+	if err := tx.UpdateWhere("users",
+		func(r sql.Row) (bool, error) { return r[1].S == "d", nil },
+		func(r sql.Row) (sql.Row, error) { r[0].I64 = 0; return r, nil }); err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	if err := tx.DeleteWhere("users", func(r sql.Row) (bool, error) { return r[1].S == "c", nil }); err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc bool
+		want string
+	}{
+		{false, "d,a,b1,b2"},
+		{true, "b1,b2,a,d"},
+	} {
+		_, rows, ok, err := scanner.ScanOrdered("users", "ID", tc.desc, nil)
+		if err != nil || !ok {
+			t.Fatalf("ScanOrdered(desc=%v): ok = %v, err = %v", tc.desc, ok, err)
+		}
+		if got := names(rows); got != tc.want {
+			t.Fatalf("ScanOrdered(desc=%v) = %s, want %s", tc.desc, got, tc.want)
+		}
+	}
+
+	_, rows, ok, err := scanner.ScanOrdered("users", "id", false, func(r sql.Row) (bool, error) { return r[0].I64 > 0, nil })
+	if err != nil || !ok || names(rows) != "a,b1,b2" {
+		t.Fatalf("filtered ScanOrdered = %s, %v, %v; want a,b1,b2", names(rows), ok, err)
+	}
+
+	// A NULL key is not in the index, so the index cannot order every row.
+	tx, _ = fs.Begin(false)
+	if err := tx.Insert("users", sql.Row{{Type: sql.TypeNull}, {Type: sql.TypeString, S: "n"}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, _, ok, err := scanner.ScanOrdered("users", "id", false, nil); ok || err != nil {
+		t.Fatalf("ScanOrdered with a NULL key: ok = %v, err = %v", ok, err)
+	}
+}
+
+func TestFilestore_ScanRange(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cols := []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "name", Type: sql.TypeString}}
+	if err := fs.CreateTable("users", cols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	row := func(id int64, name string) sql.Row {
+		return sql.Row{{Type: sql.TypeInt, I64: id}, {Type: sql.TypeString, S: name}}
+	}
+	names := func(rows []sql.Row) string {
+		var out []string
+		for _, r := range rows {
+			out = append(out, r[1].S)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("users", []sql.Row{row(7, "g"), row(3, "c"), row(5, "e1"), {{Type: sql.TypeNull}, {Type: sql.TypeString, S: "n"}}, row(1, "a"), row(5, "e2"), row(9, "i")}); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	ro, _ := fs.Begin(true)
+	scanner := ro.(storage.RangeScanner)
+	if _, _, ok, err := scanner.ScanRange("users", "id", 3, 7, nil); ok || err != nil {
+		t.Fatalf("ScanRange without an index: ok = %v, err = %v", ok, err)
+	}
+	if err := fs.CreateIndex("idx_id", "users", "id"); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+
+	// Rows come back in table order, not key order, and a NULL key does
+	// not stop the index from being used.
+	for _, tc := range []struct {
+		lo, hi int64
+		want   string
+	}{
+		{3, 7, "g,c,e1,e2"},
+		{5, 5, "e1,e2"},
+		{8, 100, "i"},
+		{7, 3, ""},
+	} {
+		_, rows, ok, err := scanner.ScanRange("users", "ID", tc.lo, tc.hi, nil)
+		if err != nil || !ok {
+			t.Fatalf("ScanRange(%d, %d): ok = %v, err = %v", tc.lo, tc.hi, ok, err)
+		}
+		if got := names(rows); got != tc.want {
+			t.Fatalf("ScanRange(%d, %d) = %s, want %s", tc.lo, tc.hi, got, tc.want)
+		}
+	}
+
+	_, rows, ok, err := scanner.ScanRange("users", "id", 1, 5, func(r sql.Row) (bool, error) { return r[1].S != "c", nil })
+	if err != nil || !ok || names(rows) != "e1,a,e2" {
+		t.Fatalf("filtered ScanRange = %s, %v, %v; want e1,a,e2", names(rows), ok, err)
+	}
+}
+
+func TestFilestore_RenameColumn(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("users", []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "name", Type: sql.TypeString}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if err := fs.CreateTable("orders", []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "buyer", Type: sql.TypeInt, References: &sql.ForeignKey{Table: "users", Column: "id"}}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	tx, _ := fs.Begin(false)
+	for i := int64(1); i <= 300; i++ {
+		if err := tx.Insert("users", sql.Row{{Type: sql.TypeInt, I64: i}, {Type: sql.TypeString, S: fmt.Sprintf("user%d", i)}}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err := fs.CreateIndex("idx_id", "users", "id"); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+
+	if err := fs.RenameColumn("users", "ID", "uid"); err != nil {
+		t.Fatalf("RenameColumn failed: %v", err)
+	}
+	if err := fs.RenameColumn("users", "uid", "name"); err == nil {
+		t.Fatalf("renaming onto an existing column should fail")
+	}
+	if err := fs.RenameColumn("users", "missing", "x"); err == nil {
+		t.Fatalf("renaming a missing column should fail")
+	}
+
+	check := func(fs *FileEngine, wantRows int) {
+		t.Helper()
+		cols, err := fs.TableSchema("users")
+		if err != nil || cols[0].Name != "uid" || cols[1].Name != "name" {
+			t.Fatalf("users schema = %+v, %v", cols, err)
+		}
+		cols, err = fs.TableSchema("orders")
+		if err != nil || cols[1].References == nil || cols[1].References.Column != "uid" {
+			t.Fatalf("orders schema = %+v, %v", cols, err)
+		}
+		if idx, err := fs.IndexedColumns("users"); err != nil || !reflect.DeepEqual(idx, []string{"uid"}) {
+			t.Fatalf("IndexedColumns = %v, %v; want [uid]", idx, err)
+		}
+
+		ro, _ := fs.Begin(true)
+		_, rows, ok, err := ro.(storage.RangeScanner).ScanRange("users", "uid", 150, 152, nil)
+		if err != nil || !ok || len(rows) != 3 || rows[0][1].S != "user150" {
+			t.Fatalf("ScanRange after rename = %v, %v, %v", rows, ok, err)
+		}
+		_, rows, err = ro.Scan("users")
+		if err != nil || len(rows) != wantRows {
+			t.Fatalf("Scan after rename returned %d rows, %v; want %d", len(rows), err, wantRows)
+		}
+	}
+	check(fs, 300)
+
+	// The index keeps following the table as rows change.
+	tx, _ = fs.Begin(false)
+	if err := tx.Insert("users", sql.Row{{Type: sql.TypeInt, I64: 1000}, {Type: sql.TypeString, S: "new"}}); err != nil {
+		t.Fatalf("Insert after rename failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	ro, _ := fs.Begin(true)
+	if _, rows, ok, err := ro.(storage.RangeScanner).ScanRange("users", "uid", 1000, 1000, nil); err != nil || !ok || len(rows) != 1 {
+		t.Fatalf("ScanRange for a new row = %v, %v, %v", rows, ok, err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer fs2.Close()
+	check(fs2, 301)
+}
+
 func TestFilestore_CreateIndexErrors(t *testing.T) {
 	dir := t.TempDir()
 	fs, err := New(dir)
@@ -366,3 +644,495 @@ func TestFilestore_CreateIndexErrors(t *testing.T) {
 		t.Fatalf("expected non-integer column index creation to fail")
 	}
 }
+
+// Underscores inside table and column names must not confuse reloading
+// indexes, including legacy table_column.idx files.
+func TestFilestore_IndexUnderscoredNames(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("user_profiles", []sql.Column{{Name: "user_id", Type: sql.TypeInt}, {Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	// Under the legacy naming, an index on user.profiles_id would have
+	// shared user_profiles_id.idx with the one on user_profiles.id.
+	if err := fs.CreateTable("user", []sql.Column{{Name: "profiles_id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	tx, err := fs.Begin(false)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := tx.Insert("user_profiles", sql.Row{{Type: sql.TypeInt, I64: 7}, {Type: sql.TypeInt, I64: 1}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := tx.Insert("user", sql.Row{{Type: sql.TypeInt, I64: 9}}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	for _, ix := range [][2]string{{"user_profiles", "user_id"}, {"user_profiles", "id"}, {"user", "profiles_id"}} {
+		if err := fs.CreateIndex("idx_"+ix[1], ix[0], ix[1]); err != nil {
+			t.Fatalf("CreateIndex(%s.%s) failed: %v", ix[0], ix[1], err)
+		}
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Give one index its legacy name; opening renames it back.
+	if err := os.Rename(filepath.Join(dir, "user_profiles.user_id.idx"), filepath.Join(dir, "user_profiles_user_id.idx")); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	fs, err = New(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer fs.Close()
+	if got, err := fs.IndexedColumns("user_profiles"); err != nil || len(got) != 2 || got[0] != "id" || got[1] != "user_id" {
+		t.Fatalf("IndexedColumns(user_profiles) = %v, %v; want [id user_id]", got, err)
+	}
+	if got, err := fs.IndexedColumns("user"); err != nil || len(got) != 1 || got[0] != "profiles_id" {
+		t.Fatalf("IndexedColumns(user) = %v, %v; want [profiles_id]", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "user_profiles.user_id.idx")); err != nil {
+		t.Fatalf("legacy index file was not renamed: %v", err)
+	}
+	for _, c := range []struct {
+		table, col string
+		key        int64
+	}{{"user_profiles", "user_id", 7}, {"user_profiles", "id", 1}, {"user", "profiles_id", 9}} {
+		rids, err := fs.indexes[c.table][c.col].btree.Search(c.key)
+		if err != nil || len(rids) != 1 {
+			t.Fatalf("search %s.%s = %d: %v, %v; want one row", c.table, c.col, c.key, rids, err)
+		}
+	}
+}
+
+// Names become file names, so ones that could leave the data directory or
+// confuse index file names are rejected.
+func TestFilestore_InvalidNames(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "db")
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	cols := []sql.Column{{Name: "id", Type: sql.TypeInt}}
+
+	for _, name := range []string{"../evil", "a/b", `a\b`, "a.b", "..", "", "a\x00b"} {
+		if err := fs.CreateTable(name, cols); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("CreateTable(%q) = %v, want ErrInvalidName", name, err)
+		}
+		if _, err := fs.TableSchema(name); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("TableSchema(%q) = %v, want ErrInvalidName", name, err)
+		}
+		if err := fs.CreateSequence(name, 1); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("CreateSequence(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+	if entries, _ := os.ReadDir(parent); len(entries) != 1 {
+		t.Fatalf("files created outside the data directory: %v", entries)
+	}
+
+	if err := fs.CreateTable("t", []sql.Column{{Name: "x.y", Type: sql.TypeInt}}); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("CreateTable with column x.y = %v, want ErrInvalidName", err)
+	}
+	if err := fs.CreateTable("t", cols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if err := fs.RenameColumn("t", "id", "../id"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("RenameColumn to ../id = %v, want ErrInvalidName", err)
+	}
+
+	tx, err := fs.Begin(false)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	defer fs.Rollback(tx)
+	if err := tx.Insert("../t", sql.Row{{Type: sql.TypeInt, I64: 1}}); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("Insert into ../t = %v, want ErrInvalidName", err)
+	}
+	if _, _, err := tx.Scan("../t"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("Scan of ../t = %v, want ErrInvalidName", err)
+	}
+}
+
+// BLOB values keep every byte, including ones that are not valid UTF-8,
+// both in table pages and through WAL replay.
+func TestFilestore_BlobRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cols := []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "data", Type: sql.TypeBytes},
+	}
+	if err := fs.CreateTable("files", cols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	want := []sql.Row{
+		{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeBytes, S: string(all)}},
+		{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeBytes, S: ""}},
+		{{Type: sql.TypeInt, I64: 3}, {Type: sql.TypeNull}},
+	}
+	tx, err := fs.Begin(false)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := tx.InsertBatch("files", want); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	check := func(fs *FileEngine) {
+		t.Helper()
+		_, rows := scanAll(t, fs, "files")
+		if len(rows) != len(want) {
+			t.Fatalf("expected %d rows, got %d", len(want), len(rows))
+		}
+		for i := range want {
+			if !equalRow(rows[i], want[i]) {
+				t.Fatalf("row %d: got %+v, want %+v", i, rows[i], want[i])
+			}
+		}
+	}
+	check(fs)
+
+	reopened, err := New(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	check(reopened)
+}
+
+// Rows that do not match the schema are rejected before they reach the WAL
+// or the table file.
+func TestFilestore_RejectsMistypedRows(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	cols := []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "name", Type: sql.TypeString},
+	}
+	if err := fs.CreateTable("users", cols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	walSize := func() int64 {
+		info, err := os.Stat(currentWALPath(t, dir))
+		if err != nil {
+			t.Fatalf("stat WAL: %v", err)
+		}
+		return info.Size()
+	}
+
+	good := sql.Row{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "Alice"}}
+	bad := sql.Row{{Type: sql.TypeString, S: "2"}, {Type: sql.TypeString, S: "Bob"}}
+
+	tx, err := fs.Begin(false)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := tx.Insert("users", good); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	before := walSize()
+	if err := tx.Insert("users", bad); err == nil {
+		t.Fatalf("expected Insert of a mistyped row to fail")
+	}
+	if err := tx.ReplaceAll("users", []sql.Row{good, bad}); err == nil {
+		t.Fatalf("expected ReplaceAll with a mistyped row to fail")
+	}
+	if err := tx.UpdateWhere("users", func(sql.Row) (bool, error) { return true, nil }, func(r sql.Row) (sql.Row, error) {
+		r[0] = sql.Value{Type: sql.TypeBool, B: true}
+		return r, nil
+	}); err == nil {
+		t.Fatalf("expected UpdateWhere producing a mistyped row to fail")
+	}
+	if after := walSize(); after != before {
+		t.Fatalf("WAL grew from %d to %d bytes for rejected rows", before, after)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	_, rows := scanAll(t, fs, "users")
+	if len(rows) != 1 || rows[0][1].S != "Alice" {
+		t.Fatalf("unexpected rows after rejected writes: %v", rows)
+	}
+}
+
+// A row that grows but still fits on its page keeps its place in scan order,
+// before and after recovery.
+func TestFilestore_UpdateGrowKeepsScanOrder(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cols := []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "name", Type: sql.TypeString},
+	}
+	if err := fs.CreateTable("users", cols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	tx, _ := fs.Begin(false)
+	for i, name := range []string{"Alice", "Bob", "Carol"} {
+		if err := tx.Insert("users", sql.Row{{Type: sql.TypeInt, I64: int64(i + 1)}, {Type: sql.TypeString, S: name}}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	long := "Bob with a much longer name than before"
+	if err := tx.UpdateWhere("users",
+		func(r sql.Row) (bool, error) { return r[0].I64 == 2, nil },
+		func(r sql.Row) (sql.Row, error) {
+			r[1] = sql.Value{Type: sql.TypeString, S: long}
+			return r, nil
+		}); err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	check := func(fs *FileEngine, when string) {
+		t.Helper()
+		_, rows := scanAll(t, fs, "users")
+		if len(rows) != 3 {
+			t.Fatalf("%s: expected 3 rows, got %d", when, len(rows))
+		}
+		for i, r := range rows {
+			if r[0].I64 != int64(i+1) {
+				t.Fatalf("%s: row %d has id %d, want %d", when, i, r[0].I64, i+1)
+			}
+		}
+		if rows[1][1].S != long {
+			t.Fatalf("%s: middle row name = %q, want %q", when, rows[1][1].S, long)
+		}
+	}
+	check(fs, "before restart")
+
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("New after restart failed: %v", err)
+	}
+	check(fs2, "after restart")
+}
+
+func TestFilestore_ConcurrentInserts(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				tx, err := fs.Begin(false)
+				if err != nil {
+					errs <- err
+					return
+				}
+				id := int64(w*perWriter + i)
+				if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: id}}); err != nil {
+					errs <- err
+					return
+				}
+				if err := fs.Commit(tx); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent insert failed: %v", err)
+	}
+
+	_, rows := scanAll(t, fs, "t")
+	if len(rows) != writers*perWriter {
+		t.Fatalf("expected %d rows, got %d", writers*perWriter, len(rows))
+	}
+	ids := make([]int, len(rows))
+	for i, r := range rows {
+		ids[i] = int(r[0].I64)
+	}
+	sort.Ints(ids)
+	for i, id := range ids {
+		if id != i {
+			t.Fatalf("row ids are not 0..%d: found %d at position %d", len(ids)-1, id, i)
+		}
+	}
+}
+
+func TestFilestore_TableStats(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	st, err := fs.TableStats("t")
+	if err != nil {
+		t.Fatalf("TableStats on empty table failed: %v", err)
+	}
+	if st.Rows != 0 || st.Pages != 0 || st.DeadSlots != 0 || st.Bytes == 0 {
+		t.Fatalf("empty table stats = %+v", st)
+	}
+
+	tx, _ := fs.Begin(false)
+	for i := int64(1); i <= 5; i++ {
+		if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: i}}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if err := tx.DeleteWhere("t", func(r sql.Row) (bool, error) { return r[0].I64%2 == 0, nil }); err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	st, err = fs.TableStats("t")
+	if err != nil {
+		t.Fatalf("TableStats failed: %v", err)
+	}
+	if st.Rows != 3 || st.Pages != 1 || st.DeadSlots != 2 {
+		t.Fatalf("stats = %+v, want 3 rows, 1 page, 2 dead slots", st)
+	}
+	if st.Bytes <= PageSize {
+		t.Fatalf("size %d does not cover the header and one page", st.Bytes)
+	}
+
+	if _, err := fs.TableStats("missing"); err == nil {
+		t.Fatalf("expected TableStats on a missing table to fail")
+	}
+}
+
+func TestFilestore_ReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewWithOptions(filepath.Join(dir, "missing"), Options{ReadOnly: true}); err == nil {
+		t.Fatalf("expected opening a missing directory read-only to fail")
+	}
+
+	w, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer w.Close()
+	if err := w.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	insert := func(id int64) {
+		t.Helper()
+		tx, _ := w.Begin(false)
+		if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: id}}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+		if err := w.Commit(tx); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+	insert(1)
+
+	walBefore, err := os.ReadFile(currentWALPath(t, dir))
+	if err != nil {
+		t.Fatalf("read WAL: %v", err)
+	}
+
+	ro, err := NewWithOptions(dir, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions(ReadOnly) failed: %v", err)
+	}
+	scanIDs := func() int {
+		t.Helper()
+		tx, err := ro.Begin(true)
+		if err != nil {
+			t.Fatalf("Begin(true) failed: %v", err)
+		}
+		defer ro.Commit(tx)
+		_, rows, err := tx.Scan("t")
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		return len(rows)
+	}
+	if n := scanIDs(); n != 1 {
+		t.Fatalf("read-only scan returned %d rows, want 1", n)
+	}
+
+	// Rows the writer adds later are visible, since nothing is cached.
+	insert(2)
+	if n := scanIDs(); n != 2 {
+		t.Fatalf("read-only scan after insert returned %d rows, want 2", n)
+	}
+
+	if _, err := ro.Begin(false); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Begin(false) error = %v, want ErrReadOnly", err)
+	}
+	if err := ro.CreateTable("u", []sql.Column{{Name: "id", Type: sql.TypeInt}}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("CreateTable error = %v, want ErrReadOnly", err)
+	}
+	if err := ro.CreateIndex("idx", "t", "id"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("CreateIndex error = %v, want ErrReadOnly", err)
+	}
+	if err := ro.CreateSequence("s", 1); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("CreateSequence error = %v, want ErrReadOnly", err)
+	}
+	if err := ro.Checkpoint(); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Checkpoint error = %v, want ErrReadOnly", err)
+	}
+	if err := ro.Backup(filepath.Join(t.TempDir(), "b")); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Backup error = %v, want ErrReadOnly", err)
+	}
+	tx, _ := ro.Begin(true)
+	if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: 3}}); err == nil {
+		t.Fatalf("expected Insert in a read-only tx to fail")
+	}
+	if err := ro.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// The reader must not have touched the WAL or created files.
+	walAfter, err := os.ReadFile(currentWALPath(t, dir))
+	if err != nil {
+		t.Fatalf("read WAL: %v", err)
+	}
+	if !strings.HasPrefix(string(walAfter), string(walBefore)) {
+		t.Fatalf("WAL was rewritten by the read-only engine")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "u.godb")); !os.IsNotExist(err) {
+		t.Fatalf("read-only engine created a table file: %v", err)
+	}
+}
@@ -1,16 +1,35 @@
 package filestore
 
 import (
-	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"goDB/internal/sql"
 	"io"
 	"math"
+	"unsafe"
 )
 
 const (
 	fileMagic = "GODB1" // 5 bytes magic
+	// fileMagicV2 marks headers that carry a flags byte after each column's
+	// type, followed by the column's default value and FOREIGN KEY target
+	// when it has them. It is only written for tables that need it, so
+	// other tables stay readable by older versions.
+	fileMagicV2 = "GODB2"
+)
+
+// errBadTableMagic is returned by readHeader for files that do not start
+// with a table file magic.
+var errBadTableMagic = errors.New("invalid file magic, not a GoDB table file")
+
+// Column flags in a GODB2 header.
+const (
+	colFlagDefault          = 1 << 0 // an encoded default value follows
+	colFlagCurrentTimestamp = 1 << 1 // DEFAULT CURRENT_TIMESTAMP
+	colFlagReferences       = 1 << 2 // parent table and column names follow
+	colFlagNoCase           = 1 << 3 // COLLATE NOCASE
+	colFlagNotNull          = 1 << 4 // NOT NULL
 )
 
 // writeHeader writes the table schema to the beginning of the file.
@@ -18,8 +37,15 @@ func writeHeader(w io.Writer, cols []sql.Column) error {
 	if len(cols) > 0xFFFF {
 		return fmt.Errorf("filestore: too many columns: %d", len(cols))
 	}
+	magic := fileMagic
+	for _, c := range cols {
+		if c.Default != nil || c.DefaultCurrentTimestamp || c.References != nil || c.NoCase || c.NotNull {
+			magic = fileMagicV2
+			break
+		}
+	}
 	// magic
-	if _, err := w.Write([]byte(fileMagic)); err != nil {
+	if _, err := w.Write([]byte(magic)); err != nil {
 		return err
 	}
 	// numCols as uint16
@@ -44,11 +70,72 @@ func writeHeader(w io.Writer, cols []sql.Column) error {
 		if err := binary.Write(w, binary.LittleEndian, uint8(c.Type)); err != nil {
 			return err
 		}
+		if magic == fileMagicV2 {
+			if err := writeColumnAttrs(w, c); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
+// writeColumnAttrs writes the GODB2 flags byte of c, followed by its
+// default value and FOREIGN KEY target when present.
+func writeColumnAttrs(w io.Writer, c sql.Column) error {
+	var flags uint8
+	if c.Default != nil {
+		flags |= colFlagDefault
+	}
+	if c.DefaultCurrentTimestamp {
+		flags |= colFlagCurrentTimestamp
+	}
+	if c.References != nil {
+		flags |= colFlagReferences
+	}
+	if c.NoCase {
+		flags |= colFlagNoCase
+	}
+	if c.NotNull {
+		flags |= colFlagNotNull
+	}
+	if _, err := w.Write([]byte{flags}); err != nil {
+		return err
+	}
+	if c.Default != nil {
+		if err := writeRow(w, sql.Row{*c.Default}); err != nil {
+			return err
+		}
+	}
+	if c.References != nil {
+		for _, name := range []string{c.References.Table, c.References.Column} {
+			if len(name) > 0xFFFF {
+				return fmt.Errorf("filestore: name too long: %s", name)
+			}
+			if err := binary.Write(w, binary.LittleEndian, uint16(len(name))); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readName reads a uint16 length-prefixed name.
+func readName(r io.Reader) (string, error) {
+	var l uint16
+	if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+		return "", err
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
 // readHeader reads the schema from the beginning of the file and leaves
 // the file position at the start of the first row.
 func readHeader(r io.Reader) ([]sql.Column, error) {
@@ -56,8 +143,9 @@ func readHeader(r io.Reader) ([]sql.Column, error) {
 	if _, err := io.ReadFull(r, magicBuf); err != nil {
 		return nil, err
 	}
-	if string(magicBuf) != fileMagic {
-		return nil, fmt.Errorf("filestore: invalid file magic, not a GoDB table file")
+	v2 := string(magicBuf) == fileMagicV2
+	if string(magicBuf) != fileMagic && !v2 {
+		return nil, fmt.Errorf("filestore: %w", errBadTableMagic)
 	}
 
 	var numCols uint16
@@ -86,54 +174,240 @@ func readHeader(r io.Reader) ([]sql.Column, error) {
 			Name: string(nameBytes),
 			Type: sql.DataType(t),
 		}
+
+		if v2 {
+			var flags uint8
+			if err := binary.Read(r, binary.LittleEndian, &flags); err != nil {
+				return nil, err
+			}
+			cols[i].DefaultCurrentTimestamp = flags&colFlagCurrentTimestamp != 0
+			cols[i].NoCase = flags&colFlagNoCase != 0
+			cols[i].NotNull = flags&colFlagNotNull != 0
+			if flags&colFlagDefault != 0 {
+				def, err := readRow(r, 1)
+				if err != nil {
+					return nil, fmt.Errorf("filestore: column %q default: %w", cols[i].Name, err)
+				}
+				cols[i].Default = &def[0]
+			}
+			if flags&colFlagReferences != 0 {
+				table, err := readName(r)
+				if err != nil {
+					return nil, err
+				}
+				col, err := readName(r)
+				if err != nil {
+					return nil, err
+				}
+				cols[i].References = &sql.ForeignKey{Table: table, Column: col}
+			}
+		}
 	}
 
 	return cols, nil
 }
 
-// writeRow encodes a row as a sequence of typed values.
-func writeRow(w io.Writer, row sql.Row) error {
-	for _, v := range row {
-		// type first
-		if err := binary.Write(w, binary.LittleEndian, uint8(v.Type)); err != nil {
-			return err
-		}
+// valueCodec encodes and decodes the values of one DataType. A value is
+// stored as its type byte followed by size fixed bytes and, for types of
+// variable length, bodyLen(fixed) more bytes.
+type valueCodec struct {
+	size    int                    // fixed bytes after the type byte, at most maxFixedSize
+	bodyLen func(fixed []byte) int // nil for fixed-size values
+
+	// encode appends the bytes of v after its type byte to dst.
+	encode func(dst []byte, v sql.Value) ([]byte, error)
+	// decode stores a value of type t in dst. With borrow set, strings
+	// may reference body instead of copying it.
+	decode func(dst *sql.Value, t sql.DataType, fixed, body []byte, borrow bool)
+}
 
-		switch v.Type {
-		case sql.TypeInt:
-			if err := binary.Write(w, binary.LittleEndian, v.I64); err != nil {
-				return err
-			}
-		case sql.TypeFloat:
-			if err := binary.Write(w, binary.LittleEndian, v.F64); err != nil {
-				return err
-			}
-		case sql.TypeString:
-			b := []byte(v.S)
-			if len(b) > 0xFFFFFFFF {
-				return fmt.Errorf("string too long")
-			}
-			if err := binary.Write(w, binary.LittleEndian, uint32(len(b))); err != nil {
-				return err
+// maxFixedSize is the largest fixed part of any encoded value.
+const maxFixedSize = 8
+
+var (
+	int64Codec = valueCodec{
+		size: 8,
+		encode: func(dst []byte, v sql.Value) ([]byte, error) {
+			return binary.LittleEndian.AppendUint64(dst, uint64(v.I64)), nil
+		},
+		decode: func(dst *sql.Value, t sql.DataType, fixed, _ []byte, _ bool) {
+			*dst = sql.Value{Type: t, I64: int64(binary.LittleEndian.Uint64(fixed))}
+		},
+	}
+	float64Codec = valueCodec{
+		size: 8,
+		encode: func(dst []byte, v sql.Value) ([]byte, error) {
+			return binary.LittleEndian.AppendUint64(dst, math.Float64bits(v.F64)), nil
+		},
+		decode: func(dst *sql.Value, t sql.DataType, fixed, _ []byte, _ bool) {
+			*dst = sql.Value{Type: t, F64: math.Float64frombits(binary.LittleEndian.Uint64(fixed))}
+		},
+	}
+	// stringCodec stores a uint32 byte length followed by the bytes.
+	stringCodec = valueCodec{
+		size:    4,
+		bodyLen: func(fixed []byte) int { return int(binary.LittleEndian.Uint32(fixed)) },
+		encode: func(dst []byte, v sql.Value) ([]byte, error) {
+			if uint64(len(v.S)) > math.MaxUint32 {
+				return nil, fmt.Errorf("string too long")
 			}
-			if _, err := w.Write(b); err != nil {
-				return err
+			dst = binary.LittleEndian.AppendUint32(dst, uint32(len(v.S)))
+			return append(dst, v.S...), nil
+		},
+		decode: func(dst *sql.Value, t sql.DataType, _, body []byte, borrow bool) {
+			if borrow {
+				*dst = sql.Value{Type: t, S: unsafe.String(unsafe.SliceData(body), len(body))}
+				return
 			}
-		case sql.TypeBool:
-			var b byte
+			*dst = sql.Value{Type: t, S: string(body)}
+		},
+	}
+	boolCodec = valueCodec{
+		size: 1,
+		encode: func(dst []byte, v sql.Value) ([]byte, error) {
 			if v.B {
-				b = 1
-			}
-			if err := binary.Write(w, binary.LittleEndian, b); err != nil {
-				return err
+				return append(dst, 1), nil
 			}
-		case sql.TypeNull:
-			// nothing else to write
-		default:
-			return fmt.Errorf("writeRow: unsupported value type %v", v.Type)
+			return append(dst, 0), nil
+		},
+		decode: func(dst *sql.Value, t sql.DataType, fixed, _ []byte, _ bool) {
+			*dst = sql.Value{Type: t, B: fixed[0] != 0}
+		},
+	}
+	nullCodec = valueCodec{
+		encode: func(dst []byte, _ sql.Value) ([]byte, error) { return dst, nil },
+		decode: func(dst *sql.Value, _ sql.DataType, _, _ []byte, _ bool) {
+			*dst = sql.Value{Type: sql.TypeNull}
+		},
+	}
+)
+
+// valueCodecs maps each type byte to the codec of its values. This is the
+// only place that knows how a type is laid out on disk: a new type adds its
+// codec here and every row reader and writer picks it up.
+var valueCodecs = [...]*valueCodec{
+	sql.TypeInt:       &int64Codec,
+	sql.TypeTimestamp: &int64Codec,
+	sql.TypeFloat:     &float64Codec,
+	sql.TypeString:    &stringCodec,
+	sql.TypeBytes:     &stringCodec,
+	sql.TypeBool:      &boolCodec,
+	sql.TypeNull:      &nullCodec,
+}
+
+// codecFor returns the codec of t, or nil when values of t cannot be stored.
+func codecFor(t sql.DataType) *valueCodec {
+	if t < 0 || int(t) >= len(valueCodecs) {
+		return nil
+	}
+	return valueCodecs[t]
+}
+
+// appendRow appends the encoding of row to dst: every value as its type
+// byte followed by the bytes of its codec.
+func appendRow(dst []byte, row sql.Row) ([]byte, error) {
+	for _, v := range row {
+		var err error
+		if dst, err = appendValue(dst, v); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// appendValue appends the type byte of v and the bytes of its codec.
+func appendValue(dst []byte, v sql.Value) ([]byte, error) {
+	c := codecFor(v.Type)
+	if c == nil {
+		return nil, fmt.Errorf("writeRow: unsupported value type %v", v.Type)
+	}
+	return c.encode(append(dst, uint8(v.Type)), v)
+}
+
+// packedRowMarker starts a row in the packed encoding, which stores BOOL
+// values as bits instead of two bytes each (see Options.PackBools). No type
+// byte has this value, so readers tell the two row encodings apart by the
+// first byte. For a row of n values the marker is followed by two bitmaps
+// of (n+7)/8 bytes: bit i of the first is set when value i is a BOOL, and
+// bit i of the second holds its value. The other values follow in the
+// encoding of appendRow.
+const packedRowMarker = 0xFF
+
+// appendPackedRow appends row in the packed encoding, or like appendRow
+// when packing would not make it shorter.
+func appendPackedRow(dst []byte, row sql.Row) ([]byte, error) {
+	bools := 0
+	for _, v := range row {
+		if v.Type == sql.TypeBool {
+			bools++
+		}
+	}
+	// Each packed BOOL saves two bytes; the marker and bitmaps cost the rest.
+	maskLen := (len(row) + 7) / 8
+	if 2*bools <= 1+2*maskLen {
+		return appendRow(dst, row)
+	}
+
+	dst = append(dst, packedRowMarker)
+	masks := make([]byte, 2*maskLen)
+	for i, v := range row {
+		if v.Type != sql.TypeBool {
+			continue
+		}
+		masks[i/8] |= 1 << (i % 8)
+		if v.B {
+			masks[maskLen+i/8] |= 1 << (i % 8)
 		}
 	}
+	dst = append(dst, masks...)
+	for _, v := range row {
+		if v.Type == sql.TypeBool {
+			continue
+		}
+		var err error
+		if dst, err = appendValue(dst, v); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
 
+// writeRow encodes a row as a sequence of typed values.
+func writeRow(w io.Writer, row sql.Row) error {
+	buf, err := appendRow(nil, row)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// writeRowChecked is writeRow for a row that belongs to a table with the
+// given columns. It refuses rows whose width or value types do not match the
+// schema (NULL is allowed except in NOT NULL columns), so an upstream bug
+// surfaces as a write error rather than as a row that cannot be decoded
+// later.
+func writeRowChecked(w io.Writer, row sql.Row, cols []sql.Column) error {
+	if err := checkRowTypes(row, cols); err != nil {
+		return err
+	}
+	return writeRow(w, row)
+}
+
+// checkRowTypes reports whether row fits the columns cols, including their
+// NOT NULL constraints.
+func checkRowTypes(row sql.Row, cols []sql.Column) error {
+	if len(row) != len(cols) {
+		return fmt.Errorf("row has %d values, expected %d", len(row), len(cols))
+	}
+	for i, v := range row {
+		if v.Type == sql.TypeNull && cols[i].NotNull {
+			return fmt.Errorf("%w: column %q is NULL", sql.ErrNotNull, cols[i].Name)
+		}
+		if v.Type != sql.TypeNull && v.Type != cols[i].Type {
+			return fmt.Errorf("%w: column %q is %s but value is %s", sql.ErrTypeMismatch, cols[i].Name, cols[i].Type, v.Type)
+		}
+	}
 	return nil
 }
 
@@ -141,61 +415,39 @@ func writeRow(w io.Writer, row sql.Row) error {
 // Returns io.EOF when there is no more data.
 func readRow(r io.Reader, numCols int) (sql.Row, error) {
 	row := make(sql.Row, numCols)
+	var fixed [maxFixedSize]byte
 
 	for i := 0; i < numCols; i++ {
-		var t uint8
-		if err := binary.Read(r, binary.LittleEndian, &t); err != nil {
+		if _, err := io.ReadFull(r, fixed[:1]); err != nil {
 			if err == io.EOF || err == io.ErrUnexpectedEOF {
 				// if we hit EOF at first column, propagate EOF;
 				// if we hit mid-row, treat as error.
 				if i == 0 {
 					return nil, io.EOF
 				}
-				return nil, fmt.Errorf("readRow: truncated row")
+				return nil, fmt.Errorf("readRow: truncated row: %w", io.ErrUnexpectedEOF)
 			}
 			return nil, err
 		}
-		vt := sql.DataType(t)
-
-		switch vt {
-		case sql.TypeInt:
-			var v int64
-			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
-				return nil, err
-			}
-			row[i] = sql.Value{Type: sql.TypeInt, I64: v}
-
-		case sql.TypeFloat:
-			var v float64
-			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
-				return nil, err
-			}
-			row[i] = sql.Value{Type: sql.TypeFloat, F64: v}
-
-		case sql.TypeString:
-			var l uint32
-			if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
-				return nil, err
-			}
-			buf := make([]byte, l)
-			if _, err := io.ReadFull(r, buf); err != nil {
-				return nil, err
-			}
-			row[i] = sql.Value{Type: sql.TypeString, S: string(buf)}
+		vt := sql.DataType(fixed[0])
+		c := codecFor(vt)
+		if c == nil {
+			return nil, fmt.Errorf("readRow: unsupported value type %v", vt)
+		}
 
-		case sql.TypeBool:
-			var b byte
-			if err := binary.Read(r, binary.LittleEndian, &b); err != nil {
+		head := fixed[:c.size]
+		if _, err := io.ReadFull(r, head); err != nil {
+			return nil, err
+		}
+		var body []byte
+		if c.bodyLen != nil {
+			body = make([]byte, c.bodyLen(head))
+			if _, err := io.ReadFull(r, body); err != nil {
 				return nil, err
 			}
-			row[i] = sql.Value{Type: sql.TypeBool, B: b != 0}
-
-		case sql.TypeNull:
-			row[i] = sql.Value{Type: sql.TypeNull}
-
-		default:
-			return nil, fmt.Errorf("readRow: unsupported value type %v", vt)
 		}
+		// body is not reused, so strings can keep it instead of a copy.
+		c.decode(&row[i], vt, head, body, true)
 	}
 
 	return row, nil
@@ -203,107 +455,81 @@ func readRow(r io.Reader, numCols int) (sql.Row, error) {
 
 // readRowFromBytes decodes a row from a byte slice, given numCols.
 // It's the same encoding as readRow, but works on a buffer instead of io.Reader.
+// The returned row is freshly allocated and does not reference buf.
 func readRowFromBytes(buf []byte, numCols int) (sql.Row, error) {
 	row := make(sql.Row, numCols)
-	offset := 0
-
-	readByte := func() (byte, error) {
-		if offset >= len(buf) {
-			return 0, fmt.Errorf("readRowFromBytes: unexpected end of buffer")
-		}
-		b := buf[offset]
-		offset++
-		return b, nil
+	if err := decodeRowInto(row, buf, false); err != nil {
+		return nil, err
 	}
+	return row, nil
+}
 
-	_ = func() (uint16, error) {
-		if offset+2 > len(buf) {
-			return 0, fmt.Errorf("readRowFromBytes: unexpected end of buffer")
+// decodeRowInto decodes a row from buf into dst, which must have one element
+// per column. It allocates nothing except string contents, which makes it
+// suitable for reusing one row buffer across a scan.
+//
+// With borrow set, string values reference buf directly instead of being
+// copied. The caller must then stop using dst's strings before buf is reused
+// or modified.
+func decodeRowInto(dst sql.Row, buf []byte, borrow bool) error {
+	offset := 0
+	var bools, values []byte // bitmaps of a packed row
+	if len(buf) > 0 && buf[0] == packedRowMarker {
+		maskLen := (len(dst) + 7) / 8
+		if 1+2*maskLen > len(buf) {
+			return fmt.Errorf("readRowFromBytes: unexpected end of buffer")
 		}
-		v := binary.LittleEndian.Uint16(buf[offset : offset+2])
-		offset += 2
-		return v, nil
+		bools, values = buf[1:1+maskLen], buf[1+maskLen:1+2*maskLen]
+		offset = 1 + 2*maskLen
 	}
-
-	readUint32 := func() (uint32, error) {
-		if offset+4 > len(buf) {
-			return 0, fmt.Errorf("readRowFromBytes: unexpected end of buffer")
+	for i := range dst {
+		if bools != nil && bools[i/8]&(1<<(i%8)) != 0 {
+			dst[i] = sql.Value{Type: sql.TypeBool, B: values[i/8]&(1<<(i%8)) != 0}
+			continue
 		}
-		v := binary.LittleEndian.Uint32(buf[offset : offset+4])
-		offset += 4
-		return v, nil
-	}
-
-	readInt64 := func() (int64, error) {
-		if offset+8 > len(buf) {
-			return 0, fmt.Errorf("readRowFromBytes: unexpected end of buffer")
+		if offset >= len(buf) {
+			return fmt.Errorf("readRowFromBytes: unexpected end of buffer")
 		}
-		v := int64(binary.LittleEndian.Uint64(buf[offset : offset+8]))
-		offset += 8
-		return v, nil
-	}
-
-	readFloat64 := func() (float64, error) {
-		if offset+8 > len(buf) {
-			return 0, fmt.Errorf("readRowFromBytes: unexpected end of buffer")
+		vt := sql.DataType(buf[offset])
+		offset++
+		c := codecFor(vt)
+		if c == nil {
+			return fmt.Errorf("readRowFromBytes: unsupported type %v", vt)
 		}
-		bits := binary.LittleEndian.Uint64(buf[offset : offset+8])
-		offset += 8
-		return math.Float64frombits(bits), nil
-	}
 
-	for i := 0; i < numCols; i++ {
-		tByte, err := readByte()
-		if err != nil {
-			return nil, err
+		if c.size > len(buf)-offset {
+			return fmt.Errorf("readRowFromBytes: unexpected end of buffer")
 		}
-		vt := sql.DataType(tByte)
-
-		switch vt {
-		case sql.TypeInt:
-			v, err := readInt64()
-			if err != nil {
-				return nil, err
-			}
-			row[i] = sql.Value{Type: sql.TypeInt, I64: v}
-		case sql.TypeFloat:
-			v, err := readFloat64()
-			if err != nil {
-				return nil, err
-			}
-			row[i] = sql.Value{Type: sql.TypeFloat, F64: v}
-		case sql.TypeString:
-			l, err := readUint32()
-			if err != nil {
-				return nil, err
-			}
-			if offset+int(l) > len(buf) {
-				return nil, fmt.Errorf("readRowFromBytes: invalid string length")
-			}
-			s := string(buf[offset : offset+int(l)])
-			offset += int(l)
-			row[i] = sql.Value{Type: sql.TypeString, S: s}
-		case sql.TypeBool:
-			b, err := readByte()
-			if err != nil {
-				return nil, err
+		fixed := buf[offset : offset+c.size]
+		offset += c.size
+		var body []byte
+		if c.bodyLen != nil {
+			l := c.bodyLen(fixed)
+			if l > len(buf)-offset {
+				return fmt.Errorf("readRowFromBytes: invalid %v length", vt)
 			}
-			row[i] = sql.Value{Type: sql.TypeBool, B: b != 0}
-		case sql.TypeNull:
-			row[i] = sql.Value{Type: sql.TypeNull}
-		default:
-			return nil, fmt.Errorf("readRowFromBytes: unsupported type %v", vt)
+			body = buf[offset : offset+l]
+			offset += l
 		}
+		c.decode(&dst[i], vt, fixed, body, borrow)
 	}
 
-	return row, nil
+	return nil
 }
 
-// encodeRowToBytes encodes a row into a byte slice using the same format as writeRow.
-func encodeRowToBytes(row sql.Row) ([]byte, error) {
-	var buf bytes.Buffer
-	if err := writeRow(&buf, row); err != nil {
+// encodeRowCheckedToBytes encodes row with writeRowChecked, in the packed
+// encoding when packBools is set.
+func encodeRowCheckedToBytes(row sql.Row, cols []sql.Column, packBools bool) ([]byte, error) {
+	if err := checkRowTypes(row, cols); err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	if packBools {
+		return appendPackedRow(nil, row)
+	}
+	return appendRow(nil, row)
+}
+
+// encodeRowToBytes encodes a row into a byte slice using the same format as writeRow.
+func encodeRowToBytes(row sql.Row) ([]byte, error) {
+	return appendRow(nil, row)
 }
@@ -0,0 +1,396 @@
+package filestore
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"goDB/internal/sql"
+)
+
+var decodeTestRow = sql.Row{
+	{Type: sql.TypeInt, I64: 42},
+	{Type: sql.TypeString, S: "Alice"},
+	{Type: sql.TypeFloat, F64: 1.5},
+	{Type: sql.TypeBool, B: true},
+	{Type: sql.TypeNull},
+	{Type: sql.TypeString, S: strings.Repeat("x", 64)},
+	sql.TimestampValue(time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)),
+	{Type: sql.TypeBytes, S: "\x00\xff\xfe"},
+}
+
+func TestDecodeRowInto(t *testing.T) {
+	buf := encodeRow(t, decodeTestRow)
+
+	for _, borrow := range []bool{false, true} {
+		dst := make(sql.Row, len(decodeTestRow))
+		if err := decodeRowInto(dst, buf, borrow); err != nil {
+			t.Fatalf("borrow=%v: decodeRowInto failed: %v", borrow, err)
+		}
+		if !equalRow(dst, decodeTestRow) {
+			t.Fatalf("borrow=%v: got %v, want %v", borrow, dst, decodeTestRow)
+		}
+	}
+
+	// Copied strings are independent of the buffer; borrowed ones are not.
+	copied := make(sql.Row, len(decodeTestRow))
+	borrowed := make(sql.Row, len(decodeTestRow))
+	_ = decodeRowInto(copied, buf, false)
+	_ = decodeRowInto(borrowed, buf, true)
+	idx := strings.Index(string(buf), "Alice")
+	buf[idx] = 'E'
+	if copied[1].S != "Alice" {
+		t.Fatalf("copied string changed to %q", copied[1].S)
+	}
+	if borrowed[1].S != "Elice" {
+		t.Fatalf("borrowed string = %q, want it to alias the buffer", borrowed[1].S)
+	}
+
+	if err := decodeRowInto(make(sql.Row, len(decodeTestRow)), buf[:len(buf)-1], false); err == nil {
+		t.Fatalf("expected error for truncated buffer")
+	}
+}
+
+// TestRowEncoding pins the on-disk layout of every value type, so existing
+// table and WAL files stay readable.
+func TestRowEncoding(t *testing.T) {
+	row := sql.Row{
+		{Type: sql.TypeInt, I64: -2},
+		{Type: sql.TypeFloat, F64: 1.5},
+		{Type: sql.TypeString, S: "hi"},
+		{Type: sql.TypeBool, B: true},
+		{Type: sql.TypeNull},
+		{Type: sql.TypeTimestamp, I64: 1},
+		{Type: sql.TypeBytes, S: "\xff"},
+	}
+	want := []byte{
+		byte(sql.TypeInt), 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		byte(sql.TypeFloat), 0, 0, 0, 0, 0, 0, 0xf8, 0x3f,
+		byte(sql.TypeString), 2, 0, 0, 0, 'h', 'i',
+		byte(sql.TypeBool), 1,
+		byte(sql.TypeNull),
+		byte(sql.TypeTimestamp), 1, 0, 0, 0, 0, 0, 0, 0,
+		byte(sql.TypeBytes), 1, 0, 0, 0, 0xff,
+	}
+
+	var buf bytes.Buffer
+	if err := writeRow(&buf, row); err != nil {
+		t.Fatalf("writeRow failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("encoded\n%x\nwant\n%x", buf.Bytes(), want)
+	}
+
+	got, err := readRow(bytes.NewReader(want), len(row))
+	if err != nil {
+		t.Fatalf("readRow failed: %v", err)
+	}
+	if !equalRow(got, row) {
+		t.Fatalf("readRow got %v, want %v", got, row)
+	}
+	if _, err := readRow(bytes.NewReader(want[:len(want)-1]), len(row)); err == nil {
+		t.Fatalf("expected error for truncated row")
+	}
+	if _, err := encodeRowToBytes(sql.Row{{Type: sql.DataType(99)}}); err == nil {
+		t.Fatalf("expected error for unsupported type")
+	}
+}
+
+// TestPackedRowEncoding pins the packed layout of Options.PackBools and
+// checks that rows it would not shorten keep the plain encoding.
+func TestPackedRowEncoding(t *testing.T) {
+	tr, fa := sql.Value{Type: sql.TypeBool, B: true}, sql.Value{Type: sql.TypeBool, B: false}
+	row := sql.Row{tr, {Type: sql.TypeInt, I64: 7}, fa, tr, {Type: sql.TypeNull}, tr}
+	want := []byte{
+		packedRowMarker,
+		0b101101, // values 0, 2, 3 and 5 are BOOLs
+		0b101001, // of which 0, 3 and 5 are true
+		byte(sql.TypeInt), 7, 0, 0, 0, 0, 0, 0, 0,
+		byte(sql.TypeNull),
+	}
+	cols := make([]sql.Column, len(row))
+	for i := range cols {
+		cols[i] = sql.Column{Name: string(rune('a' + i)), Type: sql.TypeBool}
+	}
+	cols[1].Type = sql.TypeInt
+	got, err := encodeRowCheckedToBytes(row, cols, true)
+	if err != nil {
+		t.Fatalf("encodeRowCheckedToBytes failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("encoded\n%x\nwant\n%x", got, want)
+	}
+	plain, _ := encodeRowToBytes(row)
+	if len(got) >= len(plain) {
+		t.Fatalf("packed row is %d bytes, plain %d", len(got), len(plain))
+	}
+
+	for _, borrow := range []bool{false, true} {
+		dst := make(sql.Row, len(row))
+		if err := decodeRowInto(dst, got, borrow); err != nil {
+			t.Fatalf("decodeRowInto failed: %v", err)
+		}
+		if !equalRow(dst, row) {
+			t.Fatalf("decoded %v, want %v", dst, row)
+		}
+	}
+	for _, n := range []int{1, 3, len(got) - 1} {
+		if err := decodeRowInto(make(sql.Row, len(row)), got[:n], false); err == nil {
+			t.Fatalf("expected error for a packed row cut to %d bytes", n)
+		}
+	}
+
+	// One BOOL saves less than the bitmaps cost.
+	few := decodeTestRow
+	if got, _ := appendPackedRow(nil, few); !bytes.Equal(got, encodeRow(t, few)) {
+		t.Fatalf("row with one BOOL was packed: %x", got)
+	}
+}
+
+// boolCols and boolRows make a table of an id and eight BOOL flags, with
+// some flags NULL.
+var boolCols = func() []sql.Column {
+	cols := []sql.Column{{Name: "id", Type: sql.TypeInt}}
+	for i := 0; i < 8; i++ {
+		cols = append(cols, sql.Column{Name: fmt.Sprintf("f%d", i), Type: sql.TypeBool})
+	}
+	return cols
+}()
+
+func boolRows(n int) []sql.Row {
+	rows := make([]sql.Row, n)
+	for i := range rows {
+		rows[i] = sql.Row{{Type: sql.TypeInt, I64: int64(i)}}
+		for j := 0; j < 8; j++ {
+			v := sql.Value{Type: sql.TypeBool, B: (i>>j)&1 == 1}
+			if (i+j)%7 == 0 {
+				v = sql.Value{Type: sql.TypeNull}
+			}
+			rows[i] = append(rows[i], v)
+		}
+	}
+	return rows
+}
+
+func TestFilestore_PackBools(t *testing.T) {
+	sizes := map[bool]int64{}
+	for _, pack := range []bool{false, true} {
+		dir := t.TempDir()
+		fs, err := NewWithOptions(dir, Options{PackBools: pack})
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if err := fs.CreateTable("flags", boolCols); err != nil {
+			t.Fatalf("CreateTable failed: %v", err)
+		}
+		want := boolRows(2000)
+		tx, _ := fs.Begin(false)
+		if err := tx.InsertBatch("flags", want); err != nil {
+			t.Fatalf("InsertBatch failed: %v", err)
+		}
+		flip := func(r sql.Row) (sql.Row, error) {
+			r[1] = sql.Value{Type: sql.TypeBool, B: !r[1].B}
+			return r, nil
+		}
+		if err := tx.UpdateWhere("flags", func(r sql.Row) (bool, error) { return r[0].I64%10 == 0, nil }, flip); err != nil {
+			t.Fatalf("UpdateWhere failed: %v", err)
+		}
+		if err := fs.Commit(tx); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		for i := 0; i < len(want); i += 10 {
+			want[i][1] = sql.Value{Type: sql.TypeBool, B: !want[i][1].B}
+		}
+		st, err := fs.TableStats("flags")
+		if err != nil {
+			t.Fatalf("TableStats failed: %v", err)
+		}
+		sizes[pack] = st.Bytes
+		fs.Close()
+
+		// Rows read back the same whether or not the reader packs.
+		fs, err = NewWithOptions(dir, Options{PackBools: !pack})
+		if err != nil {
+			t.Fatalf("reopen failed: %v", err)
+		}
+		rtx, _ := fs.Begin(true)
+		_, got, err := rtx.Scan("flags")
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("pack=%v: rows read back differ", pack)
+		}
+		fs.Close()
+	}
+	if sizes[true]*4 > sizes[false]*3 {
+		t.Fatalf("packed table is %d bytes, plain %d; want a quarter less", sizes[true], sizes[false])
+	}
+}
+
+func TestHeader_Defaults(t *testing.T) {
+	def := sql.Value{Type: sql.TypeString, S: "n/a"}
+	cols := []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "note", Type: sql.TypeString, Default: &def},
+		{Name: "created", Type: sql.TypeTimestamp, DefaultCurrentTimestamp: true},
+		{Name: "user_id", Type: sql.TypeInt, References: &sql.ForeignKey{Table: "users", Column: "id"}},
+		{Name: "email", Type: sql.TypeString, NoCase: true},
+	}
+
+	var buf bytes.Buffer
+	if err := writeHeader(&buf, cols); err != nil {
+		t.Fatalf("writeHeader failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte(fileMagicV2)) {
+		t.Fatalf("expected %s header, got %q", fileMagicV2, buf.Bytes()[:5])
+	}
+	got, err := readHeader(&buf)
+	if err != nil {
+		t.Fatalf("readHeader failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, cols) {
+		t.Fatalf("got %+v, want %+v", got, cols)
+	}
+
+	// Tables without defaults keep the original format.
+	buf.Reset()
+	if err := writeHeader(&buf, cols[:1]); err != nil {
+		t.Fatalf("writeHeader failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte(fileMagic)) {
+		t.Fatalf("expected %s header, got %q", fileMagic, buf.Bytes()[:5])
+	}
+}
+
+// BenchmarkDecodeRow compares allocating a row per decode with decoding into
+// a reused buffer, with and without borrowing strings from the page.
+func BenchmarkDecodeRow(b *testing.B) {
+	buf, err := encodeRowToBytes(decodeTestRow)
+	if err != nil {
+		b.Fatalf("encode: %v", err)
+	}
+
+	b.Run("alloc", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := readRowFromBytes(buf, len(decodeTestRow)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("into", func(b *testing.B) {
+		b.ReportAllocs()
+		dst := make(sql.Row, len(decodeTestRow))
+		for i := 0; i < b.N; i++ {
+			if err := decodeRowInto(dst, buf, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("borrow", func(b *testing.B) {
+		b.ReportAllocs()
+		dst := make(sql.Row, len(decodeTestRow))
+		for i := 0; i < b.N; i++ {
+			if err := decodeRowInto(dst, buf, true); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkScan(b *testing.B) {
+	fs, err := New(b.TempDir())
+	if err != nil {
+		b.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		b.Fatalf("CreateTable failed: %v", err)
+	}
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", batchRows(10000)); err != nil {
+		b.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		b.Fatalf("Commit failed: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rtx, _ := fs.Begin(true)
+		if _, _, err := rtx.Scan("t"); err != nil {
+			b.Fatalf("Scan failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkBoolTable inserts and scans a table of eight BOOL columns with
+// and without Options.PackBools, reporting the table size per row.
+func BenchmarkBoolTable(b *testing.B) {
+	rows := boolRows(10000)
+	for _, pack := range []bool{false, true} {
+		b.Run(fmt.Sprintf("pack=%v", pack), func(b *testing.B) {
+			fs, err := NewWithOptions(b.TempDir(), Options{PackBools: pack})
+			if err != nil {
+				b.Fatalf("New failed: %v", err)
+			}
+			defer fs.Close()
+			if err := fs.CreateTable("flags", boolCols); err != nil {
+				b.Fatalf("CreateTable failed: %v", err)
+			}
+			tx, _ := fs.Begin(false)
+			if err := tx.InsertBatch("flags", rows); err != nil {
+				b.Fatalf("InsertBatch failed: %v", err)
+			}
+			if err := fs.Commit(tx); err != nil {
+				b.Fatalf("Commit failed: %v", err)
+			}
+			st, err := fs.TableStats("flags")
+			if err != nil {
+				b.Fatalf("TableStats failed: %v", err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rtx, _ := fs.Begin(true)
+				if _, _, err := rtx.Scan("flags"); err != nil {
+					b.Fatalf("Scan failed: %v", err)
+				}
+			}
+			b.ReportMetric(float64(st.Bytes)/float64(len(rows)), "bytes/row")
+		})
+	}
+}
+
+func TestWriteRowChecked(t *testing.T) {
+	cols := []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "name", Type: sql.TypeString},
+	}
+	tests := []struct {
+		name    string
+		row     sql.Row
+		wantErr bool
+	}{
+		{"matching", sql.Row{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "a"}}, false},
+		{"null allowed", sql.Row{{Type: sql.TypeNull}, {Type: sql.TypeNull}}, false},
+		{"wrong type", sql.Row{{Type: sql.TypeString, S: "1"}, {Type: sql.TypeString, S: "a"}}, true},
+		{"too short", sql.Row{{Type: sql.TypeInt, I64: 1}}, true},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := writeRowChecked(&buf, tt.row, cols)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr && buf.Len() != 0 {
+			t.Fatalf("%s: %d bytes written for a rejected row", tt.name, buf.Len())
+		}
+	}
+}
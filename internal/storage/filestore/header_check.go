@@ -0,0 +1,70 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// checkTableHeaders reads the header of every table file when the engine
+// opens, so that a damaged file is reported by name right away instead of
+// failing whichever statement happens to touch it first. All bad files are
+// listed in one error.
+func (e *FileEngine) checkTableHeaders() error {
+	tables, err := e.ListTables()
+	if err != nil {
+		return err
+	}
+
+	var bad []string
+	for _, t := range tables {
+		path, err := e.tablePath(t)
+		if err == nil {
+			err = checkTableHeader(path)
+		}
+		if err != nil {
+			bad = append(bad, fmt.Sprintf("table %s (%s): corrupt header: %v", t, filepath.Base(path), err))
+		}
+	}
+	if len(bad) > 0 {
+		return fmt.Errorf("filestore: %s", strings.Join(bad, "; "))
+	}
+	return nil
+}
+
+// checkTableHeader verifies that the file at path starts with a complete,
+// decodable table header describing at least one column of a known type.
+func checkTableHeader(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cols, err := readHeader(f)
+	switch {
+	case errors.Is(err, errBadTableMagic):
+		return errBadTableMagic
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("file is truncated")
+	case err != nil:
+		return err
+	}
+
+	if len(cols) == 0 {
+		return fmt.Errorf("no columns")
+	}
+	for _, c := range cols {
+		switch c.Type {
+		case sql.TypeInt, sql.TypeFloat, sql.TypeString, sql.TypeBool, sql.TypeTimestamp, sql.TypeBytes:
+		default:
+			return fmt.Errorf("column %q has unknown type %d", c.Name, int(c.Type))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package filestore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"goDB/internal/index/btree"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// indexesByColumn returns the indexes of a table keyed by the position of
+// their column in cols.
+func (e *FileEngine) indexesByColumn(tableName string, cols []sql.Column) (map[int]*indexInfo, error) {
+	e.idxMu.RLock()
+	defer e.idxMu.RUnlock()
+
+	byCol := make(map[int]*indexInfo)
+	for colName, info := range e.indexes[tableName] {
+		colIdx := -1
+		for i, c := range cols {
+			if strings.EqualFold(c.Name, colName) {
+				colIdx = i
+				break
+			}
+		}
+		if colIdx == -1 {
+			return nil, fmt.Errorf("filestore: index on %w %q for table %q", sql.ErrColumnNotFound, colName, tableName)
+		}
+		byCol[colIdx] = info
+	}
+	return byCol, nil
+}
+
+// resolveIndexFile finds the table and column of an index file. Files named
+// by btree.IndexFileName parse directly. Legacy table_column.idx files are
+// matched against the tables instead, since table and column names may
+// themselves contain underscores: every split of the name is tried and the
+// one naming an existing table and one of its columns wins. ok is false for
+// a legacy file that matches no table, such as one left behind by a dropped
+// table; one that matches several is an error.
+func (e *FileEngine) resolveIndexFile(name string) (table, column string, ok bool, err error) {
+	if table, column, ok := btree.ParseIndexFileName(name); ok {
+		return table, column, true, nil
+	}
+	base := strings.TrimSuffix(name, ".idx")
+	var matches []string
+	for i := 1; i < len(base)-1; i++ {
+		if base[i] != '_' {
+			continue
+		}
+		t, c := base[:i], base[i+1:]
+		cols, err := e.TableSchema(t)
+		if err != nil {
+			continue
+		}
+		for _, col := range cols {
+			if strings.EqualFold(col.Name, c) {
+				table, column = t, c
+				matches = append(matches, t+"."+c)
+				break
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", "", false, nil
+	case 1:
+		return table, column, true, nil
+	default:
+		return "", "", false, fmt.Errorf("filestore: index file %s is ambiguous: it may belong to %s; rename it to table.column.idx", name, strings.Join(matches, " and "))
+	}
+}
+
+// reindexRow moves the index entries of the row stored at rid from the keys
+// of oldRow to those of newRow. Either row may be nil, for a row that is
+// being inserted or deleted. NULL keys are not indexed.
+func reindexRow(indexes map[int]*indexInfo, rid btree.RID, oldRow, newRow sql.Row) error {
+	for colIdx, idx := range indexes {
+		oldVal := sql.Value{Type: sql.TypeNull}
+		newVal := sql.Value{Type: sql.TypeNull}
+		if oldRow != nil {
+			oldVal = oldRow[colIdx]
+		}
+		if newRow != nil {
+			newVal = newRow[colIdx]
+		}
+		if oldVal.Type == newVal.Type && oldVal.I64 == newVal.I64 {
+			continue
+		}
+
+		if oldVal.Type != sql.TypeNull {
+			if err := idx.btree.Delete(oldVal.I64, rid); err != nil {
+				return fmt.Errorf("filestore: update index %q: %w", idx.name, err)
+			}
+		}
+		if newVal.Type != sql.TypeNull {
+			if err := idx.btree.Insert(newVal.I64, rid); err != nil {
+				return fmt.Errorf("filestore: update index %q: %w", idx.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ScanOrdered implements storage.OrderedScanner by reading rows in the order
+// of the index on column. It gives up (ok is false) when the index holds a
+// different number of entries than the table has rows, which is the case
+// when some rows have a NULL key, or when an entry does not match its row.
+func (tx *fileTx) ScanOrdered(tableName, column string, desc bool, pred storage.RowPredicate) ([]string, []sql.Row, bool, error) {
+	if tx.closed {
+		return nil, nil, false, fmt.Errorf("filestore: tx is closed")
+	}
+	defer tx.scanLock(tableName)()
+
+	path, err := tx.eng.tablePath(tableName)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("filestore: open table for ordered scan: %w", missingTable(tableName, err))
+	}
+	defer f.Close()
+
+	cols, err := readHeader(f)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("filestore: read header in ordered scan: %w", err)
+	}
+	headerEnd, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("filestore: seek after header: %w", err)
+	}
+
+	indexes, err := tx.eng.indexesByColumn(tableName, cols)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	colIdx := -1
+	for i, c := range cols {
+		if strings.EqualFold(c.Name, column) {
+			colIdx = i
+			break
+		}
+	}
+	idx, ok := indexes[colIdx]
+	if !ok {
+		return nil, nil, false, nil
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("filestore: stat table in ordered scan: %w", err)
+	}
+	dataBytes := fi.Size() - headerEnd
+	if dataBytes < 0 || dataBytes%PageSize != 0 {
+		return nil, nil, false, fmt.Errorf("filestore: corrupt data (not multiple of page size)")
+	}
+	numPages := uint32(dataBytes / PageSize)
+
+	// Count the live rows from the slot directories, without decoding them.
+	pages := make([]pageBuf, numPages)
+	live := 0
+	for pageID := range pages {
+		p, err := tx.eng.readPage(tableName, f, headerEnd, uint32(pageID))
+		if err != nil {
+			return nil, nil, false, err
+		}
+		pages[pageID] = p
+		for i := uint16(0); i < p.numSlots(); i++ {
+			if off, length := p.getSlot(i); off != 0xFFFF && length != 0 {
+				live++
+			}
+		}
+	}
+
+	type entry struct {
+		key btree.Key
+		rid btree.RID
+	}
+	var entries []entry
+	if err := idx.btree.Ascend(func(key btree.Key, rid btree.RID) bool {
+		entries = append(entries, entry{key, rid})
+		return len(entries) <= live
+	}); err != nil {
+		return nil, nil, false, fmt.Errorf("filestore: read index %q: %w", idx.name, err)
+	}
+	if len(entries) != live {
+		return nil, nil, false, nil
+	}
+
+	// Ascend yields equal keys in the order they sit in the leaves; put
+	// them back in table order.
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.key != b.key {
+			return (a.key < b.key) != desc
+		}
+		if a.rid.PageID != b.rid.PageID {
+			return a.rid.PageID < b.rid.PageID
+		}
+		return a.rid.SlotID < b.rid.SlotID
+	})
+
+	colNames := make([]string, len(cols))
+	for i, c := range cols {
+		colNames[i] = c.Name
+	}
+
+	var rows []sql.Row
+	for _, ent := range entries {
+		if ent.rid.PageID >= numPages {
+			return nil, nil, false, nil
+		}
+		p := pages[ent.rid.PageID]
+		if ent.rid.SlotID >= p.numSlots() {
+			return nil, nil, false, nil
+		}
+		off, length := p.getSlot(ent.rid.SlotID)
+		if off == 0xFFFF || length == 0 || int(off)+int(length) > len(p) {
+			return nil, nil, false, nil
+		}
+		row, err := readRowFromBytes(p[off:off+length], len(cols))
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("filestore: read row in ordered scan: %w", err)
+		}
+		if key := row[colIdx]; key.Type == sql.TypeNull || key.I64 != ent.key {
+			return nil, nil, false, nil
+		}
+
+		if pred != nil {
+			match, err := pred(row)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if !match {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+	return colNames, rows, true, nil
+}
+
+// ScanRange implements storage.RangeScanner. Only the pages holding a row
+// the index places in [lo, hi] are read. A row with a NULL key never falls
+// in a range, so unlike ScanOrdered this does not need the index to cover
+// every row; it gives up (ok is false) when an entry does not match its row.
+func (tx *fileTx) ScanRange(tableName, column string, lo, hi int64, pred storage.RowPredicate) ([]string, []sql.Row, bool, error) {
+	cols, rows, _, ok, err := tx.scanRange(tableName, column, lo, hi, pred)
+	return cols, rows, ok, err
+}
+
+// ScanRangeRowIDs implements storage.RowIDRangeScanner like ScanRange,
+// returning the rowid of each row too.
+func (tx *fileTx) ScanRangeRowIDs(tableName, column string, lo, hi int64) ([]string, []sql.Row, []int64, bool, error) {
+	cols, rows, rids, ok, err := tx.scanRange(tableName, column, lo, hi, nil)
+	if err != nil || !ok {
+		return nil, nil, nil, ok, err
+	}
+	ids := make([]int64, len(rids))
+	for i, rid := range rids {
+		ids[i] = rowID(rid)
+	}
+	return cols, rows, ids, true, nil
+}
+
+// scanRange reads the rows of ScanRange and the RIDs they are stored at.
+func (tx *fileTx) scanRange(tableName, column string, lo, hi int64, pred storage.RowPredicate) ([]string, []sql.Row, []btree.RID, bool, error) {
+	if tx.closed {
+		return nil, nil, nil, false, fmt.Errorf("filestore: tx is closed")
+	}
+	defer tx.scanLock(tableName)()
+
+	path, err := tx.eng.tablePath(tableName)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, false, fmt.Errorf("filestore: open table for range scan: %w", missingTable(tableName, err))
+	}
+	defer f.Close()
+
+	cols, err := readHeader(f)
+	if err != nil {
+		return nil, nil, nil, false, fmt.Errorf("filestore: read header in range scan: %w", err)
+	}
+	headerEnd, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, nil, false, fmt.Errorf("filestore: seek after header: %w", err)
+	}
+
+	indexes, err := tx.eng.indexesByColumn(tableName, cols)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+	colIdx := -1
+	for i, c := range cols {
+		if strings.EqualFold(c.Name, column) {
+			colIdx = i
+			break
+		}
+	}
+	idx, ok := indexes[colIdx]
+	if !ok {
+		return nil, nil, nil, false, nil
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, nil, false, fmt.Errorf("filestore: stat table in range scan: %w", err)
+	}
+	dataBytes := fi.Size() - headerEnd
+	if dataBytes < 0 || dataBytes%PageSize != 0 {
+		return nil, nil, nil, false, fmt.Errorf("filestore: corrupt data (not multiple of page size)")
+	}
+	numPages := uint32(dataBytes / PageSize)
+
+	rids, err := idx.btree.SearchRange(lo, hi)
+	if err != nil {
+		return nil, nil, nil, false, fmt.Errorf("filestore: read index %q: %w", idx.name, err)
+	}
+
+	colNames := make([]string, len(cols))
+	for i, c := range cols {
+		colNames[i] = c.Name
+	}
+
+	// The RIDs come sorted by page and slot, so each page is read once.
+	var rows []sql.Row
+	var matched []btree.RID
+	var p pageBuf
+	loaded := false
+	var loadedID uint32
+	for _, rid := range rids {
+		if rid.PageID >= numPages {
+			return nil, nil, nil, false, nil
+		}
+		if !loaded || rid.PageID != loadedID {
+			if p, err = tx.eng.readPage(tableName, f, headerEnd, rid.PageID); err != nil {
+				return nil, nil, nil, false, err
+			}
+			loaded, loadedID = true, rid.PageID
+		}
+		if rid.SlotID >= p.numSlots() {
+			return nil, nil, nil, false, nil
+		}
+		off, length := p.getSlot(rid.SlotID)
+		if off == 0xFFFF || length == 0 || int(off)+int(length) > len(p) {
+			return nil, nil, nil, false, nil
+		}
+		row, err := readRowFromBytes(p[off:off+length], len(cols))
+		if err != nil {
+			return nil, nil, nil, false, fmt.Errorf("filestore: read row in range scan: %w", err)
+		}
+		if key := row[colIdx]; key.Type == sql.TypeNull || key.I64 < lo || key.I64 > hi {
+			return nil, nil, nil, false, nil
+		}
+
+		if pred != nil {
+			match, err := pred(row)
+			if err != nil {
+				return nil, nil, nil, false, err
+			}
+			if !match {
+				continue
+			}
+		}
+		rows = append(rows, row)
+		matched = append(matched, rid)
+	}
+	return colNames, rows, matched, true, nil
+}
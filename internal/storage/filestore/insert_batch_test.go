@@ -0,0 +1,171 @@
+package filestore
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"goDB/internal/sql"
+)
+
+func batchRows(n int) []sql.Row {
+	rows := make([]sql.Row, n)
+	for i := range rows {
+		rows[i] = sql.Row{
+			{Type: sql.TypeInt, I64: int64(i)},
+			{Type: sql.TypeString, S: fmt.Sprintf("name-%04d-%s", i, strings.Repeat("x", 40))},
+		}
+	}
+	return rows
+}
+
+var batchCols = []sql.Column{
+	{Name: "id", Type: sql.TypeInt},
+	{Name: "name", Type: sql.TypeString},
+}
+
+func TestFilestore_InsertBatch(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if err := fs.CreateIndex("idx_t_id", "t", "id"); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+
+	// Enough rows to spill over several pages.
+	want := batchRows(300)
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", want[:1]); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := tx.InsertBatch("t", want[1:]); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	rids, err := fs.indexes["t"]["id"].btree.Search(299)
+	if err != nil || len(rids) != 1 || rids[0].PageID == 0 {
+		t.Fatalf("index lookup for last row: rids=%v err=%v", rids, err)
+	}
+
+	// Rows survive WAL replay.
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	reopened, err := New(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+
+	rtx, _ := reopened.Begin(true)
+	_, got, err := rtx.Scan("t")
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !equalRow(got[i], want[i]) {
+			t.Fatalf("row %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFilestore_InsertBatchValidatesFirst(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	rows := batchRows(3)
+	rows[2] = rows[2][:1] // wrong column count
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", rows); err == nil {
+		t.Fatalf("expected error for short row")
+	}
+
+	huge := sql.Row{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: strings.Repeat("x", PageSize)}}
+	if err := tx.InsertBatch("t", []sql.Row{huge}); err == nil {
+		t.Fatalf("expected error for row larger than a page")
+	}
+
+	_, got, err := tx.Scan("t")
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("rejected batch inserted %d rows", len(got))
+	}
+}
+
+func BenchmarkInsert(b *testing.B) {
+	const n = 1000
+	rows := batchRows(n)
+
+	b.Run("loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			fs, err := New(b.TempDir())
+			if err != nil {
+				b.Fatalf("New failed: %v", err)
+			}
+			if err := fs.CreateTable("t", batchCols); err != nil {
+				b.Fatalf("CreateTable failed: %v", err)
+			}
+			b.StartTimer()
+
+			tx, _ := fs.Begin(false)
+			for _, r := range rows {
+				if err := tx.Insert("t", r); err != nil {
+					b.Fatalf("Insert failed: %v", err)
+				}
+			}
+			if err := fs.Commit(tx); err != nil {
+				b.Fatalf("Commit failed: %v", err)
+			}
+
+			b.StopTimer()
+			fs.Close()
+			b.StartTimer()
+		}
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			fs, err := New(b.TempDir())
+			if err != nil {
+				b.Fatalf("New failed: %v", err)
+			}
+			if err := fs.CreateTable("t", batchCols); err != nil {
+				b.Fatalf("CreateTable failed: %v", err)
+			}
+			b.StartTimer()
+
+			tx, _ := fs.Begin(false)
+			if err := tx.InsertBatch("t", rows); err != nil {
+				b.Fatalf("InsertBatch failed: %v", err)
+			}
+			if err := fs.Commit(tx); err != nil {
+				b.Fatalf("Commit failed: %v", err)
+			}
+
+			b.StopTimer()
+			fs.Close()
+			b.StartTimer()
+		}
+	})
+}
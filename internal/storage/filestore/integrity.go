@@ -0,0 +1,225 @@
+package filestore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"goDB/internal/index/btree"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// CheckIntegrity implements storage.IntegrityChecker. For every table it
+// checks the header, the magic and ID of every page, that each slot points
+// inside the row area of its page without overlapping another row, and
+// that every row decodes to values of the table's column types. Each index
+// must hold exactly one entry per live row with a non-NULL key, pointing at
+// a row with that key. Writers are paused while the check runs.
+func (e *FileEngine) CheckIntegrity() []storage.Problem {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	tables, err := e.ListTables()
+	if err != nil {
+		return []storage.Problem{{Page: -1, Slot: -1, Message: err.Error()}}
+	}
+	var problems []storage.Problem
+	for _, t := range tables {
+		c := &tableCheck{table: t}
+		e.checkTable(c)
+		problems = append(problems, c.problems...)
+	}
+	return problems
+}
+
+// tableCheck collects the problems found in one table.
+type tableCheck struct {
+	table    string
+	problems []storage.Problem
+}
+
+func (c *tableCheck) report(index string, page, slot int, format string, args ...any) {
+	c.problems = append(c.problems, storage.Problem{
+		Table:   c.table,
+		Index:   index,
+		Page:    page,
+		Slot:    slot,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkTable checks the pages of a table and then its indexes against the
+// rows found in them.
+func (e *FileEngine) checkTable(c *tableCheck) {
+	path, err := e.tablePath(c.table)
+	if err != nil {
+		c.report("", -1, -1, "%v", err)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		c.report("", -1, -1, "open table: %v", err)
+		return
+	}
+	defer f.Close()
+
+	cols, err := readHeader(f)
+	if err != nil {
+		c.report("", -1, -1, "corrupt header: %v", err)
+		return
+	}
+	headerEnd, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		c.report("", -1, -1, "seek after header: %v", err)
+		return
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		c.report("", -1, -1, "stat table: %v", err)
+		return
+	}
+	dataBytes := fi.Size() - headerEnd
+	if rest := dataBytes % PageSize; rest != 0 {
+		c.report("", -1, -1, "file ends in a partial page of %d bytes", rest)
+	}
+
+	indexes, err := e.indexesByColumn(c.table, cols)
+	if err != nil {
+		c.report("", -1, -1, "%v", err)
+	}
+
+	// The rows that decoded, in table order, and the slots that did not,
+	// for the index checks. They are only kept when there is an index.
+	var live []btree.RID
+	rows := make(map[btree.RID]sql.Row)
+	unreadable := make(map[btree.RID]bool)
+	for pageID := uint32(0); pageID < uint32(dataBytes/PageSize); pageID++ {
+		p, err := e.readPage(c.table, f, headerEnd, pageID)
+		if err != nil {
+			c.report("", int(pageID), -1, "%v", err)
+			continue
+		}
+		checkPage(c, p, pageID, cols, func(slot uint16, row sql.Row) {
+			rid := btree.RID{PageID: pageID, SlotID: slot}
+			switch {
+			case len(indexes) == 0:
+			case row == nil:
+				unreadable[rid] = true
+			default:
+				live = append(live, rid)
+				rows[rid] = cloneRow(row)
+			}
+		})
+	}
+
+	colIdxs := make([]int, 0, len(indexes))
+	for colIdx := range indexes {
+		colIdxs = append(colIdxs, colIdx)
+	}
+	sort.Ints(colIdxs)
+	for _, colIdx := range colIdxs {
+		checkIndex(c, indexes[colIdx], colIdx, cols[colIdx].Name, live, rows, unreadable)
+	}
+}
+
+// checkPage checks the header and slots of page pageID and calls fn with
+// every row that decodes to the table's columns, and with a nil row for the
+// live slots whose row does not. fn must not retain the row.
+func checkPage(c *tableCheck, p pageBuf, pageID uint32, cols []sql.Column, fn func(slot uint16, row sql.Row)) {
+	page := int(pageID)
+	if string(p[0:4]) != pageMagic {
+		c.report("", page, -1, "bad page magic %q", p[0:4])
+		return
+	}
+	if id := p.pageID(); id != pageID {
+		c.report("", page, -1, "page header says page %d", id)
+	}
+	if p[8] != pageTypeHeap {
+		c.report("", page, -1, "unknown page type %d", p[8])
+	}
+
+	nSlots := p.numSlots()
+	freeEnd := PageSize - int(nSlots)*4
+	if freeEnd < 16 {
+		c.report("", page, -1, "slot directory of %d slots does not fit in the page", nSlots)
+		return
+	}
+	if fs := int(p.freeStart()); fs < 16 || fs > freeEnd {
+		c.report("", page, -1, "free space starts at %d, outside the row area [16, %d)", fs, freeEnd)
+	}
+
+	type span struct {
+		slot       uint16
+		start, end int
+	}
+	var spans []span
+	row := make(sql.Row, len(cols))
+	for i := uint16(0); i < nSlots; i++ {
+		off, length := p.getSlot(i)
+		if off == 0xFFFF || length == 0 {
+			continue
+		}
+		start, end := int(off), int(off)+int(length)
+		if start < 16 || end > freeEnd {
+			c.report("", page, int(i), "row at [%d, %d) is outside the row area [16, %d)", start, end, freeEnd)
+			continue
+		}
+		spans = append(spans, span{i, start, end})
+
+		if err := decodeRowInto(row, p[start:end], false); err != nil {
+			c.report("", page, int(i), "row does not decode: %v", err)
+			fn(i, nil)
+			continue
+		}
+		if err := checkRowTypes(row, cols); err != nil {
+			c.report("", page, int(i), "row does not match the schema: %v", err)
+			fn(i, nil)
+			continue
+		}
+		fn(i, row)
+	}
+
+	sort.Slice(spans, func(a, b int) bool { return spans[a].start < spans[b].start })
+	for k := 1; k < len(spans); k++ {
+		if prev, cur := spans[k-1], spans[k]; cur.start < prev.end {
+			c.report("", page, int(cur.slot), "row overlaps the row in slot %d", prev.slot)
+		}
+	}
+}
+
+// checkIndex checks that the index on column colIdx maps the key of every
+// live row with a non-NULL key to that row, and nothing else. Entries for
+// unreadable rows are skipped, those rows having been reported already.
+func checkIndex(c *tableCheck, idx *indexInfo, colIdx int, column string, live []btree.RID, rows map[btree.RID]sql.Row, unreadable map[btree.RID]bool) {
+	found := make(map[btree.RID]bool)
+	err := idx.btree.Ascend(func(key btree.Key, rid btree.RID) bool {
+		page, slot := int(rid.PageID), int(rid.SlotID)
+		row, ok := rows[rid]
+		switch {
+		case unreadable[rid]:
+		case !ok:
+			c.report(idx.name, page, slot, "entry for key %d points at no live row", key)
+		case row[colIdx].Type == sql.TypeNull:
+			c.report(idx.name, page, slot, "entry for key %d points at a row whose %s is NULL", key, column)
+		case row[colIdx].I64 != key:
+			c.report(idx.name, page, slot, "entry for key %d points at a row whose %s is %d", key, column, row[colIdx].I64)
+		case found[rid]:
+			c.report(idx.name, page, slot, "duplicate entry for key %d", key)
+		default:
+			found[rid] = true
+		}
+		return true
+	})
+	if err != nil {
+		c.report(idx.name, -1, -1, "read index: %v", err)
+		return
+	}
+
+	for _, rid := range live {
+		if v := rows[rid][colIdx]; v.Type != sql.TypeNull && !found[rid] {
+			c.report(idx.name, int(rid.PageID), int(rid.SlotID), "row with %s = %d has no index entry", column, v.I64)
+		}
+	}
+}
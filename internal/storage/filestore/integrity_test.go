@@ -0,0 +1,125 @@
+package filestore
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goDB/internal/index/btree"
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+func TestFilestore_CheckIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("users", []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "name", Type: sql.TypeString},
+	}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	if err := fs.CreateIndex("idx_id", "users", "id"); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+	tx, err := fs.Begin(false)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := tx.InsertBatch("users", []sql.Row{
+		{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "alice"}},
+		{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeString, S: "bob"}},
+		{{Type: sql.TypeInt, I64: 3}, {Type: sql.TypeString, S: "carol"}},
+		{{Type: sql.TypeNull}, {Type: sql.TypeString, S: "nobody"}},
+	}); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if problems := fs.CheckIntegrity(); len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v", problems)
+	}
+
+	// Break the index: lose the entry of id 2, point key 7 at id 3's row
+	// and key 8 at a page that does not exist.
+	idx := fs.indexes["users"]["id"].btree
+	if err := idx.Delete(2, btree.RID{PageID: 0, SlotID: 1}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := idx.Insert(7, btree.RID{PageID: 0, SlotID: 2}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := idx.Insert(8, btree.RID{PageID: 5, SlotID: 0}); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	want := []string{
+		"table users index idx_id page 0 slot 2: entry for key 7 points at a row whose id is 3",
+		"table users index idx_id page 5 slot 0: entry for key 8 points at no live row",
+		"table users index idx_id page 0 slot 1: row with id = 2 has no index entry",
+	}
+	assertProblems(t, fs.CheckIntegrity(), want)
+
+	// Repair the index, then give the first row an unknown value type on
+	// disk. Its index entry is not reported a second time.
+	_ = idx.Insert(2, btree.RID{PageID: 0, SlotID: 1})
+	_ = idx.Delete(7, btree.RID{PageID: 0, SlotID: 2})
+	_ = idx.Delete(8, btree.RID{PageID: 5, SlotID: 0})
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	corruptFirstRow(t, filepath.Join(fs.dir, "users.godb"))
+
+	fs, err = New(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer fs.Close()
+	want = []string{
+		"table users page 0 slot 0: row does not decode: readRowFromBytes: unsupported type DataType(200)",
+	}
+	assertProblems(t, fs.CheckIntegrity(), want)
+}
+
+// corruptFirstRow overwrites the type byte of the first value in slot 0 of
+// the first page of the table file at path.
+func corruptFirstRow(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("open table: %v", err)
+	}
+	defer f.Close()
+	if _, err := readHeader(f); err != nil {
+		t.Fatalf("readHeader failed: %v", err)
+	}
+	headerEnd, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	p := make(pageBuf, PageSize)
+	if _, err := f.ReadAt(p, headerEnd); err != nil {
+		t.Fatalf("read page: %v", err)
+	}
+	off, _ := p.getSlot(0)
+	if _, err := f.WriteAt([]byte{200}, headerEnd+int64(off)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func assertProblems(t *testing.T, problems []storage.Problem, want []string) {
+	t.Helper()
+	got := make([]string, len(problems))
+	for i, p := range problems {
+		got[i] = p.String()
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,48 @@
+package filestore
+
+import (
+	"sync/atomic"
+
+	"goDB/internal/storage"
+)
+
+// pageCounters counts the page I/O of an engine. They are updated without
+// locks, so a Metrics snapshot taken while statements run may be off by the
+// pages in flight.
+type pageCounters struct {
+	reads, writes           atomic.Int64
+	bytesRead, bytesWritten atomic.Int64
+}
+
+func (c *pageCounters) read(n int) {
+	c.reads.Add(1)
+	c.bytesRead.Add(int64(n))
+}
+
+func (c *pageCounters) written(n int) {
+	c.writes.Add(1)
+	c.bytesWritten.Add(int64(n))
+}
+
+// Metrics implements storage.IOReporter. The counters help to measure the
+// effect of settings such as the page cache size or the WAL sync mode;
+// recovery, backups and column renames are not counted. A read-only engine
+// has no WAL and no page cache, so it only counts page reads.
+func (e *FileEngine) Metrics() storage.IOMetrics {
+	m := storage.IOMetrics{
+		PageReads:    e.pageIO.reads.Load(),
+		PageWrites:   e.pageIO.writes.Load(),
+		BytesRead:    e.pageIO.bytesRead.Load(),
+		BytesWritten: e.pageIO.bytesWritten.Load(),
+	}
+	if e.cache != nil {
+		e.cache.mu.Lock()
+		m.CacheHits = int64(e.cache.hits)
+		e.cache.mu.Unlock()
+	}
+	if e.wal != nil {
+		m.WALBytes = e.wal.bytesWritten.Load()
+		m.WALSyncs = e.wal.syncs.Load()
+	}
+	return m
+}
@@ -0,0 +1,72 @@
+package filestore
+
+import (
+	"testing"
+
+	"goDB/internal/sql"
+)
+
+func TestFilestore_Metrics(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		cacheSize int
+	}{
+		{"cache", 0},
+		{"nocache", -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, err := NewWithOptions(t.TempDir(), Options{PageCacheSize: tc.cacheSize})
+			if err != nil {
+				t.Fatalf("NewWithOptions failed: %v", err)
+			}
+			defer fs.Close()
+			if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+				t.Fatalf("CreateTable failed: %v", err)
+			}
+
+			before := fs.Metrics()
+			tx, err := fs.Begin(false)
+			if err != nil {
+				t.Fatalf("Begin failed: %v", err)
+			}
+			if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: 1}}); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+			if err := fs.Commit(tx); err != nil {
+				t.Fatalf("Commit failed: %v", err)
+			}
+			m := fs.Metrics()
+			if m.WALBytes <= before.WALBytes || m.WALSyncs <= before.WALSyncs {
+				t.Fatalf("commit did not count WAL bytes or syncs: before %+v, after %+v", before, m)
+			}
+			if m.PageWrites <= before.PageWrites || m.BytesWritten != m.PageWrites*PageSize {
+				t.Fatalf("insert did not count its page write: before %+v, after %+v", before, m)
+			}
+
+			// Scan the one-page table twice. Without a cache both scans read
+			// the page; with one, at most the first does.
+			before = fs.Metrics()
+			for range 2 {
+				tx, err := fs.Begin(true)
+				if err != nil {
+					t.Fatalf("Begin failed: %v", err)
+				}
+				if _, _, err := tx.Scan("t"); err != nil {
+					t.Fatalf("Scan failed: %v", err)
+				}
+				_ = fs.Commit(tx)
+			}
+			m = fs.Metrics()
+			reads, hits := m.PageReads-before.PageReads, m.CacheHits-before.CacheHits
+			if tc.cacheSize < 0 && (reads != 2 || hits != 0) {
+				t.Fatalf("without cache: %d page reads, %d hits; want 2, 0", reads, hits)
+			}
+			if tc.cacheSize >= 0 && (reads > 1 || hits < 1) {
+				t.Fatalf("with cache: %d page reads, %d hits; want at most 1 read and a hit", reads, hits)
+			}
+			if m.BytesRead != m.PageReads*PageSize {
+				t.Fatalf("BytesRead = %d for %d page reads", m.BytesRead, m.PageReads)
+			}
+		})
+	}
+}
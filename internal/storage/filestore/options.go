@@ -0,0 +1,79 @@
+package filestore
+
+import (
+	"errors"
+	"time"
+)
+
+// SyncMode controls when COMMIT and ROLLBACK records are fsynced, trading
+// durability for commit throughput.
+type SyncMode int
+
+const (
+	// SyncEachCommit fsyncs the WAL inside every Commit and Rollback. A
+	// transaction is durable as soon as Commit returns. This is the default.
+	SyncEachCommit SyncMode = iota
+
+	// SyncGroupCommit hands fsyncs to a background flusher that covers every
+	// commit record written so far with a single fsync. Commit still waits
+	// until its record is durable, so the guarantee matches SyncEachCommit,
+	// but concurrent committers share fsyncs.
+	SyncGroupCommit
+
+	// SyncAsync lets Commit return before its record is fsynced; the
+	// background flusher syncs shortly afterwards. A crash can lose the most
+	// recently committed transactions, but never leaves a partial one.
+	SyncAsync
+)
+
+// Options configures a FileEngine. The zero value matches New.
+type Options struct {
+	// SyncMode selects the commit durability policy.
+	SyncMode SyncMode
+
+	// GroupCommitWindow is how long the background flusher waits after the
+	// first pending commit, to collect more before it fsyncs. It only applies
+	// to SyncGroupCommit and SyncAsync; zero syncs as soon as possible.
+	GroupCommitWindow time.Duration
+
+	// PageCacheSize is the number of table pages kept in the LRU page cache.
+	// Zero uses DefaultPageCacheSize; a negative value disables the cache.
+	PageCacheSize int
+
+	// ScanWorkers bounds the goroutines that decode and filter pages in
+	// parallel during full-table scans. Zero or one scans sequentially.
+	ScanWorkers int
+
+	// WALSegmentSize is the size in bytes after which the WAL continues in
+	// a new segment file. Zero uses DefaultWALSegmentSize; a negative value
+	// keeps appending to one segment until the next checkpoint.
+	WALSegmentSize int64
+
+	// CheckpointInterval makes a background goroutine check every interval
+	// whether tables have been written since the last checkpoint and, if
+	// so, run Checkpoint. This bounds the WAL size and recovery time of a
+	// long-running process that never checkpoints explicitly. A tick that
+	// finds write transactions open is skipped. Zero disables it; Close
+	// stops it. It is ignored with ReadOnly.
+	CheckpointInterval time.Duration
+
+	// ReadOnly opens an existing data directory for reading only, so several
+	// processes can query it while another one writes. The WAL is not opened
+	// and recovery does not run: reads see the table files as they are on
+	// disk. Indexes and the page cache are not used, since another process
+	// may be changing the files. Write transactions, DDL, sequences,
+	// checkpoints, backups and change feeds fail with ErrReadOnly.
+	ReadOnly bool
+
+	// PackBools stores the BOOL values of rows written from now on as bits
+	// of a bitmap instead of two bytes each, which shrinks tables with
+	// several BOOL columns. Rows are only packed when that makes them
+	// shorter, and rows in either encoding are read whatever the setting,
+	// so it can be turned on and off freely. Versions of GoDB from before
+	// the option cannot read packed rows.
+	PackBools bool
+}
+
+// ErrReadOnly is returned by every operation that would write to a data
+// directory opened with Options.ReadOnly.
+var ErrReadOnly = errors.New("filestore: engine is read-only")
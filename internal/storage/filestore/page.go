@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"goDB/internal/sql"
+	"sort"
 )
 
 const (
@@ -12,6 +13,10 @@ const (
 	pageMagic = "GPG1" // GoDB Page v1
 
 	pageTypeHeap uint8 = 1
+
+	// maxRowSize is the largest encoded row that fits in an empty page
+	// (16-byte header plus one 4-byte slot).
+	maxRowSize = PageSize - 16 - 4
 )
 
 // Page header layout (on disk):
@@ -125,7 +130,13 @@ func (p pageBuf) insertRow(rowBytes []byte) (uint16, error) {
 	freeEnd := PageSize - int(nSlots)*4
 
 	if int(freeStart)+needed > freeEnd {
-		return 0, fmt.Errorf("page: not enough free space")
+		// Holes left by deleted rows may add up to enough room even though
+		// the contiguous free area is too small.
+		if 16+p.liveBytes()+needed > freeEnd {
+			return 0, fmt.Errorf("page: not enough free space")
+		}
+		p.compact()
+		freeStart = p.freeStart()
 	}
 
 	// Write row bytes at freeStart
@@ -146,8 +157,107 @@ func (p pageBuf) insertRow(rowBytes []byte) (uint16, error) {
 	return slotIdx, nil
 }
 
+// fitsUpdate reports whether slot i can be rewritten with a row of n bytes
+// by updateRow, compacting the page if needed.
+func (p pageBuf) fitsUpdate(i uint16, n int) bool {
+	_, length := p.getSlot(i)
+	freeEnd := PageSize - int(p.numSlots())*4
+	return 16+p.liveBytes()-int(length)+n <= freeEnd
+}
+
+// updateRow replaces the row in slot i with rowBytes. The slot is kept, so
+// the row keeps its row ID and its place in scan order. A row that grows is
+// moved to the free area, compacting the page first if only the holes left
+// by deleted rows make room for it.
+func (p pageBuf) updateRow(i uint16, rowBytes []byte) error {
+	off, length := p.getSlot(i)
+	if off == 0xFFFF || length == 0 {
+		return fmt.Errorf("page: update of empty slot %d", i)
+	}
+	n := uint16(len(rowBytes))
+	if n <= length {
+		copy(p[off:off+n], rowBytes)
+		p.setSlot(i, off, n)
+		return nil
+	}
+	if !p.fitsUpdate(i, len(rowBytes)) {
+		return fmt.Errorf("page: not enough free space")
+	}
+
+	// The old bytes become a hole; when they end the row area the new row
+	// can start where they did. The slot is left as a tombstone meanwhile,
+	// so compaction skips the old row, but the directory is not trimmed.
+	p.setSlot(i, 0xFFFF, 0)
+	if off+length == p.freeStart() {
+		p.setFreeStart(off)
+	}
+	freeEnd := PageSize - int(p.numSlots())*4
+	if int(p.freeStart())+len(rowBytes) > freeEnd {
+		p.compact()
+	}
+	start := p.freeStart()
+	copy(p[start:start+n], rowBytes)
+	p.setSlot(i, start, n)
+	p.setFreeStart(start + n)
+	return nil
+}
+
+// liveBytes returns the number of row-area bytes used by non-deleted rows.
+func (p pageBuf) liveBytes() int {
+	n := 0
+	for i := uint16(0); i < p.numSlots(); i++ {
+		off, length := p.getSlot(i)
+		if off != 0xFFFF && length != 0 {
+			n += int(length)
+		}
+	}
+	return n
+}
+
+// compact rewrites the live rows contiguously from the start of the row
+// area, reclaiming every hole left by deleted rows. Slot numbers are kept,
+// so row IDs stay valid; only the offsets stored in the slots change.
+func (p pageBuf) compact() {
+	nSlots := p.numSlots()
+	live := make([]uint16, 0, nSlots)
+	for i := uint16(0); i < nSlots; i++ {
+		if off, length := p.getSlot(i); off != 0xFFFF && length != 0 {
+			live = append(live, i)
+		}
+	}
+	// Moving rows in offset order only ever copies them towards the start
+	// of the page, so a row is never overwritten before it has moved.
+	sort.Slice(live, func(a, b int) bool {
+		offA, _ := p.getSlot(live[a])
+		offB, _ := p.getSlot(live[b])
+		return offA < offB
+	})
+
+	next := uint16(16)
+	for _, i := range live {
+		off, length := p.getSlot(i)
+		if off != next {
+			copy(p[next:next+length], p[off:off+length])
+			p.setSlot(i, next, length)
+		}
+		next += length
+	}
+	p.setFreeStart(next)
+}
+
 // iterateRows calls fn(slotIndex, row) for each non-deleted row in order.
+// Every row is freshly allocated, so fn may keep it.
 func (p pageBuf) iterateRows(numCols int, fn func(slot uint16, row sql.Row) error) error {
+	return p.iterateRowsInto(make(sql.Row, numCols), false, func(slot uint16, row sql.Row) error {
+		return fn(slot, cloneRow(row))
+	})
+}
+
+// iterateRowsInto is like iterateRows but decodes every row into the same
+// buffer, which must have one element per column. fn must not retain the row
+// (or, with borrow set, its strings, which point into the page) after it
+// returns.
+func (p pageBuf) iterateRowsInto(row sql.Row, borrow bool, fn func(slot uint16, row sql.Row) error) error {
 	nSlots := p.numSlots()
 	for i := uint16(0); i < nSlots; i++ {
 		off, length := p.getSlot(i)
@@ -160,10 +270,7 @@ func (p pageBuf) iterateRows(numCols int, fn func(slot uint16, row sql.Row) erro
 		if end > len(p) {
 			return fmt.Errorf("page: corrupt slot %d", i)
 		}
-		rowBytes := p[start:end]
-		// decode rowBytes using readRowFromBytes (we'll add this helper)
-		row, err := readRowFromBytes(rowBytes, numCols)
-		if err != nil {
+		if err := decodeRowInto(row, p[start:end], borrow); err != nil {
 			return fmt.Errorf("page: read row at slot %d: %w", i, err)
 		}
 		if err := fn(i, row); err != nil {
@@ -180,32 +287,11 @@ func (p pageBuf) deleteSlot(i uint16) {
 	// Mark as deleted. We use 0xFFFF/0 as the “tombstone” value.
 	p.setSlot(i, 0xFFFF, 0)
 
-	// If this row occupied the contiguous end of the in-use area, rewind freeStart
-	// to reclaim space. We walk backwards through rows that end at the current
-	// freeStart so consecutive deletions reclaim space in order of most recent
-	// inserts.
-	freeStart := p.freeStart()
-	if off != 0xFFFF && length != 0 {
-		if end := off + length; end == freeStart {
-			newFreeStart := off
-			for {
-				progressed := false
-				for idx := uint16(0); idx < p.numSlots(); idx++ {
-					o, l := p.getSlot(idx)
-					if o == 0xFFFF || l == 0 {
-						continue
-					}
-					if o+l == newFreeStart {
-						newFreeStart = o
-						progressed = true
-					}
-				}
-				if !progressed {
-					break
-				}
-			}
-			p.setFreeStart(newFreeStart)
-		}
+	// If this row ended the in-use area, rewind freeStart over its bytes.
+	// Holes further back are left for compact: the rows before it may be
+	// live, and deleted rows no longer record where they were.
+	if off != 0xFFFF && length != 0 && off+length == p.freeStart() {
+		p.setFreeStart(off)
 	}
 
 	// Shrink slot directory by dropping tombstones at the end. This allows
@@ -0,0 +1,228 @@
+package filestore
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DefaultPageCacheSize is the number of pages cached when
+// Options.PageCacheSize is zero (1 MiB with 4 KiB pages).
+const DefaultPageCacheSize = 256
+
+// pageKey identifies a heap page of a table.
+type pageKey struct {
+	table  string
+	pageID uint32
+}
+
+type cacheEntry struct {
+	key    pageKey
+	page   pageBuf
+	offset int64 // file offset of the page, for writeback
+	dirty  bool
+}
+
+// pageCache is an LRU cache of table pages shared by all transactions.
+//
+// Modified pages are kept dirty in the cache and written back by flush at
+// the end of each mutating operation (or on eviction), so every page is
+// written once per operation however often it was touched. Callers always
+// get and put copies, which keeps cached pages safe from concurrent
+// modification.
+type pageCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[pageKey]*list.Element
+	lru      *list.List // front = most recently used
+	dir      func(table string) (string, error)
+	io       *pageCounters // counts the write-backs
+
+	hits, misses uint64
+}
+
+// newPageCache returns a cache holding up to capacity pages, or nil when
+// capacity is not positive. tablePath maps a table name to its file, which
+// is needed to write back dirty pages on eviction.
+func newPageCache(capacity int, tablePath func(table string) (string, error), io *pageCounters) *pageCache {
+	if capacity <= 0 {
+		return nil
+	}
+	return &pageCache{
+		capacity: capacity,
+		entries:  make(map[pageKey]*list.Element),
+		lru:      list.New(),
+		dir:      tablePath,
+		io:       io,
+	}
+}
+
+// get returns a copy of the cached page, if present.
+func (c *pageCache) get(key pageKey) (pageBuf, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(elem)
+	ent := elem.Value.(*cacheEntry)
+	return append(pageBuf(nil), ent.page...), true
+}
+
+// put stores a copy of p. Dirty pages must later be written back by flush.
+func (c *pageCache) put(key pageKey, offset int64, p pageBuf, dirty bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		ent := elem.Value.(*cacheEntry)
+		copy(ent.page, p)
+		ent.offset = offset
+		ent.dirty = ent.dirty || dirty
+		c.lru.MoveToFront(elem)
+		return nil
+	}
+
+	ent := &cacheEntry{key: key, page: append(pageBuf(nil), p...), offset: offset, dirty: dirty}
+	c.entries[key] = c.lru.PushFront(ent)
+
+	for c.lru.Len() > c.capacity {
+		if err := c.evictOldest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evictOldest drops the least recently used page, writing it back first if
+// it is dirty. c.mu must be held.
+func (c *pageCache) evictOldest() error {
+	elem := c.lru.Back()
+	ent := elem.Value.(*cacheEntry)
+	if ent.dirty {
+		path, err := c.dir(ent.key.table)
+		if err != nil {
+			return fmt.Errorf("filestore: page cache writeback: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+		if err != nil {
+			return fmt.Errorf("filestore: page cache writeback: %w", err)
+		}
+		_, err = f.WriteAt(ent.page, ent.offset)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("filestore: page cache writeback of page %d: %w", ent.key.pageID, err)
+		}
+		c.io.written(len(ent.page))
+	}
+	c.lru.Remove(elem)
+	delete(c.entries, ent.key)
+	return nil
+}
+
+// resize changes the capacity, evicting the least recently used pages
+// beyond it.
+func (c *pageCache) resize(capacity int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	for c.lru.Len() > c.capacity {
+		if err := c.evictOldest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes every dirty page of table to f.
+func (c *pageCache) flush(table string, f *os.File) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		ent := elem.Value.(*cacheEntry)
+		if key.table != table || !ent.dirty {
+			continue
+		}
+		if _, err := f.WriteAt(ent.page, ent.offset); err != nil {
+			return fmt.Errorf("filestore: write page %d: %w", key.pageID, err)
+		}
+		c.io.written(len(ent.page))
+		ent.dirty = false
+	}
+	return nil
+}
+
+// invalidate drops all cached pages of table without writing them back.
+// It is used when the table file is rewritten from scratch.
+func (c *pageCache) invalidate(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		if key.table == table {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// readPage returns page pageID of a table, from the cache when possible.
+func (e *FileEngine) readPage(table string, f *os.File, headerEnd int64, pageID uint32) (pageBuf, error) {
+	key := pageKey{table: table, pageID: pageID}
+	if e.cache != nil {
+		if p, ok := e.cache.get(key); ok {
+			return p, nil
+		}
+	}
+
+	p := make(pageBuf, PageSize)
+	offset := headerEnd + int64(pageID)*PageSize
+	if _, err := f.ReadAt(p, offset); err != nil {
+		return nil, fmt.Errorf("filestore: read page %d: %w", pageID, err)
+	}
+	e.pageIO.read(len(p))
+	if e.cache != nil {
+		if err := e.cache.put(key, offset, p, false); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// writePage records a modified page. With a cache the page is kept dirty
+// until flushPages; without one it is written to f immediately.
+func (e *FileEngine) writePage(table string, f *os.File, headerEnd int64, pageID uint32, p pageBuf) error {
+	offset := headerEnd + int64(pageID)*PageSize
+	if e.cache != nil {
+		return e.cache.put(pageKey{table: table, pageID: pageID}, offset, p, true)
+	}
+	if _, err := f.WriteAt(p, offset); err != nil {
+		return fmt.Errorf("filestore: write page %d: %w", pageID, err)
+	}
+	e.pageIO.written(len(p))
+	return nil
+}
+
+// flushPages writes back the dirty cached pages of table to f.
+func (e *FileEngine) flushPages(table string, f *os.File) error {
+	if e.cache == nil {
+		return nil
+	}
+	return e.cache.flush(table, f)
+}
+
+// invalidatePages forgets all cached pages of table.
+func (e *FileEngine) invalidatePages(table string) {
+	if e.cache != nil {
+		e.cache.invalidate(table)
+	}
+}
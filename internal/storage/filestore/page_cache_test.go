@@ -0,0 +1,103 @@
+package filestore
+
+import (
+	"testing"
+
+	"goDB/internal/sql"
+)
+
+func TestPageCache_ScanHits(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", batchRows(300)); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	scanAll(t, fs, "t")
+	misses := fs.cache.misses
+	_, rows := scanAll(t, fs, "t")
+	if len(rows) != 300 {
+		t.Fatalf("got %d rows, want 300", len(rows))
+	}
+	if fs.cache.misses != misses {
+		t.Fatalf("second scan missed the cache %d times", fs.cache.misses-misses)
+	}
+}
+
+func TestPageCache_EvictionWritesBack(t *testing.T) {
+	dir := t.TempDir()
+	// A two-page cache forces dirty pages out while a batch is written.
+	fs, err := NewWithOptions(dir, Options{PageCacheSize: 2})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	want := batchRows(300)
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", want); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := tx.DeleteWhere("t", func(r sql.Row) (bool, error) { return r[0].I64%2 == 0, nil }); err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, got := scanAll(t, fs, "t"); len(got) != 150 {
+		t.Fatalf("got %d rows through the cache, want 150", len(got))
+	}
+
+	// Read the table file without the cache (and without WAL recovery) to
+	// check that every page reached the disk.
+	uncached := &FileEngine{dir: dir}
+	if _, got := scanAll(t, uncached, "t"); len(got) != 150 {
+		t.Fatalf("got %d rows on disk, want 150", len(got))
+	}
+	_ = fs.Close()
+}
+
+func TestPageCache_InvalidatedByReplaceAll(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", batchRows(300)); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	scanAll(t, fs, "t")
+	if err := tx.ReplaceAll("t", batchRows(2)); err != nil {
+		t.Fatalf("ReplaceAll failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	if _, got := scanAll(t, fs, "t"); len(got) != 2 {
+		t.Fatalf("got %d rows after ReplaceAll, want 2", len(got))
+	}
+	if err := fs.CreateIndex("idx_t_id", "t", "id"); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+	if rids, err := fs.indexes["t"]["id"].btree.Search(299); err != nil || len(rids) != 0 {
+		t.Fatalf("index built from stale pages: rids=%v err=%v", rids, err)
+	}
+}
@@ -148,3 +148,127 @@ func TestPage_DeletedSlotIsSkipped(t *testing.T) {
 		t.Fatalf("unexpected remaining row: %+v", got[0])
 	}
 }
+
+func TestPage_InsertCompactsHoles(t *testing.T) {
+	p := newEmptyHeapPage(1)
+
+	// Fill the page with rows of ~500 bytes each.
+	payload := func(i int) sql.Row {
+		return sql.Row{
+			{Type: sql.TypeInt, I64: int64(i)},
+			{Type: sql.TypeString, S: string(bytes.Repeat([]byte{byte('a' + i)}, 480))},
+		}
+	}
+	var slots []uint16
+	for i := 0; ; i++ {
+		slot, err := p.insertRow(encodeRow(t, payload(i)))
+		if err != nil {
+			break
+		}
+		slots = append(slots, slot)
+	}
+	if len(slots) < 4 {
+		t.Fatalf("expected several rows to fit, got %d", len(slots))
+	}
+
+	// Delete two rows from the middle: neither touches freeStart, so the
+	// space is only reclaimable by compaction.
+	p.deleteSlot(slots[1])
+	p.deleteSlot(slots[2])
+
+	// A row bigger than either hole but smaller than both together.
+	big := sql.Row{
+		{Type: sql.TypeInt, I64: 99},
+		{Type: sql.TypeString, S: string(bytes.Repeat([]byte{'z'}, 700))},
+	}
+	slot, err := p.insertRow(encodeRow(t, big))
+	if err != nil {
+		t.Fatalf("insertRow after deleting middle rows failed: %v", err)
+	}
+	if slot != slots[1] {
+		t.Fatalf("expected deleted slot %d to be reused, got %d", slots[1], slot)
+	}
+
+	// Every surviving row keeps its slot and contents.
+	got := make(map[uint16]sql.Row)
+	if err := p.iterateRows(2, func(slot uint16, r sql.Row) error {
+		got[slot] = r
+		return nil
+	}); err != nil {
+		t.Fatalf("iterateRows failed: %v", err)
+	}
+	if len(got) != len(slots)-1 {
+		t.Fatalf("expected %d rows after compaction, got %d", len(slots)-1, len(got))
+	}
+	for i, s := range slots {
+		want := payload(i)
+		switch s {
+		case slots[1]:
+			want = big
+		case slots[2]:
+			if _, ok := got[s]; ok {
+				t.Fatalf("deleted slot %d reappeared", s)
+			}
+			continue
+		}
+		if r := got[s]; r[0].I64 != want[0].I64 || r[1].S != want[1].S {
+			t.Fatalf("slot %d: got id %d, want %d", s, r[0].I64, want[0].I64)
+		}
+	}
+}
+
+func TestPage_UpdateRowKeepsSlot(t *testing.T) {
+	row := func(id int, n int) sql.Row {
+		return sql.Row{
+			{Type: sql.TypeInt, I64: int64(id)},
+			{Type: sql.TypeString, S: string(bytes.Repeat([]byte{byte('a' + id)}, n))},
+		}
+	}
+
+	// Grow the middle and the last row well past their old length, shrink
+	// them again, then grow them so far that the page has to be compacted.
+	for _, target := range []int{1, 2} {
+		p := newEmptyHeapPage(1)
+		var slots []uint16
+		for i := 0; i < 3; i++ {
+			slot, err := p.insertRow(encodeRow(t, row(i, 10)))
+			if err != nil {
+				t.Fatalf("insertRow %d failed: %v", i, err)
+			}
+			slots = append(slots, slot)
+		}
+
+		for _, n := range []int{2000, 5, 3000, 10} {
+			if err := p.updateRow(slots[target], encodeRow(t, row(target, n))); err != nil {
+				t.Fatalf("updateRow of slot %d to %d bytes failed: %v", target, n, err)
+			}
+			if got := p.numSlots(); got != 3 {
+				t.Fatalf("numSlots = %d after updating slot %d to %d bytes, want 3", got, target, n)
+			}
+			var ids []int64
+			if err := p.iterateRows(2, func(slot uint16, r sql.Row) error {
+				ids = append(ids, r[0].I64)
+				want := 10
+				if slot == slots[target] {
+					want = n
+				}
+				if len(r[1].S) != want || r[1].S[0] != byte('a'+r[0].I64) {
+					t.Fatalf("slot %d holds %q, want %d bytes of %c", slot, r[1].S, want, 'a'+r[0].I64)
+				}
+				return nil
+			}); err != nil {
+				t.Fatalf("iterateRows after updating slot %d to %d bytes failed: %v", target, n, err)
+			}
+			if len(ids) != 3 || ids[0] != 0 || ids[1] != 1 || ids[2] != 2 {
+				t.Fatalf("rows out of order after updating slot %d to %d bytes: %v", target, n, ids)
+			}
+		}
+
+		if p.fitsUpdate(slots[target], PageSize) {
+			t.Fatalf("fitsUpdate accepted a row larger than the page")
+		}
+		if err := p.updateRow(slots[target], make([]byte, PageSize)); err == nil {
+			t.Fatalf("expected updateRow with an oversized row to fail")
+		}
+	}
+}
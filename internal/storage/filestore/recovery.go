@@ -1,13 +1,17 @@
 package filestore
 
 import (
-	"encoding/binary"
+	"bufio"
+	"errors"
 	"fmt"
 	"goDB/internal/sql"
 	"goDB/internal/storage"
 	"io"
+	"log"
+	"math"
 	"os"
-	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type walOpType int
@@ -22,7 +26,8 @@ const (
 type walOp struct {
 	typ   walOpType
 	table string
-	rows  []sql.Row // semantics depend on typ:
+	types []sql.DataType // column types when logged; nil for older records
+	rows  []sql.Row      // semantics depend on typ:
 	// Insert:      rows = [row1, row2, ...]
 	// ReplaceAll:  rows = full table snapshot
 	// Delete:      rows = [row1, row2, ...] to remove
@@ -37,172 +42,363 @@ type walTxState struct {
 	order     int
 }
 
-func (e *FileEngine) recoverFromWAL() error {
-	walPath := filepath.Join(e.dir, "wal.log")
+// walReplay holds the WAL records recovery has to apply, grouped by
+// transaction in the order the transactions began.
+type walReplay struct {
+	txs     []*walTxState
+	touched map[string]bool // tables with records newer than their snapshot
+	lastLSN uint64          // highest LSN in the WAL
+}
 
-	info, err := os.Stat(walPath)
+func (e *FileEngine) recoverFromWAL() error {
+	ckpt, err := readCheckpointLSN(e.dir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // no WAL, nothing to recover
-		}
-		return fmt.Errorf("recovery: stat WAL: %w", err)
+		return fmt.Errorf("recovery: %w", err)
 	}
+	e.checkpointLSN = ckpt
+	e.wal.setLastLSN(ckpt)
 
-	if info.Size() <= int64(len(walMagic)) {
-		return nil // WAL only has magic, no records
+	hasRecords, err := walHasRecords(e.dir)
+	if err != nil {
+		return fmt.Errorf("recovery: %w", err)
+	}
+	if !hasRecords {
+		return nil // WAL segments only have magic, no records
 	}
 
-	// 1) Load schemas for all existing tables
+	// 1) Load schemas and snapshot LSNs for all existing tables
 	tableNames, err := e.ListTables()
 	if err != nil {
 		return fmt.Errorf("recovery: list tables: %w", err)
 	}
+	schemas, err := e.tableSchemas(tableNames)
+	if err != nil {
+		return fmt.Errorf("recovery: %w", err)
+	}
+	// Tables whose file is gone but whose snapshot is not can be rebuilt
+	// from the snapshot.
+	orphans, err := e.orphanSnapshots(schemas)
+	if err != nil {
+		return fmt.Errorf("recovery: %w", err)
+	}
+	snaps, err := e.snapshotLSNs(append(tableNames, orphans...))
+	if err != nil {
+		return fmt.Errorf("recovery: %w", err)
+	}
 
-	schemas := make(map[string][]sql.Column)
+	// 2) Parse the WAL records after the checkpoint
+	replay, err := e.readWAL(ckpt, snaps, schemas)
+	if err != nil {
+		return fmt.Errorf("recovery: %w", err)
+	}
+	last := replay.lastLSN
+	for _, lsn := range snaps {
+		last = max(last, lsn)
+	}
+	e.wal.setLastLSN(max(last, ckpt))
+
+	tableNames, err = e.restoreMissingTables(tableNames, orphans, schemas, replay)
+	if err != nil {
+		return fmt.Errorf("recovery: %w", err)
+	}
+
+	// 3) Rebuild every table that changed after its snapshot, or that has
+	// none, from the snapshot plus the committed changes
+	rebuild := make(map[string]struct{})
 	for _, t := range tableNames {
+		if _, ok := snaps[t]; !ok || replay.touched[t] {
+			rebuild[t] = struct{}{}
+		}
+	}
+	if err := e.rebuildTables(rebuild, replay); err != nil {
+		return fmt.Errorf("recovery: %w", err)
+	}
+
+	// 4) Checkpoint, so the replayed records are not applied again
+	e.dirty = rebuild
+	if err := e.checkpointLocked(); err != nil {
+		return fmt.Errorf("recovery: checkpoint: %w", err)
+	}
+	return nil
+}
+
+// tableSchemas reads the schema of each table.
+func (e *FileEngine) tableSchemas(tables []string) (map[string][]sql.Column, error) {
+	schemas := make(map[string][]sql.Column)
+	for _, t := range tables {
 		cols, err := e.TableSchema(t)
 		if err != nil {
-			return fmt.Errorf("recovery: read schema for %q: %w", t, err)
+			return nil, fmt.Errorf("read schema for %q: %w", t, err)
 		}
 		schemas[t] = cols
 	}
+	return schemas, nil
+}
 
-	// 2) Truncate data for all tables (keep header)
-	for _, t := range tableNames {
-		path := e.tablePath(t)
-		f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+// orphanSnapshots adds the schema of each table that has a snapshot but no
+// table file to schemas and returns the names of those tables.
+func (e *FileEngine) orphanSnapshots(schemas map[string][]sql.Column) ([]string, error) {
+	entries, err := os.ReadDir(e.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+	var orphans []string
+	for _, ent := range entries {
+		t, ok := strings.CutSuffix(ent.Name(), ".ckpt")
+		if !ok || checkName("table", t) != nil {
+			continue
+		}
+		if _, exists := schemas[t]; exists {
+			continue
+		}
+		f, _, ok, err := e.openSnapshot(t)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		cols, err := readHeader(f)
+		f.Close()
 		if err != nil {
-			return fmt.Errorf("recovery: open table %q: %w", t, err)
+			return nil, fmt.Errorf("snapshot of %q: %w", t, err)
 		}
+		schemas[t] = cols
+		orphans = append(orphans, t)
+	}
+	return orphans, nil
+}
 
-		cols := schemas[t]
+// restoreMissingTables handles the tables that WAL records in replay change
+// but that have no table file, say because it was deleted by hand. A table
+// with a snapshot (one of orphans) gets a new, empty file with the
+// snapshot's schema (from schemas), which recovery then fills like any other table; the
+// records of a table without one cannot be replayed and are dropped with a
+// warning, so one lost file does not keep the whole database from opening.
+// It returns tables plus the recreated ones.
+func (e *FileEngine) restoreMissingTables(tables, orphans []string, schemas map[string][]sql.Column, replay *walReplay) ([]string, error) {
+	missing := make([]string, 0, len(replay.touched))
+	for t := range replay.touched {
+		if !slices.Contains(tables, t) {
+			missing = append(missing, t)
+		}
+	}
+	slices.Sort(missing)
 
-		if err := f.Truncate(0); err != nil {
-			f.Close()
-			return fmt.Errorf("recovery: truncate table %q: %w", t, err)
+	for _, t := range missing {
+		if !slices.Contains(orphans, t) {
+			log.Printf("filestore: recovery: table file of %q is missing and it has no snapshot; skipping its WAL records", t)
+			delete(replay.touched, t)
+			continue
 		}
-		if _, err := f.Seek(0, io.SeekStart); err != nil {
-			f.Close()
-			return fmt.Errorf("recovery: seek table %q: %w", t, err)
+		log.Printf("filestore: recovery: table file of %q is missing; recreating it from its snapshot", t)
+		path, err := e.tablePath(t)
+		if err != nil {
+			return nil, err
 		}
-		if err := writeHeader(f, cols); err != nil {
-			f.Close()
-			return fmt.Errorf("recovery: write header for %q: %w", t, err)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("recreate table %q: %w", t, err)
 		}
-		f.Close()
+		err = writeHeader(f, schemas[t])
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("recreate table %q: %w", t, err)
+		}
+		tables = append(tables, t)
 	}
+	return tables, nil
+}
 
-	// 3) Parse WAL into txStates
-	f, err := os.Open(walPath)
+// walHasRecords reports whether any WAL segment in dir holds a record.
+func walHasRecords(dir string) (bool, error) {
+	segs, err := walSegments(dir)
 	if err != nil {
-		return fmt.Errorf("recovery: open WAL: %w", err)
+		return false, err
 	}
-	defer f.Close()
+	for _, n := range segs {
+		info, err := os.Stat(walSegmentPath(dir, n))
+		if err != nil {
+			return false, fmt.Errorf("stat WAL: %w", err)
+		}
+		if info.Size() > int64(len(walMagic)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
 
-	// skip magic
-	if _, err := f.Seek(int64(len(walMagic)), io.SeekStart); err != nil {
-		return fmt.Errorf("recovery: seek WAL: %w", err)
+// readWAL parses the records after the checkpoint LSN ckpt into
+// per-transaction op lists, reading the WAL segments in order. Row records
+// for a table are dropped when the table's snapshot (snaps) already reflects
+// them. Records written before LSNs existed count as LSN 0.
+func (e *FileEngine) readWAL(ckpt uint64, snaps map[string]uint64, schemas map[string][]sql.Column) (*walReplay, error) {
+	segs, err := walSegments(e.dir)
+	if err != nil {
+		return nil, err
 	}
 
+	replay := &walReplay{touched: make(map[string]bool)}
 	txStates := make(map[uint64]*walTxState)
-	var txOrder []uint64
 	getTx := func(id uint64) *walTxState {
 		if s, ok := txStates[id]; ok {
 			return s
 		}
-		s := &walTxState{id: id, order: len(txOrder)}
+		s := &walTxState{id: id, order: len(replay.txs)}
 		txStates[id] = s
-		txOrder = append(txOrder, id)
+		replay.txs = append(replay.txs, s)
 		return s
 	}
 
-	for {
-		var recType uint8
-		if err := binary.Read(f, binary.LittleEndian, &recType); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("recovery: read recType: %w", err)
-		}
-
-		var txID uint64
-		if err := binary.Read(f, binary.LittleEndian, &txID); err != nil {
-			return fmt.Errorf("recovery: read txID: %w", err)
+	numCols := func(table string) (int, error) {
+		cols, ok := schemas[table]
+		if !ok {
+			return 0, fmt.Errorf("table %q in WAL but not in schema map", table)
 		}
-		txState := getTx(txID)
-
-		switch recType {
-		case walRecBegin:
-			// nothing extra
-		case walRecCommit:
-			txState.committed = true
-		case walRecRollback:
-			txState.rolled = true
-
-		case walRecInsert, walRecReplaceAll, walRecDelete, walRecUpdate:
-			// common header: table name + rowCount
-			var nameLen uint16
-			if err := binary.Read(f, binary.LittleEndian, &nameLen); err != nil {
-				return fmt.Errorf("recovery: read table name len: %w", err)
-			}
-			nameBytes := make([]byte, nameLen)
-			if _, err := io.ReadFull(f, nameBytes); err != nil {
-				return fmt.Errorf("recovery: read table name: %w", err)
-			}
-			table := string(nameBytes)
+		return len(cols), nil
+	}
 
-			var rowCount uint32
-			if err := binary.Read(f, binary.LittleEndian, &rowCount); err != nil {
-				return fmt.Errorf("recovery: read rowCount: %w", err)
+	for i, n := range segs {
+		last := i == len(segs)-1
+		err := e.readWALSegment(n, last, func(rec walRecord) {
+			replay.lastLSN = max(replay.lastLSN, rec.lsn)
+			if ckpt > 0 && rec.lsn <= ckpt {
+				return // already in the table files
 			}
+			txState := getTx(rec.txID)
+
+			switch rec.recType {
+			case walRecBegin:
+				// nothing extra
+			case walRecCommit:
+				txState.committed = true
+			case walRecRollback:
+				txState.rolled = true
+
+			case walRecInsert, walRecReplaceAll, walRecDelete, walRecUpdate:
+				if lsn, ok := snaps[rec.table]; ok && rec.lsn <= lsn {
+					return // already in the snapshot
+				}
+				replay.touched[rec.table] = true
+
+				var opType walOpType
+				switch rec.recType {
+				case walRecInsert:
+					opType = walOpInsert
+				case walRecReplaceAll:
+					opType = walOpReplaceAll
+				case walRecDelete:
+					opType = walOpDelete
+				case walRecUpdate:
+					opType = walOpUpdate
+				}
 
-			cols, ok := schemas[table]
-			if !ok {
-				return fmt.Errorf("recovery: table %q in WAL but not in schema map", table)
+				txState.ops = append(txState.ops, walOp{
+					typ:   opType,
+					table: rec.table,
+					types: rec.types,
+					rows:  rec.rows,
+				})
 			}
+		}, numCols)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return replay, nil
+}
 
-			rows := make([]sql.Row, 0, rowCount)
-			for i := uint32(0); i < rowCount; i++ {
-				r, err := readRow(f, len(cols))
-				if err != nil {
-					return fmt.Errorf("recovery: read row: %w", err)
-				}
-				rows = append(rows, r)
-			}
+// readWALSegment decodes the records of WAL segment n and passes them to fn
+// in order. A torn record at the end of the last segment is truncated away;
+// anywhere else it is an error, because a segment is fsynced before the next
+// one is started.
+func (e *FileEngine) readWALSegment(n uint64, last bool, fn func(walRecord), numCols func(string) (int, error)) error {
+	f, err := os.Open(walSegmentPath(e.dir, n))
+	if err != nil {
+		return fmt.Errorf("open WAL: %w", err)
+	}
+	defer f.Close()
 
-			var opType walOpType
-			switch recType {
-			case walRecInsert:
-				opType = walOpInsert
-			case walRecReplaceAll:
-				opType = walOpReplaceAll
-			case walRecDelete:
-				opType = walOpDelete
-			case walRecUpdate:
-				opType = walOpUpdate
-			}
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat WAL: %w", err)
+	}
 
-			txState.ops = append(txState.ops, walOp{
-				typ:   opType,
-				table: table,
-				rows:  rows,
-			})
+	magic := make([]byte, len(walMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return fmt.Errorf("read WAL segment %d magic: %w", n, err)
+	}
+	if string(magic) != walMagic {
+		return fmt.Errorf("WAL segment %d: invalid magic", n)
+	}
 
-		default:
-			return fmt.Errorf("recovery: unknown WAL record type %d", recType)
+	r := &countingReader{r: bufio.NewReader(f), n: int64(len(walMagic))}
+	for {
+		start := r.n
+		rec, err := readWALRecord(r, numCols)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if !last {
+				return fmt.Errorf("WAL segment %d: %w", n, err)
+			}
+			torn, terr := isTornTail(f, start, err)
+			if terr != nil {
+				return terr
+			}
+			if !torn {
+				return err
+			}
+			// A crash mid-append left a partial record at the end of the
+			// log. Its transaction never committed, so drop it and let new
+			// appends start at the last complete record.
+			log.Printf("filestore: recovery: truncating torn WAL record in segment %d at offset %d (%d bytes): %v",
+				n, start, info.Size()-start, err)
+			return e.wal.truncate(start)
 		}
+		fn(rec)
 	}
+}
 
-	// 4) Replay committed txs into an in-memory view of each table
+// rebuildTables rewrites each table in tables with the rows of its snapshot
+// (none if it has no snapshot) plus the committed changes in replay.
+func (e *FileEngine) rebuildTables(tables map[string]struct{}, replay *walReplay) error {
 	rowsByTable := make(map[string][]sql.Row)
+	for t := range tables {
+		rows, err := e.snapshotRows(t)
+		if err != nil {
+			return err
+		}
+		rowsByTable[t] = rows
+	}
 
-	for _, txID := range txOrder {
-		s := txStates[txID]
+	// Replay committed txs into an in-memory view of each table
+	schemas := make(map[string][]sql.Column)
+	for _, s := range replay.txs {
 		if !s.committed || s.rolled {
 			continue
 		}
 
 		for _, op := range s.ops {
+			if _, ok := tables[op.table]; !ok {
+				continue
+			}
+			if op.types != nil {
+				cols, ok := schemas[op.table]
+				if !ok {
+					var err error
+					if cols, err = e.TableSchema(op.table); err != nil {
+						return err
+					}
+					schemas[op.table] = cols
+				}
+				if err := checkLoggedTypes(op.types, cols); err != nil {
+					return fmt.Errorf("replay into table %q: %w", op.table, err)
+				}
+			}
 			switch op.typ {
 			case walOpInsert:
 				// Append rows
@@ -231,7 +427,7 @@ func (e *FileEngine) recoverFromWAL() error {
 				// rows = [old1, new1, old2, new2, ...]
 				cur := rowsByTable[op.table]
 				if len(op.rows)%2 != 0 {
-					return fmt.Errorf("recovery: update op has odd rows length for table %q", op.table)
+					return fmt.Errorf("update op has odd rows length for table %q", op.table)
 				}
 				for i := 0; i < len(op.rows); i += 2 {
 					oldRow := op.rows[i]
@@ -249,27 +445,94 @@ func (e *FileEngine) recoverFromWAL() error {
 		}
 	}
 
-	// 5) Write rebuilt contents back to disk via ReplaceAll (page-based)
+	// Write rebuilt contents back to disk (page-based)
 	for table, rows := range rowsByTable {
 		tx := &fileTx{
-			eng:      e,
-			readOnly: false,
-			closed:   false,
-			id:       0, // don't log recovery writes into WAL
+			eng: e,
+			id:  0, // don't log recovery writes into WAL
 		}
-		if err := tx.ReplaceAll(table, rows); err != nil {
-			return fmt.Errorf("recovery: rebuild table %q: %w", table, err)
+		// Read-only scans only hold the table lock, so take it while the
+		// file is truncated and rewritten.
+		unlock := e.lockTable(table)
+		err := tx.replaceAll(table, rows)
+		unlock()
+		if err != nil {
+			return fmt.Errorf("rebuild table %q: %w", table, err)
 		}
 	}
-
 	return nil
 }
 
+// checkLoggedTypes reports an error unless the column types a WAL record
+// was logged with are those of cols, the table's current schema.
+func checkLoggedTypes(types []sql.DataType, cols []sql.Column) error {
+	same := len(types) == len(cols)
+	for i := 0; same && i < len(types); i++ {
+		same = types[i] == cols[i].Type
+	}
+	if same {
+		return nil
+	}
+	logged := make([]string, len(types))
+	for i, t := range types {
+		logged[i] = t.String()
+	}
+	current := make([]string, len(cols))
+	for i, c := range cols {
+		current[i] = c.Type.String()
+	}
+	return fmt.Errorf("WAL record was logged for columns (%s), but the table has (%s)",
+		strings.Join(logged, ", "), strings.Join(current, ", "))
+}
+
+// countingReader tracks how many bytes have been read through it, which
+// gives recovery the WAL offset of each record.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// isTornTail reports whether decodeErr, raised while reading the record that
+// starts at off, is the result of an interrupted append rather than
+// corruption. The WAL has no per-record checksum, so a record counts as torn
+// when it runs past the end of the file or when everything from off onwards
+// is zero bytes (space the filesystem allocated before the data landed).
+func isTornTail(f *os.File, off int64, decodeErr error) (bool, error) {
+	if errors.Is(decodeErr, io.EOF) || errors.Is(decodeErr, io.ErrUnexpectedEOF) {
+		return true, nil
+	}
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		return false, fmt.Errorf("seek WAL: %w", err)
+	}
+	r := bufio.NewReader(f)
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("read WAL: %w", err)
+		}
+		if b != 0 {
+			return false, nil
+		}
+	}
+}
+
 func (e *FileEngine) applyTxOps(s *walTxState, schemas map[string][]sql.Column) error {
 	for _, op := range s.ops {
 		switch op.typ {
 		case walOpInsert:
-			path := e.tablePath(op.table)
+			path, err := e.tablePath(op.table)
+			if err != nil {
+				return fmt.Errorf("recovery: %w", err)
+			}
 			f, err := os.OpenFile(path, os.O_RDWR, 0o644)
 			if err != nil {
 				return fmt.Errorf("recovery: open table %q for insert: %w", op.table, err)
@@ -288,7 +551,10 @@ func (e *FileEngine) applyTxOps(s *walTxState, schemas map[string][]sql.Column)
 			f.Close()
 
 		case walOpReplaceAll:
-			path := e.tablePath(op.table)
+			path, err := e.tablePath(op.table)
+			if err != nil {
+				return fmt.Errorf("recovery: %w", err)
+			}
 			f, err := os.OpenFile(path, os.O_RDWR, 0o644)
 			if err != nil {
 				return fmt.Errorf("recovery: open table %q for replace: %w", op.table, err)
@@ -344,15 +610,16 @@ func equalRow(a, b sql.Row) bool {
 			return false
 		}
 		switch a[i].Type {
-		case sql.TypeInt:
+		case sql.TypeInt, sql.TypeTimestamp:
 			if a[i].I64 != b[i].I64 {
 				return false
 			}
 		case sql.TypeFloat:
-			if a[i].F64 != b[i].F64 {
+			// Compare the bits, so a NaN matches its own WAL records.
+			if math.Float64bits(a[i].F64) != math.Float64bits(b[i].F64) {
 				return false
 			}
-		case sql.TypeString:
+		case sql.TypeString, sql.TypeBytes:
 			if a[i].S != b[i].S {
 				return false
 			}
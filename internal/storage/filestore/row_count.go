@@ -0,0 +1,78 @@
+package filestore
+
+import "fmt"
+
+// The engine caches the number of live rows of each table, so COUNT(*)
+// without WHERE does not have to read the table. Every write that adds or
+// removes rows adjusts the count while it still holds the table lock. A
+// table missing from the cache is counted from the slot directories of its
+// pages the next time it is asked for; that is also how the counts are
+// rebuilt after a restart, once recovery has rewritten the table files.
+// The cache stays off in read-only mode, where another process may be
+// writing the files.
+
+// CountRows returns the number of live rows of the table.
+func (tx *fileTx) CountRows(tableName string) (int, error) {
+	if tx.closed {
+		return 0, fmt.Errorf("filestore: tx is closed")
+	}
+	return tx.eng.rowCount(tableName)
+}
+
+// rowCount returns the cached row count of table, counting it first when
+// it is not cached.
+func (e *FileEngine) rowCount(table string) (int, error) {
+	e.countMu.Lock()
+	n, ok := e.rowCounts[table]
+	e.countMu.Unlock()
+	if ok {
+		return n, nil
+	}
+
+	// Hold off writers so the count matches the pages it was read from.
+	e.writeMu.RLock()
+	defer e.writeMu.RUnlock()
+	defer e.lockTable(table)()
+
+	st, err := e.TableStats(table)
+	if err != nil {
+		return 0, err
+	}
+	e.setRowCount(table, st.Rows, nil)
+	return st.Rows, nil
+}
+
+// setRowCount records that table holds n rows after a write that rewrote
+// it. When the write failed the table may hold part of it, so the count is
+// dropped instead.
+func (e *FileEngine) setRowCount(table string, n int, err error) {
+	e.countMu.Lock()
+	defer e.countMu.Unlock()
+	if e.rowCounts == nil {
+		return
+	}
+	if err != nil {
+		delete(e.rowCounts, table)
+		return
+	}
+	e.rowCounts[table] = n
+}
+
+// adjustRowCount adds delta to the cached row count of table after a write,
+// or drops the count when the write failed.
+func (e *FileEngine) adjustRowCount(table string, delta int, err error) {
+	e.countMu.Lock()
+	defer e.countMu.Unlock()
+	if e.rowCounts == nil {
+		return
+	}
+	n, ok := e.rowCounts[table]
+	if !ok {
+		return
+	}
+	if err != nil {
+		delete(e.rowCounts, table)
+		return
+	}
+	e.rowCounts[table] = n + delta
+}
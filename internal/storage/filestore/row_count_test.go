@@ -0,0 +1,121 @@
+package filestore
+
+import (
+	"strings"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+func TestFilestore_CountRows(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("users", []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "name", Type: sql.TypeString},
+	}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	// check compares the cached count with the rows a scan returns.
+	check := func(fs *FileEngine, want int) {
+		t.Helper()
+		tx, err := fs.Begin(true)
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		defer fs.Commit(tx)
+		n, err := tx.(storage.RowCounter).CountRows("users")
+		if err != nil {
+			t.Fatalf("CountRows failed: %v", err)
+		}
+		_, rows, err := tx.Scan("users")
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if n != want || len(rows) != want {
+			t.Fatalf("CountRows = %d, scan returned %d rows; want %d", n, len(rows), want)
+		}
+	}
+	write := func(fn func(tx storage.Tx) error) {
+		t.Helper()
+		tx, err := fs.Begin(false)
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		if err := fn(tx); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		if err := fs.Commit(tx); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+	row := func(id int64, name string) sql.Row {
+		return sql.Row{{Type: sql.TypeInt, I64: id}, {Type: sql.TypeString, S: name}}
+	}
+
+	check(fs, 0)
+
+	rows := make([]sql.Row, 200)
+	for i := range rows {
+		rows[i] = row(int64(i), "x")
+	}
+	write(func(tx storage.Tx) error { return tx.InsertBatch("users", rows) })
+	check(fs, 200)
+
+	write(func(tx storage.Tx) error {
+		return tx.DeleteWhere("users", func(r sql.Row) (bool, error) { return r[0].I64 < 50, nil })
+	})
+	check(fs, 150)
+
+	// Growing every row moves those that no longer fit on their page.
+	long := strings.Repeat("y", 100)
+	write(func(tx storage.Tx) error {
+		return tx.UpdateWhere("users",
+			func(sql.Row) (bool, error) { return true, nil },
+			func(r sql.Row) (sql.Row, error) { r[1].S = long; return r, nil })
+	})
+	check(fs, 150)
+
+	write(func(tx storage.Tx) error { return tx.ReplaceAll("users", rows[:7]) })
+	check(fs, 7)
+
+	// A failed write leaves a count that still matches the table.
+	write(func(tx storage.Tx) error {
+		if err := tx.InsertBatch("users", []sql.Row{{{Type: sql.TypeString, S: "bad"}}}); err == nil {
+			t.Fatalf("expected a malformed row to be rejected")
+		}
+		return nil
+	})
+	check(fs, 7)
+
+	// Rolled-back inserts are undone and no longer counted.
+	tx, err := fs.Begin(false)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := tx.InsertBatch("users", rows[7:10]); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	check(fs, 10)
+	if err := fs.Rollback(tx); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	check(fs, 7)
+
+	// After a restart the count is rebuilt from the recovered table.
+	write(func(tx storage.Tx) error { return tx.InsertBatch("users", rows[7:9]) })
+	if err := fs.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	fs, err = New(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer fs.Close()
+	check(fs, 9)
+}
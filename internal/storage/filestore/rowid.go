@@ -0,0 +1,162 @@
+package filestore
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"goDB/internal/index/btree"
+	"goDB/internal/sql"
+)
+
+// rowID returns the rowid of the row at rid: its page and slot, as
+// pageID<<16 | slot. It stays the same while the row is updated in place; a
+// row that grows out of its page moves and gets a new one, and rewriting
+// the table (ReplaceAll, discarding a rolled-back transaction, recovery)
+// renumbers all rows.
+func rowID(rid btree.RID) int64 {
+	return int64(rid.PageID)<<16 | int64(rid.SlotID)
+}
+
+// ridOf returns the page and slot of a rowid; ok is false for an id no row
+// can have.
+func ridOf(id int64) (rid btree.RID, ok bool) {
+	if id < 0 || id>>16 > math.MaxUint32 {
+		return btree.RID{}, false
+	}
+	return btree.RID{PageID: uint32(id >> 16), SlotID: uint16(id)}, true
+}
+
+// ScanRowIDs implements storage.RowIDStore.
+func (tx *fileTx) ScanRowIDs(tableName string) ([]string, []sql.Row, []int64, error) {
+	if tx.closed {
+		return nil, nil, nil, fmt.Errorf("filestore: tx is closed")
+	}
+	defer tx.scanLock(tableName)()
+
+	path, err := tx.eng.tablePath(tableName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("filestore: open table for rowid scan: %w", missingTable(tableName, err))
+	}
+	defer f.Close()
+
+	cols, err := readHeader(f)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("filestore: read header in rowid scan: %w", err)
+	}
+	headerEnd, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("filestore: seek after header: %w", err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("filestore: stat table in rowid scan: %w", err)
+	}
+	dataBytes := fi.Size() - headerEnd
+	if dataBytes < 0 || dataBytes%PageSize != 0 {
+		return nil, nil, nil, fmt.Errorf("filestore: corrupt data (not multiple of page size)")
+	}
+	numPages := uint32(dataBytes / PageSize)
+
+	colNames := make([]string, len(cols))
+	for i, c := range cols {
+		colNames[i] = c.Name
+	}
+
+	var rows []sql.Row
+	var ids []int64
+	for pageID := uint32(0); pageID < numPages; pageID++ {
+		p, err := tx.eng.readPage(tableName, f, headerEnd, pageID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		err = p.iterateRows(len(cols), func(slot uint16, row sql.Row) error {
+			rows = append(rows, row)
+			ids = append(ids, rowID(btree.RID{PageID: pageID, SlotID: slot}))
+			return nil
+		})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("filestore: iterate rows in page %d: %w", pageID, err)
+		}
+	}
+	return colNames, rows, ids, nil
+}
+
+// DeleteRowIDs implements storage.RowIDStore. Only the pages holding the
+// rows are read.
+func (tx *fileTx) DeleteRowIDs(tableName string, ids []int64) error {
+	rids := make(map[btree.RID]bool, len(ids))
+	for _, id := range ids {
+		if rid, ok := ridOf(id); ok {
+			rids[rid] = true
+		}
+	}
+	if len(rids) == 0 {
+		return nil
+	}
+	return tx.deleteRows(tableName, ridPages(rids), func(rid btree.RID, _ sql.Row) (bool, error) {
+		return rids[rid], nil
+	})
+}
+
+// UpdateRowIDs implements storage.RowIDStore. Only the pages holding the
+// rows are read.
+func (tx *fileTx) UpdateRowIDs(tableName string, ids []int64, rows []sql.Row) error {
+	byRID := make(map[btree.RID]sql.Row, len(ids))
+	for i, id := range ids {
+		if rid, ok := ridOf(id); ok {
+			byRID[rid] = rows[i]
+		}
+	}
+	if len(byRID) == 0 {
+		return nil
+	}
+	return tx.updateRows(tableName, ridPages(byRID),
+		func(rid btree.RID, _ sql.Row) (bool, error) {
+			_, ok := byRID[rid]
+			return ok, nil
+		},
+		func(rid btree.RID, _ sql.Row) (sql.Row, error) {
+			return cloneRow(byRID[rid]), nil
+		})
+}
+
+// ridPages returns the pages of the RIDs in rids, in ascending order.
+func ridPages[V any](rids map[btree.RID]V) []uint32 {
+	seen := make(map[uint32]bool)
+	var pages []uint32
+	for rid := range rids {
+		if !seen[rid.PageID] {
+			seen[rid.PageID] = true
+			pages = append(pages, rid.PageID)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i] < pages[j] })
+	return pages
+}
+
+// pageList returns the IDs of the pages to visit in a table of numPages
+// pages: those in pages that exist, or all of them when pages is nil.
+func pageList(pages []uint32, numPages uint32) []uint32 {
+	if pages == nil {
+		all := make([]uint32, numPages)
+		for i := range all {
+			all[i] = uint32(i)
+		}
+		return all
+	}
+	var out []uint32
+	for _, id := range pages {
+		if id < numPages {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package filestore
+
+import (
+	"reflect"
+	"testing"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+func TestFilestore_RowIDs(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("users", []sql.Column{
+		{Name: "id", Type: sql.TypeInt},
+		{Name: "name", Type: sql.TypeString},
+	}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	row := func(id int64, name string) sql.Row {
+		return sql.Row{{Type: sql.TypeInt, I64: id}, {Type: sql.TypeString, S: name}}
+	}
+	scan := func() ([]sql.Row, []int64) {
+		t.Helper()
+		tx, err := fs.Begin(true)
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		defer fs.Commit(tx)
+		_, rows, ids, err := tx.(storage.RowIDStore).ScanRowIDs("users")
+		if err != nil {
+			t.Fatalf("ScanRowIDs failed: %v", err)
+		}
+		return rows, ids
+	}
+	write := func(fn func(s storage.RowIDStore) error) {
+		t.Helper()
+		tx, err := fs.Begin(false)
+		if err != nil {
+			t.Fatalf("Begin failed: %v", err)
+		}
+		if err := fn(tx.(storage.RowIDStore)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		if err := fs.Commit(tx); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+
+	write(func(s storage.RowIDStore) error {
+		return s.(storage.Tx).InsertBatch("users", []sql.Row{row(1, "Ada"), row(2, "Alan"), row(3, "Grace")})
+	})
+	_, ids := scan()
+	if len(ids) != 3 || ids[0] == ids[1] || ids[1] == ids[2] {
+		t.Fatalf("ids = %v, want three distinct rowids", ids)
+	}
+
+	write(func(s storage.RowIDStore) error {
+		if err := s.UpdateRowIDs("users", []int64{ids[1]}, []sql.Row{row(2, "Alan Turing")}); err != nil {
+			return err
+		}
+		// Unknown ids are ignored.
+		return s.DeleteRowIDs("users", []int64{ids[0], -1, 1 << 40})
+	})
+
+	rows, got := scan()
+	if want := []sql.Row{row(2, "Alan Turing"), row(3, "Grace")}; !reflect.DeepEqual(rows, want) {
+		t.Fatalf("rows = %v, want %v", rows, want)
+	}
+	if want := ids[1:]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ids after update and delete = %v, want %v", got, want)
+	}
+
+	tx, _ := fs.Begin(true)
+	defer fs.Commit(tx)
+	rs := tx.(storage.RowIDRangeScanner)
+	if _, _, _, ok, err := rs.ScanRangeRowIDs("users", "id", 2, 2); ok || err != nil {
+		t.Fatalf("ScanRangeRowIDs without an index: ok %v, err %v", ok, err)
+	}
+	if err := fs.CreateIndex("idx_users_id", "users", "id"); err != nil {
+		t.Fatalf("CreateIndex failed: %v", err)
+	}
+	_, rows, rangeIDs, ok, err := rs.ScanRangeRowIDs("users", "id", 3, 10)
+	if err != nil || !ok {
+		t.Fatalf("ScanRangeRowIDs: ok %v, err %v", ok, err)
+	}
+	if !reflect.DeepEqual(rows, []sql.Row{row(3, "Grace")}) || !reflect.DeepEqual(rangeIDs, ids[2:]) {
+		t.Fatalf("ScanRangeRowIDs = %v %v, want Grace with rowid %d", rows, rangeIDs, ids[2])
+	}
+}
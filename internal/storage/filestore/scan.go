@@ -0,0 +1,264 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+// Scan reads all rows from the table file.
+func (tx *fileTx) Scan(tableName string) ([]string, []sql.Row, error) {
+	return tx.scan(context.Background(), tableName, nil, true)
+}
+
+// ScanWhere returns the rows of the table for which pred returns true,
+// in table order. Rows are filtered while their pages are decoded, on up
+// to Options.ScanWorkers goroutines.
+func (tx *fileTx) ScanWhere(tableName string, pred storage.RowPredicate) ([]string, []sql.Row, error) {
+	return tx.scan(context.Background(), tableName, pred, true)
+}
+
+// ScanContext is like ScanWhere, but checks ctx before every page and gives
+// up with its error once it is done.
+func (tx *fileTx) ScanContext(ctx context.Context, tableName string, pred storage.RowPredicate) ([]string, []sql.Row, error) {
+	return tx.scan(ctx, tableName, pred, true)
+}
+
+// ScanLimit returns the first n rows of the table in table order. Pages are
+// read one at a time, and reading stops at the page where the n-th row is
+// found.
+func (tx *fileTx) ScanLimit(tableName string, n int) ([]string, []sql.Row, error) {
+	if n < 0 {
+		return nil, nil, fmt.Errorf("filestore: negative scan limit %d", n)
+	}
+	return tx.scanLimit(context.Background(), tableName, nil, true, n)
+}
+
+// ScanWhereUnordered is like ScanWhere, but returns rows in the order the
+// parallel workers produced them. It avoids holding back finished page
+// ranges for callers that sort or aggregate the result anyway.
+func (tx *fileTx) ScanWhereUnordered(tableName string, pred storage.RowPredicate) ([]string, []sql.Row, error) {
+	return tx.scan(context.Background(), tableName, pred, false)
+}
+
+// scan reads the rows of a table that match pred (all rows when pred is
+// nil). With more than one scan worker configured, page ranges are decoded
+// and filtered concurrently; ordered keeps the result in page order. The
+// scan stops between pages once ctx is done.
+func (tx *fileTx) scan(ctx context.Context, tableName string, pred storage.RowPredicate, ordered bool) ([]string, []sql.Row, error) {
+	return tx.scanLimit(ctx, tableName, pred, ordered, -1)
+}
+
+// scanLimit is like scan, but with a non-negative limit it reads pages in
+// order on the calling goroutine and stops once limit rows matched.
+func (tx *fileTx) scanLimit(ctx context.Context, tableName string, pred storage.RowPredicate, ordered bool, limit int) ([]string, []sql.Row, error) {
+	if tx.closed {
+		return nil, nil, fmt.Errorf("filestore: tx is closed")
+	}
+	defer tx.scanLock(tableName)()
+
+	path, err := tx.eng.tablePath(tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("filestore: open table for scan: %w", missingTable(tableName, err))
+	}
+	defer f.Close()
+
+	cols, err := readHeader(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("filestore: read header in scan: %w", err)
+	}
+	headerEnd, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("filestore: seek after header: %w", err)
+	}
+
+	colNames := make([]string, len(cols))
+	for i, c := range cols {
+		colNames[i] = c.Name
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("filestore: stat table in scan: %w", err)
+	}
+	fileSize := fi.Size()
+	if fileSize < headerEnd {
+		return nil, nil, fmt.Errorf("filestore: corrupt file, size < header")
+	}
+	dataBytes := fileSize - headerEnd
+	if dataBytes == 0 || limit == 0 {
+		return colNames, nil, nil
+	}
+	if dataBytes%PageSize != 0 {
+		return nil, nil, fmt.Errorf("filestore: corrupt data (not multiple of page size)")
+	}
+	numPages := uint32(dataBytes / PageSize)
+
+	s := &pageScanner{ctx: ctx, tx: tx, table: tableName, f: f, headerEnd: headerEnd, numCols: len(cols), pred: pred, limit: limit}
+
+	workers := tx.eng.opts.ScanWorkers
+	if workers <= 1 || numPages == 1 || limit > 0 {
+		rows, err := s.scanRange(0, numPages)
+		if err != nil {
+			return nil, nil, err
+		}
+		return colNames, rows, nil
+	}
+
+	rows, err := s.scanParallel(numPages, workers, ordered)
+	if err != nil {
+		return nil, nil, err
+	}
+	return colNames, rows, nil
+}
+
+// pageScanner decodes and filters the pages of one open table file.
+type pageScanner struct {
+	ctx       context.Context
+	tx        *fileTx
+	table     string
+	f         *os.File
+	headerEnd int64
+	numCols   int
+	pred      storage.RowPredicate
+	limit     int // stop after this many rows; negative for no limit
+}
+
+// scanRange returns the matching rows of pages [from, to), or only the first
+// s.limit of them. Matching rows are copied into one value slab per page
+// instead of being allocated one by one.
+func (s *pageScanner) scanRange(from, to uint32) ([]sql.Row, error) {
+	var rows []sql.Row
+	n := s.numCols
+	scratch := make(sql.Row, n)
+
+	// With a predicate most rows may be dropped, so strings borrow from the
+	// page until a row matches and is copied out.
+	borrow := s.pred != nil
+
+	for pageID := from; pageID < to; pageID++ {
+		if err := s.ctx.Err(); err != nil {
+			return nil, fmt.Errorf("filestore: scan %s: %w", s.table, err)
+		}
+		p, err := s.tx.eng.readPage(s.table, s.f, s.headerEnd, pageID)
+		if err != nil {
+			return nil, err
+		}
+
+		var slab []sql.Value
+		if s.pred == nil {
+			slab = make([]sql.Value, 0, int(p.numSlots())*n)
+		}
+		err = p.iterateRowsInto(scratch, borrow, func(slot uint16, r sql.Row) error {
+			if s.pred != nil {
+				match, err := s.pred(r)
+				if err != nil || !match {
+					return err
+				}
+			}
+			start := len(slab)
+			slab = append(slab, r...)
+			if borrow {
+				for i := start; i < len(slab); i++ {
+					slab[i].S = strings.Clone(slab[i].S)
+				}
+			}
+			rows = append(rows, sql.Row(slab[start:len(slab):len(slab)]))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("filestore: iterate rows in page %d: %w", pageID, err)
+		}
+		if s.limit >= 0 && len(rows) >= s.limit {
+			return rows[:s.limit], nil
+		}
+	}
+	return rows, nil
+}
+
+// scanParallel splits the table into page ranges and scans them on a pool
+// of workers. Ranges are small enough that a slow one does not leave the
+// other workers idle.
+func (s *pageScanner) scanParallel(numPages uint32, workers int, ordered bool) ([]sql.Row, error) {
+	rangeSize := numPages / uint32(workers*4)
+	if rangeSize == 0 {
+		rangeSize = 1
+	}
+	numRanges := int((numPages + rangeSize - 1) / rangeSize)
+	if workers > numRanges {
+		workers = numRanges
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		results  = make([][]sql.Row, numRanges) // by range, when ordered
+		out      []sql.Row                      // in completion order otherwise
+	)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				from := uint32(r) * rangeSize
+				to := min(from+rangeSize, numPages)
+				rows, err := s.scanRange(from, to)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					if firstErr == nil {
+						firstErr = err
+					}
+				case ordered:
+					results[r] = rows
+				default:
+					out = append(out, rows...)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for r := 0; r < numRanges; r++ {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if !ordered {
+		return out, nil
+	}
+
+	total := 0
+	for _, rows := range results {
+		total += len(rows)
+	}
+	out = make([]sql.Row, 0, total)
+	for _, rows := range results {
+		out = append(out, rows...)
+	}
+	return out, nil
+}
@@ -0,0 +1,317 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"goDB/internal/sql"
+	"goDB/internal/storage"
+)
+
+func newScanTestEngine(t *testing.T, workers int) *FileEngine {
+	t.Helper()
+	fs, err := NewWithOptions(t.TempDir(), Options{ScanWorkers: workers})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(func() { fs.Close() })
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", batchRows(2000)); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	return fs
+}
+
+func everyThird(r sql.Row) (bool, error) { return r[0].I64%3 == 0, nil }
+
+func TestFilestore_ParallelScanWhere(t *testing.T) {
+	fs := newScanTestEngine(t, 4)
+	tx, _ := fs.Begin(true)
+	ftx := tx.(*fileTx)
+
+	_, all, err := ftx.Scan("t")
+	if err != nil || len(all) != 2000 {
+		t.Fatalf("Scan: %d rows, err %v", len(all), err)
+	}
+	for i, r := range all {
+		if r[0].I64 != int64(i) {
+			t.Fatalf("Scan row %d has id %d, want table order", i, r[0].I64)
+		}
+	}
+
+	_, got, err := tx.(storage.FilteredScanner).ScanWhere("t", everyThird)
+	if err != nil {
+		t.Fatalf("ScanWhere failed: %v", err)
+	}
+	if len(got) != 667 {
+		t.Fatalf("ScanWhere returned %d rows, want 667", len(got))
+	}
+	for i, r := range got {
+		if r[0].I64 != int64(3*i) || r[1].S != all[3*i][1].S {
+			t.Fatalf("ScanWhere row %d = %v, want %v", i, r, all[3*i])
+		}
+	}
+
+	_, unordered, err := ftx.ScanWhereUnordered("t", everyThird)
+	if err != nil {
+		t.Fatalf("ScanWhereUnordered failed: %v", err)
+	}
+	sort.Slice(unordered, func(i, j int) bool { return unordered[i][0].I64 < unordered[j][0].I64 })
+	for i := range got {
+		if !equalRow(unordered[i], got[i]) {
+			t.Fatalf("unordered row %d = %v, want %v", i, unordered[i], got[i])
+		}
+	}
+}
+
+func TestFilestore_ParallelScanPredicateError(t *testing.T) {
+	fs := newScanTestEngine(t, 4)
+	tx, _ := fs.Begin(true)
+
+	boom := errors.New("boom")
+	_, _, err := tx.(storage.FilteredScanner).ScanWhere("t", func(r sql.Row) (bool, error) {
+		if r[0].I64 == 1500 {
+			return false, boom
+		}
+		return true, nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("ScanWhere error = %v, want %v", err, boom)
+	}
+}
+
+func TestFilestore_ScanContextCancel(t *testing.T) {
+	for _, workers := range []int{1, 4} {
+		fs := newScanTestEngine(t, workers)
+		tx, _ := fs.Begin(true)
+		cs := tx.(storage.ContextScanner)
+
+		_, rows, err := cs.ScanContext(context.Background(), "t", nil)
+		if err != nil || len(rows) != 2000 {
+			t.Fatalf("workers=%d: ScanContext: %d rows, err %v", workers, len(rows), err)
+		}
+
+		// Cancelling while the first page is being read stops the scan
+		// before the next page.
+		ctx, cancel := context.WithCancel(context.Background())
+		_, _, err = cs.ScanContext(ctx, "t", func(r sql.Row) (bool, error) {
+			cancel()
+			return true, nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("workers=%d: ScanContext error = %v, want context.Canceled", workers, err)
+		}
+	}
+}
+
+func TestFilestore_ScanLimit(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewWithOptions(dir, Options{PageCacheSize: -1})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", batchRows(20000)); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// Point the first slot of the last page past the page end: a scan that
+	// reads the whole table fails, one that stops after a few rows never
+	// gets there.
+	f, err := os.OpenFile(filepath.Join(fs.dir, "t.godb"), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("open table: %v", err)
+	}
+	fi, _ := f.Stat()
+	if _, err := f.WriteAt([]byte{0xFF, 0xFF}, fi.Size()-2); err != nil {
+		t.Fatalf("corrupt page: %v", err)
+	}
+	f.Close()
+
+	rtx, _ := fs.Begin(true)
+	if _, _, err := rtx.Scan("t"); err == nil {
+		t.Fatalf("expected a full scan to hit the corrupt page")
+	}
+	ls := rtx.(storage.LimitScanner)
+	for _, n := range []int{0, 1, 5, 100} {
+		cols, rows, err := ls.ScanLimit("t", n)
+		if err != nil {
+			t.Fatalf("ScanLimit(%d) failed: %v", n, err)
+		}
+		if len(cols) != 2 || len(rows) != n {
+			t.Fatalf("ScanLimit(%d) returned %d columns, %d rows", n, len(cols), len(rows))
+		}
+		for i, r := range rows {
+			if r[0].I64 != int64(i) {
+				t.Fatalf("ScanLimit(%d) row %d has id %d, want table order", n, i, r[0].I64)
+			}
+		}
+	}
+}
+
+func TestFilestore_ReadOnlyScanHoldsOffWriters(t *testing.T) {
+	fs := newScanTestEngine(t, 1)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	pause := func(sql.Row) (bool, error) {
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+		return true, nil
+	}
+
+	rtx, _ := fs.Begin(true)
+	type result struct {
+		rows []sql.Row
+		err  error
+	}
+	scanned := make(chan result)
+	go func() {
+		_, rows, err := rtx.(storage.FilteredScanner).ScanWhere("t", pause)
+		scanned <- result{rows, err}
+	}()
+	<-started
+
+	deleted := make(chan error)
+	go func() {
+		wtx, _ := fs.Begin(false)
+		err := wtx.DeleteWhere("t", everyThird)
+		if err == nil {
+			err = fs.Commit(wtx)
+		}
+		deleted <- err
+	}()
+	select {
+	case err := <-deleted:
+		t.Fatalf("DELETE finished during the scan (err %v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	res := <-scanned
+	if res.err != nil || len(res.rows) != 2000 {
+		t.Fatalf("scan returned %d rows, err %v; want all 2000 from before the DELETE", len(res.rows), res.err)
+	}
+	if err := <-deleted; err != nil {
+		t.Fatalf("DELETE failed: %v", err)
+	}
+	_, rows, err := rtx.Scan("t")
+	if err != nil || len(rows) != 1333 {
+		t.Fatalf("second scan: %d rows, err %v; want 1333", len(rows), err)
+	}
+}
+
+// Undoing a rollback rewrites the table, which read-only scans must never
+// see half done: each scan returns every committed row, plus at most the
+// rolled-back one before its undo.
+func TestFilestore_ReadOnlyScanDuringRollbackUndo(t *testing.T) {
+	fs, err := NewWithOptions(t.TempDir(), Options{PageCacheSize: -1})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer fs.Close()
+	if err := fs.CreateTable("t", batchCols); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	tx, _ := fs.Begin(false)
+	if err := tx.InsertBatch("t", batchRows(5000)); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	bad := make(chan string, 4)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				rtx, _ := fs.Begin(true)
+				_, rows, err := rtx.Scan("t")
+				fs.Commit(rtx)
+				if err != nil || (len(rows) != 5000 && len(rows) != 5001) {
+					bad <- fmt.Sprintf("scan returned %d rows, err %v; want 5000 committed rows", len(rows), err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 30; i++ {
+		tx, _ := fs.Begin(false)
+		if err := tx.Insert("t", batchRows(1)[0]); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+		if err := fs.Rollback(tx); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	select {
+	case msg := <-bad:
+		t.Fatal(msg)
+	default:
+	}
+}
+
+func BenchmarkScanWhere(b *testing.B) {
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			fs, err := NewWithOptions(b.TempDir(), Options{ScanWorkers: workers})
+			if err != nil {
+				b.Fatalf("New failed: %v", err)
+			}
+			defer fs.Close()
+			if err := fs.CreateTable("t", batchCols); err != nil {
+				b.Fatalf("CreateTable failed: %v", err)
+			}
+			tx, _ := fs.Begin(false)
+			if err := tx.InsertBatch("t", batchRows(20000)); err != nil {
+				b.Fatalf("InsertBatch failed: %v", err)
+			}
+			if err := fs.Commit(tx); err != nil {
+				b.Fatalf("Commit failed: %v", err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rtx, _ := fs.Begin(true)
+				if _, _, err := rtx.(storage.FilteredScanner).ScanWhere("t", everyThird); err != nil {
+					b.Fatalf("ScanWhere failed: %v", err)
+				}
+			}
+		})
+	}
+}
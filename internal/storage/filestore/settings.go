@@ -0,0 +1,147 @@
+package filestore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"goDB/internal/storage"
+)
+
+// syncModeNames maps the values of the synchronous setting to sync modes,
+// following SQLite's PRAGMA synchronous.
+var syncModeNames = map[string]SyncMode{
+	"full":   SyncEachCommit,
+	"normal": SyncGroupCommit,
+	"off":    SyncAsync,
+}
+
+func (m SyncMode) String() string {
+	for name, mode := range syncModeNames {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("SyncMode(%d)", int(m))
+}
+
+// Setting implements storage.Configurer. The settings mirror Options:
+//
+//	synchronous          SyncMode: full, normal (group commit) or off (async)
+//	group_commit_window  GroupCommitWindow, e.g. 2ms
+//	page_cache           PageCacheSize in pages; 0 when the cache is disabled
+//	checkpoint_interval  CheckpointInterval, e.g. 5m; 0s when disabled
+//	read_only            ReadOnly; it cannot be changed
+func (e *FileEngine) Setting(name string) (string, error) {
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+
+	switch name {
+	case "synchronous":
+		e.syncMu.RLock()
+		defer e.syncMu.RUnlock()
+		return e.opts.SyncMode.String(), nil
+	case "group_commit_window":
+		e.syncMu.RLock()
+		defer e.syncMu.RUnlock()
+		return e.opts.GroupCommitWindow.String(), nil
+	case "page_cache":
+		if e.cache == nil {
+			return "0", nil
+		}
+		e.cache.mu.Lock()
+		defer e.cache.mu.Unlock()
+		return strconv.Itoa(e.cache.capacity), nil
+	case "checkpoint_interval":
+		return e.opts.CheckpointInterval.String(), nil
+	case "read_only":
+		return strconv.FormatBool(e.opts.ReadOnly), nil
+	default:
+		return "", fmt.Errorf("filestore: %w %q", storage.ErrUnknownSetting, name)
+	}
+}
+
+// SetSetting implements storage.Configurer; see Setting for the names. The
+// change lasts until the engine is closed: the next NewWithOptions uses its
+// Options again.
+func (e *FileEngine) SetSetting(name, value string) error {
+	e.settingsMu.Lock()
+	defer e.settingsMu.Unlock()
+
+	switch name {
+	case "synchronous", "group_commit_window", "page_cache", "checkpoint_interval":
+		if e.opts.ReadOnly {
+			return ErrReadOnly
+		}
+	case "read_only":
+		return fmt.Errorf("filestore: read_only cannot be changed while the engine is open")
+	default:
+		return fmt.Errorf("filestore: %w %q", storage.ErrUnknownSetting, name)
+	}
+
+	switch name {
+	case "synchronous":
+		mode, ok := syncModeNames[strings.ToLower(value)]
+		if !ok {
+			return fmt.Errorf("filestore: synchronous must be full, normal or off, not %q", value)
+		}
+		e.setSync(mode, e.opts.GroupCommitWindow)
+	case "group_commit_window":
+		d, err := parseSettingDuration(name, value)
+		if err != nil {
+			return err
+		}
+		e.setSync(e.opts.SyncMode, d)
+	case "page_cache":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("filestore: page_cache must be a positive number of pages, not %q", value)
+		}
+		if e.cache == nil {
+			return fmt.Errorf("filestore: page_cache: the page cache is disabled")
+		}
+		return e.cache.resize(n)
+	case "checkpoint_interval":
+		d, err := parseSettingDuration(name, value)
+		if err != nil {
+			return err
+		}
+		if e.ckpt != nil {
+			e.ckpt.close()
+			e.ckpt = nil
+		}
+		if d > 0 {
+			e.ckpt = e.startCheckpointer(d)
+		}
+		e.opts.CheckpointInterval = d
+	}
+	return nil
+}
+
+// setSync switches the commit durability policy, replacing the background
+// flusher. Commits that are waiting for an fsync finish first under the old
+// policy.
+func (e *FileEngine) setSync(mode SyncMode, window time.Duration) {
+	e.syncMu.Lock()
+	defer e.syncMu.Unlock()
+
+	if e.flusher != nil {
+		e.flusher.close()
+		e.flusher = nil
+	}
+	if mode != SyncEachCommit {
+		e.flusher = newWALFlusher(e.wal, window)
+	}
+	e.opts.SyncMode = mode
+	e.opts.GroupCommitWindow = window
+}
+
+// parseSettingDuration parses a non-negative duration setting such as "5m".
+func parseSettingDuration(name, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("filestore: %s must be a duration such as 5m, not %q", name, value)
+	}
+	return d, nil
+}
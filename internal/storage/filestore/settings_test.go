@@ -0,0 +1,88 @@
+package filestore
+
+import (
+	"errors"
+	"testing"
+
+	"goDB/internal/storage"
+)
+
+func TestFilestore_Settings(t *testing.T) {
+	dir := t.TempDir()
+	fs := newCheckpointTable(t, dir)
+	defer fs.Close()
+
+	setting := func(name string) string {
+		t.Helper()
+		v, err := fs.Setting(name)
+		if err != nil {
+			t.Fatalf("Setting(%q) failed: %v", name, err)
+		}
+		return v
+	}
+	set := func(name, value string) {
+		t.Helper()
+		if err := fs.SetSetting(name, value); err != nil {
+			t.Fatalf("SetSetting(%q, %q) failed: %v", name, value, err)
+		}
+	}
+
+	if got := setting("synchronous"); got != "full" {
+		t.Fatalf("synchronous = %q, want full", got)
+	}
+	if got := setting("page_cache"); got != "256" {
+		t.Fatalf("page_cache = %q, want 256", got)
+	}
+
+	// Commits keep working across sync mode changes.
+	for _, mode := range []string{"normal", "OFF", "full"} {
+		set("synchronous", mode)
+		commitIDs(t, fs, 1)
+	}
+	set("synchronous", "normal")
+	set("group_commit_window", "1ms")
+	commitIDs(t, fs, 2)
+	if got := setting("synchronous"); got != "normal" {
+		t.Fatalf("synchronous = %q, want normal", got)
+	}
+	if got := setting("group_commit_window"); got != "1ms" {
+		t.Fatalf("group_commit_window = %q, want 1ms", got)
+	}
+
+	// Shrinking the cache writes back the pages it evicts.
+	commitIDs(t, fs, 3, 4)
+	set("page_cache", "1")
+	if n := fs.cache.lru.Len(); n > 1 {
+		t.Fatalf("page cache holds %d pages after resize to 1", n)
+	}
+	if _, rows := scanAll(t, fs, "t"); len(rows) != 6 {
+		t.Fatalf("got %d rows, want 6", len(rows))
+	}
+
+	set("checkpoint_interval", "1h")
+	if fs.ckpt == nil || setting("checkpoint_interval") != "1h0m0s" {
+		t.Fatalf("checkpoint_interval not applied")
+	}
+	set("checkpoint_interval", "0")
+	if fs.ckpt != nil {
+		t.Fatalf("checkpointer still running after checkpoint_interval = 0")
+	}
+
+	for _, tc := range [][2]string{
+		{"synchronous", "extra"},
+		{"page_cache", "0"},
+		{"page_cache", "many"},
+		{"checkpoint_interval", "-1s"},
+		{"read_only", "true"},
+	} {
+		if err := fs.SetSetting(tc[0], tc[1]); err == nil {
+			t.Errorf("SetSetting(%q, %q) should fail", tc[0], tc[1])
+		}
+	}
+	if _, err := fs.Setting("nope"); !errors.Is(err, storage.ErrUnknownSetting) {
+		t.Fatalf("Setting(nope) error = %v, want ErrUnknownSetting", err)
+	}
+	if err := fs.SetSetting("nope", "1"); !errors.Is(err, storage.ErrUnknownSetting) {
+		t.Fatalf("SetSetting(nope) error = %v, want ErrUnknownSetting", err)
+	}
+}
@@ -0,0 +1,59 @@
+package filestore
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"goDB/internal/storage"
+)
+
+// TableStats counts the live rows, pages and deleted-row slots of a table
+// and reports the size of its file.
+func (e *FileEngine) TableStats(tableName string) (storage.TableStats, error) {
+	var st storage.TableStats
+
+	path, err := e.tablePath(tableName)
+	if err != nil {
+		return st, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return st, fmt.Errorf("filestore: open table for stats: %w", missingTable(tableName, err))
+	}
+	defer f.Close()
+
+	if _, err := readHeader(f); err != nil {
+		return st, fmt.Errorf("filestore: read header in stats: %w", err)
+	}
+	headerEnd, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return st, fmt.Errorf("filestore: seek after header in stats: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return st, fmt.Errorf("filestore: stat table: %w", err)
+	}
+	st.Bytes = fi.Size()
+
+	dataBytes := st.Bytes - headerEnd
+	if dataBytes < 0 || dataBytes%PageSize != 0 {
+		return st, fmt.Errorf("filestore: corrupt data in stats (not multiple of page size)")
+	}
+	st.Pages = int(dataBytes / PageSize)
+
+	for pageID := uint32(0); pageID < uint32(st.Pages); pageID++ {
+		p, err := e.readPage(tableName, f, headerEnd, pageID)
+		if err != nil {
+			return st, err
+		}
+		for i := uint16(0); i < p.numSlots(); i++ {
+			if off, length := p.getSlot(i); off == 0xFFFF || length == 0 {
+				st.DeadSlots++
+			} else {
+				st.Rows++
+			}
+		}
+	}
+	return st, nil
+}
@@ -0,0 +1,266 @@
+package filestore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"goDB/internal/sql"
+)
+
+// ChangeKind identifies the kind of row-level change in a Change.
+type ChangeKind int
+
+const (
+	ChangeInsert ChangeKind = iota
+	ChangeUpdate
+	ChangeDelete
+	// ChangeReplaceAll replaces a table's whole contents. The engine's
+	// UPDATE and DELETE statements currently rewrite tables this way.
+	ChangeReplaceAll
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeInsert:
+		return "INSERT"
+	case ChangeUpdate:
+		return "UPDATE"
+	case ChangeDelete:
+		return "DELETE"
+	case ChangeReplaceAll:
+		return "REPLACEALL"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is one committed modification read from the WAL.
+type Change struct {
+	TxID  uint64
+	Kind  ChangeKind
+	Table string
+
+	// Row is the inserted row, the new row of an update, or the deleted row.
+	Row sql.Row
+	// OldRow is the row before an update.
+	OldRow sql.Row
+	// Rows is the new table contents for ChangeReplaceAll.
+	Rows []sql.Row
+}
+
+// subscriber tails the WAL on behalf of one Subscribe caller.
+type subscriber struct {
+	eng    *FileEngine
+	out    chan Change
+	wake   chan struct{} // signalled after every COMMIT/ROLLBACK
+	stop   chan struct{}
+	seg    uint64 // WAL segment being read
+	offset int64  // next byte to read in seg
+
+	// pending holds the changes of transactions whose BEGIN was seen,
+	// until their COMMIT (emit) or ROLLBACK (drop).
+	pending map[uint64][]Change
+	numCols map[string]int
+}
+
+// Subscribe streams the row-level changes of every transaction that commits
+// after the call, in commit order, by tailing the WAL. Transactions that had
+// already begun when Subscribe was called are skipped.
+//
+// The returned function stops the subscription and closes the channel. The
+// feed does not drop changes: a subscriber that stops reading eventually
+// stalls its own tailing goroutine, but never blocks writers. On a read-only
+// engine the channel is closed right away.
+func (e *FileEngine) Subscribe() (<-chan Change, func()) {
+	s := &subscriber{
+		eng:     e,
+		out:     make(chan Change, 64),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		pending: make(map[uint64][]Change),
+		numCols: make(map[string]int),
+	}
+
+	if e.opts.ReadOnly {
+		close(s.out)
+		return s.out, func() {}
+	}
+
+	e.subsMu.Lock()
+	// Read the WAL end while holding subsMu, so no COMMIT can slip in
+	// between choosing the start offset and being registered for wake-ups.
+	seg, off, err := e.wal.position()
+	if err == nil {
+		s.seg, s.offset = seg, off
+		e.subs[s] = struct{}{}
+	}
+	e.subsMu.Unlock()
+
+	if err != nil {
+		close(s.out)
+		return s.out, func() {}
+	}
+
+	go s.run()
+
+	stopped := false
+	return s.out, func() {
+		e.subsMu.Lock()
+		defer e.subsMu.Unlock()
+		if !stopped {
+			stopped = true
+			delete(e.subs, s)
+			close(s.stop)
+		}
+	}
+}
+
+// notifySubscribers wakes every subscriber after a transaction ended.
+func (e *FileEngine) notifySubscribers() {
+	e.subsMu.Lock()
+	defer e.subsMu.Unlock()
+	for s := range e.subs {
+		select {
+		case s.wake <- struct{}{}:
+		default: // already has a pending wake-up
+		}
+	}
+}
+
+func (s *subscriber) run() {
+	defer close(s.out)
+
+	f, err := os.Open(walSegmentPath(s.eng.dir, s.seg))
+	if err != nil {
+		log.Printf("filestore: subscribe: open WAL: %v", err)
+		return
+	}
+	defer func() { f.Close() }()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		}
+
+		seg, end, err := s.eng.wal.position()
+		if err != nil {
+			return
+		}
+		// Finish the segments the WAL has moved past; they no longer grow.
+		for s.seg < seg {
+			info, err := f.Stat()
+			if err != nil {
+				log.Printf("filestore: subscribe: stat WAL: %v", err)
+				return
+			}
+			if !s.readUpTo(f, info.Size()) {
+				return
+			}
+			f.Close()
+			s.seg++
+			s.offset = int64(len(walMagic))
+			if f, err = os.Open(walSegmentPath(s.eng.dir, s.seg)); err != nil {
+				log.Printf("filestore: subscribe: open WAL: %v", err)
+				return
+			}
+		}
+		if !s.readUpTo(f, end) {
+			return
+		}
+	}
+}
+
+// readUpTo decodes WAL records between the current offset and end, emitting
+// the changes of each transaction that commits. It returns false when the
+// subscription should stop.
+func (s *subscriber) readUpTo(f *os.File, end int64) bool {
+	r := bufio.NewReader(io.NewSectionReader(f, s.offset, end-s.offset))
+	for {
+		rec, err := readWALRecord(r, s.tableCols)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("filestore: subscribe: %v", err)
+			return false
+		}
+
+		switch rec.recType {
+		case walRecBegin:
+			s.pending[rec.txID] = nil
+		case walRecRollback:
+			delete(s.pending, rec.txID)
+		case walRecCommit:
+			changes, ok := s.pending[rec.txID]
+			if !ok {
+				continue
+			}
+			delete(s.pending, rec.txID)
+			for _, c := range changes {
+				select {
+				case s.out <- c:
+				case <-s.stop:
+					return false
+				}
+			}
+		default:
+			if _, ok := s.pending[rec.txID]; ok {
+				s.pending[rec.txID] = append(s.pending[rec.txID], changesFromRecord(rec)...)
+			}
+		}
+	}
+	s.offset = end
+	return true
+}
+
+// tableCols returns the column count of table, reading its schema once.
+func (s *subscriber) tableCols(table string) (int, error) {
+	if n, ok := s.numCols[table]; ok {
+		return n, nil
+	}
+	cols, err := s.eng.TableSchema(table)
+	if err != nil {
+		return 0, err
+	}
+	s.numCols[table] = len(cols)
+	return len(cols), nil
+}
+
+// changesFromRecord converts a row-carrying WAL record to Changes.
+func changesFromRecord(rec walRecord) []Change {
+	switch rec.recType {
+	case walRecInsert:
+		out := make([]Change, len(rec.rows))
+		for i, r := range rec.rows {
+			out[i] = Change{TxID: rec.txID, Kind: ChangeInsert, Table: rec.table, Row: r}
+		}
+		return out
+	case walRecDelete:
+		out := make([]Change, len(rec.rows))
+		for i, r := range rec.rows {
+			out[i] = Change{TxID: rec.txID, Kind: ChangeDelete, Table: rec.table, Row: r}
+		}
+		return out
+	case walRecUpdate:
+		out := make([]Change, 0, len(rec.rows)/2)
+		for i := 0; i+1 < len(rec.rows); i += 2 {
+			out = append(out, Change{
+				TxID:   rec.txID,
+				Kind:   ChangeUpdate,
+				Table:  rec.table,
+				OldRow: rec.rows[i],
+				Row:    rec.rows[i+1],
+			})
+		}
+		return out
+	case walRecReplaceAll:
+		return []Change{{TxID: rec.txID, Kind: ChangeReplaceAll, Table: rec.table, Rows: rec.rows}}
+	default:
+		return nil
+	}
+}
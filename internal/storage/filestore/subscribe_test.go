@@ -0,0 +1,116 @@
+package filestore
+
+import (
+	"testing"
+	"time"
+
+	"goDB/internal/sql"
+)
+
+func intRow(v int64) sql.Row { return sql.Row{{Type: sql.TypeInt, I64: v}} }
+
+// nextChange waits for one change on ch.
+func nextChange(t *testing.T, ch <-chan Change) Change {
+	t.Helper()
+	select {
+	case c, ok := <-ch:
+		if !ok {
+			t.Fatalf("change feed closed unexpectedly")
+		}
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a change")
+	}
+	return Change{}
+}
+
+func TestFilestore_SubscribeCommittedChanges(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	// Committed before subscribing: not part of the feed.
+	before, _ := fs.Begin(false)
+	_ = before.Insert("t", intRow(1))
+	_ = fs.Commit(before)
+
+	// Began before subscribing: skipped even though it commits later.
+	straddling, _ := fs.Begin(false)
+	_ = straddling.Insert("t", intRow(2))
+
+	ch, cancel := fs.Subscribe()
+	defer cancel()
+
+	_ = fs.Commit(straddling)
+
+	rolled, _ := fs.Begin(false)
+	_ = rolled.Insert("t", intRow(3))
+	_ = fs.Rollback(rolled)
+
+	tx, _ := fs.Begin(false)
+	if err := tx.Insert("t", intRow(4)); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := tx.ReplaceAll("t", []sql.Row{intRow(4), intRow(5)}); err != nil {
+		t.Fatalf("ReplaceAll failed: %v", err)
+	}
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	c := nextChange(t, ch)
+	if c.Kind != ChangeInsert || c.Table != "t" || c.Row[0].I64 != 4 {
+		t.Fatalf("unexpected first change: %+v", c)
+	}
+	c = nextChange(t, ch)
+	if c.Kind != ChangeReplaceAll || len(c.Rows) != 2 || c.Rows[1][0].I64 != 5 {
+		t.Fatalf("unexpected second change: %+v", c)
+	}
+
+	cancel()
+	for range ch {
+		t.Fatalf("no further changes expected")
+	}
+}
+
+func TestFilestore_SubscribeRowLevelUpdateDelete(t *testing.T) {
+	fs, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	setup, _ := fs.Begin(false)
+	_ = setup.Insert("t", intRow(1))
+	_ = setup.Insert("t", intRow(2))
+	_ = fs.Commit(setup)
+
+	ch, cancel := fs.Subscribe()
+	defer cancel()
+
+	tx, _ := fs.Begin(false)
+	isID := func(id int64) func(sql.Row) (bool, error) {
+		return func(r sql.Row) (bool, error) { return r[0].I64 == id, nil }
+	}
+	if err := tx.UpdateWhere("t", isID(1), func(r sql.Row) (sql.Row, error) { return intRow(10), nil }); err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	if err := tx.DeleteWhere("t", isID(2)); err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	_ = fs.Commit(tx)
+
+	c := nextChange(t, ch)
+	if c.Kind != ChangeUpdate || c.OldRow[0].I64 != 1 || c.Row[0].I64 != 10 {
+		t.Fatalf("unexpected update change: %+v", c)
+	}
+	c = nextChange(t, ch)
+	if c.Kind != ChangeDelete || c.Row[0].I64 != 2 {
+		t.Fatalf("unexpected delete change: %+v", c)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"goDB/internal/storage"
 	"io"
 	"os"
-	"strings"
 )
 
 // fileTx implements storage.Tx for FileEngine.
@@ -16,20 +15,75 @@ type fileTx struct {
 	readOnly bool
 	closed   bool
 	id       uint64 // 0 = no WAL tracking (read-only or not started)
+
+	// tables records the tables this transaction wrote, for the next
+	// checkpoint.
+	tables map[string]struct{}
+}
+
+// touch records that the transaction writes to table.
+func (tx *fileTx) touch(table string) {
+	if tx.id == 0 {
+		return
+	}
+	if tx.tables == nil {
+		tx.tables = make(map[string]struct{})
+	}
+	tx.tables[table] = struct{}{}
+}
+
+// scanLock holds off writers to table for the length of one scan by a
+// read-only transaction and returns the unlock; write transactions scan
+// without it.
+func (tx *fileTx) scanLock(table string) func() {
+	if !tx.readOnly {
+		return func() {}
+	}
+	return tx.eng.readLockTable(table)
+}
+
+// logRowOps appends the DELETE and UPDATE records of one statement to the
+// WAL in a single batch. Recovery and rollback rebuild tables from the WAL
+// rather than trusting the table files, so the records may follow the page
+// writes they describe as long as they precede COMMIT.
+func (tx *fileTx) logRowOps(tableName string, cols []sql.Column, ops []walRowOp) error {
+	if len(ops) == 0 || tx.readOnly || tx.id == 0 {
+		return nil
+	}
+	return tx.eng.wal.appendRowOps(tx.id, tableName, cols, ops)
 }
 
 func (tx *fileTx) DeleteWhere(tableName string, pred storage.RowPredicate) error {
+	return tx.deleteRows(tableName, nil, func(_ btree.RID, row sql.Row) (bool, error) { return pred(row) })
+}
+
+// deleteRows deletes the rows for which match holds, reading only the given
+// pages, or all of them when pages is nil.
+func (tx *fileTx) deleteRows(tableName string, pages []uint32, match func(rid btree.RID, row sql.Row) (bool, error)) (err error) {
 	if tx.closed {
 		return fmt.Errorf("filestore: tx is closed")
 	}
 	if tx.readOnly {
 		return fmt.Errorf("filestore: cannot delete in read-only tx")
 	}
+	if err := tx.eng.checkReservation(tx); err != nil {
+		return err
+	}
 
-	path := tx.eng.tablePath(tableName)
+	tx.eng.writeMu.RLock()
+	defer tx.eng.writeMu.RUnlock()
+	defer tx.eng.lockTable(tableName)()
+
+	deleted := 0
+	defer func() { tx.eng.adjustRowCount(tableName, -deleted, err) }()
+
+	path, err := tx.eng.tablePath(tableName)
+	if err != nil {
+		return err
+	}
 	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
 	if err != nil {
-		return fmt.Errorf("filestore: open table for delete: %w", err)
+		return fmt.Errorf("filestore: open table for delete: %w", missingTable(tableName, err))
 	}
 	defer f.Close()
 
@@ -62,12 +116,16 @@ func (tx *fileTx) DeleteWhere(tableName string, pred storage.RowPredicate) error
 	}
 	numPages := uint32(dataBytes / PageSize)
 
-	for pageID := uint32(0); pageID < numPages; pageID++ {
-		p := make(pageBuf, PageSize)
-		offset := headerEnd + int64(pageID)*PageSize
+	indexes, err := tx.eng.indexesByColumn(tableName, cols)
+	if err != nil {
+		return err
+	}
 
-		if _, err := f.ReadAt(p, offset); err != nil {
-			return fmt.Errorf("filestore: read page %d in delete: %w", pageID, err)
+	var walOps []walRowOp // logged together once every page is done
+	for _, pageID := range pageList(pages, numPages) {
+		p, err := tx.eng.readPage(tableName, f, headerEnd, pageID)
+		if err != nil {
+			return err
 		}
 
 		nSlots := p.numSlots()
@@ -90,44 +148,67 @@ func (tx *fileTx) DeleteWhere(tableName string, pred storage.RowPredicate) error
 				return fmt.Errorf("filestore: read row in delete: %w", err)
 			}
 
-			match, err := pred(row)
+			rid := btree.RID{PageID: pageID, SlotID: i}
+			ok, err := match(rid, row)
 			if err != nil {
 				return err
 			}
-			if match {
-				// WAL: log delete
-				if !tx.readOnly && tx.id != 0 {
-					if err := tx.eng.wal.appendDelete(tx.id, tableName, row); err != nil {
-						return fmt.Errorf("filestore: WAL appendDelete: %w", err)
-					}
-				}
+			if ok {
+				tx.touch(tableName)
+				walOps = append(walOps, walRowOp{oldRow: row})
 				p.deleteSlot(i)
+				deleted++
+				if err := reindexRow(indexes, rid, row, nil); err != nil {
+					return err
+				}
 			}
 		}
 
-		// Write modified page back to disk.
-		if _, err := f.WriteAt(p, offset); err != nil {
-			return fmt.Errorf("filestore: write page %d in delete: %w", pageID, err)
+		if err := tx.eng.writePage(tableName, f, headerEnd, pageID, p); err != nil {
+			return err
 		}
 	}
 
-	// NOTE: currently we do NOT log per-row deletes in WAL, so crash recovery
-	// may not restore these deletes. We’ll address WAL integration later.
-	return nil
+	if err := tx.logRowOps(tableName, cols, walOps); err != nil {
+		return fmt.Errorf("filestore: WAL delete: %w", err)
+	}
+	return tx.eng.flushPages(tableName, f)
 }
 
 func (tx *fileTx) UpdateWhere(tableName string, pred storage.RowPredicate, updater storage.RowUpdater) error {
+	return tx.updateRows(tableName, nil,
+		func(_ btree.RID, row sql.Row) (bool, error) { return pred(row) },
+		func(_ btree.RID, row sql.Row) (sql.Row, error) { return updater(row) })
+}
+
+// updateRows replaces the rows for which match holds by the result of
+// updater, reading only the given pages, or all of them when pages is nil.
+func (tx *fileTx) updateRows(tableName string, pages []uint32, match func(rid btree.RID, row sql.Row) (bool, error), updater func(rid btree.RID, row sql.Row) (sql.Row, error)) (err error) {
 	if tx.closed {
 		return fmt.Errorf("filestore: tx is closed")
 	}
 	if tx.readOnly {
 		return fmt.Errorf("filestore: cannot update in read-only tx")
 	}
+	if err := tx.eng.checkReservation(tx); err != nil {
+		return err
+	}
+
+	tx.eng.writeMu.RLock()
+	defer tx.eng.writeMu.RUnlock()
+	defer tx.eng.lockTable(tableName)()
+
+	// Rows that move are deleted here and counted again by insertBatch.
+	var extraRows []sql.Row // updated rows that no longer fit in place
+	defer func() { tx.eng.adjustRowCount(tableName, -len(extraRows), err) }()
 
-	path := tx.eng.tablePath(tableName)
+	path, err := tx.eng.tablePath(tableName)
+	if err != nil {
+		return err
+	}
 	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
 	if err != nil {
-		return fmt.Errorf("filestore: open table for update: %w", err)
+		return fmt.Errorf("filestore: open table for update: %w", missingTable(tableName, err))
 	}
 	defer f.Close()
 
@@ -159,14 +240,16 @@ func (tx *fileTx) UpdateWhere(tableName string, pred storage.RowPredicate, updat
 	}
 	numPages := uint32(dataBytes / PageSize)
 
-	var extraRows []sql.Row // updated rows that no longer fit in place
-
-	for pageID := uint32(0); pageID < numPages; pageID++ {
-		p := make(pageBuf, PageSize)
-		offset := headerEnd + int64(pageID)*PageSize
+	indexes, err := tx.eng.indexesByColumn(tableName, cols)
+	if err != nil {
+		return err
+	}
 
-		if _, err := f.ReadAt(p, offset); err != nil {
-			return fmt.Errorf("filestore: read page %d in update: %w", pageID, err)
+	var walOps []walRowOp // logged together once every page is done
+	for _, pageID := range pageList(pages, numPages) {
+		p, err := tx.eng.readPage(tableName, f, headerEnd, pageID)
+		if err != nil {
+			return err
 		}
 
 		nSlots := p.numSlots()
@@ -190,59 +273,68 @@ func (tx *fileTx) UpdateWhere(tableName string, pred storage.RowPredicate, updat
 				return fmt.Errorf("filestore: read row in update: %w", err)
 			}
 
-			match, err := pred(oldRow)
+			rid := btree.RID{PageID: pageID, SlotID: i}
+			ok, err := match(rid, oldRow)
 			if err != nil {
 				return err
 			}
-			if !match {
+			if !ok {
 				continue
 			}
 
 			// Apply updater on a copy so WAL retains the original values.
 			origRow := cloneRow(oldRow)
-			newRow, err := updater(cloneRow(oldRow))
+			newRow, err := updater(rid, cloneRow(oldRow))
 			if err != nil {
 				return err
 			}
 
-			newBytes, err := encodeRowToBytes(newRow)
+			newBytes, err := encodeRowCheckedToBytes(newRow, cols, tx.eng.opts.PackBools)
 			if err != nil {
 				return fmt.Errorf("filestore: encode updated row: %w", err)
 			}
 
-			if len(newBytes) <= int(length) {
-				// In-place update: log UPDATE, then overwrite.
-				if !tx.readOnly && tx.id != 0 {
-					if err := tx.eng.wal.appendUpdate(tx.id, tableName, origRow, newRow); err != nil {
-						return fmt.Errorf("filestore: WAL appendUpdate: %w", err)
-					}
+			if p.fitsUpdate(i, len(newBytes)) {
+				// The row stays in its slot, so scan order is unchanged:
+				// log UPDATE, then overwrite.
+				tx.touch(tableName)
+				walOps = append(walOps, walRowOp{oldRow: origRow, newRow: newRow})
+				if err := p.updateRow(i, newBytes); err != nil {
+					return fmt.Errorf("filestore: update slot %d: %w", i, err)
 				}
-
-				copy(p[start:start+len(newBytes)], newBytes)
-				p.setSlot(i, off, uint16(len(newBytes)))
-			} else {
-				// New row is larger: log DELETE(old), delete slot, and reinsert via Insert (which logs INSERT).
-				if !tx.readOnly && tx.id != 0 {
-					if err := tx.eng.wal.appendDelete(tx.id, tableName, origRow); err != nil {
-						return fmt.Errorf("filestore: WAL appendDelete (update-grow): %w", err)
-					}
+				if err := reindexRow(indexes, rid, origRow, newRow); err != nil {
+					return err
 				}
-
+			} else {
+				// New row no longer fits on the page: log DELETE(old), delete
+				// slot, and reinsert via Insert (which logs INSERT). The row
+				// moves to the end of the table.
+				tx.touch(tableName)
+				walOps = append(walOps, walRowOp{oldRow: origRow})
 				p.deleteSlot(i)
+				if err := reindexRow(indexes, rid, origRow, nil); err != nil {
+					return err
+				}
 				extraRows = append(extraRows, newRow)
 			}
 
 		}
 
-		// Write modified page back
-		if _, err := f.WriteAt(p, offset); err != nil {
-			return fmt.Errorf("filestore: write page %d in update: %w", pageID, err)
+		if err := tx.eng.writePage(tableName, f, headerEnd, pageID, p); err != nil {
+			return err
 		}
 	}
 
+	if err := tx.logRowOps(tableName, cols, walOps); err != nil {
+		return fmt.Errorf("filestore: WAL update: %w", err)
+	}
+	if err := tx.eng.flushPages(tableName, f); err != nil {
+		return err
+	}
+
 	// Reinsertion step for updated rows that did not fit in place.
 	for _, r := range extraRows {
-		if err := tx.Insert(tableName, r); err != nil {
+		if err := tx.insertBatch(tableName, []sql.Row{r}); err != nil {
 			return fmt.Errorf("filestore: insert expanded updated row: %w", err)
 		}
 	}
@@ -252,23 +344,44 @@ func (tx *fileTx) UpdateWhere(tableName string, pred storage.RowPredicate, updat
 
 // Insert using a page structure
 func (tx *fileTx) Insert(tableName string, row sql.Row) error {
+	return tx.InsertBatch(tableName, []sql.Row{row})
+}
+
+// InsertBatch appends rows to the table with a single WAL record. All rows
+// are validated and encoded before anything is written, the last page is
+// read once, and each page is written once after it has been filled.
+func (tx *fileTx) InsertBatch(tableName string, rows []sql.Row) error {
 	if tx.closed {
 		return fmt.Errorf("filestore: tx is closed")
 	}
 	if tx.readOnly {
 		return fmt.Errorf("filestore: cannot insert in read-only transaction")
 	}
-
-	if !tx.readOnly && tx.id != 0 {
-		if err := tx.eng.wal.appendInsert(tx.id, tableName, row); err != nil {
-			return fmt.Errorf("filestore: WAL appendInsert: %w", err)
-		}
+	if err := tx.eng.checkReservation(tx); err != nil {
+		return err
 	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	tx.eng.writeMu.RLock()
+	defer tx.eng.writeMu.RUnlock()
+	defer tx.eng.lockTable(tableName)()
+	return tx.insertBatch(tableName, rows)
+}
+
+// insertBatch implements InsertBatch. The caller holds writeMu and the
+// table lock.
+func (tx *fileTx) insertBatch(tableName string, rows []sql.Row) (err error) {
+	defer func() { tx.eng.adjustRowCount(tableName, len(rows), err) }()
 
-	path := tx.eng.tablePath(tableName)
+	path, err := tx.eng.tablePath(tableName)
+	if err != nil {
+		return err
+	}
 	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
 	if err != nil {
-		return fmt.Errorf("filestore: open table for insert: %w", err)
+		return fmt.Errorf("filestore: open table for insert: %w", missingTable(tableName, err))
 	}
 	defer f.Close()
 
@@ -276,9 +389,18 @@ func (tx *fileTx) Insert(tableName string, row sql.Row) error {
 	if err != nil {
 		return fmt.Errorf("filestore: read header in insert: %w", err)
 	}
-	if len(row) != len(cols) {
-		return fmt.Errorf("filestore: row has %d values, expected %d", len(row), len(cols))
+
+	encoded := make([][]byte, len(rows))
+	for i, row := range rows {
+		encoded[i], err = encodeRowCheckedToBytes(row, cols, tx.eng.opts.PackBools)
+		if err != nil {
+			return fmt.Errorf("filestore: encode row: %w", err)
+		}
+		if len(encoded[i]) > maxRowSize {
+			return fmt.Errorf("filestore: row of %d bytes does not fit in a page", len(encoded[i]))
+		}
 	}
+
 	headerEnd, err := f.Seek(0, io.SeekCurrent)
 	if err != nil {
 		return fmt.Errorf("filestore: seek after header: %w", err)
@@ -304,58 +426,53 @@ func (tx *fileTx) Insert(tableName string, row sql.Row) error {
 		numPages = 0
 	}
 
-	rowBytes, err := encodeRowToBytes(row)
-	if err != nil {
-		return fmt.Errorf("filestore: encode row: %w", err)
+	if tx.id != 0 {
+		tx.touch(tableName)
+		if err := tx.eng.wal.appendInsertBatch(tx.id, tableName, cols, rows); err != nil {
+			return fmt.Errorf("filestore: WAL appendInsert: %w", err)
+		}
 	}
 
-	var pageID uint32
-	var slotID uint16
-
 	writePage := func(id uint32, p pageBuf) error {
-		offset := headerEnd + int64(id)*PageSize
-		if _, err := f.WriteAt(p, offset); err != nil {
-			return fmt.Errorf("filestore: write page %d: %w", id, err)
-		}
-		return nil
+		return tx.eng.writePage(tableName, f, headerEnd, id, p)
 	}
 
+	// Start filling the last page, or a fresh one for an empty table.
+	var pageID uint32
+	var p pageBuf
 	if numPages == 0 {
-		p := newEmptyHeapPage(0)
-		slotID, err = p.insertRow(rowBytes)
-		if err != nil {
-			return fmt.Errorf("filestore: insert into empty page: %w", err)
-		}
 		pageID = 0
-		if err := writePage(pageID, p); err != nil {
-			return err
-		}
+		p = newEmptyHeapPage(0)
 	} else {
-		lastID := numPages - 1
-		p := make(pageBuf, PageSize)
-		offset := headerEnd + int64(lastID)*PageSize
-		if _, err := f.ReadAt(p, offset); err != nil {
-			return fmt.Errorf("filestore: read last page: %w", err)
+		pageID = numPages - 1
+		p, err = tx.eng.readPage(tableName, f, headerEnd, pageID)
+		if err != nil {
+			return err
 		}
+	}
 
-		slotID, err = p.insertRow(rowBytes)
-		if err == nil {
-			pageID = lastID
+	rids := make([]btree.RID, len(rows))
+	for i, rowBytes := range encoded {
+		slotID, err := p.insertRow(rowBytes)
+		if err != nil {
+			// Page is full: flush it and continue on a new one.
 			if err := writePage(pageID, p); err != nil {
 				return err
 			}
-		} else {
-			newID := numPages
-			p = newEmptyHeapPage(newID)
+			pageID++
+			p = newEmptyHeapPage(pageID)
 			slotID, err = p.insertRow(rowBytes)
 			if err != nil {
 				return fmt.Errorf("filestore: insert into new page: %w", err)
 			}
-			pageID = newID
-			if err := writePage(pageID, p); err != nil {
-				return err
-			}
 		}
+		rids[i] = btree.RID{PageID: pageID, SlotID: slotID}
+	}
+	if err := writePage(pageID, p); err != nil {
+		return err
+	}
+	if err := tx.eng.flushPages(tableName, f); err != nil {
+		return err
 	}
 
 	// Update indexes
@@ -364,11 +481,14 @@ func (tx *fileTx) Insert(tableName string, row sql.Row) error {
 
 	if tableIndexes, ok := tx.eng.indexes[tableName]; ok {
 		for colIdx, col := range cols {
-			if idx, ok := tableIndexes[col.Name]; ok {
+			idx, ok := tableIndexes[col.Name]
+			if !ok {
+				continue
+			}
+			for i, row := range rows {
 				val := row[colIdx]
 				if val.Type != sql.TypeNull {
-					rid := btree.RID{PageID: pageID, SlotID: slotID}
-					if err := idx.btree.Insert(val.I64, rid); err != nil {
+					if err := idx.btree.Insert(val.I64, rids[i]); err != nil {
 						return fmt.Errorf("error updating index for column %q: %w", col.Name, err)
 					}
 				}
@@ -379,70 +499,6 @@ func (tx *fileTx) Insert(tableName string, row sql.Row) error {
 	return nil
 }
 
-// Scan reads all rows from the table file.
-func (tx *fileTx) Scan(tableName string) ([]string, []sql.Row, error) {
-	if tx.closed {
-		return nil, nil, fmt.Errorf("filestore: tx is closed")
-	}
-
-	path := tx.eng.tablePath(tableName)
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, nil, fmt.Errorf("filestore: open table for scan: %w", err)
-	}
-	defer f.Close()
-
-	cols, err := readHeader(f)
-	if err != nil {
-		return nil, nil, fmt.Errorf("filestore: read header in scan: %w", err)
-	}
-	headerEnd, err := f.Seek(0, io.SeekCurrent)
-	if err != nil {
-		return nil, nil, fmt.Errorf("filestore: seek after header: %w", err)
-	}
-
-	colNames := make([]string, len(cols))
-	for i, c := range cols {
-		colNames[i] = c.Name
-	}
-
-	fi, err := f.Stat()
-	if err != nil {
-		return nil, nil, fmt.Errorf("filestore: stat table in scan: %w", err)
-	}
-	fileSize := fi.Size()
-	if fileSize < headerEnd {
-		return nil, nil, fmt.Errorf("filestore: corrupt file, size < header")
-	}
-	dataBytes := fileSize - headerEnd
-	if dataBytes == 0 {
-		return colNames, nil, nil
-	}
-	if dataBytes%PageSize != 0 {
-		return nil, nil, fmt.Errorf("filestore: corrupt data (not multiple of page size)")
-	}
-	numPages := uint32(dataBytes / PageSize)
-
-	var rows []sql.Row
-	for pageID := uint32(0); pageID < numPages; pageID++ {
-		p := make(pageBuf, PageSize)
-		offset := headerEnd + int64(pageID)*PageSize
-		if _, err := f.ReadAt(p, offset); err != nil {
-			return nil, nil, fmt.Errorf("filestore: read page %d: %w", pageID, err)
-		}
-
-		err := p.iterateRows(len(cols), func(slot uint16, r sql.Row) error {
-			rows = append(rows, r)
-			return nil
-		})
-		if err != nil {
-			return nil, nil, fmt.Errorf("filestore: iterate rows in page %d: %w", pageID, err)
-		}
-	}
-
-	return colNames, rows, nil
-}
-
 // ReplaceAll truncates the table file and rewrites header + rows.
 func (tx *fileTx) ReplaceAll(tableName string, rows []sql.Row) error {
 	if tx.closed {
@@ -451,17 +507,27 @@ func (tx *fileTx) ReplaceAll(tableName string, rows []sql.Row) error {
 	if tx.readOnly {
 		return fmt.Errorf("filestore: cannot replace in read-only transaction")
 	}
-
-	if !tx.readOnly && tx.id != 0 {
-		if err := tx.eng.wal.appendReplaceAll(tx.id, tableName, rows); err != nil {
-			return fmt.Errorf("filestore: WAL appendReplaceAll: %w", err)
-		}
+	if err := tx.eng.checkReservation(tx); err != nil {
+		return err
 	}
 
-	path := tx.eng.tablePath(tableName)
+	tx.eng.writeMu.RLock()
+	defer tx.eng.writeMu.RUnlock()
+	defer tx.eng.lockTable(tableName)()
+	return tx.replaceAll(tableName, rows)
+}
+
+// replaceAll implements ReplaceAll. The caller holds writeMu.
+func (tx *fileTx) replaceAll(tableName string, rows []sql.Row) (err error) {
+	defer func() { tx.eng.setRowCount(tableName, len(rows), err) }()
+
+	path, err := tx.eng.tablePath(tableName)
+	if err != nil {
+		return err
+	}
 	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
 	if err != nil {
-		return fmt.Errorf("filestore: open table for replace: %w", err)
+		return fmt.Errorf("filestore: open table for replace: %w", missingTable(tableName, err))
 	}
 	defer f.Close()
 
@@ -477,34 +543,25 @@ func (tx *fileTx) ReplaceAll(tableName string, rows []sql.Row) error {
 		return fmt.Errorf("filestore: replace on table %q with no columns", tableName)
 	}
 
+	// Check every row before it is logged or the table is truncated.
 	for i, r := range rows {
-		if len(r) != len(cols) {
-			return fmt.Errorf("filestore: replace row %d length mismatch: got %d, expected %d",
-				i, len(r), len(cols))
+		if err := checkRowTypes(r, cols); err != nil {
+			return fmt.Errorf("filestore: replace row %d: %w", i, err)
 		}
 	}
 
-	tx.eng.idxMu.RLock()
-	idxInfos, hasIndexes := tx.eng.indexes[tableName]
-	tx.eng.idxMu.RUnlock()
-
-	indexColumns := make(map[int]*indexInfo)
-	if hasIndexes {
-		for colName, info := range idxInfos {
-			colIdx := -1
-			for i, c := range cols {
-				if strings.EqualFold(c.Name, colName) {
-					colIdx = i
-					break
-				}
-			}
-			if colIdx == -1 {
-				return fmt.Errorf("filestore: index on unknown column %q for table %q", colName, tableName)
-			}
-			indexColumns[colIdx] = info
+	if !tx.readOnly && tx.id != 0 {
+		tx.touch(tableName)
+		if err := tx.eng.wal.appendReplaceAll(tx.id, tableName, cols, rows); err != nil {
+			return fmt.Errorf("filestore: WAL appendReplaceAll: %w", err)
 		}
 	}
 
+	indexColumns, err := tx.eng.indexesByColumn(tableName, cols)
+	if err != nil {
+		return err
+	}
+
 	oldKeys := make(map[int]map[btree.Key]struct{})
 	if len(indexColumns) > 0 {
 		fi, err := f.Stat()
@@ -521,14 +578,15 @@ func (tx *fileTx) ReplaceAll(tableName string, rows []sql.Row) error {
 		}
 		numPages := uint32(dataBytes / PageSize)
 
+		// Only integer keys are read, so rows can borrow from the page.
+		scratch := make(sql.Row, len(cols))
 		for pageID := uint32(0); pageID < numPages; pageID++ {
-			p := make(pageBuf, PageSize)
-			offset := headerEnd + int64(pageID)*PageSize
-			if _, err := f.ReadAt(p, offset); err != nil {
-				return fmt.Errorf("filestore: read page %d in replace: %w", pageID, err)
+			p, err := tx.eng.readPage(tableName, f, headerEnd, pageID)
+			if err != nil {
+				return err
 			}
 
-			if err := p.iterateRows(len(cols), func(_ uint16, r sql.Row) error {
+			if err := p.iterateRowsInto(scratch, true, func(_ uint16, r sql.Row) error {
 				for colIdx := range indexColumns {
 					val := r[colIdx]
 					if val.Type == sql.TypeNull {
@@ -555,6 +613,8 @@ func (tx *fileTx) ReplaceAll(tableName string, rows []sql.Row) error {
 		}
 	}
 
+	// The file is rewritten from scratch, bypassing the page cache.
+	tx.eng.invalidatePages(tableName)
 	if err := f.Truncate(0); err != nil {
 		return fmt.Errorf("filestore: truncate in replace: %w", err)
 	}
@@ -577,11 +637,12 @@ func (tx *fileTx) ReplaceAll(tableName string, rows []sql.Row) error {
 		if _, err := f.WriteAt(pg, offset); err != nil {
 			return fmt.Errorf("filestore: write page %d in replace: %w", id, err)
 		}
+		tx.eng.pageIO.written(len(pg))
 		return nil
 	}
 
 	for _, r := range rows {
-		rowBytes, err := encodeRowToBytes(r)
+		rowBytes, err := encodeRowCheckedToBytes(r, cols, tx.eng.opts.PackBools)
 		if err != nil {
 			return fmt.Errorf("filestore: encode row in replace: %w", err)
 		}
@@ -1,16 +1,17 @@
 package filestore
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"goDB/internal/sql"
 	"io"
 	"os"
-	"path/filepath"
 	"sync"
+	"sync/atomic"
 )
 
-// WAL file format (version 2):
+// WAL file format (version 2), used by every segment file:
 //
 //   magic: "GODBWAL2" (8 bytes)
 //
@@ -26,13 +27,30 @@ import (
 //     INSERT:     recType = 4, payload:
 //                  tableNameLen: uint16
 //                  tableName:    bytes
-//                  rowCount:     uint32 (must be 1 for INSERT)
-//                  row data:     encoded row (see writeRow)
+//                  rowCount:     uint32 (number of inserted rows)
+//                  row data:     encoded rows (see writeRow)
 //     REPLACEALL: recType = 5, payload:
 //                  tableNameLen: uint16
 //                  tableName:    bytes
 //                  rowCount:     uint32
 //                  row data:     repeated rowCount times
+//
+//   Every record sets walRecLSN in recType and stores its log sequence
+//   number, a uint64 that increases by one per record across the life of
+//   the database, right after txID. Records written before LSNs existed
+//   count as LSN 0.
+//
+//   Records that carry rows set walRecColCount in recType and store a
+//   colCount uint16 right after rowCount. Every encoded value already starts
+//   with its type tag, so with the width recorded the rows decode without
+//   consulting the table's current schema. Records written before the flag
+//   existed are decoded using the schema's column count.
+//
+//   Row records also set walRecColTypes and store the column types of the
+//   table when they were written, one uint8 per column, right after
+//   colCount. Replay checks them against the table's schema, so records
+//   are never applied to a table whose columns have changed since. Records
+//   without the flag are replayed unchecked.
 
 const (
 	walMagic = "GODBWAL2"
@@ -44,18 +62,49 @@ const (
 	walRecReplaceAll uint8 = 5
 	walRecDelete     uint8 = 6
 	walRecUpdate     uint8 = 7
+
+	// walRecColCount is set in the recType of row records that record
+	// their column count.
+	walRecColCount uint8 = 0x80
+	// walRecLSN is set in the recType of records that carry an LSN.
+	walRecLSN uint8 = 0x40
+	// walRecColTypes is set in the recType of row records that record
+	// their column types.
+	walRecColTypes uint8 = 0x20
 )
 
-// walLogger is a simple append-only WAL writer.
+// walLogger is a simple append-only WAL writer. The log is split into
+// numbered segment files (see wal_segment.go); records are always appended
+// to the newest one.
 type walLogger struct {
-	mu   sync.Mutex
-	f    *os.File
-	path string
+	mu      sync.Mutex
+	dir     string
+	f       *os.File // current segment
+	seg     uint64   // number of the current segment
+	segSize int64    // start a new segment once f reaches this size; <= 0 never
+	base    int64    // bytes in the segments before seg written since newWAL
+	lsn     uint64   // LSN of the last record appended
+
+	bytesWritten atomic.Int64 // bytes appended since newWAL, see Metrics
+	syncs        atomic.Int64 // fsyncs since newWAL
 }
 
-// newWAL opens or creates WAL file and ensures correct magic header.
-func newWAL(dir string) (*walLogger, error) {
-	path := filepath.Join(dir, "wal.log")
+// newWAL opens the newest WAL segment in dir, creating the first one if
+// there is none, and ensures it has the correct magic header. A wal.log
+// left by versions without segments becomes the first segment.
+func newWAL(dir string, segSize int64) (*walLogger, error) {
+	if err := adoptLegacyWAL(dir); err != nil {
+		return nil, err
+	}
+	segs, err := walSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	var seg uint64
+	if len(segs) > 0 {
+		seg = segs[len(segs)-1]
+	}
+	path := walSegmentPath(dir, seg)
 
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
 	if err != nil {
@@ -94,8 +143,10 @@ func newWAL(dir string) (*walLogger, error) {
 	}
 
 	return &walLogger{
-		f:    f,
-		path: path,
+		dir:     dir,
+		f:       f,
+		seg:     seg,
+		segSize: segSize,
 	}, nil
 }
 
@@ -118,9 +169,18 @@ func (w *walLogger) Sync() error {
 	if w.f == nil {
 		return fmt.Errorf("wal: closed")
 	}
+	w.syncs.Add(1)
 	return w.f.Sync()
 }
 
+// Write appends p to the current segment and counts it in Metrics. Records
+// are written through it; the caller holds w.mu.
+func (w *walLogger) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.bytesWritten.Add(int64(n))
+	return n, err
+}
+
 // appendBegin writes a BEGIN record for txID.
 func (w *walLogger) appendBegin(txID uint64) error {
 	return w.appendNoPayload(walRecBegin, txID)
@@ -143,116 +203,323 @@ func (w *walLogger) appendNoPayload(recType uint8, txID uint64) error {
 		return fmt.Errorf("wal: closed")
 	}
 
+	return w.writeRecordStart(txID, recType)
+}
+
+// writeRecordStart writes the fields every record begins with and assigns
+// the record the next LSN. When the current segment has grown past the
+// segment size, the record starts a new one.
+func (w *walLogger) writeRecordStart(txID uint64, recType uint8) error {
+	if err := w.rotateIfFull(); err != nil {
+		return err
+	}
+	if err := encodeRecordStart(w, txID, recType, w.lsn+1); err != nil {
+		return err
+	}
+	w.lsn++
+	return nil
+}
+
+// encodeRecordStart writes the fields every record begins with to dst.
+func encodeRecordStart(dst io.Writer, txID uint64, recType uint8, lsn uint64) error {
 	// recType
-	if err := binary.Write(w.f, binary.LittleEndian, recType); err != nil {
+	if err := binary.Write(dst, binary.LittleEndian, recType|walRecLSN); err != nil {
 		return err
 	}
 	// txID
-	if err := binary.Write(w.f, binary.LittleEndian, txID); err != nil {
+	if err := binary.Write(dst, binary.LittleEndian, txID); err != nil {
 		return err
 	}
-	return nil
+	// LSN
+	return binary.Write(dst, binary.LittleEndian, lsn)
 }
 
-// appendInsert logs an INSERT record for txID.
-func (w *walLogger) appendInsert(txID uint64, table string, row sql.Row) error {
+// lastLSN returns the LSN of the last record appended.
+func (w *walLogger) lastLSN() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lsn
+}
+
+// setLastLSN makes the next record get LSN lsn+1. Recovery calls it once it
+// knows the highest LSN in use.
+func (w *walLogger) setLastLSN(lsn uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lsn = lsn
+}
+
+// appendInsertBatch logs one INSERT record carrying all rows for txID. cols
+// is the schema of table.
+func (w *walLogger) appendInsertBatch(txID uint64, table string, cols []sql.Column, rows []sql.Row) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	if w.f == nil {
 		return fmt.Errorf("wal: closed")
 	}
 
-	if err := w.writeRecordHeader(txID, walRecInsert, table, 1); err != nil {
-		return err
-	}
-	if err := writeRow(w.f, row); err != nil {
-		return fmt.Errorf("wal: write row: %w", err)
-	}
-	return nil
+	return w.writeRowRecord(txID, walRecInsert, table, cols, rows)
 }
 
-// appendReplaceAll logs a REPLACEALL record for txID.
-func (w *walLogger) appendReplaceAll(txID uint64, table string, rows []sql.Row) error {
+// appendReplaceAll logs a REPLACEALL record for txID. cols is the schema of
+// table.
+func (w *walLogger) appendReplaceAll(txID uint64, table string, cols []sql.Column, rows []sql.Row) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	if w.f == nil {
 		return fmt.Errorf("wal: closed")
 	}
 
-	if err := w.writeRecordHeader(txID, walRecReplaceAll, table, len(rows)); err != nil {
-		return err
-	}
-	for _, r := range rows {
-		if err := writeRow(w.f, r); err != nil {
-			return fmt.Errorf("wal: write row: %w", err)
-		}
-	}
-	return nil
+	return w.writeRowRecord(txID, walRecReplaceAll, table, cols, rows)
 }
 
-func (w *walLogger) writeRecordHeader(txID uint64, recType uint8, table string, rowCount int) error {
+// writeRowRecord writes a row record of recType for txID, its header
+// followed by rows. The caller holds w.mu.
+func (w *walLogger) writeRowRecord(txID uint64, recType uint8, table string, cols []sql.Column, rows []sql.Row) error {
 	if w.f == nil {
 		return fmt.Errorf("wal: closed")
 	}
-
-	// recType
-	if err := binary.Write(w.f, binary.LittleEndian, recType); err != nil {
+	// Encode the whole record first: a row that does not fit the schema
+	// must not leave half a record behind.
+	var buf bytes.Buffer
+	if err := encodeRowRecord(&buf, table, cols, rows); err != nil {
 		return err
 	}
-	// txID
-	if err := binary.Write(w.f, binary.LittleEndian, txID); err != nil {
+	if err := w.writeRecordStart(txID, recType|walRecColCount|walRecColTypes); err != nil {
 		return err
 	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
 
+// encodeRowRecord writes the part of a row record that follows the record
+// start to dst: table name, row count, column count and types, then rows,
+// each of which must have one value per column of cols.
+func encodeRowRecord(dst io.Writer, table string, cols []sql.Column, rows []sql.Row) error {
 	nameBytes := []byte(table)
 	if len(nameBytes) > 0xFFFF {
 		return fmt.Errorf("wal: table name too long")
 	}
-	if err := binary.Write(w.f, binary.LittleEndian, uint16(len(nameBytes))); err != nil {
+	if len(cols) > 0xFFFF {
+		return fmt.Errorf("wal: too many columns")
+	}
+	if err := binary.Write(dst, binary.LittleEndian, uint16(len(nameBytes))); err != nil {
 		return err
 	}
-	if _, err := w.f.Write(nameBytes); err != nil {
+	if _, err := dst.Write(nameBytes); err != nil {
 		return err
 	}
-
-	if err := binary.Write(w.f, binary.LittleEndian, uint32(rowCount)); err != nil {
+	if err := binary.Write(dst, binary.LittleEndian, uint32(len(rows))); err != nil {
+		return err
+	}
+	if err := binary.Write(dst, binary.LittleEndian, uint16(len(cols))); err != nil {
+		return err
+	}
+	types := make([]byte, len(cols))
+	for i, c := range cols {
+		types[i] = byte(c.Type)
+	}
+	if _, err := dst.Write(types); err != nil {
 		return err
 	}
 
+	for _, r := range rows {
+		if len(r) != len(cols) {
+			return fmt.Errorf("wal: row has %d values for %d columns", len(r), len(cols))
+		}
+		if err := writeRow(dst, r); err != nil {
+			return fmt.Errorf("wal: write row: %w", err)
+		}
+	}
 	return nil
 }
-func (w *walLogger) appendDelete(txID uint64, table string, row sql.Row) error {
+
+// appendDelete logs a DELETE record for txID.
+func (w *walLogger) appendDelete(txID uint64, table string, cols []sql.Column, row sql.Row) error {
+	return w.appendRowOps(txID, table, cols, []walRowOp{{oldRow: row}})
+}
+
+// appendUpdate logs an UPDATE record for txID, carrying [oldRow, newRow].
+func (w *walLogger) appendUpdate(txID uint64, table string, cols []sql.Column, oldRow, newRow sql.Row) error {
+	return w.appendRowOps(txID, table, cols, []walRowOp{{oldRow: oldRow, newRow: newRow}})
+}
+
+// walRowOp is one DELETE or UPDATE of a batch passed to appendRowOps. A
+// DELETE has no newRow.
+type walRowOp struct {
+	oldRow sql.Row
+	newRow sql.Row
+}
+
+// appendRowOps logs one DELETE or UPDATE record per op for txID, in order.
+// The records are the same as appendDelete and appendUpdate write, but they
+// are encoded up front and appended with a single write under one lock, so
+// a statement's changes sit together in one segment. cols is the schema of
+// table.
+func (w *walLogger) appendRowOps(txID uint64, table string, cols []sql.Column, ops []walRowOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	if w.f == nil {
 		return fmt.Errorf("wal: closed")
 	}
 
-	if err := w.writeRecordHeader(txID, walRecDelete, table, 1); err != nil {
+	var buf bytes.Buffer
+	for i, op := range ops {
+		recType, rows := walRecDelete, []sql.Row{op.oldRow}
+		if op.newRow != nil {
+			recType, rows = walRecUpdate, []sql.Row{op.oldRow, op.newRow}
+		}
+		if err := encodeRecordStart(&buf, txID, recType|walRecColCount|walRecColTypes, w.lsn+1+uint64(i)); err != nil {
+			return err
+		}
+		if err := encodeRowRecord(&buf, table, cols, rows); err != nil {
+			return err
+		}
+	}
+
+	if err := w.rotateIfFull(); err != nil {
 		return err
 	}
-	if err := writeRow(w.f, row); err != nil {
-		return fmt.Errorf("wal: write delete row: %w", err)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
 	}
+	w.lsn += uint64(len(ops))
 	return nil
 }
 
-func (w *walLogger) appendUpdate(txID uint64, table string, oldRow, newRow sql.Row) error {
+// walRecord is one decoded WAL record. table and rows are only set for
+// records that carry row data.
+type walRecord struct {
+	recType uint8
+	txID    uint64
+	lsn     uint64 // 0 for records written before LSNs existed
+	table   string
+	types   []sql.DataType // column types when written; nil for older records
+	rows    []sql.Row
+}
+
+// readWALRecord decodes the next record from r. numCols reports how many
+// columns a table has; it is only consulted for records that predate the
+// recorded column count. It returns io.EOF when r ends exactly at a record
+// boundary.
+func readWALRecord(r io.Reader, numCols func(table string) (int, error)) (walRecord, error) {
+	var rec walRecord
+
+	if err := binary.Read(r, binary.LittleEndian, &rec.recType); err != nil {
+		if err == io.EOF {
+			return rec, io.EOF
+		}
+		return rec, fmt.Errorf("read recType: %w", err)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &rec.txID); err != nil {
+		return rec, fmt.Errorf("read txID: %w", err)
+	}
+	hasColCount := rec.recType&walRecColCount != 0
+	hasColTypes := rec.recType&walRecColTypes != 0
+	hasLSN := rec.recType&walRecLSN != 0
+	rec.recType &^= walRecColCount | walRecColTypes | walRecLSN
+	if hasLSN {
+		if err := binary.Read(r, binary.LittleEndian, &rec.lsn); err != nil {
+			return rec, fmt.Errorf("read LSN: %w", err)
+		}
+	}
+
+	switch rec.recType {
+	case walRecBegin, walRecCommit, walRecRollback:
+		return rec, nil
+	case walRecInsert, walRecReplaceAll, walRecDelete, walRecUpdate:
+		// common header: table name + rowCount
+	default:
+		return rec, fmt.Errorf("unknown WAL record type %d", rec.recType)
+	}
+
+	var nameLen uint16
+	if err := binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+		return rec, fmt.Errorf("read table name len: %w", err)
+	}
+	nameBytes := make([]byte, nameLen)
+	if _, err := io.ReadFull(r, nameBytes); err != nil {
+		return rec, fmt.Errorf("read table name: %w", err)
+	}
+	rec.table = string(nameBytes)
+
+	var rowCount uint32
+	if err := binary.Read(r, binary.LittleEndian, &rowCount); err != nil {
+		return rec, fmt.Errorf("read rowCount: %w", err)
+	}
+
+	var n int
+	if hasColCount {
+		var colCount uint16
+		if err := binary.Read(r, binary.LittleEndian, &colCount); err != nil {
+			return rec, fmt.Errorf("read colCount: %w", err)
+		}
+		n = int(colCount)
+		if hasColTypes {
+			types := make([]byte, n)
+			if _, err := io.ReadFull(r, types); err != nil {
+				return rec, fmt.Errorf("read column types: %w", err)
+			}
+			rec.types = make([]sql.DataType, n)
+			for i, t := range types {
+				rec.types[i] = sql.DataType(t)
+			}
+		}
+	} else {
+		var err error
+		if n, err = numCols(rec.table); err != nil {
+			return rec, err
+		}
+	}
+
+	rec.rows = make([]sql.Row, 0, rowCount)
+	for i := uint32(0); i < rowCount; i++ {
+		row, err := readRow(r, n)
+		if err != nil {
+			return rec, fmt.Errorf("read row: %w", err)
+		}
+		rec.rows = append(rec.rows, row)
+	}
+	return rec, nil
+}
+
+// truncate cuts the current WAL segment back to size bytes, dropping
+// everything after it, and makes the next append start there.
+func (w *walLogger) truncate(size int64) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	if w.f == nil {
 		return fmt.Errorf("wal: closed")
 	}
-
-	// rowCount = 2: [oldRow, newRow]
-	if err := w.writeRecordHeader(txID, walRecUpdate, table, 2); err != nil {
-		return err
+	if err := w.f.Truncate(size); err != nil {
+		return fmt.Errorf("wal: truncate: %w", err)
 	}
-	if err := writeRow(w.f, oldRow); err != nil {
-		return fmt.Errorf("wal: write old row in update: %w", err)
+	if _, err := w.f.Seek(size, io.SeekStart); err != nil {
+		return fmt.Errorf("wal: seek: %w", err)
 	}
-	if err := writeRow(w.f, newRow); err != nil {
-		return fmt.Errorf("wal: write new row in update: %w", err)
+	w.syncs.Add(1)
+	if err := w.f.Sync(); err != nil {
+		return fmt.Errorf("wal: sync: %w", err)
 	}
 	return nil
 }
+
+// size returns the number of bytes written to the WAL so far, counting the
+// segments filled since newWAL. Because every append holds the WAL lock, the
+// result always falls on a record boundary.
+func (w *walLogger) size() (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, fmt.Errorf("wal: closed")
+	}
+	off, err := w.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	return w.base + off, nil
+}
@@ -0,0 +1,127 @@
+package filestore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// walFlusher fsyncs the WAL on a background goroutine, so that one fsync
+// can cover the commit records of many transactions.
+type walFlusher struct {
+	wal    *walLogger
+	window time.Duration
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	synced int64 // WAL bytes known to be durable
+	gen    int   // bumped by reset; flushes started earlier are ignored
+	err    error // sticky error from a failed fsync
+	closed bool
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newWALFlusher(w *walLogger, window time.Duration) *walFlusher {
+	f := &walFlusher{
+		wal:    w,
+		window: window,
+		kick:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	f.cond = sync.NewCond(&f.mu)
+	go f.run()
+	return f
+}
+
+// request asks the flusher to sync at least the first pos bytes of the WAL
+// without waiting for it.
+func (f *walFlusher) request() {
+	select {
+	case f.kick <- struct{}{}:
+	default: // a flush is already pending
+	}
+}
+
+// wait blocks until the first pos bytes of the WAL are durable.
+func (f *walFlusher) wait(pos int64) error {
+	f.request()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.synced < pos && f.err == nil && !f.closed {
+		f.cond.Wait()
+	}
+	if f.err != nil {
+		return f.err
+	}
+	if f.synced < pos {
+		return fmt.Errorf("wal: closed before commit was flushed")
+	}
+	return nil
+}
+
+func (f *walFlusher) run() {
+	defer close(f.done)
+	for {
+		select {
+		case <-f.stop:
+			f.flush()
+			return
+		case <-f.kick:
+		}
+
+		if f.window > 0 {
+			select {
+			case <-time.After(f.window):
+			case <-f.stop:
+			}
+		}
+		f.flush()
+	}
+}
+
+// flush fsyncs everything written so far and wakes the waiters it covers.
+func (f *walFlusher) flush() {
+	f.mu.Lock()
+	gen := f.gen
+	f.mu.Unlock()
+
+	end, err := f.wal.size()
+	if err == nil {
+		err = f.wal.Sync()
+	}
+
+	f.mu.Lock()
+	if err != nil {
+		f.err = fmt.Errorf("wal: group sync: %w", err)
+	} else if end > f.synced && gen == f.gen {
+		f.synced = end
+	}
+	f.cond.Broadcast()
+	f.mu.Unlock()
+}
+
+// reset records that the WAL ends at pos bytes, all of them durable. A
+// checkpoint calls it after starting a new segment.
+func (f *walFlusher) reset(pos int64) {
+	f.mu.Lock()
+	f.gen++
+	f.synced = pos
+	f.mu.Unlock()
+}
+
+// close performs a final flush and stops the goroutine. Waiters that are
+// still not covered afterwards get an error.
+func (f *walFlusher) close() {
+	close(f.stop)
+	<-f.done
+
+	f.mu.Lock()
+	f.closed = true
+	f.cond.Broadcast()
+	f.mu.Unlock()
+}
@@ -0,0 +1,148 @@
+package filestore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"goDB/internal/sql"
+)
+
+// commitRows has `workers` goroutines each commit perWorker single-row
+// transactions in parallel.
+func commitRows(t *testing.T, fs *FileEngine, workers int, perWorker int64) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(base int64) {
+			defer wg.Done()
+			for i := int64(0); i < perWorker; i++ {
+				tx, err := fs.Begin(false)
+				if err != nil {
+					t.Errorf("Begin failed: %v", err)
+					return
+				}
+				if err := tx.Insert("t", sql.Row{{Type: sql.TypeInt, I64: base + i}}); err != nil {
+					t.Errorf("Insert failed: %v", err)
+					return
+				}
+				if err := fs.Commit(tx); err != nil {
+					t.Errorf("Commit failed: %v", err)
+					return
+				}
+			}
+		}(int64(w) * perWorker)
+	}
+	wg.Wait()
+}
+
+func TestFilestore_SyncModesSurviveReopen(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts Options
+	}{
+		{"group", Options{SyncMode: SyncGroupCommit}},
+		{"group-window", Options{SyncMode: SyncGroupCommit, GroupCommitWindow: time.Millisecond}},
+		{"async", Options{SyncMode: SyncAsync}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fs, err := NewWithOptions(dir, tc.opts)
+			if err != nil {
+				t.Fatalf("NewWithOptions failed: %v", err)
+			}
+			if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+				t.Fatalf("CreateTable failed: %v", err)
+			}
+
+			commitRows(t, fs, 4, 25)
+			if err := fs.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			reopened, err := New(dir)
+			if err != nil {
+				t.Fatalf("reopen failed: %v", err)
+			}
+			defer reopened.Close()
+
+			tx, _ := reopened.Begin(true)
+			_, rows, err := tx.Scan("t")
+			if err != nil {
+				t.Fatalf("Scan failed: %v", err)
+			}
+			if len(rows) != 100 {
+				t.Fatalf("expected 100 rows after reopen, got %d", len(rows))
+			}
+		})
+	}
+}
+
+func TestFilestore_RejectsUnknownSyncMode(t *testing.T) {
+	if _, err := NewWithOptions(t.TempDir(), Options{SyncMode: SyncMode(42)}); err == nil {
+		t.Fatalf("expected error for unknown sync mode")
+	}
+}
+
+func BenchmarkCommit(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts Options
+	}{
+		{"each", Options{SyncMode: SyncEachCommit}},
+		{"group", Options{SyncMode: SyncGroupCommit}},
+		{"async", Options{SyncMode: SyncAsync}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			fs, err := NewWithOptions(b.TempDir(), bc.opts)
+			if err != nil {
+				b.Fatalf("NewWithOptions failed: %v", err)
+			}
+			defer fs.Close()
+			if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+				b.Fatalf("CreateTable failed: %v", err)
+			}
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				row := sql.Row{{Type: sql.TypeInt, I64: 1}}
+				for pb.Next() {
+					tx, _ := fs.Begin(false)
+					if err := tx.Insert("t", row); err != nil {
+						b.Errorf("Insert failed: %v", err)
+						return
+					}
+					if err := fs.Commit(tx); err != nil {
+						b.Errorf("Commit failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// Sync fsyncs the WAL itself instead of waiting for the flusher, which with
+// SyncAsync and a long window has not synced the commit yet.
+func TestFilestore_SyncFlushesAsyncCommits(t *testing.T) {
+	fs, err := NewWithOptions(t.TempDir(), Options{SyncMode: SyncAsync, GroupCommitWindow: time.Hour})
+	if err != nil {
+		t.Fatalf("NewWithOptions failed: %v", err)
+	}
+	defer fs.Close()
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	commitRows(t, fs, 1, 1)
+
+	before := fs.Metrics().WALSyncs
+	if err := fs.Sync(); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if got := fs.Metrics().WALSyncs; got != before+1 {
+		t.Fatalf("WALSyncs = %d after Sync, want %d", got, before+1)
+	}
+}
@@ -0,0 +1,192 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The WAL is a series of segment files wal.0000, wal.0001, ... in the data
+// directory. Records are appended to the newest segment until it reaches
+// Options.WALSegmentSize, after which the next record starts a new one; a
+// record never spans two segments. Recovery replays the segments in order.
+//
+// A checkpoint makes every record written so far redundant, so instead of
+// truncating the log it starts a new segment and deletes the older ones.
+
+// DefaultWALSegmentSize is the WAL segment size used when
+// Options.WALSegmentSize is zero.
+const DefaultWALSegmentSize = 16 << 20
+
+const (
+	walSegmentPrefix = "wal."
+	legacyWALFile    = "wal.log"
+)
+
+// walSegmentPath returns the path of WAL segment n in dir.
+func walSegmentPath(dir string, n uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%04d", walSegmentPrefix, n))
+}
+
+// parseWALSegment returns the segment number of a data directory entry, and
+// false when name is not a WAL segment.
+func parseWALSegment(name string) (uint64, bool) {
+	digits, ok := strings.CutPrefix(name, walSegmentPrefix)
+	if !ok || len(digits) < 4 {
+		return 0, false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// walSegments returns the numbers of the WAL segments in dir, oldest first.
+func walSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("wal: list segments: %w", err)
+	}
+	var segs []uint64
+	for _, ent := range entries {
+		if n, ok := parseWALSegment(ent.Name()); ok && !ent.IsDir() {
+			segs = append(segs, n)
+		}
+	}
+	slices.Sort(segs)
+	return segs, nil
+}
+
+// adoptLegacyWAL renames the single wal.log written before segments existed
+// to the first segment.
+func adoptLegacyWAL(dir string) error {
+	legacy := filepath.Join(dir, legacyWALFile)
+	if _, err := os.Stat(legacy); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("wal: stat %s: %w", legacyWALFile, err)
+	}
+
+	segs, err := walSegments(dir)
+	if err != nil {
+		return err
+	}
+	if len(segs) > 0 {
+		return fmt.Errorf("wal: both %s and WAL segments exist", legacyWALFile)
+	}
+	if err := os.Rename(legacy, walSegmentPath(dir, 0)); err != nil {
+		return fmt.Errorf("wal: adopt %s: %w", legacyWALFile, err)
+	}
+	return syncDir(dir)
+}
+
+// rotateIfFull starts a new segment when the current one has reached the
+// segment size. The caller holds w.mu.
+func (w *walLogger) rotateIfFull() error {
+	if w.segSize <= 0 {
+		return nil
+	}
+	off, err := w.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if off < w.segSize {
+		return nil
+	}
+	return w.rotate(off)
+}
+
+// rotate fsyncs and closes the current segment, whose size is off, and
+// makes the next segment current. The caller holds w.mu.
+func (w *walLogger) rotate(off int64) error {
+	w.syncs.Add(1)
+	if err := w.f.Sync(); err != nil {
+		return fmt.Errorf("wal: sync segment: %w", err)
+	}
+
+	next := w.seg + 1
+	f, err := os.OpenFile(walSegmentPath(w.dir, next), os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("wal: create segment: %w", err)
+	}
+	if _, err := f.Write([]byte(walMagic)); err != nil {
+		f.Close()
+		return fmt.Errorf("wal: write magic: %w", err)
+	}
+	w.bytesWritten.Add(int64(len(walMagic)))
+	if err := syncDir(w.dir); err != nil {
+		f.Close()
+		return fmt.Errorf("wal: create segment: %w", err)
+	}
+
+	if err := w.f.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("wal: close segment: %w", err)
+	}
+	w.f = f
+	w.seg = next
+	w.base += off
+	return nil
+}
+
+// position returns the current segment and the number of bytes written to
+// it, which always falls on a record boundary.
+func (w *walLogger) position() (seg uint64, off int64, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, 0, fmt.Errorf("wal: closed")
+	}
+	off, err = w.f.Seek(0, io.SeekCurrent)
+	return w.seg, off, err
+}
+
+// dropSegments discards every record written so far: it starts a new
+// segment unless the current one is still empty, then deletes all older
+// segments. It returns the WAL size afterwards. A checkpoint calls it once
+// the records are no longer needed for recovery.
+func (w *walLogger) dropSegments() (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, fmt.Errorf("wal: closed")
+	}
+
+	off, err := w.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if off > int64(len(walMagic)) {
+		if err := w.rotate(off); err != nil {
+			return 0, err
+		}
+	}
+
+	segs, err := walSegments(w.dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range segs {
+		if n >= w.seg {
+			break
+		}
+		if err := os.Remove(walSegmentPath(w.dir, n)); err != nil {
+			return 0, fmt.Errorf("wal: remove segment: %w", err)
+		}
+	}
+	if err := syncDir(w.dir); err != nil {
+		return 0, err
+	}
+	return w.base + int64(len(walMagic)), nil
+}
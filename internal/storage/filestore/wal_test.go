@@ -1,11 +1,13 @@
 package filestore
 
 import (
+	"bytes"
 	"encoding/binary"
 	"goDB/internal/sql"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -39,13 +41,13 @@ func TestFilestore_WAL_IsWritten(t *testing.T) {
 	}
 
 	// Check WAL file exists and is non-empty
-	walPath := filepath.Join(dir, "wal.log")
+	walPath := currentWALPath(t, dir)
 	info, err := os.Stat(walPath)
 	if err != nil {
-		t.Fatalf("wal.log not found: %v", err)
+		t.Fatalf("WAL segment not found: %v", err)
 	}
 	if info.Size() <= int64(len("GODBWAL1")) {
-		t.Fatalf("wal.log too small, no records? size=%d", info.Size())
+		t.Fatalf("WAL segment too small, no records? size=%d", info.Size())
 	}
 }
 func TestFilestore_WAL_BeginCommit(t *testing.T) {
@@ -64,7 +66,7 @@ func TestFilestore_WAL_BeginCommit(t *testing.T) {
 	tx, _ := fs.Begin(false)
 	_ = fs.Commit(tx)
 
-	walPath := filepath.Join(dir, "wal.log")
+	walPath := currentWALPath(t, dir)
 	f, err := os.Open(walPath)
 	if err != nil {
 		t.Fatalf("open wal: %v", err)
@@ -80,7 +82,251 @@ func TestFilestore_WAL_BeginCommit(t *testing.T) {
 	if err := binary.Read(f, binary.LittleEndian, &recType); err != nil {
 		t.Fatalf("read recType: %v", err)
 	}
-	if recType != 1 { // BEGIN
+	if recType&^(walRecLSN|walRecColCount) != 1 { // BEGIN
 		t.Fatalf("expected first record to be BEGIN (1), got %d", recType)
 	}
 }
+
+// Row records carry their column count, so they decode the same way no matter
+// what the table's schema says at replay time. Records without it fall back
+// to the schema.
+func TestReadWALRecord_ColumnCount(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newWAL(dir, 0)
+	if err != nil {
+		t.Fatalf("newWAL failed: %v", err)
+	}
+	defer w.Close()
+
+	rows := []sql.Row{
+		{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "a"}, {Type: sql.TypeNull}},
+		{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeString, S: "b"}, {Type: sql.TypeBool, B: true}},
+	}
+	cols := []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "name", Type: sql.TypeString}, {Name: "ok", Type: sql.TypeBool}}
+	if err := w.appendInsertBatch(7, "t", cols, rows); err != nil {
+		t.Fatalf("appendInsertBatch failed: %v", err)
+	}
+	if err := w.appendUpdate(7, "t", cols, rows[0], rows[1]); err != nil {
+		t.Fatalf("appendUpdate failed: %v", err)
+	}
+
+	data, err := os.ReadFile(currentWALPath(t, dir))
+	if err != nil {
+		t.Fatalf("read WAL: %v", err)
+	}
+	r := bytes.NewReader(data[len(walMagic):])
+
+	// The schema has since gained a column; it must not be consulted.
+	schemaCols := func(string) (int, error) { return 4, nil }
+	for _, want := range []uint8{walRecInsert, walRecUpdate} {
+		rec, err := readWALRecord(r, schemaCols)
+		if err != nil {
+			t.Fatalf("readWALRecord failed: %v", err)
+		}
+		if rec.recType != want || rec.txID != 7 || rec.table != "t" {
+			t.Fatalf("unexpected record header: %+v", rec)
+		}
+		if len(rec.rows) != 2 || len(rec.rows[0]) != 3 || rec.rows[1][1].S != "b" {
+			t.Fatalf("rows decoded wrongly: %v", rec.rows)
+		}
+		if want := []sql.DataType{sql.TypeInt, sql.TypeString, sql.TypeBool}; !slices.Equal(rec.types, want) {
+			t.Fatalf("column types = %v, want %v", rec.types, want)
+		}
+	}
+	if _, err := readWALRecord(r, schemaCols); err != io.EOF {
+		t.Fatalf("expected io.EOF at end of WAL, got %v", err)
+	}
+
+	// A record in the older layout: no flag and no colCount.
+	var legacy bytes.Buffer
+	legacy.WriteByte(walRecDelete)
+	binary.Write(&legacy, binary.LittleEndian, uint64(8))
+	binary.Write(&legacy, binary.LittleEndian, uint16(1))
+	legacy.WriteString("t")
+	binary.Write(&legacy, binary.LittleEndian, uint32(1))
+	if err := writeRow(&legacy, rows[0]); err != nil {
+		t.Fatalf("writeRow failed: %v", err)
+	}
+	rec, err := readWALRecord(&legacy, func(string) (int, error) { return 3, nil })
+	if err != nil {
+		t.Fatalf("readWALRecord(legacy) failed: %v", err)
+	}
+	if rec.recType != walRecDelete || len(rec.rows) != 1 || rec.rows[0][0].I64 != 1 || rec.types != nil {
+		t.Fatalf("legacy record decoded wrongly: %+v", rec)
+	}
+}
+
+// appendRowOps writes the same records as appendDelete and appendUpdate,
+// with consecutive LSNs, in a single write.
+func TestWAL_AppendRowOps(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newWAL(dir, 0)
+	if err != nil {
+		t.Fatalf("newWAL failed: %v", err)
+	}
+	defer w.Close()
+	w.setLastLSN(10)
+
+	rows := []sql.Row{
+		{{Type: sql.TypeInt, I64: 1}, {Type: sql.TypeString, S: "a"}},
+		{{Type: sql.TypeInt, I64: 2}, {Type: sql.TypeString, S: "b"}},
+	}
+	cols := []sql.Column{{Name: "id", Type: sql.TypeInt}, {Name: "name", Type: sql.TypeString}}
+	before := w.bytesWritten.Load()
+	ops := []walRowOp{{oldRow: rows[0]}, {oldRow: rows[0], newRow: rows[1]}, {oldRow: rows[1]}}
+	if err := w.appendRowOps(3, "t", cols, ops); err != nil {
+		t.Fatalf("appendRowOps failed: %v", err)
+	}
+	if got := w.lastLSN(); got != 13 {
+		t.Fatalf("lastLSN = %d, want 13", got)
+	}
+
+	data, err := os.ReadFile(currentWALPath(t, dir))
+	if err != nil {
+		t.Fatalf("read WAL: %v", err)
+	}
+	if written := w.bytesWritten.Load() - before; written != int64(len(data)-len(walMagic)) {
+		t.Fatalf("bytesWritten grew by %d, want %d", written, len(data)-len(walMagic))
+	}
+	r := bytes.NewReader(data[len(walMagic):])
+	for i, want := range []uint8{walRecDelete, walRecUpdate, walRecDelete} {
+		rec, err := readWALRecord(r, func(string) (int, error) { return 2, nil })
+		if err != nil {
+			t.Fatalf("readWALRecord failed: %v", err)
+		}
+		if rec.recType != want || rec.txID != 3 || rec.lsn != uint64(11+i) || rec.table != "t" {
+			t.Fatalf("record %d: unexpected header %+v", i, rec)
+		}
+	}
+	if _, err := readWALRecord(r, func(string) (int, error) { return 2, nil }); err != io.EOF {
+		t.Fatalf("expected io.EOF at end of WAL, got %v", err)
+	}
+
+	if err := w.appendRowOps(3, "t", cols, []walRowOp{{oldRow: rows[0], newRow: rows[0][:1]}}); err == nil {
+		t.Fatalf("expected an error for update rows of different widths")
+	}
+	if got := w.lastLSN(); got != 13 {
+		t.Fatalf("lastLSN after a failed append = %d, want 13", got)
+	}
+}
+
+// currentWALPath returns the path of the newest WAL segment in dir, or of
+// the first one when there is none yet.
+func currentWALPath(t *testing.T, dir string) string {
+	t.Helper()
+	segs, err := walSegments(dir)
+	if err != nil {
+		t.Fatalf("walSegments failed: %v", err)
+	}
+	if len(segs) == 0 {
+		return walSegmentPath(dir, 0)
+	}
+	return walSegmentPath(dir, segs[len(segs)-1])
+}
+
+func TestFilestore_WALSegments(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewWithOptions(dir, Options{WALSegmentSize: 256})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	ch, cancel := fs.Subscribe()
+	for i := int64(1); i <= 50; i++ {
+		tx, _ := fs.Begin(false)
+		if err := tx.Insert("t", intRow(i)); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+		if err := fs.Commit(tx); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+
+	segs, err := walSegments(dir)
+	if err != nil {
+		t.Fatalf("walSegments failed: %v", err)
+	}
+	if len(segs) < 3 {
+		t.Fatalf("got %d WAL segments, want the WAL to rotate", len(segs))
+	}
+	for i, n := range segs {
+		if n != uint64(i) {
+			t.Fatalf("segments %v are not numbered consecutively", segs)
+		}
+	}
+
+	// The change feed follows the WAL across segments.
+	for i := int64(1); i <= 50; i++ {
+		if c := nextChange(t, ch); c.Row[0].I64 != i {
+			t.Fatalf("change %d has id %d", i, c.Row[0].I64)
+		}
+	}
+	cancel()
+
+	// Recovery replays every segment in order. Simulate a crash by not
+	// closing fs, so nothing is checkpointed.
+	fs2, err := NewWithOptions(dir, Options{WALSegmentSize: 256})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer fs2.Close()
+	tx, _ := fs2.Begin(true)
+	_, rows, err := tx.Scan("t")
+	if err != nil || len(rows) != 50 {
+		t.Fatalf("Scan after recovery: %d rows, err %v", len(rows), err)
+	}
+	for i, r := range rows {
+		if r[0].I64 != int64(i+1) {
+			t.Fatalf("row %d has id %d", i, r[0].I64)
+		}
+	}
+	fs2.Commit(tx)
+
+	// Recovery ends with a checkpoint, which leaves one empty segment.
+	after, err := walSegments(dir)
+	if err != nil {
+		t.Fatalf("walSegments failed: %v", err)
+	}
+	if len(after) != 1 || after[0] <= segs[len(segs)-1] {
+		t.Fatalf("segments after checkpoint = %v, want one newer than %v", after, segs)
+	}
+	if size := walSize(t, dir); size != int64(len(walMagic)) {
+		t.Fatalf("WAL segment has %d bytes after checkpoint, want only the magic", size)
+	}
+}
+
+func TestFilestore_LegacyWALAdopted(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := New(dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := fs.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+	tx, _ := fs.Begin(false)
+	_ = tx.Insert("t", intRow(1))
+	if err := fs.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// Move the WAL to where versions without segments kept it.
+	if err := os.Rename(currentWALPath(t, dir), filepath.Join(dir, "wal.log")); err != nil {
+		t.Fatalf("rename WAL: %v", err)
+	}
+	fs2, err := New(dir)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer fs2.Close()
+	if _, err := os.Stat(filepath.Join(dir, "wal.log")); !os.IsNotExist(err) {
+		t.Fatalf("wal.log still exists after opening: %v", err)
+	}
+	rtx, _ := fs2.Begin(true)
+	if _, rows, err := rtx.Scan("t"); err != nil || len(rows) != 1 {
+		t.Fatalf("Scan after recovery: %d rows, err %v", len(rows), err)
+	}
+	fs2.Commit(rtx)
+}
@@ -5,15 +5,47 @@ import (
 	"goDB/internal/index/btree"
 	"goDB/internal/sql"
 	"goDB/internal/storage"
+	"math"
 	"sort"
 	"strings"
 	"sync"
 )
 
 type table struct {
-	name string
-	cols []sql.Column // column names
-	rows []sql.Row    // stored rows
+	name   string
+	cols   []sql.Column // column names
+	rows   []sql.Row    // stored rows
+	ids    []int64      // rowid of each row
+	nextID int64        // rowid of the next row inserted
+}
+
+// newIDs returns rowids for n new rows.
+func (t *table) newIDs(n int) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = t.nextID
+		t.nextID++
+	}
+	return ids
+}
+
+// checkRow checks that row fits the columns of t.
+func (t *table) checkRow(row sql.Row) error {
+	if len(row) != len(t.cols) {
+		return fmt.Errorf("column count mismatch: expected %d, got %d", len(t.cols), len(row))
+	}
+
+	// Type check each value against the column definition.
+	for i, col := range t.cols {
+		if row[i].Type == sql.TypeNull && col.NotNull {
+			return fmt.Errorf("%w: column %q is NULL", sql.ErrNotNull, col.Name)
+		}
+		if row[i].Type != col.Type && row[i].Type != sql.TypeNull {
+			return fmt.Errorf("%w for column %q: expected %v, got %v", sql.ErrTypeMismatch,
+				col.Name, col.Type, row[i].Type)
+		}
+	}
+	return nil
 }
 
 type index struct {
@@ -28,6 +60,13 @@ type memEngine struct {
 	tables  map[string]*table
 	indexes map[string]*index
 	idxMan  *btree.Manager
+
+	// Write reservation state, guarded by mu.
+	reserved *memTx              // BEGIN IMMEDIATE transaction, if any
+	writers  map[*memTx]struct{} // open transactions that have written
+
+	seqMu     sync.Mutex
+	sequences map[string]int64 // name -> next value
 }
 
 // New creates a new in-memory storage engine with the default data directory.
@@ -38,10 +77,60 @@ func New() storage.Engine {
 // NewWithDir creates a new in-memory storage engine with the given data directory.
 func NewWithDir(dir string) storage.Engine {
 	return &memEngine{
-		tables:  make(map[string]*table),
-		indexes: make(map[string]*index),
-		idxMan:  btree.NewManager(dir),
+		tables:    make(map[string]*table),
+		indexes:   make(map[string]*index),
+		idxMan:    btree.NewManager(dir),
+		writers:   make(map[*memTx]struct{}),
+		sequences: make(map[string]int64),
+	}
+}
+
+// IndexedColumns returns the indexed columns of a table, sorted by name.
+func (e *memEngine) IndexedColumns(tableName string) ([]string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if _, ok := e.tables[tableName]; !ok {
+		return nil, fmt.Errorf("%w %q", sql.ErrTableNotFound, tableName)
+	}
+	var cols []string
+	for _, idx := range e.indexes {
+		if strings.EqualFold(idx.tableName, tableName) {
+			cols = append(cols, idx.columnName)
+		}
 	}
+	sort.Strings(cols)
+	return cols, nil
+}
+
+// CreateSequence creates a sequence whose first NEXTVAL returns start.
+func (e *memEngine) CreateSequence(name string, start int64) error {
+	e.seqMu.Lock()
+	defer e.seqMu.Unlock()
+
+	if _, exists := e.sequences[name]; exists {
+		return fmt.Errorf("sequence %s already exists", name)
+	}
+	e.sequences[name] = start
+	return nil
+}
+
+// NextVal returns the next value of a sequence and advances it. Like the
+// filestore, it is not transactional: a rolled-back INSERT does not give its
+// value back.
+func (e *memEngine) NextVal(name string) (int64, error) {
+	e.seqMu.Lock()
+	defer e.seqMu.Unlock()
+
+	v, ok := e.sequences[name]
+	if !ok {
+		return 0, fmt.Errorf("sequence %s does not exist", name)
+	}
+	if v == math.MaxInt64 {
+		return 0, fmt.Errorf("sequence %s is exhausted", name)
+	}
+	e.sequences[name] = v + 1
+	return v, nil
 }
 
 func (e *memEngine) CreateIndex(indexName, tableName, columnName string) error {
@@ -60,7 +149,7 @@ func (e *memEngine) CreateIndex(indexName, tableName, columnName string) error {
 
 	tbl, ok := e.tables[tableName]
 	if !ok {
-		return fmt.Errorf("table %q not found", tableName)
+		return fmt.Errorf("%w %q", sql.ErrTableNotFound, tableName)
 	}
 
 	colIdx := -1
@@ -72,7 +161,7 @@ func (e *memEngine) CreateIndex(indexName, tableName, columnName string) error {
 	}
 
 	if colIdx == -1 {
-		return fmt.Errorf("column %q not found in table %q", columnName, tableName)
+		return fmt.Errorf("%w %q in table %q", sql.ErrColumnNotFound, columnName, tableName)
 	}
 
 	if tbl.cols[colIdx].Type != sql.TypeInt {
@@ -125,7 +214,7 @@ func (e *memEngine) TableSchema(name string) ([]sql.Column, error) {
 
 	t, ok := e.tables[name]
 	if !ok {
-		return nil, fmt.Errorf("table %s does not exist", name)
+		return nil, fmt.Errorf("%w %q", sql.ErrTableNotFound, name)
 	}
 
 	cols := make([]sql.Column, len(t.cols))
@@ -144,24 +233,30 @@ func (tx *memTx) DeleteWhere(tableName string, pred storage.RowPredicate) error
 	if tx.readOnly {
 		return fmt.Errorf("memstore: cannot delete in read-only transaction")
 	}
+	if err := tx.startWrite(); err != nil {
+		return err
+	}
 
 	tbl, ok := tx.tables[tableName]
 	if !ok {
-		return fmt.Errorf("memstore: table %q does not exist", tableName)
+		return fmt.Errorf("memstore: %w %q", sql.ErrTableNotFound, tableName)
 	}
 
 	var newRows []sql.Row
-	for _, row := range tbl.rows {
+	var newIDs []int64
+	for i, row := range tbl.rows {
 		match, err := pred(row)
 		if err != nil {
 			return err
 		}
 		if !match {
 			newRows = append(newRows, row)
+			newIDs = append(newIDs, tbl.ids[i])
 		}
 	}
 
 	tbl.rows = newRows
+	tbl.ids = newIDs
 	return nil
 }
 
@@ -169,10 +264,13 @@ func (tx *memTx) UpdateWhere(tableName string, pred storage.RowPredicate, update
 	if tx.readOnly {
 		return fmt.Errorf("memstore: cannot update in read-only transaction")
 	}
+	if err := tx.startWrite(); err != nil {
+		return err
+	}
 
 	tbl, ok := tx.tables[tableName]
 	if !ok {
-		return fmt.Errorf("memstore: table %q does not exist", tableName)
+		return fmt.Errorf("memstore: %w %q", sql.ErrTableNotFound, tableName)
 	}
 
 	for i, row := range tbl.rows {
@@ -195,6 +293,75 @@ func (tx *memTx) UpdateWhere(tableName string, pred storage.RowPredicate, update
 	return nil
 }
 
+// ScanRowIDs returns the rows of a table with their rowids. Rowids count up
+// from 1 in insertion order; ReplaceAll gives all rows new ones.
+func (tx *memTx) ScanRowIDs(tableName string) ([]string, []sql.Row, []int64, error) {
+	cols, rows, err := tx.Scan(tableName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := tx.tables[tableName]
+	return cols, rows, append([]int64(nil), t.ids...), nil
+}
+
+// DeleteRowIDs deletes the rows with the given rowids.
+func (tx *memTx) DeleteRowIDs(tableName string, ids []int64) error {
+	if tx.readOnly {
+		return fmt.Errorf("memstore: cannot delete in read-only transaction")
+	}
+	if err := tx.startWrite(); err != nil {
+		return err
+	}
+
+	t, ok := tx.tables[tableName]
+	if !ok {
+		return fmt.Errorf("memstore: %w %q", sql.ErrTableNotFound, tableName)
+	}
+	del := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		del[id] = true
+	}
+	var newRows []sql.Row
+	var newIDs []int64
+	for i, id := range t.ids {
+		if !del[id] {
+			newRows = append(newRows, t.rows[i])
+			newIDs = append(newIDs, id)
+		}
+	}
+	t.rows = newRows
+	t.ids = newIDs
+	return nil
+}
+
+// UpdateRowIDs replaces the rows with the given rowids, keeping the ids.
+func (tx *memTx) UpdateRowIDs(tableName string, ids []int64, rows []sql.Row) error {
+	if tx.readOnly {
+		return fmt.Errorf("memstore: cannot update in read-only transaction")
+	}
+	if err := tx.startWrite(); err != nil {
+		return err
+	}
+
+	t, ok := tx.tables[tableName]
+	if !ok {
+		return fmt.Errorf("memstore: %w %q", sql.ErrTableNotFound, tableName)
+	}
+	byID := make(map[int64]sql.Row, len(ids))
+	for i, id := range ids {
+		if err := t.checkRow(rows[i]); err != nil {
+			return err
+		}
+		byID[id] = rows[i]
+	}
+	for i, id := range t.ids {
+		if r, ok := byID[id]; ok {
+			t.rows[i] = append(sql.Row(nil), r...)
+		}
+	}
+	return nil
+}
+
 func cloneTable(t *table) *table {
 	colsCopy := make([]sql.Column, len(t.cols))
 	copy(colsCopy, t.cols)
@@ -207,9 +374,11 @@ func cloneTable(t *table) *table {
 	}
 
 	return &table{
-		name: t.name,
-		cols: colsCopy,
-		rows: rowsCopy,
+		name:   t.name,
+		cols:   colsCopy,
+		rows:   rowsCopy,
+		ids:    append([]int64(nil), t.ids...),
+		nextID: t.nextID,
 	}
 }
 
@@ -217,10 +386,13 @@ func (tx *memTx) ReplaceAll(tableName string, rows []sql.Row) error {
 	if tx.readOnly {
 		return fmt.Errorf("cannot replace in a read-only transaction")
 	}
+	if err := tx.startWrite(); err != nil {
+		return err
+	}
 
 	t, ok := tx.tables[tableName]
 	if !ok {
-		return fmt.Errorf("table %s does not exist", tableName)
+		return fmt.Errorf("%w %q", sql.ErrTableNotFound, tableName)
 	}
 
 	// basic type/length validation for safety
@@ -229,8 +401,11 @@ func (tx *memTx) ReplaceAll(tableName string, rows []sql.Row) error {
 			return fmt.Errorf("column count mismatch in ReplaceAll: expected %d, got %d", len(t.cols), len(r))
 		}
 		for i, col := range t.cols {
-			if r[i].Type != col.Type {
-				return fmt.Errorf("type mismatch in ReplaceAll for column %q: expected %v, got %v",
+			if r[i].Type == sql.TypeNull && col.NotNull {
+				return fmt.Errorf("%w in ReplaceAll: column %q is NULL", sql.ErrNotNull, col.Name)
+			}
+			if r[i].Type != col.Type && r[i].Type != sql.TypeNull {
+				return fmt.Errorf("%w in ReplaceAll for column %q: expected %v, got %v", sql.ErrTypeMismatch,
 					col.Name, col.Type, r[i].Type)
 			}
 		}
@@ -245,13 +420,14 @@ func (tx *memTx) ReplaceAll(tableName string, rows []sql.Row) error {
 	}
 
 	t.rows = newRows
+	t.ids = t.newIDs(len(newRows))
 	return nil
 }
 
 func (tx *memTx) Scan(tableName string) (col []string, rows []sql.Row, err error) {
 	t, ok := tx.tables[tableName]
 	if !ok {
-		return nil, nil, fmt.Errorf("table %s does not exist", tableName)
+		return nil, nil, fmt.Errorf("%w %q", sql.ErrTableNotFound, tableName)
 	}
 
 	// Extract column names from the column metadata.
@@ -271,11 +447,40 @@ func (tx *memTx) Scan(tableName string) (col []string, rows []sql.Row, err error
 	return colNames, rowsCopy, nil
 }
 
+// CountRows returns the number of rows of the table in the transaction's
+// snapshot.
+func (tx *memTx) CountRows(tableName string) (int, error) {
+	t, ok := tx.tables[tableName]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", sql.ErrTableNotFound, tableName)
+	}
+	return len(t.rows), nil
+}
+
 // Begin starts a new transaction.
 func (e *memEngine) Begin(readOnly bool) (storage.Tx, error) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
+	return e.snapshot(readOnly), nil
+}
 
+// BeginImmediate starts a write transaction holding the write reservation.
+// It fails with storage.ErrBusy while another transaction holds it or has
+// uncommitted writes.
+func (e *memEngine) BeginImmediate() (storage.Tx, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.reserved != nil || len(e.writers) > 0 {
+		return nil, fmt.Errorf("memstore: begin immediate: %w", storage.ErrBusy)
+	}
+	tx := e.snapshot(false)
+	e.reserved = tx
+	return tx, nil
+}
+
+// snapshot returns a transaction working on a copy of the tables. The
+// caller holds mu.
+func (e *memEngine) snapshot(readOnly bool) *memTx {
 	tablesCopy := make(map[string]*table, len(e.tables))
 	for name, t := range e.tables {
 		tablesCopy[name] = cloneTable(t)
@@ -285,7 +490,29 @@ func (e *memEngine) Begin(readOnly bool) (storage.Tx, error) {
 		eng:      e,
 		readOnly: readOnly,
 		tables:   tablesCopy,
-	}, nil
+	}
+}
+
+// startWrite records that tx writes, failing with storage.ErrBusy when
+// another transaction holds the write reservation.
+func (tx *memTx) startWrite() error {
+	e := tx.eng
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.reserved != nil && e.reserved != tx {
+		return fmt.Errorf("memstore: %w", storage.ErrBusy)
+	}
+	e.writers[tx] = struct{}{}
+	return nil
+}
+
+// endTx releases what tx holds of the write reservation state. The caller
+// holds mu.
+func (e *memEngine) endTx(tx *memTx) {
+	delete(e.writers, tx)
+	if e.reserved == tx {
+		e.reserved = nil
+	}
 }
 
 // Commit finishes a transaction.
@@ -300,6 +527,7 @@ func (e *memEngine) Commit(tx storage.Tx) error {
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	e.endTx(m)
 
 	oldTables := e.tables
 	e.tables = m.tables
@@ -310,9 +538,14 @@ func (e *memEngine) Commit(tx storage.Tx) error {
 	return nil
 }
 
-// Rollback aborts a transaction.
-// For this simple in-memory implementation, it's a no-op.
+// Rollback aborts a transaction. Its changes only ever lived in its own
+// copy of the tables, so there is nothing to undo.
 func (e *memEngine) Rollback(tx storage.Tx) error {
+	if m, ok := tx.(*memTx); ok {
+		e.mu.Lock()
+		e.endTx(m)
+		e.mu.Unlock()
+	}
 	return nil
 }
 
@@ -331,7 +564,7 @@ func (e *memEngine) rebuildIndexes(oldTables map[string]*table) error {
 			}
 		}
 		if colIdx == -1 {
-			return fmt.Errorf("index %q references unknown column %q", idx.name, idx.columnName)
+			return fmt.Errorf("index %q references %w %q", idx.name, sql.ErrColumnNotFound, idx.columnName)
 		}
 
 		keysToDelete := make(map[btree.Key]struct{})
@@ -367,30 +600,78 @@ func (e *memEngine) rebuildIndexes(oldTables map[string]*table) error {
 
 // Insert adds a row into a table inside this transaction.
 func (tx *memTx) Insert(tableName string, row sql.Row) error {
+	return tx.InsertBatch(tableName, []sql.Row{row})
+}
+
+// InsertBatch adds several rows to a table, checking all of them first so
+// that a bad row leaves the table unchanged.
+func (tx *memTx) InsertBatch(tableName string, rows []sql.Row) error {
 	if tx.readOnly {
 		return fmt.Errorf("cannot insert in a read-only transaction")
 	}
+	if err := tx.startWrite(); err != nil {
+		return err
+	}
 
 	t, ok := tx.tables[tableName]
 	if !ok {
-		return fmt.Errorf("table %s does not exist", tableName)
+		return fmt.Errorf("%w %q", sql.ErrTableNotFound, tableName)
 	}
 
-	if len(row) != len(t.cols) {
-		return fmt.Errorf("column count mismatch: expected %d, got %d", len(t.cols), len(row))
+	for _, row := range rows {
+		if err := t.checkRow(row); err != nil {
+			return err
+		}
 	}
 
-	// Type check each value against the column definition.
-	for i, col := range t.cols {
-		if row[i].Type != col.Type && row[i].Type != sql.TypeNull {
-			return fmt.Errorf("type mismatch for column %q: expected %v, got %v",
-				col.Name, col.Type, row[i].Type)
+	// Add the rows to the table.
+	t.rows = append(t.rows, rows...)
+	t.ids = append(t.ids, t.newIDs(len(rows))...)
+
+	return nil
+}
+
+// RenameColumn renames a column of a table, along with its index and the
+// foreign keys of other tables that reference it. Transactions that are
+// already open keep the old schema, and committing one restores it.
+func (e *memEngine) RenameColumn(tableName, oldName, newName string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tbl, ok := e.tables[tableName]
+	if !ok {
+		return fmt.Errorf("%w %q", sql.ErrTableNotFound, tableName)
+	}
+	colIdx := -1
+	for i, c := range tbl.cols {
+		if strings.EqualFold(c.Name, newName) && !strings.EqualFold(c.Name, oldName) {
+			return fmt.Errorf("column %q already exists in table %q", newName, tableName)
+		}
+		if strings.EqualFold(c.Name, oldName) {
+			colIdx = i
 		}
 	}
+	if colIdx == -1 {
+		return fmt.Errorf("%w %q in table %q", sql.ErrColumnNotFound, oldName, tableName)
+	}
 
-	// Add the row to the table.
-	t.rows = append(t.rows, row)
+	for _, idx := range e.indexes {
+		if strings.EqualFold(idx.tableName, tableName) && strings.EqualFold(idx.columnName, oldName) {
+			if err := e.idxMan.RenameIndex(idx.tableName, idx.columnName, newName); err != nil {
+				return fmt.Errorf("rename index %q: %w", idx.name, err)
+			}
+			idx.columnName = newName
+		}
+	}
 
+	tbl.cols[colIdx].Name = newName
+	for _, t := range e.tables {
+		for i, c := range t.cols {
+			if ref := c.References; ref != nil && strings.EqualFold(ref.Table, tableName) && strings.EqualFold(ref.Column, oldName) {
+				t.cols[i].References = &sql.ForeignKey{Table: ref.Table, Column: newName}
+			}
+		}
+	}
 	return nil
 }
 
@@ -405,9 +686,10 @@ func (e *memEngine) CreateTable(name string, cols []sql.Column) error {
 	}
 
 	e.tables[name] = &table{
-		name: name,
-		cols: cols,
-		rows: make([]sql.Row, 0),
+		name:   name,
+		cols:   cols,
+		rows:   make([]sql.Row, 0),
+		nextID: 1,
 	}
 
 	return nil
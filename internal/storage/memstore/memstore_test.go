@@ -2,6 +2,7 @@ package memstore
 
 import (
 	"goDB/internal/sql"
+	"goDB/internal/storage"
 	"os"
 	"testing"
 )
@@ -125,6 +126,11 @@ func TestMemstoreCreateIndex(t *testing.T) {
 		t.Fatalf("CreateIndex failed: %v", err)
 	}
 
+	got, err := store.(storage.IndexLister).IndexedColumns("users")
+	if err != nil || len(got) != 1 || got[0] != "id" {
+		t.Fatalf("IndexedColumns = %v, %v; want [id]", got, err)
+	}
+
 	// 3. Verify index contents
 	memStore := store.(*memEngine)
 	idx, ok := memStore.indexes["idx_id"]
@@ -176,3 +182,44 @@ func TestMemstoreCreateIndexErrors(t *testing.T) {
 		t.Fatalf("expected non-integer column index creation to fail")
 	}
 }
+
+func TestMemstoreInsertBatch(t *testing.T) {
+	store := New()
+	if err := store.CreateTable("t", []sql.Column{{Name: "id", Type: sql.TypeInt}}); err != nil {
+		t.Fatalf("CreateTable failed: %v", err)
+	}
+
+	tx, err := store.Begin(false)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	// A bad row rejects the whole batch.
+	bad := []sql.Row{
+		{{Type: sql.TypeInt, I64: 1}},
+		{{Type: sql.TypeString, S: "x"}},
+	}
+	if err := tx.InsertBatch("t", bad); err == nil {
+		t.Fatalf("expected type mismatch error")
+	}
+
+	good := []sql.Row{
+		{{Type: sql.TypeInt, I64: 1}},
+		{{Type: sql.TypeInt, I64: 2}},
+	}
+	if err := tx.InsertBatch("t", good); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if err := store.Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	rtx, _ := store.Begin(true)
+	_, rows, err := rtx.Scan("t")
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(rows) != 2 || rows[1][0].I64 != 2 {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+}
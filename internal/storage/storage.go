@@ -1,6 +1,21 @@
 package storage
 
-import "goDB/internal/sql"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"goDB/internal/sql"
+)
+
+// ErrBusy is returned when an operation conflicts with a transaction that has
+// reserved the engine for writing (BEGIN IMMEDIATE).
+var ErrBusy = errors.New("storage: database is locked by another transaction")
+
+// ErrUnknownSetting is returned by a Configurer for a setting it does not
+// have.
+var ErrUnknownSetting = errors.New("storage: unknown setting")
 
 type RowPredicate func(row sql.Row) (bool, error)
 type RowUpdater func(row sql.Row) (sql.Row, error)
@@ -12,6 +27,10 @@ type RowUpdater func(row sql.Row) (sql.Row, error)
 type Tx interface {
 	Insert(tableName string, row sql.Row) error
 
+	// InsertBatch inserts several rows at once. Either all rows are
+	// validated and inserted, or an error is returned before any is.
+	InsertBatch(tableName string, rows []sql.Row) error
+
 	Scan(tableName string) (col []string, rows []sql.Row, err error)
 
 	// ReplaceAll replaces the entire rowset of a table.
@@ -22,6 +41,206 @@ type Tx interface {
 	UpdateWhere(tableName string, pred RowPredicate, updater RowUpdater) error
 }
 
+// FilteredScanner is an optional Tx extension for storage engines that can
+// evaluate a predicate while scanning, so rows that do not match are never
+// materialized. The predicate may be called from several goroutines at once
+// and must not retain the row it is given.
+type FilteredScanner interface {
+	ScanWhere(tableName string, pred RowPredicate) (cols []string, rows []sql.Row, err error)
+}
+
+// ContextScanner is an optional Tx extension for storage engines whose scans
+// can be cancelled. ScanContext returns the rows matching pred (all rows when
+// pred is nil) in table order, like ScanWhere, and stops with an error
+// wrapping ctx.Err() once ctx is done.
+type ContextScanner interface {
+	ScanContext(ctx context.Context, tableName string, pred RowPredicate) (cols []string, rows []sql.Row, err error)
+}
+
+// LimitScanner is an optional Tx extension for storage engines that can stop
+// a scan early. ScanLimit returns the first n rows of the table in table
+// order, without reading the rest of it.
+type LimitScanner interface {
+	ScanLimit(tableName string, n int) (cols []string, rows []sql.Row, err error)
+}
+
+// OrderedScanner is an optional Tx extension for storage engines that can
+// return rows in the order of an index instead of table order.
+type OrderedScanner interface {
+	// ScanOrdered returns the rows matching pred (all rows when pred is
+	// nil) ordered by column, descending when desc is set; rows with equal
+	// keys stay in table order. ok is false, and no rows are returned, when
+	// column has no index or the index does not cover every row.
+	ScanOrdered(tableName, column string, desc bool, pred RowPredicate) (cols []string, rows []sql.Row, ok bool, err error)
+}
+
+// RangeScanner is an optional Tx extension for storage engines that can use
+// an index on an INT column to read only the rows whose key lies in a range.
+type RangeScanner interface {
+	// ScanRange returns the rows whose column value lies in [lo, hi] and
+	// that match pred (all of them when pred is nil), in table order. ok is
+	// false, and no rows are returned, when column has no index or the
+	// index does not match the table.
+	ScanRange(tableName, column string, lo, hi int64, pred RowPredicate) (cols []string, rows []sql.Row, ok bool, err error)
+}
+
+// RowCounter is an optional Tx extension for storage engines that know the
+// number of rows in a table without reading them.
+type RowCounter interface {
+	// CountRows returns the number of rows in the table, as a Scan in the
+	// same transaction would return them.
+	CountRows(tableName string) (int, error)
+}
+
+// RowIDStore is an optional Tx extension for storage engines that give
+// every row an id, the rowid pseudo-column. Ids address rows for UPDATE and
+// DELETE within one transaction; engines may renumber rows between
+// transactions, and the id of a deleted row may be given to a later one.
+type RowIDStore interface {
+	// ScanRowIDs returns the rows of a table in table order and the rowid
+	// of each.
+	ScanRowIDs(tableName string) (cols []string, rows []sql.Row, ids []int64, err error)
+	// DeleteRowIDs deletes the rows with the given ids. Ids of rows that
+	// do not exist are ignored.
+	DeleteRowIDs(tableName string, ids []int64) error
+	// UpdateRowIDs replaces the row with id ids[i] by rows[i]. Ids of rows
+	// that do not exist are ignored.
+	UpdateRowIDs(tableName string, ids []int64, rows []sql.Row) error
+}
+
+// RowIDRangeScanner is an optional Tx extension for storage engines that
+// implement RowIDStore and can find the rows of an index key range with
+// their rowids, so UPDATE and DELETE can address them without reading the
+// rest of the table.
+type RowIDRangeScanner interface {
+	// ScanRangeRowIDs returns the rows whose column value lies in [lo, hi],
+	// in table order, and the rowid of each. ok is false, and no rows are
+	// returned, when column has no index or the index does not match the
+	// table.
+	ScanRangeRowIDs(tableName, column string, lo, hi int64) (cols []string, rows []sql.Row, ids []int64, ok bool, err error)
+}
+
+// Sequencer is an optional Engine extension for named counters shared
+// across tables (CREATE SEQUENCE and NEXTVAL). Sequences are not
+// transactional: a value handed out is never handed out again, even when
+// the transaction that used it rolls back.
+type Sequencer interface {
+	// CreateSequence creates a sequence whose first NextVal returns start.
+	CreateSequence(name string, start int64) error
+	// NextVal returns the next value of a sequence and advances it.
+	NextVal(name string) (int64, error)
+}
+
+// ImmediateBeginner is an optional Engine extension for BEGIN IMMEDIATE.
+type ImmediateBeginner interface {
+	// BeginImmediate starts a write transaction that reserves the engine
+	// for writing as it starts, so it never has to wait for or lose to
+	// another writer later. It fails with ErrBusy when another transaction
+	// already holds the reservation or has written. Until the transaction
+	// ends, writes by other transactions fail with ErrBusy.
+	BeginImmediate() (Tx, error)
+}
+
+// IndexLister is an optional Engine extension for engines that can report
+// which columns are indexed.
+type IndexLister interface {
+	// IndexedColumns returns the indexed columns of a table, sorted by name.
+	IndexedColumns(tableName string) ([]string, error)
+}
+
+// ColumnRenamer is an optional Engine extension for ALTER TABLE ... RENAME
+// COLUMN.
+type ColumnRenamer interface {
+	// RenameColumn renames a column of a table. An index on the column and
+	// the foreign keys of other tables that reference it follow the new
+	// name.
+	RenameColumn(tableName, oldName, newName string) error
+}
+
+// TableStats describes how much space a table takes up.
+type TableStats struct {
+	Rows      int   // live rows
+	Pages     int   // heap pages
+	DeadSlots int   // slots of deleted rows awaiting reuse
+	Bytes     int64 // size of the table's storage, including its header
+}
+
+// StatsReporter is an optional Engine extension for engines that can report
+// the on-disk footprint of a table.
+type StatsReporter interface {
+	TableStats(tableName string) (TableStats, error)
+}
+
+// IOMetrics are cumulative I/O counters of a storage engine since it was
+// opened.
+type IOMetrics struct {
+	PageReads    int64 // table pages read from disk
+	PageWrites   int64 // table pages written to disk
+	CacheHits    int64 // page reads answered by the page cache
+	BytesRead    int64 // bytes read by PageReads
+	BytesWritten int64 // bytes written by PageWrites
+	WALBytes     int64 // bytes appended to the WAL
+	WALSyncs     int64 // fsyncs of the WAL
+}
+
+// IOReporter is an optional Engine extension for engines that count their
+// disk I/O.
+type IOReporter interface {
+	Metrics() IOMetrics
+}
+
+// Syncer is an optional Engine extension for engines that can be told to
+// make all committed transactions durable, whatever their commit policy.
+type Syncer interface {
+	Sync() error
+}
+
+// Problem is one inconsistency found by an integrity check.
+type Problem struct {
+	Table   string // table the problem was found in; empty for the database
+	Index   string // index the problem was found in, if any
+	Page    int    // heap page, or -1 when not tied to a page
+	Slot    int    // slot in Page, or -1 when not tied to a slot
+	Message string
+}
+
+// String describes where the problem is and what it is, e.g.
+// "table users page 3 slot 2: row does not decode: ...".
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.Table != "" {
+		b.WriteString("table " + p.Table)
+	}
+	if p.Index != "" {
+		b.WriteString(" index " + p.Index)
+	}
+	if p.Page >= 0 {
+		fmt.Fprintf(&b, " page %d", p.Page)
+	}
+	if p.Slot >= 0 {
+		fmt.Fprintf(&b, " slot %d", p.Slot)
+	}
+	if b.Len() == 0 {
+		return p.Message
+	}
+	return strings.TrimPrefix(b.String(), " ") + ": " + p.Message
+}
+
+// IntegrityChecker is an optional Engine extension for engines that can
+// verify their files. CheckIntegrity reads every table and index and
+// returns all the problems it finds, or none when the data is consistent.
+type IntegrityChecker interface {
+	CheckIntegrity() []Problem
+}
+
+// Configurer is an optional Engine extension for engines with settings that
+// can be read and changed while they run, as PRAGMA statements do. Values
+// are text in the form the engine's options take them, e.g. "off" or "5m".
+type Configurer interface {
+	Setting(name string) (string, error)
+	SetSetting(name, value string) error
+}
+
 // Engine is a storage engine that can create and manage transactions.
 //
 // Different implementations are possible: